@@ -110,6 +110,21 @@ func main() {
 		defer kafkaClient.Close()
 	}
 
+	// 服务间调用令牌，环境变量优先
+	internalToken := os.Getenv("INTERNAL_API_TOKEN")
+	if internalToken == "" {
+		internalToken = v.GetString("internal.token")
+	}
+	if internalToken == "" {
+		logger.Printf("未配置服务间调用令牌，/internal接口将拒绝所有请求")
+	}
+
+	// HLS播放列表签名密钥，优先使用环境变量
+	hlsSigningKey := os.Getenv("HLS_SIGNING_KEY")
+	if hlsSigningKey == "" {
+		hlsSigningKey = v.GetString("hls.signing_key")
+	}
+
 	// 创建完整的配置对象
 	appConfig := &config.Config{
 		Server: config.ServerConfig{
@@ -127,10 +142,36 @@ func main() {
 			Brokers: []string{v.GetString("kafka.addr")},
 			Topic:   v.GetString("kafka.topic"),
 		},
+		Internal: config.InternalConfig{
+			Token: internalToken,
+		},
+		HLS: config.HLSConfig{
+			Enabled:         v.GetBool("hls.enabled"),
+			SegmentSeconds:  v.GetInt("hls.segment_seconds"),
+			SegmentType:     v.GetString("hls.segment_type"),
+			PlaybackBaseURL: v.GetString("hls.playback_base_url"),
+			SigningKey:      hlsSigningKey,
+			URLExpiryHours:  v.GetInt("hls.url_expiry_hours"),
+		},
+		Upload: config.UploadConfig{
+			MaxSizeMB:   v.GetInt("upload.max_size_mb"),
+			PartSizeMB:  v.GetInt("upload.part_size_mb"),
+			ExpiryHours: v.GetInt("upload.expiry_hours"),
+		},
+		Transcode: config.TranscodeConfig{
+			Concurrency:         v.GetInt("transcode.concurrency"),
+			MaxAttempts:         v.GetInt("transcode.max_attempts"),
+			RetryBackoffSeconds: v.GetInt("transcode.retry_backoff_seconds"),
+			LeaseSeconds:        v.GetInt("transcode.lease_seconds"),
+			HeartbeatSeconds:    v.GetInt("transcode.heartbeat_seconds"),
+			PollSeconds:         v.GetInt("transcode.poll_seconds"),
+			PregenerateProfiles: v.GetStringSlice("transcode.pregenerate_profiles"),
+		},
 	}
 
 	// 初始化服务层
 	contentService := services.NewContentService(repos, kafkaClient, logger, appConfig)
+	contentService.Start()
 
 	// 初始化API路由
 	router := api.NewRouter(appConfig, contentService)
@@ -216,5 +257,7 @@ func main() {
 		logger.Fatalf("服务器关闭错误: %v", err)
 	}
 
+	contentService.Stop()
+
 	logger.Println("内容服务已关闭")
 }
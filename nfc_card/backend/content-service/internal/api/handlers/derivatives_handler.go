@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"content-service/internal/domain/entities"
+	"content-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DerivativesHandler 处理渠道输出规格和衍生视频请求
+type DerivativesHandler struct {
+	contentService *services.ContentService
+}
+
+// NewDerivativesHandler 创建新的衍生视频处理器
+func NewDerivativesHandler(contentService *services.ContentService) *DerivativesHandler {
+	return &DerivativesHandler{
+		contentService: contentService,
+	}
+}
+
+// Profiles 获取所有渠道输出规格
+func (h *DerivativesHandler) Profiles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.contentService.OutputProfiles()})
+}
+
+// FindAll 获取视频在各输出规格下的衍生视频状态
+func (h *DerivativesHandler) FindAll(c *gin.Context) {
+	tenantID, videoID, ok := h.videoParams(c)
+	if !ok {
+		return
+	}
+
+	statuses := make([]entities.DerivativeStatus, 0)
+	for _, profile := range h.contentService.OutputProfiles() {
+		status, err := h.contentService.DerivativeStatus(videoID, tenantID, profile.Name)
+		if err != nil {
+			h.respondError(c, err)
+			return
+		}
+		statuses = append(statuses, status)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": statuses})
+}
+
+// FindOne 查询视频在指定输出规格下的衍生视频状态
+func (h *DerivativesHandler) FindOne(c *gin.Context) {
+	tenantID, videoID, ok := h.videoParams(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.contentService.DerivativeStatus(videoID, tenantID, c.Param("profile"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Request 请求生成指定输出规格的衍生视频，已生成时返回200，生成中返回202
+func (h *DerivativesHandler) Request(c *gin.Context) {
+	tenantID, videoID, ok := h.videoParams(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.contentService.RequestDerivative(videoID, tenantID, c.Param("profile"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	if status.Status == entities.DerivativeReady {
+		c.JSON(http.StatusOK, status)
+		return
+	}
+	c.JSON(http.StatusAccepted, status)
+}
+
+// videoParams 获取租户ID和视频ID并确认视频存在
+// 内部接口通过查询参数merchantId指定商户
+func (h *DerivativesHandler) videoParams(c *gin.Context) (string, string, bool) {
+	tenantID := c.Query("merchantId")
+	if value, exists := c.Get("tenantID"); exists {
+		tenantID, _ = value.(string)
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return "", "", false
+	}
+
+	videoID := c.Param("id")
+	if _, err := uuid.Parse(videoID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "视频ID格式无效"})
+		return "", "", false
+	}
+
+	if _, err := h.contentService.FindOne(videoID, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "视频不存在"})
+			return "", "", false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", "", false
+	}
+
+	return tenantID, videoID, true
+}
+
+// respondError 返回错误响应
+func (h *DerivativesHandler) respondError(c *gin.Context, err error) {
+	if serviceError, ok := err.(*services.ServiceError); ok {
+		c.JSON(getStatusCodeForError(serviceError), gin.H{
+			"error": serviceError.Message,
+			"code":  serviceError.Code,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
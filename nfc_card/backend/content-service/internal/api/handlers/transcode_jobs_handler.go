@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"content-service/internal/domain/entities"
+	"content-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 可筛选的转码任务状态
+var transcodeJobStatuses = map[string]bool{
+	entities.TranscodeJobQueued:    true,
+	entities.TranscodeJobRunning:   true,
+	entities.TranscodeJobCompleted: true,
+	entities.TranscodeJobFailed:    true,
+	entities.TranscodeJobCancelled: true,
+}
+
+// TranscodeJobsHandler 处理转码任务管理请求（管理员）
+type TranscodeJobsHandler struct {
+	contentService *services.ContentService
+}
+
+// NewTranscodeJobsHandler 创建新的转码任务管理处理器
+func NewTranscodeJobsHandler(contentService *services.ContentService) *TranscodeJobsHandler {
+	return &TranscodeJobsHandler{
+		contentService: contentService,
+	}
+}
+
+// FindAll 分页获取转码任务，可按status筛选，如status=failed
+func (h *TranscodeJobsHandler) FindAll(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && !transcodeJobStatuses[status] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务状态"})
+		return
+	}
+
+	// 获取分页参数
+	page := 1
+	limit := 20
+
+	if pageParam := c.Query("page"); pageParam != "" {
+		if parsedPage, err := strconv.Atoi(pageParam); err == nil && parsedPage > 0 {
+			page = parsedPage
+		}
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	jobs, total, err := h.contentService.ListTranscodeJobs(status, page, limit)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": jobs,
+		"meta": gin.H{
+			"currentPage":  page,
+			"itemsPerPage": limit,
+			"totalItems":   total,
+			"totalPages":   (total + limit - 1) / limit,
+		},
+	})
+}
+
+// Retry 重试失败或已取消的转码任务
+func (h *TranscodeJobsHandler) Retry(c *gin.Context) {
+	id, ok := h.jobID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.contentService.RetryTranscodeJob(id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// Cancel 取消排队中或执行中的转码任务
+func (h *TranscodeJobsHandler) Cancel(c *gin.Context) {
+	id, ok := h.jobID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.contentService.CancelTranscodeJob(id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// jobID 获取并校验路径中的任务ID
+func (h *TranscodeJobsHandler) jobID(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的转码任务ID"})
+		return "", false
+	}
+	return id, true
+}
+
+// respondError 返回错误响应
+func (h *TranscodeJobsHandler) respondError(c *gin.Context, err error) {
+	if serviceError, ok := err.(*services.ServiceError); ok {
+		c.JSON(getStatusCodeForError(serviceError), gin.H{
+			"error": serviceError.Message,
+			"code":  serviceError.Code,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"content-service/internal/domain/entities"
+	"content-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UploadSessionsHandler 处理直传会话请求，客户端使用预签名URL直接上传到对象存储
+type UploadSessionsHandler struct {
+	contentService *services.ContentService
+}
+
+// NewUploadSessionsHandler 创建新的直传会话处理器
+func NewUploadSessionsHandler(contentService *services.ContentService) *UploadSessionsHandler {
+	return &UploadSessionsHandler{
+		contentService: contentService,
+	}
+}
+
+// Create 创建直传会话，返回预签名上传URL
+func (h *UploadSessionsHandler) Create(c *gin.Context) {
+	tenantID, ok := h.tenantID(c)
+	if !ok {
+		return
+	}
+
+	var dto entities.CreateUploadSessionDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("输入数据验证失败: %s", err.Error()),
+			"code":  "invalid_input",
+		})
+		return
+	}
+
+	// 与直接上传相同的文件格式校验
+	if err := validateVideoType(dto.FileName, dto.FileType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("文件验证失败: %s", err.Error()),
+			"code":  "invalid_file",
+		})
+		return
+	}
+
+	session, err := h.contentService.CreateUploadSession(tenantID, dto)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// Get 获取直传会话状态
+func (h *UploadSessionsHandler) Get(c *gin.Context) {
+	tenantID, ok := h.tenantID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := h.sessionID(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.contentService.GetUploadSession(id, tenantID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// Complete 校验已上传的文件并创建视频
+func (h *UploadSessionsHandler) Complete(c *gin.Context) {
+	tenantID, ok := h.tenantID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := h.sessionID(c)
+	if !ok {
+		return
+	}
+
+	var dto entities.CompleteUploadSessionDTO
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("输入数据验证失败: %s", err.Error()),
+				"code":  "invalid_input",
+			})
+			return
+		}
+	}
+
+	video, err := h.contentService.CompleteUploadSession(id, tenantID, dto)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	var coverURL string
+	if video.CoverKey != "" {
+		coverURL = h.contentService.GetFileURL(video.CoverKey)
+	}
+
+	c.JSON(http.StatusCreated, entities.VideoResponse{
+		ID:           video.ID,
+		Title:        video.Title,
+		Description:  video.Description,
+		URL:          h.contentService.GetVideoURL(video),
+		MP4URL:       h.contentService.GetMP4URL(video),
+		CoverURL:     coverURL,
+		Duration:     video.Duration,
+		Width:        video.Width,
+		Height:       video.Height,
+		Size:         video.Size,
+		IsTranscoded: video.IsTranscoded,
+		CreatedAt:    video.CreatedAt,
+	})
+}
+
+// Abort 取消直传会话并删除已上传的数据
+func (h *UploadSessionsHandler) Abort(c *gin.Context) {
+	tenantID, ok := h.tenantID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := h.sessionID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.contentService.AbortUploadSession(id, tenantID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// tenantID 获取租户ID
+func (h *UploadSessionsHandler) tenantID(c *gin.Context) (string, bool) {
+	tenantID, _ := c.Get("tenantID")
+	tenantIDStr, ok := tenantID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "租户ID格式错误"})
+		return "", false
+	}
+	return tenantIDStr, true
+}
+
+// sessionID 获取并校验路径中的会话ID
+func (h *UploadSessionsHandler) sessionID(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的上传会话ID"})
+		return "", false
+	}
+	return id, true
+}
+
+// respondError 返回错误响应，会话过期返回410
+func (h *UploadSessionsHandler) respondError(c *gin.Context, err error) {
+	serviceError, ok := err.(*services.ServiceError)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+			"code":  "unknown_error",
+		})
+		return
+	}
+
+	status := getStatusCodeForError(serviceError)
+	if serviceError.Code == services.ErrCodeUploadExpired {
+		status = http.StatusGone
+	}
+	c.JSON(status, gin.H{
+		"error": serviceError.Message,
+		"code":  serviceError.Code,
+		"type":  serviceError.Type,
+	})
+}
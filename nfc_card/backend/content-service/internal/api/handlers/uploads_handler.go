@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"content-service/internal/domain/entities"
+	"content-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// tus 1.0 断点续传协议，见https://tus.io/protocols/resumable-upload
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,termination,expiration"
+	tusContentType = "application/offset+octet-stream"
+)
+
+// UploadsHandler 处理断点续传上传请求（tus 1.0）
+type UploadsHandler struct {
+	contentService *services.ContentService
+}
+
+// NewUploadsHandler 创建新的断点续传上传处理器
+func NewUploadsHandler(contentService *services.ContentService) *UploadsHandler {
+	return &UploadsHandler{
+		contentService: contentService,
+	}
+}
+
+// Options 返回服务端支持的tus版本和扩展
+func (h *UploadsHandler) Options(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.contentService.MaxUploadSize(), 10))
+	c.Status(http.StatusNoContent)
+}
+
+// Create 创建上传
+// 请求头Upload-Length为文件大小，Upload-Metadata需包含filename、filetype和title，可选description
+func (h *UploadsHandler) Create(c *gin.Context) {
+	tenantID, ok := h.begin(c)
+	if !ok {
+		return
+	}
+
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持延迟声明文件大小"})
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Length无效"})
+		return
+	}
+	if maxSize := h.contentService.MaxUploadSize(); length > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("文件过大，最大允许%dMB", maxSize/(1024*1024)),
+		})
+		return
+	}
+
+	metadata, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 与直接上传相同的文件格式校验
+	if err := validateVideoType(metadata["filename"], metadata["filetype"]); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("文件验证失败: %s", err.Error()),
+			"code":  "invalid_file",
+		})
+		return
+	}
+
+	upload, err := h.contentService.CreateUpload(tenantID, entities.CreateUploadDTO{
+		FileName:    metadata["filename"],
+		FileType:    metadata["filetype"],
+		Title:       metadata["title"],
+		Description: metadata["description"],
+		Length:      length,
+	})
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.Header("Location", strings.TrimRight(c.Request.URL.Path, "/")+"/"+upload.ID.String())
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// Head 查询上传进度，客户端断线后据此从Upload-Offset继续上传
+func (h *UploadsHandler) Head(c *gin.Context) {
+	tenantID, ok := h.begin(c)
+	if !ok {
+		return
+	}
+
+	id, ok := h.uploadID(c)
+	if !ok {
+		return
+	}
+
+	upload, err := h.contentService.GetUpload(id, tenantID)
+	if err != nil {
+		// HEAD响应不能带响应体
+		c.Status(h.statusForError(err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.Status == entities.UploadStatusUploading {
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	} else {
+		c.Header("X-Video-ID", upload.VideoID.String())
+	}
+	c.Status(http.StatusOK)
+}
+
+// Patch 从Upload-Offset处追加上传数据，接收完全部数据后创建视频并开始转码
+func (h *UploadsHandler) Patch(c *gin.Context) {
+	tenantID, ok := h.begin(c)
+	if !ok {
+		return
+	}
+
+	id, ok := h.uploadID(c)
+	if !ok {
+		return
+	}
+
+	if c.ContentType() != tusContentType {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type必须为" + tusContentType})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset无效"})
+		return
+	}
+
+	upload, err := h.contentService.WriteUpload(id, tenantID, offset, c.Request.Body)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if upload.Status == entities.UploadStatusUploading {
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	} else {
+		c.Header("X-Video-ID", upload.VideoID.String())
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Terminate 终止上传并删除已上传的数据
+func (h *UploadsHandler) Terminate(c *gin.Context) {
+	tenantID, ok := h.begin(c)
+	if !ok {
+		return
+	}
+
+	id, ok := h.uploadID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.contentService.TerminateUpload(id, tenantID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// begin 设置tus响应头，校验客户端协议版本并获取租户ID
+func (h *UploadsHandler) begin(c *gin.Context) (string, bool) {
+	c.Header("Tus-Resumable", tusVersion)
+
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.AbortWithStatus(http.StatusPreconditionFailed)
+		return "", false
+	}
+
+	tenantID, _ := c.Get("tenantID")
+	tenantIDStr, ok := tenantID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "租户ID格式错误"})
+		return "", false
+	}
+
+	return tenantIDStr, true
+}
+
+// uploadID 获取并校验路径中的上传ID
+func (h *UploadsHandler) uploadID(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.Status(http.StatusNotFound)
+		return "", false
+	}
+	return id, true
+}
+
+// statusForError 根据错误返回tus协议规定的状态码
+func (h *UploadsHandler) statusForError(err error) int {
+	serviceError, ok := err.(*services.ServiceError)
+	if !ok {
+		return http.StatusInternalServerError
+	}
+
+	switch serviceError.Code {
+	case services.ErrCodeUploadExpired:
+		return http.StatusGone
+	case services.ErrCodeUploadLocked:
+		return http.StatusLocked
+	}
+	return getStatusCodeForError(serviceError)
+}
+
+// respondError 返回错误响应
+func (h *UploadsHandler) respondError(c *gin.Context, err error) {
+	if serviceError, ok := err.(*services.ServiceError); ok {
+		c.JSON(h.statusForError(err), gin.H{
+			"error": serviceError.Message,
+			"code":  serviceError.Code,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// parseUploadMetadata 解析Upload-Metadata请求头
+// 格式为逗号分隔的键值对，键与值之间用空格分隔，值为Base64编码，值可以省略
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		switch len(fields) {
+		case 1:
+			metadata[fields[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("Upload-Metadata中%s的值不是有效的Base64编码", fields[0])
+			}
+			metadata[fields[0]] = string(value)
+		default:
+			return nil, fmt.Errorf("Upload-Metadata格式无效")
+		}
+	}
+
+	return metadata, nil
+}
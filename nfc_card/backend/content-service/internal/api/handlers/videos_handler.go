@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"net/http"
@@ -12,6 +14,7 @@ import (
 	"content-service/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // 允许的视频格式和大小限制
@@ -55,21 +58,29 @@ func (h *VideosHandler) validateVideoFile(file *multipart.FileHeader) error {
 		return fmt.Errorf("文件过大，最大允许%dMB", maxUploadSize/(1024*1024))
 	}
 
+	if err := validateVideoType(file.Filename, file.Header.Get("Content-Type")); err != nil {
+		return err
+	}
+
+	// TODO: 实现病毒/恶意软件扫描
+	// TODO: 实现内容安全检查（暴力、色情等）
+
+	return nil
+}
+
+// validateVideoType 验证视频文件扩展名和MIME类型
+func validateVideoType(fileName, contentType string) error {
 	// 检查文件扩展名
-	ext := strings.ToLower(filepath.Ext(file.Filename))
+	ext := strings.ToLower(filepath.Ext(fileName))
 	if !allowedVideoExtensions[ext] {
 		return fmt.Errorf("不支持的文件格式，允许的格式: mp4, mov, avi, webm")
 	}
 
 	// 检查文件MIME类型
-	contentType := file.Header.Get("Content-Type")
 	if !allowedVideoTypes[contentType] {
 		return fmt.Errorf("不支持的文件类型，允许的类型: video/mp4, video/quicktime, video/x-msvideo, video/webm")
 	}
 
-	// TODO: 实现病毒/恶意软件扫描
-	// TODO: 实现内容安全检查（暴力、色情等）
-
 	return nil
 }
 
@@ -144,6 +155,7 @@ func (h *VideosHandler) Create(c *gin.Context) {
 		Title:        video.Title,
 		Description:  video.Description,
 		URL:          videoURL,
+		MP4URL:       h.contentService.GetMP4URL(video),
 		CoverURL:     coverURL,
 		Duration:     video.Duration,
 		Width:        video.Width,
@@ -161,8 +173,10 @@ func getStatusCodeForError(err *services.ServiceError) int {
 		return http.StatusBadRequest
 	case services.ErrTypeNotFound:
 		return http.StatusNotFound
-	case services.ErrTypeUnauthorized:
+	case services.ErrTypeUnauthorized, services.ErrTypeQuota:
 		return http.StatusForbidden
+	case services.ErrTypeConflict:
+		return http.StatusConflict
 	case services.ErrTypeDatabase, services.ErrTypeStorage, services.ErrTypeTranscode:
 		return http.StatusInternalServerError
 	default:
@@ -227,6 +241,7 @@ func (h *VideosHandler) FindAll(c *gin.Context) {
 			Title:        video.Title,
 			Description:  video.Description,
 			URL:          videoURL,
+			MP4URL:       h.contentService.GetMP4URL(video),
 			CoverURL:     coverURL,
 			Duration:     video.Duration,
 			Width:        video.Width,
@@ -290,6 +305,7 @@ func (h *VideosHandler) FindOne(c *gin.Context) {
 			Title:        video.Title,
 			Description:  video.Description,
 			URL:          videoURL,
+			MP4URL:       h.contentService.GetMP4URL(video),
 			CoverURL:     coverURL,
 			Duration:     video.Duration,
 			Width:        video.Width,
@@ -346,11 +362,158 @@ func (h *VideosHandler) Transcode(c *gin.Context) {
 		return
 	}
 
-	// 开始转码
+	// 加入转码队列
 	if err := h.contentService.StartTranscode(id, tenantIDStr); err != nil {
+		if serviceError, ok := err.(*services.ServiceError); ok {
+			c.JSON(getStatusCodeForError(serviceError), gin.H{
+				"error": serviceError.Message,
+				"code":  serviceError.Code,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "视频已加入转码队列"})
+}
+
+// CancelTranscode 取消视频未完成的转码任务
+func (h *VideosHandler) CancelTranscode(c *gin.Context) {
+	// 获取租户ID
+	tenantID, _ := c.Get("tenantID")
+	tenantIDStr, ok := tenantID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "租户ID格式错误"})
+		return
+	}
+
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "视频ID格式无效"})
+		return
+	}
+
+	job, err := h.contentService.CancelTranscode(id, tenantIDStr)
+	if err != nil {
+		if serviceError, ok := err.(*services.ServiceError); ok {
+			c.JSON(getStatusCodeForError(serviceError), gin.H{
+				"error": serviceError.Message,
+				"code":  serviceError.Code,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// FindPlayback 供其他服务获取视频的播放地址和封面（内部接口）
+// 查询参数merchantId必填，只返回该商户的视频
+func (h *VideosHandler) FindPlayback(c *gin.Context) {
+	merchantID := c.Query("merchantId")
+	if _, err := uuid.Parse(merchantID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "视频ID格式无效"})
+		return
+	}
+
+	video, err := h.contentService.FindOne(id, merchantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "视频不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var coverURL string
+	if video.CoverKey != "" {
+		coverURL = h.contentService.GetFileURL(video.CoverKey)
+	}
+
+	c.JSON(http.StatusOK, entities.DetailedVideoResponse{
+		VideoResponse: entities.VideoResponse{
+			ID:           video.ID,
+			Title:        video.Title,
+			Description:  video.Description,
+			URL:          h.contentService.GetVideoURL(video),
+			MP4URL:       h.contentService.GetMP4URL(video),
+			CoverURL:     coverURL,
+			Duration:     video.Duration,
+			Width:        video.Width,
+			Height:       video.Height,
+			Size:         video.Size,
+			IsTranscoded: video.IsTranscoded,
+			CreatedAt:    video.CreatedAt,
+		},
+		TranscodeStatus: video.TranscodeStatus,
+		UpdatedAt:       video.UpdatedAt,
+	})
+}
+
+// Renditions 获取视频各分辨率的转码输出
+func (h *VideosHandler) Renditions(c *gin.Context) {
+	// 获取租户ID
+	tenantID, _ := c.Get("tenantID")
+	tenantIDStr, ok := tenantID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "租户ID格式错误"})
+		return
+	}
+
+	// 获取视频ID
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "视频ID格式无效"})
+		return
+	}
+
+	renditions, err := h.contentService.FindRenditions(id, tenantIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": renditions})
+}
+
+// Playlist 下发HLS播放列表（公开接口，通过地址签名鉴权）
+// 分辨率播放列表中的切片地址改写为对象存储预签名URL
+func (h *VideosHandler) Playlist(c *gin.Context) {
+	tenantID, err := uuid.Parse(c.Param("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+	videoID, err := uuid.Parse(c.Param("videoID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "视频ID格式无效"})
+		return
+	}
+
+	playlist, err := h.contentService.RenderPlaylist(tenantID.String(), videoID.String(), c.Param("name"), c.Query("expires"), c.Query("sig"))
+	if err != nil {
+		if serviceError, ok := err.(*services.ServiceError); ok {
+			c.JSON(getStatusCodeForError(serviceError), gin.H{
+				"error": serviceError.Message,
+				"code":  serviceError.Code,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "视频转码任务已启动"})
+	// 播放列表中的地址有时效，只允许短时间缓存
+	c.Header("Cache-Control", "private, max-age=60")
+	c.Data(http.StatusOK, "application/vnd.apple.mpegurl", playlist)
 }
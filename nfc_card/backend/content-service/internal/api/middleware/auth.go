@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
@@ -77,6 +78,20 @@ func AuthMiddleware(secretKey string) gin.HandlerFunc {
 	}
 }
 
+// InternalAuthMiddleware 服务间调用认证中间件，校验X-Internal-Token头
+// 未配置令牌时拒绝所有请求，避免内部接口意外暴露
+func InternalAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader("X-Internal-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "服务间调用令牌无效"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // RoleMiddleware 角色验证中间件
 func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -27,6 +27,10 @@ func NewRouter(cfg *config.Config, contentService *services.ContentService) http
 
 	// 初始化处理程序
 	videosHandler := handlers.NewVideosHandler(contentService)
+	uploadsHandler := handlers.NewUploadsHandler(contentService)
+	uploadSessionsHandler := handlers.NewUploadSessionsHandler(contentService)
+	transcodeJobsHandler := handlers.NewTranscodeJobsHandler(contentService)
+	derivativesHandler := handlers.NewDerivativesHandler(contentService)
 
 	// API路由组 - 公共路由（无需认证）
 	apiV1 := router.Group("/api/v1")
@@ -37,6 +41,13 @@ func NewRouter(cfg *config.Config, contentService *services.ContentService) http
 				"message": "测试成功",
 			})
 		})
+
+		// HLS播放列表，通过地址签名鉴权
+		apiV1.GET("/hls/:tenantID/:videoID/*name", videosHandler.Playlist)
+
+		// tus协议信息
+		apiV1.OPTIONS("/uploads", uploadsHandler.Options)
+		apiV1.OPTIONS("/uploads/:id", uploadsHandler.Options)
 	}
 
 	// API路由组 - 受保护路由（需要认证）
@@ -61,8 +72,83 @@ func NewRouter(cfg *config.Config, contentService *services.ContentService) http
 
 			// 转码视频
 			videos.POST("/:id/transcode", videosHandler.Transcode)
+
+			// 取消转码
+			videos.DELETE("/:id/transcode", videosHandler.CancelTranscode)
+
+			// 获取各渠道衍生视频状态
+			videos.GET("/:id/derivatives", derivativesHandler.FindAll)
+
+			// 生成指定渠道的衍生视频
+			videos.POST("/:id/derivatives/:profile", derivativesHandler.Request)
+
+			// 获取视频各分辨率的转码输出
+			videos.GET("/:id/renditions", videosHandler.Renditions)
+		}
+
+		// 渠道输出规格
+		protectedAPI.GET("/output-profiles", derivativesHandler.Profiles)
+
+		// 断点续传上传（tus 1.0）
+		uploads := protectedAPI.Group("/uploads")
+		uploads.Use(middleware.TenantAuthMiddleware(cfg.JWT.Secret))
+		{
+			// 创建上传
+			uploads.POST("", uploadsHandler.Create)
+
+			// 查询上传进度
+			uploads.HEAD("/:id", uploadsHandler.Head)
+
+			// 追加上传数据
+			uploads.PATCH("/:id", uploadsHandler.Patch)
+
+			// 终止上传
+			uploads.DELETE("/:id", uploadsHandler.Terminate)
+		}
+
+		// 直传会话（预签名URL直接上传到对象存储）
+		uploadSessions := protectedAPI.Group("/upload-sessions")
+		uploadSessions.Use(middleware.TenantAuthMiddleware(cfg.JWT.Secret))
+		{
+			// 创建会话并获取上传URL
+			uploadSessions.POST("", uploadSessionsHandler.Create)
+
+			// 获取会话状态
+			uploadSessions.GET("/:id", uploadSessionsHandler.Get)
+
+			// 完成上传并创建视频
+			uploadSessions.POST("/:id/complete", uploadSessionsHandler.Complete)
+
+			// 取消会话
+			uploadSessions.DELETE("/:id", uploadSessionsHandler.Abort)
+		}
+
+		// 转码任务管理（管理员）
+		transcodeJobs := protectedAPI.Group("/admin/transcode-jobs")
+		transcodeJobs.Use(middleware.RoleMiddleware(middleware.RoleAdmin))
+		{
+			// 获取转码任务列表
+			transcodeJobs.GET("", transcodeJobsHandler.FindAll)
+
+			// 重试失败的转码任务
+			transcodeJobs.POST("/:id/retry", transcodeJobsHandler.Retry)
+
+			// 取消转码任务
+			transcodeJobs.POST("/:id/cancel", transcodeJobsHandler.Cancel)
 		}
 	}
 
+	// 内部路由组 - 供其他服务调用，使用服务间令牌认证
+	internalAPI := router.Group("/internal/v1")
+	internalAPI.Use(middleware.InternalAuthMiddleware(cfg.Internal.Token))
+	{
+		// 获取视频播放地址和封面（nfc-service落地页使用）
+		internalAPI.GET("/videos/:id", videosHandler.FindPlayback)
+
+		// 查询和按需生成渠道衍生视频（distribution-service分发时使用）
+		internalAPI.GET("/videos/:id/derivatives/:profile", derivativesHandler.FindOne)
+		internalAPI.POST("/videos/:id/derivatives/:profile", derivativesHandler.Request)
+	}
+
 	return router
 }
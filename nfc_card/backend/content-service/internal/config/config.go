@@ -8,11 +8,15 @@ import (
 
 // Config 应用程序配置
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Kafka    KafkaConfig
-	Storage  StorageConfig
-	JWT      JWTConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Kafka     KafkaConfig
+	Storage   StorageConfig
+	JWT       JWTConfig
+	Internal  InternalConfig
+	HLS       HLSConfig
+	Upload    UploadConfig
+	Transcode TranscodeConfig
 }
 
 // ServerConfig 服务器配置
@@ -51,6 +55,56 @@ type JWTConfig struct {
 	ExpiryHours int
 }
 
+// InternalConfig 服务间调用配置
+type InternalConfig struct {
+	// Token 其他服务调用/internal接口时在X-Internal-Token头中携带的令牌
+	Token string
+}
+
+// HLSConfig HLS自适应码率播放配置
+type HLSConfig struct {
+	// Enabled 转码后是否打包HLS
+	Enabled bool
+	// SegmentSeconds 切片时长（秒），转码时按2秒对齐关键帧，建议取2的倍数
+	SegmentSeconds int
+	// SegmentType 切片格式，fmp4或mpegts
+	SegmentType string
+	// PlaybackBaseURL 播放列表接口的外部访问地址，如https://api.example.com/content，为空时只返回MP4地址
+	PlaybackBaseURL string
+	// SigningKey 播放列表地址的签名密钥
+	SigningKey string
+	// URLExpiryHours 播放列表和切片地址的有效期（小时）
+	URLExpiryHours int
+}
+
+// UploadConfig 断点续传上传配置
+type UploadConfig struct {
+	// MaxSizeMB 单个视频的最大大小（MB）
+	MaxSizeMB int
+	// PartSizeMB 写入对象存储的分片大小（MB），不能小于5
+	PartSizeMB int
+	// ExpiryHours 上传在最后一次写入后保留的时间（小时），过期后清理已上传的分片
+	ExpiryHours int
+}
+
+// TranscodeConfig 转码任务队列配置
+type TranscodeConfig struct {
+	// Concurrency 每个实例同时执行的转码任务数
+	Concurrency int
+	// MaxAttempts 每个任务的最大执行次数
+	MaxAttempts int
+	// RetryBackoffSeconds 首次重试的等待时间（秒），之后每次翻倍
+	RetryBackoffSeconds int
+	// LeaseSeconds 任务租约时长（秒），超过该时间没有心跳的任务由其他实例接管
+	LeaseSeconds int
+	// HeartbeatSeconds 心跳间隔（秒），应明显小于租约时长
+	HeartbeatSeconds int
+	// PollSeconds 队列为空时的轮询间隔（秒）
+	PollSeconds int
+	// PregenerateProfiles 视频转码完成后预先生成衍生视频的输出规格，未列出的规格在分发时按需生成
+	PregenerateProfiles []string
+}
+
 // LoadConfig 从文件加载配置
 func LoadConfig(configPath string) (*Config, error) {
 	viper.SetConfigFile(configPath)
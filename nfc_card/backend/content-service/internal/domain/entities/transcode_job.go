@@ -0,0 +1,37 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 转码任务状态
+const (
+	TranscodeJobQueued    = "queued"
+	TranscodeJobRunning   = "running"
+	TranscodeJobCompleted = "completed"
+	TranscodeJobFailed    = "failed"
+	TranscodeJobCancelled = "cancelled"
+)
+
+// TranscodeJob 转码队列中的任务，Profile不为空时生成该输出规格的衍生视频
+type TranscodeJob struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	TenantID        uuid.UUID  `json:"tenantId" db:"merchant_id"`
+	VideoID         uuid.UUID  `json:"videoId" db:"video_id"`
+	Profile         string     `json:"profile,omitempty" db:"profile"`
+	Status          string     `json:"status" db:"status"`
+	Attempts        int        `json:"attempts" db:"attempts"`
+	MaxAttempts     int        `json:"maxAttempts" db:"max_attempts"`
+	RunAt           time.Time  `json:"runAt" db:"run_at"`
+	WorkerID        string     `json:"workerId,omitempty" db:"worker_id"`
+	LeaseExpiresAt  *time.Time `json:"leaseExpiresAt,omitempty" db:"lease_expires_at"`
+	HeartbeatAt     *time.Time `json:"heartbeatAt,omitempty" db:"heartbeat_at"`
+	CancelRequested bool       `json:"cancelRequested" db:"cancel_requested"`
+	LastError       string     `json:"lastError,omitempty" db:"last_error"`
+	StartedAt       *time.Time `json:"startedAt,omitempty" db:"started_at"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty" db:"finished_at"`
+	CreatedAt       time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updatedAt" db:"updated_at"`
+}
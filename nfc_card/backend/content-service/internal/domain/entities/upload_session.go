@@ -0,0 +1,76 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 直传会话状态
+const (
+	UploadSessionPending   = "pending"
+	UploadSessionCompleted = "completed"
+	UploadSessionFailed    = "failed"
+)
+
+// UploadSession 视频直传会话
+// 客户端使用预签名URL直接上传到对象存储，不经过content-service
+type UploadSession struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	TenantID        uuid.UUID  `json:"tenantId" db:"merchant_id"`
+	VideoID         uuid.UUID  `json:"videoId" db:"video_id"`
+	FileKey         string     `json:"fileKey" db:"file_key"`
+	StorageUploadID string     `json:"-" db:"storage_upload_id"`
+	PartSize        int64      `json:"partSize" db:"part_size"`
+	PartCount       int        `json:"partCount" db:"part_count"`
+	FileName        string     `json:"fileName" db:"file_name"`
+	FileType        string     `json:"fileType" db:"file_type"`
+	Title           string     `json:"title" db:"title"`
+	Description     string     `json:"description" db:"description"`
+	Size            int64      `json:"size" db:"size"`
+	ChecksumSHA256  string     `json:"checksumSha256" db:"checksum_sha256"`
+	Status          string     `json:"status" db:"status"`
+	FailureReason   string     `json:"failureReason,omitempty" db:"failure_reason"`
+	ExpiresAt       time.Time  `json:"expiresAt" db:"expires_at"`
+	CompletedAt     *time.Time `json:"completedAt,omitempty" db:"completed_at"`
+	CreatedAt       time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// Multipart 是否使用分片上传
+func (s *UploadSession) Multipart() bool {
+	return s.StorageUploadID != ""
+}
+
+// CreateUploadSessionDTO 创建直传会话的数据传输对象
+type CreateUploadSessionDTO struct {
+	FileName       string `json:"fileName" binding:"required,max=255"`
+	FileType       string `json:"fileType" binding:"required"`
+	Size           int64  `json:"size" binding:"required,gt=0"`
+	ChecksumSHA256 string `json:"checksumSha256" binding:"required,len=64,hexadecimal"`
+	Title          string `json:"title" binding:"required,max=255"`
+	Description    string `json:"description"`
+}
+
+// UploadSessionPart 分片上传的一个分片
+type UploadSessionPart struct {
+	PartNumber int    `json:"partNumber"`
+	URL        string `json:"url,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	ETag       string `json:"etag,omitempty"`
+}
+
+// UploadSessionResponse 创建直传会话的响应
+// 单次上传时使用URL，分片上传时按Parts逐个PUT，并在完成时提交每个分片响应头中的ETag
+type UploadSessionResponse struct {
+	UploadSession
+	Method  string              `json:"method"`
+	URL     string              `json:"url,omitempty"`
+	Parts   []UploadSessionPart `json:"parts,omitempty"`
+	Headers map[string]string   `json:"headers"`
+}
+
+// CompleteUploadSessionDTO 完成直传会话的数据传输对象，分片上传时必须提交全部分片的ETag
+type CompleteUploadSessionDTO struct {
+	Parts []UploadSessionPart `json:"parts"`
+}
@@ -35,6 +35,7 @@ type Video struct {
 	StoragePath     string          `json:"storagePath" db:"storage_path"`
 	CoverURL        string          `json:"coverUrl" db:"cover_url"`
 	IsPublic        bool            `json:"isPublic" db:"is_public"`
+	HLSMasterKey    string          `json:"hlsMasterKey" db:"hls_master_key"`
 	CreatedAt       time.Time       `json:"createdAt" db:"created_at"`
 	UpdatedAt       time.Time       `json:"updatedAt" db:"updated_at"`
 }
@@ -51,6 +52,7 @@ type VideoResponse struct {
 	Title        string    `json:"title" db:"Title"`
 	Description  string    `json:"description,omitempty" db:"Description"`
 	URL          string    `json:"url" db:"U_r_l"`
+	MP4URL       string    `json:"mp4Url,omitempty" db:"M_p4_u_r_l"`
 	CoverURL     string    `json:"coverUrl,omitempty" db:"Cover_u_r_l"`
 	Duration     float64   `json:"duration,omitempty" db:"Duration"`
 	Width        int       `json:"width,omitempty" db:"Width"`
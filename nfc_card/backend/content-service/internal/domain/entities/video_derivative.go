@@ -0,0 +1,72 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 画面适配方式：源视频宽高比与输出规格不一致时的处理
+const (
+	FitCrop = "crop" // 等比放大后裁掉超出部分
+	FitPad  = "pad"  // 等比缩小后用黑边填充
+	FitBlur = "blur" // 等比缩小后用模糊放大的画面填充背景
+)
+
+// OutputProfile 视频输出规格，对应一个分发渠道的格式要求
+type OutputProfile struct {
+	Name   string `json:"name"`
+	Label  string `json:"label"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Fit 画面适配方式
+	Fit string `json:"fit"`
+	// MaxDuration 最大时长（秒），超过时截断，0表示不限制
+	MaxDuration float64 `json:"maxDuration"`
+	// MaxSizeMB 文件大小上限（MB），按时长降低码率以满足限制，0表示不限制
+	MaxSizeMB int `json:"maxSizeMb"`
+	// MaxBitrateKbps 视频码率上限（kbps）
+	MaxBitrateKbps int `json:"maxBitrateKbps"`
+	// AudioBitrateKbps 音频码率（kbps）
+	AudioBitrateKbps int `json:"audioBitrateKbps"`
+	// 编码参数
+	VideoCodec   string `json:"videoCodec"`
+	CodecProfile string `json:"codecProfile"`
+	Level        string `json:"level"`
+	Preset       string `json:"preset"`
+	CRF          int    `json:"crf"`
+	FrameRate    int    `json:"frameRate"`
+}
+
+// VideoDerivative 按输出规格生成的衍生视频
+type VideoDerivative struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	VideoID   uuid.UUID `json:"videoId" db:"video_id"`
+	TenantID  uuid.UUID `json:"tenantId" db:"merchant_id"`
+	Profile   string    `json:"profile" db:"profile"`
+	FileKey   string    `json:"fileKey" db:"file_key"`
+	Width     int       `json:"width" db:"width"`
+	Height    int       `json:"height" db:"height"`
+	Duration  float64   `json:"duration" db:"duration"`
+	FileSize  int64     `json:"fileSize" db:"file_size"`
+	Bitrate   int       `json:"bitrate" db:"bitrate"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// 衍生视频状态，其余取值与转码任务状态相同
+const (
+	DerivativeReady = "ready"
+	DerivativeNone  = "none"
+)
+
+// DerivativeStatus 衍生视频的生成状态
+// Status为ready时URL为衍生视频的临时访问地址，否则为最近一次生成任务的状态
+type DerivativeStatus struct {
+	Profile    string           `json:"profile"`
+	Status     string           `json:"status"`
+	Derivative *VideoDerivative `json:"derivative,omitempty"`
+	URL        string           `json:"url,omitempty"`
+	JobID      *uuid.UUID       `json:"jobId,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
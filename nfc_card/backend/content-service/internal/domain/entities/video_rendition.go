@@ -0,0 +1,25 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VideoRendition 视频某个分辨率的转码输出
+type VideoRendition struct {
+	ID               uuid.UUID `json:"id" db:"id"`
+	VideoID          uuid.UUID `json:"videoId" db:"video_id"`
+	TenantID         uuid.UUID `json:"tenantId" db:"merchant_id"`
+	Name             string    `json:"name" db:"name"`
+	Width            int       `json:"width" db:"width"`
+	Height           int       `json:"height" db:"height"`
+	FileKey          string    `json:"fileKey" db:"file_key"`
+	FileSize         int64     `json:"fileSize" db:"file_size"`
+	PlaylistKey      string    `json:"playlistKey" db:"playlist_key"`
+	SegmentCount     int       `json:"segmentCount" db:"segment_count"`
+	Bandwidth        int       `json:"bandwidth" db:"bandwidth"`
+	AverageBandwidth int       `json:"averageBandwidth" db:"average_bandwidth"`
+	Codecs           string    `json:"codecs" db:"codecs"`
+	CreatedAt        time.Time `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,98 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 断点续传上传状态
+const (
+	UploadStatusUploading = "uploading"
+	UploadStatusCompleted = "completed"
+)
+
+// VideoUpload 视频断点续传上传
+// 数据按固定大小写入对象存储分片上传，不足一个分片的数据暂存在临时对象中，下次写入时拼接
+type VideoUpload struct {
+	ID              uuid.UUID   `json:"id" db:"id"`
+	TenantID        uuid.UUID   `json:"tenantId" db:"merchant_id"`
+	VideoID         uuid.UUID   `json:"videoId" db:"video_id"`
+	FileKey         string      `json:"fileKey" db:"file_key"`
+	StorageUploadID string      `json:"-" db:"storage_upload_id"`
+	FileName        string      `json:"fileName" db:"file_name"`
+	FileType        string      `json:"fileType" db:"file_type"`
+	Title           string      `json:"title" db:"title"`
+	Description     string      `json:"description" db:"description"`
+	Length          int64       `json:"length" db:"upload_length"`
+	Offset          int64       `json:"offset" db:"upload_offset"`
+	Parts           UploadParts `json:"-" db:"parts"`
+	PendingSize     int64       `json:"-" db:"pending_size"`
+	Status          string      `json:"status" db:"status"`
+	ExpiresAt       time.Time   `json:"expiresAt" db:"expires_at"`
+	CreatedAt       time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt       time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+// Completed 是否已接收全部数据
+func (u *VideoUpload) Completed() bool {
+	return u.Offset == u.Length
+}
+
+// PartsSize 已写入分片的字节数
+func (u *VideoUpload) PartsSize() int64 {
+	return u.Offset - u.PendingSize
+}
+
+// UploadPart 已写入对象存储的分片
+type UploadPart struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// UploadParts 分片列表，按分片号递增
+type UploadParts []UploadPart
+
+// Value 实现driver.Valuer接口
+func (p UploadParts) Value() (driver.Value, error) {
+	if p == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现sql.Scanner接口
+func (p *UploadParts) Scan(value interface{}) error {
+	if value == nil {
+		*p = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("无法扫描分片列表")
+	}
+	return json.Unmarshal(data, p)
+}
+
+// CreateUploadDTO 创建断点续传上传的数据传输对象
+type CreateUploadDTO struct {
+	FileName    string
+	FileType    string
+	Title       string
+	Description string
+	Length      int64
+}
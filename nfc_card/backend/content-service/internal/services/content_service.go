@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"io"
 	"log"
 	"mime/multipart"
 
@@ -18,6 +19,8 @@ type ContentService struct {
 	logger      *log.Logger
 	// 添加VideoService作为内部实现
 	videoService *VideoService
+	// 断点续传上传
+	uploadService *UploadService
 }
 
 // NewContentService 创建内容服务实例
@@ -55,10 +58,31 @@ func NewContentService(repos *storage.Repositories, kafkaClient *messaging.Kafka
 	videoService := NewVideoService(cfg, storageService, kafkaProducer)
 
 	return &ContentService{
-		repos:        repos,
-		kafkaClient:  kafkaClient,
-		logger:       logger,
-		videoService: videoService,
+		repos:         repos,
+		kafkaClient:   kafkaClient,
+		logger:        logger,
+		videoService:  videoService,
+		uploadService: NewUploadService(videoService, cfg),
+	}
+}
+
+// Start 启动后台任务
+func (s *ContentService) Start() {
+	if s.videoService != nil {
+		s.videoService.Start()
+	}
+	if s.uploadService != nil {
+		s.uploadService.Start()
+	}
+}
+
+// Stop 停止后台任务
+func (s *ContentService) Stop() {
+	if s.uploadService != nil {
+		s.uploadService.Stop()
+	}
+	if s.videoService != nil {
+		s.videoService.Stop()
 	}
 }
 
@@ -127,3 +151,152 @@ func (s *ContentService) StartTranscode(videoID, tenantID string) error {
 	}
 	return errors.New("视频服务未初始化")
 }
+
+// CancelTranscode 取消视频未完成的转码任务
+func (s *ContentService) CancelTranscode(videoID, tenantID string) (entities.TranscodeJob, error) {
+	if s.videoService != nil {
+		return s.videoService.CancelTranscode(videoID, tenantID)
+	}
+	return entities.TranscodeJob{}, errors.New("视频服务未初始化")
+}
+
+// ListTranscodeJobs 按状态分页获取所有商户的转码任务（管理员）
+func (s *ContentService) ListTranscodeJobs(status string, page, limit int) ([]entities.TranscodeJob, int, error) {
+	if s.videoService != nil {
+		return s.videoService.transcodeService.ListJobs(status, page, limit)
+	}
+	return nil, 0, errors.New("视频服务未初始化")
+}
+
+// RetryTranscodeJob 重试失败或已取消的转码任务（管理员）
+func (s *ContentService) RetryTranscodeJob(id string) (entities.TranscodeJob, error) {
+	if s.videoService != nil {
+		return s.videoService.transcodeService.RetryJob(id)
+	}
+	return entities.TranscodeJob{}, errors.New("视频服务未初始化")
+}
+
+// CancelTranscodeJob 取消转码任务（管理员）
+func (s *ContentService) CancelTranscodeJob(id string) (entities.TranscodeJob, error) {
+	if s.videoService != nil {
+		return s.videoService.transcodeService.CancelJob(id)
+	}
+	return entities.TranscodeJob{}, errors.New("视频服务未初始化")
+}
+
+// GetMP4URL 获取视频MP4文件的访问URL
+func (s *ContentService) GetMP4URL(video entities.Video) string {
+	if s.videoService != nil {
+		return s.videoService.GetMP4URL(video)
+	}
+	return ""
+}
+
+// FindRenditions 获取视频各分辨率的转码输出
+func (s *ContentService) FindRenditions(videoID, tenantID string) ([]entities.VideoRendition, error) {
+	if s.videoService != nil {
+		return s.videoService.FindRenditions(videoID, tenantID)
+	}
+	return nil, errors.New("视频服务未初始化")
+}
+
+// RenderPlaylist 校验签名后返回改写过地址的HLS播放列表
+func (s *ContentService) RenderPlaylist(tenantID, videoID, name, expires, sig string) ([]byte, error) {
+	if s.videoService != nil {
+		return s.videoService.RenderPlaylist(tenantID, videoID, name, expires, sig)
+	}
+	return nil, errors.New("视频服务未初始化")
+}
+
+// MaxUploadSize 断点续传上传的最大文件大小（字节）
+func (s *ContentService) MaxUploadSize() int64 {
+	if s.uploadService != nil {
+		return s.uploadService.MaxSize()
+	}
+	return 0
+}
+
+// CreateUpload 创建断点续传上传
+func (s *ContentService) CreateUpload(tenantID string, dto entities.CreateUploadDTO) (entities.VideoUpload, error) {
+	if s.uploadService != nil {
+		return s.uploadService.CreateUpload(tenantID, dto)
+	}
+	return entities.VideoUpload{}, errors.New("上传服务未初始化")
+}
+
+// GetUpload 获取断点续传上传进度
+func (s *ContentService) GetUpload(id, tenantID string) (entities.VideoUpload, error) {
+	if s.uploadService != nil {
+		return s.uploadService.GetUpload(id, tenantID)
+	}
+	return entities.VideoUpload{}, errors.New("上传服务未初始化")
+}
+
+// WriteUpload 从offset处追加写入上传数据
+func (s *ContentService) WriteUpload(id, tenantID string, offset int64, body io.Reader) (entities.VideoUpload, error) {
+	if s.uploadService != nil {
+		return s.uploadService.WriteUpload(id, tenantID, offset, body)
+	}
+	return entities.VideoUpload{}, errors.New("上传服务未初始化")
+}
+
+// TerminateUpload 终止断点续传上传
+func (s *ContentService) TerminateUpload(id, tenantID string) error {
+	if s.uploadService != nil {
+		return s.uploadService.TerminateUpload(id, tenantID)
+	}
+	return errors.New("上传服务未初始化")
+}
+
+// CreateUploadSession 创建直传会话
+func (s *ContentService) CreateUploadSession(tenantID string, dto entities.CreateUploadSessionDTO) (entities.UploadSessionResponse, error) {
+	if s.uploadService != nil {
+		return s.uploadService.CreateSession(tenantID, dto)
+	}
+	return entities.UploadSessionResponse{}, errors.New("上传服务未初始化")
+}
+
+// GetUploadSession 获取直传会话
+func (s *ContentService) GetUploadSession(id, tenantID string) (entities.UploadSession, error) {
+	if s.uploadService != nil {
+		return s.uploadService.GetSession(id, tenantID)
+	}
+	return entities.UploadSession{}, errors.New("上传服务未初始化")
+}
+
+// CompleteUploadSession 完成直传会话并创建视频
+func (s *ContentService) CompleteUploadSession(id, tenantID string, dto entities.CompleteUploadSessionDTO) (entities.Video, error) {
+	if s.uploadService != nil {
+		return s.uploadService.CompleteSession(id, tenantID, dto)
+	}
+	return entities.Video{}, errors.New("上传服务未初始化")
+}
+
+// AbortUploadSession 取消直传会话
+func (s *ContentService) AbortUploadSession(id, tenantID string) error {
+	if s.uploadService != nil {
+		return s.uploadService.AbortSession(id, tenantID)
+	}
+	return errors.New("上传服务未初始化")
+}
+
+// OutputProfiles 获取所有渠道输出规格
+func (s *ContentService) OutputProfiles() []entities.OutputProfile {
+	return OutputProfiles()
+}
+
+// DerivativeStatus 查询视频在某个输出规格下的衍生视频状态
+func (s *ContentService) DerivativeStatus(videoID, tenantID, profile string) (entities.DerivativeStatus, error) {
+	if s.videoService != nil {
+		return s.videoService.transcodeService.DerivativeStatus(videoID, tenantID, profile)
+	}
+	return entities.DerivativeStatus{}, errors.New("视频服务未初始化")
+}
+
+// RequestDerivative 请求生成衍生视频
+func (s *ContentService) RequestDerivative(videoID, tenantID, profile string) (entities.DerivativeStatus, error) {
+	if s.videoService != nil {
+		return s.videoService.transcodeService.RequestDerivative(videoID, tenantID, profile)
+	}
+	return entities.DerivativeStatus{}, errors.New("视频服务未初始化")
+}
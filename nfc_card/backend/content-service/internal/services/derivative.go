@@ -0,0 +1,392 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"content-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// derivativeURLExpiry 衍生视频临时访问地址的有效期，需覆盖分发服务下载并上传到平台的时间
+	derivativeURLExpiry = 6 * time.Hour
+	// minDerivativeBitrateKbps 按文件大小限制降低码率时的下限
+	minDerivativeBitrateKbps = 500
+)
+
+// outputProfiles 各分发渠道的输出规格，名称与分发任务的渠道一致
+var outputProfiles = map[string]entities.OutputProfile{
+	"douyin": {
+		Name: "douyin", Label: "抖音", Width: 1080, Height: 1920, Fit: entities.FitBlur,
+		MaxDuration: 900, MaxBitrateKbps: 8000, AudioBitrateKbps: 128,
+		VideoCodec: "libx264", CodecProfile: "high", Level: "4.1", Preset: "medium", CRF: 21, FrameRate: 30,
+	},
+	"kuaishou": {
+		Name: "kuaishou", Label: "快手", Width: 1080, Height: 1920, Fit: entities.FitBlur,
+		MaxDuration: 600, MaxBitrateKbps: 8000, AudioBitrateKbps: 128,
+		VideoCodec: "libx264", CodecProfile: "high", Level: "4.1", Preset: "medium", CRF: 21, FrameRate: 30,
+	},
+	"xiaohongshu": {
+		Name: "xiaohongshu", Label: "小红书", Width: 1080, Height: 1440, Fit: entities.FitCrop,
+		MaxDuration: 900, MaxBitrateKbps: 6000, AudioBitrateKbps: 128,
+		VideoCodec: "libx264", CodecProfile: "high", Level: "4.1", Preset: "medium", CRF: 22, FrameRate: 30,
+	},
+	"wechat": {
+		Name: "wechat", Label: "微信", Width: 1280, Height: 720, Fit: entities.FitPad,
+		MaxDuration: 1800, MaxSizeMB: 200, MaxBitrateKbps: 4000, AudioBitrateKbps: 96,
+		VideoCodec: "libx264", CodecProfile: "main", Level: "3.1", Preset: "medium", CRF: 23, FrameRate: 30,
+	},
+}
+
+// OutputProfiles 返回所有输出规格，按名称排序
+func OutputProfiles() []entities.OutputProfile {
+	profiles := make([]entities.OutputProfile, 0, len(outputProfiles))
+	for _, profile := range outputProfiles {
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
+
+// derivativePrefix 视频衍生文件的存储前缀
+func derivativePrefix(tenantID, videoID string) string {
+	return fmt.Sprintf("%s/%s/derivatives/", tenantID, videoID)
+}
+
+// profileFilter 按画面适配方式生成ffmpeg滤镜，模糊填充需要使用filter_complex并输出到[v]
+func profileFilter(profile entities.OutputProfile) (string, bool) {
+	w, h := profile.Width, profile.Height
+
+	switch profile.Fit {
+	case entities.FitCrop:
+		return fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d,setsar=1", w, h, w, h), false
+	case entities.FitBlur:
+		return fmt.Sprintf(
+			"[0:v]split=2[bg][fg];"+
+				"[bg]scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d,boxblur=20:2[bg];"+
+				"[fg]scale=%d:%d:force_original_aspect_ratio=decrease[fg];"+
+				"[bg][fg]overlay=(W-w)/2:(H-h)/2,setsar=1[v]",
+			w, h, w, h, w, h,
+		), true
+	default:
+		return fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1", w, h, w, h), false
+	}
+}
+
+// profileBitrate 视频码率上限（kbps），有文件大小限制时按输出时长计算不超过限制的码率
+func profileBitrate(profile entities.OutputProfile, duration float64) int {
+	bitrate := profile.MaxBitrateKbps
+	if profile.MaxSizeMB <= 0 || duration <= 0 {
+		return bitrate
+	}
+
+	// 预留5%给容器开销和码率波动
+	budget := int(float64(profile.MaxSizeMB)*8*1024*0.95/duration) - profile.AudioBitrateKbps
+	if budget < minDerivativeBitrateKbps {
+		budget = minDerivativeBitrateKbps
+	}
+	if bitrate <= 0 || budget < bitrate {
+		bitrate = budget
+	}
+	return bitrate
+}
+
+// derivativeArgs 生成衍生视频的ffmpeg参数，duration为源视频时长
+// 使用CRF控制质量，同时以-maxrate限制峰值码率
+func derivativeArgs(profile entities.OutputProfile, inputPath, outputPath string, duration float64) []string {
+	args := []string{"-i", inputPath}
+
+	if profile.MaxDuration > 0 && duration > profile.MaxDuration {
+		args = append(args, "-t", strconv.FormatFloat(profile.MaxDuration, 'f', -1, 64))
+		duration = profile.MaxDuration
+	}
+
+	filter, complex := profileFilter(profile)
+	if complex {
+		args = append(args, "-filter_complex", filter, "-map", "[v]")
+	} else {
+		args = append(args, "-vf", filter, "-map", "0:v:0")
+	}
+	args = append(args, "-map", "0:a:0?")
+
+	args = append(args, "-c:v", profile.VideoCodec)
+	if profile.CodecProfile != "" {
+		args = append(args, "-profile:v", profile.CodecProfile)
+	}
+	if profile.Level != "" {
+		args = append(args, "-level", profile.Level)
+	}
+	if profile.Preset != "" {
+		args = append(args, "-preset", profile.Preset)
+	}
+	if profile.CRF > 0 {
+		args = append(args, "-crf", strconv.Itoa(profile.CRF))
+	}
+	if bitrate := profileBitrate(profile, duration); bitrate > 0 {
+		args = append(args,
+			"-maxrate", fmt.Sprintf("%dk", bitrate),
+			"-bufsize", fmt.Sprintf("%dk", bitrate*2),
+		)
+	}
+	if profile.FrameRate > 0 {
+		args = append(args, "-r", strconv.Itoa(profile.FrameRate))
+	}
+
+	args = append(args,
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-b:a", fmt.Sprintf("%dk", profile.AudioBitrateKbps),
+		"-ar", "44100",
+		"-movflags", "+faststart",
+		"-y", outputPath,
+	)
+	return args
+}
+
+// generateDerivative 从原始视频生成输出规格的衍生视频并保存
+func (s *TranscodeService) generateDerivative(ctx context.Context, video entities.Video, profile entities.OutputProfile) error {
+	inputPath, err := s.downloadVideo(video.FileKey)
+	if err != nil {
+		return fmt.Errorf("下载视频失败: %w", err)
+	}
+	defer os.Remove(inputPath)
+
+	duration, _, _, err := s.getVideoInfo(inputPath)
+	if err != nil {
+		return fmt.Errorf("获取视频信息失败: %w", err)
+	}
+
+	outputPath := filepath.Join(s.tempDir, fmt.Sprintf("%s_%s.mp4", video.ID.String(), profile.Name))
+	defer os.Remove(outputPath)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", derivativeArgs(profile, inputPath, outputPath, duration)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("生成%s衍生视频失败: %v, %s", profile.Name, err, stderr.String())
+	}
+
+	fileInfo, err := os.Stat(outputPath)
+	if err != nil {
+		return fmt.Errorf("获取衍生视频信息失败: %w", err)
+	}
+	if profile.MaxSizeMB > 0 && fileInfo.Size() > int64(profile.MaxSizeMB)<<20 {
+		return fmt.Errorf("%s衍生视频大小%dMB超过上限%dMB", profile.Name, fileInfo.Size()>>20, profile.MaxSizeMB)
+	}
+
+	if profile.MaxDuration > 0 && duration > profile.MaxDuration {
+		duration = profile.MaxDuration
+	}
+
+	fileKey := derivativePrefix(video.TenantID.String(), video.ID.String()) + profile.Name + ".mp4"
+	if err := s.uploadFile(outputPath, fileKey); err != nil {
+		return fmt.Errorf("上传衍生视频失败: %w", err)
+	}
+
+	derivative := entities.VideoDerivative{
+		ID:       uuid.New(),
+		VideoID:  video.ID,
+		TenantID: video.TenantID,
+		Profile:  profile.Name,
+		FileKey:  fileKey,
+		Width:    profile.Width,
+		Height:   profile.Height,
+		Duration: duration,
+		FileSize: fileInfo.Size(),
+	}
+	if duration > 0 {
+		derivative.Bitrate = int(float64(fileInfo.Size()*8) / duration)
+	}
+
+	return s.saveDerivative(derivative)
+}
+
+// saveDerivative 保存衍生视频，重新生成时覆盖
+func (s *TranscodeService) saveDerivative(derivative entities.VideoDerivative) error {
+	query := `
+		INSERT INTO video_derivatives (
+			id, video_id, merchant_id, profile, file_key, width, height, duration, file_size, bitrate
+		) VALUES (
+			:id, :video_id, :merchant_id, :profile, :file_key, :width, :height, :duration, :file_size, :bitrate
+		)
+		ON CONFLICT (video_id, profile) DO UPDATE SET
+			file_key = EXCLUDED.file_key,
+			width = EXCLUDED.width,
+			height = EXCLUDED.height,
+			duration = EXCLUDED.duration,
+			file_size = EXCLUDED.file_size,
+			bitrate = EXCLUDED.bitrate,
+			updated_at = NOW()
+	`
+	if _, err := s.db.NamedExec(query, derivative); err != nil {
+		return fmt.Errorf("保存衍生视频失败: %w", err)
+	}
+	return nil
+}
+
+// enqueueDerivatives 视频转码完成后为配置的输出规格预先生成衍生视频
+func (s *TranscodeService) enqueueDerivatives(job entities.TranscodeJob) {
+	if len(s.pregenerateProfiles) == 0 {
+		return
+	}
+
+	video, err := s.getVideo(job.VideoID.String(), job.TenantID.String())
+	if err != nil {
+		log.Printf("获取视频信息失败: %v", err)
+		return
+	}
+
+	for _, name := range s.pregenerateProfiles {
+		if _, err := s.enqueue(s.db, video, name); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("创建%s衍生视频任务失败: %v", name, err)
+		}
+	}
+	s.notify()
+}
+
+// FindDerivatives 获取视频已生成的衍生视频
+func (s *TranscodeService) FindDerivatives(videoID, tenantID string) ([]entities.VideoDerivative, error) {
+	derivatives := []entities.VideoDerivative{}
+	query := `
+		SELECT * FROM video_derivatives
+		WHERE video_id = $1 AND merchant_id = $2
+		ORDER BY profile
+	`
+	if err := s.db.Select(&derivatives, query, videoID, tenantID); err != nil {
+		return nil, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "获取衍生视频失败",
+			Err:     err,
+		}
+	}
+	return derivatives, nil
+}
+
+// DerivativeStatus 查询视频在某个输出规格下的衍生视频状态
+func (s *TranscodeService) DerivativeStatus(videoID, tenantID, profileName string) (entities.DerivativeStatus, error) {
+	if _, ok := outputProfiles[profileName]; !ok {
+		return entities.DerivativeStatus{}, &ServiceError{
+			Type:    ErrTypeValidation,
+			Code:    ErrCodeInvalidInput,
+			Message: fmt.Sprintf("不支持的输出规格: %s", profileName),
+		}
+	}
+
+	status := entities.DerivativeStatus{Profile: profileName, Status: entities.DerivativeNone}
+
+	var derivative entities.VideoDerivative
+	query := "SELECT * FROM video_derivatives WHERE video_id = $1 AND merchant_id = $2 AND profile = $3"
+	err := s.db.Get(&derivative, query, videoID, tenantID, profileName)
+	switch {
+	case err == nil:
+		url, err := s.storageService.GetFileURLWithExpiry(derivative.FileKey, derivativeURLExpiry)
+		if err != nil {
+			return entities.DerivativeStatus{}, &ServiceError{
+				Type:    ErrTypeStorage,
+				Code:    ErrCodeFileDownload,
+				Message: "获取衍生视频地址失败",
+				Err:     err,
+			}
+		}
+		status.Status = entities.DerivativeReady
+		status.Derivative = &derivative
+		status.URL = url
+		return status, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return entities.DerivativeStatus{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "获取衍生视频失败",
+			Err:     err,
+		}
+	}
+
+	// 尚未生成时返回最近一次生成任务的状态
+	var job entities.TranscodeJob
+	query = `
+		SELECT * FROM transcode_jobs
+		WHERE video_id = $1 AND merchant_id = $2 AND profile = $3
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	err = s.db.Get(&job, query, videoID, tenantID, profileName)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return status, nil
+	case err != nil:
+		return entities.DerivativeStatus{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "获取衍生视频任务失败",
+			Err:     err,
+		}
+	}
+
+	// 任务已完成但衍生视频不存在（如已被删除）时视为未生成
+	if job.Status != entities.TranscodeJobCompleted {
+		status.Status = job.Status
+		status.JobID = &job.ID
+		status.Error = job.LastError
+	}
+	return status, nil
+}
+
+// RequestDerivative 请求生成衍生视频，已生成或正在生成时直接返回当前状态
+func (s *TranscodeService) RequestDerivative(videoID, tenantID, profileName string) (entities.DerivativeStatus, error) {
+	status, err := s.DerivativeStatus(videoID, tenantID, profileName)
+	if err != nil {
+		return entities.DerivativeStatus{}, err
+	}
+	switch status.Status {
+	case entities.DerivativeReady, entities.TranscodeJobQueued, entities.TranscodeJobRunning:
+		return status, nil
+	}
+
+	video, err := s.getVideo(videoID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.DerivativeStatus{}, &ServiceError{
+				Type:    ErrTypeNotFound,
+				Code:    ErrCodeResourceNotFound,
+				Message: "视频不存在",
+				Err:     err,
+			}
+		}
+		return entities.DerivativeStatus{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "获取视频信息失败",
+			Err:     err,
+		}
+	}
+
+	// 并发请求时另一个请求已创建任务，返回该任务的状态
+	if _, err := s.enqueue(s.db, video, profileName); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return entities.DerivativeStatus{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "创建衍生视频任务失败",
+			Err:     err,
+		}
+	}
+	s.notify()
+
+	return s.DerivativeStatus(videoID, tenantID, profileName)
+}
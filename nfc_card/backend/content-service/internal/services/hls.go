@@ -0,0 +1,391 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"content-service/internal/domain/entities"
+)
+
+// HLS切片格式
+const (
+	HLSSegmentFMP4   = "fmp4"
+	HLSSegmentMPEGTS = "mpegts"
+)
+
+const (
+	// defaultHLSSegmentSeconds 默认切片时长（秒）
+	defaultHLSSegmentSeconds = 6
+	// keyframeIntervalSeconds 转码时强制关键帧的间隔（秒），各分辨率的切片边界因此对齐，播放器切换码率时不会卡顿
+	keyframeIntervalSeconds = 2
+
+	// hlsMasterPlaylist 主播放列表文件名
+	hlsMasterPlaylist = "master.m3u8"
+	// hlsVariantPlaylist 各分辨率播放列表文件名
+	hlsVariantPlaylist = "playlist.m3u8"
+	// hlsInitSegment fMP4初始化片段文件名
+	hlsInitSegment = "init.mp4"
+)
+
+// hlsPackage 一个分辨率的HLS打包结果
+type hlsPackage struct {
+	dir              string
+	files            []string // 相对dir的文件名，包含播放列表
+	segmentCount     int
+	bandwidth        int
+	averageBandwidth int
+}
+
+// hlsPrefix 视频HLS文件在对象存储中的前缀，与视频文件的Key同级
+func hlsPrefix(tenantID, videoID string) string {
+	return fmt.Sprintf("%s/%s/hls/", tenantID, videoID)
+}
+
+// hlsSegmentSeconds 配置的切片时长
+func (s *TranscodeService) hlsSegmentSeconds() int {
+	if s.config.HLS.SegmentSeconds > 0 {
+		return s.config.HLS.SegmentSeconds
+	}
+	return defaultHLSSegmentSeconds
+}
+
+// hlsSegmentType 配置的切片格式，未配置或无效时使用fMP4
+func (s *TranscodeService) hlsSegmentType() string {
+	if s.config.HLS.SegmentType == HLSSegmentMPEGTS {
+		return HLSSegmentMPEGTS
+	}
+	return HLSSegmentFMP4
+}
+
+// packageHLS 将已转码的MP4无损切片为HLS，输出到outputDir
+// 转码时已按固定间隔插入关键帧，这里直接复制音视频流，不再重新编码
+func (s *TranscodeService) packageHLS(ctx context.Context, inputPath, outputDir string) (*hlsPackage, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建HLS目录失败: %w", err)
+	}
+
+	segmentType := s.hlsSegmentType()
+	segmentPattern := "seg_%04d.m4s"
+	if segmentType == HLSSegmentMPEGTS {
+		segmentPattern = "seg_%04d.ts"
+	}
+
+	args := []string{
+		"-i", inputPath,
+		"-c", "copy",
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(s.hlsSegmentSeconds()),
+		"-hls_playlist_type", "vod",
+		"-hls_flags", "independent_segments",
+		"-hls_segment_type", segmentType,
+		"-hls_segment_filename", filepath.Join(outputDir, segmentPattern),
+	}
+	if segmentType == HLSSegmentFMP4 {
+		args = append(args, "-hls_fmp4_init_filename", hlsInitSegment)
+	}
+	args = append(args, "-y", filepath.Join(outputDir, hlsVariantPlaylist))
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("HLS切片失败: %v, %s", err, stderr.String())
+	}
+
+	return measurePlaylist(outputDir)
+}
+
+// packageRendition 将一个分辨率打包为HLS并上传，成功后填充rendition的播放列表和码率信息
+// 重新转码时先删除该分辨率已有的切片，避免切片数量变化后残留旧文件
+func (s *TranscodeService) packageRendition(ctx context.Context, video entities.Video, inputPath string, rendition *entities.VideoRendition) error {
+	outputDir := filepath.Join(s.tempDir, fmt.Sprintf("%s_hls_%s", video.ID.String(), rendition.Name))
+	defer os.RemoveAll(outputDir)
+
+	pkg, err := s.packageHLS(ctx, inputPath, outputDir)
+	if err != nil {
+		return err
+	}
+
+	prefix := hlsPrefix(video.TenantID.String(), video.ID.String()) + rendition.Name + "/"
+	if err := s.storageService.DeletePrefix(prefix); err != nil {
+		return err
+	}
+
+	// 最后上传播放列表，保证播放列表可见时切片均已上传
+	for i := len(pkg.files) - 1; i >= 0; i-- {
+		name := pkg.files[i]
+		if err := s.uploadFile(filepath.Join(pkg.dir, name), prefix+name); err != nil {
+			return fmt.Errorf("上传%s失败: %w", name, err)
+		}
+	}
+
+	rendition.PlaylistKey = prefix + hlsVariantPlaylist
+	rendition.SegmentCount = pkg.segmentCount
+	rendition.Bandwidth = pkg.bandwidth
+	rendition.AverageBandwidth = pkg.averageBandwidth
+	return nil
+}
+
+// publishMasterPlaylist 生成主播放列表并上传，更新视频的主播放列表Key
+// 没有分辨率打包成功时清空主播放列表Key，播放端回退到MP4
+func (s *TranscodeService) publishMasterPlaylist(video entities.Video, renditions []entities.VideoRendition) error {
+	var packaged []entities.VideoRendition
+	for _, rendition := range renditions {
+		if rendition.PlaylistKey != "" {
+			packaged = append(packaged, rendition)
+		}
+	}
+	if len(packaged) == 0 {
+		return s.updateHLSMasterKey(video.ID.String(), "")
+	}
+
+	masterPath := filepath.Join(s.tempDir, fmt.Sprintf("%s_%s", video.ID.String(), hlsMasterPlaylist))
+	if err := os.WriteFile(masterPath, buildMasterPlaylist(packaged, s.hlsSegmentType()), 0644); err != nil {
+		return fmt.Errorf("写入主播放列表失败: %w", err)
+	}
+	defer os.Remove(masterPath)
+
+	masterKey := hlsPrefix(video.TenantID.String(), video.ID.String()) + hlsMasterPlaylist
+	if err := s.uploadFile(masterPath, masterKey); err != nil {
+		return fmt.Errorf("上传主播放列表失败: %w", err)
+	}
+
+	return s.updateHLSMasterKey(video.ID.String(), masterKey)
+}
+
+// saveRendition 保存分辨率转码输出，重新转码时覆盖同名分辨率的记录
+func (s *TranscodeService) saveRendition(rendition entities.VideoRendition) error {
+	query := `
+		INSERT INTO video_renditions (
+			id, video_id, merchant_id, name, width, height, file_key, file_size,
+			playlist_key, segment_count, bandwidth, average_bandwidth, codecs
+		) VALUES (
+			:id, :video_id, :merchant_id, :name, :width, :height, :file_key, :file_size,
+			:playlist_key, :segment_count, :bandwidth, :average_bandwidth, :codecs
+		)
+		ON CONFLICT (video_id, name) DO UPDATE SET
+			width = EXCLUDED.width,
+			height = EXCLUDED.height,
+			file_key = EXCLUDED.file_key,
+			file_size = EXCLUDED.file_size,
+			playlist_key = EXCLUDED.playlist_key,
+			segment_count = EXCLUDED.segment_count,
+			bandwidth = EXCLUDED.bandwidth,
+			average_bandwidth = EXCLUDED.average_bandwidth,
+			codecs = EXCLUDED.codecs,
+			created_at = NOW()
+	`
+
+	if _, err := s.db.NamedExec(query, rendition); err != nil {
+		return fmt.Errorf("保存转码输出失败: %w", err)
+	}
+
+	return nil
+}
+
+// updateHLSMasterKey 更新视频的HLS主播放列表Key
+func (s *TranscodeService) updateHLSMasterKey(videoID, masterKey string) error {
+	query := `
+		UPDATE videos
+		SET hls_master_key = $1, updated_at = $2
+		WHERE id = $3
+	`
+
+	if _, err := s.db.Exec(query, masterKey, time.Now(), videoID); err != nil {
+		return fmt.Errorf("更新HLS主播放列表失败: %w", err)
+	}
+
+	return nil
+}
+
+// measurePlaylist 读取播放列表，根据每个切片的时长和文件大小计算峰值码率和平均码率
+func measurePlaylist(dir string) (*hlsPackage, error) {
+	file, err := os.Open(filepath.Join(dir, hlsVariantPlaylist))
+	if err != nil {
+		return nil, fmt.Errorf("读取HLS播放列表失败: %w", err)
+	}
+	defer file.Close()
+
+	pkg := &hlsPackage{dir: dir, files: []string{hlsVariantPlaylist}}
+	var totalBits, totalSeconds, segmentSeconds float64
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)[0]
+			segmentSeconds, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("解析切片时长失败: %s", line)
+			}
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			name := attributeValue(line, "URI")
+			info, err := os.Stat(filepath.Join(dir, name))
+			if err != nil {
+				return nil, fmt.Errorf("读取初始化片段失败: %w", err)
+			}
+			pkg.files = append(pkg.files, name)
+			totalBits += float64(info.Size() * 8)
+		case strings.HasPrefix(line, "#"):
+		default:
+			info, err := os.Stat(filepath.Join(dir, line))
+			if err != nil {
+				return nil, fmt.Errorf("读取切片失败: %w", err)
+			}
+			pkg.files = append(pkg.files, line)
+			pkg.segmentCount++
+
+			bits := float64(info.Size() * 8)
+			totalBits += bits
+			totalSeconds += segmentSeconds
+			if segmentSeconds > 0 {
+				if peak := int(bits / segmentSeconds); peak > pkg.bandwidth {
+					pkg.bandwidth = peak
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取HLS播放列表失败: %w", err)
+	}
+
+	if pkg.segmentCount == 0 || totalSeconds <= 0 {
+		return nil, fmt.Errorf("HLS播放列表没有切片")
+	}
+	pkg.averageBandwidth = int(totalBits / totalSeconds)
+	if pkg.bandwidth < pkg.averageBandwidth {
+		pkg.bandwidth = pkg.averageBandwidth
+	}
+
+	return pkg, nil
+}
+
+// attributeValue 读取播放列表标签中带引号的属性值，如#EXT-X-MAP:URI="init.mp4"
+func attributeValue(line, name string) string {
+	marker := name + `="`
+	start := strings.Index(line, marker)
+	if start < 0 {
+		return ""
+	}
+	value := line[start+len(marker):]
+	if end := strings.Index(value, `"`); end >= 0 {
+		return value[:end]
+	}
+	return value
+}
+
+// probeStreams ffprobe -of json输出的流信息，只有视频流带level
+type probeStreams struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		CodecName string `json:"codec_name"`
+		Profile   string `json:"profile"`
+		Level     *int   `json:"level"`
+	} `json:"streams"`
+}
+
+// probeCodecs 读取转码输出的编码参数，生成主播放列表CODECS属性（RFC 6381）
+// 无法识别时返回空字符串，播放器会自行探测
+func (s *TranscodeService) probeCodecs(inputPath string) string {
+	cmd := exec.Command(
+		"ffprobe",
+		"-v", "error",
+		"-show_entries", "stream=codec_type,codec_name,profile,level",
+		"-of", "json",
+		inputPath,
+	)
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+
+	// 按字段名读取，不依赖ffprobe输出字段的顺序
+	var probe probeStreams
+	if err := json.Unmarshal(stdout.Bytes(), &probe); err != nil {
+		return ""
+	}
+
+	var video, audio string
+	for _, stream := range probe.Streams {
+		switch {
+		case stream.CodecType == "video" && stream.CodecName == "h264" && video == "":
+			if stream.Level == nil || *stream.Level <= 0 {
+				continue
+			}
+			if prefix, ok := avcProfiles[stream.Profile]; ok {
+				video = fmt.Sprintf("avc1.%s%02x", prefix, *stream.Level)
+			}
+		case stream.CodecType == "audio" && stream.CodecName == "aac" && audio == "":
+			audio = "mp4a.40.2"
+		}
+	}
+
+	if video == "" {
+		return ""
+	}
+	if audio == "" {
+		return video
+	}
+	return video + "," + audio
+}
+
+// avcProfiles H.264 profile对应的profile_idc和约束标志（十六进制）
+var avcProfiles = map[string]string{
+	"Constrained Baseline": "42e0",
+	"Baseline":             "4200",
+	"Main":                 "4d40",
+	"High":                 "6400",
+}
+
+// buildMasterPlaylist 生成主播放列表，各分辨率按码率从低到高排列
+// 播放器从第一个码率开始播放，弱网下首屏更快，网络较好时会很快切换到更高码率
+func buildMasterPlaylist(renditions []entities.VideoRendition, segmentType string) []byte {
+	sorted := make([]entities.VideoRendition, 0, len(renditions))
+	for _, rendition := range renditions {
+		if rendition.PlaylistKey != "" {
+			sorted = append(sorted, rendition)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Bandwidth < sorted[j].Bandwidth
+	})
+
+	// fMP4切片需要EXT-X-MAP，要求版本7
+	version := 3
+	if segmentType == HLSSegmentFMP4 {
+		version = 7
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&buf, "#EXT-X-VERSION:%d\n", version)
+	buf.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	for _, rendition := range sorted {
+		fmt.Fprintf(&buf, "#EXT-X-STREAM-INF:BANDWIDTH=%d,AVERAGE-BANDWIDTH=%d,RESOLUTION=%dx%d",
+			rendition.Bandwidth, rendition.AverageBandwidth, rendition.Width, rendition.Height)
+		if rendition.Codecs != "" {
+			fmt.Fprintf(&buf, `,CODECS="%s"`, rendition.Codecs)
+		}
+		buf.WriteString("\n")
+		buf.WriteString(path.Join(rendition.Name, hlsVariantPlaylist))
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
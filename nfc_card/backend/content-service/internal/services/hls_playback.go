@@ -0,0 +1,187 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"content-service/internal/domain/entities"
+)
+
+const (
+	// defaultHLSURLExpiry 播放地址默认有效期
+	defaultHLSURLExpiry = 24 * time.Hour
+	// maxPresignExpiry 对象存储预签名URL的最长有效期
+	maxPresignExpiry = 7 * 24 * time.Hour
+	// maxPlaylistSize 播放列表的最大读取大小
+	maxPlaylistSize = 4 << 20
+)
+
+// 存储桶是私有的，切片不能直接按相对路径访问，播放列表通过签名接口下发：
+// 主播放列表中的分辨率播放列表地址附带同一签名，分辨率播放列表中的切片改写为对象存储预签名URL
+
+// hlsPlaybackURL 生成视频主播放列表的签名地址，视频未打包HLS或未配置播放地址时返回空字符串
+func (s *VideoService) hlsPlaybackURL(video entities.Video) string {
+	if video.HLSMasterKey == "" || s.cfg.HLS.PlaybackBaseURL == "" || s.cfg.HLS.SigningKey == "" {
+		return ""
+	}
+
+	expiry := defaultHLSURLExpiry
+	if s.cfg.HLS.URLExpiryHours > 0 {
+		expiry = time.Duration(s.cfg.HLS.URLExpiryHours) * time.Hour
+	}
+	expires := time.Now().Add(expiry).Unix()
+
+	tenantID, videoID := video.TenantID.String(), video.ID.String()
+	return fmt.Sprintf("%s/api/v1/hls/%s/%s/%s?%s",
+		strings.TrimRight(s.cfg.HLS.PlaybackBaseURL, "/"), tenantID, videoID, hlsMasterPlaylist,
+		s.playlistQuery(tenantID, videoID, expires))
+}
+
+// playlistQuery 生成播放列表地址的签名参数
+func (s *VideoService) playlistQuery(tenantID, videoID string, expires int64) string {
+	values := url.Values{}
+	values.Set("expires", strconv.FormatInt(expires, 10))
+	values.Set("sig", s.signPlaylist(tenantID, videoID, expires))
+	return values.Encode()
+}
+
+// signPlaylist 计算播放列表签名，同一视频的所有播放列表共用一个签名
+func (s *VideoService) signPlaylist(tenantID, videoID string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.HLS.SigningKey))
+	fmt.Fprintf(mac, "%s/%s:%d", tenantID, videoID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RenderPlaylist 校验签名后读取视频的播放列表并改写其中的地址
+// name为master.m3u8或<分辨率>/playlist.m3u8
+func (s *VideoService) RenderPlaylist(tenantID, videoID, name, expiresParam, sig string) ([]byte, error) {
+	if s.cfg.HLS.SigningKey == "" {
+		return nil, &ServiceError{
+			Type:    ErrTypeNotFound,
+			Code:    ErrCodeResourceNotFound,
+			Message: "未启用HLS播放",
+		}
+	}
+
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return nil, &ServiceError{
+			Type:    ErrTypeUnauthorized,
+			Code:    ErrCodeUnauthorized,
+			Message: "播放地址已过期",
+		}
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signPlaylist(tenantID, videoID, expires))) {
+		return nil, &ServiceError{
+			Type:    ErrTypeUnauthorized,
+			Code:    ErrCodeUnauthorized,
+			Message: "播放地址签名无效",
+		}
+	}
+
+	name = strings.TrimPrefix(name, "/")
+	if path.Clean(name) != name || strings.Contains(name, "..") || path.Ext(name) != ".m3u8" {
+		return nil, &ServiceError{
+			Type:    ErrTypeValidation,
+			Code:    ErrCodeInvalidInput,
+			Message: "无效的播放列表名称",
+		}
+	}
+
+	video, err := s.FindOne(videoID, tenantID)
+	if err != nil || video.HLSMasterKey == "" {
+		return nil, &ServiceError{
+			Type:    ErrTypeNotFound,
+			Code:    ErrCodeResourceNotFound,
+			Message: "播放列表不存在",
+			Err:     err,
+		}
+	}
+
+	key := hlsPrefix(tenantID, videoID) + name
+	reader, err := s.storageService.GetObject(key)
+	if err != nil {
+		return nil, &ServiceError{
+			Type:    ErrTypeStorage,
+			Code:    ErrCodeFileDownload,
+			Message: "读取播放列表失败",
+			Err:     err,
+		}
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxPlaylistSize))
+	if err != nil {
+		// MinIO对象不存在时在读取时才返回错误
+		return nil, &ServiceError{
+			Type:    ErrTypeNotFound,
+			Code:    ErrCodeResourceNotFound,
+			Message: "播放列表不存在",
+			Err:     err,
+		}
+	}
+
+	if name == hlsMasterPlaylist {
+		query := s.playlistQuery(tenantID, videoID, expires)
+		return rewritePlaylist(data, func(uri string) (string, error) {
+			return uri + "?" + query, nil
+		})
+	}
+
+	// 切片的预签名URL与播放列表同时过期
+	expiry := time.Until(time.Unix(expires, 0))
+	if expiry < time.Second {
+		expiry = time.Second
+	}
+	if expiry > maxPresignExpiry {
+		expiry = maxPresignExpiry
+	}
+	dir := path.Dir(key)
+	return rewritePlaylist(data, func(uri string) (string, error) {
+		return s.storageService.GetFileURLWithExpiry(path.Join(dir, uri), expiry)
+	})
+}
+
+// rewritePlaylist 改写播放列表中的相对地址，包括URI行和EXT-X-MAP的URI属性
+func rewritePlaylist(data []byte, rewrite func(uri string) (string, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			if uri := attributeValue(line, "URI"); uri != "" {
+				rewritten, err := rewrite(uri)
+				if err != nil {
+					return nil, err
+				}
+				line = strings.Replace(line, `URI="`+uri+`"`, `URI="`+rewritten+`"`, 1)
+			}
+		case !strings.HasPrefix(line, "#"):
+			rewritten, err := rewrite(line)
+			if err != nil {
+				return nil, err
+			}
+			line = rewritten
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取播放列表失败: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
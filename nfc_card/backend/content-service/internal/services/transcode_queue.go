@@ -0,0 +1,643 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"content-service/internal/config"
+	"content-service/internal/domain/entities"
+	"content-service/internal/messaging"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	// defaultTranscodeConcurrency 默认每个实例同时执行的转码任务数
+	defaultTranscodeConcurrency = 2
+	// defaultTranscodeMaxAttempts 默认每个任务的最大执行次数
+	defaultTranscodeMaxAttempts = 3
+	// defaultTranscodeRetryBackoff 默认首次重试的等待时间
+	defaultTranscodeRetryBackoff = time.Minute
+	// maxTranscodeRetryBackoff 重试等待时间上限
+	maxTranscodeRetryBackoff = time.Hour
+	// defaultTranscodeLease 默认任务租约时长
+	defaultTranscodeLease = 2 * time.Minute
+	// defaultTranscodeHeartbeat 默认心跳间隔
+	defaultTranscodeHeartbeat = 30 * time.Second
+	// defaultTranscodePoll 默认轮询间隔
+	defaultTranscodePoll = 5 * time.Second
+
+	// pqUniqueViolation 唯一约束冲突
+	pqUniqueViolation = "23505"
+)
+
+// 转码任务被中断的原因
+const (
+	jobInterruptCancelled = "cancelled"
+	jobInterruptLeaseLost = "lease_lost"
+)
+
+// 转码任务队列：任务保存在transcode_jobs表，工作进程以FOR UPDATE SKIP LOCKED领取，
+// 执行期间定期心跳续租；实例崩溃后租约过期，任务由其他实例重新领取
+
+// configureQueue 根据配置设置队列参数，未配置的项使用默认值
+func (s *TranscodeService) configureQueue(cfg config.TranscodeConfig) {
+	s.concurrency = cfg.Concurrency
+	if s.concurrency <= 0 {
+		s.concurrency = defaultTranscodeConcurrency
+	}
+
+	s.maxAttempts = cfg.MaxAttempts
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = defaultTranscodeMaxAttempts
+	}
+
+	s.retryBackoff = time.Duration(cfg.RetryBackoffSeconds) * time.Second
+	if s.retryBackoff <= 0 {
+		s.retryBackoff = defaultTranscodeRetryBackoff
+	}
+
+	s.leaseTimeout = time.Duration(cfg.LeaseSeconds) * time.Second
+	if s.leaseTimeout <= 0 {
+		s.leaseTimeout = defaultTranscodeLease
+	}
+
+	s.heartbeatInterval = time.Duration(cfg.HeartbeatSeconds) * time.Second
+	if s.heartbeatInterval <= 0 {
+		s.heartbeatInterval = defaultTranscodeHeartbeat
+	}
+	if s.heartbeatInterval >= s.leaseTimeout {
+		s.heartbeatInterval = s.leaseTimeout / 4
+	}
+
+	s.pollInterval = time.Duration(cfg.PollSeconds) * time.Second
+	if s.pollInterval <= 0 {
+		s.pollInterval = defaultTranscodePoll
+	}
+
+	for _, name := range cfg.PregenerateProfiles {
+		if _, ok := outputProfiles[name]; !ok {
+			log.Printf("忽略不存在的预生成输出规格: %s", name)
+			continue
+		}
+		s.pregenerateProfiles = append(s.pregenerateProfiles, name)
+	}
+
+	hostname, _ := os.Hostname()
+	s.workerID = fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.New().String()[:8])
+	s.wake = make(chan struct{}, s.concurrency)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+}
+
+// Start 启动转码工作进程和过期租约回收
+func (s *TranscodeService) Start() {
+	for i := 0; i < s.concurrency; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+
+	s.wg.Add(1)
+	go s.reclaimLoop()
+
+	log.Printf("转码队列已启动，工作进程%s，并发数%d", s.workerID, s.concurrency)
+}
+
+// Stop 停止领取新任务，中断正在执行的任务并放回队列
+func (s *TranscodeService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+		s.cancel()
+	})
+	s.wg.Wait()
+}
+
+// TranscodeVideo 将视频加入转码队列
+func (s *TranscodeService) TranscodeVideo(videoID, tenantID string) error {
+	// 查询视频信息
+	video, err := s.getVideo(videoID, tenantID)
+	if err != nil {
+		return fmt.Errorf("获取视频信息失败: %w", err)
+	}
+
+	if _, err := s.enqueue(s.db, video, ""); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &ServiceError{
+				Type:    ErrTypeConflict,
+				Code:    ErrCodeResourceExists,
+				Message: "该视频已有未完成的转码任务",
+			}
+		}
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "创建转码任务失败",
+			Err:     err,
+		}
+	}
+
+	if err := s.updateTranscodeStatus(video.ID.String(), entities.TranscodeStatusPending); err != nil {
+		log.Printf("更新视频转码状态失败: %v", err)
+	}
+
+	s.notify()
+	return nil
+}
+
+// enqueue 创建转码任务，profile不为空时生成该输出规格的衍生视频
+// 视频已有同类未结束的任务时返回sql.ErrNoRows；q可以是事务，与视频记录一起提交
+func (s *TranscodeService) enqueue(q sqlx.Queryer, video entities.Video, profile string) (entities.TranscodeJob, error) {
+	var job entities.TranscodeJob
+	query := `
+		INSERT INTO transcode_jobs (id, merchant_id, video_id, profile, max_attempts)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (video_id, profile) WHERE status IN ('queued', 'running') DO NOTHING
+		RETURNING *
+	`
+	err := sqlx.Get(q, &job, query, uuid.New(), video.TenantID, video.ID, profile, s.maxAttempts)
+	return job, err
+}
+
+// notify 唤醒空闲的工作进程立即领取任务
+func (s *TranscodeService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// worker 循环领取并执行任务，队列为空时等待唤醒或轮询
+func (s *TranscodeService) worker() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for s.runNext() {
+		}
+
+		select {
+		case <-s.done:
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext 领取并执行一个任务，没有可执行的任务或正在停止时返回false
+func (s *TranscodeService) runNext() bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+
+	job, err := s.claimJob()
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("领取转码任务失败: %v", err)
+		}
+		return false
+	}
+
+	s.runJob(job)
+	return true
+}
+
+// claimJob 领取最早到期的任务并获得租约
+func (s *TranscodeService) claimJob() (entities.TranscodeJob, error) {
+	var job entities.TranscodeJob
+	query := `
+		UPDATE transcode_jobs
+		SET status = 'running', worker_id = $1, attempts = attempts + 1,
+			lease_expires_at = NOW() + make_interval(secs => $2), heartbeat_at = NOW(),
+			started_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM transcode_jobs
+			WHERE status = 'queued' AND run_at <= NOW()
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`
+	err := s.db.Get(&job, query, s.workerID, s.leaseTimeout.Seconds())
+	return job, err
+}
+
+// runJob 执行任务，执行期间定期心跳续租并检查取消请求
+func (s *TranscodeService) runJob(job entities.TranscodeJob) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	// interrupt在心跳协程退出后读取
+	var interrupt string
+	stop := make(chan struct{})
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+
+		ticker := time.NewTicker(s.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				cancelRequested, err := s.heartbeat(job)
+				switch {
+				case errors.Is(err, sql.ErrNoRows):
+					interrupt = jobInterruptLeaseLost
+					cancel()
+					return
+				case err != nil:
+					log.Printf("转码任务%s心跳失败: %v", job.ID, err)
+				case cancelRequested:
+					interrupt = jobInterruptCancelled
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err := s.executeJob(ctx, job)
+	close(stop)
+	<-heartbeatDone
+
+	switch {
+	case err == nil:
+		if err := s.completeJob(job); err != nil {
+			log.Printf("保存转码任务%s状态失败: %v", job.ID, err)
+		}
+		if job.Profile == "" {
+			s.enqueueDerivatives(job)
+		}
+	case interrupt == jobInterruptLeaseLost:
+		// 租约已被回收，任务由其他实例处理
+		log.Printf("转码任务%s租约已失效，放弃执行", job.ID)
+	case interrupt == jobInterruptCancelled:
+		s.finishCancelledJob(job)
+	case s.ctx.Err() != nil:
+		s.releaseJob(job)
+	default:
+		log.Printf("转码任务%s第%d次执行失败: %v", job.ID, job.Attempts, err)
+		s.failJob(job, err)
+	}
+}
+
+// executeJob 执行一次转码
+func (s *TranscodeService) executeJob(ctx context.Context, job entities.TranscodeJob) error {
+	video, err := s.getVideo(job.VideoID.String(), job.TenantID.String())
+	if err != nil {
+		return fmt.Errorf("获取视频信息失败: %w", err)
+	}
+
+	// 衍生视频任务不影响视频本身的转码状态
+	if job.Profile != "" {
+		profile, ok := outputProfiles[job.Profile]
+		if !ok {
+			return fmt.Errorf("输出规格%s不存在", job.Profile)
+		}
+		return s.generateDerivative(ctx, video, profile)
+	}
+
+	// 更新视频转码状态为处理中
+	if err := s.updateTranscodeStatus(video.ID.String(), entities.TranscodeStatusProcessing); err != nil {
+		return fmt.Errorf("更新转码状态失败: %w", err)
+	}
+
+	// 发送视频处理事件
+	if s.kafkaProducer != nil {
+		payload := messaging.VideoProcessingPayload{
+			ID:           video.ID.String(),
+			TenantID:     video.TenantID.String(),
+			Status:       string(entities.TranscodeStatusProcessing),
+			ProcessingAt: time.Now().Format(time.RFC3339),
+		}
+		if err := s.kafkaProducer.SendVideoProcessing(payload); err != nil {
+			log.Printf("发送视频处理事件失败: %v", err)
+		}
+	}
+
+	return s.processTranscode(ctx, video)
+}
+
+// heartbeat 续租并返回是否已请求取消，租约已不属于当前工作进程时返回sql.ErrNoRows
+func (s *TranscodeService) heartbeat(job entities.TranscodeJob) (bool, error) {
+	var cancelRequested bool
+	query := `
+		UPDATE transcode_jobs
+		SET heartbeat_at = NOW(), lease_expires_at = NOW() + make_interval(secs => $1), updated_at = NOW()
+		WHERE id = $2 AND worker_id = $3 AND status = 'running'
+		RETURNING cancel_requested
+	`
+	err := s.db.Get(&cancelRequested, query, s.leaseTimeout.Seconds(), job.ID, s.workerID)
+	return cancelRequested, err
+}
+
+// completeJob 标记任务完成
+func (s *TranscodeService) completeJob(job entities.TranscodeJob) error {
+	query := `
+		UPDATE transcode_jobs
+		SET status = 'completed', last_error = '', lease_expires_at = NULL,
+			finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND worker_id = $2 AND status = 'running'
+	`
+	_, err := s.db.Exec(query, job.ID, s.workerID)
+	return err
+}
+
+// failJob 记录失败原因，未达到最大执行次数时按指数退避重新排队，否则标记任务和视频为失败
+func (s *TranscodeService) failJob(job entities.TranscodeJob, cause error) {
+	backoff := s.retryBackoff << uint(job.Attempts-1)
+	if backoff <= 0 || backoff > maxTranscodeRetryBackoff {
+		backoff = maxTranscodeRetryBackoff
+	}
+
+	var status string
+	query := `
+		UPDATE transcode_jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'failed' ELSE 'queued' END,
+			run_at = NOW() + make_interval(secs => $1),
+			finished_at = CASE WHEN attempts >= max_attempts THEN NOW() END,
+			last_error = $2, worker_id = '', lease_expires_at = NULL, updated_at = NOW()
+		WHERE id = $3 AND worker_id = $4 AND status = 'running'
+		RETURNING status
+	`
+	if err := s.db.Get(&status, query, backoff.Seconds(), cause.Error(), job.ID, s.workerID); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("保存转码任务%s状态失败: %v", job.ID, err)
+		}
+		return
+	}
+
+	videoStatus := entities.TranscodeStatusPending
+	if status == entities.TranscodeJobFailed {
+		videoStatus = entities.TranscodeStatusFailed
+	}
+	s.updateJobVideoStatus(job, videoStatus)
+}
+
+// finishCancelledJob 标记已取消的任务结束
+func (s *TranscodeService) finishCancelledJob(job entities.TranscodeJob) {
+	query := `
+		UPDATE transcode_jobs
+		SET status = 'cancelled', last_error = '已取消', worker_id = '', lease_expires_at = NULL,
+			finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND worker_id = $2 AND status = 'running'
+	`
+	if _, err := s.db.Exec(query, job.ID, s.workerID); err != nil {
+		log.Printf("保存转码任务%s状态失败: %v", job.ID, err)
+		return
+	}
+
+	s.updateJobVideoStatus(job, entities.TranscodeStatusFailed)
+}
+
+// releaseJob 服务停止时将执行中的任务放回队列，本次执行不计入次数
+func (s *TranscodeService) releaseJob(job entities.TranscodeJob) {
+	query := `
+		UPDATE transcode_jobs
+		SET status = 'queued', attempts = attempts - 1, run_at = NOW(),
+			worker_id = '', lease_expires_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND worker_id = $2 AND status = 'running'
+	`
+	if _, err := s.db.Exec(query, job.ID, s.workerID); err != nil {
+		log.Printf("释放转码任务%s失败: %v", job.ID, err)
+		return
+	}
+
+	s.updateJobVideoStatus(job, entities.TranscodeStatusPending)
+}
+
+// updateJobVideoStatus 根据任务结果更新视频转码状态，衍生视频任务不更新
+func (s *TranscodeService) updateJobVideoStatus(job entities.TranscodeJob, status entities.TranscodeStatus) {
+	if job.Profile != "" {
+		return
+	}
+	if err := s.updateTranscodeStatus(job.VideoID.String(), status); err != nil {
+		log.Printf("更新视频转码状态失败: %v", err)
+	}
+}
+
+// reclaimLoop 定期回收租约过期的任务
+func (s *TranscodeService) reclaimLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			reclaimed, err := s.ReclaimExpired()
+			if err != nil {
+				log.Printf("回收过期转码任务失败: %v", err)
+			} else if reclaimed > 0 {
+				log.Printf("已回收%d个租约过期的转码任务", reclaimed)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// ReclaimExpired 回收工作进程已停止心跳的任务
+// 崩溃的执行计入执行次数，已达到最大次数或已请求取消的任务直接结束
+func (s *TranscodeService) ReclaimExpired() (int, error) {
+	query := `
+		UPDATE transcode_jobs j
+		SET status = CASE
+				WHEN j.cancel_requested THEN 'cancelled'
+				WHEN j.attempts >= j.max_attempts THEN 'failed'
+				ELSE 'queued'
+			END,
+			finished_at = CASE WHEN j.cancel_requested OR j.attempts >= j.max_attempts THEN NOW() END,
+			last_error = '工作进程租约过期', run_at = NOW(),
+			worker_id = '', lease_expires_at = NULL, updated_at = NOW()
+		FROM (
+			SELECT id FROM transcode_jobs
+			WHERE status = 'running' AND lease_expires_at < NOW()
+			FOR UPDATE SKIP LOCKED
+		) expired
+		WHERE j.id = expired.id
+		RETURNING j.*
+	`
+
+	var jobs []entities.TranscodeJob
+	if err := s.db.Select(&jobs, query); err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		videoStatus := entities.TranscodeStatusFailed
+		if job.Status == entities.TranscodeJobQueued {
+			videoStatus = entities.TranscodeStatusPending
+			s.notify()
+		}
+		s.updateJobVideoStatus(job, videoStatus)
+	}
+
+	return len(jobs), nil
+}
+
+// ListJobs 按状态分页获取转码任务，status为空时返回全部任务
+func (s *TranscodeService) ListJobs(status string, page, limit int) ([]entities.TranscodeJob, int, error) {
+	var total int
+	if err := s.db.Get(&total, "SELECT COUNT(*) FROM transcode_jobs WHERE $1::text = '' OR status = $1", status); err != nil {
+		return nil, 0, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "获取转码任务总数失败",
+			Err:     err,
+		}
+	}
+
+	jobs := []entities.TranscodeJob{}
+	query := `
+		SELECT * FROM transcode_jobs
+		WHERE $1::text = '' OR status = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	if err := s.db.Select(&jobs, query, status, limit, (page-1)*limit); err != nil {
+		return nil, 0, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "获取转码任务列表失败",
+			Err:     err,
+		}
+	}
+
+	return jobs, total, nil
+}
+
+// RetryJob 重新执行失败或已取消的任务，执行次数从零开始计算
+func (s *TranscodeService) RetryJob(id string) (entities.TranscodeJob, error) {
+	var job entities.TranscodeJob
+	query := `
+		UPDATE transcode_jobs
+		SET status = 'queued', attempts = 0, cancel_requested = FALSE, run_at = NOW(),
+			finished_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status IN ('failed', 'cancelled')
+		RETURNING *
+	`
+	if err := s.db.Get(&job, query, id); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+			return entities.TranscodeJob{}, &ServiceError{
+				Type:    ErrTypeConflict,
+				Code:    ErrCodeResourceExists,
+				Message: "该视频已有未完成的转码任务",
+				Err:     err,
+			}
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.TranscodeJob{}, s.jobStateError(id, "只能重试失败或已取消的转码任务")
+		}
+		return entities.TranscodeJob{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "重试转码任务失败",
+			Err:     err,
+		}
+	}
+
+	s.updateJobVideoStatus(job, entities.TranscodeStatusPending)
+
+	s.notify()
+	return job, nil
+}
+
+// CancelJob 取消任务，排队中的任务立即取消，执行中的任务在下次心跳时中断
+func (s *TranscodeService) CancelJob(id string) (entities.TranscodeJob, error) {
+	var job entities.TranscodeJob
+	query := `
+		UPDATE transcode_jobs
+		SET status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+			finished_at = CASE WHEN status = 'queued' THEN NOW() ELSE finished_at END,
+			last_error = CASE WHEN status = 'queued' THEN '已取消' ELSE last_error END,
+			cancel_requested = TRUE, updated_at = NOW()
+		WHERE id = $1 AND status IN ('queued', 'running')
+		RETURNING *
+	`
+	if err := s.db.Get(&job, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.TranscodeJob{}, s.jobStateError(id, "转码任务已结束")
+		}
+		return entities.TranscodeJob{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "取消转码任务失败",
+			Err:     err,
+		}
+	}
+
+	if job.Status == entities.TranscodeJobCancelled {
+		s.updateJobVideoStatus(job, entities.TranscodeStatusFailed)
+	}
+
+	return job, nil
+}
+
+// CancelVideoTranscode 取消视频未完成的转码任务
+func (s *TranscodeService) CancelVideoTranscode(videoID, tenantID string) (entities.TranscodeJob, error) {
+	var id uuid.UUID
+	query := `
+		SELECT id FROM transcode_jobs
+		WHERE video_id = $1 AND merchant_id = $2 AND profile = '' AND status IN ('queued', 'running')
+	`
+	if err := s.db.Get(&id, query, videoID, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.TranscodeJob{}, &ServiceError{
+				Type:    ErrTypeNotFound,
+				Code:    ErrCodeResourceNotFound,
+				Message: "该视频没有未完成的转码任务",
+				Err:     err,
+			}
+		}
+		return entities.TranscodeJob{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "获取转码任务失败",
+			Err:     err,
+		}
+	}
+
+	return s.CancelJob(id.String())
+}
+
+// jobStateError 任务状态不允许操作时区分任务不存在和状态冲突
+func (s *TranscodeService) jobStateError(id, message string) error {
+	var exists bool
+	if err := s.db.Get(&exists, "SELECT EXISTS (SELECT 1 FROM transcode_jobs WHERE id = $1)", id); err != nil {
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "获取转码任务失败",
+			Err:     err,
+		}
+	}
+	if !exists {
+		return &ServiceError{
+			Type:    ErrTypeNotFound,
+			Code:    ErrCodeResourceNotFound,
+			Message: "转码任务不存在",
+		}
+	}
+	return &ServiceError{
+		Type:    ErrTypeConflict,
+		Code:    ErrCodeInvalidInput,
+		Message: message,
+	}
+}
@@ -6,15 +6,16 @@ import (
 	"content-service/internal/domain/entities"
 	"content-service/internal/messaging"
 	"content-service/internal/storage"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"mime/multipart"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -37,6 +38,23 @@ type TranscodeService struct {
 	kafkaProducer  *messaging.KafkaProducer
 	tempDir        string
 	resolutions    []Resolution
+
+	// 转码任务队列
+	workerID          string
+	concurrency       int
+	maxAttempts       int
+	retryBackoff      time.Duration
+	leaseTimeout      time.Duration
+	heartbeatInterval time.Duration
+	pollInterval      time.Duration
+	// 转码完成后预先生成衍生视频的输出规格
+	pregenerateProfiles []string
+	wake                chan struct{}
+	ctx                 context.Context
+	cancel              context.CancelFunc
+	stopOnce            sync.Once
+	done                chan struct{}
+	wg                  sync.WaitGroup
 }
 
 // NewTranscodeService 创建新的转码服务
@@ -59,59 +77,23 @@ func NewTranscodeService(
 		{Name: "360p", Width: 640, Height: 360, Suffix: "_360p"},
 	}
 
-	return &TranscodeService{
+	service := &TranscodeService{
 		db:             db,
 		storageService: storageService,
 		config:         config,
 		kafkaProducer:  kafkaProducer,
 		tempDir:        tempDir,
 		resolutions:    resolutions,
-	}, nil
-}
-
-// TranscodeVideo 转码视频
-func (s *TranscodeService) TranscodeVideo(videoID, tenantID string) error {
-	// 查询视频信息
-	video, err := s.getVideo(videoID, tenantID)
-	if err != nil {
-		return fmt.Errorf("获取视频信息失败: %w", err)
-	}
-
-	// 更新视频转码状态为处理中
-	if err := s.updateTranscodeStatus(video.ID.String(), entities.TranscodeStatusProcessing); err != nil {
-		return fmt.Errorf("更新转码状态失败: %w", err)
+		done:           make(chan struct{}),
 	}
+	service.configureQueue(config.Transcode)
 
-	// 发送视频处理事件
-	if s.kafkaProducer != nil {
-		payload := messaging.VideoProcessingPayload{
-			ID:           video.ID.String(),
-			TenantID:     video.TenantID.String(),
-			Status:       string(entities.TranscodeStatusProcessing),
-			ProcessingAt: time.Now().Format(time.RFC3339),
-		}
-		if err := s.kafkaProducer.SendVideoProcessing(payload); err != nil {
-			log.Printf("发送视频处理事件失败: %v", err)
-		}
-	}
-
-	// 在新的goroutine中处理转码
-	go func() {
-		err := s.processTranscode(video)
-		if err != nil {
-			log.Printf("视频转码失败: %v", err)
-			// 更新状态为失败
-			if updateErr := s.updateTranscodeStatus(video.ID.String(), entities.TranscodeStatusFailed); updateErr != nil {
-				log.Printf("更新视频转码状态失败: %v", updateErr)
-			}
-		}
-	}()
-
-	return nil
+	return service, nil
 }
 
 // processTranscode 处理视频转码
-func (s *TranscodeService) processTranscode(video entities.Video) error {
+// ctx取消时中断正在执行的ffmpeg并返回错误
+func (s *TranscodeService) processTranscode(ctx context.Context, video entities.Video) error {
 	// 下载视频到临时目录
 	inputPath, err := s.downloadVideo(video.FileKey)
 	if err != nil {
@@ -159,7 +141,7 @@ func (s *TranscodeService) processTranscode(video entities.Video) error {
 
 	// 转码并优化视频
 	optimizedPath := filepath.Join(s.tempDir, fmt.Sprintf("%s_optimized.mp4", video.ID.String()))
-	if err := s.optimizeForWeb(inputPath, optimizedPath); err != nil {
+	if err := s.optimizeForWeb(ctx, inputPath, optimizedPath); err != nil {
 		log.Printf("优化视频失败，将使用原始视频继续处理: %v", err)
 		// 使用原始视频继续处理
 		optimizedPath = inputPath
@@ -175,6 +157,7 @@ func (s *TranscodeService) processTranscode(video entities.Video) error {
 		height   int
 		fileSize int64
 		suffix   string
+		name     string
 	}
 
 	// 确定最终使用的分辨率列表
@@ -193,13 +176,20 @@ func (s *TranscodeService) processTranscode(video entities.Video) error {
 
 	// 对每个分辨率进行转码
 	for _, res := range finalResolutions {
+		if err := ctx.Err(); err != nil {
+			for _, file := range transcodedFiles {
+				os.Remove(file.path)
+			}
+			return fmt.Errorf("转码已中断: %w", err)
+		}
+
 		outputPath := filepath.Join(s.tempDir, fmt.Sprintf("%s%s.mp4", video.ID.String(), res.Suffix))
 
 		// 计算目标分辨率
 		targetWidth, targetHeight := s.calculateDimensions(width, height, res.Width, res.Height)
 
 		// 执行转码
-		if err := s.transcodeToMP4(optimizedPath, outputPath, targetWidth, targetHeight); err != nil {
+		if err := s.transcodeToMP4(ctx, optimizedPath, outputPath, targetWidth, targetHeight); err != nil {
 			log.Printf("转码到%s分辨率失败: %v", res.Name, err)
 			continue
 		}
@@ -221,6 +211,7 @@ func (s *TranscodeService) processTranscode(video entities.Video) error {
 			height   int
 			fileSize int64
 			suffix   string
+			name     string
 		}{
 			path:     outputPath,
 			fileKey:  fileKey,
@@ -228,14 +219,17 @@ func (s *TranscodeService) processTranscode(video entities.Video) error {
 			height:   targetHeight,
 			fileSize: fileInfo.Size(),
 			suffix:   res.Suffix,
+			name:     res.Name,
 		})
 	}
 
-	// 上传转码后的文件
+	// 上传转码后的文件，每个分辨率都记录到视频转码输出表
 	var mainFileKey string
+	var renditions []entities.VideoRendition
 	for _, file := range transcodedFiles {
 		if err := s.uploadFile(file.path, file.fileKey); err != nil {
 			log.Printf("上传转码文件失败: %v", err)
+			os.Remove(file.path)
 			continue
 		}
 
@@ -244,10 +238,47 @@ func (s *TranscodeService) processTranscode(video entities.Video) error {
 			mainFileKey = file.fileKey
 		}
 
+		rendition := entities.VideoRendition{
+			ID:       uuid.New(),
+			VideoID:  video.ID,
+			TenantID: video.TenantID,
+			Name:     file.name,
+			Width:    file.width,
+			Height:   file.height,
+			FileKey:  file.fileKey,
+			FileSize: file.fileSize,
+			Codecs:   s.probeCodecs(file.path),
+		}
+
+		// 打包HLS，失败时该分辨率只保留MP4
+		if s.config.HLS.Enabled {
+			if err := s.packageRendition(ctx, video, file.path, &rendition); err != nil {
+				log.Printf("打包%s分辨率HLS失败: %v", file.name, err)
+			}
+		}
+
+		if err := s.saveRendition(rendition); err != nil {
+			log.Printf("保存%s分辨率转码输出失败: %v", file.name, err)
+		} else {
+			renditions = append(renditions, rendition)
+		}
+
 		// 清理临时文件
 		os.Remove(file.path)
 	}
 
+	// 生成并上传主播放列表
+	if s.config.HLS.Enabled {
+		if err := s.publishMasterPlaylist(video, renditions); err != nil {
+			log.Printf("生成HLS主播放列表失败: %v", err)
+		}
+	}
+
+	// 打包过程中被中断时HLS输出不完整，不标记为完成
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("转码已中断: %w", err)
+	}
+
 	// 如果没有成功转码的文件，标记为失败
 	if len(transcodedFiles) == 0 {
 		return fmt.Errorf("没有成功转码的文件")
@@ -377,7 +408,7 @@ func (s *TranscodeService) generateCover(inputPath, videoID string) (string, err
 }
 
 // transcodeToMP4 转码到MP4格式
-func (s *TranscodeService) transcodeToMP4(inputPath, outputPath string, width, height int) error {
+func (s *TranscodeService) transcodeToMP4(ctx context.Context, inputPath, outputPath string, width, height int) error {
 	// 增加压缩和优化参数
 	// -crf 质量控制参数(0-51)，值越大压缩程度越高、质量越低，一般推荐18-28
 	// -preset 压缩速度与质量的平衡，medium为平衡选项
@@ -386,8 +417,10 @@ func (s *TranscodeService) transcodeToMP4(inputPath, outputPath string, width, h
 	// -pix_fmt 像素格式，yuv420p是最广泛支持的
 	// -maxrate 限制最大码率
 	// -bufsize 码率控制缓冲区大小
+	// -force_key_frames 按固定间隔插入关键帧，-sc_threshold 0 禁止场景切换时额外插入关键帧，
+	// 各分辨率的HLS切片边界因此一致
 
-	cmd := exec.Command(
+	cmd := exec.CommandContext(ctx,
 		"ffmpeg",
 		"-i", inputPath,
 		"-c:v", "libx264",
@@ -401,6 +434,8 @@ func (s *TranscodeService) transcodeToMP4(inputPath, outputPath string, width, h
 		"-movflags", "faststart",
 		"-maxrate", "2M",
 		"-bufsize", "4M",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", keyframeIntervalSeconds),
+		"-sc_threshold", "0",
 		"-y",
 		outputPath,
 	)
@@ -442,9 +477,9 @@ func (s *TranscodeService) compressVideo(inputPath, outputPath string) error {
 }
 
 // optimizeForWeb 优化视频供网络传输
-func (s *TranscodeService) optimizeForWeb(inputPath, outputPath string) error {
+func (s *TranscodeService) optimizeForWeb(ctx context.Context, inputPath, outputPath string) error {
 	// 使用适合网络传输的参数
-	cmd := exec.Command(
+	cmd := exec.CommandContext(ctx,
 		"ffmpeg",
 		"-i", inputPath,
 		"-c:v", "libx264",
@@ -503,43 +538,25 @@ func (s *TranscodeService) calculateDimensions(srcWidth, srcHeight, maxWidth, ma
 
 // uploadFile 上传文件到存储服务
 func (s *TranscodeService) uploadFile(filePath, fileKey string) error {
-	// 打开文件
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("打开文件失败: %w", err)
-	}
-	defer file.Close()
-
-	// 获取文件信息
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("获取文件信息失败: %w", err)
-	}
-
-	// 创建临时的multipart.FileHeader
-	tempHeader := &multipart.FileHeader{
-		Filename: filepath.Base(filePath),
-		Size:     fileInfo.Size(),
-	}
-	tempHeader.Header = make(map[string][]string)
-
 	// 根据文件扩展名设置ContentType
 	ext := strings.ToLower(filepath.Ext(filePath))
 	contentType := "application/octet-stream"
-	if ext == ".mp4" {
+	switch ext {
+	case ".mp4":
 		contentType = "video/mp4"
-	} else if ext == ".jpg" || ext == ".jpeg" {
+	case ".jpg", ".jpeg":
 		contentType = "image/jpeg"
-	} else if ext == ".png" {
+	case ".png":
 		contentType = "image/png"
+	case ".m3u8":
+		contentType = "application/vnd.apple.mpegurl"
+	case ".m4s":
+		contentType = "video/iso.segment"
+	case ".ts":
+		contentType = "video/mp2t"
 	}
-	tempHeader.Header.Set("Content-Type", contentType)
-
-	// 关闭当前文件
-	file.Close()
 
-	// 上传文件
-	return s.storageService.UploadFile(tempHeader, fileKey)
+	return s.storageService.UploadLocalFile(filePath, fileKey, contentType)
 }
 
 // getVideo 获取视频信息
@@ -0,0 +1,628 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"content-service/internal/config"
+	"content-service/internal/domain/entities"
+	"content-service/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	// defaultUploadMaxSize 默认单个视频的最大大小
+	defaultUploadMaxSize int64 = 2048 << 20
+	// minUploadPartSize 对象存储分片上传要求除最后一个分片外每个分片不小于5MB
+	minUploadPartSize int64 = 5 << 20
+	// defaultUploadPartSize 默认分片大小
+	defaultUploadPartSize int64 = 8 << 20
+	// defaultUploadExpiry 默认上传保留时间
+	defaultUploadExpiry = 24 * time.Hour
+	// uploadCleanupInterval 清理过期上传的间隔
+	uploadCleanupInterval = 10 * time.Minute
+	// uploadCleanupBatchSize 每批清理的过期上传数量
+	uploadCleanupBatchSize = 100
+
+	// pqLockNotAvailable 行锁被占用（FOR UPDATE NOWAIT）
+	pqLockNotAvailable = "55P03"
+)
+
+// UploadService 视频断点续传上传服务
+// 每次写入都锁定上传记录，同一上传同时只能有一个请求写入
+type UploadService struct {
+	db             *sqlx.DB
+	storageService *storage.StorageService
+	videoService   *VideoService
+	maxSize        int64
+	partSize       int64
+	expiry         time.Duration
+	stopOnce       sync.Once
+	done           chan struct{}
+	wg             sync.WaitGroup
+}
+
+// NewUploadService 创建断点续传上传服务
+func NewUploadService(videoService *VideoService, cfg *config.Config) *UploadService {
+	maxSize := int64(cfg.Upload.MaxSizeMB) << 20
+	if maxSize <= 0 {
+		maxSize = defaultUploadMaxSize
+	}
+
+	partSize := int64(cfg.Upload.PartSizeMB) << 20
+	if partSize <= 0 {
+		partSize = defaultUploadPartSize
+	}
+	if partSize < minUploadPartSize {
+		partSize = minUploadPartSize
+	}
+
+	expiry := time.Duration(cfg.Upload.ExpiryHours) * time.Hour
+	if expiry <= 0 {
+		expiry = defaultUploadExpiry
+	}
+
+	return &UploadService{
+		db:             videoService.db,
+		storageService: videoService.storageService,
+		videoService:   videoService,
+		maxSize:        maxSize,
+		partSize:       partSize,
+		expiry:         expiry,
+		done:           make(chan struct{}),
+	}
+}
+
+// MaxSize 单个视频的最大大小（字节）
+func (s *UploadService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// CreateUpload 创建断点续传上传，同时在对象存储中创建分片上传
+func (s *UploadService) CreateUpload(tenantID string, dto entities.CreateUploadDTO) (entities.VideoUpload, error) {
+	if dto.Title == "" {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeValidation,
+			Code:    ErrCodeInvalidInput,
+			Message: "标题不能为空",
+		}
+	}
+
+	if dto.Length <= 0 || dto.Length > s.maxSize {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeValidation,
+			Code:    ErrCodeInvalidInput,
+			Message: fmt.Sprintf("文件大小无效，最大允许%dMB", s.maxSize>>20),
+		}
+	}
+
+	tenantUUID, err := uuid.Parse(tenantID)
+	if err != nil {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeValidation,
+			Code:    ErrCodeInvalidInput,
+			Message: "无效的租户ID格式",
+			Err:     err,
+		}
+	}
+
+	// 文件Key与直接上传一致，上传完成后直接作为视频文件
+	videoID := uuid.New()
+	fileKey := fmt.Sprintf("%s/%s%s", tenantID, videoID.String(), filepath.Ext(dto.FileName))
+
+	storageUploadID, err := s.storageService.NewMultipartUpload(fileKey, dto.FileType)
+	if err != nil {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeStorage,
+			Code:    ErrCodeFileUpload,
+			Message: "创建上传失败",
+			Err:     err,
+		}
+	}
+
+	upload := entities.VideoUpload{
+		ID:              uuid.New(),
+		TenantID:        tenantUUID,
+		VideoID:         videoID,
+		FileKey:         fileKey,
+		StorageUploadID: storageUploadID,
+		FileName:        dto.FileName,
+		FileType:        dto.FileType,
+		Title:           dto.Title,
+		Description:     dto.Description,
+		Length:          dto.Length,
+		Status:          entities.UploadStatusUploading,
+		ExpiresAt:       time.Now().Add(s.expiry),
+	}
+
+	query := `
+		INSERT INTO video_uploads (
+			id, merchant_id, video_id, file_key, storage_upload_id, file_name, file_type,
+			title, description, upload_length, status, expires_at
+		) VALUES (
+			:id, :merchant_id, :video_id, :file_key, :storage_upload_id, :file_name, :file_type,
+			:title, :description, :upload_length, :status, :expires_at
+		) RETURNING *
+	`
+
+	rows, err := s.db.NamedQuery(query, upload)
+	if err != nil {
+		_ = s.storageService.AbortMultipartUpload(fileKey, storageUploadID)
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存上传信息失败",
+			Err:     err,
+		}
+	}
+	defer rows.Close()
+
+	var result entities.VideoUpload
+	if !rows.Next() {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "创建上传失败",
+		}
+	}
+	if err := rows.StructScan(&result); err != nil {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "读取上传信息失败",
+			Err:     err,
+		}
+	}
+
+	return result, nil
+}
+
+// GetUpload 获取上传进度，已过期的上传视为不存在
+func (s *UploadService) GetUpload(id, tenantID string) (entities.VideoUpload, error) {
+	var upload entities.VideoUpload
+	query := "SELECT * FROM video_uploads WHERE id = $1 AND merchant_id = $2"
+	if err := s.db.Get(&upload, query, id, tenantID); err != nil {
+		return entities.VideoUpload{}, uploadQueryError(err)
+	}
+
+	if err := checkUploadExpiry(upload); err != nil {
+		return entities.VideoUpload{}, err
+	}
+
+	return upload, nil
+}
+
+// WriteUpload 从offset处追加写入数据，offset必须等于已接收的字节数
+// 请求中断时已读取的数据同样保存，客户端查询进度后从新的offset继续
+// 接收完全部数据后合并分片并创建视频记录，流程与直接上传一致
+func (s *UploadService) WriteUpload(id, tenantID string, offset int64, body io.Reader) (entities.VideoUpload, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "写入上传数据失败",
+			Err:     err,
+		}
+	}
+	defer tx.Rollback()
+
+	upload, err := s.lockUpload(tx, id, tenantID)
+	if err != nil {
+		return entities.VideoUpload{}, err
+	}
+
+	if offset != upload.Offset {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeConflict,
+			Code:    ErrCodeUploadOffset,
+			Message: fmt.Sprintf("上传偏移量不一致，当前偏移量为%d", upload.Offset),
+		}
+	}
+
+	// 已接收全部数据但上次合并失败时，客户端可以发送空请求重试
+	if upload.Status == entities.UploadStatusCompleted || upload.Completed() {
+		tx.Rollback()
+		return s.finishUpload(id, tenantID)
+	}
+
+	previousPending := pendingObjectKey(upload)
+	hadPending := upload.PendingSize > 0
+	writeErr := s.writeParts(&upload, body)
+
+	upload.ExpiresAt = time.Now().Add(s.expiry)
+	query := `
+		UPDATE video_uploads
+		SET upload_offset = $1, parts = $2, pending_size = $3, expires_at = $4, updated_at = NOW()
+		WHERE id = $5
+	`
+	if _, err := tx.Exec(query, upload.Offset, upload.Parts, upload.PendingSize, upload.ExpiresAt, upload.ID); err != nil {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存上传进度失败",
+			Err:     err,
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存上传进度失败",
+			Err:     err,
+		}
+	}
+
+	// 暂存数据已写入分片或换了新的临时对象，提交后再删除旧的临时对象
+	if hadPending && (upload.PendingSize == 0 || pendingObjectKey(upload) != previousPending) {
+		if err := s.storageService.DeleteFile(previousPending); err != nil {
+			log.Printf("删除上传临时数据失败: %v", err)
+		}
+	}
+
+	if writeErr != nil {
+		return upload, writeErr
+	}
+
+	if upload.Completed() {
+		return s.finishUpload(id, tenantID)
+	}
+
+	return upload, nil
+}
+
+// writeParts 按分片大小写入数据，最后不足一个分片的数据写入临时对象
+// 只有成功写入对象存储的数据才计入偏移量
+func (s *UploadService) writeParts(upload *entities.VideoUpload, body io.Reader) error {
+	reader := io.LimitReader(body, upload.Length-upload.Offset)
+
+	// 上次暂存的数据拼接在本次数据之前
+	if upload.PendingSize > 0 {
+		pending, err := s.storageService.GetObject(pendingObjectKey(*upload))
+		if err != nil {
+			return &ServiceError{
+				Type:    ErrTypeStorage,
+				Code:    ErrCodeFileDownload,
+				Message: "读取上传临时数据失败",
+				Err:     err,
+			}
+		}
+		defer pending.Close()
+		reader = io.MultiReader(io.LimitReader(pending, upload.PendingSize), reader)
+	}
+
+	buf := make([]byte, s.partSize)
+	for {
+		n, readErr := io.ReadFull(reader, buf)
+		if n > 0 {
+			partsSize := upload.PartsSize()
+			if int64(n) == s.partSize || partsSize+int64(n) == upload.Length {
+				number := len(upload.Parts) + 1
+				etag, err := s.storageService.PutObjectPart(upload.FileKey, upload.StorageUploadID, number, bytes.NewReader(buf[:n]), int64(n))
+				if err != nil {
+					return &ServiceError{
+						Type:    ErrTypeStorage,
+						Code:    ErrCodeFileUpload,
+						Message: "写入上传数据失败",
+						Err:     err,
+					}
+				}
+				upload.Parts = append(upload.Parts, entities.UploadPart{Number: number, ETag: etag, Size: int64(n)})
+				upload.PendingSize = 0
+				upload.Offset = partsSize + int64(n)
+			} else {
+				// 临时对象的Key由已写入分片的字节数决定，分片不变时新数据以旧数据为前缀，覆盖写入是安全的
+				key := pendingKey(upload.FileKey, partsSize)
+				if err := s.storageService.PutObject(key, bytes.NewReader(buf[:n]), int64(n), "application/octet-stream"); err != nil {
+					return &ServiceError{
+						Type:    ErrTypeStorage,
+						Code:    ErrCodeFileUpload,
+						Message: "写入上传数据失败",
+						Err:     err,
+					}
+				}
+				upload.PendingSize = int64(n)
+				upload.Offset = partsSize + int64(n)
+			}
+		}
+
+		switch {
+		case readErr == nil:
+			continue
+		case errors.Is(readErr, io.EOF), errors.Is(readErr, io.ErrUnexpectedEOF):
+			return nil
+		default:
+			// 客户端断开连接，已读取的数据已经保存
+			return &ServiceError{
+				Type:    ErrTypeValidation,
+				Code:    ErrCodeInvalidInput,
+				Message: "读取上传数据中断",
+				Err:     readErr,
+			}
+		}
+	}
+}
+
+// finishUpload 合并分片并创建视频记录，可以重复调用
+func (s *UploadService) finishUpload(id, tenantID string) (entities.VideoUpload, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "完成上传失败",
+			Err:     err,
+		}
+	}
+	defer tx.Rollback()
+
+	upload, err := s.lockUpload(tx, id, tenantID)
+	if err != nil {
+		return entities.VideoUpload{}, err
+	}
+	if upload.Status == entities.UploadStatusCompleted {
+		return upload, nil
+	}
+
+	// 上次合并成功但后续步骤失败时，分片上传已不存在，直接使用已合并的文件
+	if size, err := s.storageService.StatObject(upload.FileKey); err != nil || size != upload.Length {
+		parts := make([]storage.CompletedPart, 0, len(upload.Parts))
+		for _, part := range upload.Parts {
+			parts = append(parts, storage.CompletedPart{Number: part.Number, ETag: part.ETag})
+		}
+		if err := s.storageService.CompleteMultipartUpload(upload.FileKey, upload.StorageUploadID, parts); err != nil {
+			return entities.VideoUpload{}, &ServiceError{
+				Type:    ErrTypeStorage,
+				Code:    ErrCodeFileUpload,
+				Message: "合并上传分片失败",
+				Err:     err,
+			}
+		}
+		size, err := s.storageService.StatObject(upload.FileKey)
+		if err != nil || size != upload.Length {
+			return entities.VideoUpload{}, &ServiceError{
+				Type:    ErrTypeStorage,
+				Code:    ErrCodeFileUpload,
+				Message: "上传文件大小与声明不一致",
+				Err:     err,
+			}
+		}
+	}
+
+	if _, err := s.videoService.FindOne(upload.VideoID.String(), tenantID); err != nil {
+		dto := entities.CreateVideoDTO{Title: upload.Title, Description: upload.Description}
+		if _, err := s.videoService.CreateFromObject(tenantID, upload.VideoID, upload.FileKey, upload.FileName, upload.FileType, upload.Length, dto); err != nil {
+			return entities.VideoUpload{}, err
+		}
+	}
+
+	upload.Status = entities.UploadStatusCompleted
+	upload.ExpiresAt = time.Now().Add(s.expiry)
+	query := `
+		UPDATE video_uploads
+		SET status = $1, expires_at = $2, updated_at = NOW()
+		WHERE id = $3
+	`
+	if _, err := tx.Exec(query, upload.Status, upload.ExpiresAt, upload.ID); err != nil {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存上传状态失败",
+			Err:     err,
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return entities.VideoUpload{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存上传状态失败",
+			Err:     err,
+		}
+	}
+
+	return upload, nil
+}
+
+// TerminateUpload 终止上传，删除已上传的分片和暂存数据
+func (s *UploadService) TerminateUpload(id, tenantID string) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "终止上传失败",
+			Err:     err,
+		}
+	}
+	defer tx.Rollback()
+
+	upload, err := s.lockUpload(tx, id, tenantID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM video_uploads WHERE id = $1", upload.ID); err != nil {
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "终止上传失败",
+			Err:     err,
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "终止上传失败",
+			Err:     err,
+		}
+	}
+
+	s.discardUpload(upload)
+	return nil
+}
+
+// lockUpload 锁定上传记录，记录已被其他请求锁定时立即返回错误
+func (s *UploadService) lockUpload(tx *sqlx.Tx, id, tenantID string) (entities.VideoUpload, error) {
+	var upload entities.VideoUpload
+	query := "SELECT * FROM video_uploads WHERE id = $1 AND merchant_id = $2 FOR UPDATE NOWAIT"
+	if err := tx.Get(&upload, query, id, tenantID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pqLockNotAvailable {
+			return entities.VideoUpload{}, &ServiceError{
+				Type:    ErrTypeConflict,
+				Code:    ErrCodeUploadLocked,
+				Message: "该上传正在被其他请求写入",
+				Err:     err,
+			}
+		}
+		return entities.VideoUpload{}, uploadQueryError(err)
+	}
+
+	if err := checkUploadExpiry(upload); err != nil {
+		return entities.VideoUpload{}, err
+	}
+
+	return upload, nil
+}
+
+// discardUpload 删除未完成上传在对象存储中的分片和暂存数据，失败只记录日志
+func (s *UploadService) discardUpload(upload entities.VideoUpload) {
+	if upload.Status == entities.UploadStatusCompleted {
+		return
+	}
+	if err := s.storageService.AbortMultipartUpload(upload.FileKey, upload.StorageUploadID); err != nil {
+		log.Printf("取消上传%s的分片上传失败: %v", upload.ID, err)
+	}
+	if upload.PendingSize > 0 {
+		if err := s.storageService.DeleteFile(pendingObjectKey(upload)); err != nil {
+			log.Printf("删除上传%s的临时数据失败: %v", upload.ID, err)
+		}
+	}
+}
+
+// Start 启动过期上传清理协程
+func (s *UploadService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	log.Printf("上传清理协程已启动，间隔: %s", uploadCleanupInterval)
+}
+
+// Stop 停止过期上传清理协程
+func (s *UploadService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+}
+
+// run 定期清理过期上传和直传会话
+func (s *UploadService) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(uploadCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			purged, err := s.PurgeExpired()
+			if err != nil {
+				log.Printf("清理过期上传失败: %v", err)
+			} else if purged > 0 {
+				log.Printf("已清理%d个过期上传", purged)
+			}
+
+			purged, err = s.purgeExpiredSessions()
+			if err != nil {
+				log.Printf("清理过期上传会话失败: %v", err)
+			} else if purged > 0 {
+				log.Printf("已清理%d个过期上传会话", purged)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// PurgeExpired 删除过期的上传记录及其分片，跳过正在写入的上传
+func (s *UploadService) PurgeExpired() (int, error) {
+	query := `
+		DELETE FROM video_uploads
+		WHERE id IN (
+			SELECT id FROM video_uploads
+			WHERE expires_at < NOW()
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`
+
+	total := 0
+	for {
+		var uploads []entities.VideoUpload
+		if err := s.db.Select(&uploads, query, uploadCleanupBatchSize); err != nil {
+			return total, fmt.Errorf("删除过期上传失败: %w", err)
+		}
+
+		for _, upload := range uploads {
+			s.discardUpload(upload)
+		}
+		total += len(uploads)
+
+		if len(uploads) < uploadCleanupBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// pendingObjectKey 上传当前暂存数据的临时对象Key
+func pendingObjectKey(upload entities.VideoUpload) string {
+	return pendingKey(upload.FileKey, upload.PartsSize())
+}
+
+// pendingKey 已写入partsSize字节分片时暂存数据的临时对象Key
+func pendingKey(fileKey string, partsSize int64) string {
+	return fmt.Sprintf("%s.%d.part", fileKey, partsSize)
+}
+
+// checkUploadExpiry 未完成且已过期的上传不能继续
+func checkUploadExpiry(upload entities.VideoUpload) error {
+	if upload.Status == entities.UploadStatusUploading && time.Now().After(upload.ExpiresAt) {
+		return &ServiceError{
+			Type:    ErrTypeNotFound,
+			Code:    ErrCodeUploadExpired,
+			Message: "上传已过期",
+		}
+	}
+	return nil
+}
+
+// uploadQueryError 转换上传记录的查询错误
+func uploadQueryError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return &ServiceError{
+			Type:    ErrTypeNotFound,
+			Code:    ErrCodeResourceNotFound,
+			Message: "上传不存在",
+			Err:     err,
+		}
+	}
+	return &ServiceError{
+		Type:    ErrTypeDatabase,
+		Code:    ErrCodeDBQuery,
+		Message: "获取上传信息失败",
+		Err:     err,
+	}
+}
@@ -0,0 +1,573 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"content-service/internal/domain/entities"
+	"content-service/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// maxUploadParts 对象存储分片上传的最大分片数
+const maxUploadParts = 10000
+
+// 直传会话：客户端先声明文件大小、类型和SHA-256，服务端校验格式和套餐配额后
+// 返回限定在商户前缀下的预签名URL，客户端直接上传到对象存储，完成后回调校验再创建视频
+
+// CreateSession 创建直传会话并生成预签名上传URL
+// 文件不超过一个分片时使用单次PUT，否则使用分片上传
+func (s *UploadService) CreateSession(tenantID string, dto entities.CreateUploadSessionDTO) (entities.UploadSessionResponse, error) {
+	if dto.Size <= 0 || dto.Size > s.maxSize {
+		return entities.UploadSessionResponse{}, &ServiceError{
+			Type:    ErrTypeValidation,
+			Code:    ErrCodeInvalidInput,
+			Message: fmt.Sprintf("文件大小无效，最大允许%dMB", s.maxSize>>20),
+		}
+	}
+
+	tenantUUID, err := uuid.Parse(tenantID)
+	if err != nil {
+		return entities.UploadSessionResponse{}, &ServiceError{
+			Type:    ErrTypeValidation,
+			Code:    ErrCodeInvalidInput,
+			Message: "无效的租户ID格式",
+			Err:     err,
+		}
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return entities.UploadSessionResponse{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "创建上传会话失败",
+			Err:     err,
+		}
+	}
+	defer tx.Rollback()
+
+	if err := s.checkQuota(tx, tenantID, dto.Size); err != nil {
+		return entities.UploadSessionResponse{}, err
+	}
+
+	videoID := uuid.New()
+	session := entities.UploadSession{
+		ID:             uuid.New(),
+		TenantID:       tenantUUID,
+		VideoID:        videoID,
+		FileKey:        fmt.Sprintf("%s/%s%s", tenantID, videoID.String(), filepath.Ext(dto.FileName)),
+		FileName:       dto.FileName,
+		FileType:       dto.FileType,
+		Title:          dto.Title,
+		Description:    dto.Description,
+		Size:           dto.Size,
+		ChecksumSHA256: strings.ToLower(dto.ChecksumSHA256),
+		Status:         entities.UploadSessionPending,
+		ExpiresAt:      time.Now().Add(s.expiry),
+	}
+
+	if dto.Size > s.partSize {
+		// 分片数不能超过上限，超过时按上限放大分片
+		partSize := s.partSize
+		if (dto.Size+partSize-1)/partSize > maxUploadParts {
+			partSize = (dto.Size + maxUploadParts - 1) / maxUploadParts
+		}
+		session.PartSize = partSize
+		session.PartCount = int((dto.Size + partSize - 1) / partSize)
+
+		session.StorageUploadID, err = s.storageService.NewMultipartUpload(session.FileKey, dto.FileType)
+		if err != nil {
+			return entities.UploadSessionResponse{}, &ServiceError{
+				Type:    ErrTypeStorage,
+				Code:    ErrCodeFileUpload,
+				Message: "创建上传会话失败",
+				Err:     err,
+			}
+		}
+	}
+
+	query := `
+		INSERT INTO video_upload_sessions (
+			id, merchant_id, video_id, file_key, storage_upload_id, part_size, part_count,
+			file_name, file_type, title, description, size, checksum_sha256, status, expires_at
+		) VALUES (
+			:id, :merchant_id, :video_id, :file_key, :storage_upload_id, :part_size, :part_count,
+			:file_name, :file_type, :title, :description, :size, :checksum_sha256, :status, :expires_at
+		)
+	`
+	if _, err := tx.NamedExec(query, session); err != nil {
+		s.discardSession(session)
+		return entities.UploadSessionResponse{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存上传会话失败",
+			Err:     err,
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		s.discardSession(session)
+		return entities.UploadSessionResponse{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存上传会话失败",
+			Err:     err,
+		}
+	}
+
+	return s.presignSession(session)
+}
+
+// presignSession 生成会话的预签名上传URL，有效期与会话一致
+func (s *UploadService) presignSession(session entities.UploadSession) (entities.UploadSessionResponse, error) {
+	response := entities.UploadSessionResponse{
+		UploadSession: session,
+		Method:        "PUT",
+		Headers:       map[string]string{},
+	}
+	expiry := time.Until(session.ExpiresAt)
+
+	if !session.Multipart() {
+		url, err := s.storageService.PresignedPutURL(session.FileKey, expiry)
+		if err != nil {
+			return entities.UploadSessionResponse{}, &ServiceError{
+				Type:    ErrTypeStorage,
+				Code:    ErrCodeFileUpload,
+				Message: "生成上传URL失败",
+				Err:     err,
+			}
+		}
+		response.URL = url
+		response.Headers["Content-Type"] = session.FileType
+		return response, nil
+	}
+
+	response.Parts = make([]entities.UploadSessionPart, 0, session.PartCount)
+	for number := 1; number <= session.PartCount; number++ {
+		url, err := s.storageService.PresignedPartURL(session.FileKey, session.StorageUploadID, number, expiry)
+		if err != nil {
+			return entities.UploadSessionResponse{}, &ServiceError{
+				Type:    ErrTypeStorage,
+				Code:    ErrCodeFileUpload,
+				Message: "生成上传URL失败",
+				Err:     err,
+			}
+		}
+
+		size := session.PartSize
+		if number == session.PartCount {
+			size = session.Size - session.PartSize*int64(session.PartCount-1)
+		}
+		response.Parts = append(response.Parts, entities.UploadSessionPart{PartNumber: number, URL: url, Size: size})
+	}
+
+	return response, nil
+}
+
+// GetSession 获取直传会话
+func (s *UploadService) GetSession(id, tenantID string) (entities.UploadSession, error) {
+	var session entities.UploadSession
+	query := "SELECT * FROM video_upload_sessions WHERE id = $1 AND merchant_id = $2"
+	if err := s.db.Get(&session, query, id, tenantID); err != nil {
+		return entities.UploadSession{}, sessionQueryError(err)
+	}
+
+	return session, nil
+}
+
+// CompleteSession 校验已上传文件的大小和SHA-256后创建视频并开始转码
+// 校验不通过时删除文件并将会话标记为失败，客户端需要重新创建会话；已完成的会话重复调用返回同一个视频
+func (s *UploadService) CompleteSession(id, tenantID string, dto entities.CompleteUploadSessionDTO) (entities.Video, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return entities.Video{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "完成上传会话失败",
+			Err:     err,
+		}
+	}
+	defer tx.Rollback()
+
+	session, err := s.lockSession(tx, id, tenantID)
+	if err != nil {
+		return entities.Video{}, err
+	}
+
+	switch {
+	case session.Status == entities.UploadSessionCompleted:
+		return s.videoService.FindOne(session.VideoID.String(), tenantID)
+	case session.Status == entities.UploadSessionFailed:
+		return entities.Video{}, &ServiceError{
+			Type:    ErrTypeConflict,
+			Code:    ErrCodeInvalidInput,
+			Message: "上传会话已失败: " + session.FailureReason,
+		}
+	case time.Now().After(session.ExpiresAt):
+		return entities.Video{}, &ServiceError{
+			Type:    ErrTypeNotFound,
+			Code:    ErrCodeUploadExpired,
+			Message: "上传会话已过期",
+		}
+	}
+
+	size, statErr := s.storageService.StatObject(session.FileKey)
+	if session.Multipart() && (statErr != nil || size != session.Size) {
+		parts, err := sessionParts(session, dto.Parts)
+		if err != nil {
+			return entities.Video{}, err
+		}
+		if err := s.storageService.CompleteMultipartUpload(session.FileKey, session.StorageUploadID, parts); err != nil {
+			return entities.Video{}, &ServiceError{
+				Type:    ErrTypeValidation,
+				Code:    ErrCodeFileUpload,
+				Message: "合并上传分片失败，请检查分片是否全部上传成功",
+				Err:     err,
+			}
+		}
+		size, statErr = s.storageService.StatObject(session.FileKey)
+	}
+	if statErr != nil {
+		return entities.Video{}, &ServiceError{
+			Type:    ErrTypeValidation,
+			Code:    ErrCodeInvalidInput,
+			Message: "文件尚未上传",
+			Err:     statErr,
+		}
+	}
+
+	if size != session.Size {
+		return entities.Video{}, s.failSession(tx, session, fmt.Sprintf("文件大小与声明不一致，声明%d字节，实际%d字节", session.Size, size))
+	}
+
+	checksum, err := s.objectChecksum(session.FileKey)
+	if err != nil {
+		return entities.Video{}, &ServiceError{
+			Type:    ErrTypeStorage,
+			Code:    ErrCodeFileDownload,
+			Message: "校验文件失败",
+			Err:     err,
+		}
+	}
+	if checksum != session.ChecksumSHA256 {
+		return entities.Video{}, s.failSession(tx, session, "文件SHA-256与声明不一致")
+	}
+
+	video, err := s.videoService.FindOne(session.VideoID.String(), tenantID)
+	if err != nil {
+		dto := entities.CreateVideoDTO{Title: session.Title, Description: session.Description}
+		video, err = s.videoService.CreateFromObject(tenantID, session.VideoID, session.FileKey, session.FileName, session.FileType, session.Size, dto)
+		if err != nil {
+			return entities.Video{}, err
+		}
+	}
+
+	query := `
+		UPDATE video_upload_sessions
+		SET status = $1, completed_at = NOW(), expires_at = $2, updated_at = NOW()
+		WHERE id = $3
+	`
+	if _, err := tx.Exec(query, entities.UploadSessionCompleted, time.Now().Add(s.expiry), session.ID); err != nil {
+		return entities.Video{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存上传会话状态失败",
+			Err:     err,
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return entities.Video{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存上传会话状态失败",
+			Err:     err,
+		}
+	}
+
+	return video, nil
+}
+
+// AbortSession 取消未完成的直传会话，删除已上传的数据
+func (s *UploadService) AbortSession(id, tenantID string) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "取消上传会话失败",
+			Err:     err,
+		}
+	}
+	defer tx.Rollback()
+
+	session, err := s.lockSession(tx, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if session.Status != entities.UploadSessionPending {
+		return &ServiceError{
+			Type:    ErrTypeConflict,
+			Code:    ErrCodeInvalidInput,
+			Message: "上传会话已结束",
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM video_upload_sessions WHERE id = $1", session.ID); err != nil {
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "取消上传会话失败",
+			Err:     err,
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "取消上传会话失败",
+			Err:     err,
+		}
+	}
+
+	s.discardSession(session)
+	return nil
+}
+
+// checkQuota 校验商户套餐的视频数量和存储空间，未完成的会话按声明大小计入
+// 锁定商户记录，避免并发创建会话同时通过校验；套餐上限为0表示不限制
+func (s *UploadService) checkQuota(tx *sqlx.Tx, tenantID string, size int64) error {
+	var quota struct {
+		MaxVideos    int `db:"max_videos"`
+		MaxStorageGB int `db:"max_storage_gb"`
+	}
+	query := `
+		SELECT p.max_videos, p.max_storage_gb
+		FROM merchants m
+		JOIN plans p ON p.id = m.plan_id
+		WHERE m.id = $1
+		FOR UPDATE OF m
+	`
+	if err := tx.Get(&quota, query, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "获取套餐配额失败",
+			Err:     err,
+		}
+	}
+	if quota.MaxVideos == 0 && quota.MaxStorageGB == 0 {
+		return nil
+	}
+
+	var usage struct {
+		Count int   `db:"count"`
+		Size  int64 `db:"size"`
+	}
+	query = `
+		SELECT
+			(SELECT COUNT(*) FROM videos WHERE tenant_id = $1)
+				+ (SELECT COUNT(*) FROM video_upload_sessions WHERE merchant_id = $1 AND status = 'pending' AND expires_at > NOW()) AS count,
+			(SELECT COALESCE(SUM(size), 0) FROM videos WHERE tenant_id = $1)
+				+ (SELECT COALESCE(SUM(size), 0) FROM video_upload_sessions WHERE merchant_id = $1 AND status = 'pending' AND expires_at > NOW()) AS size
+	`
+	if err := tx.Get(&usage, query, tenantID); err != nil {
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "获取已用配额失败",
+			Err:     err,
+		}
+	}
+
+	if quota.MaxVideos > 0 && usage.Count >= quota.MaxVideos {
+		return &ServiceError{
+			Type:    ErrTypeQuota,
+			Code:    ErrCodeQuotaExceeded,
+			Message: fmt.Sprintf("视频数量已达到套餐上限（%d个）", quota.MaxVideos),
+		}
+	}
+	if quota.MaxStorageGB > 0 && usage.Size+size > int64(quota.MaxStorageGB)<<30 {
+		return &ServiceError{
+			Type:    ErrTypeQuota,
+			Code:    ErrCodeQuotaExceeded,
+			Message: fmt.Sprintf("存储空间不足，套餐上限%dGB", quota.MaxStorageGB),
+		}
+	}
+
+	return nil
+}
+
+// lockSession 锁定直传会话，会话已被其他请求锁定时立即返回错误
+func (s *UploadService) lockSession(tx *sqlx.Tx, id, tenantID string) (entities.UploadSession, error) {
+	var session entities.UploadSession
+	query := "SELECT * FROM video_upload_sessions WHERE id = $1 AND merchant_id = $2 FOR UPDATE NOWAIT"
+	if err := tx.Get(&session, query, id, tenantID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pqLockNotAvailable {
+			return entities.UploadSession{}, &ServiceError{
+				Type:    ErrTypeConflict,
+				Code:    ErrCodeUploadLocked,
+				Message: "该上传会话正在处理中",
+				Err:     err,
+			}
+		}
+		return entities.UploadSession{}, sessionQueryError(err)
+	}
+
+	return session, nil
+}
+
+// failSession 将会话标记为失败并删除已上传的文件，返回校验错误
+func (s *UploadService) failSession(tx *sqlx.Tx, session entities.UploadSession, reason string) error {
+	query := `
+		UPDATE video_upload_sessions
+		SET status = $1, failure_reason = $2, updated_at = NOW()
+		WHERE id = $3
+	`
+	if _, err := tx.Exec(query, entities.UploadSessionFailed, reason, session.ID); err != nil {
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存上传会话状态失败",
+			Err:     err,
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存上传会话状态失败",
+			Err:     err,
+		}
+	}
+
+	if err := s.storageService.DeleteFile(session.FileKey); err != nil {
+		log.Printf("删除上传会话%s的文件失败: %v", session.ID, err)
+	}
+
+	return &ServiceError{
+		Type:    ErrTypeValidation,
+		Code:    ErrCodeInvalidInput,
+		Message: reason,
+	}
+}
+
+// discardSession 删除未完成会话已上传的分片或文件，失败只记录日志
+func (s *UploadService) discardSession(session entities.UploadSession) {
+	if session.Status != entities.UploadSessionPending {
+		return
+	}
+	if session.Multipart() {
+		if err := s.storageService.AbortMultipartUpload(session.FileKey, session.StorageUploadID); err != nil {
+			log.Printf("取消上传会话%s的分片上传失败: %v", session.ID, err)
+		}
+	}
+	// 单次上传的文件或已合并但未完成校验的文件
+	if err := s.storageService.DeleteFile(session.FileKey); err != nil {
+		log.Printf("删除上传会话%s的文件失败: %v", session.ID, err)
+	}
+}
+
+// purgeExpiredSessions 删除过期的直传会话，未完成的会话同时删除已上传的数据
+func (s *UploadService) purgeExpiredSessions() (int, error) {
+	query := `
+		DELETE FROM video_upload_sessions
+		WHERE id IN (
+			SELECT id FROM video_upload_sessions
+			WHERE expires_at < NOW()
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`
+
+	total := 0
+	for {
+		var sessions []entities.UploadSession
+		if err := s.db.Select(&sessions, query, uploadCleanupBatchSize); err != nil {
+			return total, fmt.Errorf("删除过期上传会话失败: %w", err)
+		}
+
+		for _, session := range sessions {
+			s.discardSession(session)
+		}
+		total += len(sessions)
+
+		if len(sessions) < uploadCleanupBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// objectChecksum 读取对象计算SHA-256
+func (s *UploadService) objectChecksum(fileKey string) (string, error) {
+	reader, err := s.storageService.GetObject(fileKey)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", fmt.Errorf("读取文件失败: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sessionParts 校验客户端提交的分片列表，必须按分片号完整提交全部分片的ETag
+func sessionParts(session entities.UploadSession, submitted []entities.UploadSessionPart) ([]storage.CompletedPart, error) {
+	if len(submitted) != session.PartCount {
+		return nil, &ServiceError{
+			Type:    ErrTypeValidation,
+			Code:    ErrCodeInvalidInput,
+			Message: fmt.Sprintf("需要提交%d个分片的ETag", session.PartCount),
+		}
+	}
+
+	parts := make([]storage.CompletedPart, session.PartCount)
+	for _, part := range submitted {
+		if part.PartNumber < 1 || part.PartNumber > session.PartCount || part.ETag == "" || parts[part.PartNumber-1].ETag != "" {
+			return nil, &ServiceError{
+				Type:    ErrTypeValidation,
+				Code:    ErrCodeInvalidInput,
+				Message: fmt.Sprintf("分片%d无效或重复", part.PartNumber),
+			}
+		}
+		parts[part.PartNumber-1] = storage.CompletedPart{Number: part.PartNumber, ETag: part.ETag}
+	}
+
+	return parts, nil
+}
+
+// sessionQueryError 转换直传会话的查询错误
+func sessionQueryError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return &ServiceError{
+			Type:    ErrTypeNotFound,
+			Code:    ErrCodeResourceNotFound,
+			Message: "上传会话不存在",
+			Err:     err,
+		}
+	}
+	return &ServiceError{
+		Type:    ErrTypeDatabase,
+		Code:    ErrCodeDBQuery,
+		Message: "获取上传会话失败",
+		Err:     err,
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"errors"
 	"fmt"
+	"log"
 	"mime/multipart"
 	"path/filepath"
 	"time"
@@ -26,6 +27,8 @@ const (
 	ErrTypeNotFound     = "not_found_error"
 	ErrTypeTranscode    = "transcode_error"
 	ErrTypeUnauthorized = "unauthorized_error"
+	ErrTypeConflict     = "conflict_error"
+	ErrTypeQuota        = "quota_error"
 
 	// 错误代码
 	ErrCodeDBConnection     = "db_connection_failed"
@@ -38,6 +41,10 @@ const (
 	ErrCodeResourceNotFound = "resource_not_found"
 	ErrCodeTranscodeFailed  = "transcode_failed"
 	ErrCodeUnauthorized     = "unauthorized_access"
+	ErrCodeUploadOffset     = "upload_offset_mismatch"
+	ErrCodeUploadLocked     = "upload_locked"
+	ErrCodeUploadExpired    = "upload_expired"
+	ErrCodeQuotaExceeded    = "quota_exceeded"
 )
 
 // ServiceError 服务错误结构
@@ -194,7 +201,50 @@ func (s *VideoService) Create(tenantID string, file *multipart.FileHeader, dto e
 		UpdatedAt:       time.Now(),
 	}
 
-	// 保存到数据库
+	return s.insertVideo(video, fileKey)
+}
+
+// CreateFromObject 为已上传到对象存储的文件创建视频记录并启动转码
+// 用于断点续传等不经过multipart表单的上传方式，校验规则与Create一致
+func (s *VideoService) CreateFromObject(tenantID string, videoID uuid.UUID, fileKey, fileName, fileType string, size int64, dto entities.CreateVideoDTO) (entities.Video, error) {
+	if dto.Title == "" {
+		return entities.Video{}, &ServiceError{
+			Type:    ErrTypeValidation,
+			Code:    ErrCodeInvalidInput,
+			Message: "标题不能为空",
+		}
+	}
+
+	tenantUUID, err := uuid.Parse(tenantID)
+	if err != nil {
+		return entities.Video{}, &ServiceError{
+			Type:    ErrTypeValidation,
+			Code:    ErrCodeInvalidInput,
+			Message: "无效的租户ID格式",
+			Err:     err,
+		}
+	}
+
+	video := entities.Video{
+		ID:              videoID,
+		TenantID:        tenantUUID,
+		Title:           dto.Title,
+		Description:     dto.Description,
+		FileName:        fileName,
+		FileKey:         fileKey,
+		FileType:        fileType,
+		Size:            size,
+		IsTranscoded:    false,
+		TranscodeStatus: entities.TranscodeStatusPending,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	return s.insertVideo(video, "")
+}
+
+// insertVideo 保存视频记录并加入转码队列，保存失败时删除cleanupKey指定的已上传文件
+func (s *VideoService) insertVideo(video entities.Video, cleanupKey string) (entities.Video, error) {
 	query := `
 		INSERT INTO videos (
 			id, tenant_id, title, description, file_name, file_key, file_type, 
@@ -207,10 +257,11 @@ func (s *VideoService) Create(tenantID string, file *multipart.FileHeader, dto e
 		) RETURNING *
 	`
 
-	rows, err := s.db.NamedQuery(query, video)
+	tx, err := s.db.Beginx()
 	if err != nil {
-		// 删除已上传的文件
-		_ = s.storageService.DeleteFile(fileKey)
+		if cleanupKey != "" {
+			_ = s.storageService.DeleteFile(cleanupKey)
+		}
 		return entities.Video{}, &ServiceError{
 			Type:    ErrTypeDatabase,
 			Code:    ErrCodeDBQuery,
@@ -218,34 +269,45 @@ func (s *VideoService) Create(tenantID string, file *multipart.FileHeader, dto e
 			Err:     err,
 		}
 	}
-	defer rows.Close()
-
-	if rows.Next() {
-		var result entities.Video
-		if err := rows.StructScan(&result); err != nil {
-			return entities.Video{}, &ServiceError{
-				Type:    ErrTypeDatabase,
-				Code:    ErrCodeDBQuery,
-				Message: "读取视频信息失败",
-				Err:     err,
-			}
-		}
-
-		// 创建成功后，启动视频转码过程
-		go func() {
-			if err := s.transcodeService.TranscodeVideo(result.ID.String(), result.TenantID.String()); err != nil {
-				fmt.Printf("启动视频转码失败: %v\n", err)
-			}
-		}()
+	defer tx.Rollback()
 
-		return result, nil
+	var result entities.Video
+	query, args, err := tx.BindNamed(query, video)
+	if err == nil {
+		err = tx.Get(&result, query, args...)
+	}
+	if err != nil {
+		// 删除已上传的文件
+		if cleanupKey != "" {
+			_ = s.storageService.DeleteFile(cleanupKey)
+		}
+		return entities.Video{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "保存视频信息失败",
+			Err:     err,
+		}
 	}
 
-	return entities.Video{}, &ServiceError{
-		Type:    ErrTypeDatabase,
-		Code:    ErrCodeDBQuery,
-		Message: "创建视频失败",
+	// 转码任务与视频记录在同一事务中创建，服务重启后由转码队列继续处理
+	_, err = s.transcodeService.enqueue(tx, result, "")
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		if cleanupKey != "" {
+			_ = s.storageService.DeleteFile(cleanupKey)
+		}
+		return entities.Video{}, &ServiceError{
+			Type:    ErrTypeDatabase,
+			Code:    ErrCodeDBQuery,
+			Message: "创建转码任务失败",
+			Err:     err,
+		}
 	}
+
+	s.transcodeService.notify()
+	return result, nil
 }
 
 // FindAll 获取租户所有视频
@@ -302,6 +364,12 @@ func (s *VideoService) Remove(id string, tenantID string) error {
 		return err
 	}
 
+	// 转码输出随视频记录级联删除，先查出需要删除的文件
+	renditions, err := s.FindRenditions(id, tenantID)
+	if err != nil {
+		return err
+	}
+
 	// 从数据库删除记录
 	query := "DELETE FROM videos WHERE id = $1 AND tenant_id = $2"
 	result, err := s.db.Exec(query, id, tenantID)
@@ -323,11 +391,48 @@ func (s *VideoService) Remove(id string, tenantID string) error {
 		return fmt.Errorf("删除视频文件失败: %w", err)
 	}
 
+	// 删除各分辨率的MP4文件和HLS切片，失败只记录日志，不影响删除结果
+	for _, rendition := range renditions {
+		if err := s.storageService.DeleteFile(rendition.FileKey); err != nil {
+			log.Printf("删除%s分辨率文件失败: %v", rendition.Name, err)
+		}
+	}
+	if err := s.storageService.DeletePrefix(hlsPrefix(tenantID, id)); err != nil {
+		log.Printf("删除HLS文件失败: %v", err)
+	}
+	if err := s.storageService.DeletePrefix(derivativePrefix(tenantID, id)); err != nil {
+		log.Printf("删除衍生视频失败: %v", err)
+	}
+
 	return nil
 }
 
+// FindRenditions 获取视频各分辨率的转码输出
+func (s *VideoService) FindRenditions(videoID, tenantID string) ([]entities.VideoRendition, error) {
+	renditions := []entities.VideoRendition{}
+	query := `
+		SELECT * FROM video_renditions
+		WHERE video_id = $1 AND merchant_id = $2
+		ORDER BY height DESC
+	`
+	if err := s.db.Select(&renditions, query, videoID, tenantID); err != nil {
+		return nil, fmt.Errorf("获取视频转码输出失败: %w", err)
+	}
+
+	return renditions, nil
+}
+
 // GetVideoURL 获取视频访问URL
+// 视频已打包HLS且配置了播放列表地址时返回签名的主播放列表地址，否则返回MP4地址
 func (s *VideoService) GetVideoURL(video entities.Video) string {
+	if url := s.hlsPlaybackURL(video); url != "" {
+		return url
+	}
+	return s.GetMP4URL(video)
+}
+
+// GetMP4URL 获取视频MP4文件的访问URL，供不支持HLS的播放器回退使用
+func (s *VideoService) GetMP4URL(video entities.Video) string {
 	url, err := s.storageService.GetFileURL(video.FileKey)
 	if err != nil {
 		// 如果获取URL失败，返回一个固定的错误URL
@@ -345,6 +450,21 @@ func (s *VideoService) GetFileURL(fileKey string) string {
 	return url
 }
 
+// Start 启动转码队列
+func (s *VideoService) Start() {
+	s.transcodeService.Start()
+}
+
+// Stop 停止转码队列
+func (s *VideoService) Stop() {
+	s.transcodeService.Stop()
+}
+
+// CancelTranscode 取消视频未完成的转码任务
+func (s *VideoService) CancelTranscode(videoID, tenantID string) (entities.TranscodeJob, error) {
+	return s.transcodeService.CancelVideoTranscode(videoID, tenantID)
+}
+
 // StartTranscode 手动开始视频转码
 func (s *VideoService) StartTranscode(videoID, tenantID string) error {
 	// 查询视频信息
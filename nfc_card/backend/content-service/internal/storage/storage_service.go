@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"content-service/internal/config"
@@ -76,6 +79,22 @@ func (s *StorageService) UploadFile(file *multipart.FileHeader, objectKey string
 	return nil
 }
 
+// UploadLocalFile 上传本地文件到对象存储
+func (s *StorageService) UploadLocalFile(filePath, objectKey, contentType string) error {
+	_, err := s.client.FPutObject(
+		context.Background(),
+		s.bucketName,
+		objectKey,
+		filePath,
+		minio.PutObjectOptions{ContentType: contentType},
+	)
+	if err != nil {
+		return fmt.Errorf("上传文件失败: %w", err)
+	}
+
+	return nil
+}
+
 // GetFileURL 获取文件的访问URL
 func (s *StorageService) GetFileURL(objectKey string) (string, error) {
 	// 获取预签名URL
@@ -93,6 +112,22 @@ func (s *StorageService) GetFileURL(objectKey string) (string, error) {
 	return url.String(), nil
 }
 
+// GetFileURLWithExpiry 获取指定有效期的文件访问URL
+func (s *StorageService) GetFileURLWithExpiry(objectKey string, expiry time.Duration) (string, error) {
+	url, err := s.client.PresignedGetObject(
+		context.Background(),
+		s.bucketName,
+		objectKey,
+		expiry,
+		nil,
+	)
+	if err != nil {
+		return "", fmt.Errorf("获取文件URL失败: %w", err)
+	}
+
+	return url.String(), nil
+}
+
 // DeleteFile 从对象存储中删除文件
 func (s *StorageService) DeleteFile(objectKey string) error {
 	err := s.client.RemoveObject(
@@ -122,3 +157,121 @@ func (s *StorageService) GetObject(objectKey string) (io.ReadCloser, error) {
 
 	return obj, nil
 }
+
+// DeletePrefix 删除指定前缀下的所有文件，用于删除HLS切片等目录
+func (s *StorageService) DeletePrefix(prefix string) error {
+	ctx := context.Background()
+	objects := s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true})
+	for result := range s.client.RemoveObjects(ctx, s.bucketName, objects, minio.RemoveObjectsOptions{}) {
+		if result.Err != nil {
+			return fmt.Errorf("删除文件%s失败: %w", result.ObjectName, result.Err)
+		}
+	}
+
+	return nil
+}
+
+// PutObject 上传数据流到对象存储
+func (s *StorageService) PutObject(objectKey string, reader io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(
+		context.Background(),
+		s.bucketName,
+		objectKey,
+		reader,
+		size,
+		minio.PutObjectOptions{ContentType: contentType},
+	)
+	if err != nil {
+		return fmt.Errorf("上传文件失败: %w", err)
+	}
+
+	return nil
+}
+
+// StatObject 获取文件大小，文件不存在时返回错误
+func (s *StorageService) StatObject(objectKey string) (int64, error) {
+	info, err := s.client.StatObject(context.Background(), s.bucketName, objectKey, minio.StatObjectOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("获取文件信息失败: %w", err)
+	}
+
+	return info.Size, nil
+}
+
+// NewMultipartUpload 创建分片上传，返回分片上传ID
+func (s *StorageService) NewMultipartUpload(objectKey, contentType string) (string, error) {
+	core := minio.Core{Client: s.client}
+	uploadID, err := core.NewMultipartUpload(context.Background(), s.bucketName, objectKey, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return "", fmt.Errorf("创建分片上传失败: %w", err)
+	}
+
+	return uploadID, nil
+}
+
+// PutObjectPart 上传一个分片，返回分片的ETag
+// 除最后一个分片外，每个分片不能小于5MB
+func (s *StorageService) PutObjectPart(objectKey, uploadID string, partNumber int, reader io.Reader, size int64) (string, error) {
+	core := minio.Core{Client: s.client}
+	part, err := core.PutObjectPart(context.Background(), s.bucketName, objectKey, uploadID, partNumber, reader, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return "", fmt.Errorf("上传分片%d失败: %w", partNumber, err)
+	}
+
+	return part.ETag, nil
+}
+
+// CompletedPart 已上传的分片
+type CompletedPart struct {
+	Number int
+	ETag   string
+}
+
+// CompleteMultipartUpload 合并分片，生成完整文件
+func (s *StorageService) CompleteMultipartUpload(objectKey, uploadID string, parts []CompletedPart) error {
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.Number, ETag: part.ETag})
+	}
+
+	core := minio.Core{Client: s.client}
+	if _, err := core.CompleteMultipartUpload(context.Background(), s.bucketName, objectKey, uploadID, completeParts, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("合并分片失败: %w", err)
+	}
+
+	return nil
+}
+
+// AbortMultipartUpload 取消分片上传并删除已上传的分片
+func (s *StorageService) AbortMultipartUpload(objectKey, uploadID string) error {
+	core := minio.Core{Client: s.client}
+	if err := core.AbortMultipartUpload(context.Background(), s.bucketName, objectKey, uploadID); err != nil {
+		return fmt.Errorf("取消分片上传失败: %w", err)
+	}
+
+	return nil
+}
+
+// PresignedPutURL 生成直接上传文件的预签名URL
+func (s *StorageService) PresignedPutURL(objectKey string, expiry time.Duration) (string, error) {
+	url, err := s.client.PresignedPutObject(context.Background(), s.bucketName, objectKey, expiry)
+	if err != nil {
+		return "", fmt.Errorf("生成上传URL失败: %w", err)
+	}
+
+	return url.String(), nil
+}
+
+// PresignedPartURL 生成直接上传分片的预签名URL
+func (s *StorageService) PresignedPartURL(objectKey, uploadID string, partNumber int, expiry time.Duration) (string, error) {
+	params := url.Values{}
+	params.Set("partNumber", strconv.Itoa(partNumber))
+	params.Set("uploadId", uploadID)
+
+	presigned, err := s.client.Presign(context.Background(), http.MethodPut, s.bucketName, objectKey, expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("生成分片上传URL失败: %w", err)
+	}
+
+	return presigned.String(), nil
+}
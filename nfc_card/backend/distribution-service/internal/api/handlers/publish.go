@@ -81,13 +81,14 @@ func (h *PublishHandler) ListJobs(c *gin.Context) {
 	status := c.Query("status")
 	videoID := c.Query("videoId")
 	nfcCardID := c.Query("nfcCardId")
+	storeID := c.Query("storeId")
 	channel := c.Query("channel")
 
 	// 模拟租户ID
 	tenantID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
 
 	// 查询任务
-	jobs, err := h.publishService.ListJobs(c.Request.Context(), tenantID, status, videoID, nfcCardID, channel)
+	jobs, err := h.publishService.ListJobs(c.Request.Context(), tenantID, status, videoID, nfcCardID, storeID, channel)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
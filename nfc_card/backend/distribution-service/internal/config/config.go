@@ -20,6 +20,7 @@ type Config struct {
 	// 兼容旧代码
 	Adapters PlatformsConfig
 	Storage  StorageConfig
+	Content  ContentConfig
 }
 
 // ServerConfig 服务器配置
@@ -129,6 +130,15 @@ type StorageConfig struct {
 	S3SecretKey string
 }
 
+// ContentConfig content-service配置，用于获取与渠道输出规格匹配的衍生视频
+type ContentConfig struct {
+	ServiceURL            string `yaml:"serviceURL"`            // content-service地址，为空时直接分发原始视频
+	InternalToken         string `yaml:"internalToken"`         // 服务间调用令牌，为空时读取环境变量INTERNAL_API_TOKEN
+	RequestTimeoutSeconds int    `yaml:"requestTimeoutSeconds"` // 单次请求超时时间（秒）
+	DerivativeWaitMinutes int    `yaml:"derivativeWaitMinutes"` // 等待衍生视频生成的最长时间（分钟）
+	PollIntervalSeconds   int    `yaml:"pollIntervalSeconds"`   // 查询衍生视频生成状态的间隔（秒）
+}
+
 // LoadConfig 从文件加载配置
 func LoadConfig(configPath string) (*Config, error) {
 	data, err := os.ReadFile(configPath)
@@ -141,6 +151,10 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("解析配置文件错误: %w", err)
 	}
 
+	if config.Content.InternalToken == "" {
+		config.Content.InternalToken = os.Getenv("INTERNAL_API_TOKEN")
+	}
+
 	return &config, nil
 }
 
@@ -0,0 +1,93 @@
+package content
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 衍生视频状态
+const (
+	DerivativeReady     = "ready"
+	DerivativeNone      = "none"
+	DerivativeQueued    = "queued"
+	DerivativeRunning   = "running"
+	DerivativeFailed    = "failed"
+	DerivativeCancelled = "cancelled"
+)
+
+// Derivative content-service返回的衍生视频状态
+// Status为ready时URL为衍生视频的临时下载地址
+type Derivative struct {
+	Profile string `json:"profile"`
+	Status  string `json:"status"`
+	URL     string `json:"url"`
+	Error   string `json:"error"`
+}
+
+// Client content-service内部接口的客户端
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// NewClient 创建content-service客户端，token为服务间调用令牌
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// RequestDerivative 请求生成视频在指定输出规格下的衍生视频，已生成或生成中时返回当前状态
+func (c *Client) RequestDerivative(ctx context.Context, merchantID, videoID uuid.UUID, profile string) (*Derivative, error) {
+	return c.derivative(ctx, http.MethodPost, merchantID, videoID, profile)
+}
+
+// GetDerivative 查询视频在指定输出规格下的衍生视频状态
+func (c *Client) GetDerivative(ctx context.Context, merchantID, videoID uuid.UUID, profile string) (*Derivative, error) {
+	return c.derivative(ctx, http.MethodGet, merchantID, videoID, profile)
+}
+
+// derivative 调用衍生视频接口
+func (c *Client) derivative(ctx context.Context, method string, merchantID, videoID uuid.UUID, profile string) (*Derivative, error) {
+	endpoint := fmt.Sprintf("%s/internal/v1/videos/%s/derivatives/%s?merchantId=%s",
+		c.BaseURL, url.PathEscape(videoID.String()), url.PathEscape(profile), url.QueryEscape(merchantID.String()))
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建衍生视频请求失败: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Internal-Token", c.Token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求content-service失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("读取content-service响应失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return nil, fmt.Errorf("content-service返回状态码%d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var derivative Derivative
+	if err := json.Unmarshal(body, &derivative); err != nil {
+		return nil, fmt.Errorf("解析衍生视频状态失败: %w", err)
+	}
+	return &derivative, nil
+}
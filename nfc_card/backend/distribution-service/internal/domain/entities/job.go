@@ -25,6 +25,8 @@ type PublishJob struct {
 	MaxRetries  int        `json:"maxRetries" db:"max_retries"`    // 最大重试次数
 	NextRetryAt *time.Time `json:"nextRetryAt" db:"next_retry_at"` // 下次重试时间
 	LastError   string     `json:"lastError" db:"last_error"`      // 最后一次错误
+	// StoreID 创建任务时卡片所属的门店，由数据库根据卡片填充
+	StoreID *uuid.UUID `json:"storeId" db:"store_id"`
 }
 
 // NewPublishJob 创建新的分发任务
@@ -23,7 +23,7 @@ type JobRepository interface {
 	FindByID(ctx context.Context, tenantID, jobID uuid.UUID) (*entities.PublishJob, error)
 
 	// Find 查找任务列表
-	Find(ctx context.Context, tenantID uuid.UUID, status, videoID, nfcCardID, channel string) ([]*entities.PublishJob, error)
+	Find(ctx context.Context, tenantID uuid.UUID, status, videoID, nfcCardID, storeID, channel string) ([]*entities.PublishJob, error)
 }
 
 // PostgresJobRepository PostgreSQL任务仓库实现
@@ -55,16 +55,23 @@ func (r *PostgresJobRepository) Create(ctx context.Context, job *entities.Publis
 	query := `
 		INSERT INTO publish_jobs (
 			id, tenant_id, video_id, nfc_card_id, channel, status, 
-			result, error_msg, created_at, updated_at
+			result, error_msg, created_at, updated_at, store_id
 		) VALUES (
 			:id, :tenant_id, :video_id, :nfc_card_id, :channel, :status, 
-			:result, :error_msg, :created_at, :updated_at
+			:result, :error_msg, :created_at, :updated_at,
+			(SELECT store_id FROM nfc_cards WHERE id = :nfc_card_id)
 		)
+		RETURNING store_id
 	`
 
-	// 执行SQL
-	_, err := r.db.NamedExecContext(ctx, query, job)
-	return err
+	// 执行SQL，门店取卡片当前的分配，之后换店不影响已创建的任务
+	stmt, err := r.db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.GetContext(ctx, &job.StoreID, job)
 }
 
 // Update 更新任务
@@ -104,7 +111,7 @@ func (r *PostgresJobRepository) FindByID(ctx context.Context, tenantID, jobID uu
 }
 
 // Find 查找任务列表
-func (r *PostgresJobRepository) Find(ctx context.Context, tenantID uuid.UUID, status, videoID, nfcCardID, channel string) ([]*entities.PublishJob, error) {
+func (r *PostgresJobRepository) Find(ctx context.Context, tenantID uuid.UUID, status, videoID, nfcCardID, storeID, channel string) ([]*entities.PublishJob, error) {
 	// 构建基础SQL
 	query := `
 		SELECT * FROM publish_jobs
@@ -142,6 +149,16 @@ func (r *PostgresJobRepository) Find(ctx context.Context, tenantID uuid.UUID, st
 		argIndex++
 	}
 
+	if storeID != "" {
+		query += fmt.Sprintf(" AND store_id = $%d", argIndex)
+		storeUUID, err := uuid.Parse(storeID)
+		if err != nil {
+			return nil, fmt.Errorf("无效的门店ID: %w", err)
+		}
+		args = append(args, storeUUID)
+		argIndex++
+	}
+
 	if channel != "" {
 		query += fmt.Sprintf(" AND channel = $%d", argIndex)
 		args = append(args, channel)
@@ -46,10 +46,10 @@ func (h *PublishTaskHandler) HandleTask(ctx context.Context, task *kafka.TaskMes
 		return fmt.Errorf("更新任务状态失败: %w", err)
 	}
 
-	// 查询视频信息
-	video, err := h.publishService.GetVideo(ctx, job.TenantID, job.VideoID)
+	// 查询视频信息，与PublishService.processJob一样使用渠道衍生视频
+	video, err := h.publishService.GetPublishVideo(ctx, &job)
 	if err != nil {
-		errorMsg := fmt.Sprintf("查询视频信息失败: %v", err)
+		errorMsg := err.Error()
 		h.publishService.UpdateJobStatus(ctx, &job, "failed", errorMsg)
 		return errors.New(errorMsg)
 	}
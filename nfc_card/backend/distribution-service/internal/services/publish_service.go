@@ -13,6 +13,7 @@ import (
 	"distribution-service/internal/adapters/wechat"
 	"distribution-service/internal/adapters/xiaohongshu"
 	"distribution-service/internal/config"
+	"distribution-service/internal/content"
 	"distribution-service/internal/domain/entities"
 	"distribution-service/internal/domain/repositories"
 	"distribution-service/internal/storage"
@@ -57,6 +58,10 @@ type PublishService struct {
 	wechatAdapter      PlatformAdapter
 	kafkaProducer      KafkaProducer
 	storageService     storage.StorageService
+	// contentClient 为空时直接分发原始视频
+	contentClient  *content.Client
+	derivativeWait time.Duration
+	derivativePoll time.Duration
 }
 
 // NewPublishService 创建发布服务
@@ -73,7 +78,7 @@ func NewPublishService(
 	xiaohongshuAdapter := xiaohongshu.NewXiaohongshuAdapter(config.Adapters.Xiaohongshu, config.Adapters.TempDir)
 	wechatAdapter := wechat.NewWechatAdapter(config.Adapters.Wechat, config.Adapters.TempDir)
 
-	return &PublishService{
+	service := &PublishService{
 		jobRepository:      jobRepo,
 		videoRepository:    videoRepo,
 		douyinAdapter:      douyinAdapter,
@@ -82,7 +87,26 @@ func NewPublishService(
 		wechatAdapter:      wechatAdapter,
 		kafkaProducer:      kafkaProducer,
 		storageService:     storageService,
+		derivativeWait:     30 * time.Minute,
+		derivativePoll:     10 * time.Second,
 	}
+
+	// 配置content-service后按渠道输出规格分发衍生视频
+	if config.Content.ServiceURL != "" {
+		timeout := 10 * time.Second
+		if config.Content.RequestTimeoutSeconds > 0 {
+			timeout = time.Duration(config.Content.RequestTimeoutSeconds) * time.Second
+		}
+		if config.Content.DerivativeWaitMinutes > 0 {
+			service.derivativeWait = time.Duration(config.Content.DerivativeWaitMinutes) * time.Minute
+		}
+		if config.Content.PollIntervalSeconds > 0 {
+			service.derivativePoll = time.Duration(config.Content.PollIntervalSeconds) * time.Second
+		}
+		service.contentClient = content.NewClient(config.Content.ServiceURL, config.Content.InternalToken, timeout)
+	}
+
+	return service
 }
 
 // SetKafkaProducer 设置Kafka生产者
@@ -126,8 +150,8 @@ func (s *PublishService) CreateJob(ctx context.Context, job *entities.PublishJob
 }
 
 // ListJobs 获取分发任务列表
-func (s *PublishService) ListJobs(ctx context.Context, tenantID uuid.UUID, status, videoID, nfcCardID, channel string) ([]*entities.PublishJob, error) {
-	return s.jobRepository.Find(ctx, tenantID, status, videoID, nfcCardID, channel)
+func (s *PublishService) ListJobs(ctx context.Context, tenantID uuid.UUID, status, videoID, nfcCardID, storeID, channel string) ([]*entities.PublishJob, error) {
+	return s.jobRepository.Find(ctx, tenantID, status, videoID, nfcCardID, storeID, channel)
 }
 
 // GetJob 获取单个分发任务
@@ -282,11 +306,11 @@ func (s *PublishService) processJob(job *entities.PublishJob) {
 		return
 	}
 
-	// 查询视频信息
-	video, err := s.videoRepository.FindByID(ctx, job.TenantID, job.VideoID)
+	// 查询视频信息，使用与渠道输出规格匹配的衍生视频
+	video, err := s.GetPublishVideo(ctx, job)
 	if err != nil {
-		log.Printf("查询视频信息失败: %v", err)
-		s.UpdateJobStatus(ctx, job, "failed", fmt.Sprintf("查询视频信息失败: %v", err))
+		log.Printf("获取%s渠道发布视频失败: %v", job.Channel, err)
+		s.UpdateJobStatus(ctx, job, "failed", err.Error())
 		return
 	}
 
@@ -338,11 +362,67 @@ func (s *PublishService) processJob(job *entities.PublishJob) {
 	s.UpdateJobStatus(ctx, job, "completed", "")
 }
 
+// channelVideo 请求生成与任务渠道输出规格匹配的衍生视频，等待生成完成后返回其下载地址
+func (s *PublishService) channelVideo(ctx context.Context, job *entities.PublishJob) (string, error) {
+	derivative, err := s.contentClient.RequestDerivative(ctx, job.TenantID, job.VideoID, job.Channel)
+	if err != nil {
+		return "", err
+	}
+
+	deadline := time.Now().Add(s.derivativeWait)
+	for {
+		switch derivative.Status {
+		case content.DerivativeReady:
+			if derivative.URL == "" {
+				return "", fmt.Errorf("衍生视频地址为空")
+			}
+			return derivative.URL, nil
+		case content.DerivativeFailed, content.DerivativeCancelled:
+			return "", fmt.Errorf("衍生视频生成失败: %s", derivative.Error)
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("等待衍生视频生成超时")
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(s.derivativePoll):
+		}
+
+		// 查询失败时保留上一次状态，继续等待
+		latest, err := s.contentClient.GetDerivative(ctx, job.TenantID, job.VideoID, job.Channel)
+		if err != nil {
+			log.Printf("查询衍生视频状态失败: %v", err)
+			continue
+		}
+		derivative = latest
+	}
+}
+
 // GetVideo 获取视频信息
 func (s *PublishService) GetVideo(ctx context.Context, tenantID, videoID uuid.UUID) (*entities.Video, error) {
 	return s.videoRepository.FindByID(ctx, tenantID, videoID)
 }
 
+// GetPublishVideo 获取任务要发布的视频，配置了内容服务时存储路径替换为该渠道衍生视频的下载地址
+func (s *PublishService) GetPublishVideo(ctx context.Context, job *entities.PublishJob) (*entities.Video, error) {
+	video, err := s.videoRepository.FindByID(ctx, job.TenantID, job.VideoID)
+	if err != nil {
+		return nil, fmt.Errorf("查询视频信息失败: %w", err)
+	}
+
+	if s.contentClient != nil {
+		url, err := s.channelVideo(ctx, job)
+		if err != nil {
+			return nil, fmt.Errorf("获取渠道衍生视频失败: %w", err)
+		}
+		video.StoragePath = url
+	}
+	return video, nil
+}
+
 // PublishToDouyin 发布到抖音
 func (s *PublishService) PublishToDouyin(ctx context.Context, job *entities.PublishJob, video *entities.Video) error {
 	if s.douyinAdapter == nil {
@@ -0,0 +1,204 @@
+// redirect-bench 对短链接重定向接口进行压测，输出吞吐量和延迟分布
+//
+// 用法:
+//
+//	go run ./cmd/redirect-bench -base http://localhost:8083/api/v1/r -slugs abc123,def456 -c 100 -d 30s
+//
+// 建议分别在冷缓存（服务刚启动）和热缓存下运行，对比缓存对重定向延迟的影响；
+// 通过 -missing 混入一定比例不存在的slug，可以观察负缓存对数据库压力的影响。
+//
+// 重定向接口经过刷量防护：同一IP默认每分钟只允许30次请求（突发10次），超出返回429，
+// 爬虫User-Agent会返回分享预览页而不是跳转。压测时所有请求来自同一IP，因此：
+//   - 请求默认使用浏览器User-Agent（-ua），保证测量的是跳转路径；
+//   - 压测前在被测服务的配置中调大 tap_guard.ip_rate_per_minute 和 tap_guard.ip_burst
+//     （或设置 TAP_GUARD_IP_RATE_PER_MINUTE、TAP_GUARD_IP_BURST 环境变量）后重启服务，
+//     不要通过管理接口修改线上商户的刷量防护设置。
+//
+// 缓存查询和重定向处理的基准测试见 internal/services/shortlinks 和 internal/api/handlers 中的 Benchmark*，
+// 通过 go test -bench . 运行，不依赖数据库。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// browserUserAgent 默认的浏览器User-Agent，刷量防护和预览判断都按正常访问者处理
+const browserUserAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+
+// workerResult 单个压测协程的结果
+type workerResult struct {
+	latencies []time.Duration
+	statuses  map[int]int
+	errors    int
+}
+
+func main() {
+	baseURL := flag.String("base", "http://localhost:8083/api/v1/r", "重定向接口地址，slug会拼接在其后")
+	slugList := flag.String("slugs", "", "逗号分隔的slug列表，请求时随机选择")
+	concurrency := flag.Int("c", 50, "并发数")
+	duration := flag.Duration("d", 30*time.Second, "压测时长")
+	total := flag.Int("n", 0, "总请求数，大于0时忽略-d")
+	missingRatio := flag.Float64("missing", 0, "请求不存在slug的比例(0-1)")
+	userAgent := flag.String("ua", browserUserAgent, "请求使用的User-Agent")
+	flag.Parse()
+
+	slugs := splitSlugs(*slugList)
+	if len(slugs) == 0 {
+		fmt.Fprintln(os.Stderr, "必须通过-slugs指定至少一个slug")
+		os.Exit(2)
+	}
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        *concurrency,
+			MaxIdleConnsPerHost: *concurrency,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// 只测量重定向接口本身，不跟随跳转
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	base := strings.TrimRight(*baseURL, "/")
+	deadline := time.Now().Add(*duration)
+	var issued atomic.Int64
+
+	results := make([]*workerResult, *concurrency)
+	var wg sync.WaitGroup
+
+	log.Printf("开始压测: %s, 并发: %d, slug数量: %d", base, *concurrency, len(slugs))
+	start := time.Now()
+
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+
+			rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(idx)))
+			result := &workerResult{statuses: make(map[int]int)}
+			results[idx] = result
+
+			for {
+				if *total > 0 {
+					if issued.Add(1) > int64(*total) {
+						return
+					}
+				} else if time.Now().After(deadline) {
+					return
+				}
+
+				slug := slugs[rng.Intn(len(slugs))]
+				if *missingRatio > 0 && rng.Float64() < *missingRatio {
+					slug = fmt.Sprintf("missing-%d", rng.Int63())
+				}
+
+				req, err := http.NewRequest(http.MethodGet, base+"/"+slug, nil)
+				if err != nil {
+					result.errors++
+					continue
+				}
+				req.Header.Set("User-Agent", *userAgent)
+				req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+				reqStart := time.Now()
+				resp, err := client.Do(req)
+				if err != nil {
+					result.errors++
+					continue
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+
+				result.latencies = append(result.latencies, time.Since(reqStart))
+				result.statuses[resp.StatusCode]++
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	report(results, time.Since(start))
+}
+
+// splitSlugs 解析逗号分隔的slug列表
+func splitSlugs(value string) []string {
+	var slugs []string
+	for _, slug := range strings.Split(value, ",") {
+		if slug = strings.TrimSpace(slug); slug != "" {
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
+
+// report 汇总并输出压测结果
+func report(results []*workerResult, elapsed time.Duration) {
+	var latencies []time.Duration
+	statuses := make(map[int]int)
+	errors := 0
+
+	for _, result := range results {
+		latencies = append(latencies, result.latencies...)
+		for status, count := range result.statuses {
+			statuses[status] += count
+		}
+		errors += result.errors
+	}
+
+	if len(latencies) == 0 {
+		fmt.Printf("没有成功的请求，错误数: %d\n", errors)
+		return
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var sum time.Duration
+	for _, latency := range latencies {
+		sum += latency
+	}
+
+	fmt.Printf("\n请求总数: %d, 错误数: %d, 耗时: %s\n", len(latencies)+errors, errors, elapsed.Round(time.Millisecond))
+	fmt.Printf("吞吐量:   %.1f req/s\n", float64(len(latencies))/elapsed.Seconds())
+	fmt.Printf("平均延迟: %s\n", (sum / time.Duration(len(latencies))).Round(time.Microsecond))
+	for _, p := range []float64{50, 90, 95, 99, 99.9} {
+		fmt.Printf("P%-5g    %s\n", p, percentile(latencies, p).Round(time.Microsecond))
+	}
+	fmt.Printf("最大延迟: %s\n", latencies[len(latencies)-1].Round(time.Microsecond))
+
+	codes := make([]int, 0, len(statuses))
+	for status := range statuses {
+		codes = append(codes, status)
+	}
+	sort.Ints(codes)
+	fmt.Println("状态码分布:")
+	for _, status := range codes {
+		fmt.Printf("  %d: %d\n", status, statuses[status])
+	}
+	if statuses[http.StatusTooManyRequests] > 0 {
+		fmt.Println("存在429响应：请求被刷量防护拒绝，结果包含限流路径，请在配置中调大tap_guard.ip_rate_per_minute和tap_guard.ip_burst后重新压测")
+	}
+}
+
+// percentile 计算已排序延迟的百分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sorted))*p/100+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
@@ -15,10 +15,27 @@ import (
 
 	"nfc-service/internal/api"
 	"nfc-service/internal/config"
+	"nfc-service/internal/messaging"
+	"nfc-service/internal/services/campaigns"
+	"nfc-service/internal/services/cardimport"
 	"nfc-service/internal/services/cards"
+	"nfc-service/internal/services/clicks"
+	"nfc-service/internal/services/edgesync"
+	"nfc-service/internal/services/landing"
+	"nfc-service/internal/services/qrcodes"
+	"nfc-service/internal/services/schedules"
 	"nfc-service/internal/services/shortlinks"
+	"nfc-service/internal/services/stores"
+	"nfc-service/internal/services/sun"
+	"nfc-service/internal/services/tagmanifest"
+	"nfc-service/internal/services/tapguard"
+	"nfc-service/internal/services/transfers"
+	"nfc-service/internal/services/webhooks"
 	"nfc-service/internal/storage"
 	"nfc-service/pkg/cloudflare"
+	"nfc-service/pkg/content"
+	"nfc-service/pkg/geoip"
+	"nfc-service/pkg/slug"
 )
 
 func main() {
@@ -61,12 +78,137 @@ func main() {
 	// 使用适配器创建领域仓库
 	domainCardRepo := cards.NewCardRepositoryAdapter(repos.CardRepository)
 
+	// 初始化Kafka客户端
+	var kafkaProducer clicks.KafkaProducer
+	kafkaClient, err := messaging.NewKafkaClient(&cfg.Kafka)
+	if err != nil {
+		logger.Printf("连接Kafka失败: %v, 将以无消息队列模式运行", err)
+	} else {
+		defer kafkaClient.Close()
+		kafkaProducer = kafkaClient
+	}
+
+	// 加载本地GeoIP数据库
+	var geoDB *geoip.DB
+	if cfg.Clicks.GeoIPDBPath != "" {
+		geoDB, err = geoip.Open(cfg.Clicks.GeoIPDBPath)
+		if err != nil {
+			logger.Printf("加载GeoIP数据库失败: %v, 点击事件将不包含地理位置", err)
+		} else {
+			logger.Printf("已加载GeoIP数据库，共%d个IP区间", geoDB.Len())
+		}
+	}
+
+	// 初始化Slug生成器
+	slugGen, err := slug.NewGenerator(cfg.ShortLink.SlugLength, cfg.ShortLink.SlugAlphabet, cfg.ShortLink.ReservedSlugs)
+	if err != nil {
+		logger.Fatalf("初始化Slug生成器失败: %v", err)
+	}
+
+	// 初始化Cloudflare Workers KV边缘同步
+	var edgeSyncService edgesync.Service
+	if cfg.Cloudflare.EdgeSyncEnabled() {
+		workersClient := cloudflare.NewWorkersClient(
+			cfg.Cloudflare.BaseURL,
+			cfg.Cloudflare.APIToken,
+			cfg.Cloudflare.AccountID,
+			cfg.Cloudflare.KVNamespaceID,
+		)
+		edgeSyncService = edgesync.NewEdgeSyncService(repos.ShortlinkRepository, repos.EdgeOutbox, repos.SUN, workersClient, cfg.Cloudflare, logger)
+		edgeSyncService.Start()
+	} else {
+		logger.Printf("未配置Cloudflare Workers KV，短链接仅由本服务提供重定向")
+	}
+
 	// 初始化服务层
-	cardService := cards.NewCardService(domainCardRepo, logger)
-	shortlinkService := shortlinks.NewShortlinkService(repos.ShortlinkRepository, cfClient, logger)
+	contentClient := content.NewClient(cfg.Landing.ContentServiceURL, cfg.Landing.InternalToken, time.Duration(cfg.Landing.RequestTimeoutSeconds)*time.Second)
+	shortlinkService := shortlinks.NewShortlinkService(
+		repos.ShortlinkRepository,
+		cfClient,
+		slugGen,
+		edgeSyncService,
+		kafkaProducer,
+		domainCardRepo,
+		contentClient,
+		cfg.ShortLink,
+		logger,
+	)
+	shortlinkService.Start()
+	cardService := cards.NewCardService(domainCardRepo, shortlinkService, kafkaProducer, cfg.Cards, logger)
+	cardService.Start()
+	clickService := clicks.NewClickService(repos.ClickRepository, kafkaProducer, geoDB, cfg.Clicks, logger)
+	clickService.Start()
+	cardImportService := cardimport.NewCardImportService(repos.CardImport, kafkaProducer, cfg.CardImport, logger)
+	cardImportService.Start()
+	campaignService := campaigns.NewCampaignService(repos.Campaign, domainCardRepo, shortlinkService, kafkaProducer, cfg.Campaigns, logger)
+	campaignService.Start()
+	tagManifestService := tagmanifest.NewTagManifestService(domainCardRepo, repos.ShortlinkRepository, cfg.ShortLink.BaseURL, logger)
+	qrCodeService := qrcodes.NewQRCodeService(domainCardRepo, repos.ShortlinkRepository, repos.SUN, repos.Merchant, cfg.QRCode, cfg.ShortLink.BaseURL, logger)
+	sunService, err := sun.NewSUNService(repos.SUN, repos.ShortlinkRepository, edgeSyncService, kafkaProducer, cfg.SUN, cfg.ShortLink.BaseURL, logger)
+	if err != nil {
+		logger.Fatalf("初始化SUN服务失败: %v", err)
+	}
+	storeService := stores.NewStoreService(repos.Store, domainCardRepo, logger)
+	tapGuardService := tapguard.NewTapGuardService(repos.TapGuard, cfg.TapGuard, logger)
+	tapGuardService.Start()
+	scheduleService := schedules.NewScheduleService(repos.Schedule, domainCardRepo, kafkaProducer, logger)
+	webhookService := webhooks.NewWebhookService(repos.Webhook, cfg.Webhooks, logger)
+	webhookService.Start()
+	transferService := transfers.NewCardTransferService(repos.CardTransfer, domainCardRepo, repos.Merchant, shortlinkService, kafkaProducer, cfg.Cards, logger)
+	landingService, err := landing.NewLandingService(domainCardRepo, repos.ShortlinkRepository, repos.LandingTemplate, repos.Merchant, contentClient, scheduleService, kafkaProducer, cfg.Landing, cfg.ShortLink.BaseURL, logger)
+	if err != nil {
+		logger.Fatalf("初始化落地页服务失败: %v", err)
+	}
+
+	// 消费卡片事件，为新创建（包括批量导入）的卡片创建默认短链接
+	// 消费发布任务事件，发布成功后为卡片短链接生成平台深度链接
+	if kafkaClient != nil {
+		kafkaClient.RegisterHandler(cardimport.TopicCardEvents, messaging.NewCardHandler(cardService, shortlinkService, campaignService, logger))
+		kafkaClient.RegisterHandler(messaging.TopicPublishEvents, messaging.NewPublishHandler(shortlinkService, logger))
+		kafkaClient.StartConsumers()
+	}
+
+	// 订阅卡片和短链接变更事件以失效本实例的重定向缓存
+	// 每个实例使用独立的消费者组，保证所有实例都能收到全部事件
+	if kafkaClient != nil {
+		hostname, err := os.Hostname()
+		if err != nil || hostname == "" {
+			hostname = strconv.FormatInt(time.Now().UnixNano(), 36)
+		}
+
+		cacheKafkaConfig := cfg.Kafka
+		cacheKafkaConfig.ConsumerGroup = fmt.Sprintf("%s-cache-%s", cfg.Kafka.ConsumerGroup, hostname)
+		cacheKafkaConfig.ConsumerTopics = []string{shortlinks.TopicCardEvents}
+
+		cacheConsumer, err := messaging.NewKafkaClient(&cacheKafkaConfig)
+		if err != nil {
+			logger.Printf("创建缓存失效消费者失败: %v, 缓存将仅依赖过期时间失效", err)
+		} else {
+			defer cacheConsumer.Close()
+			cacheConsumer.RegisterHandler(shortlinks.TopicCardEvents, messaging.NewCacheInvalidationHandler(shortlinkService, sunService, landingService, scheduleService, logger))
+			cacheConsumer.StartConsumers()
+		}
+
+		// 商户Webhook使用独立的消费者组，所有实例共用，每条消息只生成一次投递记录
+		webhookKafkaConfig := cfg.Kafka
+		webhookKafkaConfig.ConsumerGroup = cfg.Kafka.ConsumerGroup + "-webhooks"
+		webhookKafkaConfig.ConsumerTopics = []string{webhooks.TopicCardEvents, webhooks.TopicPublishEvents, webhooks.TopicStatsEvents}
+
+		webhookConsumer, err := messaging.NewKafkaClient(&webhookKafkaConfig)
+		if err != nil {
+			logger.Printf("创建Webhook消费者失败: %v, 商户Webhook将不会收到事件", err)
+		} else {
+			defer webhookConsumer.Close()
+			webhookHandler := messaging.NewWebhookHandler(webhookService, logger)
+			for _, topic := range webhookKafkaConfig.ConsumerTopics {
+				webhookConsumer.RegisterHandler(topic, webhookHandler)
+			}
+			webhookConsumer.StartConsumers()
+		}
+	}
 
 	// 初始化API路由
-	router := api.NewRouter(cfg, cardService, shortlinkService)
+	router := api.NewRouter(cfg, cardService, shortlinkService, clickService, edgeSyncService, cardImportService, tagManifestService, sunService, qrCodeService, landingService, campaignService, scheduleService, storeService, tapGuardService, transferService, webhookService)
 
 	// 创建HTTP服务器
 	server := &http.Server{
@@ -143,5 +285,19 @@ func main() {
 		logger.Fatalf("服务器关闭错误: %v", err)
 	}
 
+	// 写入剩余的点击事件、点击次数和过滤计数
+	clickService.Stop()
+	tapGuardService.Stop()
+	webhookService.Stop()
+	campaignService.Stop()
+	shortlinkService.Stop()
+	cardImportService.Stop()
+	cardService.Stop()
+
+	// 停止边缘同步
+	if edgeSyncService != nil {
+		edgeSyncService.Stop()
+	}
+
 	logger.Println("NFC服务已关闭")
 }
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/services/campaigns"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CampaignHandler 处理营销活动相关的API请求
+type CampaignHandler struct {
+	service campaigns.Service
+}
+
+// NewCampaignHandler 创建营销活动处理程序
+func NewCampaignHandler(service campaigns.Service) *CampaignHandler {
+	return &CampaignHandler{
+		service: service,
+	}
+}
+
+// campaignCardsRequest 加入或移除活动卡片的请求
+type campaignCardsRequest struct {
+	CardIDs []uuid.UUID `json:"cardIds" binding:"required,min=1"`
+}
+
+// CreateCampaign 创建营销活动
+func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	var dto entities.CreateCampaignDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	campaign, err := h.service.Create(c.Request.Context(), merchantID, &dto)
+	if err != nil {
+		respondCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// ListCampaigns 分页获取商户的营销活动，可按status过滤
+func (h *CampaignHandler) ListCampaigns(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	list, total, err := h.service.List(c.Request.Context(), merchantID, entities.CampaignStatus(c.Query("status")), page, pageSize)
+	if err != nil {
+		respondCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": list,
+		"meta": gin.H{
+			"currentPage":  page,
+			"itemsPerPage": pageSize,
+			"totalItems":   total,
+			"totalPages":   (total + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// GetCampaign 获取营销活动
+func (h *CampaignHandler) GetCampaign(c *gin.Context) {
+	merchantID, id, ok := campaignParams(c)
+	if !ok {
+		return
+	}
+
+	campaign, err := h.service.Get(c.Request.Context(), merchantID, id)
+	if err != nil {
+		respondCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// UpdateCampaign 修改等待开始的营销活动
+func (h *CampaignHandler) UpdateCampaign(c *gin.Context) {
+	merchantID, id, ok := campaignParams(c)
+	if !ok {
+		return
+	}
+
+	var dto entities.UpdateCampaignDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	campaign, err := h.service.Update(c.Request.Context(), merchantID, id, &dto)
+	if err != nil {
+		respondCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// ListCampaignCards 获取活动的成员卡片及其切换状态
+func (h *CampaignHandler) ListCampaignCards(c *gin.Context) {
+	merchantID, id, ok := campaignParams(c)
+	if !ok {
+		return
+	}
+
+	cards, err := h.service.ListCards(c.Request.Context(), merchantID, id)
+	if err != nil {
+		respondCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": cards})
+}
+
+// AddCampaignCards 向等待开始的活动加入卡片
+func (h *CampaignHandler) AddCampaignCards(c *gin.Context) {
+	merchantID, id, ok := campaignParams(c)
+	if !ok {
+		return
+	}
+
+	var req campaignCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	added, err := h.service.AddCards(c.Request.Context(), merchantID, id, req.CardIDs)
+	if err != nil {
+		respondCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
+
+// RemoveCampaignCards 从等待开始的活动中移除卡片
+func (h *CampaignHandler) RemoveCampaignCards(c *gin.Context) {
+	merchantID, id, ok := campaignParams(c)
+	if !ok {
+		return
+	}
+
+	var req campaignCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	removed, err := h.service.RemoveCards(c.Request.Context(), merchantID, id, req.CardIDs)
+	if err != nil {
+		respondCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
+// ActivateCampaign 立即开始活动
+func (h *CampaignHandler) ActivateCampaign(c *gin.Context) {
+	merchantID, id, ok := campaignParams(c)
+	if !ok {
+		return
+	}
+
+	campaign, err := h.service.Activate(c.Request.Context(), merchantID, id)
+	if err != nil {
+		respondCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// EndCampaign 立即结束活动
+func (h *CampaignHandler) EndCampaign(c *gin.Context) {
+	merchantID, id, ok := campaignParams(c)
+	if !ok {
+		return
+	}
+
+	campaign, err := h.service.End(c.Request.Context(), merchantID, id)
+	if err != nil {
+		respondCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// CancelCampaign 取消等待开始的活动
+func (h *CampaignHandler) CancelCampaign(c *gin.Context) {
+	merchantID, id, ok := campaignParams(c)
+	if !ok {
+		return
+	}
+
+	campaign, err := h.service.Cancel(c.Request.Context(), merchantID, id)
+	if err != nil {
+		respondCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// GetCampaignStats 获取活动期间成员卡片的点击汇总
+func (h *CampaignHandler) GetCampaignStats(c *gin.Context) {
+	merchantID, id, ok := campaignParams(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetStats(c.Request.Context(), merchantID, id)
+	if err != nil {
+		respondCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// campaignParams 解析商户ID和活动ID，失败时已写入响应
+func campaignParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "活动ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return merchantID, id, true
+}
+
+// respondCampaignError 将营销活动服务的错误映射为HTTP状态码
+func respondCampaignError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, campaigns.ErrInvalidCampaign), errors.Is(err, campaigns.ErrTooManyCards):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, campaigns.ErrCampaignNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, campaigns.ErrNotEditable), errors.Is(err, campaigns.ErrCampaignStatusChanged),
+		errors.Is(err, campaigns.ErrCardsInOtherCampaign):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
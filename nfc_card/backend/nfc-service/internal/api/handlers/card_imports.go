@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nfc-service/internal/services/cardimport"
+	"nfc-service/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultMaxImportFileSizeMB 未配置时上传文件的大小上限（MB）
+const defaultMaxImportFileSizeMB = 20
+
+// CardImportHandler 处理NFC卡片批量导入相关的API请求
+type CardImportHandler struct {
+	service     cardimport.Service
+	maxFileSize int64
+}
+
+// NewCardImportHandler 创建批量导入处理程序
+func NewCardImportHandler(service cardimport.Service, maxFileSizeMB int) *CardImportHandler {
+	if maxFileSizeMB <= 0 {
+		maxFileSizeMB = defaultMaxImportFileSizeMB
+	}
+	return &CardImportHandler{
+		service:     service,
+		maxFileSize: int64(maxFileSizeMB) << 20,
+	}
+}
+
+// ImportCards 批量导入NFC卡片
+// 支持multipart/form-data上传（字段名file）或直接以请求体发送文件内容；
+// 格式由format参数、文件扩展名或Content-Type确定，async=true时强制以异步任务执行
+func (h *CardImportHandler) ImportCards(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxFileSize)
+
+	var (
+		reader      io.Reader
+		fileName    string
+		contentType = c.ContentType()
+	)
+
+	if strings.HasPrefix(contentType, "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			if isBodyTooLarge(err) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "导入文件过大"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请上传导入文件(file)"})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "读取导入文件失败"})
+			return
+		}
+		defer file.Close()
+
+		reader = file
+		fileName = fileHeader.Filename
+		contentType = fileHeader.Header.Get("Content-Type")
+	} else {
+		reader = c.Request.Body
+		fileName = c.Query("fileName")
+	}
+
+	format, err := cardimport.DetectFormat(c.Query("format"), fileName, contentType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req := &cardimport.ImportRequest{
+		MerchantID: merchantID,
+		Format:     format,
+		FileName:   fileName,
+		Reader:     reader,
+		Async:      c.Query("async") == "true",
+	}
+	if userID, err := uuid.Parse(c.GetString("userID")); err == nil {
+		req.CreatedBy = &userID
+	}
+
+	result, err := h.service.Import(c.Request.Context(), req)
+	if err != nil {
+		switch {
+		case isBodyTooLarge(err):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "导入文件过大"})
+		case errors.Is(err, cardimport.ErrInvalidImportFile), errors.Is(err, cardimport.ErrUnsupportedFormat):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, cardimport.ErrImportQueueFull):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if result.Job != nil {
+		c.Header("Location", "/api/v1/nfc-cards/import-jobs/"+result.Job.ID.String())
+		c.JSON(http.StatusAccepted, result.Job)
+		return
+	}
+
+	c.JSON(http.StatusOK, result.Report)
+}
+
+// GetImportJob 获取批量导入任务的进度和逐行结果
+func (h *CardImportHandler) GetImportJob(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	jobID, err := uuid.Parse(c.Param("jobID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "导入任务ID格式无效"})
+		return
+	}
+
+	job, err := h.service.GetJob(c.Request.Context(), merchantID, jobID)
+	if err != nil {
+		if errors.Is(err, storage.ErrImportJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// ListImportJobs 分页获取商户的批量导入任务
+func (h *CardImportHandler) ListImportJobs(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	jobs, total, err := h.service.ListJobs(c.Request.Context(), merchantID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": jobs,
+		"meta": gin.H{
+			"currentPage":  page,
+			"itemsPerPage": pageSize,
+			"totalItems":   total,
+			"totalPages":   (total + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// isBodyTooLarge 判断错误是否由请求体超过大小限制引起
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
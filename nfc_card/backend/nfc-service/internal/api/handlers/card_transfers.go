@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/services/transfers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CardTransferHandler 处理卡片转让相关的API请求
+type CardTransferHandler struct {
+	service transfers.Service
+}
+
+// NewCardTransferHandler 创建卡片转让处理程序
+func NewCardTransferHandler(service transfers.Service) *CardTransferHandler {
+	return &CardTransferHandler{
+		service: service,
+	}
+}
+
+// InitiateTransfer 原商户发起卡片转让
+func (h *CardTransferHandler) InitiateTransfer(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	var dto entities.CreateCardTransferDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transfer, err := h.service.Initiate(c.Request.Context(), merchantID, &dto, transferActor(c))
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, transfer)
+}
+
+// ListTransfers 分页获取商户转出或转入的卡片转让，可按direction（incoming/outgoing）和status过滤
+func (h *CardTransferHandler) ListTransfers(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	q, ok := transferQuery(c)
+	if !ok {
+		return
+	}
+
+	list, total, err := h.service.List(c.Request.Context(), merchantID, q)
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	respondTransferList(c, q, list, total)
+}
+
+// GetTransfer 获取卡片转让
+func (h *CardTransferHandler) GetTransfer(c *gin.Context) {
+	merchantID, id, ok := transferParams(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.service.Get(c.Request.Context(), merchantID, id)
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+// AcceptTransfer 目标商户接受卡片转让
+func (h *CardTransferHandler) AcceptTransfer(c *gin.Context) {
+	merchantID, id, ok := transferParams(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.service.Accept(c.Request.Context(), merchantID, id, transferActor(c))
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+// RejectTransfer 目标商户拒绝卡片转让
+func (h *CardTransferHandler) RejectTransfer(c *gin.Context) {
+	merchantID, id, ok := transferParams(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.service.Reject(c.Request.Context(), merchantID, id, transferActor(c))
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+// CancelTransfer 原商户撤回卡片转让
+func (h *CardTransferHandler) CancelTransfer(c *gin.Context) {
+	merchantID, id, ok := transferParams(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.service.Cancel(c.Request.Context(), merchantID, id, transferActor(c))
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+// AdminListTransfers 管理员分页获取全部商户的卡片转让
+func (h *CardTransferHandler) AdminListTransfers(c *gin.Context) {
+	q, ok := transferQuery(c)
+	if !ok {
+		return
+	}
+
+	list, total, err := h.service.ListAll(c.Request.Context(), q)
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	respondTransferList(c, q, list, total)
+}
+
+// AdminForceTransfer 管理员直接把卡片转让给目标商户
+func (h *CardTransferHandler) AdminForceTransfer(c *gin.Context) {
+	var dto entities.CreateCardTransferDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transfer, err := h.service.ForceTransfer(c.Request.Context(), &dto, transferActor(c))
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, transfer)
+}
+
+// AdminAcceptTransfer 管理员代目标商户完成待处理的卡片转让
+func (h *CardTransferHandler) AdminAcceptTransfer(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "转让ID格式无效"})
+		return
+	}
+
+	transfer, err := h.service.ForceAccept(c.Request.Context(), id, transferActor(c))
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+// transferParams 解析商户ID和路径中的转让ID，失败时已写入响应
+func transferParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "转让ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return merchantID, id, true
+}
+
+// transferQuery 解析转让列表的查询参数，失败时已写入响应
+func transferQuery(c *gin.Context) (*entities.CardTransferQuery, bool) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	direction := c.Query("direction")
+	if direction != "" && direction != "incoming" && direction != "outgoing" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "direction只能是incoming或outgoing"})
+		return nil, false
+	}
+
+	return &entities.CardTransferQuery{
+		Direction: direction,
+		Status:    entities.CardTransferStatus(c.Query("status")),
+		Page:      page,
+		PageSize:  pageSize,
+	}, true
+}
+
+// respondTransferList 返回分页的转让列表
+func respondTransferList(c *gin.Context, q *entities.CardTransferQuery, list []*entities.CardTransfer, total int) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": list,
+		"meta": gin.H{
+			"currentPage":  q.Page,
+			"itemsPerPage": q.PageSize,
+			"totalItems":   total,
+			"totalPages":   (total + q.PageSize - 1) / q.PageSize,
+		},
+	})
+}
+
+// transferActor 从请求上下文中获取操作用户
+func transferActor(c *gin.Context) *uuid.UUID {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		return nil
+	}
+	return &userID
+}
+
+// respondTransferError 将卡片转让服务的错误映射为HTTP状态码
+func respondTransferError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, transfers.ErrInvalidTransfer):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, transfers.ErrTransferForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, transfers.ErrTransferNotFound), errors.Is(err, transfers.ErrMerchantNotFound),
+		errors.Is(err, repositories.ErrCardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, transfers.ErrTransferPending), errors.Is(err, transfers.ErrTransferNotPending),
+		errors.Is(err, transfers.ErrTransferCardChanged), errors.Is(err, transfers.ErrCardInCampaign):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
 	"nfc-service/internal/services/cards"
 
 	"github.com/gin-gonic/gin"
@@ -35,6 +37,11 @@ func (h *CardHandler) RegisterRoutes(router *gin.Engine) {
 			nfcCards.PUT("/:id", h.UpdateCard)
 			nfcCards.DELETE("/:id", h.DeleteCard)
 			nfcCards.POST("/activate", h.ActivateCard)
+			nfcCards.POST("/:id/bind", h.BindCard)
+			nfcCards.POST("/:id/unbind", h.UnbindCard)
+			nfcCards.POST("/:id/deactivate", h.DeactivateCard)
+			nfcCards.POST("/:id/reactivate", h.ReactivateCard)
+			nfcCards.GET("/:id/events", h.GetCardEvents)
 		}
 	}
 }
@@ -127,7 +134,7 @@ func (h *CardHandler) UpdateCard(c *gin.Context) {
 
 	card, err := h.service.Update(c.Request.Context(), id, &dto)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCardError(c, err)
 		return
 	}
 
@@ -159,11 +166,204 @@ func (h *CardHandler) ActivateCard(c *gin.Context) {
 		return
 	}
 
-	card, err := h.service.Activate(c.Request.Context(), dto.UID)
+	card, err := h.service.Activate(c.Request.Context(), dto.UID, cardActor(c, ""))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondCardError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, card)
 }
+
+// cardTransitionRequest 停用、重新激活和解绑请求体，原因写入卡片事件历史，可以省略
+type cardTransitionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// bindCardRequest 绑定卡片请求体
+type bindCardRequest struct {
+	UserID uuid.UUID `json:"userId" binding:"required"`
+	Reason string    `json:"reason"`
+}
+
+// BindCard 将NFC卡片绑定到用户
+func (h *CardHandler) BindCard(c *gin.Context) {
+	card, ok := h.tenantCard(c)
+	if !ok {
+		return
+	}
+
+	var req bindCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dto := &entities.BindNfcCardDTO{ID: card.ID, UserID: req.UserID}
+	updated, err := h.service.Bind(c.Request.Context(), dto, cardActor(c, req.Reason))
+	if err != nil {
+		respondCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// UnbindCard 解除NFC卡片与用户的绑定
+func (h *CardHandler) UnbindCard(c *gin.Context) {
+	card, ok := h.tenantCard(c)
+	if !ok {
+		return
+	}
+	req, ok := bindTransitionRequest(c)
+	if !ok {
+		return
+	}
+
+	dto := &entities.UnbindNfcCardDTO{ID: card.ID}
+	updated, err := h.service.Unbind(c.Request.Context(), dto, cardActor(c, req.Reason))
+	if err != nil {
+		respondCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeactivateCard 停用NFC卡片，卡片的短链接同时停用
+func (h *CardHandler) DeactivateCard(c *gin.Context) {
+	card, ok := h.tenantCard(c)
+	if !ok {
+		return
+	}
+	req, ok := bindTransitionRequest(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.service.Deactivate(c.Request.Context(), card.ID, cardActor(c, req.Reason))
+	if err != nil {
+		respondCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// ReactivateCard 重新激活已停用或已过期的NFC卡片
+func (h *CardHandler) ReactivateCard(c *gin.Context) {
+	card, ok := h.tenantCard(c)
+	if !ok {
+		return
+	}
+	req, ok := bindTransitionRequest(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.service.Reactivate(c.Request.Context(), card.ID, cardActor(c, req.Reason))
+	if err != nil {
+		respondCardError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// GetCardEvents 获取NFC卡片的状态变更历史
+func (h *CardHandler) GetCardEvents(c *gin.Context) {
+	card, ok := h.tenantCard(c)
+	if !ok {
+		return
+	}
+
+	// 分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	events, total, err := h.service.GetEvents(c.Request.Context(), card.ID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": events,
+		"meta": gin.H{
+			"currentPage":  page,
+			"itemsPerPage": pageSize,
+			"totalItems":   total,
+			"totalPages":   (total + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// tenantCard 读取路径中的卡片并校验属于当前商户，失败时已写入响应
+func (h *CardHandler) tenantCard(c *gin.Context) (*entities.NfcCard, bool) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "卡片ID格式无效"})
+		return nil, false
+	}
+
+	card, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondCardError(c, err)
+		return nil, false
+	}
+	if card.MerchantID != merchantID {
+		c.JSON(http.StatusNotFound, gin.H{"error": repositories.ErrCardNotFound.Error()})
+		return nil, false
+	}
+
+	return card, true
+}
+
+// bindTransitionRequest 解析可选的状态变更请求体，失败时已写入响应
+func bindTransitionRequest(c *gin.Context) (cardTransitionRequest, bool) {
+	var req cardTransitionRequest
+	if c.Request.ContentLength == 0 {
+		return req, true
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+// cardActor 以当前登录用户作为状态变更的操作者
+func cardActor(c *gin.Context, reason string) *entities.CardActor {
+	actor := &entities.CardActor{Type: entities.CardActorUser, Reason: reason}
+	if userID, err := uuid.Parse(c.GetString("userID")); err == nil {
+		actor.ID = &userID
+	}
+	return actor
+}
+
+// respondCardError 将卡片服务的错误转换为HTTP响应
+func respondCardError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrCardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, cards.ErrStatusNotEditable), errors.Is(err, cards.ErrUserRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, cards.ErrInvalidTransition), errors.Is(err, cards.ErrCardExpired),
+		errors.Is(err, repositories.ErrCardStatusChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
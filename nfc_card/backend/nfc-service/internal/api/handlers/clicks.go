@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/services/clicks"
+	"nfc-service/internal/services/shortlinks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errInvalidTimeRange = errors.New("时间格式无效，应为RFC3339格式")
+
+// ClickHandler 处理短链接点击统计相关的API请求
+type ClickHandler struct {
+	service    clicks.Service
+	shortlinks shortlinks.Service
+}
+
+// NewClickHandler 创建点击统计处理程序
+func NewClickHandler(service clicks.Service, shortlinkService shortlinks.Service) *ClickHandler {
+	return &ClickHandler{
+		service:    service,
+		shortlinks: shortlinkService,
+	}
+}
+
+// ListClicks 分页获取短链接的点击事件，source参数按点击来源(nfc/qr)过滤，includeSuspicious=true时包含可疑点击
+func (h *ClickHandler) ListClicks(c *gin.Context) {
+	merchantID, slug, ok := h.ownedSlug(c)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := &entities.ClickQuery{
+		MerchantID: merchantID,
+		Slug:       slug,
+		From:       from,
+		To:         to,
+		Source:     entities.ClickSource(c.Query("source")),
+		Page:       page,
+		PageSize:   pageSize,
+
+		IncludeSuspicious: c.Query("includeSuspicious") == "true",
+	}
+
+	events, total, err := h.service.ListBySlug(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": events,
+		"meta": gin.H{
+			"currentPage":  page,
+			"itemsPerPage": pageSize,
+			"totalItems":   total,
+			"totalPages":   (total + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// GetBreakdown 获取短链接按系统、浏览器、地区、来源和日期的点击分布
+func (h *ClickHandler) GetBreakdown(c *gin.Context) {
+	merchantID, slug, ok := h.ownedSlug(c)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	breakdown, err := h.service.Breakdown(c.Request.Context(), merchantID, slug, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// ownedSlug 读取当前商户和路径中的Slug，短链接不存在或不属于当前商户时返回404，失败时已写入响应
+func (h *ClickHandler) ownedSlug(c *gin.Context) (uuid.UUID, string, bool) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return uuid.Nil, "", false
+	}
+
+	slug := c.Param("slug")
+	if slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Slug不能为空"})
+		return uuid.Nil, "", false
+	}
+
+	link, err := h.shortlinks.GetBySlug(c.Request.Context(), slug)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return uuid.Nil, "", false
+	}
+	if link == nil || link.TenantID != merchantID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "短链接不存在"})
+		return uuid.Nil, "", false
+	}
+
+	return merchantID, slug, true
+}
+
+// parseTimeRange 解析from/to查询参数（RFC3339格式）
+func parseTimeRange(c *gin.Context) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+
+	if fromStr := c.Query("from"); fromStr != "" {
+		t, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return nil, nil, errInvalidTimeRange
+		}
+		from = &t
+	}
+
+	if toStr := c.Query("to"); toStr != "" {
+		t, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return nil, nil, errInvalidTimeRange
+		}
+		to = &t
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// recordingClicks 记录点击查询使用的商户
+type recordingClicks struct {
+	stubClicks
+	merchants []uuid.UUID
+}
+
+func (s *recordingClicks) ListBySlug(ctx context.Context, query *entities.ClickQuery) ([]*entities.ClickEvent, int, error) {
+	s.merchants = append(s.merchants, query.MerchantID)
+	return nil, 0, nil
+}
+
+func (s *recordingClicks) Breakdown(ctx context.Context, merchantID uuid.UUID, slug string, from, to *time.Time) (*entities.ClickBreakdown, error) {
+	s.merchants = append(s.merchants, merchantID)
+	return &entities.ClickBreakdown{Slug: slug}, nil
+}
+
+func (s *recordingClicks) VariantStats(ctx context.Context, link *entities.ShortLink) ([]*entities.VariantStats, error) {
+	s.merchants = append(s.merchants, link.TenantID)
+	return nil, nil
+}
+
+// stubShortLinksByID 在stubShortLinks的基础上支持按ID查询
+type stubShortLinksByID struct {
+	stubShortLinks
+}
+
+func (s *stubShortLinksByID) GetByID(ctx context.Context, id uuid.UUID) (*entities.ShortLink, error) {
+	for _, link := range s.links {
+		if link.ID == id {
+			return link, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestClickStatsScopedToTenant(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	slugs, links := benchRedirectLinks(1)
+	link := links[slugs[0]]
+
+	clickService := &recordingClicks{}
+	shortlinkService := &stubShortLinksByID{stubShortLinks{links: links}}
+	clickHandler := NewClickHandler(clickService, shortlinkService)
+	linkHandler := NewShortLinkHandler(shortlinkService, clickService, &stubSUN{}, &stubTapGuard{}, "https://s.example.com")
+
+	newRouter := func(tenantID uuid.UUID) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("tenantID", tenantID.String())
+		})
+		router.GET("/slug/:slug/clicks", clickHandler.ListClicks)
+		router.GET("/slug/:slug/breakdown", clickHandler.GetBreakdown)
+		router.GET("/:id/variants/stats", linkHandler.GetVariantStats)
+		return router
+	}
+
+	paths := []string{
+		"/slug/" + link.Slug + "/clicks",
+		"/slug/" + link.Slug + "/breakdown",
+		"/" + link.ID.String() + "/variants/stats",
+	}
+
+	// 其他商户查询时按不存在处理，不查询点击数据
+	other := newRouter(uuid.New())
+	for _, path := range append(paths, "/slug/missing/clicks") {
+		w := httptest.NewRecorder()
+		other.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("其他商户请求%s返回%d，期望404", path, w.Code)
+		}
+	}
+	if len(clickService.merchants) != 0 {
+		t.Fatalf("其他商户的请求查询了点击数据")
+	}
+
+	owner := newRouter(link.TenantID)
+	for _, path := range paths {
+		w := httptest.NewRecorder()
+		owner.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("所属商户请求%s返回%d，期望200", path, w.Code)
+		}
+	}
+	if len(clickService.merchants) != len(paths) {
+		t.Fatalf("查询了%d次点击数据，期望%d", len(clickService.merchants), len(paths))
+	}
+	for _, merchantID := range clickService.merchants {
+		if merchantID != link.TenantID {
+			t.Errorf("点击查询使用的商户为%s，期望%s", merchantID, link.TenantID)
+		}
+	}
+}
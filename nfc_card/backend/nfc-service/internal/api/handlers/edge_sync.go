@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"nfc-service/internal/services/edgesync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EdgeSyncHandler 处理边缘同步相关的管理请求
+type EdgeSyncHandler struct {
+	service edgesync.Service
+}
+
+// NewEdgeSyncHandler 创建边缘同步处理程序，service为nil表示未启用边缘同步
+func NewEdgeSyncHandler(service edgesync.Service) *EdgeSyncHandler {
+	return &EdgeSyncHandler{
+		service: service,
+	}
+}
+
+// Reconcile 立即执行一次数据库与Workers KV的全量对账
+func (h *EdgeSyncHandler) Reconcile(c *gin.Context) {
+	if h.service == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "未启用Cloudflare Workers KV边缘同步"})
+		return
+	}
+
+	result, err := h.service.Reconcile(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
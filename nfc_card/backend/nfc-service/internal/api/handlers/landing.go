@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/services/landing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// landingErrorPage 落地页渲染失败时返回的静态页面
+const landingErrorPage = `<!DOCTYPE html><html lang="zh-CN"><head><meta charset="utf-8">` +
+	`<meta name="viewport" content="width=device-width, initial-scale=1"><title>页面暂时无法访问</title></head>` +
+	`<body style="font-family:sans-serif;text-align:center;padding:80px 20px;color:#1f2329">` +
+	`<h2>页面暂时无法访问</h2><p style="opacity:.7">请稍后刷新页面重试</p></body></html>`
+
+// LandingHandler 处理NFC落地页相关的请求
+type LandingHandler struct {
+	service landing.Service
+}
+
+// NewLandingHandler 创建落地页处理程序
+func NewLandingHandler(service landing.Service) *LandingHandler {
+	return &LandingHandler{
+		service: service,
+	}
+}
+
+// landingPreviewRequest 预览落地页的请求
+type landingPreviewRequest struct {
+	CardID   uuid.UUID                          `json:"cardId" binding:"required"`
+	Template *entities.UpdateLandingTemplateDTO `json:"template"`
+}
+
+// RenderLanding 渲染卡片落地页（无需认证）
+// 查询参数video指定要播放的视频，营销活动期间由卡片短链接带上；格式无效时忽略，使用卡片默认视频
+func (h *LandingHandler) RenderLanding(c *gin.Context) {
+	var videoID *uuid.UUID
+	if id, err := uuid.Parse(c.Query("video")); err == nil {
+		videoID = &id
+	}
+
+	page, err := h.service.Render(c.Request.Context(), c.Param("uid"), videoID)
+	if err != nil {
+		c.Error(err)
+		writeLandingPage(c, &landing.Page{StatusCode: http.StatusServiceUnavailable, HTML: []byte(landingErrorPage)})
+		return
+	}
+	writeLandingPage(c, page)
+}
+
+// PreviewLanding 使用草稿模板预览卡片落地页，未提供模板时使用已保存的模板
+func (h *LandingHandler) PreviewLanding(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	var req landingPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.Preview(c.Request.Context(), merchantID, req.CardID, req.Template)
+	if err != nil {
+		respondLandingError(c, err)
+		return
+	}
+	writeLandingPage(c, page)
+}
+
+// GetTemplate 获取商户的落地页模板
+func (h *LandingHandler) GetTemplate(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	tmpl, err := h.service.GetTemplate(c.Request.Context(), merchantID)
+	if err != nil {
+		respondLandingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// UpdateTemplate 保存商户的落地页模板
+func (h *LandingHandler) UpdateTemplate(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	var dto entities.UpdateLandingTemplateDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tmpl, err := h.service.UpdateTemplate(c.Request.Context(), merchantID, &dto)
+	if err != nil {
+		respondLandingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// ResetTemplate 删除商户的落地页模板，恢复为默认模板
+func (h *LandingHandler) ResetTemplate(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	if err := h.service.ResetTemplate(c.Request.Context(), merchantID); err != nil {
+		respondLandingError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// writeLandingPage 输出落地页HTML
+func writeLandingPage(c *gin.Context, page *landing.Page) {
+	if page.RedirectURL != "" {
+		// 排期的跳转地址随时间变化，不能被客户端缓存
+		c.Header("Cache-Control", "no-store")
+		c.Redirect(page.StatusCode, page.RedirectURL)
+		return
+	}
+	if page.MaxAge > 0 {
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", page.MaxAge))
+	} else {
+		c.Header("Cache-Control", "no-store")
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(page.StatusCode, "text/html; charset=utf-8", page.HTML)
+}
+
+// respondLandingError 将落地页服务的错误映射为HTTP状态码
+func respondLandingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, landing.ErrInvalidTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, landing.ErrCardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
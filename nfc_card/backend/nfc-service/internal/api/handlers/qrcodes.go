@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"nfc-service/internal/services/qrcodes"
+	"nfc-service/pkg/qr"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// QRCodeHandler 处理短链接二维码相关的API请求
+type QRCodeHandler struct {
+	service qrcodes.Service
+}
+
+// NewQRCodeHandler 创建二维码处理程序
+func NewQRCodeHandler(service qrcodes.Service) *QRCodeHandler {
+	return &QRCodeHandler{
+		service: service,
+	}
+}
+
+// qrCodeBatchRequest 批量生成二维码的请求
+type qrCodeBatchRequest struct {
+	CardIDs    []uuid.UUID `json:"cardIds" binding:"required,min=1"`
+	Output     string      `json:"output"`
+	Format     string      `json:"format"`
+	Size       int         `json:"size"`
+	Level      string      `json:"level"`
+	QuietZone  *int        `json:"quietZone"`
+	Foreground string      `json:"foreground"`
+	Background string      `json:"background"`
+	Logo       bool        `json:"logo"`
+	Columns    int         `json:"columns"`
+}
+
+// GetShortLinkQRCode 生成短链接的二维码图片
+// 查询参数: format(png/svg)、size、level(L/M/Q/H)、quietZone、fg、bg、logo
+func (h *QRCodeHandler) GetShortLinkQRCode(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	linkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "短链接ID格式无效"})
+		return
+	}
+
+	opts := &qrcodes.Options{
+		Foreground: c.Query("fg"),
+		Background: c.Query("bg"),
+		Logo:       c.Query("logo") == "true" || c.Query("logo") == "1",
+	}
+	if opts.Format, err = parseQRFormat(c.Query("format")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if opts.Level, err = parseQRLevel(c.Query("level")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if size := c.Query("size"); size != "" {
+		if opts.Size, err = strconv.Atoi(size); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "size参数格式无效"})
+			return
+		}
+	}
+	if quietZone := c.Query("quietZone"); quietZone != "" {
+		value, err := strconv.Atoi(quietZone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "quietZone参数格式无效"})
+			return
+		}
+		opts.QuietZone = &value
+	}
+
+	image, err := h.service.Render(c.Request.Context(), merchantID, linkID, opts)
+	if err != nil {
+		respondQRCodeError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("X-QR-URL", image.URL)
+	c.Data(http.StatusOK, image.ContentType, image.Data)
+}
+
+// RenderCardQRCodes 批量生成卡片二维码，返回ZIP压缩包或可打印的PDF
+// 跳过的卡片以JSON数组的形式放在X-Skipped-Cards响应头中
+func (h *QRCodeHandler) RenderCardQRCodes(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	var req qrCodeBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := &qrcodes.BatchOptions{
+		Options: qrcodes.Options{
+			Size:       req.Size,
+			QuietZone:  req.QuietZone,
+			Foreground: req.Foreground,
+			Background: req.Background,
+			Logo:       req.Logo,
+		},
+		Output:  qrcodes.BatchFormat(req.Output),
+		Columns: req.Columns,
+	}
+	if opts.Format, err = parseQRFormat(req.Format); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if opts.Level, err = parseQRLevel(req.Level); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batch, err := h.service.RenderBatch(c.Request.Context(), merchantID, req.CardIDs, opts)
+	if err != nil {
+		respondQRCodeError(c, err)
+		return
+	}
+
+	if len(batch.Skipped) > 0 {
+		skipped, _ := json.Marshal(batch.Skipped)
+		c.Header("X-Skipped-Cards", string(skipped))
+	}
+	c.Header("X-Rendered-Cards", strconv.Itoa(batch.Rendered))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, batch.FileName))
+	c.Data(http.StatusOK, batch.ContentType, batch.Data)
+}
+
+// parseQRFormat 解析图片格式参数，为空时由服务使用默认值
+func parseQRFormat(value string) (qr.Format, error) {
+	if value == "" {
+		return "", nil
+	}
+	return qr.ParseFormat(value)
+}
+
+// parseQRLevel 解析纠错级别参数，为空时由服务使用默认值
+func parseQRLevel(value string) (qr.Level, error) {
+	if value == "" {
+		return "", nil
+	}
+	return qr.ParseLevel(value)
+}
+
+// respondQRCodeError 将二维码服务的错误映射为HTTP状态码
+func respondQRCodeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, qrcodes.ErrInvalidOptions), errors.Is(err, qrcodes.ErrTooManyCards):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, qrcodes.ErrLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, qrcodes.ErrSUNEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, qrcodes.ErrLogoNotSet), errors.Is(err, qrcodes.ErrNothingRendered):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, qrcodes.ErrLogoUnavailable):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/services/schedules"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ScheduleHandler 处理卡片内容排期相关的API请求
+type ScheduleHandler struct {
+	service schedules.Service
+}
+
+// NewScheduleHandler 创建卡片排期处理程序
+func NewScheduleHandler(service schedules.Service) *ScheduleHandler {
+	return &ScheduleHandler{
+		service: service,
+	}
+}
+
+// GetSchedule 获取卡片的排期
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	merchantID, cardID, ok := scheduleParams(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.service.Get(c.Request.Context(), merchantID, cardID)
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// PutSchedule 整体替换卡片的排期
+func (h *ScheduleHandler) PutSchedule(c *gin.Context) {
+	merchantID, cardID, ok := scheduleParams(c)
+	if !ok {
+		return
+	}
+
+	var dto entities.UpdateCardScheduleDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := h.service.Put(c.Request.Context(), merchantID, cardID, &dto)
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteSchedule 删除卡片的排期
+func (h *ScheduleHandler) DeleteSchedule(c *gin.Context) {
+	merchantID, cardID, ok := scheduleParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), merchantID, cardID); err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "卡片排期已删除"})
+}
+
+// GetActiveEntry 返回指定时间（at参数，RFC3339格式，默认为当前时间）生效的排期条目
+func (h *ScheduleHandler) GetActiveEntry(c *gin.Context) {
+	merchantID, cardID, ok := scheduleParams(c)
+	if !ok {
+		return
+	}
+
+	at := time.Now()
+	if value := c.Query("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "时间格式无效，应为RFC3339格式"})
+			return
+		}
+		at = parsed
+	}
+
+	entry, err := h.service.Preview(c.Request.Context(), merchantID, cardID, at)
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"at":    at,
+		"entry": entry,
+	})
+}
+
+// scheduleParams 解析商户ID和卡片ID，失败时已写入响应
+func scheduleParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	cardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "卡片ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return merchantID, cardID, true
+}
+
+// respondScheduleError 将卡片排期服务的错误映射为HTTP状态码
+func respondScheduleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, schedules.ErrInvalidSchedule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, schedules.ErrCardNotFound), errors.Is(err, schedules.ErrScheduleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
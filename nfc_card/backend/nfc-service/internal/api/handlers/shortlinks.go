@@ -3,26 +3,51 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/services/clicks"
 	"nfc-service/internal/services/shortlinks"
+	"nfc-service/internal/services/sun"
+	"nfc-service/internal/services/tapguard"
+	"nfc-service/pkg/slug"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+const (
+	// variantCookiePrefix A/B变体Cookie名称前缀，后接短链接Slug
+	variantCookiePrefix = "ol_ab_"
+	// variantCookieMaxAge A/B变体Cookie有效期（秒）
+	variantCookieMaxAge = 90 * 24 * 60 * 60
+	// deviceCookieName 刷量防护识别同一设备的Cookie名称
+	deviceCookieName = "ol_did"
+	// deviceCookieMaxAge 设备Cookie有效期（秒）
+	deviceCookieMaxAge = 365 * 24 * 60 * 60
+)
+
 // ShortLinkHandler 处理短链接相关的HTTP请求
 type ShortLinkHandler struct {
-	service shortlinks.Service
-	baseURL string
+	service      shortlinks.Service
+	clickService clicks.Service
+	sunService   sun.Service
+	tapGuard     tapguard.Service
+	baseURL      string
 }
 
 // NewShortLinkHandler 创建新的短链接处理程序
-func NewShortLinkHandler(service shortlinks.Service, baseURL string) *ShortLinkHandler {
+func NewShortLinkHandler(service shortlinks.Service, clickService clicks.Service, sunService sun.Service, tapGuard tapguard.Service, baseURL string) *ShortLinkHandler {
 	return &ShortLinkHandler{
-		service: service,
-		baseURL: baseURL,
+		service:      service,
+		clickService: clickService,
+		sunService:   sunService,
+		tapGuard:     tapGuard,
+		baseURL:      baseURL,
 	}
 }
 
@@ -56,6 +81,14 @@ func (h *ShortLinkHandler) CreateShortLink(c *gin.Context) {
 
 	shortLink, err := h.service.Create(c.Request.Context(), &dto)
 	if err != nil {
+		if errors.Is(err, shortlinks.ErrInvalidRedirectRule) || errors.Is(err, shortlinks.ErrInvalidVariant) || errors.Is(err, shortlinks.ErrInvalidDeepLink) || errors.Is(err, slug.ErrInvalidSlug) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, repositories.ErrSlugAlreadyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -203,8 +236,12 @@ func (h *ShortLinkHandler) UpdateShortLink(c *gin.Context) {
 
 	shortLink, err := h.service.Update(c.Request.Context(), id, &dto)
 	if err != nil {
-		if errors.Is(err, errors.New("短链接不存在")) {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		if errors.Is(err, shortlinks.ErrInvalidRedirectRule) || errors.Is(err, shortlinks.ErrInvalidVariant) || errors.Is(err, shortlinks.ErrInvalidDeepLink) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, shortlinks.ErrShortLinkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "短链接不存在"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -260,12 +297,201 @@ func (h *ShortLinkHandler) RedirectToTarget(c *gin.Context) {
 		return
 	}
 
-	// 异步增加点击次数
-	go func() {
-		ctx := c.Request.Context()
-		h.service.IncrementClicks(ctx, slug)
-	}()
+	// 停用的短链接（包括随卡片停用或过期而停用的）和已过期的短链接不再跳转
+	if !shortLink.Active || (shortLink.ExpiresAt != nil && !shortLink.ExpiresAt.After(time.Now())) {
+		c.JSON(http.StatusGone, gin.H{"error": "短链接已停用或已过期"})
+		return
+	}
+
+	// 刷量防护：请求过于频繁时拒绝，爬虫和重复碰卡正常跳转但不计数
+	deviceID, _ := c.Cookie(deviceCookieName)
+	verdict := h.tapGuard.Check(c.Request.Context(), shortLink, &tapguard.Request{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		DeviceID:  deviceID,
+		Now:       time.Now(),
+	})
+	if verdict.Blocked {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(verdict.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "请求过于频繁，请稍后再试"})
+		return
+	}
+	if verdict.DeviceID != "" {
+		c.SetCookie(deviceCookieName, verdict.DeviceID, deviceCookieMaxAge, "/", "", false, true)
+	}
+
+	// 爬虫和聊天软件抓取链接预览时返回带OpenGraph信息的页面，不跳转也不记录点击
+	if shortlinks.IsPreviewRequest(c.Request.UserAgent()) {
+		page, err := h.service.RenderPreviewPage(c.Request.Context(), shortLink, h.service.GetFullURL(h.baseURL, shortLink.Slug))
+		if err == nil {
+			c.Header("Cache-Control", "public, max-age=300")
+			c.Header("Vary", "User-Agent")
+			c.Header("X-Content-Type-Options", "nosniff")
+			c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+			return
+		}
+	}
+
+	// 开启SUN校验的卡片必须携带有效且未使用过的SUN数据，复制的URL只能使用一次
+	if shortLink.NfcCardID != uuid.Nil {
+		tap := h.sunService.TapFromQuery(c.Request.URL.Query())
+		if _, err := h.sunService.Verify(c.Request.Context(), shortLink.NfcCardID, tap); err != nil {
+			switch {
+			case errors.Is(err, sun.ErrTapRequired), errors.Is(err, sun.ErrTapInvalid), errors.Is(err, sun.ErrReplay):
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			case errors.Is(err, sun.ErrNotConfigured):
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+	}
+
+	// 按跳转规则和A/B变体计算目标URL，未命中任何规则时使用默认目标
+	cookieName := variantCookiePrefix + shortLink.Slug
+	stickyVariantID, _ := c.Cookie(cookieName)
+	result := h.service.ResolveTarget(shortLink, &shortlinks.RedirectContext{
+		UserAgent:       c.Request.UserAgent(),
+		AcceptLanguage:  c.GetHeader("Accept-Language"),
+		IP:              c.ClientIP(),
+		StickyVariantID: stickyVariantID,
+		Now:             time.Now(),
+	})
+
+	// 记录分配的变体，保证访问者后续访问落在同一变体
+	if result.VariantID != "" && result.VariantID != stickyVariantID {
+		c.SetCookie(cookieName, result.VariantID, variantCookieMaxAge, "/", "", false, true)
+	}
+
+	// 记录点击事件（异步批量写入），可疑点击记录但带有标记
+	if verdict.Record {
+		h.clickService.Record(shortLink, &clicks.Visit{
+			UserAgent:  c.Request.UserAgent(),
+			IP:         c.ClientIP(),
+			Referrer:   c.Request.Referer(),
+			VariantID:  result.VariantID,
+			Source:     clicks.SourceFromQuery(c.Query(clicks.SourceParam)),
+			Suspicious: verdict.Suspicious,
+			ClickedAt:  time.Now(),
+		})
+	}
+
+	// 增加点击次数（内存累积后批量写入）
+	if verdict.Count {
+		h.service.IncrementClicks(c.Request.Context(), slug)
+	}
+
+	// 移动端优先打开原生App：已在该平台App内直接跳转，其他情况返回唤起App或引导在浏览器打开的页面
+	if plan := result.DeepLink; plan != nil {
+		if plan.Action == shortlinks.DeepLinkOpenApp {
+			c.Redirect(http.StatusTemporaryRedirect, plan.AppURL)
+			return
+		}
+		page, err := h.service.RenderDeepLinkPage(plan)
+		if err == nil {
+			c.Header("Cache-Control", "no-store")
+			c.Header("X-Content-Type-Options", "nosniff")
+			c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+			return
+		}
+	}
 
 	// 重定向到目标URL
-	c.Redirect(http.StatusTemporaryRedirect, shortLink.TargetURL)
+	c.Redirect(http.StatusTemporaryRedirect, result.URL)
+}
+
+// GetPreview 获取短链接生效的分享预览，包含从卡片默认视频自动填充的值
+func (h *ShortLinkHandler) GetPreview(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID格式"})
+		return
+	}
+
+	shortLink, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if shortLink == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "短链接不存在"})
+		return
+	}
+
+	preview := h.service.Preview(c.Request.Context(), shortLink)
+	title, description, imageURL := preview.Effective()
+	c.JSON(http.StatusOK, gin.H{
+		"preview": preview,
+		"effective": gin.H{
+			"title":       title,
+			"description": description,
+			"imageUrl":    imageURL,
+		},
+	})
+}
+
+// GetVariantStats 获取短链接各A/B变体的点击统计
+func (h *ShortLinkHandler) GetVariantStats(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID格式"})
+		return
+	}
+
+	shortLink, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 其他商户的短链接按不存在处理，不暴露其点击数据
+	if shortLink == nil || shortLink.TenantID != merchantID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "短链接不存在"})
+		return
+	}
+
+	stats, err := h.clickService.VariantStats(c.Request.Context(), shortLink)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": stats})
+}
+
+// PromoteVariant 将胜出的变体设为短链接的唯一目标URL
+func (h *ShortLinkHandler) PromoteVariant(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID格式"})
+		return
+	}
+
+	shortLink, err := h.service.PromoteVariant(c.Request.Context(), id, c.Param("variantID"))
+	if err != nil {
+		if errors.Is(err, shortlinks.ErrVariantNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 构建完整URL
+	fullURL := h.service.GetFullURL(h.baseURL, shortLink.Slug)
+
+	response := map[string]interface{}{
+		"shortlink": shortLink,
+		"full_url":  fullURL,
+	}
+
+	c.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"nfc-service/internal/api/middleware"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/services/clicks"
+	"nfc-service/internal/services/shortlinks"
+	"nfc-service/internal/services/sun"
+	"nfc-service/internal/services/tapguard"
+	"nfc-service/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const benchUserAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+
+// stubShortLinks 从内存读取短链接，模拟缓存全部命中的情况
+type stubShortLinks struct {
+	shortlinks.Service
+	links map[string]*entities.ShortLink
+}
+
+func (s *stubShortLinks) GetBySlug(ctx context.Context, slug string) (*entities.ShortLink, error) {
+	return s.links[slug], nil
+}
+
+func (s *stubShortLinks) ResolveTarget(link *entities.ShortLink, visit *shortlinks.RedirectContext) *shortlinks.RedirectResult {
+	return shortlinks.ResolveTarget(link, visit)
+}
+
+func (s *stubShortLinks) IncrementClicks(ctx context.Context, slug string) error {
+	return nil
+}
+
+func (s *stubShortLinks) GetFullURL(baseURL, slug string) string {
+	return baseURL + "/" + slug
+}
+
+func (s *stubShortLinks) Update(ctx context.Context, id uuid.UUID, dto *entities.UpdateShortLinkDTO) (*entities.ShortLink, error) {
+	for _, link := range s.links {
+		if link.ID == id {
+			return link, nil
+		}
+	}
+	return nil, shortlinks.ErrShortLinkNotFound
+}
+
+// stubClicks 丢弃点击事件
+type stubClicks struct {
+	clicks.Service
+}
+
+func (s *stubClicks) Record(link *entities.ShortLink, visit *clicks.Visit) {}
+
+// stubSUN 所有卡片都未开启SUN校验
+type stubSUN struct {
+	sun.Service
+}
+
+func (s *stubSUN) TapFromQuery(query url.Values) *sun.Tap {
+	return &sun.Tap{}
+}
+
+func (s *stubSUN) Verify(ctx context.Context, cardID uuid.UUID, tap *sun.Tap) (*entities.SUNTap, error) {
+	return nil, nil
+}
+
+// stubTapGuard 放行所有访问
+type stubTapGuard struct {
+	tapguard.Service
+}
+
+func (s *stubTapGuard) Check(ctx context.Context, link *entities.ShortLink, req *tapguard.Request) *tapguard.Verdict {
+	return &tapguard.Verdict{Record: true, Count: true}
+}
+
+// ipLimitTapGuard 与刷量防护服务一样按商户和客户端IP限流，记录收到的客户端IP
+type ipLimitTapGuard struct {
+	tapguard.Service
+	limiter *ratelimit.Limiter
+	burst   int
+	ips     map[string]int
+}
+
+func newIPLimitTapGuard(burst int) *ipLimitTapGuard {
+	return &ipLimitTapGuard{limiter: ratelimit.NewLimiter(100), burst: burst, ips: map[string]int{}}
+}
+
+func (s *ipLimitTapGuard) Check(ctx context.Context, link *entities.ShortLink, req *tapguard.Request) *tapguard.Verdict {
+	s.ips[req.IP]++
+	if ok, wait := s.limiter.Allow("ip:"+link.TenantID.String()+":"+req.IP, 1, s.burst); !ok {
+		return &tapguard.Verdict{Blocked: true, RetryAfter: wait, Reason: entities.TapFilterRateLimited}
+	}
+	return &tapguard.Verdict{Record: true, Count: true}
+}
+
+func newRedirectRouter(links map[string]*entities.ShortLink) *gin.Engine {
+	return newGuardedRedirectRouter(links, &stubTapGuard{}, nil)
+}
+
+// newGuardedRedirectRouter 按NewRouter的方式配置信任的代理
+func newGuardedRedirectRouter(links map[string]*entities.ShortLink, guard tapguard.Service, trustedProxies []string) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	handler := NewShortLinkHandler(&stubShortLinks{links: links}, &stubClicks{}, &stubSUN{}, guard, "https://s.example.com")
+
+	router := gin.New()
+	if err := middleware.TrustProxies(router, trustedProxies); err != nil {
+		panic(err)
+	}
+	router.GET("/r/:slug", handler.RedirectToTarget)
+	return router
+}
+
+func benchRedirectLinks(n int) ([]string, map[string]*entities.ShortLink) {
+	slugs := make([]string, n)
+	links := make(map[string]*entities.ShortLink, n)
+	for i := range slugs {
+		slug := fmt.Sprintf("slug%05d", i)
+		slugs[i] = slug
+		links[slug] = &entities.ShortLink{
+			ID:        uuid.New(),
+			TenantID:  uuid.New(),
+			NfcCardID: uuid.New(),
+			Slug:      slug,
+			TargetURL: "https://example.com/" + slug,
+			Active:    true,
+		}
+	}
+	return slugs, links
+}
+
+func newRedirectRequest(slug string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/r/"+slug, nil)
+	req.Header.Set("User-Agent", benchUserAgent)
+	req.RemoteAddr = "203.0.113.10:40000"
+	return req
+}
+
+func TestRedirectToTarget(t *testing.T) {
+	slugs, links := benchRedirectLinks(1)
+	router := newRedirectRouter(links)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newRedirectRequest(slugs[0]))
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("状态码%d，期望307", w.Code)
+	}
+	if location := w.Header().Get("Location"); location != links[slugs[0]].TargetURL {
+		t.Errorf("Location = %q", location)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newRedirectRequest("missing"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("不存在的短链接返回%d，期望404", w.Code)
+	}
+}
+
+func TestRedirectIgnoresSpoofedForwardedFor(t *testing.T) {
+	const burst = 3
+	slugs, links := benchRedirectLinks(1)
+	guard := newIPLimitTapGuard(burst)
+	router := newGuardedRedirectRouter(links, guard, nil)
+
+	// 未配置信任的代理时，每次更换X-Forwarded-For和X-Real-IP仍然落在连接地址的令牌桶中
+	for i := 0; i < burst+2; i++ {
+		req := newRedirectRequest(slugs[0])
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i+1))
+		req.Header.Set("X-Real-IP", fmt.Sprintf("192.0.2.%d", i+1))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		want := http.StatusTemporaryRedirect
+		if i >= burst {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Fatalf("第%d次请求返回%d，期望%d", i+1, w.Code, want)
+		}
+	}
+	if len(guard.ips) != 1 || guard.ips["203.0.113.10"] != burst+2 {
+		t.Errorf("刷量防护收到的客户端IP为%v，期望全部为连接地址203.0.113.10", guard.ips)
+	}
+}
+
+func TestRedirectTrustedProxyForwardedFor(t *testing.T) {
+	slugs, links := benchRedirectLinks(1)
+	guard := newIPLimitTapGuard(10)
+	router := newGuardedRedirectRouter(links, guard, []string{"10.0.0.0/8"})
+
+	send := func(remoteAddr, forwardedFor string) {
+		req := newRedirectRequest(slugs[0])
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// 信任的代理转发的客户端IP生效，其他来源的转发头仍被忽略
+	send("10.1.2.3:40000", "198.51.100.7")
+	send("203.0.113.10:40000", "198.51.100.8")
+	if guard.ips["198.51.100.7"] != 1 || guard.ips["203.0.113.10"] != 1 || len(guard.ips) != 2 {
+		t.Errorf("刷量防护收到的客户端IP为%v", guard.ips)
+	}
+}
+
+func BenchmarkRedirectToTarget(b *testing.B) {
+	slugs, links := benchRedirectLinks(1000)
+	router := newRedirectRouter(links)
+
+	requests := make([]*http.Request, len(slugs))
+	for i, slug := range slugs {
+		requests[i] = newRedirectRequest(slug)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, requests[i%len(requests)])
+		if w.Code != http.StatusTemporaryRedirect {
+			b.Fatalf("状态码%d，期望307", w.Code)
+		}
+	}
+}
+
+func BenchmarkRedirectToTargetParallel(b *testing.B) {
+	slugs, links := benchRedirectLinks(1000)
+	router := newRedirectRouter(links)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newRedirectRequest(slugs[i%len(slugs)]))
+			if w.Code != http.StatusTemporaryRedirect {
+				b.Fatalf("状态码%d，期望307", w.Code)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkRedirectToTargetMissing(b *testing.B) {
+	router := newRedirectRouter(map[string]*entities.ShortLink{})
+	req := newRedirectRequest("missing")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+	}
+}
+
+func TestUpdateShortLinkNotFound(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	slugs, links := benchRedirectLinks(1)
+	handler := NewShortLinkHandler(&stubShortLinks{links: links}, &stubClicks{}, &stubSUN{}, &stubTapGuard{}, "https://s.example.com")
+
+	router := gin.New()
+	router.PUT("/:id", handler.UpdateShortLink)
+
+	tests := []struct {
+		id   uuid.UUID
+		want int
+	}{
+		{links[slugs[0]].ID, http.StatusOK},
+		{uuid.New(), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/"+tt.id.String(), strings.NewReader(`{"targetUrl":"https://example.com/new"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("更新短链接%s返回%d，期望%d", tt.id, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/services/stores"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultStoreStatsDays 未指定统计区间时默认统计最近的天数
+const defaultStoreStatsDays = 30
+
+// StoreHandler 处理门店相关的API请求
+type StoreHandler struct {
+	service stores.Service
+}
+
+// NewStoreHandler 创建门店处理程序
+func NewStoreHandler(service stores.Service) *StoreHandler {
+	return &StoreHandler{
+		service: service,
+	}
+}
+
+// assignStoreCardsRequest 分配门店卡片的请求
+type assignStoreCardsRequest struct {
+	Cards []entities.StoreCardAssignment `json:"cards" binding:"required,min=1,dive"`
+}
+
+// unassignStoreCardsRequest 移出门店卡片的请求
+type unassignStoreCardsRequest struct {
+	CardIDs []uuid.UUID `json:"cardIds" binding:"required,min=1"`
+}
+
+// CreateStore 创建门店
+func (h *StoreHandler) CreateStore(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	var dto entities.StoreDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, err := h.service.Create(c.Request.Context(), merchantID, &dto)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, store)
+}
+
+// ListStores 分页获取商户的门店，可按city过滤
+func (h *StoreHandler) ListStores(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	page, pageSize := storePagination(c)
+	list, total, err := h.service.List(c.Request.Context(), merchantID, c.Query("city"), page, pageSize)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": list,
+		"meta": gin.H{
+			"currentPage":  page,
+			"itemsPerPage": pageSize,
+			"totalItems":   total,
+			"totalPages":   (total + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// GetStore 获取门店
+func (h *StoreHandler) GetStore(c *gin.Context) {
+	merchantID, id, ok := storeParams(c)
+	if !ok {
+		return
+	}
+
+	store, err := h.service.Get(c.Request.Context(), merchantID, id)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, store)
+}
+
+// UpdateStore 整体修改门店信息
+func (h *StoreHandler) UpdateStore(c *gin.Context) {
+	merchantID, id, ok := storeParams(c)
+	if !ok {
+		return
+	}
+
+	var dto entities.StoreDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, err := h.service.Update(c.Request.Context(), merchantID, id, &dto)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, store)
+}
+
+// DeleteStore 删除没有卡片的门店
+func (h *StoreHandler) DeleteStore(c *gin.Context) {
+	merchantID, id, ok := storeParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), merchantID, id); err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "门店已删除"})
+}
+
+// ListStoreCards 分页获取门店的卡片及其位置
+func (h *StoreHandler) ListStoreCards(c *gin.Context) {
+	merchantID, id, ok := storeParams(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := storePagination(c)
+	cards, total, err := h.service.ListCards(c.Request.Context(), merchantID, id, page, pageSize)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": cards,
+		"meta": gin.H{
+			"currentPage":  page,
+			"itemsPerPage": pageSize,
+			"totalItems":   total,
+			"totalPages":   (total + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// AssignStoreCards 把卡片分配到门店的指定位置
+func (h *StoreHandler) AssignStoreCards(c *gin.Context) {
+	merchantID, id, ok := storeParams(c)
+	if !ok {
+		return
+	}
+
+	var req assignStoreCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	assigned, err := h.service.AssignCards(c.Request.Context(), merchantID, id, req.Cards)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"assigned": assigned})
+}
+
+// UnassignStoreCards 把卡片移出门店
+func (h *StoreHandler) UnassignStoreCards(c *gin.Context) {
+	merchantID, id, ok := storeParams(c)
+	if !ok {
+		return
+	}
+
+	var req unassignStoreCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	removed, err := h.service.UnassignCards(c.Request.Context(), merchantID, id, req.CardIDs)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
+// CompareStores 汇总各门店的碰卡，from/to为RFC3339格式，默认为最近30天
+func (h *StoreHandler) CompareStores(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	from, to, err := storeStatsRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summaries, err := h.service.Compare(c.Request.Context(), merchantID, from, to)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": from,
+		"to":   to,
+		"data": summaries,
+	})
+}
+
+// GetStoreStats 获取单个门店按日期、小时、位置和卡片的碰卡明细
+func (h *StoreHandler) GetStoreStats(c *gin.Context) {
+	merchantID, id, ok := storeParams(c)
+	if !ok {
+		return
+	}
+
+	from, to, err := storeStatsRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.service.Stats(c.Request.Context(), merchantID, id, from, to)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// storeStatsRange 解析统计区间，未指定时默认为最近30天
+func storeStatsRange(c *gin.Context) (time.Time, time.Time, error) {
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.AddDate(0, 0, -defaultStoreStatsDays)
+	if from != nil {
+		start = *from
+	}
+	return start, end, nil
+}
+
+// storePagination 解析分页参数
+func storePagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	return page, pageSize
+}
+
+// storeParams 解析商户ID和门店ID，失败时已写入响应
+func storeParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "门店ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return merchantID, id, true
+}
+
+// respondStoreError 将门店服务的错误映射为HTTP状态码
+func respondStoreError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, stores.ErrInvalidStore):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, stores.ErrStoreNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, stores.ErrStoreExists), errors.Is(err, stores.ErrStoreHasCards):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/services/sun"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SUNHandler 处理NTAG 424 DNA安全动态消息(SUN)设置相关的API请求
+type SUNHandler struct {
+	service sun.Service
+}
+
+// NewSUNHandler 创建SUN处理程序
+func NewSUNHandler(service sun.Service) *SUNHandler {
+	return &SUNHandler{
+		service: service,
+	}
+}
+
+// EnableSUN 为卡片开启SUN校验，已开启时轮换密钥
+// 响应中的密钥明文只返回这一次，需要写入标签后妥善处理
+func (h *SUNHandler) EnableSUN(c *gin.Context) {
+	merchantID, cardID, ok := parseSUNParams(c)
+	if !ok {
+		return
+	}
+
+	provisioning, err := h.service.Enable(c.Request.Context(), merchantID, cardID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// 响应包含密钥明文，禁止任何中间层缓存
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, provisioning)
+}
+
+// GetSUNStatus 获取卡片的SUN状态
+func (h *SUNHandler) GetSUNStatus(c *gin.Context) {
+	merchantID, cardID, ok := parseSUNParams(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.service.Status(c.Request.Context(), merchantID, cardID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// DisableSUN 关闭卡片的SUN校验
+func (h *SUNHandler) DisableSUN(c *gin.Context) {
+	merchantID, cardID, ok := parseSUNParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Disable(c.Request.Context(), merchantID, cardID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已关闭卡片的SUN校验"})
+}
+
+// respondError 将SUN服务的错误转换为HTTP响应
+func (h *SUNHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrCardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, sun.ErrInvalidUID):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, sun.ErrNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// parseSUNParams 解析商户ID和卡片ID，失败时已写入响应
+func parseSUNParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	cardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "卡片ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return merchantID, cardID, true
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"nfc-service/internal/services/tagmanifest"
+	"nfc-service/pkg/ndef"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TagManifestHandler 处理NFC标签写卡清单相关的API请求
+type TagManifestHandler struct {
+	service tagmanifest.Service
+}
+
+// NewTagManifestHandler 创建写卡清单处理程序
+func NewTagManifestHandler(service tagmanifest.Service) *TagManifestHandler {
+	return &TagManifestHandler{
+		service: service,
+	}
+}
+
+// tagManifestRequest 批量生成写卡清单的请求
+type tagManifestRequest struct {
+	CardIDs        []uuid.UUID `json:"cardIds" binding:"required,min=1"`
+	TagType        string      `json:"tagType"`
+	Text           string      `json:"text"`
+	TextLang       string      `json:"textLang"`
+	AndroidPackage string      `json:"androidPackage"`
+}
+
+// GetCardManifest 获取单张卡片的写卡数据
+// 查询参数: tagType、text、textLang、androidPackage，format=csv时返回CSV
+func (h *TagManifestHandler) GetCardManifest(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	cardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "卡片ID格式无效"})
+		return
+	}
+
+	req := &tagManifestRequest{
+		CardIDs:        []uuid.UUID{cardID},
+		TagType:        c.Query("tagType"),
+		Text:           c.Query("text"),
+		TextLang:       c.Query("textLang"),
+		AndroidPackage: c.Query("androidPackage"),
+	}
+	h.respond(c, merchantID, req)
+}
+
+// BuildManifest 批量生成卡片的写卡清单，format=csv时返回CSV文件
+func (h *TagManifestHandler) BuildManifest(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	var req tagManifestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respond(c, merchantID, &req)
+}
+
+// respond 生成写卡清单并按format参数输出JSON或CSV
+func (h *TagManifestHandler) respond(c *gin.Context, merchantID uuid.UUID, req *tagManifestRequest) {
+	opts := &tagmanifest.Options{
+		Text:           req.Text,
+		TextLang:       req.TextLang,
+		AndroidPackage: req.AndroidPackage,
+	}
+	if req.TagType != "" {
+		tagType, err := ndef.ParseTagType(req.TagType)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		opts.TagType = tagType
+	}
+
+	manifest, err := h.service.Build(c.Request.Context(), merchantID, req.CardIDs, opts)
+	if err != nil {
+		if errors.Is(err, tagmanifest.ErrInvalidOptions) || errors.Is(err, tagmanifest.ErrTooManyCards) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, manifest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.service.WriteCSV(&buf, manifest); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	fileName := fmt.Sprintf("ndef-manifest-%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
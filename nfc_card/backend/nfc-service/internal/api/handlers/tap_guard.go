@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/services/tapguard"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultTapFilterStatsDays 未指定统计区间时默认统计最近的天数
+const defaultTapFilterStatsDays = 30
+
+// TapGuardHandler 处理刷量防护相关的API请求
+type TapGuardHandler struct {
+	service tapguard.Service
+}
+
+// NewTapGuardHandler 创建刷量防护处理程序
+func NewTapGuardHandler(service tapguard.Service) *TapGuardHandler {
+	return &TapGuardHandler{
+		service: service,
+	}
+}
+
+// GetSettings 获取商户生效的刷量防护阈值
+func (h *TapGuardHandler) GetSettings(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	settings, err := h.service.GetSettings(c.Request.Context(), merchantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings 整体修改商户的刷量防护阈值
+func (h *TapGuardHandler) UpdateSettings(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	var dto entities.TapGuardSettingsDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(c.Request.Context(), merchantID, &dto)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// ResetSettings 恢复默认的刷量防护阈值
+func (h *TapGuardHandler) ResetSettings(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	settings, err := h.service.ResetSettings(c.Request.Context(), merchantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetFilterStats 获取每天按原因被过滤的碰卡次数，from/to为YYYY-MM-DD格式，默认为最近30天
+func (h *TapGuardHandler) GetFilterStats(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		if to, err = time.ParseInLocation("2006-01-02", value, time.Local); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "结束日期格式无效，应为YYYY-MM-DD"})
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -defaultTapFilterStatsDays)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.ParseInLocation("2006-01-02", value, time.Local); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "开始日期格式无效，应为YYYY-MM-DD"})
+			return
+		}
+	}
+
+	stats, err := h.service.FilterStats(c.Request.Context(), merchantID, from, to)
+	if err != nil {
+		if errors.Is(err, tapguard.ErrInvalidRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
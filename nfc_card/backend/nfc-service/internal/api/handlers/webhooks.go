@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/services/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WebhookHandler 处理商户Webhook相关的API请求
+type WebhookHandler struct {
+	service webhooks.Service
+}
+
+// NewWebhookHandler 创建Webhook处理程序
+func NewWebhookHandler(service webhooks.Service) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+	}
+}
+
+// ListEventTypes 获取可订阅的事件类型
+func (h *WebhookHandler) ListEventTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.service.EventTypes()})
+}
+
+// CreateEndpoint 注册Webhook接收端，响应中的签名密钥只返回这一次
+func (h *WebhookHandler) CreateEndpoint(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	var dto entities.CreateWebhookEndpointDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := h.service.CreateEndpoint(c.Request.Context(), merchantID, &dto)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, endpoint)
+}
+
+// ListEndpoints 获取商户的Webhook接收端
+func (h *WebhookHandler) ListEndpoints(c *gin.Context) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return
+	}
+
+	endpoints, err := h.service.ListEndpoints(c.Request.Context(), merchantID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": endpoints})
+}
+
+// GetEndpoint 获取Webhook接收端
+func (h *WebhookHandler) GetEndpoint(c *gin.Context) {
+	merchantID, id, ok := webhookParams(c)
+	if !ok {
+		return
+	}
+
+	endpoint, err := h.service.GetEndpoint(c.Request.Context(), merchantID, id)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// UpdateEndpoint 修改Webhook接收端，active设为true可重新启用被自动停用的接收端
+func (h *WebhookHandler) UpdateEndpoint(c *gin.Context) {
+	merchantID, id, ok := webhookParams(c)
+	if !ok {
+		return
+	}
+
+	var dto entities.UpdateWebhookEndpointDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := h.service.UpdateEndpoint(c.Request.Context(), merchantID, id, &dto)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// DeleteEndpoint 删除Webhook接收端及其投递记录
+func (h *WebhookHandler) DeleteEndpoint(c *gin.Context) {
+	merchantID, id, ok := webhookParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteEndpoint(c.Request.Context(), merchantID, id); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook接收端已删除"})
+}
+
+// RotateSecret 更换Webhook签名密钥
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	merchantID, id, ok := webhookParams(c)
+	if !ok {
+		return
+	}
+
+	endpoint, err := h.service.RotateSecret(c.Request.Context(), merchantID, id)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// PingEndpoint 向接收端投递测试事件并返回投递结果
+func (h *WebhookHandler) PingEndpoint(c *gin.Context) {
+	merchantID, id, ok := webhookParams(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.Ping(c.Request.Context(), merchantID, id)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// ListDeliveries 分页获取接收端的投递记录，可按status过滤
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	merchantID, id, ok := webhookParams(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	status := entities.WebhookDeliveryStatus(c.Query("status"))
+	list, total, err := h.service.ListDeliveries(c.Request.Context(), merchantID, id, status, page, pageSize)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": list,
+		"meta": gin.H{
+			"currentPage":  page,
+			"itemsPerPage": pageSize,
+			"totalItems":   total,
+			"totalPages":   (total + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// GetDelivery 获取投递记录，包含请求体和接收端的响应
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	merchantID, id, deliveryID, ok := webhookDeliveryParams(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.GetDelivery(c.Request.Context(), merchantID, id, deliveryID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// RedeliverDelivery 以相同的事件ID和内容重新投递
+func (h *WebhookHandler) RedeliverDelivery(c *gin.Context) {
+	merchantID, id, deliveryID, ok := webhookDeliveryParams(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.Redeliver(c.Request.Context(), merchantID, id, deliveryID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// webhookParams 解析商户ID和路径中的接收端ID，失败时已写入响应
+func webhookParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	merchantID, err := uuid.Parse(c.GetString("tenantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商户ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "接收端ID格式无效"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return merchantID, id, true
+}
+
+// webhookDeliveryParams 解析商户ID、接收端ID和投递记录ID，失败时已写入响应
+func webhookDeliveryParams(c *gin.Context) (uuid.UUID, uuid.UUID, uuid.UUID, bool) {
+	merchantID, id, ok := webhookParams(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	deliveryID, err := uuid.Parse(c.Param("deliveryID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "投递记录ID格式无效"})
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	return merchantID, id, deliveryID, true
+}
+
+// respondWebhookError 将Webhook服务的错误映射为HTTP状态码
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, webhooks.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, webhooks.ErrWebhookNotFound), errors.Is(err, webhooks.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, webhooks.ErrTooManyEndpoints), errors.Is(err, webhooks.ErrEndpointDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,12 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// TrustProxies 设置信任的反向代理，只有来自这些地址的请求才从X-Forwarded-For/X-Real-IP读取客户端IP
+// proxies为空时不信任任何转发头，ClientIP返回连接的对端地址；gin默认信任所有来源，必须显式设置
+func TrustProxies(engine *gin.Engine, proxies []string) error {
+	if len(proxies) == 0 {
+		return engine.SetTrustedProxies(nil)
+	}
+	return engine.SetTrustedProxies(proxies)
+}
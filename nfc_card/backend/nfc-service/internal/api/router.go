@@ -1,19 +1,40 @@
 package api
 
 import (
+	"log"
+
 	"nfc-service/internal/api/handlers"
 	"nfc-service/internal/api/middleware"
 	"nfc-service/internal/config"
+	"nfc-service/internal/services/campaigns"
+	"nfc-service/internal/services/cardimport"
 	"nfc-service/internal/services/cards"
+	"nfc-service/internal/services/clicks"
+	"nfc-service/internal/services/edgesync"
+	"nfc-service/internal/services/landing"
+	"nfc-service/internal/services/qrcodes"
+	"nfc-service/internal/services/schedules"
 	"nfc-service/internal/services/shortlinks"
+	"nfc-service/internal/services/stores"
+	"nfc-service/internal/services/sun"
+	"nfc-service/internal/services/tagmanifest"
+	"nfc-service/internal/services/tapguard"
+	"nfc-service/internal/services/transfers"
+	"nfc-service/internal/services/webhooks"
 
 	"github.com/gin-gonic/gin"
 )
 
 // NewRouter 创建并配置API路由器
-func NewRouter(cfg *config.Config, cardService cards.Service, shortlinkService *shortlinks.ShortlinkService) *gin.Engine {
+func NewRouter(cfg *config.Config, cardService cards.Service, shortlinkService *shortlinks.ShortlinkService, clickService clicks.Service, edgeSyncService edgesync.Service, cardImportService cardimport.Service, tagManifestService tagmanifest.Service, sunService sun.Service, qrCodeService qrcodes.Service, landingService landing.Service, campaignService campaigns.Service, scheduleService schedules.Service, storeService stores.Service, tapGuardService tapguard.Service, transferService transfers.Service, webhookService webhooks.Service) *gin.Engine {
 	router := gin.Default()
 
+	// 刷量防护按客户端IP限流，只信任配置的反向代理转发的IP，避免伪造X-Forwarded-For绕过
+	if err := middleware.TrustProxies(router, cfg.Server.TrustedProxies); err != nil {
+		log.Printf("信任代理配置无效，忽略转发的客户端IP: %v", err)
+		middleware.TrustProxies(router, nil)
+	}
+
 	// 添加中间件
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
@@ -28,7 +49,23 @@ func NewRouter(cfg *config.Config, cardService cards.Service, shortlinkService *
 
 	// 初始化处理程序
 	cardHandler := handlers.NewCardHandler(cardService)
-	shortLinkHandler := handlers.NewShortLinkHandler(shortlinkService, cfg.ShortLink.BaseURL)
+	shortLinkHandler := handlers.NewShortLinkHandler(shortlinkService, clickService, sunService, tapGuardService, cfg.ShortLink.BaseURL)
+	clickHandler := handlers.NewClickHandler(clickService, shortlinkService)
+	edgeSyncHandler := handlers.NewEdgeSyncHandler(edgeSyncService)
+	cardImportHandler := handlers.NewCardImportHandler(cardImportService, cfg.CardImport.MaxFileSizeMB)
+	tagManifestHandler := handlers.NewTagManifestHandler(tagManifestService)
+	sunHandler := handlers.NewSUNHandler(sunService)
+	qrCodeHandler := handlers.NewQRCodeHandler(qrCodeService)
+	landingHandler := handlers.NewLandingHandler(landingService)
+	campaignHandler := handlers.NewCampaignHandler(campaignService)
+	scheduleHandler := handlers.NewScheduleHandler(scheduleService)
+	storeHandler := handlers.NewStoreHandler(storeService)
+	tapGuardHandler := handlers.NewTapGuardHandler(tapGuardService)
+	transferHandler := handlers.NewCardTransferHandler(transferService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+
+	// NFC落地页，默认短链接跳转到这里（无需认证）
+	router.GET("/nfc-landing/:uid", landingHandler.RenderLanding)
 
 	// API路由组 - 公共路由
 	apiV1 := router.Group("/api/v1")
@@ -58,6 +95,24 @@ func NewRouter(cfg *config.Config, cardService cards.Service, shortlinkService *
 			nfcCards.PUT("/:id", cardHandler.UpdateCard)
 			nfcCards.DELETE("/:id", cardHandler.DeleteCard)
 			nfcCards.POST("/activate", cardHandler.ActivateCard)
+			nfcCards.POST("/:id/bind", cardHandler.BindCard)
+			nfcCards.POST("/:id/unbind", cardHandler.UnbindCard)
+			nfcCards.POST("/:id/deactivate", cardHandler.DeactivateCard)
+			nfcCards.POST("/:id/reactivate", cardHandler.ReactivateCard)
+			nfcCards.GET("/:id/events", cardHandler.GetCardEvents)
+			nfcCards.POST("/import", cardImportHandler.ImportCards)
+			nfcCards.GET("/import-jobs", cardImportHandler.ListImportJobs)
+			nfcCards.GET("/import-jobs/:jobID", cardImportHandler.GetImportJob)
+			nfcCards.GET("/:id/ndef", tagManifestHandler.GetCardManifest)
+			nfcCards.POST("/ndef-manifest", tagManifestHandler.BuildManifest)
+			nfcCards.POST("/qrcodes", qrCodeHandler.RenderCardQRCodes)
+			nfcCards.GET("/:id/sun", sunHandler.GetSUNStatus)
+			nfcCards.POST("/:id/sun", sunHandler.EnableSUN)
+			nfcCards.DELETE("/:id/sun", sunHandler.DisableSUN)
+			nfcCards.GET("/:id/schedule", scheduleHandler.GetSchedule)
+			nfcCards.PUT("/:id/schedule", scheduleHandler.PutSchedule)
+			nfcCards.DELETE("/:id/schedule", scheduleHandler.DeleteSchedule)
+			nfcCards.GET("/:id/schedule/active", scheduleHandler.GetActiveEntry)
 		}
 
 		// 短链接路由
@@ -67,11 +122,109 @@ func NewRouter(cfg *config.Config, cardService cards.Service, shortlinkService *
 			shortlinks.POST("", shortLinkHandler.CreateShortLink)
 			shortlinks.GET("/:id", shortLinkHandler.GetShortLinkByID)
 			shortlinks.GET("/slug/:slug", shortLinkHandler.GetShortLinkBySlug)
+			shortlinks.GET("/slug/:slug/clicks", clickHandler.ListClicks)
+			shortlinks.GET("/slug/:slug/breakdown", clickHandler.GetBreakdown)
 			shortlinks.GET("/merchant/:merchantID", shortLinkHandler.GetShortLinksByMerchantID)
 			shortlinks.GET("/card/:cardID", shortLinkHandler.GetShortLinksByNfcCardID)
 			shortlinks.PUT("/:id", shortLinkHandler.UpdateShortLink)
+			shortlinks.GET("/:id/qrcode", qrCodeHandler.GetShortLinkQRCode)
+			shortlinks.GET("/:id/preview", shortLinkHandler.GetPreview)
+			shortlinks.GET("/:id/variants/stats", shortLinkHandler.GetVariantStats)
+			shortlinks.POST("/:id/variants/:variantID/promote", shortLinkHandler.PromoteVariant)
 			shortlinks.DELETE("/:id", shortLinkHandler.DeleteShortLink)
 		}
+
+		// 落地页模板路由
+		landingPages := protectedAPI.Group("/landing")
+		landingPages.Use(middleware.TenantAuthMiddleware(cfg.JWT.Secret))
+		{
+			landingPages.GET("/template", landingHandler.GetTemplate)
+			landingPages.PUT("/template", landingHandler.UpdateTemplate)
+			landingPages.DELETE("/template", landingHandler.ResetTemplate)
+			landingPages.POST("/preview", landingHandler.PreviewLanding)
+		}
+
+		// 营销活动路由
+		campaignRoutes := protectedAPI.Group("/campaigns")
+		campaignRoutes.Use(middleware.TenantAuthMiddleware(cfg.JWT.Secret))
+		{
+			campaignRoutes.POST("", campaignHandler.CreateCampaign)
+			campaignRoutes.GET("", campaignHandler.ListCampaigns)
+			campaignRoutes.GET("/:id", campaignHandler.GetCampaign)
+			campaignRoutes.PUT("/:id", campaignHandler.UpdateCampaign)
+			campaignRoutes.GET("/:id/cards", campaignHandler.ListCampaignCards)
+			campaignRoutes.POST("/:id/cards", campaignHandler.AddCampaignCards)
+			campaignRoutes.DELETE("/:id/cards", campaignHandler.RemoveCampaignCards)
+			campaignRoutes.POST("/:id/activate", campaignHandler.ActivateCampaign)
+			campaignRoutes.POST("/:id/end", campaignHandler.EndCampaign)
+			campaignRoutes.POST("/:id/cancel", campaignHandler.CancelCampaign)
+			campaignRoutes.GET("/:id/stats", campaignHandler.GetCampaignStats)
+		}
+
+		// 门店路由
+		storeRoutes := protectedAPI.Group("/stores")
+		storeRoutes.Use(middleware.TenantAuthMiddleware(cfg.JWT.Secret))
+		{
+			storeRoutes.POST("", storeHandler.CreateStore)
+			storeRoutes.GET("", storeHandler.ListStores)
+			storeRoutes.GET("/stats", storeHandler.CompareStores)
+			storeRoutes.GET("/:id", storeHandler.GetStore)
+			storeRoutes.PUT("/:id", storeHandler.UpdateStore)
+			storeRoutes.DELETE("/:id", storeHandler.DeleteStore)
+			storeRoutes.GET("/:id/cards", storeHandler.ListStoreCards)
+			storeRoutes.POST("/:id/cards", storeHandler.AssignStoreCards)
+			storeRoutes.DELETE("/:id/cards", storeHandler.UnassignStoreCards)
+			storeRoutes.GET("/:id/stats", storeHandler.GetStoreStats)
+		}
+
+		// 刷量防护路由
+		tapGuardRoutes := protectedAPI.Group("/tap-guard")
+		tapGuardRoutes.Use(middleware.TenantAuthMiddleware(cfg.JWT.Secret))
+		{
+			tapGuardRoutes.GET("/settings", tapGuardHandler.GetSettings)
+			tapGuardRoutes.PUT("/settings", tapGuardHandler.UpdateSettings)
+			tapGuardRoutes.DELETE("/settings", tapGuardHandler.ResetSettings)
+			tapGuardRoutes.GET("/stats", tapGuardHandler.GetFilterStats)
+		}
+
+		// 卡片转让路由
+		transferRoutes := protectedAPI.Group("/card-transfers")
+		transferRoutes.Use(middleware.TenantAuthMiddleware(cfg.JWT.Secret))
+		{
+			transferRoutes.POST("", transferHandler.InitiateTransfer)
+			transferRoutes.GET("", transferHandler.ListTransfers)
+			transferRoutes.GET("/:id", transferHandler.GetTransfer)
+			transferRoutes.POST("/:id/accept", transferHandler.AcceptTransfer)
+			transferRoutes.POST("/:id/reject", transferHandler.RejectTransfer)
+			transferRoutes.POST("/:id/cancel", transferHandler.CancelTransfer)
+		}
+
+		// 商户Webhook路由
+		webhookRoutes := protectedAPI.Group("/webhooks")
+		webhookRoutes.Use(middleware.TenantAuthMiddleware(cfg.JWT.Secret))
+		{
+			webhookRoutes.GET("/event-types", webhookHandler.ListEventTypes)
+			webhookRoutes.POST("", webhookHandler.CreateEndpoint)
+			webhookRoutes.GET("", webhookHandler.ListEndpoints)
+			webhookRoutes.GET("/:id", webhookHandler.GetEndpoint)
+			webhookRoutes.PUT("/:id", webhookHandler.UpdateEndpoint)
+			webhookRoutes.DELETE("/:id", webhookHandler.DeleteEndpoint)
+			webhookRoutes.POST("/:id/rotate-secret", webhookHandler.RotateSecret)
+			webhookRoutes.POST("/:id/ping", webhookHandler.PingEndpoint)
+			webhookRoutes.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+			webhookRoutes.GET("/:id/deliveries/:deliveryID", webhookHandler.GetDelivery)
+			webhookRoutes.POST("/:id/deliveries/:deliveryID/redeliver", webhookHandler.RedeliverDelivery)
+		}
+
+		// 管理员路由
+		admin := protectedAPI.Group("/admin")
+		admin.Use(middleware.RoleMiddleware(middleware.RoleAdmin))
+		{
+			admin.POST("/edge-sync/reconcile", edgeSyncHandler.Reconcile)
+			admin.GET("/card-transfers", transferHandler.AdminListTransfers)
+			admin.POST("/card-transfers", transferHandler.AdminForceTransfer)
+			admin.POST("/card-transfers/:id/accept", transferHandler.AdminAcceptTransfer)
+		}
 	}
 
 	return router
@@ -16,6 +16,15 @@ type Config struct {
 	Cloudflare CloudflareConfig `json:"cloudflare" mapstructure:"cloudflare"`
 	Kafka      KafkaConfig      `json:"kafka" mapstructure:"kafka"`
 	ShortLink  ShortLinkConfig  `json:"shortlink" mapstructure:"shortlink"`
+	Clicks     ClickConfig      `json:"clicks" mapstructure:"clicks"`
+	Cards      CardsConfig      `json:"cards" mapstructure:"cards"`
+	CardImport CardImportConfig `json:"card_import" mapstructure:"card_import"`
+	SUN        SUNConfig        `json:"sun" mapstructure:"sun"`
+	QRCode     QRCodeConfig     `json:"qrcode" mapstructure:"qrcode"`
+	Landing    LandingConfig    `json:"landing" mapstructure:"landing"`
+	Campaigns  CampaignsConfig  `json:"campaigns" mapstructure:"campaigns"`
+	TapGuard   TapGuardConfig   `json:"tap_guard" mapstructure:"tap_guard"`
+	Webhooks   WebhooksConfig   `json:"webhooks" mapstructure:"webhooks"`
 	Nacos      NacosConfig      `json:"nacos" mapstructure:"nacos"`
 }
 
@@ -25,6 +34,9 @@ type ServerConfig struct {
 	ReadTimeoutSeconds  int    `json:"read_timeout_seconds" mapstructure:"read_timeout_seconds"`
 	WriteTimeoutSeconds int    `json:"write_timeout_seconds" mapstructure:"write_timeout_seconds"`
 	IdleTimeoutSeconds  int    `json:"idle_timeout_seconds" mapstructure:"idle_timeout_seconds"`
+	// TrustedProxies 信任的反向代理IP或CIDR，只读取这些地址转发的X-Forwarded-For/X-Real-IP
+	// 为空时不信任任何转发头，客户端IP取连接的对端地址
+	TrustedProxies []string `json:"trusted_proxies" mapstructure:"trusted_proxies"`
 }
 
 // DatabaseConfig 数据库配置
@@ -44,14 +56,126 @@ type JWTConfig struct {
 
 // CloudflareConfig Cloudflare配置
 type CloudflareConfig struct {
-	APIToken string `json:"api_token" mapstructure:"api_token"`
-	ZoneID   string `json:"zone_id" mapstructure:"zone_id"`
-	BaseURL  string `json:"base_url" mapstructure:"base_url"`
+	APIToken                 string `json:"api_token" mapstructure:"api_token"`
+	ZoneID                   string `json:"zone_id" mapstructure:"zone_id"`
+	BaseURL                  string `json:"base_url" mapstructure:"base_url"`
+	AccountID                string `json:"account_id" mapstructure:"account_id"`                                 // Workers KV所属账户ID
+	KVNamespaceID            string `json:"kv_namespace_id" mapstructure:"kv_namespace_id"`                       // 存储短链接重定向的KV命名空间ID
+	SyncIntervalSeconds      int    `json:"sync_interval_seconds" mapstructure:"sync_interval_seconds"`           // 发件箱同步间隔（秒）
+	ReconcileIntervalMinutes int    `json:"reconcile_interval_minutes" mapstructure:"reconcile_interval_minutes"` // 全量对账间隔（分钟）
+}
+
+// EdgeSyncEnabled 是否启用Workers KV边缘同步
+func (c CloudflareConfig) EdgeSyncEnabled() bool {
+	return c.APIToken != "" && c.AccountID != "" && c.KVNamespaceID != ""
 }
 
 // ShortLinkConfig 短链接配置
 type ShortLinkConfig struct {
-	BaseURL string `json:"base_url" mapstructure:"base_url"`
+	BaseURL       string   `json:"base_url" mapstructure:"base_url"`
+	SlugLength    int      `json:"slug_length" mapstructure:"slug_length"`       // 随机Slug长度
+	SlugAlphabet  string   `json:"slug_alphabet" mapstructure:"slug_alphabet"`   // 随机Slug字符集，为空时使用默认字符集
+	ReservedSlugs []string `json:"reserved_slugs" mapstructure:"reserved_slugs"` // 额外的保留字
+
+	CacheSize                 int `json:"cache_size" mapstructure:"cache_size"`                                     // 重定向缓存的最大条目数
+	CacheTTLSeconds           int `json:"cache_ttl_seconds" mapstructure:"cache_ttl_seconds"`                       // 缓存过期时间（秒）
+	NegativeCacheTTLSeconds   int `json:"negative_cache_ttl_seconds" mapstructure:"negative_cache_ttl_seconds"`     // 不存在的slug的缓存过期时间（秒）
+	ClickFlushIntervalSeconds int `json:"click_flush_interval_seconds" mapstructure:"click_flush_interval_seconds"` // 点击次数批量写入间隔（秒）
+	DeepLinkTimeoutMillis     int `json:"deep_link_timeout_millis" mapstructure:"deep_link_timeout_millis"`         // 深度链接中间页唤起App失败后回退到网页的等待时间（毫秒）
+	PreviewRefreshMinutes     int `json:"preview_refresh_minutes" mapstructure:"preview_refresh_minutes"`           // 从视频自动填充的分享预览多久后重新获取（分钟）
+}
+
+// ClickConfig 点击事件采集配置
+type ClickConfig struct {
+	BatchSize            int    `json:"batch_size" mapstructure:"batch_size"`                         // 批量写入大小
+	BufferSize           int    `json:"buffer_size" mapstructure:"buffer_size"`                       // 内存缓冲区大小
+	FlushIntervalSeconds int    `json:"flush_interval_seconds" mapstructure:"flush_interval_seconds"` // 刷新间隔（秒）
+	GeoIPDBPath          string `json:"geoip_db_path" mapstructure:"geoip_db_path"`                   // 本地GeoIP数据库路径，为空时不解析地理位置
+}
+
+// CardsConfig NFC卡片生命周期配置
+type CardsConfig struct {
+	ExpirySweepIntervalSeconds int `json:"expiry_sweep_interval_seconds" mapstructure:"expiry_sweep_interval_seconds"` // 过期扫描间隔（秒）
+	ExpiryBatchSize            int `json:"expiry_batch_size" mapstructure:"expiry_batch_size"`                         // 每批处理的到期卡片数量
+	TransferExpiryHours        int `json:"transfer_expiry_hours" mapstructure:"transfer_expiry_hours"`                 // 卡片转让等待目标商户接受的有效期（小时）
+}
+
+// CardImportConfig NFC卡片批量导入配置
+type CardImportConfig struct {
+	ChunkSize      int `json:"chunk_size" mapstructure:"chunk_size"`             // 每个事务插入的卡片数量
+	AsyncThreshold int `json:"async_threshold" mapstructure:"async_threshold"`   // 超过该行数时转为异步任务
+	MaxRows        int `json:"max_rows" mapstructure:"max_rows"`                 // 单个文件允许的最大行数
+	MaxFileSizeMB  int `json:"max_file_size_mb" mapstructure:"max_file_size_mb"` // 上传文件大小上限（MB）
+	Workers        int `json:"workers" mapstructure:"workers"`                   // 同时执行的异步导入任务数
+}
+
+// SUNConfig NTAG 424 DNA安全动态消息(SUN)校验配置
+type SUNConfig struct {
+	MasterKey        string `json:"master_key" mapstructure:"master_key"`                 // 派生卡片密钥的AES-128主密钥（十六进制）
+	KeyEncryptionKey string `json:"key_encryption_key" mapstructure:"key_encryption_key"` // 加密存储卡片密钥的AES-256密钥（十六进制）
+	SystemIdentifier string `json:"system_identifier" mapstructure:"system_identifier"`   // 密钥分散输入中的系统标识
+	PICCDataParam    string `json:"picc_data_param" mapstructure:"picc_data_param"`       // 加密PICC数据的URL参数名
+	MACParam         string `json:"mac_param" mapstructure:"mac_param"`                   // SDM MAC的URL参数名
+	CacheTTLSeconds  int    `json:"cache_ttl_seconds" mapstructure:"cache_ttl_seconds"`   // 卡片密钥的进程内缓存时间（秒）
+}
+
+// Configured 是否配置了主密钥和密钥加密密钥，未配置时不能为卡片开启SUN
+func (c SUNConfig) Configured() bool {
+	return c.MasterKey != "" && c.KeyEncryptionKey != ""
+}
+
+// QRCodeConfig 短链接二维码生成配置
+type QRCodeConfig struct {
+	DefaultSize         int `json:"default_size" mapstructure:"default_size"`                     // 默认图片边长（像素）
+	MaxSize             int `json:"max_size" mapstructure:"max_size"`                             // 允许的最大图片边长（像素）
+	MaxBatchSize        int `json:"max_batch_size" mapstructure:"max_batch_size"`                 // 单次批量生成的最大卡片数量
+	LogoMaxSizeKB       int `json:"logo_max_size_kb" mapstructure:"logo_max_size_kb"`             // 商户Logo的最大下载大小（KB）
+	LogoTimeoutSeconds  int `json:"logo_timeout_seconds" mapstructure:"logo_timeout_seconds"`     // 下载商户Logo的超时时间（秒）
+	LogoCacheTTLSeconds int `json:"logo_cache_ttl_seconds" mapstructure:"logo_cache_ttl_seconds"` // 商户Logo的进程内缓存时间（秒）
+}
+
+// LandingConfig NFC落地页配置
+type LandingConfig struct {
+	ContentServiceURL       string `json:"content_service_url" mapstructure:"content_service_url"`               // content-service地址，用于获取视频播放地址和封面
+	InternalToken           string `json:"internal_token" mapstructure:"internal_token"`                         // 调用content-service内部接口的令牌
+	RequestTimeoutSeconds   int    `json:"request_timeout_seconds" mapstructure:"request_timeout_seconds"`       // 请求content-service的超时时间（秒）
+	VideoCacheTTLSeconds    int    `json:"video_cache_ttl_seconds" mapstructure:"video_cache_ttl_seconds"`       // 视频播放信息的进程内缓存时间（秒），需小于播放地址的有效期
+	TemplateCacheTTLSeconds int    `json:"template_cache_ttl_seconds" mapstructure:"template_cache_ttl_seconds"` // 商户落地页模板的进程内缓存时间（秒）
+}
+
+// CampaignsConfig 营销活动配置
+type CampaignsConfig struct {
+	SchedulerIntervalSeconds int `json:"scheduler_interval_seconds" mapstructure:"scheduler_interval_seconds"` // 检查到期开始和结束的活动的间隔（秒）
+	MaxCards                 int `json:"max_cards" mapstructure:"max_cards"`                                   // 单个活动允许的最大卡片数量
+	StaleSeconds             int `json:"stale_seconds" mapstructure:"stale_seconds"`                           // 切换中的活动超过该时间没有进展时由调度器接手继续（秒）
+}
+
+// TapGuardConfig 公开重定向接口的刷量防护配置，阈值为商户未自定义时的默认值
+type TapGuardConfig struct {
+	IPRatePerMinute             int `json:"ip_rate_per_minute" mapstructure:"ip_rate_per_minute"`                         // 同一IP每分钟允许的请求数
+	IPBurst                     int `json:"ip_burst" mapstructure:"ip_burst"`                                             // 同一IP允许的突发请求数
+	SlugRatePerMinute           int `json:"slug_rate_per_minute" mapstructure:"slug_rate_per_minute"`                     // 单个短链接每分钟计数的点击数
+	SlugBurst                   int `json:"slug_burst" mapstructure:"slug_burst"`                                         // 单个短链接允许的突发点击数
+	DuplicateWindowSeconds      int `json:"duplicate_window_seconds" mapstructure:"duplicate_window_seconds"`             // 重复碰卡的去重时间（秒）
+	TrackedKeys                 int `json:"tracked_keys" mapstructure:"tracked_keys"`                                     // 令牌桶和去重记录最多跟踪的键数量
+	SettingsCacheTTLSeconds     int `json:"settings_cache_ttl_seconds" mapstructure:"settings_cache_ttl_seconds"`         // 商户阈值的进程内缓存时间（秒）
+	CounterFlushIntervalSeconds int `json:"counter_flush_interval_seconds" mapstructure:"counter_flush_interval_seconds"` // 过滤计数批量写入间隔（秒）
+}
+
+// WebhooksConfig 商户Webhook投递配置
+type WebhooksConfig struct {
+	Workers              int  `json:"workers" mapstructure:"workers"`                               // 同时投递的请求数
+	PollIntervalSeconds  int  `json:"poll_interval_seconds" mapstructure:"poll_interval_seconds"`   // 没有待投递事件时的轮询间隔（秒）
+	BatchSize            int  `json:"batch_size" mapstructure:"batch_size"`                         // 每次领取的投递数量
+	TimeoutSeconds       int  `json:"timeout_seconds" mapstructure:"timeout_seconds"`               // 单次请求的超时时间（秒）
+	LeaseSeconds         int  `json:"lease_seconds" mapstructure:"lease_seconds"`                   // 领取后的租约时间（秒），实例退出后由其他实例接手
+	MaxAttempts          int  `json:"max_attempts" mapstructure:"max_attempts"`                     // 单个事件的最大投递次数
+	BackoffBaseSeconds   int  `json:"backoff_base_seconds" mapstructure:"backoff_base_seconds"`     // 第一次重试的等待时间（秒），之后每次翻倍
+	BackoffMaxSeconds    int  `json:"backoff_max_seconds" mapstructure:"backoff_max_seconds"`       // 重试等待时间的上限（秒）
+	DisableAfterFailures int  `json:"disable_after_failures" mapstructure:"disable_after_failures"` // 接收端连续失败该次数后自动停用，0表示不停用
+	MaxEndpoints         int  `json:"max_endpoints" mapstructure:"max_endpoints"`                   // 每个商户最多注册的接收端数量
+	RetentionDays        int  `json:"retention_days" mapstructure:"retention_days"`                 // 投递记录的保留天数
+	AllowPrivateTargets  bool `json:"allow_private_targets" mapstructure:"allow_private_targets"`   // 允许HTTP地址和内网地址，仅用于本地开发和测试
 }
 
 // KafkaConfig Kafka配置
@@ -104,6 +228,7 @@ func Load() (*Config, error) {
 			ReadTimeoutSeconds:  getEnvAsInt("SERVER_READ_TIMEOUT", 10),
 			WriteTimeoutSeconds: getEnvAsInt("SERVER_WRITE_TIMEOUT", 10),
 			IdleTimeoutSeconds:  getEnvAsInt("SERVER_IDLE_TIMEOUT", 60),
+			TrustedProxies:      getEnvAsStringSlice("SERVER_TRUSTED_PROXIES", []string{}),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "postgres"),
@@ -117,17 +242,101 @@ func Load() (*Config, error) {
 			Secret: getEnv("JWT_SECRET", "your-secret-key"),
 		},
 		Cloudflare: CloudflareConfig{
-			APIToken: getEnv("CLOUDFLARE_API_TOKEN", ""),
-			ZoneID:   getEnv("CLOUDFLARE_ZONE_ID", ""),
-			BaseURL:  getEnv("CLOUDFLARE_BASE_URL", "https://api.cloudflare.com/client/v4"),
+			APIToken:                 getEnv("CLOUDFLARE_API_TOKEN", ""),
+			ZoneID:                   getEnv("CLOUDFLARE_ZONE_ID", ""),
+			BaseURL:                  getEnv("CLOUDFLARE_BASE_URL", "https://api.cloudflare.com/client/v4"),
+			AccountID:                getEnv("CLOUDFLARE_ACCOUNT_ID", ""),
+			KVNamespaceID:            getEnv("CLOUDFLARE_KV_NAMESPACE_ID", ""),
+			SyncIntervalSeconds:      getEnvAsInt("CLOUDFLARE_SYNC_INTERVAL", 5),
+			ReconcileIntervalMinutes: getEnvAsInt("CLOUDFLARE_RECONCILE_INTERVAL", 30),
 		},
 		ShortLink: ShortLinkConfig{
-			BaseURL: getEnv("SHORTLINK_BASE_URL", "https://s.example.com"),
+			BaseURL:       getEnv("SHORTLINK_BASE_URL", "https://s.example.com"),
+			SlugLength:    getEnvAsInt("SHORTLINK_SLUG_LENGTH", 8),
+			SlugAlphabet:  getEnv("SHORTLINK_SLUG_ALPHABET", ""),
+			ReservedSlugs: getEnvAsStringSlice("SHORTLINK_RESERVED_SLUGS", []string{}),
+
+			CacheSize:                 getEnvAsInt("SHORTLINK_CACHE_SIZE", 10000),
+			CacheTTLSeconds:           getEnvAsInt("SHORTLINK_CACHE_TTL", 60),
+			NegativeCacheTTLSeconds:   getEnvAsInt("SHORTLINK_NEGATIVE_CACHE_TTL", 10),
+			ClickFlushIntervalSeconds: getEnvAsInt("SHORTLINK_CLICK_FLUSH_INTERVAL", 5),
+			DeepLinkTimeoutMillis:     getEnvAsInt("SHORTLINK_DEEP_LINK_TIMEOUT_MILLIS", 2000),
+			PreviewRefreshMinutes:     getEnvAsInt("SHORTLINK_PREVIEW_REFRESH_MINUTES", 60),
+		},
+		Clicks: ClickConfig{
+			BatchSize:            getEnvAsInt("CLICKS_BATCH_SIZE", 100),
+			BufferSize:           getEnvAsInt("CLICKS_BUFFER_SIZE", 10000),
+			FlushIntervalSeconds: getEnvAsInt("CLICKS_FLUSH_INTERVAL", 2),
+			GeoIPDBPath:          getEnv("GEOIP_DB_PATH", ""),
+		},
+		Cards: CardsConfig{
+			ExpirySweepIntervalSeconds: getEnvAsInt("CARDS_EXPIRY_SWEEP_INTERVAL", 60),
+			ExpiryBatchSize:            getEnvAsInt("CARDS_EXPIRY_BATCH_SIZE", 100),
+			TransferExpiryHours:        getEnvAsInt("CARDS_TRANSFER_EXPIRY_HOURS", 168),
+		},
+		CardImport: CardImportConfig{
+			ChunkSize:      getEnvAsInt("CARD_IMPORT_CHUNK_SIZE", 500),
+			AsyncThreshold: getEnvAsInt("CARD_IMPORT_ASYNC_THRESHOLD", 500),
+			MaxRows:        getEnvAsInt("CARD_IMPORT_MAX_ROWS", 50000),
+			MaxFileSizeMB:  getEnvAsInt("CARD_IMPORT_MAX_FILE_SIZE_MB", 20),
+			Workers:        getEnvAsInt("CARD_IMPORT_WORKERS", 2),
+		},
+		SUN: SUNConfig{
+			MasterKey:        getEnv("SUN_MASTER_KEY", ""),
+			KeyEncryptionKey: getEnv("SUN_KEY_ENCRYPTION_KEY", ""),
+			SystemIdentifier: getEnv("SUN_SYSTEM_IDENTIFIER", "nfc_card"),
+			PICCDataParam:    getEnv("SUN_PICC_DATA_PARAM", "e"),
+			MACParam:         getEnv("SUN_MAC_PARAM", "c"),
+			CacheTTLSeconds:  getEnvAsInt("SUN_CACHE_TTL", 30),
+		},
+		QRCode: QRCodeConfig{
+			DefaultSize:         getEnvAsInt("QRCODE_DEFAULT_SIZE", 512),
+			MaxSize:             getEnvAsInt("QRCODE_MAX_SIZE", 2048),
+			MaxBatchSize:        getEnvAsInt("QRCODE_MAX_BATCH_SIZE", 500),
+			LogoMaxSizeKB:       getEnvAsInt("QRCODE_LOGO_MAX_SIZE_KB", 1024),
+			LogoTimeoutSeconds:  getEnvAsInt("QRCODE_LOGO_TIMEOUT", 5),
+			LogoCacheTTLSeconds: getEnvAsInt("QRCODE_LOGO_CACHE_TTL", 600),
+		},
+		Landing: LandingConfig{
+			ContentServiceURL:       getEnv("LANDING_CONTENT_SERVICE_URL", "http://content-service:8081"),
+			InternalToken:           getEnv("INTERNAL_API_TOKEN", ""),
+			RequestTimeoutSeconds:   getEnvAsInt("LANDING_REQUEST_TIMEOUT", 3),
+			VideoCacheTTLSeconds:    getEnvAsInt("LANDING_VIDEO_CACHE_TTL", 300),
+			TemplateCacheTTLSeconds: getEnvAsInt("LANDING_TEMPLATE_CACHE_TTL", 60),
+		},
+		Campaigns: CampaignsConfig{
+			SchedulerIntervalSeconds: getEnvAsInt("CAMPAIGNS_SCHEDULER_INTERVAL", 30),
+			MaxCards:                 getEnvAsInt("CAMPAIGNS_MAX_CARDS", 5000),
+			StaleSeconds:             getEnvAsInt("CAMPAIGNS_STALE_SECONDS", 300),
+		},
+		TapGuard: TapGuardConfig{
+			IPRatePerMinute:             getEnvAsInt("TAP_GUARD_IP_RATE_PER_MINUTE", 30),
+			IPBurst:                     getEnvAsInt("TAP_GUARD_IP_BURST", 10),
+			SlugRatePerMinute:           getEnvAsInt("TAP_GUARD_SLUG_RATE_PER_MINUTE", 120),
+			SlugBurst:                   getEnvAsInt("TAP_GUARD_SLUG_BURST", 30),
+			DuplicateWindowSeconds:      getEnvAsInt("TAP_GUARD_DUPLICATE_WINDOW", 30),
+			TrackedKeys:                 getEnvAsInt("TAP_GUARD_TRACKED_KEYS", 100000),
+			SettingsCacheTTLSeconds:     getEnvAsInt("TAP_GUARD_SETTINGS_CACHE_TTL", 60),
+			CounterFlushIntervalSeconds: getEnvAsInt("TAP_GUARD_COUNTER_FLUSH_INTERVAL", 10),
+		},
+		Webhooks: WebhooksConfig{
+			Workers:              getEnvAsInt("WEBHOOKS_WORKERS", 4),
+			PollIntervalSeconds:  getEnvAsInt("WEBHOOKS_POLL_INTERVAL", 5),
+			BatchSize:            getEnvAsInt("WEBHOOKS_BATCH_SIZE", 50),
+			TimeoutSeconds:       getEnvAsInt("WEBHOOKS_TIMEOUT", 10),
+			LeaseSeconds:         getEnvAsInt("WEBHOOKS_LEASE", 60),
+			MaxAttempts:          getEnvAsInt("WEBHOOKS_MAX_ATTEMPTS", 8),
+			BackoffBaseSeconds:   getEnvAsInt("WEBHOOKS_BACKOFF_BASE", 30),
+			BackoffMaxSeconds:    getEnvAsInt("WEBHOOKS_BACKOFF_MAX", 21600),
+			DisableAfterFailures: getEnvAsInt("WEBHOOKS_DISABLE_AFTER_FAILURES", 30),
+			MaxEndpoints:         getEnvAsInt("WEBHOOKS_MAX_ENDPOINTS", 10),
+			RetentionDays:        getEnvAsInt("WEBHOOKS_RETENTION_DAYS", 30),
+			AllowPrivateTargets:  getEnvAsBool("WEBHOOKS_ALLOW_PRIVATE_TARGETS", false),
 		},
 		Kafka: KafkaConfig{
 			Brokers:        getEnvAsStringSlice("KAFKA_BROKERS", []string{"kafka:9092"}),
 			ConsumerGroup:  getEnv("KAFKA_CONSUMER_GROUP", "nfc-service"),
-			ConsumerTopics: getEnvAsStringSlice("KAFKA_CONSUMER_TOPICS", []string{"card-events"}),
+			ConsumerTopics: getEnvAsStringSlice("KAFKA_CONSUMER_TOPICS", []string{"card-events", "publish-events"}),
 			ProducerTopics: getEnvAsStringSlice("KAFKA_PRODUCER_TOPICS", []string{"card-events"}),
 		},
 		Nacos: NacosConfig{
@@ -0,0 +1,121 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CampaignStatus 营销活动状态
+type CampaignStatus string
+
+const (
+	// CampaignScheduled 等待开始，可以修改活动和成员卡片
+	CampaignScheduled CampaignStatus = "scheduled"
+	// CampaignActivating 正在切换成员卡片的短链接
+	CampaignActivating CampaignStatus = "activating"
+	// CampaignActive 进行中，成员卡片的默认短链接指向活动目标
+	CampaignActive CampaignStatus = "active"
+	// CampaignEnding 正在恢复成员卡片的短链接
+	CampaignEnding CampaignStatus = "ending"
+	// CampaignEnded 已结束，成员卡片已恢复原来的目标
+	CampaignEnded CampaignStatus = "ended"
+	// CampaignCancelled 开始前被取消
+	CampaignCancelled CampaignStatus = "cancelled"
+)
+
+// CampaignCardState 成员卡片在活动中的切换状态
+type CampaignCardState string
+
+const (
+	// CampaignCardPending 尚未切换
+	CampaignCardPending CampaignCardState = "pending"
+	// CampaignCardApplied 默认短链接已指向活动目标
+	CampaignCardApplied CampaignCardState = "applied"
+	// CampaignCardSkipped 卡片没有短链接，未切换
+	CampaignCardSkipped CampaignCardState = "skipped"
+	// CampaignCardRestored 活动结束后已恢复
+	CampaignCardRestored CampaignCardState = "restored"
+)
+
+// Campaign 营销活动，活动期间成员卡片的默认短链接统一指向目标视频或目标URL
+type Campaign struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	MerchantID  uuid.UUID `json:"merchantId" db:"merchant_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	// TargetVideoID 活动视频，卡片跳转到带该视频的落地页；与TargetURL二选一
+	TargetVideoID *uuid.UUID `json:"targetVideoId" db:"target_video_id"`
+	TargetURL     *string    `json:"targetUrl" db:"target_url"`
+	StartsAt      time.Time  `json:"startsAt" db:"starts_at"`
+	// EndsAt 为空时活动需要手动结束
+	EndsAt      *time.Time     `json:"endsAt" db:"ends_at"`
+	Status      CampaignStatus `json:"status" db:"status"`
+	LastError   string         `json:"lastError,omitempty" db:"last_error"`
+	CardCount   int            `json:"cardCount" db:"card_count"`
+	ActivatedAt *time.Time     `json:"activatedAt" db:"activated_at"`
+	EndedAt     *time.Time     `json:"endedAt" db:"ended_at"`
+	CreatedAt   time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time      `json:"updatedAt" db:"updated_at"`
+}
+
+// CampaignCard 活动的成员卡片
+type CampaignCard struct {
+	CampaignID  uuid.UUID         `json:"campaignId" db:"campaign_id"`
+	NfcCardID   uuid.UUID         `json:"nfcCardId" db:"nfc_card_id"`
+	MerchantID  uuid.UUID         `json:"merchantId" db:"merchant_id"`
+	UID         string            `json:"uid" db:"uid"`
+	Name        string            `json:"name" db:"name"`
+	State       CampaignCardState `json:"state" db:"state"`
+	ShortLinkID *uuid.UUID        `json:"shortLinkId" db:"short_link_id"`
+	// PreviousTargetURL 切换前的目标，活动结束后恢复为该地址
+	PreviousTargetURL *string    `json:"previousTargetUrl" db:"previous_target_url"`
+	AppliedTargetURL  *string    `json:"appliedTargetUrl" db:"applied_target_url"`
+	Note              string     `json:"note,omitempty" db:"note"`
+	AppliedAt         *time.Time `json:"appliedAt" db:"applied_at"`
+	RestoredAt        *time.Time `json:"restoredAt" db:"restored_at"`
+	CreatedAt         time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// CreateCampaignDTO 创建营销活动的数据传输对象
+type CreateCampaignDTO struct {
+	Name          string      `json:"name" binding:"required,max=100"`
+	Description   string      `json:"description"`
+	TargetVideoID *uuid.UUID  `json:"targetVideoId"`
+	TargetURL     string      `json:"targetUrl" binding:"omitempty,url"`
+	StartsAt      time.Time   `json:"startsAt" binding:"required"`
+	EndsAt        *time.Time  `json:"endsAt"`
+	CardIDs       []uuid.UUID `json:"cardIds"`
+}
+
+// UpdateCampaignDTO 修改营销活动的数据传输对象，只能在活动开始前修改，整体替换目标和时间窗口
+type UpdateCampaignDTO struct {
+	Name          string     `json:"name" binding:"required,max=100"`
+	Description   string     `json:"description"`
+	TargetVideoID *uuid.UUID `json:"targetVideoId"`
+	TargetURL     string     `json:"targetUrl" binding:"omitempty,url"`
+	StartsAt      time.Time  `json:"startsAt" binding:"required"`
+	EndsAt        *time.Time `json:"endsAt"`
+}
+
+// CampaignCardClicks 单张卡片在活动期间的点击数
+type CampaignCardClicks struct {
+	NfcCardID uuid.UUID `json:"nfcCardId" db:"nfc_card_id"`
+	UID       string    `json:"uid" db:"uid"`
+	Name      string    `json:"name" db:"name"`
+	Count     int       `json:"count" db:"count"`
+}
+
+// CampaignStats 活动期间成员卡片的点击汇总
+type CampaignStats struct {
+	CampaignID uuid.UUID  `json:"campaignId"`
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+	Total      int        `json:"total"`
+	// ClickedCards 活动期间至少被点击过一次的卡片数
+	ClickedCards int                   `json:"clickedCards"`
+	ByDay        []*ClickBreakdownItem `json:"byDay"`
+	BySource     []*ClickBreakdownItem `json:"bySource"`
+	ByDevice     []*ClickBreakdownItem `json:"byDevice"`
+	ByCard       []*CampaignCardClicks `json:"byCard"`
+}
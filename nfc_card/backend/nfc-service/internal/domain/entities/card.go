@@ -37,8 +37,12 @@ type NfcCard struct {
 	BoundAt        *time.Time `json:"boundAt" db:"bound_at"`
 	DeactivatedAt  *time.Time `json:"deactivatedAt" db:"deactivated_at"`
 	ExpiresAt      *time.Time `json:"expiresAt" db:"expires_at"`
-	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
-	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+	// StoreID 卡片所属门店，未分配时为空
+	StoreID *uuid.UUID `json:"storeId" db:"store_id"`
+	// Position 卡片在门店内的位置，例如"12号桌"
+	Position  string    `json:"position,omitempty" db:"position"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 }
 
 // CreateNfcCardDTO 创建NFC卡片的数据传输对象
@@ -54,15 +58,12 @@ type CreateNfcCardDTO struct {
 }
 
 // UpdateNfcCardDTO 更新NFC卡片的数据传输对象
+// 状态、绑定用户和各状态时间只能通过生命周期接口修改，Status仅用于拒绝直接修改状态的请求
 type UpdateNfcCardDTO struct {
 	Name           string     `json:"name,omitempty" db:"name"`
 	Description    string     `json:"description,omitempty" db:"description"`
 	DefaultVideoID *uuid.UUID `json:"defaultVideoId" db:"default_video_id"`
 	Status         CardStatus `json:"status,omitempty" db:"status"`
-	UserID         *uuid.UUID `json:"userId,omitempty" db:"user_id"`
-	ActivatedAt    *time.Time `json:"activatedAt" db:"activated_at"`
-	BoundAt        *time.Time `json:"boundAt,omitempty" db:"bound_at"`
-	DeactivatedAt  *time.Time `json:"deactivatedAt,omitempty" db:"deactivated_at"`
 	ExpiresAt      *time.Time `json:"expiresAt,omitempty" db:"expires_at"`
 }
 
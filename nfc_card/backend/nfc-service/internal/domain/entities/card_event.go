@@ -0,0 +1,71 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CardAction 卡片生命周期操作
+type CardAction string
+
+const (
+	// CardActionActivate 激活新卡片
+	CardActionActivate CardAction = "activate"
+	// CardActionBind 绑定到用户
+	CardActionBind CardAction = "bind"
+	// CardActionUnbind 解除与用户的绑定
+	CardActionUnbind CardAction = "unbind"
+	// CardActionDeactivate 停用卡片
+	CardActionDeactivate CardAction = "deactivate"
+	// CardActionReactivate 重新激活已停用或已过期的卡片
+	CardActionReactivate CardAction = "reactivate"
+	// CardActionExpire 有效期已到，由系统置为过期
+	CardActionExpire CardAction = "expire"
+	// CardActionTransferOut 卡片转让给其他商户，记在原商户名下
+	CardActionTransferOut CardAction = "transfer_out"
+	// CardActionTransferIn 从其他商户转入，记在目标商户名下
+	CardActionTransferIn CardAction = "transfer_in"
+)
+
+// CardActorType 状态变更的操作者类型
+type CardActorType string
+
+const (
+	// CardActorUser 由用户通过API操作
+	CardActorUser CardActorType = "user"
+	// CardActorSystem 由系统后台任务操作
+	CardActorSystem CardActorType = "system"
+)
+
+// CardActor 状态变更的操作者及原因，写入卡片事件历史
+type CardActor struct {
+	Type   CardActorType
+	ID     *uuid.UUID
+	Reason string
+}
+
+// CardEvent 卡片状态变更历史记录
+type CardEvent struct {
+	ID         uuid.UUID     `json:"id" db:"id"`
+	MerchantID uuid.UUID     `json:"merchantId" db:"merchant_id"`
+	NfcCardID  uuid.UUID     `json:"nfcCardId" db:"nfc_card_id"`
+	Action     CardAction    `json:"action" db:"action"`
+	FromStatus CardStatus    `json:"fromStatus" db:"from_status"`
+	ToStatus   CardStatus    `json:"toStatus" db:"to_status"`
+	UserID     *uuid.UUID    `json:"userId,omitempty" db:"user_id"`
+	ActorType  CardActorType `json:"actorType" db:"actor_type"`
+	ActorID    *uuid.UUID    `json:"actorId,omitempty" db:"actor_id"`
+	Reason     string        `json:"reason" db:"reason"`
+	CreatedAt  time.Time     `json:"createdAt" db:"created_at"`
+}
+
+// CardTransition 一次待执行的卡片状态变更
+type CardTransition struct {
+	Action CardAction
+	From   CardStatus
+	To     CardStatus
+	// UserID 绑定操作的目标用户
+	UserID *uuid.UUID
+	Actor  *CardActor
+}
@@ -0,0 +1,155 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CardImportFormat 批量导入文件格式
+type CardImportFormat string
+
+const (
+	// CardImportFormatCSV 带表头的CSV文件
+	CardImportFormatCSV CardImportFormat = "csv"
+	// CardImportFormatJSONL 每行一个JSON对象
+	CardImportFormatJSONL CardImportFormat = "jsonl"
+)
+
+// CardImportJobStatus 批量导入任务状态
+type CardImportJobStatus string
+
+const (
+	// CardImportJobPending 等待处理
+	CardImportJobPending CardImportJobStatus = "pending"
+	// CardImportJobRunning 正在导入
+	CardImportJobRunning CardImportJobStatus = "running"
+	// CardImportJobCompleted 导入完成（可能包含失败的行）
+	CardImportJobCompleted CardImportJobStatus = "completed"
+	// CardImportJobFailed 导入任务整体失败
+	CardImportJobFailed CardImportJobStatus = "failed"
+)
+
+// CardImportRowStatus 单行导入结果
+type CardImportRowStatus string
+
+const (
+	// CardImportRowCreated 卡片已创建
+	CardImportRowCreated CardImportRowStatus = "created"
+	// CardImportRowDuplicate UID已存在或在文件中重复
+	CardImportRowDuplicate CardImportRowStatus = "duplicate"
+	// CardImportRowInvalid 行数据校验失败
+	CardImportRowInvalid CardImportRowStatus = "invalid"
+	// CardImportRowFailed 写入数据库失败
+	CardImportRowFailed CardImportRowStatus = "failed"
+)
+
+// CardImportRow 导入文件中的一行卡片数据
+type CardImportRow struct {
+	Line           int        `json:"line"`
+	UID            string     `json:"uid"`
+	Name           string     `json:"name"`
+	DefaultVideoID *uuid.UUID `json:"defaultVideoId,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	// Error 解析或校验阶段发现的错误，非空时该行不会写入数据库
+	Error string `json:"-"`
+}
+
+// CardImportRowResult 单行的导入结果
+type CardImportRowResult struct {
+	Line   int                 `json:"line"`
+	UID    string              `json:"uid"`
+	Status CardImportRowStatus `json:"status"`
+	CardID *uuid.UUID          `json:"cardId,omitempty"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// CardImportResults 逐行导入结果，以JSONB形式存储在card_import_jobs.results
+type CardImportResults []CardImportRowResult
+
+// Value 实现driver.Valuer接口
+func (r CardImportResults) Value() (driver.Value, error) {
+	if r == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(r)
+}
+
+// Scan 实现sql.Scanner接口
+func (r *CardImportResults) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("无法将%T解析为导入结果", src)
+	}
+
+	if len(data) == 0 {
+		*r = nil
+		return nil
+	}
+
+	var results []CardImportRowResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return fmt.Errorf("解析导入结果失败: %w", err)
+	}
+	*r = results
+	return nil
+}
+
+// CardImportSummary 导入结果汇总
+type CardImportSummary struct {
+	Total      int `json:"total" db:"total_rows"`
+	Processed  int `json:"processed" db:"processed_rows"`
+	Created    int `json:"created" db:"created_count"`
+	Duplicates int `json:"duplicates" db:"duplicate_count"`
+	Invalid    int `json:"invalid" db:"invalid_count"`
+	Failed     int `json:"failed" db:"failed_count"`
+}
+
+// Add 将一行结果计入汇总
+func (s *CardImportSummary) Add(status CardImportRowStatus) {
+	s.Processed++
+	switch status {
+	case CardImportRowCreated:
+		s.Created++
+	case CardImportRowDuplicate:
+		s.Duplicates++
+	case CardImportRowInvalid:
+		s.Invalid++
+	case CardImportRowFailed:
+		s.Failed++
+	}
+}
+
+// CardImportJob 批量导入任务
+type CardImportJob struct {
+	ID         uuid.UUID           `json:"id" db:"id"`
+	MerchantID uuid.UUID           `json:"merchantId" db:"merchant_id"`
+	Status     CardImportJobStatus `json:"status" db:"status"`
+	Format     CardImportFormat    `json:"format" db:"format"`
+	FileName   *string             `json:"fileName,omitempty" db:"file_name"`
+	CardImportSummary
+	Results      CardImportResults `json:"results,omitempty" db:"results"`
+	ErrorMessage *string           `json:"errorMessage,omitempty" db:"error_message"`
+	CreatedBy    *uuid.UUID        `json:"createdBy,omitempty" db:"created_by"`
+	StartedAt    *time.Time        `json:"startedAt" db:"started_at"`
+	CompletedAt  *time.Time        `json:"completedAt" db:"completed_at"`
+	CreatedAt    time.Time         `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time         `json:"updatedAt" db:"updated_at"`
+}
+
+// CardImportReport 同步导入的结果报告
+type CardImportReport struct {
+	CardImportSummary
+	Results CardImportResults `json:"results"`
+}
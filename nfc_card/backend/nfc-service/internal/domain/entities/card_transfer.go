@@ -0,0 +1,76 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CardTransferStatus 卡片转让的状态
+type CardTransferStatus string
+
+const (
+	// CardTransferPending 等待目标商户接受
+	CardTransferPending CardTransferStatus = "pending"
+	// CardTransferAccepted 已接受，卡片已归属目标商户
+	CardTransferAccepted CardTransferStatus = "accepted"
+	// CardTransferRejected 目标商户拒绝
+	CardTransferRejected CardTransferStatus = "rejected"
+	// CardTransferCancelled 原商户撤回
+	CardTransferCancelled CardTransferStatus = "cancelled"
+	// CardTransferExpired 超过有效期未处理
+	CardTransferExpired CardTransferStatus = "expired"
+)
+
+// CardTransferLinkMode 转让时卡片短链接的处理方式
+type CardTransferLinkMode string
+
+const (
+	// CardTransferMoveLinks 短链接随卡片迁移到目标商户，已写入卡片的URL继续有效，原商户的跳转配置被清除
+	CardTransferMoveLinks CardTransferLinkMode = "move"
+	// CardTransferResetLinks 短链接留在原商户并停用，为目标商户创建新的默认短链接，卡片需要重新写入
+	CardTransferResetLinks CardTransferLinkMode = "reset"
+)
+
+// CardTransfer 卡片在商户之间的一次转让
+type CardTransfer struct {
+	ID             uuid.UUID            `json:"id" db:"id"`
+	NfcCardID      uuid.UUID            `json:"nfcCardId" db:"nfc_card_id"`
+	CardUID        string               `json:"cardUid" db:"card_uid"`
+	FromMerchantID uuid.UUID            `json:"fromMerchantId" db:"from_merchant_id"`
+	ToMerchantID   uuid.UUID            `json:"toMerchantId" db:"to_merchant_id"`
+	Status         CardTransferStatus   `json:"status" db:"status"`
+	LinkMode       CardTransferLinkMode `json:"linkMode" db:"link_mode"`
+	Note           string               `json:"note" db:"note"`
+	InitiatedBy    *uuid.UUID           `json:"initiatedBy,omitempty" db:"initiated_by"`
+	RespondedBy    *uuid.UUID           `json:"respondedBy,omitempty" db:"responded_by"`
+	AdminOverride  bool                 `json:"adminOverride" db:"admin_override"`
+	ExpiresAt      time.Time            `json:"expiresAt" db:"expires_at"`
+	RespondedAt    *time.Time           `json:"respondedAt,omitempty" db:"responded_at"`
+	CreatedAt      time.Time            `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time            `json:"updatedAt" db:"updated_at"`
+}
+
+// CreateCardTransferDTO 发起卡片转让的请求
+type CreateCardTransferDTO struct {
+	NfcCardID    uuid.UUID            `json:"nfcCardId" binding:"required"`
+	ToMerchantID uuid.UUID            `json:"toMerchantId" binding:"required"`
+	LinkMode     CardTransferLinkMode `json:"linkMode" binding:"omitempty,oneof=move reset"`
+	Note         string               `json:"note" binding:"max=500"`
+}
+
+// CardTransferQuery 转让列表的查询条件
+type CardTransferQuery struct {
+	// Direction incoming为转入，outgoing为转出，为空时两者都返回
+	Direction string
+	Status    CardTransferStatus
+	Page      int
+	PageSize  int
+}
+
+// CardTransferResult 转让执行后受影响的短链接
+type CardTransferResult struct {
+	Transfer *CardTransfer
+	// ShortLinkIDs 迁移或停用的短链接
+	ShortLinkIDs []uuid.UUID
+}
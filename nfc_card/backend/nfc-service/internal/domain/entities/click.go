@@ -0,0 +1,76 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ClickSource 点击来源
+type ClickSource string
+
+const (
+	// ClickSourceNFC 碰NFC标签，未带来源参数的访问都按碰卡计算
+	ClickSourceNFC ClickSource = "nfc"
+	// ClickSourceQR 扫描打印的二维码
+	ClickSourceQR ClickSource = "qr"
+)
+
+// ClickEvent 表示一次短链接点击（碰卡）事件
+type ClickEvent struct {
+	ID          uuid.UUID   `json:"id" db:"id"`
+	TenantID    uuid.UUID   `json:"tenantId" db:"merchant_id"`
+	ShortLinkID uuid.UUID   `json:"shortLinkId" db:"short_link_id"`
+	Slug        string      `json:"slug" db:"slug"`
+	NfcCardID   *uuid.UUID  `json:"nfcCardId" db:"nfc_card_id"`
+	ClickedAt   time.Time   `json:"clickedAt" db:"clicked_at"`
+	UserAgent   string      `json:"userAgent" db:"user_agent"`
+	OS          string      `json:"os" db:"os"`
+	Browser     string      `json:"browser" db:"browser"`
+	DeviceType  string      `json:"deviceType" db:"device_type"`
+	IPAddress   string      `json:"ipAddress" db:"ip_address"` // 已匿名化的IP
+	Country     string      `json:"country" db:"country"`
+	Region      string      `json:"region" db:"region"`
+	Referrer    string      `json:"referrer" db:"referrer"`
+	VariantID   string      `json:"variantId" db:"variant_id"` // 本次点击命中的A/B变体，未参与测试时为空
+	Source      ClickSource `json:"source" db:"source"`
+	// StoreID 点击发生时卡片所属的门店，写入时由数据库根据卡片填充
+	StoreID *uuid.UUID `json:"storeId" db:"store_id"`
+	// Suspicious 刷量防护判断为可疑的点击，不计入点击次数和统计
+	Suspicious bool `json:"suspicious" db:"suspicious"`
+}
+
+// ClickBreakdownItem 点击分布统计项
+type ClickBreakdownItem struct {
+	Key   string `json:"key" db:"key"`
+	Count int    `json:"count" db:"count"`
+}
+
+// ClickBreakdown 单个短链接的点击分布统计
+type ClickBreakdown struct {
+	Slug       string                `json:"slug"`
+	Total      int                   `json:"total"`
+	ByOS       []*ClickBreakdownItem `json:"byOs"`
+	ByBrowser  []*ClickBreakdownItem `json:"byBrowser"`
+	ByDevice   []*ClickBreakdownItem `json:"byDevice"`
+	ByCountry  []*ClickBreakdownItem `json:"byCountry"`
+	ByRegion   []*ClickBreakdownItem `json:"byRegion"`
+	ByReferrer []*ClickBreakdownItem `json:"byReferrer"`
+	ByDay      []*ClickBreakdownItem `json:"byDay"`
+	ByVariant  []*ClickBreakdownItem `json:"byVariant"`
+	BySource   []*ClickBreakdownItem `json:"bySource"`
+}
+
+// ClickQuery 点击事件查询条件
+type ClickQuery struct {
+	// MerchantID 只返回该商户名下的点击，卡片转让前的点击仍属于原商户
+	MerchantID uuid.UUID
+	Slug       string
+	From       *time.Time
+	To         *time.Time
+	Source     ClickSource // 为空时不按来源过滤
+	Page       int
+	PageSize   int
+	// IncludeSuspicious 是否包含刷量防护标记的可疑点击，统计时始终排除
+	IncludeSuspicious bool
+}
@@ -0,0 +1,81 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// DeepLinkSource 深度链接的来源
+type DeepLinkSource string
+
+const (
+	// DeepLinkSourceManual 商户手动配置
+	DeepLinkSourceManual DeepLinkSource = "manual"
+	// DeepLinkSourcePublishJob 发布任务完成后根据平台内容ID自动生成
+	DeepLinkSourcePublishJob DeepLinkSource = "publish_job"
+)
+
+// DeepLink 短链接目标在原生App中的打开方式
+// 移动端访问时先尝试AppURL（iOS优先使用UniversalURL），App未安装或打开失败时回退到WebURL
+type DeepLink struct {
+	Platform       string         `json:"platform" binding:"required"`                    // 平台，如douyin、xiaohongshu、kuaishou
+	AppURL         string         `json:"appUrl" binding:"required"`                      // App的自定义scheme链接，如snssdk1128://aweme/detail/{id}
+	UniversalURL   string         `json:"universalUrl,omitempty" binding:"omitempty,url"` // iOS通用链接或Android App Links
+	AndroidPackage string         `json:"androidPackage,omitempty"`                       // Android包名，用于生成intent链接
+	WebURL         string         `json:"webUrl,omitempty" binding:"omitempty,url"`       // 回退的网页地址，为空时使用短链接的跳转目标
+	ContentID      string         `json:"contentId,omitempty"`                            // 平台内容ID
+	Source         DeepLinkSource `json:"source,omitempty"`
+	PublishJobID   *uuid.UUID     `json:"publishJobId,omitempty"` // 自动生成时对应的发布任务
+}
+
+// DeepLinks 短链接的深度链接列表，以JSONB形式存储在short_links.deep_links，每个平台最多一条
+type DeepLinks []DeepLink
+
+// Find 根据平台查找深度链接，未找到时返回nil
+func (d DeepLinks) Find(platform string) *DeepLink {
+	for i := range d {
+		if d[i].Platform == platform {
+			return &d[i]
+		}
+	}
+	return nil
+}
+
+// Value 实现driver.Valuer接口
+func (d DeepLinks) Value() (driver.Value, error) {
+	if d == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(d)
+}
+
+// Scan 实现sql.Scanner接口
+func (d *DeepLinks) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*d = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("无法将%T解析为深度链接", src)
+	}
+
+	if len(data) == 0 {
+		*d = nil
+		return nil
+	}
+
+	var links []DeepLink
+	if err := json.Unmarshal(data, &links); err != nil {
+		return fmt.Errorf("解析深度链接失败: %w", err)
+	}
+	*d = links
+	return nil
+}
@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"time"
+)
+
+// EdgeSyncAction 边缘同步操作类型
+type EdgeSyncAction string
+
+const (
+	// EdgeSyncUpsert 写入或更新边缘重定向
+	EdgeSyncUpsert EdgeSyncAction = "upsert"
+	// EdgeSyncDelete 删除边缘重定向
+	EdgeSyncDelete EdgeSyncAction = "delete"
+)
+
+// EdgeSyncTask 边缘同步发件箱中的一条任务
+type EdgeSyncTask struct {
+	ID          int64          `json:"id" db:"id"`
+	Slug        string         `json:"slug" db:"slug"`
+	Action      EdgeSyncAction `json:"action" db:"action"`
+	TargetURL   string         `json:"targetUrl" db:"target_url"`
+	Attempts    int            `json:"attempts" db:"attempts"`
+	LastError   *string        `json:"lastError" db:"last_error"`
+	CreatedAt   time.Time      `json:"createdAt" db:"created_at"`
+	ProcessedAt *time.Time     `json:"processedAt" db:"processed_at"`
+}
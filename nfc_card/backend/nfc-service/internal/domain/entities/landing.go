@@ -0,0 +1,158 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LandingLayout 落地页的内置布局
+type LandingLayout string
+
+const (
+	// LandingLayoutClassic 顶部品牌栏，视频居中，下方分享按钮
+	LandingLayoutClassic LandingLayout = "classic"
+	// LandingLayoutImmersive 视频铺满屏幕，标题和分享按钮浮在视频上
+	LandingLayoutImmersive LandingLayout = "immersive"
+	// LandingLayoutMinimal 只有视频和分享按钮
+	LandingLayoutMinimal LandingLayout = "minimal"
+)
+
+// LandingLayouts 所有内置布局
+var LandingLayouts = []LandingLayout{LandingLayoutClassic, LandingLayoutImmersive, LandingLayoutMinimal}
+
+// Valid 是否为内置布局
+func (l LandingLayout) Valid() bool {
+	for _, layout := range LandingLayouts {
+		if l == layout {
+			return true
+		}
+	}
+	return false
+}
+
+// SharePlatform 落地页的一键分享平台
+type SharePlatform string
+
+const (
+	SharePlatformDouyin      SharePlatform = "douyin"
+	SharePlatformKuaishou    SharePlatform = "kuaishou"
+	SharePlatformXiaohongshu SharePlatform = "xiaohongshu"
+	SharePlatformWeChat      SharePlatform = "wechat"
+)
+
+// SharePlatformList 所有支持的分享平台，也是默认的按钮顺序
+var SharePlatformList = []SharePlatform{SharePlatformDouyin, SharePlatformKuaishou, SharePlatformXiaohongshu, SharePlatformWeChat}
+
+// Valid 是否为支持的分享平台
+func (p SharePlatform) Valid() bool {
+	for _, platform := range SharePlatformList {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// SharePlatforms 落地页显示的分享按钮，按顺序排列
+type SharePlatforms []SharePlatform
+
+// Value 实现driver.Valuer接口
+func (p SharePlatforms) Value() (driver.Value, error) {
+	if p == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(p)
+}
+
+// Scan 实现sql.Scanner接口
+func (p *SharePlatforms) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("无法将%T解析为分享平台", src)
+	}
+	return json.Unmarshal(data, p)
+}
+
+// LandingTheme 落地页主题，为空的字段使用默认值
+type LandingTheme struct {
+	// PrimaryColor 按钮和强调色，#RRGGBB格式
+	PrimaryColor string `json:"primaryColor,omitempty"`
+	// BackgroundColor 页面背景色
+	BackgroundColor string `json:"backgroundColor,omitempty"`
+	// TextColor 文字颜色
+	TextColor string `json:"textColor,omitempty"`
+	// LogoURL 品牌Logo，为空时使用商户Logo
+	LogoURL string `json:"logoUrl,omitempty"`
+	// Title 页面标题，为空时使用视频标题或商户名称；支持{name}和{merchant}占位符
+	Title string `json:"title,omitempty"`
+	// Subtitle 标题下方的说明文字
+	Subtitle string `json:"subtitle,omitempty"`
+	// ShareText 分享按钮上方的引导文字
+	ShareText string `json:"shareText,omitempty"`
+	// FooterText 页脚文字
+	FooterText string `json:"footerText,omitempty"`
+}
+
+// Value 实现driver.Valuer接口
+func (t LandingTheme) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+// Scan 实现sql.Scanner接口
+func (t *LandingTheme) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*t = LandingTheme{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("无法将%T解析为落地页主题", src)
+	}
+	return json.Unmarshal(data, t)
+}
+
+// LandingTemplate 商户的落地页模板
+type LandingTemplate struct {
+	ID             uuid.UUID      `json:"id" db:"id"`
+	MerchantID     uuid.UUID      `json:"merchantId" db:"merchant_id"`
+	Layout         LandingLayout  `json:"layout" db:"layout"`
+	Theme          LandingTheme   `json:"theme" db:"theme"`
+	SharePlatforms SharePlatforms `json:"sharePlatforms" db:"share_platforms"`
+	// IsDefault 商户未设置模板，返回的是系统默认模板
+	IsDefault bool      `json:"isDefault" db:"-"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// DefaultLandingTemplate 商户未设置模板时使用的默认模板
+func DefaultLandingTemplate(merchantID uuid.UUID) *LandingTemplate {
+	return &LandingTemplate{
+		MerchantID:     merchantID,
+		Layout:         LandingLayoutClassic,
+		SharePlatforms: append(SharePlatforms(nil), SharePlatformList...),
+		IsDefault:      true,
+	}
+}
+
+// UpdateLandingTemplateDTO 更新落地页模板的数据传输对象，整体替换主题和分享平台
+type UpdateLandingTemplateDTO struct {
+	Layout         LandingLayout  `json:"layout" binding:"required"`
+	Theme          LandingTheme   `json:"theme"`
+	SharePlatforms SharePlatforms `json:"sharePlatforms"`
+}
@@ -0,0 +1,93 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LinkPreview 短链接分享到聊天软件或社交平台时的预览信息，以JSONB形式存储在short_links.preview
+// Title、Description、ImageURL为商户设置的值，为空的字段使用从卡片默认视频自动填充的值
+type LinkPreview struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"imageUrl,omitempty"`
+	// Auto 从卡片默认视频自动填充的值，由系统维护
+	Auto *AutoLinkPreview `json:"auto,omitempty"`
+}
+
+// AutoLinkPreview 从视频自动填充的预览信息
+type AutoLinkPreview struct {
+	VideoID     *uuid.UUID `json:"videoId,omitempty"` // 卡片没有默认视频或视频已删除时为空
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	ImageURL    string     `json:"imageUrl,omitempty"`
+	FilledAt    time.Time  `json:"filledAt"`
+}
+
+// LinkPreviewDTO 商户设置的预览信息，提供时整体替换商户设置的值，空字段表示使用自动填充的值
+type LinkPreviewDTO struct {
+	Title       string `json:"title,omitempty" binding:"omitempty,max=100"`
+	Description string `json:"description,omitempty" binding:"omitempty,max=300"`
+	ImageURL    string `json:"imageUrl,omitempty" binding:"omitempty,url,max=2048"`
+}
+
+// Effective 合并商户设置的值和自动填充的值
+func (p LinkPreview) Effective() (title, description, imageURL string) {
+	title, description, imageURL = p.Title, p.Description, p.ImageURL
+	if p.Auto != nil {
+		if title == "" {
+			title = p.Auto.Title
+		}
+		if description == "" {
+			description = p.Auto.Description
+		}
+		if imageURL == "" {
+			imageURL = p.Auto.ImageURL
+		}
+	}
+	return title, description, imageURL
+}
+
+// Customized 商户是否设置了全部预览字段，此时不需要自动填充
+func (p LinkPreview) Customized() bool {
+	return p.Title != "" && p.Description != "" && p.ImageURL != ""
+}
+
+// Value 实现driver.Valuer接口
+func (p LinkPreview) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan 实现sql.Scanner接口
+func (p *LinkPreview) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*p = LinkPreview{}
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("无法将%T解析为分享预览", src)
+	}
+
+	var preview LinkPreview
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &preview); err != nil {
+			return fmt.Errorf("解析分享预览失败: %w", err)
+		}
+	}
+	*p = preview
+	return nil
+}
+
+// Value 实现driver.Valuer接口
+func (d LinkPreviewDTO) Value() (driver.Value, error) {
+	return json.Marshal(d)
+}
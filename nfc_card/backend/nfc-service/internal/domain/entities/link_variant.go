@@ -0,0 +1,74 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// LinkVariant 短链接A/B测试的一个目标变体
+// 每位访问者按权重分配到一个变体，并通过Cookie或设备指纹保持粘性
+type LinkVariant struct {
+	ID        string `json:"id"`
+	Name      string `json:"name" binding:"required"`
+	TargetURL string `json:"targetUrl" binding:"required,url"`
+	Weight    int    `json:"weight" binding:"min=0"`
+}
+
+// LinkVariants 短链接的变体列表，以JSONB形式存储在short_links.variants
+type LinkVariants []LinkVariant
+
+// Find 根据ID查找变体，未找到时返回nil
+func (v LinkVariants) Find(id string) *LinkVariant {
+	for i := range v {
+		if v[i].ID == id {
+			return &v[i]
+		}
+	}
+	return nil
+}
+
+// Value 实现driver.Valuer接口
+func (v LinkVariants) Value() (driver.Value, error) {
+	if v == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(v)
+}
+
+// Scan 实现sql.Scanner接口
+func (v *LinkVariants) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("无法将%T解析为链接变体", src)
+	}
+
+	if len(data) == 0 {
+		*v = nil
+		return nil
+	}
+
+	var variants []LinkVariant
+	if err := json.Unmarshal(data, &variants); err != nil {
+		return fmt.Errorf("解析链接变体失败: %w", err)
+	}
+	*v = variants
+	return nil
+}
+
+// VariantStats 单个变体的点击统计
+type VariantStats struct {
+	VariantID string `json:"variantId"`
+	Name      string `json:"name"`
+	TargetURL string `json:"targetUrl"`
+	Weight    int    `json:"weight"`
+	Clicks    int    `json:"clicks"`
+}
@@ -0,0 +1,80 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RedirectRule 短链接的一条动态跳转规则
+// 规则按顺序匹配，第一条满足全部条件的规则生效；都不满足时使用短链接的TargetURL作为兜底
+type RedirectRule struct {
+	Name       string         `json:"name,omitempty"`
+	TargetURL  string         `json:"targetUrl" binding:"required,url"`
+	Conditions RuleConditions `json:"conditions"`
+}
+
+// RuleConditions 规则的匹配条件
+// 不同条件之间为"且"关系，同一条件中的多个取值为"或"关系，未设置的条件视为满足
+type RuleConditions struct {
+	// OS 操作系统，可选值: ios, android, harmonyos, windows, macos, linux
+	OS []string `json:"os,omitempty"`
+	// InAppBrowsers App内置浏览器，可选值: wechat, douyin, xiaohongshu, kuaishou, alipay, qq
+	InAppBrowsers []string `json:"inAppBrowsers,omitempty"`
+	// TimeOfDay 每日时间段，例如 {"start":"09:00","end":"18:00"}，结束时间早于开始时间表示跨天
+	TimeOfDay *TimeOfDayWindow `json:"timeOfDay,omitempty"`
+	// Timezone 时间段和日期窗口使用的时区，默认为Asia/Shanghai
+	Timezone string `json:"timezone,omitempty"`
+	// StartDate 生效开始时间（包含）
+	StartDate *time.Time `json:"startDate,omitempty"`
+	// EndDate 生效结束时间（不包含）
+	EndDate *time.Time `json:"endDate,omitempty"`
+	// Languages 访问者语言，按前缀匹配Accept-Language，例如 zh 匹配 zh-CN
+	Languages []string `json:"languages,omitempty"`
+}
+
+// TimeOfDayWindow 每日时间段，格式为HH:MM
+type TimeOfDayWindow struct {
+	Start string `json:"start" binding:"required"`
+	End   string `json:"end" binding:"required"`
+}
+
+// RedirectRules 有序的跳转规则列表，以JSONB形式存储在short_links.redirect_rules
+type RedirectRules []RedirectRule
+
+// Value 实现driver.Valuer接口
+func (r RedirectRules) Value() (driver.Value, error) {
+	if r == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(r)
+}
+
+// Scan 实现sql.Scanner接口
+func (r *RedirectRules) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("无法将%T解析为跳转规则", src)
+	}
+
+	if len(data) == 0 {
+		*r = nil
+		return nil
+	}
+
+	var rules []RedirectRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("解析跳转规则失败: %w", err)
+	}
+	*r = rules
+	return nil
+}
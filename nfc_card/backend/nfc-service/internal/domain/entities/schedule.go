@@ -0,0 +1,84 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduleEntry 卡片内容排期中的一条
+// 时间条件之间为"且"关系，未设置的条件视为满足；排期条目之间不允许有重叠的生效时间
+type ScheduleEntry struct {
+	Name string `json:"name,omitempty"`
+	// VideoID 生效时落地页播放的视频，与TargetURL二选一
+	VideoID *uuid.UUID `json:"videoId,omitempty"`
+	// TargetURL 生效时落地页直接跳转的地址
+	TargetURL string `json:"targetUrl,omitempty"`
+	// StartsAt 生效开始时间（包含）
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+	// EndsAt 生效结束时间（不包含）
+	EndsAt *time.Time `json:"endsAt,omitempty"`
+	// Days 每周生效的日期，0为周日，6为周六；跨天的时间段以开始的那天为准
+	Days []int `json:"days,omitempty"`
+	// TimeOfDay 每日时间段，结束时间早于开始时间表示跨天
+	TimeOfDay *TimeOfDayWindow `json:"timeOfDay,omitempty"`
+}
+
+// ScheduleEntries 有序的排期条目，以JSONB形式存储在card_schedules.entries
+type ScheduleEntries []ScheduleEntry
+
+// Value 实现driver.Valuer接口
+func (e ScheduleEntries) Value() (driver.Value, error) {
+	if e == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(e)
+}
+
+// Scan 实现sql.Scanner接口
+func (e *ScheduleEntries) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("无法将%T解析为排期条目", src)
+	}
+
+	if len(data) == 0 {
+		*e = nil
+		return nil
+	}
+
+	var entries []ScheduleEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("解析排期条目失败: %w", err)
+	}
+	*e = entries
+	return nil
+}
+
+// CardSchedule 卡片的内容排期，碰卡时落地页按当前时间选择生效的条目，没有条目生效时使用卡片的默认视频
+type CardSchedule struct {
+	NfcCardID  uuid.UUID `json:"nfcCardId" db:"nfc_card_id"`
+	MerchantID uuid.UUID `json:"merchantId" db:"merchant_id"`
+	// Timezone 日期和每日时间段使用的时区，默认为Asia/Shanghai
+	Timezone  string          `json:"timezone" db:"timezone"`
+	Entries   ScheduleEntries `json:"entries" db:"entries"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time       `json:"updatedAt" db:"updated_at"`
+}
+
+// UpdateCardScheduleDTO 设置卡片排期的数据传输对象，整体替换全部条目
+type UpdateCardScheduleDTO struct {
+	Timezone string          `json:"timezone"`
+	Entries  ScheduleEntries `json:"entries" binding:"required"`
+}
@@ -8,38 +8,58 @@ import (
 
 // ShortLink 表示短链接实体
 type ShortLink struct {
-	ID        uuid.UUID  `json:"id" db:"id"`
-	TenantID  uuid.UUID  `json:"tenantId" db:"merchant_id"`
-	NfcCardID uuid.UUID  `json:"nfcCardId" db:"nfc_card_id"`
-	Title     string     `json:"title" db:"title"`
-	Slug      string     `json:"slug" db:"slug"`
-	TargetURL string     `json:"targetUrl" db:"target_url"`
-	Clicks    int        `json:"clicks" db:"clicks"`
-	Active    bool       `json:"active" db:"active"`
-	IsDefault bool       `json:"isDefault" db:"is_default"`
-	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time  `json:"updatedAt" db:"updated_at"`
-	ExpiresAt *time.Time `json:"expiresAt" db:"expires_at"`
+	ID        uuid.UUID     `json:"id" db:"id"`
+	TenantID  uuid.UUID     `json:"tenantId" db:"merchant_id"`
+	NfcCardID uuid.UUID     `json:"nfcCardId" db:"nfc_card_id"`
+	Title     string        `json:"title" db:"title"`
+	Slug      string        `json:"slug" db:"slug"`
+	TargetURL string        `json:"targetUrl" db:"target_url"`
+	Rules     RedirectRules `json:"rules" db:"redirect_rules"`
+	Variants  LinkVariants  `json:"variants" db:"variants"`
+	Clicks    int           `json:"clicks" db:"clicks"`
+	Active    bool          `json:"active" db:"active"`
+	IsDefault bool          `json:"isDefault" db:"is_default"`
+	CreatedAt time.Time     `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time     `json:"updatedAt" db:"updated_at"`
+	ExpiresAt *time.Time    `json:"expiresAt" db:"expires_at"`
+	// SuspendedByCard 卡片停用或过期时由系统停用，卡片重新激活时自动恢复
+	SuspendedByCard bool `json:"suspendedByCard" db:"suspended_by_card"`
+	// DeepLinks 移动端访问时优先尝试打开的原生App链接
+	DeepLinks DeepLinks `json:"deepLinks" db:"deep_links"`
+	// Preview 聊天软件和社交平台抓取短链接时展示的标题、描述和封面
+	Preview LinkPreview `json:"preview" db:"preview"`
 }
 
 // CreateShortLinkDTO 创建短链接的数据传输对象
 type CreateShortLinkDTO struct {
-	TenantID  uuid.UUID  `json:"tenantId" binding:"required" db:"merchant_id"`
-	NfcCardID uuid.UUID  `json:"nfcCardId" binding:"required" db:"nfc_card_id"`
-	Title     string     `json:"title" binding:"required" db:"title"`
-	Slug      string     `json:"slug" db:"slug"`
-	TargetURL string     `json:"targetUrl" binding:"required,url" db:"target_url"`
-	IsDefault bool       `json:"isDefault" db:"is_default"`
-	ExpiresAt *time.Time `json:"expiresAt" db:"expires_at"`
+	TenantID  uuid.UUID     `json:"tenantId" binding:"required" db:"merchant_id"`
+	NfcCardID uuid.UUID     `json:"nfcCardId" binding:"required" db:"nfc_card_id"`
+	Title     string        `json:"title" binding:"required" db:"title"`
+	Slug      string        `json:"slug" db:"slug"`
+	TargetURL string        `json:"targetUrl" binding:"required,url" db:"target_url"`
+	Rules     RedirectRules `json:"rules" binding:"omitempty,dive" db:"redirect_rules"`
+	Variants  LinkVariants  `json:"variants" binding:"omitempty,dive" db:"variants"`
+	IsDefault bool          `json:"isDefault" db:"is_default"`
+	ExpiresAt *time.Time    `json:"expiresAt" db:"expires_at"`
+	// DeepLinks 原生App深度链接，每个平台最多一条
+	DeepLinks DeepLinks `json:"deepLinks" binding:"omitempty,dive" db:"deep_links"`
+	// Preview 分享预览，为空的字段从卡片默认视频自动填充
+	Preview *LinkPreviewDTO `json:"preview,omitempty" db:"preview"`
 }
 
 // UpdateShortLinkDTO 更新短链接的数据传输对象
 type UpdateShortLinkDTO struct {
-	Title     string     `json:"title" binding:"omitempty" db:"title"`
-	TargetURL string     `json:"targetUrl" binding:"omitempty,url" db:"target_url"`
-	Active    *bool      `json:"active,omitempty" db:"active"`
-	IsDefault *bool      `json:"isDefault,omitempty" db:"is_default"`
-	ExpiresAt *time.Time `json:"expiresAt" db:"expires_at"`
+	Title     string         `json:"title" binding:"omitempty" db:"title"`
+	TargetURL string         `json:"targetUrl" binding:"omitempty,url" db:"target_url"`
+	Rules     *RedirectRules `json:"rules,omitempty" binding:"omitempty,dive" db:"redirect_rules"`
+	Variants  *LinkVariants  `json:"variants,omitempty" binding:"omitempty,dive" db:"variants"`
+	Active    *bool          `json:"active,omitempty" db:"active"`
+	IsDefault *bool          `json:"isDefault,omitempty" db:"is_default"`
+	ExpiresAt *time.Time     `json:"expiresAt" db:"expires_at"`
+	// DeepLinks 提供时整体替换原生App深度链接
+	DeepLinks *DeepLinks `json:"deepLinks,omitempty" binding:"omitempty,dive" db:"deep_links"`
+	// Preview 提供时整体替换商户设置的分享预览，空字段恢复为自动填充
+	Preview *LinkPreviewDTO `json:"preview,omitempty" db:"preview"`
 }
 
 // IncrementClicksDTO 增加点击次数的数据传输对象
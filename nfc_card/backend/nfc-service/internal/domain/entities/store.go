@@ -0,0 +1,129 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OpeningPeriod 门店的一段营业时间
+type OpeningPeriod struct {
+	// Days 适用的星期，0为周日，6为周六
+	Days []int `json:"days"`
+	// Open 开门时间，格式为HH:MM
+	Open string `json:"open"`
+	// Close 打烊时间，早于开门时间表示营业到次日
+	Close string `json:"close"`
+}
+
+// OpeningHours 门店营业时间，以JSONB形式存储在stores.opening_hours
+type OpeningHours []OpeningPeriod
+
+// Value 实现driver.Valuer接口
+func (h OpeningHours) Value() (driver.Value, error) {
+	if h == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(h)
+}
+
+// Scan 实现sql.Scanner接口
+func (h *OpeningHours) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("无法将%T解析为营业时间", src)
+	}
+
+	if len(data) == 0 {
+		*h = nil
+		return nil
+	}
+
+	var periods []OpeningPeriod
+	if err := json.Unmarshal(data, &periods); err != nil {
+		return fmt.Errorf("解析营业时间失败: %w", err)
+	}
+	*h = periods
+	return nil
+}
+
+// Store 商户的实体门店
+type Store struct {
+	ID           uuid.UUID    `json:"id" db:"id"`
+	MerchantID   uuid.UUID    `json:"merchantId" db:"merchant_id"`
+	Name         string       `json:"name" db:"name"`
+	Code         *string      `json:"code" db:"code"`
+	Address      string       `json:"address" db:"address"`
+	City         string       `json:"city" db:"city"`
+	Phone        string       `json:"phone" db:"phone"`
+	Latitude     *float64     `json:"latitude" db:"latitude"`
+	Longitude    *float64     `json:"longitude" db:"longitude"`
+	Timezone     string       `json:"timezone" db:"timezone"`
+	OpeningHours OpeningHours `json:"openingHours" db:"opening_hours"`
+	CardCount    int          `json:"cardCount" db:"card_count"`
+	CreatedAt    time.Time    `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time    `json:"updatedAt" db:"updated_at"`
+}
+
+// StoreDTO 创建或修改门店的数据传输对象，修改时整体替换
+type StoreDTO struct {
+	Name         string       `json:"name" binding:"required,max=100"`
+	Code         string       `json:"code" binding:"max=50"`
+	Address      string       `json:"address"`
+	City         string       `json:"city" binding:"max=100"`
+	Phone        string       `json:"phone" binding:"max=50"`
+	Latitude     *float64     `json:"latitude"`
+	Longitude    *float64     `json:"longitude"`
+	Timezone     string       `json:"timezone"`
+	OpeningHours OpeningHours `json:"openingHours"`
+}
+
+// StoreCardAssignment 把一张卡片分配到门店的指定位置
+type StoreCardAssignment struct {
+	CardID uuid.UUID `json:"cardId" binding:"required"`
+	// Position 店内位置，例如"12号桌"、"收银台"，可为空
+	Position string `json:"position" binding:"max=100"`
+}
+
+// StoreCard 门店中的卡片
+type StoreCard struct {
+	ID       uuid.UUID  `json:"id" db:"id"`
+	UID      string     `json:"uid" db:"uid"`
+	Name     string     `json:"name" db:"name"`
+	Status   CardStatus `json:"status" db:"status"`
+	Position string     `json:"position" db:"position"`
+}
+
+// StoreTapSummary 门店在统计区间内的碰卡汇总
+type StoreTapSummary struct {
+	StoreID     uuid.UUID `json:"storeId" db:"store_id"`
+	StoreName   string    `json:"storeName" db:"store_name"`
+	City        string    `json:"city" db:"city"`
+	CardCount   int       `json:"cardCount" db:"card_count"`
+	Taps        int       `json:"taps" db:"taps"`
+	NfcTaps     int       `json:"nfcTaps" db:"nfc_taps"`
+	QrTaps      int       `json:"qrTaps" db:"qr_taps"`
+	TappedCards int       `json:"tappedCards" db:"tapped_cards"`
+	// TapsPerCard 平均每张卡片的碰卡次数，便于比较卡片数量不同的门店
+	TapsPerCard float64 `json:"tapsPerCard" db:"-"`
+}
+
+// StoreStats 单个门店的碰卡明细
+type StoreStats struct {
+	StoreTapSummary
+	ByDay      []*ClickBreakdownItem `json:"byDay"`
+	ByHour     []*ClickBreakdownItem `json:"byHour"`
+	ByPosition []*ClickBreakdownItem `json:"byPosition"`
+	ByCard     []*ClickBreakdownItem `json:"byCard"`
+}
@@ -0,0 +1,56 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SUNCardKeys 卡片的SUN密钥记录，密钥字段为加密后的密文
+type SUNCardKeys struct {
+	CardID      uuid.UUID  `db:"id"`
+	MerchantID  uuid.UUID  `db:"merchant_id"`
+	UID         string     `db:"uid"`
+	Enabled     bool       `db:"sun_enabled"`
+	KeyVersion  int        `db:"sun_key_version"`
+	MetaReadKey []byte     `db:"sun_meta_read_key"`
+	FileReadKey []byte     `db:"sun_file_read_key"`
+	ReadCounter *int       `db:"sun_read_counter"`
+	LastTapAt   *time.Time `db:"sun_last_tap_at"`
+}
+
+// SUNStatus 卡片的SUN状态，不包含密钥
+type SUNStatus struct {
+	CardID      uuid.UUID  `json:"cardId"`
+	UID         string     `json:"uid"`
+	Enabled     bool       `json:"enabled"`
+	KeyVersion  int        `json:"keyVersion"`
+	ReadCounter *int       `json:"readCounter"`
+	LastTapAt   *time.Time `json:"lastTapAt"`
+}
+
+// SUNProvisioning 为卡片开启SUN后返回的写卡数据
+// 密钥明文只在开启时返回一次，需由写卡工具通过ChangeKey写入标签
+type SUNProvisioning struct {
+	SUNStatus
+	// MetaReadKey SDMMetaReadKey（十六进制），用于加密PICC数据
+	MetaReadKey string `json:"metaReadKey"`
+	// FileReadKey SDMFileReadKey（十六进制），用于生成SDM MAC
+	FileReadKey string `json:"fileReadKey"`
+	// Slug 生成URL模板使用的短链接
+	Slug string `json:"slug"`
+	// URL 带占位符的SUN URL模板
+	URL     string `json:"url"`
+	NDEFHex string `json:"ndefHex"`
+	// 以下为ChangeFileSettings中的SDM偏移量，均相对于NDEF文件开头
+	PICCDataOffset    int `json:"piccDataOffset"`
+	SDMMACInputOffset int `json:"sdmMacInputOffset"`
+	SDMMACOffset      int `json:"sdmMacOffset"`
+}
+
+// SUNTap 一次通过校验的SUN读取
+type SUNTap struct {
+	CardID      uuid.UUID `json:"cardId"`
+	UID         string    `json:"uid"`
+	ReadCounter int       `json:"readCounter"`
+}
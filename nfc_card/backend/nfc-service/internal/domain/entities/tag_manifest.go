@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TagWriteItem 一张卡片的写卡数据
+type TagWriteItem struct {
+	CardID uuid.UUID `json:"cardId"`
+	UID    string    `json:"uid"`
+	Name   string    `json:"name"`
+	Slug   string    `json:"slug,omitempty"`
+	URL    string    `json:"url,omitempty"`
+	// NDEFHex NDEF消息的十六进制字节，不包含TLV包装
+	NDEFHex string `json:"ndefHex,omitempty"`
+	// TLVHex 包装为NDEF TLV并带终止符后的十六进制字节，可直接从标签第4页开始写入
+	TLVHex      string `json:"tlvHex,omitempty"`
+	MessageSize int    `json:"messageSize"`
+	TLVSize     int    `json:"tlvSize"`
+	// Capacity 目标标签型号的用户存储区字节数
+	Capacity int  `json:"capacity"`
+	Fits     bool `json:"fits"`
+	// SmallestTag 能容纳该消息的最小标签型号，都放不下时为空
+	SmallestTag string `json:"smallestTag,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// TagWriteManifest 一批卡片的写卡清单
+type TagWriteManifest struct {
+	GeneratedAt time.Time       `json:"generatedAt"`
+	TagType     string          `json:"tagType"`
+	Capacity    int             `json:"capacity"`
+	Total       int             `json:"total"`
+	Ready       int             `json:"ready"`
+	Oversize    int             `json:"oversize"`
+	Failed      int             `json:"failed"`
+	Items       []*TagWriteItem `json:"items"`
+}
@@ -0,0 +1,69 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TapFilterReason 碰卡被过滤的原因
+type TapFilterReason string
+
+const (
+	// TapFilterRateLimited 同一IP请求过于频繁，请求被拒绝
+	TapFilterRateLimited TapFilterReason = "rate_limited"
+	// TapFilterCrawler 爬虫或聊天软件的链接预览，正常跳转但不记录
+	TapFilterCrawler TapFilterReason = "crawler"
+	// TapFilterDuplicate 同一设备在去重时间内重复碰卡，正常跳转但不记录
+	TapFilterDuplicate TapFilterReason = "duplicate"
+	// TapFilterSuspicious 疑似脚本刷量，记录为可疑点击但不计数
+	TapFilterSuspicious TapFilterReason = "suspicious"
+)
+
+// TapFilterReasons 所有的过滤原因，按严重程度排列
+var TapFilterReasons = []TapFilterReason{
+	TapFilterRateLimited,
+	TapFilterSuspicious,
+	TapFilterCrawler,
+	TapFilterDuplicate,
+}
+
+// TapGuardSettings 商户的刷量防护阈值
+type TapGuardSettings struct {
+	MerchantID             uuid.UUID `json:"merchantId" db:"merchant_id"`
+	Enabled                bool      `json:"enabled" db:"enabled"`
+	IPRatePerMinute        int       `json:"ipRatePerMinute" db:"ip_rate_per_minute"`              // 同一IP每分钟允许的请求数
+	IPBurst                int       `json:"ipBurst" db:"ip_burst"`                                // 同一IP允许的突发请求数
+	SlugRatePerMinute      int       `json:"slugRatePerMinute" db:"slug_rate_per_minute"`          // 单个短链接每分钟计数的点击数，超出的记为可疑
+	SlugBurst              int       `json:"slugBurst" db:"slug_burst"`                            // 单个短链接允许的突发点击数
+	DuplicateWindowSeconds int       `json:"duplicateWindowSeconds" db:"duplicate_window_seconds"` // 重复碰卡的去重时间（秒），0表示不去重
+	// Custom 是否为商户自定义的阈值，false表示使用服务默认值
+	Custom    bool       `json:"custom" db:"-"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+}
+
+// TapGuardSettingsDTO 修改商户刷量防护阈值的请求
+type TapGuardSettingsDTO struct {
+	Enabled                *bool `json:"enabled"`
+	IPRatePerMinute        int   `json:"ipRatePerMinute" binding:"required,min=1,max=10000"`
+	IPBurst                int   `json:"ipBurst" binding:"required,min=1,max=10000"`
+	SlugRatePerMinute      int   `json:"slugRatePerMinute" binding:"required,min=1,max=100000"`
+	SlugBurst              int   `json:"slugBurst" binding:"required,min=1,max=100000"`
+	DuplicateWindowSeconds int   `json:"duplicateWindowSeconds" binding:"min=0,max=86400"`
+}
+
+// TapFilterCount 某天按某个原因被过滤的碰卡次数
+type TapFilterCount struct {
+	Date   string          `json:"date" db:"date"`
+	Reason TapFilterReason `json:"reason" db:"reason"`
+	Count  int64           `json:"count" db:"count"`
+}
+
+// TapFilterStats 商户在统计区间内被过滤的碰卡
+type TapFilterStats struct {
+	From     time.Time                 `json:"from"`
+	To       time.Time                 `json:"to"`
+	Total    int64                     `json:"total"`
+	ByReason map[TapFilterReason]int64 `json:"byReason"`
+	ByDay    []*TapFilterCount         `json:"byDay"`
+}
@@ -0,0 +1,121 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// WebhookEventTypes 接收端订阅的事件类型，以TEXT[]存储在webhook_endpoints.event_types，为空表示订阅全部事件
+type WebhookEventTypes []string
+
+// Value 实现driver.Valuer接口
+func (t WebhookEventTypes) Value() (driver.Value, error) {
+	if t == nil {
+		return "{}", nil
+	}
+	return pq.StringArray(t).Value()
+}
+
+// Scan 实现sql.Scanner接口
+func (t *WebhookEventTypes) Scan(src interface{}) error {
+	var values pq.StringArray
+	if err := values.Scan(src); err != nil {
+		return err
+	}
+	*t = WebhookEventTypes(values)
+	return nil
+}
+
+// Subscribes 判断是否订阅了该事件类型
+func (t WebhookEventTypes) Subscribes(eventType string) bool {
+	if len(t) == 0 {
+		return true
+	}
+	for _, value := range t {
+		if value == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEndpoint 商户注册的Webhook接收端
+type WebhookEndpoint struct {
+	ID          uuid.UUID         `json:"id" db:"id"`
+	MerchantID  uuid.UUID         `json:"merchantId" db:"merchant_id"`
+	URL         string            `json:"url" db:"url"`
+	Description string            `json:"description" db:"description"`
+	EventTypes  WebhookEventTypes `json:"eventTypes" db:"event_types"`
+	Active      bool              `json:"active" db:"active"`
+	// ConsecutiveFailures 连续投递失败的次数，达到阈值后接收端被自动停用
+	ConsecutiveFailures int        `json:"consecutiveFailures" db:"consecutive_failures"`
+	DisabledReason      string     `json:"disabledReason,omitempty" db:"disabled_reason"`
+	DisabledAt          *time.Time `json:"disabledAt,omitempty" db:"disabled_at"`
+	LastSuccessAt       *time.Time `json:"lastSuccessAt,omitempty" db:"last_success_at"`
+	LastFailureAt       *time.Time `json:"lastFailureAt,omitempty" db:"last_failure_at"`
+	CreatedAt           time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updatedAt" db:"updated_at"`
+	// Secret 签名密钥，只在创建和轮换密钥时返回
+	Secret string `json:"secret,omitempty" db:"secret"`
+}
+
+// CreateWebhookEndpointDTO 注册Webhook接收端的请求
+type CreateWebhookEndpointDTO struct {
+	URL         string            `json:"url" binding:"required,url"`
+	Description string            `json:"description" binding:"max=255"`
+	EventTypes  WebhookEventTypes `json:"eventTypes"`
+}
+
+// UpdateWebhookEndpointDTO 修改Webhook接收端的请求，未提供的字段保持不变
+// 重新启用被自动停用的接收端时清零连续失败次数
+type UpdateWebhookEndpointDTO struct {
+	URL         *string            `json:"url" binding:"omitempty,url"`
+	Description *string            `json:"description" binding:"omitempty,max=255"`
+	EventTypes  *WebhookEventTypes `json:"eventTypes"`
+	Active      *bool              `json:"active"`
+}
+
+// WebhookDeliveryStatus Webhook投递状态
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending 等待投递或等待重试
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliverySucceeded 接收端返回2xx
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryFailed 重试次数耗尽或接收端已停用
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery 一次事件投递及其最近一次尝试的结果
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id" db:"id"`
+	EndpointID     uuid.UUID             `json:"endpointId" db:"endpoint_id"`
+	MerchantID     uuid.UUID             `json:"merchantId" db:"merchant_id"`
+	EventID        uuid.UUID             `json:"eventId" db:"event_id"`
+	EventType      string                `json:"eventType" db:"event_type"`
+	Payload        json.RawMessage       `json:"payload,omitempty" db:"payload"`
+	Status         WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts       int                   `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time             `json:"nextAttemptAt" db:"next_attempt_at"`
+	ResponseStatus *int                  `json:"responseStatus,omitempty" db:"response_status"`
+	ResponseBody   string                `json:"responseBody,omitempty" db:"response_body"`
+	LastError      string                `json:"lastError,omitempty" db:"last_error"`
+	DurationMs     *int                  `json:"durationMs,omitempty" db:"duration_ms"`
+	DeliveredAt    *time.Time            `json:"deliveredAt,omitempty" db:"delivered_at"`
+	RedeliveryOf   *uuid.UUID            `json:"redeliveryOf,omitempty" db:"redelivery_of"`
+	CreatedAt      time.Time             `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time             `json:"updatedAt" db:"updated_at"`
+}
+
+// WebhookEvent 投递给接收端的事件，序列化后作为请求体
+type WebhookEvent struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
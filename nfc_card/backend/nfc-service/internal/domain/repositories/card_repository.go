@@ -11,13 +11,20 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 var (
 	ErrCardNotFound     = errors.New("NFC卡片未找到")
 	ErrUIDAlreadyExists = errors.New("UID已存在")
+	// ErrCardStatusChanged 执行状态变更时卡片状态已被其他请求修改
+	ErrCardStatusChanged = errors.New("NFC卡片状态已被修改，请刷新后重试")
 )
 
+// cardColumns 读取NFC卡片实体的列
+const cardColumns = `id, merchant_id, uid, name, COALESCE(description, '') AS description, default_video_id, status, user_id,
+		activated_at, bound_at, deactivated_at, expires_at, store_id, COALESCE(position, '') AS position, created_at, updated_at`
+
 // NfcCardRepository 实现NFC卡片的数据库访问
 type NfcCardRepository struct {
 	db *sqlx.DB
@@ -51,13 +58,16 @@ func (r *NfcCardRepository) Create(ctx context.Context, card *entities.CreateNfc
 		Name:           card.Name,
 		Description:    card.Description,
 		DefaultVideoID: card.DefaultVideoID,
+		Status:         entities.CardStatusNew,
+		ExpiresAt:      card.ExpiresAt,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
 
+	// 新卡片总是从new状态开始，之后只能通过生命周期操作改变状态
 	query := `
-		INSERT INTO nfc_cards (id, merchant_id, uid, name, description, default_video_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO nfc_cards (id, merchant_id, uid, name, description, default_video_id, status, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 
 	_, err = r.db.ExecContext(
@@ -69,6 +79,8 @@ func (r *NfcCardRepository) Create(ctx context.Context, card *entities.CreateNfc
 		newCard.Name,
 		newCard.Description,
 		newCard.DefaultVideoID,
+		newCard.Status,
+		newCard.ExpiresAt,
 		newCard.CreatedAt,
 		newCard.UpdatedAt,
 	)
@@ -84,7 +96,7 @@ func (r *NfcCardRepository) Create(ctx context.Context, card *entities.CreateNfc
 func (r *NfcCardRepository) FindByID(ctx context.Context, id uuid.UUID) (*entities.NfcCard, error) {
 	var card entities.NfcCard
 	query := `
-		SELECT id, merchant_id, uid, name, description, default_video_id, activated_at, created_at, updated_at
+		SELECT ` + cardColumns + `
 		FROM nfc_cards
 		WHERE id = $1
 	`
@@ -104,7 +116,7 @@ func (r *NfcCardRepository) FindByID(ctx context.Context, id uuid.UUID) (*entiti
 func (r *NfcCardRepository) FindByUID(ctx context.Context, uid string) (*entities.NfcCard, error) {
 	var card entities.NfcCard
 	query := `
-		SELECT id, merchant_id, uid, name, description, default_video_id, activated_at, created_at, updated_at
+		SELECT ` + cardColumns + `
 		FROM nfc_cards
 		WHERE uid = $1
 	`
@@ -120,6 +132,32 @@ func (r *NfcCardRepository) FindByUID(ctx context.Context, uid string) (*entitie
 	return &card, nil
 }
 
+// FindByIDs 批量查找商户的NFC卡片，不属于该商户的ID会被忽略
+func (r *NfcCardRepository) FindByIDs(ctx context.Context, merchantID uuid.UUID, ids []uuid.UUID) ([]*entities.NfcCard, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+
+	query := `
+		SELECT ` + cardColumns + `
+		FROM nfc_cards
+		WHERE merchant_id = $1 AND id = ANY($2::uuid[])
+	`
+
+	var cards []*entities.NfcCard
+	err := r.db.SelectContext(ctx, &cards, query, merchantID, pq.Array(values))
+	if err != nil {
+		return nil, fmt.Errorf("批量获取NFC卡片失败: %w", err)
+	}
+
+	return cards, nil
+}
+
 // FindByMerchantID 查找商户的所有NFC卡片
 func (r *NfcCardRepository) FindByMerchantID(ctx context.Context, merchantID uuid.UUID, page, pageSize int) ([]*entities.NfcCard, int, error) {
 	if page < 1 {
@@ -141,7 +179,7 @@ func (r *NfcCardRepository) FindByMerchantID(ctx context.Context, merchantID uui
 
 	// 获取分页数据
 	query := `
-		SELECT id, merchant_id, uid, name, description, default_video_id, activated_at, created_at, updated_at
+		SELECT ` + cardColumns + `
 		FROM nfc_cards
 		WHERE merchant_id = $1
 		ORDER BY created_at DESC
@@ -175,14 +213,14 @@ func (r *NfcCardRepository) Update(ctx context.Context, id uuid.UUID, card *enti
 	if card.DefaultVideoID != nil {
 		existingCard.DefaultVideoID = card.DefaultVideoID
 	}
-	if card.ActivatedAt != nil {
-		existingCard.ActivatedAt = card.ActivatedAt
+	if card.ExpiresAt != nil {
+		existingCard.ExpiresAt = card.ExpiresAt
 	}
 	existingCard.UpdatedAt = time.Now()
 
 	query := `
 		UPDATE nfc_cards
-		SET name = $1, description = $2, default_video_id = $3, activated_at = $4, updated_at = $5
+		SET name = $1, description = $2, default_video_id = $3, expires_at = $4, updated_at = $5
 		WHERE id = $6
 	`
 
@@ -192,7 +230,7 @@ func (r *NfcCardRepository) Update(ctx context.Context, id uuid.UUID, card *enti
 		existingCard.Name,
 		existingCard.Description,
 		existingCard.DefaultVideoID,
-		existingCard.ActivatedAt,
+		existingCard.ExpiresAt,
 		existingCard.UpdatedAt,
 		id,
 	)
@@ -225,188 +263,150 @@ func (r *NfcCardRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-// Activate 激活NFC卡片
-func (r *NfcCardRepository) Activate(ctx context.Context, uid string) (*entities.NfcCard, error) {
-	// 查找卡片
-	card, err := r.FindByUID(ctx, uid)
-	if err != nil {
-		return nil, err
-	}
-
-	// 设置激活时间
+// ApplyTransition 在事务中执行卡片状态变更并写入事件历史
+// 只有卡片当前状态仍为transition.From时才会更新，否则返回ErrCardStatusChanged，保证并发的状态变更不会互相覆盖
+func (r *NfcCardRepository) ApplyTransition(ctx context.Context, cardID uuid.UUID, transition *entities.CardTransition) (*entities.NfcCard, *entities.CardEvent, error) {
 	now := time.Now()
-	card.ActivatedAt = &now
-	card.UpdatedAt = now
-
-	query := `
-		UPDATE nfc_cards
-		SET activated_at = $1, updated_at = $2
-		WHERE id = $3
-	`
-
-	_, err = r.db.ExecContext(ctx, query, card.ActivatedAt, card.UpdatedAt, card.ID)
+	sets := "status = $3, updated_at = $4"
+	params := []interface{}{cardID, transition.From, transition.To, now}
+
+	// 各操作需要同步修改的字段
+	switch transition.Action {
+	case entities.CardActionActivate:
+		sets += ", activated_at = $4"
+	case entities.CardActionBind:
+		sets += ", user_id = $5, bound_at = $4"
+		params = append(params, transition.UserID)
+	case entities.CardActionUnbind:
+		sets += ", user_id = NULL, bound_at = NULL"
+	case entities.CardActionDeactivate:
+		sets += ", deactivated_at = $4"
+	case entities.CardActionReactivate:
+		sets += ", deactivated_at = NULL"
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("激活NFC卡片失败: %w", err)
+		return nil, nil, fmt.Errorf("开启事务失败: %w", err)
 	}
+	defer tx.Rollback()
 
-	return card, nil
-}
-
-// BindToUser 将卡片绑定到用户
-func (r *NfcCardRepository) BindToUser(ctx context.Context, cardID, userID uuid.UUID) (*entities.NfcCard, error) {
-	// 查找卡片
-	card, err := r.FindByID(ctx, cardID)
+	// 解绑事件需要记录原来的用户，先锁定卡片读取
+	var previousUserID *uuid.UUID
+	err = tx.GetContext(ctx, &previousUserID, `SELECT user_id FROM nfc_cards WHERE id = $1 AND status = $2 FOR UPDATE`, cardID, transition.From)
 	if err != nil {
-		return nil, err
+		if err == sql.ErrNoRows {
+			return nil, nil, r.transitionMissError(ctx, cardID)
+		}
+		return nil, nil, fmt.Errorf("锁定NFC卡片失败: %w", err)
 	}
 
-	// 设置绑定信息
-	now := time.Now()
-	card.UserID = &userID
-	card.BoundAt = &now
-	card.Status = entities.CardStatusBound
-	card.UpdatedAt = now
+	query := `UPDATE nfc_cards SET ` + sets + ` WHERE id = $1 AND status = $2 RETURNING ` + cardColumns
 
-	query := `
-		UPDATE nfc_cards
-		SET user_id = $1, bound_at = $2, status = $3, updated_at = $4
-		WHERE id = $5
-	`
-
-	_, err = r.db.ExecContext(ctx, query, card.UserID, card.BoundAt, card.Status, card.UpdatedAt, card.ID)
-	if err != nil {
-		return nil, fmt.Errorf("绑定NFC卡片到用户失败: %w", err)
+	var card entities.NfcCard
+	if err := tx.GetContext(ctx, &card, query, params...); err != nil {
+		return nil, nil, fmt.Errorf("更新NFC卡片状态失败: %w", err)
 	}
 
-	return card, nil
-}
-
-// UnbindFromUser 解除卡片与用户的绑定
-func (r *NfcCardRepository) UnbindFromUser(ctx context.Context, cardID uuid.UUID) (*entities.NfcCard, error) {
-	// 查找卡片
-	card, err := r.FindByID(ctx, cardID)
-	if err != nil {
-		return nil, err
+	event := &entities.CardEvent{
+		ID:         uuid.New(),
+		MerchantID: card.MerchantID,
+		NfcCardID:  card.ID,
+		Action:     transition.Action,
+		FromStatus: transition.From,
+		ToStatus:   transition.To,
+		UserID:     transition.UserID,
+		ActorType:  entities.CardActorSystem,
+		CreatedAt:  now,
 	}
-
-	// 确保卡片确实已绑定
-	if card.UserID == nil || card.Status != entities.CardStatusBound {
-		return nil, fmt.Errorf("卡片没有绑定用户")
+	if transition.Action == entities.CardActionUnbind {
+		event.UserID = previousUserID
+	}
+	if transition.Actor != nil {
+		event.ActorType = transition.Actor.Type
+		event.ActorID = transition.Actor.ID
+		event.Reason = transition.Actor.Reason
 	}
 
-	// 解除绑定
-	now := time.Now()
-	card.UserID = nil
-	card.Status = entities.CardStatusActivated // 恢复到激活状态
-	card.UpdatedAt = now
-
-	query := `
-		UPDATE nfc_cards
-		SET user_id = NULL, status = $1, updated_at = $2
-		WHERE id = $3
+	eventQuery := `
+		INSERT INTO nfc_card_events (id, merchant_id, nfc_card_id, action, from_status, to_status, user_id, actor_type, actor_id, reason, created_at)
+		VALUES (:id, :merchant_id, :nfc_card_id, :action, :from_status, :to_status, :user_id, :actor_type, :actor_id, :reason, :created_at)
 	`
+	if _, err := tx.NamedExecContext(ctx, eventQuery, event); err != nil {
+		return nil, nil, fmt.Errorf("写入NFC卡片事件失败: %w", err)
+	}
 
-	_, err = r.db.ExecContext(ctx, query, card.Status, card.UpdatedAt, card.ID)
-	if err != nil {
-		return nil, fmt.Errorf("解除NFC卡片与用户的绑定失败: %w", err)
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("提交事务失败: %w", err)
 	}
 
-	return card, nil
+	return &card, event, nil
 }
 
-// Deactivate 停用NFC卡片
-func (r *NfcCardRepository) Deactivate(ctx context.Context, id uuid.UUID) (*entities.NfcCard, error) {
-	// 查找卡片
-	card, err := r.FindByID(ctx, id)
-	if err != nil {
-		return nil, err
+// transitionMissError 区分状态变更时卡片不存在和状态已被修改两种情况
+func (r *NfcCardRepository) transitionMissError(ctx context.Context, cardID uuid.UUID) error {
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM nfc_cards WHERE id = $1)`, cardID); err != nil {
+		return fmt.Errorf("查找NFC卡片失败: %w", err)
 	}
-
-	// 设置停用信息
-	now := time.Now()
-	card.DeactivatedAt = &now
-	card.Status = entities.CardStatusDeactivated
-	card.UpdatedAt = now
-
-	query := `
-		UPDATE nfc_cards
-		SET deactivated_at = $1, status = $2, updated_at = $3
-		WHERE id = $4
-	`
-
-	_, err = r.db.ExecContext(ctx, query, card.DeactivatedAt, card.Status, card.UpdatedAt, card.ID)
-	if err != nil {
-		return nil, fmt.Errorf("停用NFC卡片失败: %w", err)
+	if !exists {
+		return ErrCardNotFound
 	}
-
-	return card, nil
+	return ErrCardStatusChanged
 }
 
-// Reactivate 重新激活已停用的NFC卡片
-func (r *NfcCardRepository) Reactivate(ctx context.Context, id uuid.UUID) (*entities.NfcCard, error) {
-	// 查找卡片
-	card, err := r.FindByID(ctx, id)
-	if err != nil {
-		return nil, err
+// FindEvents 分页获取卡片的状态变更历史，按时间倒序
+func (r *NfcCardRepository) FindEvents(ctx context.Context, cardID uuid.UUID, page, pageSize int) ([]*entities.CardEvent, int, error) {
+	if page < 1 {
+		page = 1
 	}
-
-	// 确保卡片确实已停用
-	if card.Status != entities.CardStatusDeactivated {
-		return nil, fmt.Errorf("卡片不处于停用状态")
+	if pageSize < 1 {
+		pageSize = 20
 	}
 
-	// 重新激活
-	now := time.Now()
-	card.DeactivatedAt = nil
-	card.Status = entities.CardStatusActivated
-	card.UpdatedAt = now
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM nfc_card_events WHERE nfc_card_id = $1`, cardID); err != nil {
+		return nil, 0, fmt.Errorf("获取NFC卡片事件总数失败: %w", err)
+	}
 
 	query := `
-		UPDATE nfc_cards
-		SET deactivated_at = NULL, status = $1, updated_at = $2
-		WHERE id = $3
+		SELECT id, merchant_id, nfc_card_id, action, from_status, to_status, user_id, actor_type, actor_id, reason, created_at
+		FROM nfc_card_events
+		WHERE nfc_card_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
 	`
 
-	_, err = r.db.ExecContext(ctx, query, card.Status, card.UpdatedAt, card.ID)
-	if err != nil {
-		return nil, fmt.Errorf("重新激活NFC卡片失败: %w", err)
+	var events []*entities.CardEvent
+	if err := r.db.SelectContext(ctx, &events, query, cardID, pageSize, (page-1)*pageSize); err != nil {
+		return nil, 0, fmt.Errorf("获取NFC卡片事件失败: %w", err)
 	}
 
-	return card, nil
+	return events, total, nil
 }
 
-// UpdateStatus 更新卡片状态
-func (r *NfcCardRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.CardStatus) (*entities.NfcCard, error) {
-	// 查找卡片
-	card, err := r.FindByID(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-
-	// 更新状态
-	now := time.Now()
-	card.Status = status
-	card.UpdatedAt = now
-
+// FindDueForExpiry 查找有效期已到但尚未置为过期的卡片
+func (r *NfcCardRepository) FindDueForExpiry(ctx context.Context, now time.Time, limit int) ([]*entities.NfcCard, error) {
 	query := `
-		UPDATE nfc_cards
-		SET status = $1, updated_at = $2
-		WHERE id = $3
+		SELECT ` + cardColumns + `
+		FROM nfc_cards
+		WHERE expires_at IS NOT NULL AND expires_at <= $1 AND status <> $2
+		ORDER BY expires_at
+		LIMIT $3
 	`
 
-	_, err = r.db.ExecContext(ctx, query, card.Status, card.UpdatedAt, card.ID)
-	if err != nil {
-		return nil, fmt.Errorf("更新NFC卡片状态失败: %w", err)
+	var cards []*entities.NfcCard
+	if err := r.db.SelectContext(ctx, &cards, query, now, entities.CardStatusExpired, limit); err != nil {
+		return nil, fmt.Errorf("查找到期的NFC卡片失败: %w", err)
 	}
 
-	return card, nil
+	return cards, nil
 }
 
 // FindByUserID 查找用户的所有已绑定NFC卡片
 func (r *NfcCardRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]*entities.NfcCard, error) {
 	query := `
-		SELECT id, merchant_id, uid, name, description, default_video_id, status, user_id, 
-		activated_at, bound_at, deactivated_at, expires_at, created_at, updated_at
+		SELECT ` + cardColumns + `
 		FROM nfc_cards
 		WHERE user_id = $1
 		ORDER BY created_at DESC
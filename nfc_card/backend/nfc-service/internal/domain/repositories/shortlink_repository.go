@@ -8,11 +8,16 @@ import (
 	"time"
 
 	"nfc-service/internal/domain/entities"
+	"nfc-service/pkg/slug"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+// slugUniqueConstraint short_links.slug列的唯一约束名
+const slugUniqueConstraint = "short_links_slug_key"
+
 var (
 	ErrShortLinkNotFound = errors.New("短链接未找到")
 	ErrSlugAlreadyExists = errors.New("短链接slug已存在")
@@ -45,7 +50,11 @@ func (r *ShortLinkRepository) Create(ctx context.Context, link *entities.CreateS
 		}
 	} else {
 		// 生成唯一的slug
-		link.Slug = generateSlug()
+		generated, err := generateSlug()
+		if err != nil {
+			return nil, err
+		}
+		link.Slug = generated
 
 		// 确保slug唯一
 		for {
@@ -59,7 +68,9 @@ func (r *ShortLinkRepository) Create(ctx context.Context, link *entities.CreateS
 				break
 			}
 
-			link.Slug = generateSlug()
+			if link.Slug, err = generateSlug(); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -94,6 +105,9 @@ func (r *ShortLinkRepository) Create(ctx context.Context, link *entities.CreateS
 	)
 
 	if err != nil {
+		if IsSlugConflict(err) {
+			return nil, ErrSlugAlreadyExists
+		}
 		return nil, fmt.Errorf("创建短链接失败: %w", err)
 	}
 
@@ -269,16 +283,22 @@ func (r *ShortLinkRepository) IncrementClicks(ctx context.Context, slug string)
 	return nil
 }
 
-// 生成随机的6位短链slug
-func generateSlug() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const length = 8
+// IsUniqueViolation 判断错误是否为唯一约束冲突
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
 
-	result := make([]byte, length)
-	for i := range result {
-		// 使用随机UUID的字节作为随机源
-		randByte := byte(uuid.New().String()[i%36]) % byte(len(charset))
-		result[i] = charset[randByte]
-	}
-	return string(result)
+// IsSlugConflict 判断错误是否为slug唯一约束冲突，其他唯一约束冲突不应触发重新生成slug
+func IsSlugConflict(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == slugUniqueConstraint
+}
+
+// defaultSlugGenerator 使用默认长度和字符集的Slug生成器
+var defaultSlugGenerator = slug.MustNewGenerator(slug.DefaultLength, slug.DefaultAlphabet, nil)
+
+// generateSlug 使用加密安全的随机源生成短链slug
+func generateSlug() (string, error) {
+	return defaultSlugGenerator.Generate()
 }
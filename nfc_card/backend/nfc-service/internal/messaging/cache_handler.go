@@ -0,0 +1,84 @@
+package messaging
+
+import (
+	"encoding/json"
+	"log"
+
+	"nfc-service/internal/services/landing"
+	"nfc-service/internal/services/schedules"
+	"nfc-service/internal/services/shortlinks"
+	"nfc-service/internal/services/sun"
+	"nfc-service/internal/services/transfers"
+
+	"github.com/google/uuid"
+)
+
+// CacheInvalidationHandler 根据短链接和卡片变更事件失效本实例的重定向缓存、SUN密钥缓存、落地页模板缓存和卡片排期缓存
+// 每个实例需要使用独立的消费者组订阅，才能收到全部变更事件
+type CacheInvalidationHandler struct {
+	shortlinkService shortlinks.Service
+	sunService       sun.Service
+	landingService   landing.Service
+	scheduleService  schedules.Service
+	logger           *log.Logger
+}
+
+// NewCacheInvalidationHandler 创建缓存失效消息处理器
+func NewCacheInvalidationHandler(shortlinkService shortlinks.Service, sunService sun.Service, landingService landing.Service, scheduleService schedules.Service, logger *log.Logger) *CacheInvalidationHandler {
+	return &CacheInvalidationHandler{
+		shortlinkService: shortlinkService,
+		sunService:       sunService,
+		landingService:   landingService,
+		scheduleService:  scheduleService,
+		logger:           logger,
+	}
+}
+
+// HandleMessage 处理接收到的消息
+func (h *CacheInvalidationHandler) HandleMessage(topic, msgType string, data []byte) error {
+	switch msgType {
+	case shortlinks.TypeShortLinkUpdated, shortlinks.TypeShortLinkDeleted:
+		var event shortlinks.ShortLinkChangedEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		h.shortlinkService.InvalidateSlug(event.Slug)
+	case TypeCardUpdated, TypeCardActivated, TypeCardBound, TypeCardUnbound,
+		TypeCardDeactivated, TypeCardReactivated, TypeCardExpired:
+		var event struct {
+			ID uuid.UUID `json:"id"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		h.shortlinkService.InvalidateCard(event.ID)
+	case sun.TypeCardSUNUpdated:
+		var event sun.CardSUNUpdatedEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		h.sunService.InvalidateCard(event.ID)
+	case landing.TypeLandingTemplateUpdated:
+		var event landing.LandingTemplateUpdatedEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		h.landingService.InvalidateTemplate(event.MerchantID)
+	case schedules.TypeCardScheduleUpdated:
+		var event schedules.CardScheduleUpdatedEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		h.scheduleService.InvalidateCard(event.ID)
+	case transfers.TypeCardTransferred:
+		// 转让后卡片的短链接、SUN密钥和排期都可能变化，其他实例缓存的卡片数据仍属于原商户
+		var event transfers.CardTransferredEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		h.shortlinkService.InvalidateCard(event.ID)
+		h.sunService.InvalidateCard(event.ID)
+		h.scheduleService.InvalidateCard(event.ID)
+	}
+	return nil
+}
@@ -20,24 +20,36 @@ const (
 	TypeCardActivated = "card.activated"
 	TypeCardBound     = "card.bound"
 	TypeCardUnbound   = "card.unbound"
+	// 以下生命周期事件只用于失效缓存，卡片短链接的停用和恢复由卡片服务同步完成
+	TypeCardDeactivated = "card.deactivated"
+	TypeCardReactivated = "card.reactivated"
+	TypeCardExpired     = "card.expired"
 )
 
+// CampaignTargets 卡片处于进行中的营销活动时接管卡片的新目标，由campaigns.Service实现
+type CampaignTargets interface {
+	DeferRetarget(ctx context.Context, cardID uuid.UUID, targetURL string) (bool, error)
+}
+
 // CardHandler 处理NFC卡相关消息
 type CardHandler struct {
 	cardService      cards.Service
 	shortlinkService shortlinks.Service
+	campaigns        CampaignTargets
 	logger           *log.Logger
 }
 
-// NewCardHandler 创建卡片消息处理器
+// NewCardHandler 创建卡片消息处理器，campaigns为nil时不检查卡片是否处于营销活动中
 func NewCardHandler(
 	cardService cards.Service,
 	shortlinkService shortlinks.Service,
+	campaigns CampaignTargets,
 	logger *log.Logger,
 ) *CardHandler {
 	return &CardHandler{
 		cardService:      cardService,
 		shortlinkService: shortlinkService,
+		campaigns:        campaigns,
 		logger:           logger,
 	}
 }
@@ -81,6 +93,17 @@ func (h *CardHandler) handleCardCreated(data []byte) error {
 
 	h.logger.Printf("处理卡片创建事件: ID=%v, UID=%v", event.ID, event.UID)
 
+	// 事件可能被重复投递，已有短链接时不再创建
+	links, err := h.shortlinkService.GetByNfcCardID(context.Background(), event.ID)
+	if err != nil {
+		h.logger.Printf("获取卡片短链接失败: %v", err)
+		return err
+	}
+	if len(links) > 0 {
+		h.logger.Printf("卡片 %s 已有短链接，跳过创建", event.ID)
+		return nil
+	}
+
 	// 创建默认的短链接
 	defaultLink := &entities.CreateShortLinkDTO{
 		TenantID:  event.MerchantID,
@@ -89,7 +112,7 @@ func (h *CardHandler) handleCardCreated(data []byte) error {
 		Slug:      "", // 系统自动生成
 	}
 
-	_, err := h.shortlinkService.Create(context.Background(), defaultLink)
+	_, err = h.shortlinkService.Create(context.Background(), defaultLink)
 	if err != nil {
 		h.logger.Printf("为新卡片创建默认短链接失败: %v", err)
 		return err
@@ -119,6 +142,19 @@ func (h *CardHandler) handleCardUpdated(data []byte) error {
 	// 如果默认视频ID发生变化，更新相关短链接
 	if _, hasVideoChange := event.Changes["default_video_id"]; hasVideoChange {
 		h.logger.Printf("卡片默认视频已更新，需要更新短链接")
+		targetURL := "/nfc-landing/" + event.ID.String()
+
+		// 营销活动期间短链接指向活动目标，新目标在活动结束后恢复时生效
+		if h.campaigns != nil {
+			deferred, err := h.campaigns.DeferRetarget(context.Background(), event.ID, targetURL)
+			if err != nil {
+				h.logger.Printf("检查卡片活动状态失败: %v", err)
+				return err
+			}
+			if deferred {
+				return nil
+			}
+		}
 
 		// 获取该卡片的所有短链接
 		links, err := h.shortlinkService.GetByNfcCardID(context.Background(), event.ID)
@@ -133,7 +169,7 @@ func (h *CardHandler) handleCardUpdated(data []byte) error {
 				// 这里我们假设第一个链接为默认链接进行更新
 				// 实际项目中可能需要添加IsDefault标记到实体中
 				updateDTO := &entities.UpdateShortLinkDTO{
-					TargetURL: "/nfc-landing/" + event.ID.String(),
+					TargetURL: targetURL,
 				}
 
 				_, err := h.shortlinkService.Update(context.Background(), link.ID, updateDTO)
@@ -0,0 +1,95 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"nfc-service/internal/services/shortlinks"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TopicPublishEvents distribution-service的发布任务事件主题
+	TopicPublishEvents = "publish-events"
+	// TypePublishJobCompleted 发布任务完成（成功或失败）事件类型
+	TypePublishJobCompleted = "publish_job.completed"
+)
+
+// publishJobStatusCompleted 发布成功的任务状态
+const publishJobStatusCompleted = "completed"
+
+// PublishHandler 处理distribution-service的发布任务事件，为卡片短链接生成平台深度链接
+type PublishHandler struct {
+	shortlinkService shortlinks.Service
+	logger           *log.Logger
+}
+
+// NewPublishHandler 创建发布任务消息处理器
+func NewPublishHandler(shortlinkService shortlinks.Service, logger *log.Logger) *PublishHandler {
+	return &PublishHandler{
+		shortlinkService: shortlinkService,
+		logger:           logger,
+	}
+}
+
+// PublishJobEvent 发布任务事件数据
+type PublishJobEvent struct {
+	ID        uuid.UUID              `json:"id"`
+	TenantID  uuid.UUID              `json:"tenantId"`
+	VideoID   uuid.UUID              `json:"videoId"`
+	NfcCardID uuid.UUID              `json:"nfcCardId"`
+	Channel   string                 `json:"channel"`
+	Status    string                 `json:"status"`
+	Result    map[string]interface{} `json:"result"`
+}
+
+// HandleMessage 处理接收到的消息，只关心发布完成事件
+func (h *PublishHandler) HandleMessage(topic, msgType string, data []byte) error {
+	if msgType != TypePublishJobCompleted {
+		return nil
+	}
+
+	var event PublishJobEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("解析发布任务事件失败: %w", err)
+	}
+
+	if event.Status != publishJobStatusCompleted || event.NfcCardID == uuid.Nil {
+		return nil
+	}
+
+	// 发布结果中的平台内容ID和分享地址由各平台适配器写入
+	contentID := resultString(event.Result, "platformId")
+	if contentID == "" {
+		h.logger.Printf("发布任务 %s 的结果中没有平台内容ID，跳过生成深度链接", event.ID)
+		return nil
+	}
+	webURL := resultString(event.Result, "shareUrl")
+	if webURL == "" {
+		webURL = resultString(event.Result, "url")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	updated, err := h.shortlinkService.ApplyPublishedContent(ctx, event.NfcCardID, event.ID, event.Channel, contentID, webURL)
+	if err != nil {
+		return fmt.Errorf("为卡片 %s 生成深度链接失败: %w", event.NfcCardID, err)
+	}
+	if updated > 0 {
+		h.logger.Printf("已根据发布任务 %s 为卡片 %s 的%d条短链接生成%s深度链接", event.ID, event.NfcCardID, updated, event.Channel)
+	}
+	return nil
+}
+
+// resultString 读取发布结果中的字符串字段
+func resultString(result map[string]interface{}, key string) string {
+	if value, ok := result[key].(string); ok {
+		return value
+	}
+	return ""
+}
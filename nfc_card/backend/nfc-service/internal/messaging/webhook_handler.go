@@ -0,0 +1,36 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"nfc-service/internal/services/webhooks"
+)
+
+// WebhookHandler 把卡片、发布和统计事件转为商户Webhook投递记录
+// 多个实例共用同一个消费者组，每条消息只由一个实例写入投递记录
+type WebhookHandler struct {
+	webhookService webhooks.Service
+	logger         *log.Logger
+}
+
+// NewWebhookHandler 创建Webhook消息处理器
+func NewWebhookHandler(webhookService webhooks.Service, logger *log.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		logger:         logger,
+	}
+}
+
+// HandleMessage 处理接收到的消息，未开放订阅的消息类型直接忽略
+func (h *WebhookHandler) HandleMessage(topic, msgType string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := h.webhookService.Dispatch(ctx, topic, msgType, data); err != nil {
+		return fmt.Errorf("生成%s事件的Webhook投递失败: %w", msgType, err)
+	}
+	return nil
+}
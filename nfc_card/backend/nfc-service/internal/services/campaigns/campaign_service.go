@@ -0,0 +1,694 @@
+package campaigns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TopicCardEvents 卡片事件主题
+	TopicCardEvents = "card-events"
+	// TypeCampaignActivated 活动开始事件
+	TypeCampaignActivated = "campaign.activated"
+	// TypeCampaignEnded 活动结束事件
+	TypeCampaignEnded = "campaign.ended"
+
+	// runTimeout 单次调度的超时时间，切换大量卡片时可能较久
+	runTimeout = 5 * time.Minute
+	// dueBatchSize 每次调度最多处理的活动数量
+	dueBatchSize = 20
+	// touchEvery 切换多少张卡片后刷新一次活动的更新时间
+	touchEvery = 50
+	// topCards 点击统计中按卡片返回的最大条数
+	topCards = 50
+)
+
+var (
+	// ErrInvalidCampaign 活动参数无效
+	ErrInvalidCampaign = errors.New("活动参数无效")
+	// ErrCampaignNotFound 活动不存在
+	ErrCampaignNotFound = storage.ErrCampaignNotFound
+	// ErrCampaignStatusChanged 活动状态已变更
+	ErrCampaignStatusChanged = storage.ErrCampaignStatusChanged
+	// ErrTooManyCards 活动卡片数量超过上限
+	ErrTooManyCards = storage.ErrCampaignTooManyCards
+	// ErrNotEditable 活动已开始，不能修改
+	ErrNotEditable = errors.New("活动已开始，不能修改")
+	// ErrCardsInOtherCampaign 部分成员卡片正在参加其他活动
+	ErrCardsInOtherCampaign = errors.New("部分卡片正在参加其他活动")
+)
+
+// campaignService 营销活动服务的实现
+type campaignService struct {
+	repo       *storage.CampaignRepository
+	cards      *repositories.NfcCardRepository
+	links      CardLinks
+	producer   KafkaProducer
+	interval   time.Duration
+	staleAfter time.Duration
+	maxCards   int
+	logger     *log.Logger
+
+	stopOnce sync.Once
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewCampaignService 创建营销活动服务，producer为nil时不发布活动事件
+func NewCampaignService(
+	repo *storage.CampaignRepository,
+	cards *repositories.NfcCardRepository,
+	links CardLinks,
+	producer KafkaProducer,
+	cfg config.CampaignsConfig,
+	logger *log.Logger,
+) Service {
+	interval := time.Duration(cfg.SchedulerIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	staleAfter := time.Duration(cfg.StaleSeconds) * time.Second
+	if staleAfter <= 0 {
+		staleAfter = 5 * time.Minute
+	}
+	maxCards := cfg.MaxCards
+	if maxCards <= 0 {
+		maxCards = 5000
+	}
+
+	return &campaignService{
+		repo:       repo,
+		cards:      cards,
+		links:      links,
+		producer:   producer,
+		interval:   interval,
+		staleAfter: staleAfter,
+		maxCards:   maxCards,
+		logger:     logger,
+		done:       make(chan struct{}),
+	}
+}
+
+// Create 创建活动
+func (s *campaignService) Create(ctx context.Context, merchantID uuid.UUID, dto *entities.CreateCampaignDTO) (*entities.Campaign, error) {
+	campaign := &entities.Campaign{
+		MerchantID:  merchantID,
+		Name:        strings.TrimSpace(dto.Name),
+		Description: strings.TrimSpace(dto.Description),
+		StartsAt:    dto.StartsAt,
+		EndsAt:      dto.EndsAt,
+	}
+	if err := s.setTarget(ctx, campaign, dto.TargetVideoID, dto.TargetURL); err != nil {
+		return nil, err
+	}
+	if err := validateCampaign(campaign); err != nil {
+		return nil, err
+	}
+
+	cardIDs, err := s.ownedCards(ctx, merchantID, dto.CardIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(cardIDs) > s.maxCards {
+		return nil, fmt.Errorf("%w: 最多%d张", ErrTooManyCards, s.maxCards)
+	}
+
+	created, err := s.repo.Create(ctx, campaign, cardIDs)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Printf("商户 %s 创建了活动 %s，卡片数: %d，开始时间: %s", merchantID, created.ID, created.CardCount, created.StartsAt)
+	return created, nil
+}
+
+// Get 获取活动
+func (s *campaignService) Get(ctx context.Context, merchantID, id uuid.UUID) (*entities.Campaign, error) {
+	return s.repo.FindByID(ctx, merchantID, id)
+}
+
+// List 分页获取商户的活动
+func (s *campaignService) List(ctx context.Context, merchantID uuid.UUID, status entities.CampaignStatus, page, pageSize int) ([]*entities.Campaign, int, error) {
+	return s.repo.FindByMerchant(ctx, merchantID, status, page, pageSize)
+}
+
+// Update 修改等待开始的活动
+func (s *campaignService) Update(ctx context.Context, merchantID, id uuid.UUID, dto *entities.UpdateCampaignDTO) (*entities.Campaign, error) {
+	existing, err := s.repo.FindByID(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Status != entities.CampaignScheduled {
+		return nil, ErrNotEditable
+	}
+
+	campaign := &entities.Campaign{
+		ID:          id,
+		MerchantID:  merchantID,
+		Name:        strings.TrimSpace(dto.Name),
+		Description: strings.TrimSpace(dto.Description),
+		StartsAt:    dto.StartsAt,
+		EndsAt:      dto.EndsAt,
+	}
+	if err := s.setTarget(ctx, campaign, dto.TargetVideoID, dto.TargetURL); err != nil {
+		return nil, err
+	}
+	if err := validateCampaign(campaign); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Update(ctx, campaign)
+}
+
+// AddCards 向等待开始的活动加入卡片
+func (s *campaignService) AddCards(ctx context.Context, merchantID, id uuid.UUID, cardIDs []uuid.UUID) (int, error) {
+	owned, err := s.ownedCards(ctx, merchantID, cardIDs)
+	if err != nil {
+		return 0, err
+	}
+	if len(owned) == 0 {
+		return 0, fmt.Errorf("%w: 卡片列表不能为空", ErrInvalidCampaign)
+	}
+
+	added, err := s.repo.AddCards(ctx, merchantID, id, owned, s.maxCards)
+	if errors.Is(err, storage.ErrCampaignTooManyCards) {
+		return 0, fmt.Errorf("%w: 最多%d张", ErrTooManyCards, s.maxCards)
+	}
+	return added, s.editError(err)
+}
+
+// RemoveCards 从等待开始的活动中移除卡片
+func (s *campaignService) RemoveCards(ctx context.Context, merchantID, id uuid.UUID, cardIDs []uuid.UUID) (int, error) {
+	if len(cardIDs) == 0 {
+		return 0, fmt.Errorf("%w: 卡片列表不能为空", ErrInvalidCampaign)
+	}
+	removed, err := s.repo.RemoveCards(ctx, merchantID, id, cardIDs)
+	return removed, s.editError(err)
+}
+
+// ListCards 获取活动的成员卡片
+func (s *campaignService) ListCards(ctx context.Context, merchantID, id uuid.UUID) ([]*entities.CampaignCard, error) {
+	if _, err := s.repo.FindByID(ctx, merchantID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.FindCards(ctx, id)
+}
+
+// Activate 立即开始等待中的活动
+func (s *campaignService) Activate(ctx context.Context, merchantID, id uuid.UUID) (*entities.Campaign, error) {
+	campaign, err := s.repo.FindByID(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.Status != entities.CampaignScheduled {
+		return nil, ErrCampaignStatusChanged
+	}
+	if campaign.EndsAt != nil && !campaign.EndsAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: 活动结束时间已过", ErrInvalidCampaign)
+	}
+
+	claimed, err := s.repo.Transition(ctx, id, []entities.CampaignStatus{entities.CampaignScheduled}, entities.CampaignActivating, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.activate(ctx, claimed)
+}
+
+// End 立即结束进行中的活动
+func (s *campaignService) End(ctx context.Context, merchantID, id uuid.UUID) (*entities.Campaign, error) {
+	campaign, err := s.repo.FindByID(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.Status != entities.CampaignActive {
+		return nil, ErrCampaignStatusChanged
+	}
+
+	claimed, err := s.repo.Transition(ctx, id, []entities.CampaignStatus{entities.CampaignActive}, entities.CampaignEnding, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.end(ctx, claimed)
+}
+
+// Cancel 取消等待开始的活动，活动开始前成员卡片的短链接没有被修改，无需恢复
+func (s *campaignService) Cancel(ctx context.Context, merchantID, id uuid.UUID) (*entities.Campaign, error) {
+	if _, err := s.repo.FindByID(ctx, merchantID, id); err != nil {
+		return nil, err
+	}
+	cancelled, err := s.repo.Transition(ctx, id, []entities.CampaignStatus{entities.CampaignScheduled}, entities.CampaignCancelled, "")
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Printf("活动 %s 已取消", id)
+	return cancelled, nil
+}
+
+// GetStats 统计活动期间成员卡片的点击，活动尚未开始时返回空统计
+func (s *campaignService) GetStats(ctx context.Context, merchantID, id uuid.UUID) (*entities.CampaignStats, error) {
+	campaign, err := s.repo.FindByID(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.ActivatedAt == nil {
+		return &entities.CampaignStats{CampaignID: id}, nil
+	}
+
+	to := time.Now()
+	if campaign.EndedAt != nil {
+		to = *campaign.EndedAt
+	}
+	return s.repo.Stats(ctx, id, *campaign.ActivatedAt, to, topCards)
+}
+
+// DeferRetarget 卡片处于进行中的活动时更新其恢复目标
+func (s *campaignService) DeferRetarget(ctx context.Context, cardID uuid.UUID, targetURL string) (bool, error) {
+	deferred, err := s.repo.UpdatePreviousTarget(ctx, cardID, targetURL)
+	if err != nil {
+		return false, err
+	}
+	if deferred {
+		s.logger.Printf("卡片 %s 正在参加活动，新目标将在活动结束后生效: %s", cardID, targetURL)
+	}
+	return deferred, nil
+}
+
+// RunDue 开始和结束到期的活动，并接手超时未完成的切换
+func (s *campaignService) RunDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	due, err := s.repo.FindDue(ctx, now, now.Add(-s.staleAfter), dueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	handled := 0
+	for _, campaign := range due {
+		if err := s.runOne(ctx, campaign, now); err != nil {
+			if errors.Is(err, storage.ErrCampaignStatusChanged) {
+				// 其他实例或商户已处理
+				continue
+			}
+			s.logger.Printf("处理到期活动 %s 失败: %v", campaign.ID, err)
+			continue
+		}
+		handled++
+	}
+	return handled, nil
+}
+
+// runOne 按活动当前状态执行到期处理
+func (s *campaignService) runOne(ctx context.Context, campaign *entities.Campaign, now time.Time) error {
+	switch campaign.Status {
+	case entities.CampaignScheduled:
+		if campaign.EndsAt != nil && !campaign.EndsAt.After(now) {
+			// 整个时间窗口内都没能开始，例如卡片一直被其他活动占用
+			_, err := s.repo.Transition(ctx, campaign.ID, []entities.CampaignStatus{entities.CampaignScheduled},
+				entities.CampaignEnded, valueOr(campaign.LastError, "活动结束时间已过，未能开始"))
+			if err == nil {
+				s.logger.Printf("活动 %s 在结束时间前未能开始，已标记为结束", campaign.ID)
+			}
+			return err
+		}
+		claimed, err := s.repo.Transition(ctx, campaign.ID, []entities.CampaignStatus{entities.CampaignScheduled}, entities.CampaignActivating, "")
+		if err != nil {
+			return err
+		}
+		_, err = s.activate(ctx, claimed)
+		return err
+	case entities.CampaignActive:
+		claimed, err := s.repo.Transition(ctx, campaign.ID, []entities.CampaignStatus{entities.CampaignActive}, entities.CampaignEnding, "")
+		if err != nil {
+			return err
+		}
+		_, err = s.end(ctx, claimed)
+		return err
+	case entities.CampaignActivating, entities.CampaignEnding:
+		// 切换过程中实例退出，接手后从中断处继续
+		ok, err := s.repo.Reclaim(ctx, campaign.ID, campaign.Status, now.Add(-s.staleAfter))
+		if err != nil || !ok {
+			return err
+		}
+		s.logger.Printf("接手中断的活动切换: 活动=%s, 状态=%s", campaign.ID, campaign.Status)
+		if campaign.Status == entities.CampaignActivating {
+			_, err = s.activate(ctx, campaign)
+		} else {
+			_, err = s.end(ctx, campaign)
+		}
+		return err
+	}
+	return nil
+}
+
+// activate 将处于activating状态的活动的成员卡片切换到活动目标
+// 先记录切换前的目标再切换，实例中途退出时可以从记录继续；任意一张卡片切换失败时恢复已切换的卡片，活动回到等待开始
+func (s *campaignService) activate(ctx context.Context, campaign *entities.Campaign) (*entities.Campaign, error) {
+	conflicts, err := s.repo.FindConflicts(ctx, campaign.ID)
+	if err != nil {
+		return nil, s.rollback(ctx, campaign, err)
+	}
+	if len(conflicts) > 0 {
+		return nil, s.rollback(ctx, campaign, fmt.Errorf("%w: %d张", ErrCardsInOtherCampaign, len(conflicts)))
+	}
+
+	members, err := s.repo.FindCards(ctx, campaign.ID)
+	if err != nil {
+		return nil, s.rollback(ctx, campaign, err)
+	}
+
+	switched, skipped := 0, 0
+	for i, member := range members {
+		if i > 0 && i%touchEvery == 0 {
+			if err := s.repo.Touch(ctx, campaign.ID); err != nil {
+				return nil, s.rollback(ctx, campaign, err)
+			}
+		}
+
+		target := targetFor(campaign, member)
+		switch member.State {
+		case entities.CampaignCardSkipped:
+			skipped++
+			continue
+		case entities.CampaignCardPending:
+			links, err := s.links.GetByNfcCardID(ctx, member.NfcCardID)
+			if err != nil {
+				return nil, s.rollback(ctx, campaign, fmt.Errorf("获取卡片 %s 的短链接失败: %w", member.UID, err))
+			}
+			link := defaultLink(links)
+			if link == nil {
+				if err := s.repo.MarkCardSkipped(ctx, campaign.ID, member.NfcCardID, "卡片没有短链接"); err != nil {
+					return nil, s.rollback(ctx, campaign, err)
+				}
+				skipped++
+				continue
+			}
+			if err := s.repo.MarkCardApplied(ctx, campaign.ID, member.NfcCardID, link.ID, link.TargetURL, target); err != nil {
+				return nil, s.rollback(ctx, campaign, err)
+			}
+		}
+
+		// 已记录但可能尚未切换的卡片（接手中断的切换时）重新切换一次，UpdateDefaultForCard是幂等的
+		if err := s.links.UpdateDefaultForCard(ctx, member.NfcCardID, target); err != nil {
+			return nil, s.rollback(ctx, campaign, fmt.Errorf("切换卡片 %s 的短链接失败: %w", member.UID, err))
+		}
+		switched++
+	}
+
+	activated, err := s.repo.Transition(ctx, campaign.ID, []entities.CampaignStatus{entities.CampaignActivating}, entities.CampaignActive, "")
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Printf("活动 %s 已开始，切换卡片: %d，跳过: %d", campaign.ID, switched, skipped)
+	s.publish(TypeCampaignActivated, activated, switched, skipped)
+	return activated, nil
+}
+
+// rollback 激活失败时恢复已切换的卡片
+// 全部恢复成功时活动回到等待开始，调度器会在下一轮重试；否则转入结束流程，由调度器继续恢复剩余卡片
+func (s *campaignService) rollback(ctx context.Context, campaign *entities.Campaign, cause error) error {
+	s.logger.Printf("活动 %s 开始失败，回滚已切换的卡片: %v", campaign.ID, cause)
+
+	members, err := s.repo.FindCards(ctx, campaign.ID)
+	if err == nil {
+		for _, member := range members {
+			if member.State != entities.CampaignCardApplied || member.PreviousTargetURL == nil {
+				continue
+			}
+			if err = s.links.UpdateDefaultForCard(ctx, member.NfcCardID, *member.PreviousTargetURL); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = s.repo.ResetCards(ctx, campaign.ID)
+	}
+
+	if err != nil {
+		s.logger.Printf("活动 %s 回滚失败，转入结束流程: %v", campaign.ID, err)
+		message := fmt.Sprintf("开始失败: %v；回滚失败: %v", cause, err)
+		if _, terr := s.repo.Transition(ctx, campaign.ID, []entities.CampaignStatus{entities.CampaignActivating}, entities.CampaignEnding, message); terr != nil {
+			s.logger.Printf("更新活动 %s 状态失败: %v", campaign.ID, terr)
+		}
+		return cause
+	}
+
+	if _, terr := s.repo.Transition(ctx, campaign.ID, []entities.CampaignStatus{entities.CampaignActivating}, entities.CampaignScheduled, cause.Error()); terr != nil {
+		s.logger.Printf("更新活动 %s 状态失败: %v", campaign.ID, terr)
+	}
+	return cause
+}
+
+// end 将处于ending状态的活动的成员卡片恢复为切换前的目标
+// 活动期间商户手动修改过目标的卡片保留当前目标；恢复失败时活动保持ending，由调度器稍后继续
+func (s *campaignService) end(ctx context.Context, campaign *entities.Campaign) (*entities.Campaign, error) {
+	members, err := s.repo.FindCards(ctx, campaign.ID)
+	if err != nil {
+		return nil, s.endFailed(ctx, campaign, err)
+	}
+
+	restored, kept := 0, 0
+	for i, member := range members {
+		if member.State != entities.CampaignCardApplied {
+			continue
+		}
+		if i > 0 && i%touchEvery == 0 {
+			if err := s.repo.Touch(ctx, campaign.ID); err != nil {
+				return nil, s.endFailed(ctx, campaign, err)
+			}
+		}
+
+		links, err := s.links.GetByNfcCardID(ctx, member.NfcCardID)
+		if err != nil {
+			return nil, s.endFailed(ctx, campaign, fmt.Errorf("获取卡片 %s 的短链接失败: %w", member.UID, err))
+		}
+
+		note := ""
+		link := defaultLink(links)
+		switch {
+		case link == nil:
+			note = "短链接已删除"
+		case member.AppliedTargetURL != nil && link.TargetURL != *member.AppliedTargetURL:
+			note = "活动期间短链接目标已被修改，保留当前目标"
+		case member.PreviousTargetURL != nil:
+			if err := s.links.UpdateDefaultForCard(ctx, member.NfcCardID, *member.PreviousTargetURL); err != nil {
+				return nil, s.endFailed(ctx, campaign, fmt.Errorf("恢复卡片 %s 的短链接失败: %w", member.UID, err))
+			}
+		}
+		if note != "" {
+			kept++
+		} else {
+			restored++
+		}
+
+		if err := s.repo.MarkCardRestored(ctx, campaign.ID, member.NfcCardID, note); err != nil {
+			return nil, s.endFailed(ctx, campaign, err)
+		}
+	}
+
+	ended, err := s.repo.Transition(ctx, campaign.ID, []entities.CampaignStatus{entities.CampaignEnding}, entities.CampaignEnded, campaign.LastError)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Printf("活动 %s 已结束，恢复卡片: %d，保留当前目标: %d", campaign.ID, restored, kept)
+	s.publish(TypeCampaignEnded, ended, restored, kept)
+	return ended, nil
+}
+
+// endFailed 记录结束失败的原因，活动保持ending状态
+func (s *campaignService) endFailed(ctx context.Context, campaign *entities.Campaign, cause error) error {
+	s.logger.Printf("活动 %s 结束失败，稍后重试: %v", campaign.ID, cause)
+	if _, err := s.repo.Transition(ctx, campaign.ID, []entities.CampaignStatus{entities.CampaignEnding}, entities.CampaignEnding, cause.Error()); err != nil {
+		s.logger.Printf("更新活动 %s 状态失败: %v", campaign.ID, err)
+	}
+	return cause
+}
+
+// publish 发布活动事件，失败只记录日志
+func (s *campaignService) publish(msgType string, campaign *entities.Campaign, switched, skipped int) {
+	if s.producer == nil {
+		return
+	}
+	event := &CampaignEvent{
+		ID:         campaign.ID,
+		MerchantID: campaign.MerchantID,
+		Name:       campaign.Name,
+		Status:     campaign.Status,
+		CardCount:  campaign.CardCount,
+		Switched:   switched,
+		Skipped:    skipped,
+		OccurredAt: time.Now(),
+	}
+	if err := s.producer.SendMessage(TopicCardEvents, msgType, event); err != nil {
+		s.logger.Printf("发布活动事件失败: %v", err)
+	}
+}
+
+// Start 启动活动调度协程
+func (s *campaignService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	s.logger.Printf("活动调度协程已启动，间隔: %s", s.interval)
+}
+
+// Stop 停止活动调度协程
+func (s *campaignService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+}
+
+// run 定期处理到期的活动
+func (s *campaignService) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.runOnce()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// runOnce 执行一次调度
+func (s *campaignService) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+
+	handled, err := s.RunDue(ctx)
+	if err != nil {
+		s.logger.Printf("活动调度失败: %v", err)
+	}
+	if handled > 0 {
+		s.logger.Printf("已处理 %d 个到期活动", handled)
+	}
+}
+
+// setTarget 校验并设置活动目标，视频必须属于该商户
+func (s *campaignService) setTarget(ctx context.Context, campaign *entities.Campaign, videoID *uuid.UUID, targetURL string) error {
+	targetURL = strings.TrimSpace(targetURL)
+	switch {
+	case videoID != nil && targetURL != "":
+		return fmt.Errorf("%w: 目标视频和目标URL只能设置一个", ErrInvalidCampaign)
+	case videoID != nil:
+		owned, err := s.repo.MerchantHasVideo(ctx, campaign.MerchantID, *videoID)
+		if err != nil {
+			return err
+		}
+		if !owned {
+			return fmt.Errorf("%w: 视频不存在或不属于当前商户", ErrInvalidCampaign)
+		}
+		campaign.TargetVideoID = videoID
+	case targetURL != "":
+		parsed, err := url.Parse(targetURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("%w: 目标URL必须是http或https地址", ErrInvalidCampaign)
+		}
+		campaign.TargetURL = &targetURL
+	default:
+		return fmt.Errorf("%w: 必须设置目标视频或目标URL", ErrInvalidCampaign)
+	}
+	return nil
+}
+
+// ownedCards 去重并校验卡片都属于该商户
+func (s *campaignService) ownedCards(ctx context.Context, merchantID uuid.UUID, cardIDs []uuid.UUID) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool, len(cardIDs))
+	unique := make([]uuid.UUID, 0, len(cardIDs))
+	for _, id := range cardIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return unique, nil
+	}
+	if len(unique) > s.maxCards {
+		return nil, fmt.Errorf("%w: 最多%d张", ErrTooManyCards, s.maxCards)
+	}
+
+	cards, err := s.cards.FindByIDs(ctx, merchantID, unique)
+	if err != nil {
+		return nil, err
+	}
+	if missing := len(unique) - len(cards); missing > 0 {
+		return nil, fmt.Errorf("%w: %d张卡片不存在或不属于当前商户", ErrInvalidCampaign, missing)
+	}
+	return unique, nil
+}
+
+// editError 将修改成员时的状态冲突转换为ErrNotEditable
+func (s *campaignService) editError(err error) error {
+	if errors.Is(err, storage.ErrCampaignStatusChanged) {
+		return ErrNotEditable
+	}
+	return err
+}
+
+// validateCampaign 校验活动名称和时间窗口
+func validateCampaign(campaign *entities.Campaign) error {
+	if campaign.Name == "" {
+		return fmt.Errorf("%w: 活动名称不能为空", ErrInvalidCampaign)
+	}
+	if campaign.StartsAt.IsZero() {
+		return fmt.Errorf("%w: 必须设置开始时间", ErrInvalidCampaign)
+	}
+	if campaign.EndsAt != nil {
+		if !campaign.EndsAt.After(campaign.StartsAt) {
+			return fmt.Errorf("%w: 结束时间必须晚于开始时间", ErrInvalidCampaign)
+		}
+		if !campaign.EndsAt.After(time.Now()) {
+			return fmt.Errorf("%w: 结束时间已过", ErrInvalidCampaign)
+		}
+	}
+	return nil
+}
+
+// targetFor 成员卡片在活动期间的目标：活动视频对应带视频参数的卡片落地页，否则为活动URL
+func targetFor(campaign *entities.Campaign, member *entities.CampaignCard) string {
+	if campaign.TargetVideoID != nil {
+		return "/nfc-landing/" + url.PathEscape(member.UID) + "?video=" + campaign.TargetVideoID.String()
+	}
+	if campaign.TargetURL != nil {
+		return *campaign.TargetURL
+	}
+	return ""
+}
+
+// defaultLink 返回UpdateDefaultForCard会修改的短链接：默认短链接，没有时为第一条
+func defaultLink(links []*entities.ShortLink) *entities.ShortLink {
+	for _, link := range links {
+		if link.IsDefault {
+			return link
+		}
+	}
+	if len(links) > 0 {
+		return links[0]
+	}
+	return nil
+}
+
+// valueOr value为空时返回fallback
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,71 @@
+package campaigns
+
+import (
+	"context"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// Service 营销活动服务接口
+// 活动开始时将全部成员卡片的默认短链接切换到活动目标，任意一张卡片切换失败时整体回滚；
+// 活动结束时逐张恢复为切换前的目标
+type Service interface {
+	// Create 创建活动，活动到达开始时间后由调度器自动开始
+	Create(ctx context.Context, merchantID uuid.UUID, dto *entities.CreateCampaignDTO) (*entities.Campaign, error)
+	Get(ctx context.Context, merchantID, id uuid.UUID) (*entities.Campaign, error)
+	// List 分页获取商户的活动，status为空时返回全部状态
+	List(ctx context.Context, merchantID uuid.UUID, status entities.CampaignStatus, page, pageSize int) ([]*entities.Campaign, int, error)
+	// Update 修改等待开始的活动
+	Update(ctx context.Context, merchantID, id uuid.UUID, dto *entities.UpdateCampaignDTO) (*entities.Campaign, error)
+	// AddCards 向等待开始的活动加入卡片，返回新加入的数量
+	AddCards(ctx context.Context, merchantID, id uuid.UUID, cardIDs []uuid.UUID) (int, error)
+	// RemoveCards 从等待开始的活动中移除卡片，返回移除的数量
+	RemoveCards(ctx context.Context, merchantID, id uuid.UUID, cardIDs []uuid.UUID) (int, error)
+	// ListCards 获取活动的成员卡片及其切换状态
+	ListCards(ctx context.Context, merchantID, id uuid.UUID) ([]*entities.CampaignCard, error)
+	// Activate 立即开始等待中的活动
+	Activate(ctx context.Context, merchantID, id uuid.UUID) (*entities.Campaign, error)
+	// End 立即结束进行中的活动
+	End(ctx context.Context, merchantID, id uuid.UUID) (*entities.Campaign, error)
+	// Cancel 取消等待开始的活动
+	Cancel(ctx context.Context, merchantID, id uuid.UUID) (*entities.Campaign, error)
+	// GetStats 统计活动期间成员卡片的点击
+	GetStats(ctx context.Context, merchantID, id uuid.UUID) (*entities.CampaignStats, error)
+	// DeferRetarget 卡片处于进行中的活动时，把卡片的新目标记为活动结束后的恢复目标并返回true，
+	// 调用方不应再直接修改该卡片的默认短链接
+	DeferRetarget(ctx context.Context, cardID uuid.UUID, targetURL string) (bool, error)
+	// RunDue 开始和结束到期的活动，并接手中断的切换，返回处理的活动数量
+	RunDue(ctx context.Context) (int, error)
+	// Start 启动活动调度
+	Start()
+	// Stop 停止活动调度
+	Stop()
+}
+
+// CardLinks 读取和切换卡片的默认短链接，由shortlinks.Service实现
+type CardLinks interface {
+	GetByNfcCardID(ctx context.Context, nfcCardID uuid.UUID) ([]*entities.ShortLink, error)
+	UpdateDefaultForCard(ctx context.Context, cardID uuid.UUID, targetURL string) error
+}
+
+// KafkaProducer Kafka生产者接口
+type KafkaProducer interface {
+	// SendMessage 发送消息到指定主题
+	SendMessage(topic string, messageType string, data interface{}) error
+}
+
+// CampaignEvent 活动开始或结束事件
+type CampaignEvent struct {
+	ID         uuid.UUID               `json:"id"`
+	MerchantID uuid.UUID               `json:"merchant_id"`
+	Name       string                  `json:"name"`
+	Status     entities.CampaignStatus `json:"status"`
+	CardCount  int                     `json:"card_count"`
+	// Switched 开始时为切换到活动目标的卡片数，结束时为恢复的卡片数
+	Switched   int       `json:"switched"`
+	Skipped    int       `json:"skipped"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
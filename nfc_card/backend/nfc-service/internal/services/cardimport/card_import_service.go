@@ -0,0 +1,458 @@
+package cardimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TopicCardEvents 卡片事件主题
+	TopicCardEvents = "card-events"
+	// TypeCardCreated 卡片创建事件类型，由messaging.CardHandler消费并创建默认短链接
+	TypeCardCreated = "card.created"
+
+	defaultChunkSize      = 500
+	maxChunkSize          = 1000
+	defaultAsyncThreshold = 500
+	defaultMaxRows        = 50000
+	defaultWorkers        = 2
+	jobQueueSize          = 16
+	// staleJobTimeout 超过该时间没有进度更新的未完成任务视为已中断（例如处理该任务的实例已重启）
+	staleJobTimeout = 10 * time.Minute
+	jobTimeout      = time.Hour
+)
+
+// ErrImportQueueFull 异步导入任务队列已满
+var ErrImportQueueFull = errors.New("导入任务过多，请稍后重试")
+
+// cardCreatedEvent 卡片创建事件数据，与messaging.CardCreatedEvent保持一致
+type cardCreatedEvent struct {
+	ID         uuid.UUID `json:"id"`
+	MerchantID uuid.UUID `json:"merchant_id"`
+	UID        string    `json:"uid"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// queuedJob 等待执行的异步导入任务
+type queuedJob struct {
+	job  *entities.CardImportJob
+	rows []*entities.CardImportRow
+}
+
+// cardImportService 批量导入服务的实现
+type cardImportService struct {
+	repo           *storage.CardImportRepository
+	producer       KafkaProducer
+	logger         *log.Logger
+	chunkSize      int
+	asyncThreshold int
+	maxRows        int
+	workers        int
+	queue          chan *queuedJob
+	ctx            context.Context
+	cancel         context.CancelFunc
+	stopOnce       sync.Once
+	wg             sync.WaitGroup
+}
+
+// NewCardImportService 创建批量导入服务
+// producer可以为nil，此时不发布卡片创建事件，也不会自动创建默认短链接
+func NewCardImportService(
+	repo *storage.CardImportRepository,
+	producer KafkaProducer,
+	cfg config.CardImportConfig,
+	logger *log.Logger,
+) Service {
+	chunkSize := cfg.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	if chunkSize > maxChunkSize {
+		chunkSize = maxChunkSize
+	}
+
+	asyncThreshold := cfg.AsyncThreshold
+	if asyncThreshold <= 0 {
+		asyncThreshold = defaultAsyncThreshold
+	}
+
+	maxRows := cfg.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultMaxRows
+	}
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &cardImportService{
+		repo:           repo,
+		producer:       producer,
+		logger:         logger,
+		chunkSize:      chunkSize,
+		asyncThreshold: asyncThreshold,
+		maxRows:        maxRows,
+		workers:        workers,
+		queue:          make(chan *queuedJob, jobQueueSize),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+// Start 启动异步导入协程和中断任务的清理
+func (s *cardImportService) Start() {
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.runWorker()
+	}
+
+	s.wg.Add(1)
+	go s.runStaleJobSweeper()
+}
+
+// Stop 停止导入协程，正在执行的任务在当前批次结束后、尚未开始的任务立即标记为失败
+func (s *cardImportService) Stop() {
+	s.stopOnce.Do(func() {
+		s.cancel()
+		s.wg.Wait()
+
+		for {
+			select {
+			case queued := <-s.queue:
+				s.finishJob(queued.job.ID, entities.CardImportJobFailed, &entities.CardImportReport{}, "服务关闭，导入任务未执行")
+			default:
+				return
+			}
+		}
+	})
+}
+
+// Import 解析并导入卡片
+func (s *cardImportService) Import(ctx context.Context, req *ImportRequest) (*ImportResult, error) {
+	rows, err := ParseRows(req.Reader, req.Format, s.maxRows, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: 文件中没有卡片数据", ErrInvalidImportFile)
+	}
+
+	s.logger.Printf("批量导入NFC卡片: 商户=%s, 格式=%s, 行数=%d", req.MerchantID, req.Format, len(rows))
+
+	if !req.Async && len(rows) <= s.asyncThreshold {
+		report := s.importRows(ctx, req.MerchantID, rows, nil)
+		return &ImportResult{Report: report}, nil
+	}
+
+	now := time.Now()
+	job := &entities.CardImportJob{
+		ID:                uuid.New(),
+		MerchantID:        req.MerchantID,
+		Status:            entities.CardImportJobPending,
+		Format:            req.Format,
+		CardImportSummary: entities.CardImportSummary{Total: len(rows)},
+		CreatedBy:         req.CreatedBy,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if req.FileName != "" {
+		job.FileName = &req.FileName
+	}
+
+	if err := s.repo.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.queue <- &queuedJob{job: job, rows: rows}:
+	default:
+		s.finishJob(job.ID, entities.CardImportJobFailed, &entities.CardImportReport{}, ErrImportQueueFull.Error())
+		return nil, ErrImportQueueFull
+	}
+
+	return &ImportResult{Job: job}, nil
+}
+
+// GetJob 获取导入任务
+func (s *cardImportService) GetJob(ctx context.Context, merchantID, jobID uuid.UUID) (*entities.CardImportJob, error) {
+	return s.repo.FindJob(ctx, merchantID, jobID)
+}
+
+// ListJobs 分页获取商户的导入任务
+func (s *cardImportService) ListJobs(ctx context.Context, merchantID uuid.UUID, page, pageSize int) ([]*entities.CardImportJob, int, error) {
+	return s.repo.FindJobsByMerchant(ctx, merchantID, page, pageSize)
+}
+
+// runWorker 依次执行队列中的异步导入任务
+func (s *cardImportService) runWorker() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case queued := <-s.queue:
+			s.runJob(queued)
+		}
+	}
+}
+
+// runJob 执行一个异步导入任务并记录结果
+func (s *cardImportService) runJob(queued *queuedJob) {
+	ctx, cancel := context.WithTimeout(s.ctx, jobTimeout)
+	defer cancel()
+
+	job := queued.job
+	started, err := s.repo.MarkJobRunning(ctx, job.ID)
+	if err != nil {
+		s.logger.Printf("开始导入任务 %s 失败: %v", job.ID, err)
+		s.finishJob(job.ID, entities.CardImportJobFailed, &entities.CardImportReport{}, "开始导入失败")
+		return
+	}
+	if !started {
+		// 等待时间过长，已被清理为失败
+		s.logger.Printf("导入任务 %s 已不处于等待状态，跳过", job.ID)
+		return
+	}
+
+	report := s.importRows(ctx, job.MerchantID, queued.rows, func(summary entities.CardImportSummary) {
+		if err := s.repo.UpdateJobProgress(ctx, job.ID, summary); err != nil {
+			s.logger.Printf("更新导入任务 %s 进度失败: %v", job.ID, err)
+		}
+	})
+
+	status := entities.CardImportJobCompleted
+	errorMessage := ""
+	if err := ctx.Err(); err != nil {
+		status = entities.CardImportJobFailed
+		errorMessage = "导入被中断: " + err.Error()
+	}
+
+	s.finishJob(job.ID, status, report, errorMessage)
+	s.logger.Printf("导入任务 %s 结束: 状态=%s, 创建=%d, 重复=%d, 无效=%d, 失败=%d",
+		job.ID, status, report.Created, report.Duplicates, report.Invalid, report.Failed)
+}
+
+// finishJob 记录任务的最终状态，使用独立的上下文保证服务关闭时也能写入
+func (s *cardImportService) finishJob(id uuid.UUID, status entities.CardImportJobStatus, report *entities.CardImportReport, errorMessage string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := s.repo.FinishJob(ctx, id, status, report, errorMessage); err != nil {
+		s.logger.Printf("记录导入任务 %s 结果失败: %v", id, err)
+	}
+}
+
+// runStaleJobSweeper 定期将长时间没有进度的未完成任务标记为失败
+func (s *cardImportService) runStaleJobSweeper() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(staleJobTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		s.failStaleJobs()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// failStaleJobs 清理已中断的导入任务
+func (s *cardImportService) failStaleJobs() {
+	ctx, cancel := context.WithTimeout(s.ctx, 30*time.Second)
+	defer cancel()
+
+	count, err := s.repo.FailStaleJobs(ctx, time.Now().Add(-staleJobTimeout), "导入任务已中断，请重新上传文件")
+	if err != nil {
+		s.logger.Printf("清理中断的导入任务失败: %v", err)
+		return
+	}
+	if count > 0 {
+		s.logger.Printf("已将%d个中断的导入任务标记为失败", count)
+	}
+}
+
+// importRows 校验并分批导入卡片，每批在一个事务中写入
+// 行顺序与results一一对应；progress在每批完成后调用，可以为nil
+func (s *cardImportService) importRows(
+	ctx context.Context,
+	merchantID uuid.UUID,
+	rows []*entities.CardImportRow,
+	progress func(entities.CardImportSummary),
+) *entities.CardImportReport {
+	report := &entities.CardImportReport{
+		CardImportSummary: entities.CardImportSummary{Total: len(rows)},
+		Results:           make(entities.CardImportResults, len(rows)),
+	}
+
+	setResult := func(i int, status entities.CardImportRowStatus, cardID *uuid.UUID, message string) {
+		report.Results[i] = entities.CardImportRowResult{
+			Line:   rows[i].Line,
+			UID:    rows[i].UID,
+			Status: status,
+			CardID: cardID,
+			Error:  message,
+		}
+		report.Add(status)
+	}
+
+	// 先处理解析阶段的错误和文件内重复的UID，剩下的行按批次写入
+	firstLine := make(map[string]int, len(rows))
+	pending := make([]int, 0, len(rows))
+	for i, row := range rows {
+		if row.Error != "" {
+			setResult(i, entities.CardImportRowInvalid, nil, row.Error)
+			continue
+		}
+		if line, dup := firstLine[row.UID]; dup {
+			setResult(i, entities.CardImportRowDuplicate, nil, fmt.Sprintf("UID与第%d行重复", line))
+			continue
+		}
+		firstLine[row.UID] = row.Line
+		pending = append(pending, i)
+	}
+
+	for start := 0; start < len(pending); start += s.chunkSize {
+		end := start + s.chunkSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		if err := ctx.Err(); err != nil {
+			for _, i := range pending[start:] {
+				setResult(i, entities.CardImportRowFailed, nil, "导入被中断")
+			}
+			break
+		}
+
+		s.importChunk(ctx, merchantID, rows, pending[start:end], setResult)
+
+		if progress != nil {
+			progress(report.CardImportSummary)
+		}
+	}
+
+	return report
+}
+
+// importChunk 导入一批卡片：去除数据库中已存在的UID，校验默认视频归属后在一个事务中写入
+func (s *cardImportService) importChunk(
+	ctx context.Context,
+	merchantID uuid.UUID,
+	rows []*entities.CardImportRow,
+	indexes []int,
+	setResult func(int, entities.CardImportRowStatus, *uuid.UUID, string),
+) {
+	failAll := func(candidates []int, err error) {
+		s.logger.Printf("批量导入NFC卡片失败: %v", err)
+		for _, i := range candidates {
+			setResult(i, entities.CardImportRowFailed, nil, "写入数据库失败")
+		}
+	}
+
+	uids := make([]string, 0, len(indexes))
+	var videoIDs []uuid.UUID
+	for _, i := range indexes {
+		uids = append(uids, rows[i].UID)
+		if rows[i].DefaultVideoID != nil {
+			videoIDs = append(videoIDs, *rows[i].DefaultVideoID)
+		}
+	}
+
+	existing, err := s.repo.ExistingUIDs(ctx, uids)
+	if err != nil {
+		failAll(indexes, err)
+		return
+	}
+
+	ownedVideos, err := s.repo.MerchantVideoIDs(ctx, merchantID, videoIDs)
+	if err != nil {
+		failAll(indexes, err)
+		return
+	}
+
+	now := time.Now()
+	cards := make([]*entities.NfcCard, 0, len(indexes))
+	candidates := make([]int, 0, len(indexes))
+	for _, i := range indexes {
+		row := rows[i]
+		if existing[row.UID] {
+			setResult(i, entities.CardImportRowDuplicate, nil, "UID已存在")
+			continue
+		}
+		if row.DefaultVideoID != nil && !ownedVideos[*row.DefaultVideoID] {
+			setResult(i, entities.CardImportRowInvalid, nil, "默认视频不存在或不属于该商户")
+			continue
+		}
+
+		cards = append(cards, &entities.NfcCard{
+			ID:             uuid.New(),
+			TenantID:       merchantID,
+			MerchantID:     merchantID,
+			UID:            row.UID,
+			Name:           row.Name,
+			DefaultVideoID: row.DefaultVideoID,
+			Status:         entities.CardStatusNew,
+			ExpiresAt:      row.ExpiresAt,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+		candidates = append(candidates, i)
+	}
+
+	inserted, err := s.repo.InsertCards(ctx, cards)
+	if err != nil {
+		failAll(candidates, err)
+		return
+	}
+
+	for n, i := range candidates {
+		card := cards[n]
+		id, ok := inserted[card.UID]
+		if !ok {
+			// 查询之后被并发的导入或创建请求抢先写入
+			setResult(i, entities.CardImportRowDuplicate, nil, "UID已存在")
+			continue
+		}
+
+		card.ID = id
+		setResult(i, entities.CardImportRowCreated, &id, "")
+		s.publishCreated(card)
+	}
+}
+
+// publishCreated 发布卡片创建事件，失败只记录日志，不影响导入结果
+func (s *cardImportService) publishCreated(card *entities.NfcCard) {
+	if s.producer == nil {
+		return
+	}
+
+	event := cardCreatedEvent{
+		ID:         card.ID,
+		MerchantID: card.MerchantID,
+		UID:        card.UID,
+		Name:       card.Name,
+		CreatedAt:  card.CreatedAt,
+	}
+	if err := s.producer.SendMessage(TopicCardEvents, TypeCardCreated, event); err != nil {
+		s.logger.Printf("发布卡片创建事件失败: card=%s, %v", card.ID, err)
+	}
+}
@@ -0,0 +1,303 @@
+package cardimport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxUIDLength 与nfc_cards.uid列长度一致
+	maxUIDLength = 255
+	// maxNameLength 与nfc_cards.name列长度一致
+	maxNameLength = 255
+	// maxJSONLineSize JSON Lines中单行的最大字节数
+	maxJSONLineSize = 64 * 1024
+)
+
+var (
+	// ErrInvalidImportFile 导入文件无法解析
+	ErrInvalidImportFile = errors.New("导入文件无效")
+	// ErrUnsupportedFormat 不支持的导入文件格式
+	ErrUnsupportedFormat = errors.New("不支持的导入文件格式")
+)
+
+// UID会拼接到落地页URL中，只允许URL安全的字符，例如 04A2B3C4D5E6F7 或 04:A2:B3:C4:D5:E6:F7
+var uidPattern = regexp.MustCompile(`^[A-Za-z0-9:_-]+$`)
+
+// 失效时间支持的格式，不带时区的按服务器本地时区解析
+var expiresAtLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// CSV表头别名，比较前会转为小写并去掉空格、下划线和连字符
+var csvColumnAliases = map[string]string{
+	"uid":            "uid",
+	"carduid":        "uid",
+	"name":           "name",
+	"defaultvideoid": "default_video_id",
+	"videoid":        "default_video_id",
+	"expiresat":      "expires_at",
+	"expiry":         "expires_at",
+	"expires":        "expires_at",
+}
+
+// DetectFormat 根据显式指定的格式、文件名或Content-Type判断导入文件格式
+func DetectFormat(explicit, fileName, contentType string) (entities.CardImportFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(explicit)) {
+	case "csv":
+		return entities.CardImportFormatCSV, nil
+	case "jsonl", "ndjson":
+		return entities.CardImportFormatJSONL, nil
+	case "":
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, explicit)
+	}
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return entities.CardImportFormatCSV, nil
+	case ".jsonl", ".ndjson":
+		return entities.CardImportFormatJSONL, nil
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return entities.CardImportFormatCSV, nil
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines", "application/json-lines":
+		return entities.CardImportFormatJSONL, nil
+	}
+
+	return "", fmt.Errorf("%w: 请通过format参数指定csv或jsonl", ErrUnsupportedFormat)
+}
+
+// ParseRows 解析导入文件
+// 文件结构错误（缺少必需的列、JSON无法解析、行数超限等）返回错误；单行数据的校验错误记录在该行的Error中
+func ParseRows(r io.Reader, format entities.CardImportFormat, maxRows int, now time.Time) ([]*entities.CardImportRow, error) {
+	switch format {
+	case entities.CardImportFormatCSV:
+		return parseCSV(r, maxRows, now)
+	case entities.CardImportFormatJSONL:
+		return parseJSONL(r, maxRows, now)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+}
+
+// parseCSV 解析带表头的CSV文件，列顺序不限
+func parseCSV(r io.Reader, maxRows int, now time.Time) ([]*entities.CardImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: 文件为空", ErrInvalidImportFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: 读取表头失败: %w", ErrInvalidImportFile, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// Excel导出的CSV带有UTF-8 BOM
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		key := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+		if column, ok := csvColumnAliases[key]; ok {
+			if _, dup := columns[column]; dup {
+				return nil, fmt.Errorf("%w: 表头中%s列重复", ErrInvalidImportFile, column)
+			}
+			columns[column] = i
+		}
+	}
+	for _, required := range []string{"uid", "name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: 表头缺少%s列", ErrInvalidImportFile, required)
+		}
+	}
+
+	field := func(record []string, column string) string {
+		idx, ok := columns[column]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return record[idx]
+	}
+
+	var rows []*entities.CardImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidImportFile, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if isBlankRecord(record) {
+			continue
+		}
+		if maxRows > 0 && len(rows) >= maxRows {
+			return nil, fmt.Errorf("%w: 行数超过上限%d", ErrInvalidImportFile, maxRows)
+		}
+
+		rows = append(rows, buildRow(line,
+			field(record, "uid"),
+			field(record, "name"),
+			field(record, "default_video_id"),
+			field(record, "expires_at"),
+			now,
+		))
+	}
+
+	return rows, nil
+}
+
+// jsonlRecord JSON Lines中的一行，同时支持驼峰和下划线命名
+type jsonlRecord struct {
+	UID                 string `json:"uid"`
+	Name                string `json:"name"`
+	DefaultVideoID      string `json:"defaultVideoId"`
+	DefaultVideoIDSnake string `json:"default_video_id"`
+	ExpiresAt           string `json:"expiresAt"`
+	ExpiresAtSnake      string `json:"expires_at"`
+}
+
+// parseJSONL 解析每行一个JSON对象的文件，空行会被忽略
+func parseJSONL(r io.Reader, maxRows int, now time.Time) ([]*entities.CardImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxJSONLineSize)
+
+	var rows []*entities.CardImportRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text == "" {
+			continue
+		}
+		if maxRows > 0 && len(rows) >= maxRows {
+			return nil, fmt.Errorf("%w: 行数超过上限%d", ErrInvalidImportFile, maxRows)
+		}
+
+		var record jsonlRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			rows = append(rows, &entities.CardImportRow{Line: line, Error: "JSON格式无效: " + err.Error()})
+			continue
+		}
+
+		rows = append(rows, buildRow(line,
+			record.UID,
+			record.Name,
+			firstNonEmpty(record.DefaultVideoID, record.DefaultVideoIDSnake),
+			firstNonEmpty(record.ExpiresAt, record.ExpiresAtSnake),
+			now,
+		))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: 第%d行之后读取失败: %w", ErrInvalidImportFile, line, err)
+	}
+
+	return rows, nil
+}
+
+// buildRow 校验单行字段并构造导入行
+func buildRow(line int, uid, name, videoID, expiresAt string, now time.Time) *entities.CardImportRow {
+	row := &entities.CardImportRow{
+		Line: line,
+		UID:  strings.TrimSpace(uid),
+		Name: strings.TrimSpace(name),
+	}
+
+	switch {
+	case row.UID == "":
+		row.Error = "UID不能为空"
+		return row
+	case len(row.UID) > maxUIDLength:
+		row.Error = fmt.Sprintf("UID长度不能超过%d个字符", maxUIDLength)
+		return row
+	case !uidPattern.MatchString(row.UID):
+		row.Error = "UID只能包含字母、数字、冒号、连字符和下划线"
+		return row
+	case row.Name == "":
+		row.Error = "名称不能为空"
+		return row
+	case utf8.RuneCountInString(row.Name) > maxNameLength:
+		row.Error = fmt.Sprintf("名称长度不能超过%d个字符", maxNameLength)
+		return row
+	}
+
+	if videoID = strings.TrimSpace(videoID); videoID != "" {
+		id, err := uuid.Parse(videoID)
+		if err != nil {
+			row.Error = "默认视频ID格式无效"
+			return row
+		}
+		row.DefaultVideoID = &id
+	}
+
+	if expiresAt = strings.TrimSpace(expiresAt); expiresAt != "" {
+		t, err := parseExpiresAt(expiresAt)
+		if err != nil {
+			row.Error = "失效时间格式无效，应为RFC3339或YYYY-MM-DD"
+			return row
+		}
+		if !t.After(now) {
+			row.Error = "失效时间必须晚于当前时间"
+			return row
+		}
+		row.ExpiresAt = &t
+	}
+
+	return row
+}
+
+// parseExpiresAt 按支持的格式依次尝试解析失效时间
+func parseExpiresAt(value string) (time.Time, error) {
+	var lastErr error
+	for _, layout := range expiresAtLayouts {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err == nil {
+			return t, nil
+		}
+		lastErr = err
+	}
+	return time.Time{}, lastErr
+}
+
+// isBlankRecord 判断CSV记录是否所有字段都为空
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
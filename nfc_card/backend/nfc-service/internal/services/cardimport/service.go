@@ -0,0 +1,44 @@
+package cardimport
+
+import (
+	"context"
+	"io"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// Service NFC卡片批量导入服务接口
+type Service interface {
+	// Import 解析并导入卡片
+	// 行数不超过异步阈值时同步导入并返回逐行报告；否则创建异步任务后立即返回，通过GetJob查询进度和结果
+	Import(ctx context.Context, req *ImportRequest) (*ImportResult, error)
+	GetJob(ctx context.Context, merchantID, jobID uuid.UUID) (*entities.CardImportJob, error)
+	ListJobs(ctx context.Context, merchantID uuid.UUID, page, pageSize int) ([]*entities.CardImportJob, int, error)
+	Start()
+	Stop()
+}
+
+// KafkaProducer Kafka生产者接口
+type KafkaProducer interface {
+	// SendMessage 发送消息到指定主题
+	SendMessage(topic string, messageType string, data interface{}) error
+}
+
+// ImportRequest 一次批量导入请求
+type ImportRequest struct {
+	MerchantID uuid.UUID
+	CreatedBy  *uuid.UUID
+	Format     entities.CardImportFormat
+	FileName   string
+	Reader     io.Reader
+	// Async 为true时无论行数多少都以异步任务执行
+	Async bool
+}
+
+// ImportResult 批量导入的返回结果，同步导入时Report非空，异步导入时Job非空
+type ImportResult struct {
+	Report *entities.CardImportReport
+	Job    *entities.CardImportJob
+}
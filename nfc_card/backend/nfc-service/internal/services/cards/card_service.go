@@ -2,26 +2,73 @@ package cards
 
 import (
 	"context"
+	"errors"
 	"log"
+	"sync"
+	"time"
+
+	"nfc-service/internal/config"
 	"nfc-service/internal/domain/entities"
 	"nfc-service/internal/domain/repositories"
 
 	"github.com/google/uuid"
 )
 
+// TopicCardEvents 卡片事件主题
+const TopicCardEvents = "card-events"
+
+// sweepTimeout 单次过期扫描的超时时间
+const sweepTimeout = 30 * time.Second
+
+// expireReason 过期扫描写入事件历史的原因
+const expireReason = "有效期已到"
+
+// lifecycleEventTypes 生命周期操作对应的Kafka消息类型
+var lifecycleEventTypes = map[entities.CardAction]string{
+	entities.CardActionActivate:   "card.activated",
+	entities.CardActionBind:       "card.bound",
+	entities.CardActionUnbind:     "card.unbound",
+	entities.CardActionDeactivate: "card.deactivated",
+	entities.CardActionReactivate: "card.reactivated",
+	entities.CardActionExpire:     "card.expired",
+}
+
 // 保留服务接口定义在service.go中，此文件实现该接口
 
 // cardService 是NFC卡片服务的实现
 type cardService struct {
-	cardRepo *repositories.NfcCardRepository
-	logger   *log.Logger
+	cardRepo      *repositories.NfcCardRepository
+	links         CardLinks
+	producer      KafkaProducer
+	sweepInterval time.Duration
+	batchSize     int
+	logger        *log.Logger
+
+	stopOnce sync.Once
+	done     chan struct{}
+	wg       sync.WaitGroup
 }
 
 // NewCardService 创建新的NFC卡片服务
-func NewCardService(cardRepo *repositories.NfcCardRepository, logger *log.Logger) Service {
+// links和producer可以为nil，此时分别不同步短链接状态、不发布生命周期事件
+func NewCardService(cardRepo *repositories.NfcCardRepository, links CardLinks, producer KafkaProducer, cfg config.CardsConfig, logger *log.Logger) Service {
+	sweepInterval := time.Duration(cfg.ExpirySweepIntervalSeconds) * time.Second
+	if sweepInterval <= 0 {
+		sweepInterval = time.Minute
+	}
+	batchSize := cfg.ExpiryBatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
 	return &cardService{
-		cardRepo: cardRepo,
-		logger:   logger,
+		cardRepo:      cardRepo,
+		links:         links,
+		producer:      producer,
+		sweepInterval: sweepInterval,
+		batchSize:     batchSize,
+		logger:        logger,
+		done:          make(chan struct{}),
 	}
 }
 
@@ -50,6 +97,7 @@ func (s *cardService) GetByMerchantID(ctx context.Context, merchantID uuid.UUID,
 }
 
 // Update 更新NFC卡片
+// 状态不能通过更新接口修改，请求中的状态与当前状态不同时返回ErrStatusNotEditable
 func (s *cardService) Update(ctx context.Context, id uuid.UUID, card *entities.UpdateNfcCardDTO) (*entities.NfcCard, error) {
 	s.logger.Printf("更新NFC卡片，ID: %s", id)
 	// 验证更新的卡片是否存在
@@ -62,6 +110,10 @@ func (s *cardService) Update(ctx context.Context, id uuid.UUID, card *entities.U
 		return nil, repositories.ErrCardNotFound
 	}
 
+	if card.Status != "" && card.Status != existingCard.Status {
+		return nil, ErrStatusNotEditable
+	}
+
 	return s.cardRepo.Update(ctx, id, card)
 }
 
@@ -72,18 +124,13 @@ func (s *cardService) Delete(ctx context.Context, id uuid.UUID) error {
 }
 
 // Activate 激活NFC卡片
-func (s *cardService) Activate(ctx context.Context, uid string) (*entities.NfcCard, error) {
+func (s *cardService) Activate(ctx context.Context, uid string, actor *entities.CardActor) (*entities.NfcCard, error) {
 	s.logger.Printf("激活NFC卡片，UID: %s", uid)
-	card, err := s.cardRepo.Activate(ctx, uid)
+	card, err := s.cardRepo.FindByUID(ctx, uid)
 	if err != nil {
 		return nil, err
 	}
-
-	// 生成激活事件，通知其他服务
-	s.logger.Printf("NFC卡片已激活，触发后续流程")
-	// TODO: 发送激活事件到消息队列
-
-	return card, nil
+	return s.transition(ctx, card, entities.CardActionActivate, nil, actor)
 }
 
 // GetByUserID 获取用户的所有NFC卡片
@@ -93,67 +140,234 @@ func (s *cardService) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*ent
 }
 
 // Bind 绑定NFC卡片到用户
-func (s *cardService) Bind(ctx context.Context, bind *entities.BindNfcCardDTO) (*entities.NfcCard, error) {
+func (s *cardService) Bind(ctx context.Context, bind *entities.BindNfcCardDTO, actor *entities.CardActor) (*entities.NfcCard, error) {
 	s.logger.Printf("绑定NFC卡片，ID: %s, 用户ID: %s", bind.ID, bind.UserID)
-	card, err := s.cardRepo.BindToUser(ctx, bind.ID, bind.UserID)
+	if bind.UserID == uuid.Nil {
+		return nil, ErrUserRequired
+	}
+
+	card, err := s.cardRepo.FindByID(ctx, bind.ID)
 	if err != nil {
 		return nil, err
 	}
-
-	// 触发绑定事件
-	s.logger.Printf("NFC卡片已绑定到用户，触发后续流程")
-	// TODO: 发送绑定事件到消息队列
-
-	return card, nil
+	userID := bind.UserID
+	return s.transition(ctx, card, entities.CardActionBind, &userID, actor)
 }
 
 // Unbind 解绑NFC卡片
-func (s *cardService) Unbind(ctx context.Context, unbind *entities.UnbindNfcCardDTO) (*entities.NfcCard, error) {
+func (s *cardService) Unbind(ctx context.Context, unbind *entities.UnbindNfcCardDTO, actor *entities.CardActor) (*entities.NfcCard, error) {
 	s.logger.Printf("解绑NFC卡片，ID: %s", unbind.ID)
-	card, err := s.cardRepo.UnbindFromUser(ctx, unbind.ID)
+	card, err := s.cardRepo.FindByID(ctx, unbind.ID)
 	if err != nil {
 		return nil, err
 	}
+	return s.transition(ctx, card, entities.CardActionUnbind, nil, actor)
+}
 
-	// 触发解绑事件
-	s.logger.Printf("NFC卡片已解绑，触发后续流程")
-	// TODO: 发送解绑事件到消息队列
+// Deactivate 停用NFC卡片，同时停用卡片的短链接
+func (s *cardService) Deactivate(ctx context.Context, id uuid.UUID, actor *entities.CardActor) (*entities.NfcCard, error) {
+	s.logger.Printf("停用NFC卡片，ID: %s", id)
+	card, err := s.cardRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.transition(ctx, card, entities.CardActionDeactivate, nil, actor)
+}
 
-	return card, nil
+// Reactivate 重新激活已停用或已过期的NFC卡片，同时恢复因卡片停用而停用的短链接
+// 有效期已过的卡片需要先通过Update延长有效期
+func (s *cardService) Reactivate(ctx context.Context, id uuid.UUID, actor *entities.CardActor) (*entities.NfcCard, error) {
+	s.logger.Printf("重新激活NFC卡片，ID: %s", id)
+	card, err := s.cardRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.transition(ctx, card, entities.CardActionReactivate, nil, actor)
 }
 
-// Deactivate 停用NFC卡片
-func (s *cardService) Deactivate(ctx context.Context, id uuid.UUID) (*entities.NfcCard, error) {
-	s.logger.Printf("停用NFC卡片，ID: %s", id)
-	card, err := s.cardRepo.Deactivate(ctx, id)
+// UpdateStatus 将NFC卡片变更到指定状态，按转换表推断对应的生命周期操作
+// 绑定需要指定用户，不能通过该方法完成
+func (s *cardService) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.CardStatus, actor *entities.CardActor) (*entities.NfcCard, error) {
+	s.logger.Printf("更新NFC卡片状态，ID: %s, 状态: %s", id, status)
+	card, err := s.cardRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	// 触发停用事件
-	s.logger.Printf("NFC卡片已停用，触发后续流程")
-	// TODO: 发送停用事件到消息队列
+	action, err := actionFor(card.Status, status)
+	if err != nil {
+		return nil, err
+	}
+	// 推断出的操作可能到达与请求不同的状态，例如重新激活已绑定用户的卡片会回到bound
+	to, err := nextStatus(card, action)
+	if err != nil {
+		return nil, err
+	}
+	if to != status {
+		return nil, &TransitionError{From: card.Status, To: status}
+	}
 
-	return card, nil
+	return s.transition(ctx, card, action, nil, actor)
 }
 
-// Reactivate 重新激活NFC卡片
-func (s *cardService) Reactivate(ctx context.Context, id uuid.UUID) (*entities.NfcCard, error) {
-	s.logger.Printf("重新激活NFC卡片，ID: %s", id)
-	card, err := s.cardRepo.Reactivate(ctx, id)
+// GetEvents 分页获取卡片的状态变更历史
+func (s *cardService) GetEvents(ctx context.Context, id uuid.UUID, page, pageSize int) ([]*entities.CardEvent, int, error) {
+	return s.cardRepo.FindEvents(ctx, id, page, pageSize)
+}
+
+// ExpireDue 将有效期已到的卡片置为过期
+// 每批处理batchSize张卡片，直到没有到期卡片；扫描期间被其他请求修改状态的卡片留到下一轮处理
+func (s *cardService) ExpireDue(ctx context.Context) (int, error) {
+	actor := &entities.CardActor{Type: entities.CardActorSystem, Reason: expireReason}
+	expired := 0
+
+	for {
+		due, err := s.cardRepo.FindDueForExpiry(ctx, time.Now(), s.batchSize)
+		if err != nil {
+			return expired, err
+		}
+
+		progressed := 0
+		for _, card := range due {
+			if _, err := s.transition(ctx, card, entities.CardActionExpire, nil, actor); err != nil {
+				if errors.Is(err, repositories.ErrCardStatusChanged) || errors.Is(err, repositories.ErrCardNotFound) {
+					continue
+				}
+				return expired, err
+			}
+			expired++
+			progressed++
+		}
+
+		if len(due) < s.batchSize || progressed == 0 {
+			return expired, nil
+		}
+	}
+}
+
+// Start 启动过期扫描协程
+func (s *cardService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	s.logger.Printf("卡片过期扫描协程已启动，间隔: %s", s.sweepInterval)
+}
+
+// Stop 停止过期扫描协程
+func (s *cardService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+}
+
+// run 定期扫描到期的卡片
+func (s *cardService) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sweepOnce()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// sweepOnce 执行一次过期扫描
+func (s *cardService) sweepOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), sweepTimeout)
+	defer cancel()
+
+	expired, err := s.ExpireDue(ctx)
+	if err != nil {
+		s.logger.Printf("卡片过期扫描失败: %v", err)
+	}
+	if expired > 0 {
+		s.logger.Printf("已将 %d 张到期卡片置为过期", expired)
+	}
+}
+
+// transition 校验并执行一次状态变更，随后同步短链接并发布生命周期事件
+func (s *cardService) transition(ctx context.Context, card *entities.NfcCard, action entities.CardAction, userID *uuid.UUID, actor *entities.CardActor) (*entities.NfcCard, error) {
+	to, err := nextStatus(card, action)
+	if err != nil {
+		return nil, err
+	}
+	if action == entities.CardActionReactivate && card.ExpiresAt != nil && !card.ExpiresAt.After(time.Now()) {
+		return nil, ErrCardExpired
+	}
+
+	updated, event, err := s.cardRepo.ApplyTransition(ctx, card.ID, &entities.CardTransition{
+		Action: action,
+		From:   card.Status,
+		To:     to,
+		UserID: userID,
+		Actor:  actor,
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.logger.Printf("NFC卡片状态已变更，ID: %s, %s -> %s", updated.ID, event.FromStatus, event.ToStatus)
 
-	// 触发重新激活事件
-	s.logger.Printf("NFC卡片已重新激活，触发后续流程")
-	// TODO: 发送重新激活事件到消息队列
+	s.syncLinks(ctx, updated, action)
+	s.publish(updated, event)
 
-	return card, nil
+	return updated, nil
 }
 
-// UpdateStatus 更新NFC卡片状态
-func (s *cardService) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.CardStatus) (*entities.NfcCard, error) {
-	s.logger.Printf("更新NFC卡片状态，ID: %s, 状态: %s", id, status)
-	return s.cardRepo.UpdateStatus(ctx, id, status)
+// syncLinks 停用或过期时停用卡片的短链接，重新激活时恢复
+// 失败只记录日志：重定向时还会检查短链接的启用状态和有效期，不会因此放行已停用的卡片
+func (s *cardService) syncLinks(ctx context.Context, card *entities.NfcCard, action entities.CardAction) {
+	if s.links == nil {
+		return
+	}
+
+	switch action {
+	case entities.CardActionDeactivate, entities.CardActionExpire:
+		if _, err := s.links.SuspendCardLinks(ctx, card.ID); err != nil {
+			s.logger.Printf("停用卡片 %s 的短链接失败: %v", card.ID, err)
+		}
+	case entities.CardActionReactivate:
+		if _, err := s.links.ResumeCardLinks(ctx, card.ID); err != nil {
+			s.logger.Printf("恢复卡片 %s 的短链接失败: %v", card.ID, err)
+		}
+	}
+}
+
+// publish 发布卡片生命周期事件
+func (s *cardService) publish(card *entities.NfcCard, event *entities.CardEvent) {
+	if s.producer == nil {
+		return
+	}
+
+	msg := CardLifecycleEvent{
+		ID:         card.ID,
+		CardID:     card.ID,
+		MerchantID: card.MerchantID,
+		UID:        card.UID,
+		Action:     event.Action,
+		FromStatus: event.FromStatus,
+		ToStatus:   event.ToStatus,
+		UserID:     event.UserID,
+		ActorType:  event.ActorType,
+		ActorID:    event.ActorID,
+		Reason:     event.Reason,
+		OccurredAt: event.CreatedAt,
+	}
+	switch event.Action {
+	case entities.CardActionActivate:
+		msg.ActivatedAt = &event.CreatedAt
+	case entities.CardActionBind:
+		msg.BoundAt = &event.CreatedAt
+	case entities.CardActionUnbind:
+		msg.UnboundAt = &event.CreatedAt
+	}
+
+	if err := s.producer.SendMessage(TopicCardEvents, lifecycleEventTypes[event.Action], msg); err != nil {
+		s.logger.Printf("发布卡片事件失败: %v", err)
+	}
 }
@@ -0,0 +1,105 @@
+package cards
+
+import (
+	"errors"
+	"fmt"
+
+	"nfc-service/internal/domain/entities"
+)
+
+var (
+	// ErrInvalidTransition 卡片当前状态不允许执行该操作，具体信息见TransitionError
+	ErrInvalidTransition = errors.New("非法的卡片状态变更")
+	// ErrStatusNotEditable 不能通过更新接口直接修改卡片状态
+	ErrStatusNotEditable = errors.New("卡片状态只能通过激活、绑定、解绑、停用和重新激活接口修改")
+	// ErrCardExpired 卡片有效期已过，需要先延长有效期才能重新激活
+	ErrCardExpired = errors.New("卡片已过有效期，请先延长有效期")
+	// ErrUserRequired 绑定卡片需要指定用户
+	ErrUserRequired = errors.New("绑定卡片需要指定用户")
+)
+
+// TransitionError 非法的卡片状态变更
+type TransitionError struct {
+	Action entities.CardAction
+	From   entities.CardStatus
+	To     entities.CardStatus
+}
+
+// Error 实现error接口
+func (e *TransitionError) Error() string {
+	if e.To == "" {
+		return fmt.Sprintf("%s: 卡片状态为%s，不能执行%s操作", ErrInvalidTransition, e.From, e.Action)
+	}
+	return fmt.Sprintf("%s: 不能从%s变为%s", ErrInvalidTransition, e.From, e.To)
+}
+
+// Is 使errors.Is(err, ErrInvalidTransition)成立
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// cardTransitions 卡片生命周期状态转换表：当前状态 -> 操作 -> 目标状态
+// 重新激活时如果卡片仍关联用户，目标状态为bound而不是activated，见reactivateTarget
+var cardTransitions = map[entities.CardStatus]map[entities.CardAction]entities.CardStatus{
+	entities.CardStatusNew: {
+		entities.CardActionActivate:   entities.CardStatusActivated,
+		entities.CardActionDeactivate: entities.CardStatusDeactivated,
+		entities.CardActionExpire:     entities.CardStatusExpired,
+	},
+	entities.CardStatusActivated: {
+		entities.CardActionBind:       entities.CardStatusBound,
+		entities.CardActionDeactivate: entities.CardStatusDeactivated,
+		entities.CardActionExpire:     entities.CardStatusExpired,
+	},
+	entities.CardStatusBound: {
+		entities.CardActionUnbind:     entities.CardStatusActivated,
+		entities.CardActionDeactivate: entities.CardStatusDeactivated,
+		entities.CardActionExpire:     entities.CardStatusExpired,
+	},
+	entities.CardStatusDeactivated: {
+		entities.CardActionReactivate: entities.CardStatusActivated,
+		entities.CardActionExpire:     entities.CardStatusExpired,
+	},
+	entities.CardStatusExpired: {
+		entities.CardActionReactivate: entities.CardStatusActivated,
+	},
+}
+
+// nextStatus 根据转换表返回卡片执行操作后的状态，不允许时返回TransitionError
+func nextStatus(card *entities.NfcCard, action entities.CardAction) (entities.CardStatus, error) {
+	to, ok := cardTransitions[card.Status][action]
+	if !ok {
+		return "", &TransitionError{Action: action, From: card.Status}
+	}
+	if action == entities.CardActionReactivate {
+		to = reactivateTarget(card)
+	}
+	return to, nil
+}
+
+// reactivateTarget 停用或过期前已绑定用户的卡片重新激活后恢复为绑定状态
+func reactivateTarget(card *entities.NfcCard) entities.CardStatus {
+	if card.UserID != nil {
+		return entities.CardStatusBound
+	}
+	return entities.CardStatusActivated
+}
+
+// actionFor 推断从from变为to对应的生命周期操作，用于UpdateStatus
+func actionFor(from, to entities.CardStatus) (entities.CardAction, error) {
+	switch {
+	case to == entities.CardStatusDeactivated:
+		return entities.CardActionDeactivate, nil
+	case to == entities.CardStatusExpired:
+		return entities.CardActionExpire, nil
+	case from == entities.CardStatusDeactivated || from == entities.CardStatusExpired:
+		return entities.CardActionReactivate, nil
+	case from == entities.CardStatusNew && to == entities.CardStatusActivated:
+		return entities.CardActionActivate, nil
+	case from == entities.CardStatusBound && to == entities.CardStatusActivated:
+		return entities.CardActionUnbind, nil
+	case to == entities.CardStatusBound:
+		return "", ErrUserRequired
+	}
+	return "", &TransitionError{From: from, To: to}
+}
@@ -2,6 +2,7 @@ package cards
 
 import (
 	"context"
+	"time"
 
 	"nfc-service/internal/domain/entities"
 
@@ -9,6 +10,8 @@ import (
 )
 
 // Service 是NFC卡片服务的接口
+// 卡片状态只能通过Activate、Bind、Unbind、Deactivate、Reactivate、UpdateStatus和过期扫描按转换表变更，
+// 每次变更都会写入卡片事件历史
 type Service interface {
 	Create(ctx context.Context, card *entities.CreateNfcCardDTO) (*entities.NfcCard, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*entities.NfcCard, error)
@@ -17,12 +20,50 @@ type Service interface {
 	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entities.NfcCard, error)
 	Update(ctx context.Context, id uuid.UUID, card *entities.UpdateNfcCardDTO) (*entities.NfcCard, error)
 	Delete(ctx context.Context, id uuid.UUID) error
-	Activate(ctx context.Context, uid string) (*entities.NfcCard, error)
-	Bind(ctx context.Context, bind *entities.BindNfcCardDTO) (*entities.NfcCard, error)
-	Unbind(ctx context.Context, unbind *entities.UnbindNfcCardDTO) (*entities.NfcCard, error)
-	Deactivate(ctx context.Context, id uuid.UUID) (*entities.NfcCard, error)
-	Reactivate(ctx context.Context, id uuid.UUID) (*entities.NfcCard, error)
-	UpdateStatus(ctx context.Context, id uuid.UUID, status entities.CardStatus) (*entities.NfcCard, error)
+	Activate(ctx context.Context, uid string, actor *entities.CardActor) (*entities.NfcCard, error)
+	Bind(ctx context.Context, bind *entities.BindNfcCardDTO, actor *entities.CardActor) (*entities.NfcCard, error)
+	Unbind(ctx context.Context, unbind *entities.UnbindNfcCardDTO, actor *entities.CardActor) (*entities.NfcCard, error)
+	Deactivate(ctx context.Context, id uuid.UUID, actor *entities.CardActor) (*entities.NfcCard, error)
+	Reactivate(ctx context.Context, id uuid.UUID, actor *entities.CardActor) (*entities.NfcCard, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status entities.CardStatus, actor *entities.CardActor) (*entities.NfcCard, error)
+	// GetEvents 分页获取卡片的状态变更历史
+	GetEvents(ctx context.Context, id uuid.UUID, page, pageSize int) ([]*entities.CardEvent, int, error)
+	// ExpireDue 将有效期已到的卡片置为过期并停用其短链接，返回处理的卡片数量
+	ExpireDue(ctx context.Context) (int, error)
+	// Start 启动过期扫描
+	Start()
+	// Stop 停止过期扫描
+	Stop()
 }
 
-// 移除原有重复的service实现，统一使用card_service.go中的实现
+// CardLinks 卡片停用、过期或重新激活时同步其短链接的启用状态，由shortlinks.Service实现
+type CardLinks interface {
+	SuspendCardLinks(ctx context.Context, cardID uuid.UUID) (int, error)
+	ResumeCardLinks(ctx context.Context, cardID uuid.UUID) (int, error)
+}
+
+// KafkaProducer Kafka生产者接口
+type KafkaProducer interface {
+	// SendMessage 发送消息到指定主题
+	SendMessage(topic string, messageType string, data interface{}) error
+}
+
+// CardLifecycleEvent 卡片状态变更事件
+// card_id与id相同，兼容按card_id读取的card.bound/card.unbound消费者
+type CardLifecycleEvent struct {
+	ID          uuid.UUID              `json:"id"`
+	CardID      uuid.UUID              `json:"card_id"`
+	MerchantID  uuid.UUID              `json:"merchant_id"`
+	UID         string                 `json:"uid"`
+	Action      entities.CardAction    `json:"action"`
+	FromStatus  entities.CardStatus    `json:"from_status"`
+	ToStatus    entities.CardStatus    `json:"to_status"`
+	UserID      *uuid.UUID             `json:"user_id,omitempty"`
+	ActorType   entities.CardActorType `json:"actor_type"`
+	ActorID     *uuid.UUID             `json:"actor_id,omitempty"`
+	Reason      string                 `json:"reason,omitempty"`
+	ActivatedAt *time.Time             `json:"activated_at,omitempty"`
+	BoundAt     *time.Time             `json:"bound_at,omitempty"`
+	UnboundAt   *time.Time             `json:"unbound_at,omitempty"`
+	OccurredAt  time.Time              `json:"occurred_at"`
+}
@@ -0,0 +1,306 @@
+package clicks
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/storage"
+	"nfc-service/pkg/geoip"
+	"nfc-service/pkg/useragent"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TopicStatsEvents 统计事件主题
+	TopicStatsEvents = "stats-events"
+	// TypeShortLinkClicked 短链接点击事件类型
+	TypeShortLinkClicked = "shortlink.clicked"
+
+	defaultBatchSize     = 100
+	defaultBufferSize    = 10000
+	defaultFlushInterval = 2 * time.Second
+	writeTimeout         = 10 * time.Second
+)
+
+// KafkaProducer Kafka生产者接口
+type KafkaProducer interface {
+	// SendMessage 发送消息到指定主题
+	SendMessage(topic string, messageType string, data interface{}) error
+}
+
+// clickService 点击事件服务的实现，按批次异步写入数据库并发布到Kafka
+type clickService struct {
+	repo          *storage.ClickRepository
+	producer      KafkaProducer
+	geo           *geoip.DB
+	logger        *log.Logger
+	events        chan *entities.ClickEvent
+	batchSize     int
+	flushInterval time.Duration
+	stopOnce      sync.Once
+	done          chan struct{}
+	wg            sync.WaitGroup
+}
+
+// NewClickService 创建点击事件服务
+// producer和geo可以为nil，此时分别跳过事件发布和地理位置解析
+func NewClickService(
+	repo *storage.ClickRepository,
+	producer KafkaProducer,
+	geo *geoip.DB,
+	cfg config.ClickConfig,
+	logger *log.Logger,
+) Service {
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	flushInterval := time.Duration(cfg.FlushIntervalSeconds) * time.Second
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+
+	return &clickService{
+		repo:          repo,
+		producer:      producer,
+		geo:           geo,
+		logger:        logger,
+		events:        make(chan *entities.ClickEvent, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+	}
+}
+
+// Record 记录一次点击
+func (s *clickService) Record(link *entities.ShortLink, visit *Visit) {
+	event := s.buildEvent(link, visit)
+
+	select {
+	case s.events <- event:
+	default:
+		// 缓冲区已满时丢弃事件，避免拖慢重定向
+		s.logger.Printf("点击事件缓冲区已满，丢弃事件: slug=%s", link.Slug)
+	}
+}
+
+// buildEvent 根据短链接和请求信息构建点击事件
+func (s *clickService) buildEvent(link *entities.ShortLink, visit *Visit) *entities.ClickEvent {
+	ua := useragent.Parse(visit.UserAgent)
+
+	clickedAt := visit.ClickedAt
+	if clickedAt.IsZero() {
+		clickedAt = time.Now()
+	}
+
+	event := &entities.ClickEvent{
+		ID:          uuid.New(),
+		TenantID:    link.TenantID,
+		ShortLinkID: link.ID,
+		Slug:        link.Slug,
+		ClickedAt:   clickedAt,
+		UserAgent:   visit.UserAgent,
+		OS:          ua.OS,
+		Browser:     ua.Browser,
+		DeviceType:  ua.DeviceType,
+		IPAddress:   geoip.Anonymize(visit.IP),
+		Referrer:    visit.Referrer,
+		VariantID:   visit.VariantID,
+		Source:      visit.Source,
+		Suspicious:  visit.Suspicious,
+	}
+	if event.Source == "" {
+		event.Source = entities.ClickSourceNFC
+	}
+
+	if link.NfcCardID != uuid.Nil {
+		cardID := link.NfcCardID
+		event.NfcCardID = &cardID
+	}
+
+	// 地理位置必须在匿名化之前使用完整IP解析
+	if location, ok := s.geo.Lookup(visit.IP); ok {
+		event.Country = location.Country
+		event.Region = location.Region
+	}
+
+	return event
+}
+
+// Start 启动后台批量写入协程
+func (s *clickService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	s.logger.Printf("点击事件写入协程已启动，批量大小: %d, 刷新间隔: %s", s.batchSize, s.flushInterval)
+}
+
+// Stop 停止后台协程并写入剩余的事件
+func (s *clickService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+}
+
+// run 收集事件，达到批量大小或刷新间隔时写入
+func (s *clickService) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*entities.ClickEvent, 0, s.batchSize)
+	for {
+		select {
+		case event := <-s.events:
+			batch = append(batch, event)
+			if len(batch) >= s.batchSize {
+				s.flush(batch)
+				batch = make([]*entities.ClickEvent, 0, s.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = make([]*entities.ClickEvent, 0, s.batchSize)
+			}
+		case <-s.done:
+			// 写入缓冲区中剩余的事件
+			for {
+				select {
+				case event := <-s.events:
+					batch = append(batch, event)
+					if len(batch) >= s.batchSize {
+						s.flush(batch)
+						batch = make([]*entities.ClickEvent, 0, s.batchSize)
+					}
+				default:
+					if len(batch) > 0 {
+						s.flush(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush 写入一批点击事件并发布到统计主题
+func (s *clickService) flush(batch []*entities.ClickEvent) {
+	// 使用独立的上下文，避免受请求生命周期影响
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	if err := s.repo.BatchInsert(ctx, batch); err != nil {
+		s.logger.Printf("写入点击事件失败，丢弃%d条: %v", len(batch), err)
+		return
+	}
+
+	if s.producer == nil {
+		return
+	}
+
+	for _, event := range batch {
+		if err := s.producer.SendMessage(TopicStatsEvents, TypeShortLinkClicked, event); err != nil {
+			s.logger.Printf("发布点击事件失败: %v", err)
+		}
+	}
+}
+
+// ListBySlug 分页获取短链接的点击事件
+func (s *clickService) ListBySlug(ctx context.Context, query *entities.ClickQuery) ([]*entities.ClickEvent, int, error) {
+	s.logger.Printf("获取点击事件列表: slug=%s, 页码: %d, 每页数量: %d", query.Slug, query.Page, query.PageSize)
+	return s.repo.FindBySlug(ctx, query)
+}
+
+// Breakdown 获取短链接按各维度的点击分布
+func (s *clickService) Breakdown(ctx context.Context, merchantID uuid.UUID, slug string, from, to *time.Time) (*entities.ClickBreakdown, error) {
+	s.logger.Printf("获取点击分布统计: slug=%s", slug)
+
+	query := &entities.ClickQuery{MerchantID: merchantID, Slug: slug, From: from, To: to}
+
+	total, err := s.repo.CountBySlug(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := &entities.ClickBreakdown{
+		Slug:  slug,
+		Total: total,
+	}
+
+	dimensions := []struct {
+		name   string
+		target *[]*entities.ClickBreakdownItem
+	}{
+		{"os", &breakdown.ByOS},
+		{"browser", &breakdown.ByBrowser},
+		{"device", &breakdown.ByDevice},
+		{"country", &breakdown.ByCountry},
+		{"region", &breakdown.ByRegion},
+		{"referrer", &breakdown.ByReferrer},
+		{"day", &breakdown.ByDay},
+		{"variant", &breakdown.ByVariant},
+		{"source", &breakdown.BySource},
+	}
+
+	for _, dimension := range dimensions {
+		items, err := s.repo.GroupBy(ctx, query, dimension.name)
+		if err != nil {
+			return nil, err
+		}
+		*dimension.target = items
+	}
+
+	return breakdown, nil
+}
+
+// VariantStats 统计短链接各A/B变体的点击次数
+// 已从短链接中移除的变体如果仍有点击记录，也会出现在结果中
+func (s *clickService) VariantStats(ctx context.Context, link *entities.ShortLink) ([]*entities.VariantStats, error) {
+	s.logger.Printf("获取变体点击统计: slug=%s", link.Slug)
+
+	items, err := s.repo.GroupBy(ctx, &entities.ClickQuery{MerchantID: link.TenantID, Slug: link.Slug}, "variant")
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(items))
+	for _, item := range items {
+		counts[item.Key] = item.Count
+	}
+
+	stats := make([]*entities.VariantStats, 0, len(link.Variants))
+	for _, variant := range link.Variants {
+		stats = append(stats, &entities.VariantStats{
+			VariantID: variant.ID,
+			Name:      variant.Name,
+			TargetURL: variant.TargetURL,
+			Weight:    variant.Weight,
+			Clicks:    counts[variant.ID],
+		})
+		delete(counts, variant.ID)
+	}
+
+	for _, item := range items {
+		if _, ok := counts[item.Key]; !ok || item.Key == "unknown" {
+			continue
+		}
+		stats = append(stats, &entities.VariantStats{
+			VariantID: item.Key,
+			Clicks:    item.Count,
+		})
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,45 @@
+package clicks
+
+import (
+	"context"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// Service 点击事件服务接口
+type Service interface {
+	// Record 异步记录一次点击，不阻塞重定向请求
+	Record(link *entities.ShortLink, visit *Visit)
+	ListBySlug(ctx context.Context, query *entities.ClickQuery) ([]*entities.ClickEvent, int, error)
+	Breakdown(ctx context.Context, merchantID uuid.UUID, slug string, from, to *time.Time) (*entities.ClickBreakdown, error)
+	// VariantStats 统计短链接各A/B变体的点击次数
+	VariantStats(ctx context.Context, link *entities.ShortLink) ([]*entities.VariantStats, error)
+	Start()
+	Stop()
+}
+
+// SourceParam 标记点击来源的URL参数，二维码中的短链接带有src=qr
+const SourceParam = "src"
+
+// Visit 一次访问的请求信息
+type Visit struct {
+	UserAgent string
+	IP        string
+	Referrer  string
+	VariantID string
+	Source    entities.ClickSource
+	ClickedAt time.Time
+	// Suspicious 刷量防护判断为可疑的点击，记录但不计入统计
+	Suspicious bool
+}
+
+// SourceFromQuery 根据来源参数判断点击来源，无法识别时按碰卡计算
+func SourceFromQuery(value string) entities.ClickSource {
+	if entities.ClickSource(value) == entities.ClickSourceQR {
+		return entities.ClickSourceQR
+	}
+	return entities.ClickSourceNFC
+}
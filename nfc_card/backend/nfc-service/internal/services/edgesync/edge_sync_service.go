@@ -0,0 +1,282 @@
+package edgesync
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/storage"
+	"nfc-service/pkg/cloudflare"
+)
+
+const (
+	defaultSyncInterval      = 5 * time.Second
+	defaultReconcileInterval = 30 * time.Minute
+	outboxBatchSize          = 100
+	maxOutboxAttempts        = 10
+	outboxRetention          = 7 * 24 * time.Hour
+	syncTimeout              = time.Minute
+	reconcileTimeout         = 10 * time.Minute
+)
+
+// edgeSyncService 边缘同步服务的实现
+type edgeSyncService struct {
+	links             linkStore
+	outbox            outboxStore
+	sun               sunStore
+	kv                KVClient
+	logger            *log.Logger
+	syncInterval      time.Duration
+	reconcileInterval time.Duration
+	stopOnce          sync.Once
+	done              chan struct{}
+	wg                sync.WaitGroup
+}
+
+// NewEdgeSyncService 创建边缘同步服务
+func NewEdgeSyncService(
+	links *storage.ShortlinkRepository,
+	outbox *storage.EdgeOutboxRepository,
+	sun *storage.SUNRepository,
+	kv KVClient,
+	cfg config.CloudflareConfig,
+	logger *log.Logger,
+) Service {
+	syncInterval := time.Duration(cfg.SyncIntervalSeconds) * time.Second
+	if syncInterval <= 0 {
+		syncInterval = defaultSyncInterval
+	}
+
+	reconcileInterval := time.Duration(cfg.ReconcileIntervalMinutes) * time.Minute
+	if reconcileInterval <= 0 {
+		reconcileInterval = defaultReconcileInterval
+	}
+
+	return &edgeSyncService{
+		links:             links,
+		outbox:            outbox,
+		sun:               sun,
+		kv:                kv,
+		logger:            logger,
+		syncInterval:      syncInterval,
+		reconcileInterval: reconcileInterval,
+		done:              make(chan struct{}),
+	}
+}
+
+// EdgeTarget 计算短链接在边缘KV中应有的目标URL
+// 已停用、已过期或配置了动态跳转规则/A/B变体/深度链接的短链接不写入KV，由Worker回源到nfc-service处理
+func EdgeTarget(link *entities.ShortLink) (string, bool) {
+	if !link.Active || link.TargetURL == "" {
+		return "", false
+	}
+	if link.ExpiresAt != nil && !link.ExpiresAt.After(time.Now()) {
+		return "", false
+	}
+	if len(link.Rules) > 0 || len(link.Variants) > 0 || len(link.DeepLinks) > 0 {
+		return "", false
+	}
+	return link.TargetURL, true
+}
+
+// EnqueueLink 将短链接的最新状态写入发件箱
+// 卡片开启了SUN校验时Worker无法校验，同样从KV中移除
+func (s *edgeSyncService) EnqueueLink(ctx context.Context, link *entities.ShortLink) error {
+	if target, ok := EdgeTarget(link); ok {
+		sunEnabled, err := s.sun.IsEnabled(ctx, link.NfcCardID)
+		if err != nil {
+			return err
+		}
+		if !sunEnabled {
+			return s.outbox.Enqueue(ctx, link.Slug, entities.EdgeSyncUpsert, target)
+		}
+	}
+	return s.outbox.Enqueue(ctx, link.Slug, entities.EdgeSyncDelete, "")
+}
+
+// EnqueueDelete 将短链接的删除写入发件箱
+func (s *edgeSyncService) EnqueueDelete(ctx context.Context, slug string) error {
+	return s.outbox.Enqueue(ctx, slug, entities.EdgeSyncDelete, "")
+}
+
+// Start 启动后台同步协程
+func (s *edgeSyncService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	s.logger.Printf("边缘同步协程已启动，发件箱间隔: %s, 对账间隔: %s", s.syncInterval, s.reconcileInterval)
+}
+
+// Stop 停止后台同步协程
+func (s *edgeSyncService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+}
+
+// run 定期处理发件箱并执行全量对账
+func (s *edgeSyncService) run() {
+	defer s.wg.Done()
+
+	syncTicker := time.NewTicker(s.syncInterval)
+	defer syncTicker.Stop()
+	reconcileTicker := time.NewTicker(s.reconcileInterval)
+	defer reconcileTicker.Stop()
+
+	// 启动时先做一次全量对账
+	s.reconcileOnce()
+
+	for {
+		select {
+		case <-syncTicker.C:
+			s.drainOutbox()
+		case <-reconcileTicker.C:
+			s.reconcileOnce()
+		case <-s.done:
+			s.drainOutbox()
+			return
+		}
+	}
+}
+
+// drainOutbox 处理发件箱中所有待处理任务
+func (s *edgeSyncService) drainOutbox() {
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+
+	for {
+		processed, err := s.outbox.ProcessPending(ctx, outboxBatchSize, maxOutboxAttempts, s.applyTasks)
+		if err != nil {
+			s.logger.Printf("处理边缘同步发件箱失败: %v", err)
+			return
+		}
+		if processed < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// applyTasks 将一批发件箱任务同步到KV
+// 同一个slug只执行最后一条任务，被覆盖的任务与最终任务共享结果
+func (s *edgeSyncService) applyTasks(tasks []*entities.EdgeSyncTask) []error {
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+
+	latest := make(map[string]*entities.EdgeSyncTask, len(tasks))
+	for _, task := range tasks {
+		latest[task.Slug] = task
+	}
+
+	upserts := make(map[string]string)
+	var deletes []string
+	for slug, task := range latest {
+		if task.Action == entities.EdgeSyncUpsert {
+			upserts[slug] = task.TargetURL
+		} else {
+			deletes = append(deletes, slug)
+		}
+	}
+
+	var upsertErr, deleteErr error
+	if len(upserts) > 0 {
+		if upsertErr = s.kv.PutRedirects(ctx, upserts); upsertErr != nil {
+			s.logger.Printf("同步%d条边缘重定向失败: %v", len(upserts), upsertErr)
+		}
+	}
+	if len(deletes) > 0 {
+		if deleteErr = s.kv.BulkDelete(ctx, deletes); deleteErr != nil {
+			s.logger.Printf("删除%d条边缘重定向失败: %v", len(deletes), deleteErr)
+		}
+	}
+
+	errs := make([]error, len(tasks))
+	for i, task := range tasks {
+		if latest[task.Slug].Action == entities.EdgeSyncUpsert {
+			errs[i] = upsertErr
+		} else {
+			errs[i] = deleteErr
+		}
+	}
+	return errs
+}
+
+// reconcileOnce 执行一次全量对账并清理过期的发件箱记录
+func (s *edgeSyncService) reconcileOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+	defer cancel()
+
+	result, err := s.Reconcile(ctx)
+	if err != nil {
+		s.logger.Printf("边缘KV全量对账失败: %v", err)
+		return
+	}
+	s.logger.Printf("边缘KV全量对账完成，检查%d条，写入%d条，删除%d条", result.Checked, result.Upserts, result.Deletes)
+
+	if purged, err := s.outbox.PurgeProcessed(ctx, time.Now().Add(-outboxRetention)); err != nil {
+		s.logger.Printf("清理边缘同步发件箱失败: %v", err)
+	} else if purged > 0 {
+		s.logger.Printf("已清理%d条已处理的边缘同步任务", purged)
+	}
+}
+
+// Reconcile 对比数据库与Workers KV的全量数据并修复差异
+func (s *edgeSyncService) Reconcile(ctx context.Context) (*ReconcileResult, error) {
+	links, err := s.links.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := s.kv.ListRedirects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sunCards, err := s.sun.EnabledCardIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := make(map[string]string, len(links))
+	for _, link := range links {
+		if sunCards[link.NfcCardID] {
+			continue
+		}
+		if target, ok := EdgeTarget(link); ok {
+			desired[link.Slug] = target
+		}
+	}
+
+	upserts := make(map[string]string)
+	for slug, target := range desired {
+		if hash, ok := remote[slug]; !ok || hash != cloudflare.TargetHash(target) {
+			upserts[slug] = target
+		}
+	}
+
+	var deletes []string
+	for slug := range remote {
+		if _, ok := desired[slug]; !ok {
+			deletes = append(deletes, slug)
+		}
+	}
+
+	if len(upserts) > 0 {
+		if err := s.kv.PutRedirects(ctx, upserts); err != nil {
+			return nil, err
+		}
+	}
+	if len(deletes) > 0 {
+		if err := s.kv.BulkDelete(ctx, deletes); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ReconcileResult{
+		Checked: len(desired),
+		Upserts: len(upserts),
+		Deletes: len(deletes),
+	}, nil
+}
@@ -0,0 +1,331 @@
+package edgesync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/pkg/cloudflare"
+	"nfc-service/pkg/cloudflare/cloudflaretest"
+
+	"github.com/google/uuid"
+)
+
+// fakeLinks 内存中的短链接存储
+type fakeLinks struct {
+	links []*entities.ShortLink
+}
+
+func (f *fakeLinks) FindAll(ctx context.Context) ([]*entities.ShortLink, error) {
+	return f.links, nil
+}
+
+// fakeSUN 内存中的卡片SUN状态
+type fakeSUN struct {
+	enabled map[uuid.UUID]bool
+}
+
+func (f *fakeSUN) IsEnabled(ctx context.Context, cardID uuid.UUID) (bool, error) {
+	return f.enabled[cardID], nil
+}
+
+func (f *fakeSUN) EnabledCardIDs(ctx context.Context) (map[uuid.UUID]bool, error) {
+	return f.enabled, nil
+}
+
+// fakeOutbox 内存中的发件箱，处理规则与storage.EdgeOutboxRepository一致
+type fakeOutbox struct {
+	mu     sync.Mutex
+	nextID int64
+	tasks  []*entities.EdgeSyncTask
+}
+
+func (f *fakeOutbox) Enqueue(ctx context.Context, slug string, action entities.EdgeSyncAction, targetURL string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	f.tasks = append(f.tasks, &entities.EdgeSyncTask{
+		ID:        f.nextID,
+		Slug:      slug,
+		Action:    action,
+		TargetURL: targetURL,
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+func (f *fakeOutbox) ProcessPending(ctx context.Context, limit, maxAttempts int, handler func(tasks []*entities.EdgeSyncTask) []error) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var pending []*entities.EdgeSyncTask
+	for _, task := range f.tasks {
+		if task.ProcessedAt == nil && task.Attempts < maxAttempts && len(pending) < limit {
+			pending = append(pending, task)
+		}
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	errs := handler(pending)
+	now := time.Now()
+	for i, task := range pending {
+		task.Attempts++
+		if i < len(errs) && errs[i] != nil {
+			message := errs[i].Error()
+			task.LastError = &message
+			continue
+		}
+		task.ProcessedAt = &now
+		task.LastError = nil
+	}
+	return len(pending), nil
+}
+
+func (f *fakeOutbox) PurgeProcessed(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeOutbox) unprocessed() []*entities.EdgeSyncTask {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var tasks []*entities.EdgeSyncTask
+	for _, task := range f.tasks {
+		if task.ProcessedAt == nil {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+type testEnv struct {
+	service *edgeSyncService
+	kv      *cloudflaretest.KVServer
+	links   *fakeLinks
+	outbox  *fakeOutbox
+	sun     *fakeSUN
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	kv := cloudflaretest.NewKVServer("token", "account", "namespace")
+	t.Cleanup(kv.Close)
+
+	env := &testEnv{
+		kv:     kv,
+		links:  &fakeLinks{},
+		outbox: &fakeOutbox{},
+		sun:    &fakeSUN{enabled: map[uuid.UUID]bool{}},
+	}
+	client := cloudflare.NewWorkersClient(kv.URL, "token", "account", "namespace")
+	env.service = NewEdgeSyncService(nil, nil, nil, client, config.CloudflareConfig{}, log.New(io.Discard, "", 0)).(*edgeSyncService)
+	env.service.links = env.links
+	env.service.outbox = env.outbox
+	env.service.sun = env.sun
+	return env
+}
+
+func activeLink(slug, target string) *entities.ShortLink {
+	return &entities.ShortLink{ID: uuid.New(), NfcCardID: uuid.New(), Slug: slug, TargetURL: target, Active: true}
+}
+
+func (e *testEnv) setRemote(slug, target string) {
+	e.kv.Set(slug, target, map[string]string{"target_hash": cloudflare.TargetHash(target)})
+}
+
+func TestReconcileAddsUpdatesAndDeletes(t *testing.T) {
+	env := newTestEnv(t)
+	past := time.Now().Add(-time.Hour)
+
+	expired := activeLink("expired", "https://example.com/expired")
+	expired.ExpiresAt = &past
+	inactive := activeLink("inactive", "https://example.com/inactive")
+	inactive.Active = false
+	sunCard := activeLink("sun", "https://example.com/sun")
+	env.sun.enabled[sunCard.NfcCardID] = true
+
+	env.links.links = []*entities.ShortLink{
+		activeLink("unchanged", "https://example.com/same"),
+		activeLink("added", "https://example.com/new"),
+		activeLink("changed", "https://example.com/after"),
+		expired,
+		inactive,
+		sunCard,
+	}
+
+	env.setRemote("unchanged", "https://example.com/same")
+	env.setRemote("changed", "https://example.com/before")
+	env.setRemote("expired", "https://example.com/expired")
+	env.setRemote("inactive", "https://example.com/inactive")
+	env.setRemote("sun", "https://example.com/sun")
+	env.setRemote("orphan", "https://example.com/orphan")
+
+	result, err := env.service.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	want := ReconcileResult{Checked: 3, Upserts: 2, Deletes: 4}
+	if *result != want {
+		t.Errorf("Reconcile = %+v，期望%+v", *result, want)
+	}
+
+	if got := env.kv.Keys(); !reflect.DeepEqual(got, []string{"added", "changed", "unchanged"}) {
+		t.Errorf("KV中的键为%v", got)
+	}
+	if entry, _ := env.kv.Get("changed"); entry.Value != "https://example.com/after" {
+		t.Errorf("changed的目标为%q，期望已更新", entry.Value)
+	}
+	if entry, _ := env.kv.Get("added"); entry.Metadata["target_hash"] != cloudflare.TargetHash("https://example.com/new") {
+		t.Errorf("added的摘要为%q", entry.Metadata["target_hash"])
+	}
+
+	// 再次对账没有差异
+	result, err = env.service.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if result.Upserts != 0 || result.Deletes != 0 {
+		t.Errorf("第二次对账仍有差异: %+v", *result)
+	}
+}
+
+func TestReconcileReturnsListError(t *testing.T) {
+	env := newTestEnv(t)
+	env.links.links = []*entities.ShortLink{activeLink("a", "https://example.com/a")}
+	env.kv.FailNext(1)
+
+	if _, err := env.service.Reconcile(context.Background()); err == nil {
+		t.Fatal("列出KV键失败时应返回错误")
+	}
+	if len(env.kv.Keys()) != 0 {
+		t.Error("列出失败后不应继续写入")
+	}
+}
+
+func TestEnqueueLinkSkipsSUNCards(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	plain := activeLink("plain", "https://example.com/plain")
+	sunCard := activeLink("sun", "https://example.com/sun")
+	env.sun.enabled[sunCard.NfcCardID] = true
+
+	if err := env.service.EnqueueLink(ctx, plain); err != nil {
+		t.Fatalf("EnqueueLink: %v", err)
+	}
+	if err := env.service.EnqueueLink(ctx, sunCard); err != nil {
+		t.Fatalf("EnqueueLink: %v", err)
+	}
+
+	tasks := env.outbox.unprocessed()
+	if len(tasks) != 2 {
+		t.Fatalf("发件箱任务%d条，期望2条", len(tasks))
+	}
+	if tasks[0].Action != entities.EdgeSyncUpsert || tasks[0].TargetURL != plain.TargetURL {
+		t.Errorf("普通短链接的任务为%+v", tasks[0])
+	}
+	if tasks[1].Action != entities.EdgeSyncDelete {
+		t.Errorf("开启SUN的短链接应从KV删除，任务为%+v", tasks[1])
+	}
+}
+
+func TestDrainOutboxRetriesFailedTasks(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.setRemote("gone", "https://example.com/gone")
+
+	env.outbox.Enqueue(ctx, "a", entities.EdgeSyncUpsert, "https://example.com/a1")
+	env.outbox.Enqueue(ctx, "gone", entities.EdgeSyncDelete, "")
+	env.outbox.Enqueue(ctx, "a", entities.EdgeSyncUpsert, "https://example.com/a2")
+
+	// 写入失败，删除成功
+	env.kv.FailNext(1)
+	env.service.drainOutbox()
+
+	pending := env.outbox.unprocessed()
+	if len(pending) != 2 {
+		t.Fatalf("失败后待重试的任务%d条，期望2条", len(pending))
+	}
+	for _, task := range pending {
+		if task.Slug != "a" || task.Attempts != 1 || task.LastError == nil {
+			t.Errorf("待重试任务为%+v", task)
+		}
+	}
+	if _, ok := env.kv.Get("gone"); ok {
+		t.Error("删除任务未执行")
+	}
+
+	env.service.drainOutbox()
+
+	if pending := env.outbox.unprocessed(); len(pending) != 0 {
+		t.Fatalf("重试后仍有%d条任务未处理", len(pending))
+	}
+	// 同一slug只写入最后一条任务的目标
+	entry, ok := env.kv.Get("a")
+	if !ok || entry.Value != "https://example.com/a2" {
+		t.Errorf("a的目标为%q，期望最后一次写入的值", entry.Value)
+	}
+}
+
+func TestDrainOutboxStopsAfterMaxAttempts(t *testing.T) {
+	env := newTestEnv(t)
+	env.outbox.Enqueue(context.Background(), "a", entities.EdgeSyncUpsert, "https://example.com/a")
+
+	env.kv.FailNext(maxOutboxAttempts + 5)
+	for i := 0; i < maxOutboxAttempts+2; i++ {
+		env.service.drainOutbox()
+	}
+
+	tasks := env.outbox.unprocessed()
+	if len(tasks) != 1 || tasks[0].Attempts != maxOutboxAttempts {
+		t.Fatalf("任务为%+v，期望重试%d次后停止", tasks, maxOutboxAttempts)
+	}
+
+	var requests []string
+	for _, request := range env.kv.Requests() {
+		if request == "PUT /bulk" {
+			requests = append(requests, request)
+		}
+	}
+	if len(requests) != maxOutboxAttempts {
+		t.Errorf("写入请求%d次，期望%d次", len(requests), maxOutboxAttempts)
+	}
+}
+
+func TestApplyTasksSharesResultForSupersededTasks(t *testing.T) {
+	env := newTestEnv(t)
+	tasks := []*entities.EdgeSyncTask{
+		{ID: 1, Slug: "a", Action: entities.EdgeSyncUpsert, TargetURL: "https://example.com/a"},
+		{ID: 2, Slug: "b", Action: entities.EdgeSyncUpsert, TargetURL: "https://example.com/b"},
+		{ID: 3, Slug: "a", Action: entities.EdgeSyncDelete},
+	}
+
+	// 第一个请求是批量写入（只有b），失败
+	env.kv.FailNext(1)
+	errs := env.service.applyTasks(tasks)
+
+	var failed []int64
+	for i, err := range errs {
+		if err != nil {
+			var apiErr *cloudflare.APIError
+			if !errors.As(err, &apiErr) {
+				t.Errorf("任务%d的错误类型为%T", tasks[i].ID, err)
+			}
+			failed = append(failed, tasks[i].ID)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i] < failed[j] })
+	if !reflect.DeepEqual(failed, []int64{2}) {
+		t.Errorf("失败的任务为%v，期望只有写入b的任务失败", failed)
+	}
+}
@@ -0,0 +1,54 @@
+package edgesync
+
+import (
+	"context"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// Service 短链接边缘同步服务接口
+type Service interface {
+	// EnqueueLink 将短链接的最新状态写入发件箱，由后台协程同步到Workers KV
+	EnqueueLink(ctx context.Context, link *entities.ShortLink) error
+	// EnqueueDelete 将短链接的删除写入发件箱
+	EnqueueDelete(ctx context.Context, slug string) error
+	// Reconcile 对比数据库与Workers KV的全量数据并修复差异
+	Reconcile(ctx context.Context) (*ReconcileResult, error)
+	Start()
+	Stop()
+}
+
+// KVClient Workers KV客户端接口，由cloudflare.WorkersClient实现
+type KVClient interface {
+	PutRedirects(ctx context.Context, redirects map[string]string) error
+	BulkDelete(ctx context.Context, keys []string) error
+	ListRedirects(ctx context.Context) (map[string]string, error)
+}
+
+// linkStore 对账读取短链接的存储，由storage.ShortlinkRepository实现
+type linkStore interface {
+	FindAll(ctx context.Context) ([]*entities.ShortLink, error)
+}
+
+// outboxStore 边缘同步发件箱存储，由storage.EdgeOutboxRepository实现
+type outboxStore interface {
+	Enqueue(ctx context.Context, slug string, action entities.EdgeSyncAction, targetURL string) error
+	ProcessPending(ctx context.Context, limit, maxAttempts int, handler func(tasks []*entities.EdgeSyncTask) []error) (int, error)
+	PurgeProcessed(ctx context.Context, before time.Time) (int64, error)
+}
+
+// sunStore 卡片SUN状态存储，由storage.SUNRepository实现
+type sunStore interface {
+	IsEnabled(ctx context.Context, cardID uuid.UUID) (bool, error)
+	EnabledCardIDs(ctx context.Context) (map[uuid.UUID]bool, error)
+}
+
+// ReconcileResult 一次全量对账的结果
+type ReconcileResult struct {
+	Checked int `json:"checked"`
+	Upserts int `json:"upserts"`
+	Deletes int `json:"deletes"`
+}
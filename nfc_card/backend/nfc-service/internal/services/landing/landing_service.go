@@ -0,0 +1,509 @@
+package landing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/storage"
+	"nfc-service/pkg/cache"
+	"nfc-service/pkg/content"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TopicCardEvents 卡片事件主题
+	TopicCardEvents = "card-events"
+	// TypeLandingTemplateUpdated 商户落地页模板变更事件
+	TypeLandingTemplateUpdated = "landing_template.updated"
+
+	defaultVideoCacheTTL    = 5 * time.Minute
+	defaultTemplateCacheTTL = time.Minute
+	videoCacheSize          = 10000
+	tenantCacheSize         = 1000
+
+	// 页面缓存时间（秒），停用和过期的卡片可能被重新激活，不宜缓存太久
+	playableMaxAge = 60
+	noticeMaxAge   = 30
+
+	maxTitleLength    = 60
+	maxSubtitleLength = 200
+	maxShareLength    = 60
+	maxFooterLength   = 120
+	maxLogoURLLength  = 2048
+)
+
+var (
+	// ErrInvalidTemplate 落地页模板参数无效
+	ErrInvalidTemplate = errors.New("落地页模板参数无效")
+	// ErrCardNotFound 卡片不存在或不属于当前商户
+	ErrCardNotFound = repositories.ErrCardNotFound
+
+	colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// tenantView 商户的模板和品牌信息，按商户缓存
+type tenantView struct {
+	template *entities.LandingTemplate
+	branding *storage.MerchantBranding
+}
+
+// videoKey 视频缓存的键
+type videoKey struct {
+	merchantID uuid.UUID
+	videoID    uuid.UUID
+}
+
+// landingService 落地页服务的实现
+type landingService struct {
+	cards      *repositories.NfcCardRepository
+	links      *storage.ShortlinkRepository
+	templates  *storage.LandingTemplateRepository
+	merchants  *storage.MerchantRepository
+	videos     VideoSource
+	schedules  ScheduleResolver
+	producer   KafkaProducer
+	baseURL    string
+	views      *template.Template
+	tenants    *cache.LRU[uuid.UUID, *tenantView]
+	videoCache *cache.LRU[videoKey, *content.Video]
+	tenantTTL  time.Duration
+	videoTTL   time.Duration
+	logger     *log.Logger
+}
+
+// NewLandingService 创建落地页服务，baseURL为短链接域名，用于生成分享地址
+func NewLandingService(
+	cards *repositories.NfcCardRepository,
+	links *storage.ShortlinkRepository,
+	templates *storage.LandingTemplateRepository,
+	merchants *storage.MerchantRepository,
+	videos VideoSource,
+	schedules ScheduleResolver,
+	producer KafkaProducer,
+	cfg config.LandingConfig,
+	baseURL string,
+	logger *log.Logger,
+) (Service, error) {
+	views, err := parseTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	videoTTL := time.Duration(cfg.VideoCacheTTLSeconds) * time.Second
+	if videoTTL <= 0 {
+		videoTTL = defaultVideoCacheTTL
+	}
+	tenantTTL := time.Duration(cfg.TemplateCacheTTLSeconds) * time.Second
+	if tenantTTL <= 0 {
+		tenantTTL = defaultTemplateCacheTTL
+	}
+
+	return &landingService{
+		cards:      cards,
+		links:      links,
+		templates:  templates,
+		merchants:  merchants,
+		videos:     videos,
+		schedules:  schedules,
+		producer:   producer,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		views:      views,
+		tenants:    cache.NewLRU[uuid.UUID, *tenantView](tenantCacheSize),
+		videoCache: cache.NewLRU[videoKey, *content.Video](videoCacheSize),
+		tenantTTL:  tenantTTL,
+		videoTTL:   videoTTL,
+		logger:     logger,
+	}, nil
+}
+
+// Render 渲染卡片的落地页
+func (s *landingService) Render(ctx context.Context, uid string, videoID *uuid.UUID) (*Page, error) {
+	card, err := s.cards.FindByUID(ctx, uid)
+	if errors.Is(err, repositories.ErrCardNotFound) {
+		// 卡片更新事件生成的短链接使用卡片ID作为路径
+		if id, parseErr := uuid.Parse(uid); parseErr == nil {
+			card, err = s.cards.FindByID(ctx, id)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, repositories.ErrCardNotFound) {
+			return s.renderNotice(entities.DefaultLandingTemplate(uuid.Nil), nil, nil, noticeNotFound, http.StatusNotFound)
+		}
+		return nil, err
+	}
+
+	// 营销活动指定的视频优先于卡片的内容排期，停用和过期的卡片仍渲染提示页
+	if videoID == nil && s.schedules != nil && !unavailable(card) {
+		entry, err := s.schedules.Resolve(ctx, card.ID, time.Now())
+		if err != nil {
+			// 排期不可用时退回卡片的默认视频
+			s.logger.Printf("解析卡片排期失败: 卡片=%s, %v", card.ID, err)
+		} else if entry != nil {
+			if entry.TargetURL != "" {
+				return &Page{StatusCode: http.StatusFound, RedirectURL: entry.TargetURL}, nil
+			}
+			videoID = entry.VideoID
+		}
+	}
+
+	view := s.tenant(ctx, card.MerchantID)
+	return s.renderCard(ctx, card, videoID, view.template, view.branding)
+}
+
+// Preview 使用草稿模板渲染商户卡片的落地页
+func (s *landingService) Preview(ctx context.Context, merchantID, cardID uuid.UUID, draft *entities.UpdateLandingTemplateDTO) (*Page, error) {
+	card, err := s.cards.FindByID(ctx, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card.MerchantID != merchantID {
+		return nil, ErrCardNotFound
+	}
+
+	view := s.tenant(ctx, merchantID)
+	tmpl := view.template
+	if draft != nil {
+		if tmpl, err = buildTemplate(merchantID, draft); err != nil {
+			return nil, err
+		}
+	}
+
+	page, err := s.renderCard(ctx, card, nil, tmpl, view.branding)
+	if err != nil {
+		return nil, err
+	}
+	page.MaxAge = 0
+	return page, nil
+}
+
+// renderCard 按卡片状态和视频可用性渲染落地页，videoID为空时使用卡片的默认视频
+func (s *landingService) renderCard(ctx context.Context, card *entities.NfcCard, videoID *uuid.UUID, tmpl *entities.LandingTemplate, branding *storage.MerchantBranding) (*Page, error) {
+	switch {
+	case card.Status == entities.CardStatusDeactivated:
+		return s.renderNotice(tmpl, branding, card, noticeInactive, http.StatusGone)
+	case expired(card):
+		return s.renderNotice(tmpl, branding, card, noticeExpired, http.StatusGone)
+	}
+
+	data := s.pageData(tmpl, branding, card)
+	data.ShareURL = s.shareURL(ctx, card)
+	data.ShareTitle = data.Title
+	for _, platform := range tmpl.SharePlatforms {
+		if button, ok := shareButtons[platform]; ok {
+			data.Shares = append(data.Shares, button)
+		}
+	}
+
+	if videoID == nil {
+		videoID = card.DefaultVideoID
+	}
+	video, kind := s.video(ctx, card, videoID)
+	if video != nil {
+		data.Poster = video.CoverURL
+		if tmpl.Theme.Title == "" && video.Title != "" {
+			data.Title = video.Title
+			data.ShareTitle = video.Title
+		}
+		if data.Subtitle == "" {
+			data.Subtitle = video.Description
+		}
+	}
+	maxAge := playableMaxAge
+	if kind != "" {
+		data.Notice = notices[kind]
+		maxAge = noticeMaxAge
+	} else {
+		data.Video = video
+	}
+
+	html, err := execute(s.views, tmpl.Layout, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{StatusCode: http.StatusOK, HTML: html, MaxAge: maxAge}, nil
+}
+
+// renderNotice 渲染不显示视频和分享按钮的提示页
+func (s *landingService) renderNotice(tmpl *entities.LandingTemplate, branding *storage.MerchantBranding, card *entities.NfcCard, kind noticeKind, status int) (*Page, error) {
+	data := s.pageData(tmpl, branding, card)
+	data.Notice = notices[kind]
+	data.Title = data.Notice.Title
+	data.Subtitle = ""
+
+	html, err := execute(s.views, tmpl.Layout, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{StatusCode: status, HTML: html, MaxAge: noticeMaxAge}, nil
+}
+
+// pageData 根据模板主题和商户信息生成页面数据
+func (s *landingService) pageData(tmpl *entities.LandingTemplate, branding *storage.MerchantBranding, card *entities.NfcCard) *pageData {
+	theme := tmpl.Theme
+	data := &pageData{
+		Subtitle:  theme.Subtitle,
+		ShareText: theme.ShareText,
+		Footer:    theme.FooterText,
+		LogoURL:   theme.LogoURL,
+		Colors: themeColors{
+			Primary:    valueOr(theme.PrimaryColor, defaultPrimaryColor),
+			Background: valueOr(theme.BackgroundColor, defaultBackgroundColor),
+			Text:       valueOr(theme.TextColor, defaultTextColor),
+		},
+	}
+	if branding != nil {
+		data.MerchantName = branding.Name
+		if data.LogoURL == "" && isHTTPURL(branding.LogoURL) {
+			data.LogoURL = branding.LogoURL
+		}
+	}
+
+	var cardName string
+	if card != nil {
+		cardName = card.Name
+	}
+	data.Title = strings.NewReplacer("{name}", cardName, "{merchant}", data.MerchantName).Replace(theme.Title)
+	if data.Title == "" {
+		data.Title = valueOr(data.MerchantName, valueOr(cardName, "精彩视频"))
+	}
+	return data
+}
+
+// video 获取卡片要播放的视频，不能播放时返回对应的提示类型
+// 视频按卡片所属商户查询，不会播放其他商户的视频；content-service不可用时只记录日志，页面降级为提示而不是报错
+func (s *landingService) video(ctx context.Context, card *entities.NfcCard, videoID *uuid.UUID) (*content.Video, noticeKind) {
+	if videoID == nil {
+		return nil, noticePending
+	}
+
+	key := videoKey{merchantID: card.MerchantID, videoID: *videoID}
+	video, ok := s.videoCache.Get(key)
+	if !ok {
+		var err error
+		video, err = s.videos.GetVideo(ctx, card.MerchantID, *videoID)
+		if err != nil {
+			if errors.Is(err, content.ErrVideoNotFound) {
+				return nil, noticeRemoved
+			}
+			s.logger.Printf("获取落地页视频失败: 卡片=%s, 视频=%s, %v", card.ID, *videoID, err)
+			return nil, noticeUnavailable
+		}
+		// 转码中的视频很快会变成可播放，不缓存
+		if video.Playable() {
+			s.videoCache.Set(key, video, s.videoTTL)
+		}
+	}
+
+	if !video.Playable() {
+		if video.TranscodeStatus == content.TranscodeFailed {
+			return video, noticeUnavailable
+		}
+		return video, noticeProcessing
+	}
+	return video, ""
+}
+
+// shareURL 分享地址，优先使用卡片的默认短链接
+func (s *landingService) shareURL(ctx context.Context, card *entities.NfcCard) string {
+	links, err := s.links.FindPrimaryByNfcCardIDs(ctx, []uuid.UUID{card.ID})
+	if err != nil {
+		s.logger.Printf("获取卡片短链接失败: 卡片=%s, %v", card.ID, err)
+	} else if link, ok := links[card.ID]; ok {
+		return s.baseURL + "/" + link.Slug
+	}
+	return s.baseURL + "/nfc-landing/" + url.PathEscape(card.UID)
+}
+
+// tenant 获取商户的模板和品牌信息，读取失败时使用默认模板，保证落地页可用
+func (s *landingService) tenant(ctx context.Context, merchantID uuid.UUID) *tenantView {
+	if view, ok := s.tenants.Get(merchantID); ok {
+		return view
+	}
+
+	view := &tenantView{}
+	tmpl, err := s.templates.FindByMerchant(ctx, merchantID)
+	if err != nil {
+		s.logger.Printf("获取商户落地页模板失败，使用默认模板: 商户=%s, %v", merchantID, err)
+	}
+	if tmpl == nil {
+		tmpl = entities.DefaultLandingTemplate(merchantID)
+	}
+	view.template = tmpl
+
+	branding, err := s.merchants.FindBranding(ctx, merchantID)
+	if err != nil {
+		s.logger.Printf("获取商户信息失败: 商户=%s, %v", merchantID, err)
+	}
+	view.branding = branding
+
+	// 读取失败时不缓存，下次请求重试
+	if err == nil {
+		s.tenants.Set(merchantID, view, s.tenantTTL)
+	}
+	return view
+}
+
+// GetTemplate 获取商户的落地页模板
+func (s *landingService) GetTemplate(ctx context.Context, merchantID uuid.UUID) (*entities.LandingTemplate, error) {
+	tmpl, err := s.templates.FindByMerchant(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl == nil {
+		return entities.DefaultLandingTemplate(merchantID), nil
+	}
+	return tmpl, nil
+}
+
+// UpdateTemplate 保存商户的落地页模板
+func (s *landingService) UpdateTemplate(ctx context.Context, merchantID uuid.UUID, dto *entities.UpdateLandingTemplateDTO) (*entities.LandingTemplate, error) {
+	tmpl, err := buildTemplate(merchantID, dto)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.templates.Upsert(ctx, tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("商户 %s 更新了落地页模板: 布局=%s", merchantID, saved.Layout)
+	s.changed(merchantID)
+	return saved, nil
+}
+
+// ResetTemplate 删除商户的落地页模板
+func (s *landingService) ResetTemplate(ctx context.Context, merchantID uuid.UUID) error {
+	if err := s.templates.Delete(ctx, merchantID); err != nil {
+		return err
+	}
+	s.changed(merchantID)
+	return nil
+}
+
+// InvalidateTemplate 使商户模板的本地缓存失效
+func (s *landingService) InvalidateTemplate(merchantID uuid.UUID) {
+	s.tenants.Delete(merchantID)
+}
+
+// changed 模板变更后失效本地缓存并通知其他实例
+func (s *landingService) changed(merchantID uuid.UUID) {
+	s.InvalidateTemplate(merchantID)
+
+	if s.producer != nil {
+		event := &LandingTemplateUpdatedEvent{MerchantID: merchantID}
+		if err := s.producer.SendMessage(TopicCardEvents, TypeLandingTemplateUpdated, event); err != nil {
+			s.logger.Printf("发布落地页模板变更事件失败: %v", err)
+		}
+	}
+}
+
+// buildTemplate 校验并规范化模板参数
+func buildTemplate(merchantID uuid.UUID, dto *entities.UpdateLandingTemplateDTO) (*entities.LandingTemplate, error) {
+	if dto == nil {
+		return nil, fmt.Errorf("%w: 模板不能为空", ErrInvalidTemplate)
+	}
+
+	layout := dto.Layout
+	if layout == "" {
+		layout = entities.LandingLayoutClassic
+	}
+	if !layout.Valid() {
+		return nil, fmt.Errorf("%w: 不支持的布局: %s", ErrInvalidTemplate, layout)
+	}
+
+	theme := dto.Theme
+	theme.Title = strings.TrimSpace(theme.Title)
+	theme.Subtitle = strings.TrimSpace(theme.Subtitle)
+	theme.ShareText = strings.TrimSpace(theme.ShareText)
+	theme.FooterText = strings.TrimSpace(theme.FooterText)
+	theme.LogoURL = strings.TrimSpace(theme.LogoURL)
+
+	for name, color := range map[string]string{
+		"primaryColor":    theme.PrimaryColor,
+		"backgroundColor": theme.BackgroundColor,
+		"textColor":       theme.TextColor,
+	} {
+		if color != "" && !colorPattern.MatchString(color) {
+			return nil, fmt.Errorf("%w: %s必须是#RRGGBB格式", ErrInvalidTemplate, name)
+		}
+	}
+	for name, limit := range map[string]struct {
+		value string
+		max   int
+	}{
+		"title":      {theme.Title, maxTitleLength},
+		"subtitle":   {theme.Subtitle, maxSubtitleLength},
+		"shareText":  {theme.ShareText, maxShareLength},
+		"footerText": {theme.FooterText, maxFooterLength},
+	} {
+		if utf8.RuneCountInString(limit.value) > limit.max {
+			return nil, fmt.Errorf("%w: %s不能超过%d个字符", ErrInvalidTemplate, name, limit.max)
+		}
+	}
+	if theme.LogoURL != "" && (len(theme.LogoURL) > maxLogoURLLength || !isHTTPURL(theme.LogoURL)) {
+		return nil, fmt.Errorf("%w: logoUrl必须是http或https地址", ErrInvalidTemplate)
+	}
+
+	// 未指定分享平台时显示全部，重复的平台只保留第一个
+	platforms := dto.SharePlatforms
+	if platforms == nil {
+		platforms = entities.SharePlatformList
+	}
+	seen := make(map[entities.SharePlatform]bool, len(platforms))
+	normalized := make(entities.SharePlatforms, 0, len(platforms))
+	for _, platform := range platforms {
+		if !platform.Valid() {
+			return nil, fmt.Errorf("%w: 不支持的分享平台: %s", ErrInvalidTemplate, platform)
+		}
+		if seen[platform] {
+			continue
+		}
+		seen[platform] = true
+		normalized = append(normalized, platform)
+	}
+
+	return &entities.LandingTemplate{
+		MerchantID:     merchantID,
+		Layout:         layout,
+		Theme:          theme,
+		SharePlatforms: normalized,
+	}, nil
+}
+
+// expired 判断卡片是否过期，过期扫描尚未处理的卡片同样视为过期
+func expired(card *entities.NfcCard) bool {
+	return card.Status == entities.CardStatusExpired || card.ExpiresAt != nil && !card.ExpiresAt.After(time.Now())
+}
+
+// unavailable 判断卡片是否停用或过期
+func unavailable(card *entities.NfcCard) bool {
+	return card.Status == entities.CardStatusDeactivated || expired(card)
+}
+
+// isHTTPURL 是否为http或https的绝对地址
+func isHTTPURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// valueOr value为空时返回fallback
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,62 @@
+package landing
+
+import (
+	"context"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/pkg/content"
+
+	"github.com/google/uuid"
+)
+
+// Service NFC落地页服务接口
+// 碰卡后默认短链接跳转到/nfc-landing/<uid>，由本服务渲染卡片默认视频和一键分享按钮
+type Service interface {
+	// Render 渲染卡片的落地页，卡片不存在、停用或过期时渲染对应的提示页
+	// videoID非空时播放该视频而不是卡片的默认视频，用于营销活动期间指向活动视频
+	// videoID为空时按卡片的内容排期选择当前生效的视频或跳转地址
+	Render(ctx context.Context, uid string, videoID *uuid.UUID) (*Page, error)
+	// Preview 使用草稿模板渲染商户卡片的落地页，draft为nil时使用已保存的模板
+	Preview(ctx context.Context, merchantID, cardID uuid.UUID, draft *entities.UpdateLandingTemplateDTO) (*Page, error)
+	// GetTemplate 获取商户的落地页模板，未设置时返回默认模板
+	GetTemplate(ctx context.Context, merchantID uuid.UUID) (*entities.LandingTemplate, error)
+	// UpdateTemplate 保存商户的落地页模板
+	UpdateTemplate(ctx context.Context, merchantID uuid.UUID, dto *entities.UpdateLandingTemplateDTO) (*entities.LandingTemplate, error)
+	// ResetTemplate 删除商户的落地页模板，恢复为默认模板
+	ResetTemplate(ctx context.Context, merchantID uuid.UUID) error
+	// InvalidateTemplate 使商户模板的本地缓存失效
+	InvalidateTemplate(merchantID uuid.UUID)
+}
+
+// VideoSource 获取视频播放信息，由content-service客户端实现
+type VideoSource interface {
+	GetVideo(ctx context.Context, merchantID, videoID uuid.UUID) (*content.Video, error)
+}
+
+// ScheduleResolver 解析卡片当前生效的排期条目，由卡片内容排期服务实现
+type ScheduleResolver interface {
+	Resolve(ctx context.Context, cardID uuid.UUID, at time.Time) (*entities.ScheduleEntry, error)
+}
+
+// KafkaProducer Kafka生产者接口
+type KafkaProducer interface {
+	// SendMessage 发送消息到指定主题
+	SendMessage(topic string, messageType string, data interface{}) error
+}
+
+// Page 渲染好的落地页
+type Page struct {
+	// StatusCode 卡片不存在时为404，停用或过期时为410，其余为200
+	StatusCode int
+	HTML       []byte
+	// MaxAge 允许客户端缓存的秒数，为0时不缓存
+	MaxAge int
+	// RedirectURL 非空时不渲染页面，直接跳转到该地址（排期条目指定了跳转地址）
+	RedirectURL string
+}
+
+// LandingTemplateUpdatedEvent 商户落地页模板变更事件，通知其他实例失效模板缓存
+type LandingTemplateUpdatedEvent struct {
+	MerchantID uuid.UUID `json:"merchant_id"`
+}
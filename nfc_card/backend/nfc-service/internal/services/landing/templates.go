@@ -0,0 +1,150 @@
+package landing
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/pkg/content"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// 默认主题颜色
+const (
+	defaultPrimaryColor    = "#fe2c55"
+	defaultBackgroundColor = "#ffffff"
+	defaultTextColor       = "#1f2329"
+)
+
+// shareButton 落地页上的一个分享按钮
+type shareButton struct {
+	Platform entities.SharePlatform
+	Label    string
+	// Icon 按钮图标上的文字
+	Icon string
+	// Scheme 复制链接后唤起的App地址
+	Scheme string
+	// Hint 复制链接后的提示文字
+	Hint string
+}
+
+// shareButtons 各平台的分享按钮
+// 这些平台都不支持网页直接发起分享，点击后复制链接并唤起App，由用户在App内粘贴分享；
+// 微信内置浏览器禁止唤起其他App，微信按钮改为引导用户使用右上角菜单分享
+var shareButtons = map[entities.SharePlatform]shareButton{
+	entities.SharePlatformDouyin: {
+		Platform: entities.SharePlatformDouyin,
+		Label:    "抖音",
+		Icon:     "抖",
+		Scheme:   "snssdk1128://",
+		Hint:     "链接已复制，打开抖音粘贴即可分享",
+	},
+	entities.SharePlatformKuaishou: {
+		Platform: entities.SharePlatformKuaishou,
+		Label:    "快手",
+		Icon:     "快",
+		Scheme:   "kwai://",
+		Hint:     "链接已复制，打开快手粘贴即可分享",
+	},
+	entities.SharePlatformXiaohongshu: {
+		Platform: entities.SharePlatformXiaohongshu,
+		Label:    "小红书",
+		Icon:     "红",
+		Scheme:   "xhsdiscover://",
+		Hint:     "链接已复制，打开小红书粘贴即可分享",
+	},
+	entities.SharePlatformWeChat: {
+		Platform: entities.SharePlatformWeChat,
+		Label:    "微信",
+		Icon:     "微",
+		Scheme:   "weixin://",
+		Hint:     "链接已复制，打开微信发送给好友",
+	},
+}
+
+// noticeKind 落地页降级提示的类型
+type noticeKind string
+
+const (
+	noticeNotFound    noticeKind = "notfound"
+	noticeInactive    noticeKind = "inactive"
+	noticeExpired     noticeKind = "expired"
+	noticePending     noticeKind = "pending"
+	noticeProcessing  noticeKind = "processing"
+	noticeRemoved     noticeKind = "removed"
+	noticeUnavailable noticeKind = "unavailable"
+)
+
+// notice 替代视频显示的提示
+type notice struct {
+	Kind    noticeKind
+	Title   string
+	Message string
+}
+
+// notices 各类提示的文案
+var notices = map[noticeKind]*notice{
+	noticeNotFound:    {Kind: noticeNotFound, Title: "卡片不存在", Message: "请确认卡片是否由商户发放"},
+	noticeInactive:    {Kind: noticeInactive, Title: "卡片已停用", Message: "该卡片已被商户停用，如有疑问请联系商户"},
+	noticeExpired:     {Kind: noticeExpired, Title: "卡片已过期", Message: "该卡片已超过有效期，如有疑问请联系商户"},
+	noticePending:     {Kind: noticePending, Title: "内容准备中", Message: "商户还没有为这张卡片设置视频，请稍后再来"},
+	noticeProcessing:  {Kind: noticeProcessing, Title: "视频处理中", Message: "视频正在处理，请稍后刷新页面"},
+	noticeRemoved:     {Kind: noticeRemoved, Title: "视频已下架", Message: "该视频已被商户下架"},
+	noticeUnavailable: {Kind: noticeUnavailable, Title: "视频暂时无法播放", Message: "请稍后刷新页面重试"},
+}
+
+// themeColors 页面使用的主题颜色
+type themeColors struct {
+	Primary    string
+	Background string
+	Text       string
+}
+
+// pageData 落地页模板的数据
+type pageData struct {
+	Title        string
+	Subtitle     string
+	ShareText    string
+	Footer       string
+	MerchantName string
+	LogoURL      string
+	Colors       themeColors
+	// Video 可播放的视频，为nil时显示Notice
+	Video  *content.Video
+	Poster string
+	Notice *notice
+	// ShareURL 分享出去的地址，优先使用卡片的短链接，便于统计分享带来的访问
+	ShareURL   string
+	ShareTitle string
+	Shares     []shareButton
+}
+
+// parseTemplates 解析内置的落地页模板，每种布局对应一个同名模板
+func parseTemplates() (*template.Template, error) {
+	views, err := template.New("landing").ParseFS(templateFS, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("解析落地页模板失败: %w", err)
+	}
+	for _, layout := range entities.LandingLayouts {
+		if views.Lookup(string(layout)) == nil {
+			return nil, fmt.Errorf("缺少落地页布局模板: %s", layout)
+		}
+	}
+	return views, nil
+}
+
+// execute 按布局渲染落地页
+func execute(views *template.Template, layout entities.LandingLayout, data *pageData) ([]byte, error) {
+	if !layout.Valid() {
+		layout = entities.LandingLayoutClassic
+	}
+	var buf bytes.Buffer
+	if err := views.ExecuteTemplate(&buf, string(layout), data); err != nil {
+		return nil, fmt.Errorf("渲染落地页失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,382 @@
+package qrcodes
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/pkg/pdf"
+	"nfc-service/pkg/qr"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultColumns = 3
+	maxColumns     = 6
+
+	// PDF排版参数（点）
+	pageMargin    = 28.35 // 10mm
+	cellPadding   = 8.5   // 3mm
+	labelLineUID  = 9.0
+	labelLineText = 7.0
+	labelSpacing  = 3.0
+
+	// pdfLogoSize PDF中内嵌Logo位图的边长（像素）
+	pdfLogoSize = 256
+)
+
+// manifestHeader ZIP中清单manifest.csv的列
+var manifestHeader = []string{"card_id", "uid", "name", "url", "file", "error"}
+
+// batchItem 批量生成中的一张卡片
+type batchItem struct {
+	cardID uuid.UUID
+	card   *entities.NfcCard
+	link   *entities.ShortLink
+	url    string
+	file   string
+	err    string
+}
+
+// RenderBatch 为商户的一批卡片生成二维码
+func (s *qrCodeService) RenderBatch(ctx context.Context, merchantID uuid.UUID, cardIDs []uuid.UUID, opts *BatchOptions) (*Batch, error) {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+
+	ids := uniqueIDs(cardIDs)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: 卡片ID不能为空", ErrInvalidOptions)
+	}
+	if len(ids) > s.maxBatchSize {
+		return nil, fmt.Errorf("%w: 最多%d张", ErrTooManyCards, s.maxBatchSize)
+	}
+
+	output := opts.Output
+	if output == "" {
+		output = BatchZIP
+	}
+	if output != BatchZIP && output != BatchPDF {
+		return nil, fmt.Errorf("%w: 不支持的输出格式: %s", ErrInvalidOptions, output)
+	}
+	columns := opts.Columns
+	if columns == 0 {
+		columns = defaultColumns
+	}
+	if columns < 1 || columns > maxColumns {
+		return nil, fmt.Errorf("%w: 每行二维码数量必须在1到%d之间", ErrInvalidOptions, maxColumns)
+	}
+
+	spec, err := s.prepare(ctx, merchantID, &opts.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.loadItems(ctx, merchantID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("批量生成二维码: 商户=%s, 卡片数=%d, 输出=%s", merchantID, len(ids), output)
+
+	var batch *Batch
+	if output == BatchPDF {
+		batch, err = s.writePDF(items, spec, columns)
+	} else {
+		batch, err = s.writeZIP(items, spec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		if item.err != "" {
+			batch.Skipped = append(batch.Skipped, &SkippedCard{CardID: item.cardID, Reason: item.err})
+		}
+	}
+	if batch.Rendered == 0 {
+		return nil, ErrNothingRendered
+	}
+
+	batch.FileName = fmt.Sprintf("qrcodes-%s.%s", time.Now().Format("20060102150405"), output)
+	return batch, nil
+}
+
+// loadItems 加载卡片、短链接和SUN状态，不能生成二维码的卡片记录原因
+func (s *qrCodeService) loadItems(ctx context.Context, merchantID uuid.UUID, ids []uuid.UUID) ([]*batchItem, error) {
+	cards, err := s.cards.FindByIDs(ctx, merchantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	cardsByID := make(map[uuid.UUID]*entities.NfcCard, len(cards))
+	for _, card := range cards {
+		cardsByID[card.ID] = card
+	}
+
+	linksByCard, err := s.links.FindPrimaryByNfcCardIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	sunEnabled, err := s.sun.EnabledAmong(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*batchItem, 0, len(ids))
+	for _, id := range ids {
+		item := &batchItem{cardID: id}
+		items = append(items, item)
+
+		card, ok := cardsByID[id]
+		if !ok {
+			item.err = "卡片不存在"
+			continue
+		}
+		item.card = card
+
+		link, ok := linksByCard[id]
+		if !ok {
+			item.err = "卡片没有可用的短链接"
+			continue
+		}
+		if sunEnabled[id] {
+			item.err = ErrSUNEnabled.Error()
+			continue
+		}
+		item.link = link
+		item.url = s.qrURL(link)
+	}
+	return items, nil
+}
+
+// writeZIP 每张卡片输出一个图片文件，附带manifest.csv
+func (s *qrCodeService) writeZIP(items []*batchItem, spec *renderSpec) (*Batch, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	batch := &Batch{ContentType: "application/zip"}
+
+	usedNames := make(map[string]int, len(items))
+	for _, item := range items {
+		if item.err != "" {
+			continue
+		}
+
+		data, err := s.render(item.url, spec)
+		if err != nil {
+			item.err = err.Error()
+			continue
+		}
+
+		name := fileBaseName(item.card)
+		if n := usedNames[name]; n > 0 {
+			usedNames[name] = n + 1
+			name = fmt.Sprintf("%s-%d", name, n+1)
+		} else {
+			usedNames[name] = 1
+		}
+		item.file = name + "." + string(spec.format)
+
+		w, err := zw.Create(item.file)
+		if err != nil {
+			return nil, fmt.Errorf("写入压缩包失败: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("写入压缩包失败: %w", err)
+		}
+		batch.Rendered++
+	}
+
+	w, err := zw.Create("manifest.csv")
+	if err != nil {
+		return nil, fmt.Errorf("写入压缩包失败: %w", err)
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(manifestHeader)
+	for _, item := range items {
+		var uid, name string
+		if item.card != nil {
+			uid, name = item.card.UID, item.card.Name
+		}
+		writer.Write([]string{item.cardID.String(), uid, name, item.url, item.file, item.err})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("写入清单失败: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("写入压缩包失败: %w", err)
+	}
+	batch.Data = buf.Bytes()
+	return batch, nil
+}
+
+// writePDF 按网格排版到A4页面，二维码以矢量矩形绘制，下方标注UID、名称和短链接
+func (s *qrCodeService) writePDF(items []*batchItem, spec *renderSpec, columns int) (*Batch, error) {
+	doc := pdf.New(pdf.A4Width, pdf.A4Height)
+
+	cellWidth := (doc.Width() - 2*pageMargin) / float64(columns)
+	qrSide := cellWidth - 2*cellPadding
+	labelHeight := labelLineUID + 2*labelLineText + 3*labelSpacing
+	cellHeight := qrSide + labelHeight + 2*cellPadding
+	rows := int((doc.Height() - 2*pageMargin) / cellHeight)
+	if rows < 1 {
+		rows = 1
+	}
+	perPage := rows * columns
+
+	// Logo只嵌入一次，各页面重复引用
+	var logo *pdf.Image
+	var logoW, logoH int
+	if spec.style.Logo != nil {
+		fitted := qr.FitImage(spec.style.Logo, pdfLogoSize, pdfLogoSize)
+		logo = doc.AddImage(fitted)
+		logoW, logoH = fitted.Bounds().Dx(), fitted.Bounds().Dy()
+	}
+
+	batch := &Batch{ContentType: "application/pdf"}
+	var page *pdf.Page
+	for _, item := range items {
+		if item.err != "" {
+			continue
+		}
+
+		matrix, err := qr.Encode(item.url, spec.level, spec.quietZone, logo != nil)
+		if err != nil {
+			item.err = err.Error()
+			continue
+		}
+
+		slot := batch.Rendered % perPage
+		if slot == 0 {
+			page = doc.AddPage()
+		}
+		x := pageMargin + float64(slot%columns)*cellWidth + cellPadding
+		y := pageMargin + float64(slot/columns)*cellHeight + cellPadding
+
+		drawMatrix(page, matrix, spec.style, x, y, qrSide)
+		if logo != nil {
+			drawPDFLogo(page, matrix, spec.style, logo, logoW, logoH, x, y, qrSide)
+		}
+		drawLabels(page, item, s.displayURL(item.link), x, y+qrSide, qrSide)
+		batch.Rendered++
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("生成PDF失败: %w", err)
+	}
+	batch.Data = buf.Bytes()
+	return batch, nil
+}
+
+// drawMatrix 以矢量矩形绘制二维码，同一行连续的深色模块合并为一个矩形
+func drawMatrix(page *pdf.Page, matrix *qr.Matrix, style *qr.Style, x, y, side float64) {
+	dim := matrix.Size()
+	module := side / float64(dim)
+
+	page.SetFillColor(style.Background)
+	page.Rect(x, y, side, side)
+
+	page.SetFillColor(style.Foreground)
+	for my := 0; my < dim; my++ {
+		for mx := 0; mx < dim; {
+			if !matrix.Dark(mx, my) {
+				mx++
+				continue
+			}
+			run := 1
+			for mx+run < dim && matrix.Dark(mx+run, my) {
+				run++
+			}
+			page.Rect(x+float64(mx)*module, y+float64(my)*module, float64(run)*module, module)
+			mx += run
+		}
+	}
+}
+
+// drawPDFLogo 在二维码中心铺背景色后等比绘制Logo，四周保留一个模块的留白
+func drawPDFLogo(page *pdf.Page, matrix *qr.Matrix, style *qr.Style, logo *pdf.Image, logoW, logoH int, x, y, side float64) {
+	module := side / float64(matrix.Size())
+	offset, size := matrix.LogoArea()
+	areaX, areaY, areaSide := x+float64(offset)*module, y+float64(offset)*module, float64(size)*module
+
+	page.SetFillColor(style.Background)
+	page.Rect(areaX, areaY, areaSide, areaSide)
+
+	inner := areaSide - 2*module
+	if inner <= 0 || logoW == 0 || logoH == 0 {
+		return
+	}
+	w, h := inner, inner*float64(logoH)/float64(logoW)
+	if h > inner {
+		w, h = inner*float64(logoW)/float64(logoH), inner
+	}
+	page.DrawImage(logo, areaX+module+(inner-w)/2, areaY+module+(inner-h)/2, w, h)
+}
+
+// drawLabels 在二维码下方居中输出UID、名称和短链接地址
+func drawLabels(page *pdf.Page, item *batchItem, displayURL string, x, y, width float64) {
+	page.SetFillColor(qr.DefaultStyle(0).Foreground)
+
+	y += labelSpacing + labelLineUID
+	centerText(page, x, y, width, labelLineUID, item.card.UID)
+
+	// 中文等Helvetica无法输出的名称不打印，避免出现问号
+	if item.card.Name != "" && pdf.Encodable(item.card.Name) {
+		y += labelSpacing + labelLineText
+		centerText(page, x, y, width, labelLineText, item.card.Name)
+	}
+
+	y += labelSpacing + labelLineText
+	centerText(page, x, y, width, labelLineText, displayURL)
+}
+
+// centerText 在宽度范围内居中输出一行文字，超长时截断
+func centerText(page *pdf.Page, x, y, width, size float64, text string) {
+	runes := []rune(text)
+	for len(runes) > 1 && pdf.TextWidth(size, string(runes)) > width {
+		runes = append(runes[:len(runes)-2], '.')
+	}
+	text = string(runes)
+	page.Text(x+(width-pdf.TextWidth(size, text))/2, y, size, text)
+}
+
+// fileBaseName 以卡片UID作为文件名，去掉文件名中不安全的字符
+func fileBaseName(card *entities.NfcCard) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r == ':' || r == ' ':
+			return -1
+		default:
+			return '_'
+		}
+	}, card.UID)
+	if name == "" {
+		return card.ID.String()
+	}
+	return name
+}
+
+// uniqueIDs 去掉重复的卡片ID，保持原有顺序
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if id == uuid.Nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
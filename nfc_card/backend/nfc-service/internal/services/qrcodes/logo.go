@@ -0,0 +1,94 @@
+package qrcodes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册GIF解码器
+	_ "image/jpeg" // 注册JPEG解码器
+	_ "image/png"  // 注册PNG解码器
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"nfc-service/pkg/cache"
+)
+
+const (
+	// logoCacheSize 进程内缓存的商户Logo数量
+	logoCacheSize = 256
+	// maxLogoDimension Logo允许的最大边长（像素），防止小文件解码出超大图片
+	maxLogoDimension = 4096
+)
+
+// logoFetcher 下载并解码商户Logo，按URL缓存解码后的图片
+type logoFetcher struct {
+	client   *http.Client
+	maxBytes int64
+	cache    *cache.LRU[string, image.Image]
+	cacheTTL time.Duration
+}
+
+// newLogoFetcher 创建Logo下载器
+func newLogoFetcher(timeout time.Duration, maxBytes int64, cacheTTL time.Duration) *logoFetcher {
+	return &logoFetcher{
+		client:   &http.Client{Timeout: timeout},
+		maxBytes: maxBytes,
+		cache:    cache.NewLRU[string, image.Image](logoCacheSize),
+		cacheTTL: cacheTTL,
+	}
+}
+
+// fetch 获取Logo图片，只支持http和https地址的PNG、JPEG和GIF
+func (f *logoFetcher) fetch(ctx context.Context, logoURL string) (image.Image, error) {
+	if img, ok := f.cache.Get(logoURL); ok {
+		return img, nil
+	}
+
+	parsed, err := url.Parse(logoURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("Logo地址无效: %s", logoURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logoURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建Logo请求失败: %w", err)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载Logo失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载Logo失败，状态码: %d", resp.StatusCode)
+	}
+
+	// 多读一个字节用于判断是否超过大小限制
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取Logo失败: %w", err)
+	}
+	if int64(len(data)) > f.maxBytes {
+		return nil, fmt.Errorf("Logo超过大小限制%dKB", f.maxBytes/1024)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解码Logo失败: %w", err)
+	}
+	if config.Width > maxLogoDimension || config.Height > maxLogoDimension {
+		return nil, fmt.Errorf("Logo尺寸超过%d像素", maxLogoDimension)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解码Logo失败: %w", err)
+	}
+
+	f.cache.Set(logoURL, img, f.cacheTTL)
+	return img, nil
+}
@@ -0,0 +1,250 @@
+package qrcodes
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/services/clicks"
+	"nfc-service/internal/storage"
+	"nfc-service/pkg/qr"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSize         = 512
+	defaultMaxSize      = 2048
+	defaultMaxBatchSize = 500
+	defaultLogoMaxKB    = 1024
+	defaultLogoTimeout  = 5 * time.Second
+	defaultLogoCacheTTL = 10 * time.Minute
+)
+
+var (
+	// ErrInvalidOptions 二维码参数无效
+	ErrInvalidOptions = qr.ErrInvalidOptions
+	// ErrTooManyCards 单次批量生成的卡片数量超过上限
+	ErrTooManyCards = errors.New("单次批量生成的卡片数量超过上限")
+	// ErrLinkNotFound 短链接不存在或不属于当前商户
+	ErrLinkNotFound = errors.New("短链接不存在")
+	// ErrSUNEnabled 卡片开启了SUN校验，不带SUN数据的二维码地址会被拒绝访问
+	ErrSUNEnabled = errors.New("卡片已开启SUN校验，不能生成二维码")
+	// ErrLogoNotSet 商户未设置Logo
+	ErrLogoNotSet = errors.New("商户未设置Logo")
+	// ErrLogoUnavailable 商户Logo无法下载或解码
+	ErrLogoUnavailable = errors.New("商户Logo不可用")
+	// ErrNothingRendered 批量生成时没有任何卡片可以生成二维码
+	ErrNothingRendered = errors.New("没有可以生成二维码的卡片")
+)
+
+// renderSpec 校验后的渲染参数
+type renderSpec struct {
+	format    qr.Format
+	level     qr.Level
+	quietZone int
+	style     *qr.Style
+}
+
+// qrCodeService 二维码服务的实现
+type qrCodeService struct {
+	cards        *repositories.NfcCardRepository
+	links        *storage.ShortlinkRepository
+	sun          *storage.SUNRepository
+	merchants    *storage.MerchantRepository
+	logos        *logoFetcher
+	baseURL      string
+	defaultSize  int
+	maxSize      int
+	maxBatchSize int
+	logger       *log.Logger
+}
+
+// NewQRCodeService 创建二维码服务，baseURL为短链接域名
+func NewQRCodeService(
+	cards *repositories.NfcCardRepository,
+	links *storage.ShortlinkRepository,
+	sun *storage.SUNRepository,
+	merchants *storage.MerchantRepository,
+	cfg config.QRCodeConfig,
+	baseURL string,
+	logger *log.Logger,
+) Service {
+	s := &qrCodeService{
+		cards:        cards,
+		links:        links,
+		sun:          sun,
+		merchants:    merchants,
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		defaultSize:  cfg.DefaultSize,
+		maxSize:      cfg.MaxSize,
+		maxBatchSize: cfg.MaxBatchSize,
+		logger:       logger,
+	}
+	if s.defaultSize <= 0 {
+		s.defaultSize = defaultSize
+	}
+	if s.maxSize <= 0 {
+		s.maxSize = defaultMaxSize
+	}
+	if s.maxBatchSize <= 0 {
+		s.maxBatchSize = defaultMaxBatchSize
+	}
+
+	logoMaxKB := cfg.LogoMaxSizeKB
+	if logoMaxKB <= 0 {
+		logoMaxKB = defaultLogoMaxKB
+	}
+	logoTimeout := time.Duration(cfg.LogoTimeoutSeconds) * time.Second
+	if logoTimeout <= 0 {
+		logoTimeout = defaultLogoTimeout
+	}
+	logoCacheTTL := time.Duration(cfg.LogoCacheTTLSeconds) * time.Second
+	if logoCacheTTL <= 0 {
+		logoCacheTTL = defaultLogoCacheTTL
+	}
+	s.logos = newLogoFetcher(logoTimeout, int64(logoMaxKB)*1024, logoCacheTTL)
+
+	return s
+}
+
+// Render 生成商户短链接的二维码图片
+func (s *qrCodeService) Render(ctx context.Context, merchantID, linkID uuid.UUID, opts *Options) (*Image, error) {
+	link, err := s.links.FindByID(ctx, linkID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLinkNotFound
+		}
+		return nil, err
+	}
+	if link.TenantID != merchantID {
+		return nil, ErrLinkNotFound
+	}
+
+	if link.NfcCardID != uuid.Nil {
+		enabled, err := s.sun.IsEnabled(ctx, link.NfcCardID)
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			return nil, ErrSUNEnabled
+		}
+	}
+
+	spec, err := s.prepare(ctx, merchantID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	url := s.qrURL(link)
+	data, err := s.render(url, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		ContentType: spec.format.ContentType(),
+		Data:        data,
+		URL:         url,
+	}, nil
+}
+
+// prepare 校验参数并补全默认值，需要Logo时下载商户Logo
+func (s *qrCodeService) prepare(ctx context.Context, merchantID uuid.UUID, opts *Options) (*renderSpec, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	spec := &renderSpec{
+		format:    opts.Format,
+		level:     opts.Level,
+		quietZone: qr.DefaultQuietZone,
+		style:     qr.DefaultStyle(opts.Size),
+	}
+	if spec.format == "" {
+		spec.format = qr.FormatPNG
+	}
+	if spec.format != qr.FormatPNG && spec.format != qr.FormatSVG {
+		return nil, fmt.Errorf("%w: 不支持的图片格式: %s", ErrInvalidOptions, spec.format)
+	}
+	if spec.level == "" {
+		spec.level = qr.LevelM
+	}
+	if opts.QuietZone != nil {
+		spec.quietZone = *opts.QuietZone
+	}
+
+	if spec.style.Size == 0 {
+		spec.style.Size = s.defaultSize
+	}
+	if spec.style.Size < 0 || spec.style.Size > s.maxSize {
+		return nil, fmt.Errorf("%w: 图片边长必须在1到%d像素之间", ErrInvalidOptions, s.maxSize)
+	}
+
+	var err error
+	if spec.style.Foreground, err = parseColor(opts.Foreground, spec.style.Foreground); err != nil {
+		return nil, err
+	}
+	if spec.style.Background, err = parseColor(opts.Background, spec.style.Background); err != nil {
+		return nil, err
+	}
+
+	if opts.Logo {
+		logoURL, err := s.merchants.FindLogoURL(ctx, merchantID)
+		if err != nil {
+			return nil, err
+		}
+		if logoURL == "" {
+			return nil, ErrLogoNotSet
+		}
+		logo, err := s.logos.fetch(ctx, logoURL)
+		if err != nil {
+			s.logger.Printf("获取商户Logo失败: 商户=%s, %v", merchantID, err)
+			return nil, fmt.Errorf("%w: %v", ErrLogoUnavailable, err)
+		}
+		spec.style.Logo = logo
+	}
+
+	return spec, nil
+}
+
+// render 编码并输出单个二维码图片
+func (s *qrCodeService) render(content string, spec *renderSpec) ([]byte, error) {
+	matrix, err := qr.Encode(content, spec.level, spec.quietZone, spec.style.Logo != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := matrix.Write(&buf, spec.format, spec.style); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// qrURL 二维码中编码的地址，带来源参数用于区分扫码和碰卡
+func (s *qrCodeService) qrURL(link *entities.ShortLink) string {
+	return fmt.Sprintf("%s?%s=%s", s.displayURL(link), clicks.SourceParam, entities.ClickSourceQR)
+}
+
+// displayURL 打印在二维码下方的短链接地址
+func (s *qrCodeService) displayURL(link *entities.ShortLink) string {
+	return s.baseURL + "/" + link.Slug
+}
+
+// parseColor 解析颜色参数，为空时使用默认颜色
+func parseColor(value string, fallback color.NRGBA) (color.NRGBA, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return qr.ParseColor(value)
+}
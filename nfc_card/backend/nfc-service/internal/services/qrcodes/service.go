@@ -0,0 +1,78 @@
+package qrcodes
+
+import (
+	"context"
+
+	"nfc-service/pkg/qr"
+
+	"github.com/google/uuid"
+)
+
+// Service 短链接二维码服务接口
+// 二维码中的短链接带有src=qr参数，扫码访问在点击统计中记为二维码来源
+type Service interface {
+	// Render 生成商户短链接的二维码图片
+	Render(ctx context.Context, merchantID, linkID uuid.UUID, opts *Options) (*Image, error)
+	// RenderBatch 为商户的一批卡片生成二维码，输出ZIP压缩包或可打印的PDF，文件顺序与cardIDs一致
+	RenderBatch(ctx context.Context, merchantID uuid.UUID, cardIDs []uuid.UUID, opts *BatchOptions) (*Batch, error)
+}
+
+// Options 二维码的编码参数和外观
+type Options struct {
+	// Format 图片格式，为空时使用PNG
+	Format qr.Format
+	// Size 图片边长（像素），为0时使用配置的默认值
+	Size int
+	// Level 纠错级别，为空时使用M；带Logo时低于Q会提高到Q
+	Level qr.Level
+	// QuietZone 静区宽度（模块数），为nil时使用规范要求的4个模块
+	QuietZone *int
+	// Foreground 深色模块颜色，RRGGBB或RRGGBBAA格式，为空时为黑色
+	Foreground string
+	// Background 背景颜色，为空时为白色
+	Background string
+	// Logo 是否在中心绘制商户Logo(merchants.logo_url)
+	Logo bool
+}
+
+// BatchFormat 批量生成的输出格式
+type BatchFormat string
+
+const (
+	// BatchZIP 每张卡片一个图片文件，附带清单manifest.csv
+	BatchZIP BatchFormat = "zip"
+	// BatchPDF A4打印页，每张卡片的二维码下方标注UID和短链接
+	BatchPDF BatchFormat = "pdf"
+)
+
+// BatchOptions 批量生成参数
+type BatchOptions struct {
+	Options
+	// Output 输出格式，为空时使用ZIP
+	Output BatchFormat
+	// Columns PDF每行的二维码数量，为0时为3
+	Columns int
+}
+
+// Image 生成的二维码图片
+type Image struct {
+	ContentType string
+	Data        []byte
+	// URL 二维码中编码的地址
+	URL string
+}
+
+// SkippedCard 批量生成时跳过的卡片及原因
+type SkippedCard struct {
+	CardID uuid.UUID `json:"cardId"`
+	Reason string    `json:"reason"`
+}
+
+// Batch 批量生成的结果文件
+type Batch struct {
+	ContentType string
+	FileName    string
+	Data        []byte
+	Rendered    int
+	Skipped     []*SkippedCard
+}
@@ -0,0 +1,323 @@
+package schedules
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+)
+
+const (
+	// maxEntries 单张卡片允许的最大排期条目数量
+	maxEntries = 20
+	// defaultTimezone 排期未指定时区时使用的默认时区，与短链接跳转规则一致
+	defaultTimezone = "Asia/Shanghai"
+	// timeOfDayLayout 每日时间段的格式
+	timeOfDayLayout = "15:04"
+
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+
+	// exactCheckSpan 两个条目共同的日期范围短于一周时逐日比较实际时间段，否则按每周的时间段比较
+	exactCheckSpan = 7 * 24 * time.Hour
+)
+
+// ErrInvalidSchedule 卡片排期无效
+var ErrInvalidSchedule = errors.New("卡片排期无效")
+
+// span 半开区间[start, end)
+type span struct {
+	start, end int
+}
+
+// timeSpan 半开时间区间[start, end)
+type timeSpan struct {
+	start, end time.Time
+}
+
+// ActiveEntry 返回排期在指定时间生效的条目，按顺序取第一条，没有条目生效时返回nil
+func ActiveEntry(schedule *entities.CardSchedule, at time.Time) *entities.ScheduleEntry {
+	local := at.In(location(schedule.Timezone))
+	for i := range schedule.Entries {
+		if matches(&schedule.Entries[i], local) {
+			return &schedule.Entries[i]
+		}
+	}
+	return nil
+}
+
+// matches 判断条目在指定的本地时间是否生效
+func matches(entry *entities.ScheduleEntry, local time.Time) bool {
+	if entry.StartsAt != nil && local.Before(*entry.StartsAt) {
+		return false
+	}
+	if entry.EndsAt != nil && !local.Before(*entry.EndsAt) {
+		return false
+	}
+
+	day := int(local.Weekday())
+	if entry.TimeOfDay == nil {
+		return onDay(entry.Days, day)
+	}
+
+	start, end, err := parseWindow(entry.TimeOfDay)
+	if err != nil {
+		return false
+	}
+	minute := local.Hour()*60 + local.Minute()
+	if start < end {
+		return minute >= start && minute < end && onDay(entry.Days, day)
+	}
+	// 跨天的时间段属于开始的那天，例如周五 22:00 - 02:00 包含周六凌晨
+	if minute >= start {
+		return onDay(entry.Days, day)
+	}
+	if minute < end {
+		return onDay(entry.Days, (day+6)%7)
+	}
+	return false
+}
+
+// onDay 判断星期是否在条目的生效日期中，未设置时每天生效
+func onDay(days []int, day int) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSchedule 校验排期的时区和条目，并拒绝生效时间有重叠的条目
+func validateSchedule(schedule *entities.CardSchedule) error {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return fmt.Errorf("%w: 时区无效: %s", ErrInvalidSchedule, schedule.Timezone)
+	}
+
+	if len(schedule.Entries) > maxEntries {
+		return fmt.Errorf("%w: 条目数量不能超过%d条", ErrInvalidSchedule, maxEntries)
+	}
+
+	for i := range schedule.Entries {
+		if err := validateEntry(&schedule.Entries[i]); err != nil {
+			return fmt.Errorf("%w: 第%d条%s", ErrInvalidSchedule, i+1, err.Error())
+		}
+	}
+
+	for i := range schedule.Entries {
+		for j := i + 1; j < len(schedule.Entries); j++ {
+			if overlaps(&schedule.Entries[i], &schedule.Entries[j], loc) {
+				return fmt.Errorf("%w: 第%d条和第%d条的生效时间重叠", ErrInvalidSchedule, i+1, j+1)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEntry 校验单个排期条目
+func validateEntry(entry *entities.ScheduleEntry) error {
+	switch {
+	case entry.VideoID != nil && entry.TargetURL != "":
+		return errors.New("的视频和跳转地址只能设置一个")
+	case entry.VideoID == nil && entry.TargetURL == "":
+		return errors.New("必须设置视频或跳转地址")
+	case entry.TargetURL != "":
+		target, err := url.Parse(entry.TargetURL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return errors.New("的跳转地址无效")
+		}
+	}
+
+	if entry.StartsAt != nil && entry.EndsAt != nil && !entry.StartsAt.Before(*entry.EndsAt) {
+		return errors.New("的开始时间必须早于结束时间")
+	}
+
+	seen := make(map[int]bool, len(entry.Days))
+	for _, day := range entry.Days {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("的星期无效，应为0（周日）到6（周六）: %d", day)
+		}
+		if seen[day] {
+			return fmt.Errorf("的星期重复: %d", day)
+		}
+		seen[day] = true
+	}
+
+	if entry.TimeOfDay != nil {
+		if _, err := time.Parse(timeOfDayLayout, entry.TimeOfDay.Start); err != nil {
+			return fmt.Errorf("的每日开始时间格式无效，应为HH:MM: %s", entry.TimeOfDay.Start)
+		}
+		if _, err := time.Parse(timeOfDayLayout, entry.TimeOfDay.End); err != nil {
+			return fmt.Errorf("的每日结束时间格式无效，应为HH:MM: %s", entry.TimeOfDay.End)
+		}
+		if entry.TimeOfDay.Start == entry.TimeOfDay.End {
+			return errors.New("的每日开始时间和结束时间不能相同")
+		}
+	}
+	return nil
+}
+
+// overlaps 判断两个条目是否存在同时生效的时刻
+func overlaps(a, b *entities.ScheduleEntry, loc *time.Location) bool {
+	from := later(a.StartsAt, b.StartsAt)
+	to := earlier(a.EndsAt, b.EndsAt)
+	if from != nil && to != nil {
+		if !from.Before(*to) {
+			return false
+		}
+		if to.Sub(*from) < exactCheckSpan {
+			return overlapsBetween(a, b, *from, *to, loc)
+		}
+	}
+	// 共同的日期范围至少一周时，每周的每个时刻都会出现，按每周的时间段比较即可
+	return spansOverlap(weeklySpans(a), weeklySpans(b))
+}
+
+// weeklySpans 条目在一周内生效的分钟区间，从周日0点起算
+func weeklySpans(entry *entities.ScheduleEntry) []span {
+	start, end := 0, minutesPerDay
+	if entry.TimeOfDay != nil {
+		start, end, _ = parseWindow(entry.TimeOfDay)
+		if end <= start {
+			end += minutesPerDay
+		}
+	}
+
+	var spans []span
+	for _, day := range entryDays(entry) {
+		s, e := day*minutesPerDay+start, day*minutesPerDay+end
+		if e > minutesPerWeek {
+			// 周六跨到周日的部分回绕到一周的开头
+			spans = append(spans, span{s, minutesPerWeek}, span{0, e - minutesPerWeek})
+			continue
+		}
+		spans = append(spans, span{s, e})
+	}
+	return spans
+}
+
+// spansOverlap 判断两组区间是否有交集
+func spansOverlap(a, b []span) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.start < y.end && y.start < x.end {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// overlapsBetween 在[from, to)内逐日展开两个条目的实际生效时间并比较
+func overlapsBetween(a, b *entities.ScheduleEntry, from, to time.Time, loc *time.Location) bool {
+	first := from.In(loc)
+	// 从前一天开始展开，以包含前一天跨天到当天的时间段
+	day := time.Date(first.Year(), first.Month(), first.Day()-1, 0, 0, 0, 0, loc)
+
+	var spansA, spansB []timeSpan
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		spansA = append(spansA, daySpans(a, day, from, to)...)
+		spansB = append(spansB, daySpans(b, day, from, to)...)
+	}
+
+	for _, x := range spansA {
+		for _, y := range spansB {
+			if x.start.Before(y.end) && y.start.Before(x.end) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// daySpans 条目从day开始的那次生效时间，裁剪到[from, to)内
+func daySpans(entry *entities.ScheduleEntry, day, from, to time.Time) []timeSpan {
+	if !onDay(entry.Days, int(day.Weekday())) {
+		return nil
+	}
+
+	start, end := day, day.AddDate(0, 0, 1)
+	if entry.TimeOfDay != nil {
+		s, e, err := parseWindow(entry.TimeOfDay)
+		if err != nil {
+			return nil
+		}
+		start = day.Add(time.Duration(s) * time.Minute)
+		end = day.Add(time.Duration(e) * time.Minute)
+		if e <= s {
+			end = end.AddDate(0, 0, 1)
+		}
+	}
+
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !start.Before(end) {
+		return nil
+	}
+	return []timeSpan{{start, end}}
+}
+
+// entryDays 条目生效的星期，未设置时为每天
+func entryDays(entry *entities.ScheduleEntry) []int {
+	if len(entry.Days) > 0 {
+		return entry.Days
+	}
+	return []int{0, 1, 2, 3, 4, 5, 6}
+}
+
+// parseWindow 将每日时间段解析为当天的分钟数
+func parseWindow(window *entities.TimeOfDayWindow) (int, int, error) {
+	start, err := time.Parse(timeOfDayLayout, window.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := time.Parse(timeOfDayLayout, window.End)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
+
+// later 返回两个可选开始时间中较晚的一个，nil表示不限
+func later(a, b *time.Time) *time.Time {
+	if a == nil {
+		return b
+	}
+	if b == nil || a.After(*b) {
+		return a
+	}
+	return b
+}
+
+// earlier 返回两个可选结束时间中较早的一个，nil表示不限
+func earlier(a, b *time.Time) *time.Time {
+	if a == nil {
+		return b
+	}
+	if b == nil || a.Before(*b) {
+		return a
+	}
+	return b
+}
+
+// location 获取排期使用的时区，加载失败时回退到东八区
+func location(name string) *time.Location {
+	if name == "" {
+		name = defaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.FixedZone("CST", 8*60*60)
+	}
+	return loc
+}
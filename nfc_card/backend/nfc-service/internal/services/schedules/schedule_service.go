@@ -0,0 +1,213 @@
+package schedules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/storage"
+	"nfc-service/pkg/cache"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TopicCardEvents 卡片事件主题
+	TopicCardEvents = "card-events"
+	// TypeCardScheduleUpdated 卡片排期变更事件
+	TypeCardScheduleUpdated = "card_schedule.updated"
+
+	// scheduleCacheTTL 排期缓存时间，其他实例的修改最迟在此时间后生效
+	scheduleCacheTTL  = time.Minute
+	scheduleCacheSize = 10000
+)
+
+var (
+	// ErrScheduleNotFound 卡片没有排期
+	ErrScheduleNotFound = errors.New("卡片排期不存在")
+	// ErrCardNotFound 卡片不存在或不属于当前商户
+	ErrCardNotFound = repositories.ErrCardNotFound
+)
+
+// scheduleService 卡片内容排期服务的实现
+type scheduleService struct {
+	repo     *storage.ScheduleRepository
+	cards    *repositories.NfcCardRepository
+	producer KafkaProducer
+	// schedules 按卡片缓存排期，没有排期的卡片缓存nil，避免每次碰卡都查询数据库
+	schedules *cache.LRU[uuid.UUID, *entities.CardSchedule]
+	logger    *log.Logger
+}
+
+// NewScheduleService 创建卡片内容排期服务，producer为nil时不发布排期变更事件
+func NewScheduleService(
+	repo *storage.ScheduleRepository,
+	cards *repositories.NfcCardRepository,
+	producer KafkaProducer,
+	logger *log.Logger,
+) Service {
+	return &scheduleService{
+		repo:      repo,
+		cards:     cards,
+		producer:  producer,
+		schedules: cache.NewLRU[uuid.UUID, *entities.CardSchedule](scheduleCacheSize),
+		logger:    logger,
+	}
+}
+
+// Get 获取卡片的排期
+func (s *scheduleService) Get(ctx context.Context, merchantID, cardID uuid.UUID) (*entities.CardSchedule, error) {
+	if err := s.ownCard(ctx, merchantID, cardID); err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.repo.FindByCard(ctx, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, ErrScheduleNotFound
+	}
+	return schedule, nil
+}
+
+// Put 整体替换卡片的排期
+func (s *scheduleService) Put(ctx context.Context, merchantID, cardID uuid.UUID, dto *entities.UpdateCardScheduleDTO) (*entities.CardSchedule, error) {
+	if err := s.ownCard(ctx, merchantID, cardID); err != nil {
+		return nil, err
+	}
+
+	schedule := &entities.CardSchedule{
+		NfcCardID:  cardID,
+		MerchantID: merchantID,
+		Timezone:   strings.TrimSpace(dto.Timezone),
+		Entries:    dto.Entries,
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = defaultTimezone
+	}
+	if schedule.Entries == nil {
+		schedule.Entries = entities.ScheduleEntries{}
+	}
+	for i := range schedule.Entries {
+		schedule.Entries[i].Name = strings.TrimSpace(schedule.Entries[i].Name)
+		schedule.Entries[i].TargetURL = strings.TrimSpace(schedule.Entries[i].TargetURL)
+	}
+	if err := validateSchedule(schedule); err != nil {
+		return nil, err
+	}
+	if err := s.checkVideos(ctx, merchantID, schedule.Entries); err != nil {
+		return nil, err
+	}
+
+	saved, err := s.repo.Upsert(ctx, schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	s.changed(saved.NfcCardID, merchantID)
+	s.logger.Printf("商户 %s 更新了卡片 %s 的排期，条目数: %d", merchantID, cardID, len(saved.Entries))
+	return saved, nil
+}
+
+// Delete 删除卡片的排期
+func (s *scheduleService) Delete(ctx context.Context, merchantID, cardID uuid.UUID) error {
+	if err := s.ownCard(ctx, merchantID, cardID); err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.Delete(ctx, cardID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrScheduleNotFound
+	}
+
+	s.changed(cardID, merchantID)
+	s.logger.Printf("商户 %s 删除了卡片 %s 的排期", merchantID, cardID)
+	return nil
+}
+
+// Preview 返回卡片排期在指定时间生效的条目
+func (s *scheduleService) Preview(ctx context.Context, merchantID, cardID uuid.UUID, at time.Time) (*entities.ScheduleEntry, error) {
+	schedule, err := s.Get(ctx, merchantID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	return ActiveEntry(schedule, at), nil
+}
+
+// Resolve 返回卡片在指定时间生效的条目
+func (s *scheduleService) Resolve(ctx context.Context, cardID uuid.UUID, at time.Time) (*entities.ScheduleEntry, error) {
+	schedule, ok := s.schedules.Get(cardID)
+	if !ok {
+		var err error
+		schedule, err = s.repo.FindByCard(ctx, cardID)
+		if err != nil {
+			return nil, err
+		}
+		s.schedules.Set(cardID, schedule, scheduleCacheTTL)
+	}
+
+	if schedule == nil {
+		return nil, nil
+	}
+	return ActiveEntry(schedule, at), nil
+}
+
+// InvalidateCard 使卡片排期的本地缓存失效
+func (s *scheduleService) InvalidateCard(cardID uuid.UUID) {
+	s.schedules.Delete(cardID)
+}
+
+// ownCard 检查卡片属于该商户
+func (s *scheduleService) ownCard(ctx context.Context, merchantID, cardID uuid.UUID) error {
+	card, err := s.cards.FindByID(ctx, cardID)
+	if err != nil {
+		return err
+	}
+	if card.MerchantID != merchantID {
+		return ErrCardNotFound
+	}
+	return nil
+}
+
+// checkVideos 检查条目引用的视频都属于该商户
+func (s *scheduleService) checkVideos(ctx context.Context, merchantID uuid.UUID, entries entities.ScheduleEntries) error {
+	var ids []uuid.UUID
+	for _, entry := range entries {
+		if entry.VideoID != nil {
+			ids = append(ids, *entry.VideoID)
+		}
+	}
+
+	owned, err := s.repo.MerchantVideoIDs(ctx, merchantID, ids)
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		if entry.VideoID != nil && !owned[*entry.VideoID] {
+			return fmt.Errorf("%w: 第%d条的视频不存在: %s", ErrInvalidSchedule, i+1, entry.VideoID)
+		}
+	}
+	return nil
+}
+
+// changed 失效本地缓存并通知其他实例
+func (s *scheduleService) changed(cardID, merchantID uuid.UUID) {
+	s.InvalidateCard(cardID)
+	if s.producer == nil {
+		return
+	}
+
+	event := CardScheduleUpdatedEvent{ID: cardID, MerchantID: merchantID}
+	if err := s.producer.SendMessage(TopicCardEvents, TypeCardScheduleUpdated, event); err != nil {
+		s.logger.Printf("发送卡片排期变更事件失败: %v", err)
+	}
+}
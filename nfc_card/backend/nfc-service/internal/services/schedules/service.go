@@ -0,0 +1,39 @@
+package schedules
+
+import (
+	"context"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// Service 卡片内容排期服务接口
+// 排期在落地页解析：碰卡时按当前时间选择生效的条目，播放条目的视频或跳转到条目的地址
+type Service interface {
+	// Get 获取卡片的排期
+	Get(ctx context.Context, merchantID, cardID uuid.UUID) (*entities.CardSchedule, error)
+	// Put 整体替换卡片的排期，条目的生效时间有重叠时返回ErrInvalidSchedule
+	Put(ctx context.Context, merchantID, cardID uuid.UUID, dto *entities.UpdateCardScheduleDTO) (*entities.CardSchedule, error)
+	// Delete 删除卡片的排期，卡片恢复为始终使用默认视频
+	Delete(ctx context.Context, merchantID, cardID uuid.UUID) error
+	// Preview 返回卡片排期在指定时间生效的条目，没有条目生效时返回nil
+	Preview(ctx context.Context, merchantID, cardID uuid.UUID, at time.Time) (*entities.ScheduleEntry, error)
+	// Resolve 返回卡片在指定时间生效的条目，排期按卡片缓存；没有排期或没有条目生效时返回nil
+	Resolve(ctx context.Context, cardID uuid.UUID, at time.Time) (*entities.ScheduleEntry, error)
+	// InvalidateCard 使卡片排期的本地缓存失效
+	InvalidateCard(cardID uuid.UUID)
+}
+
+// KafkaProducer Kafka生产者接口
+type KafkaProducer interface {
+	// SendMessage 发送消息到指定主题
+	SendMessage(topic string, messageType string, data interface{}) error
+}
+
+// CardScheduleUpdatedEvent 卡片排期变更事件，通知其他实例失效排期缓存
+type CardScheduleUpdatedEvent struct {
+	ID         uuid.UUID `json:"id"`
+	MerchantID uuid.UUID `json:"merchant_id"`
+}
@@ -0,0 +1,106 @@
+package shortlinks
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"nfc-service/internal/storage"
+)
+
+const (
+	defaultClickFlushInterval = 5 * time.Second
+	clickFlushTimeout         = 10 * time.Second
+)
+
+// clickCounter 在内存中累积短链接的点击次数并定期批量写入数据库
+// 写入使用独立的上下文，不受请求生命周期影响
+type clickCounter struct {
+	repo     *storage.ShortlinkRepository
+	logger   *log.Logger
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]int
+
+	stopOnce sync.Once
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// newClickCounter 创建点击计数器
+func newClickCounter(repo *storage.ShortlinkRepository, interval time.Duration, logger *log.Logger) *clickCounter {
+	if interval <= 0 {
+		interval = defaultClickFlushInterval
+	}
+
+	return &clickCounter{
+		repo:     repo,
+		logger:   logger,
+		interval: interval,
+		pending:  make(map[string]int),
+		done:     make(chan struct{}),
+	}
+}
+
+// add 累加一次点击
+func (c *clickCounter) add(slug string) {
+	c.mu.Lock()
+	c.pending[slug]++
+	c.mu.Unlock()
+}
+
+// start 启动后台刷新协程
+func (c *clickCounter) start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.flush()
+			case <-c.done:
+				c.flush()
+				return
+			}
+		}
+	}()
+}
+
+// stop 停止后台协程并写入剩余的点击次数
+func (c *clickCounter) stop() {
+	c.stopOnce.Do(func() {
+		close(c.done)
+	})
+	c.wg.Wait()
+}
+
+// flush 将累积的点击次数批量写入数据库，失败时放回缓冲区等待下次写入
+func (c *clickCounter) flush() {
+	c.mu.Lock()
+	if len(c.pending) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	batch := c.pending
+	c.pending = make(map[string]int, len(batch))
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), clickFlushTimeout)
+	defer cancel()
+
+	if err := c.repo.IncrementClicksBatch(ctx, batch); err != nil {
+		c.logger.Printf("批量写入点击次数失败，将在下次重试: %v", err)
+
+		c.mu.Lock()
+		for slug, count := range batch {
+			c.pending[slug] += count
+		}
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,236 @@
+package shortlinks
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/pkg/deeplink"
+	"nfc-service/pkg/useragent"
+
+	"github.com/google/uuid"
+)
+
+//go:embed templates/deeplink.html
+var deepLinkFS embed.FS
+
+// deepLinkPage 深度链接中间页模板
+var deepLinkPage = template.Must(template.ParseFS(deepLinkFS, "templates/deeplink.html"))
+
+// maxDeepLinks 单个短链接允许的最大深度链接数量
+const maxDeepLinks = 10
+
+// ErrInvalidDeepLink 深度链接无效
+var ErrInvalidDeepLink = errors.New("深度链接无效")
+
+// DeepLinkAction 移动端访问配置了深度链接的短链接时的处理方式
+type DeepLinkAction string
+
+const (
+	// DeepLinkOpenApp 已在该平台App的内置浏览器中，直接跳转App链接
+	DeepLinkOpenApp DeepLinkAction = "open_app"
+	// DeepLinkInterstitial 返回中间页，先尝试唤起App，超时后回退到网页
+	DeepLinkInterstitial DeepLinkAction = "interstitial"
+	// DeepLinkGuide 当前App内置浏览器（如微信）禁止唤起其他App，引导用户在系统浏览器中打开
+	DeepLinkGuide DeepLinkAction = "guide"
+)
+
+// DeepLinkPlan 本次访问打开原生App的方式
+type DeepLinkPlan struct {
+	Action   DeepLinkAction
+	Platform string
+	// AppURL 自动尝试唤起的链接，Android上为intent链接
+	AppURL string
+	// ButtonURL "打开App"按钮的链接，iOS配置了通用链接时使用通用链接
+	ButtonURL string
+	// FallbackURL App未安装或无法唤起时打开的网页
+	FallbackURL string
+}
+
+// deepLinkPageData 中间页模板的数据
+type deepLinkPageData struct {
+	Guide         bool
+	PlatformName  string
+	AppURL        string
+	ButtonURL     template.URL // 已通过ValidateAppURL校验的自定义scheme，避免被模板替换为#ZgotmplZ
+	FallbackURL   string
+	TimeoutMillis int64
+}
+
+// prepareDeepLinks 补全深度链接的来源并校验，每个平台最多一条
+func prepareDeepLinks(links entities.DeepLinks) error {
+	if len(links) > maxDeepLinks {
+		return fmt.Errorf("%w: 深度链接数量不能超过%d个", ErrInvalidDeepLink, maxDeepLinks)
+	}
+
+	platforms := make(map[string]bool, len(links))
+	for i := range links {
+		link := &links[i]
+		if link.Platform == "" {
+			return fmt.Errorf("%w: 平台不能为空", ErrInvalidDeepLink)
+		}
+		if platforms[link.Platform] {
+			return fmt.Errorf("%w: 平台重复: %s", ErrInvalidDeepLink, link.Platform)
+		}
+		platforms[link.Platform] = true
+
+		if err := deeplink.ValidateAppURL(link.AppURL); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidDeepLink, err)
+		}
+		if link.UniversalURL != "" && (!isWebURL(link.UniversalURL) || !strings.HasPrefix(link.UniversalURL, "https://")) {
+			return fmt.Errorf("%w: 通用链接必须是HTTPS地址", ErrInvalidDeepLink)
+		}
+		if link.WebURL != "" && !isWebURL(link.WebURL) {
+			return fmt.Errorf("%w: 回退网页必须是HTTP或HTTPS地址", ErrInvalidDeepLink)
+		}
+		if link.Source == "" {
+			link.Source = entities.DeepLinkSourceManual
+		}
+	}
+	return nil
+}
+
+// isWebURL 判断是否为HTTP或HTTPS地址
+func isWebURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// planDeepLink 根据访问者的设备和浏览器决定如何打开深度链接
+// 桌面端、爬虫和未配置深度链接时返回nil，按原有方式直接重定向到targetURL
+func planDeepLink(links entities.DeepLinks, targetURL string, ua useragent.Info) *DeepLinkPlan {
+	if len(links) == 0 || ua.IsBot || ua.IsHeadless || ua.DeviceType == useragent.DeviceDesktop {
+		return nil
+	}
+
+	// 在某个平台App的内置浏览器中访问，且配置了该平台的深度链接，App自身总能处理自己的scheme
+	if ua.IsInAppBrowser() {
+		if link := links.Find(deeplink.PlatformForBrowser(ua.Browser)); link != nil {
+			return &DeepLinkPlan{Action: DeepLinkOpenApp, Platform: link.Platform, AppURL: link.AppURL}
+		}
+	}
+
+	link := &links[0]
+	fallback := link.WebURL
+	if fallback == "" {
+		fallback = targetURL
+	}
+	// 回退地址会写入页面脚本，只接受网页地址
+	if !isWebURL(fallback) {
+		return nil
+	}
+
+	// 微信、QQ等内置浏览器禁止唤起其他App，只能引导用户到系统浏览器中打开
+	if ua.IsInAppBrowser() {
+		return &DeepLinkPlan{Action: DeepLinkGuide, Platform: link.Platform, FallbackURL: fallback}
+	}
+
+	plan := &DeepLinkPlan{
+		Action:      DeepLinkInterstitial,
+		Platform:    link.Platform,
+		AppURL:      link.AppURL,
+		ButtonURL:   link.AppURL,
+		FallbackURL: fallback,
+	}
+	switch ua.OS {
+	case useragent.OSiOS:
+		// 通用链接只有用户点击时才会唤起App，用于按钮；自动跳转仍使用自定义scheme
+		if link.UniversalURL != "" {
+			plan.ButtonURL = link.UniversalURL
+		}
+	case useragent.OSAndroid:
+		// Chrome不允许页面脚本直接跳转自定义scheme，使用intent链接并由浏览器处理回退
+		if link.AndroidPackage != "" {
+			if intent, err := deeplink.IntentURL(link.AppURL, link.AndroidPackage, fallback); err == nil {
+				plan.AppURL = intent
+				plan.ButtonURL = intent
+			}
+		}
+	}
+	return plan
+}
+
+// RenderDeepLinkPage 渲染唤起App的中间页或引导页
+func (s *ShortlinkService) RenderDeepLinkPage(plan *DeepLinkPlan) ([]byte, error) {
+	data := &deepLinkPageData{
+		Guide:         plan.Action == DeepLinkGuide,
+		PlatformName:  deeplink.DisplayName(plan.Platform),
+		AppURL:        plan.AppURL,
+		ButtonURL:     template.URL(plan.ButtonURL),
+		FallbackURL:   plan.FallbackURL,
+		TimeoutMillis: s.deepLinkTimeout.Milliseconds(),
+	}
+
+	var buf bytes.Buffer
+	if err := deepLinkPage.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("渲染深度链接页面失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ApplyPublishedContent 发布任务完成后为卡片的默认短链接生成该平台的深度链接，返回更新的短链接数量
+// 商户手动配置过该平台的深度链接时保留手动配置
+func (s *ShortlinkService) ApplyPublishedContent(ctx context.Context, cardID, jobID uuid.UUID, platform, contentID, webURL string) (int, error) {
+	built, ok := deeplink.Build(platform, contentID, webURL)
+	if !ok {
+		return 0, nil
+	}
+	if built.WebURL != "" && !isWebURL(built.WebURL) {
+		built.WebURL = ""
+	}
+
+	links, err := s.repo.FindByNfcCardID(ctx, cardID)
+	if err != nil {
+		return 0, err
+	}
+
+	var targets []*entities.ShortLink
+	for _, link := range links {
+		if link.IsDefault {
+			targets = append(targets, link)
+		}
+	}
+	if len(targets) == 0 && len(links) > 0 {
+		targets = links[:1]
+	}
+
+	entry := entities.DeepLink{
+		Platform:       built.Platform,
+		AppURL:         built.AppURL,
+		AndroidPackage: built.AndroidPackage,
+		WebURL:         built.WebURL,
+		ContentID:      contentID,
+		Source:         entities.DeepLinkSourcePublishJob,
+		PublishJobID:   &jobID,
+	}
+
+	updated := 0
+	for _, link := range targets {
+		deepLinks := append(entities.DeepLinks{}, link.DeepLinks...)
+		if existing := deepLinks.Find(platform); existing != nil {
+			if existing.Source != entities.DeepLinkSourcePublishJob {
+				continue
+			}
+			*existing = entry
+		} else {
+			if len(deepLinks) >= maxDeepLinks {
+				continue
+			}
+			deepLinks = append(deepLinks, entry)
+		}
+
+		result, err := s.repo.Update(ctx, link.ID, &entities.UpdateShortLinkDTO{DeepLinks: &deepLinks})
+		if err != nil {
+			return updated, err
+		}
+		s.onLinkChanged(ctx, result)
+		updated++
+	}
+	return updated, nil
+}
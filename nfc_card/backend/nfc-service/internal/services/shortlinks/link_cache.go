@@ -0,0 +1,75 @@
+package shortlinks
+
+import (
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/pkg/cache"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultCacheSize        = 10000
+	defaultCacheTTL         = time.Minute
+	defaultNegativeCacheTTL = 10 * time.Second
+)
+
+// linkCache slug到短链接的进程内缓存
+// 值为nil表示该slug不存在（负缓存），使用更短的过期时间，避免刚创建的短链接长时间不可用
+type linkCache struct {
+	lru         *cache.LRU[string, *entities.ShortLink]
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+// newLinkCache 创建短链接缓存，参数小于等于0时使用默认值
+func newLinkCache(size int, ttl, negativeTTL time.Duration) *linkCache {
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	if negativeTTL <= 0 {
+		negativeTTL = defaultNegativeCacheTTL
+	}
+
+	return &linkCache{
+		lru:         cache.NewLRU[string, *entities.ShortLink](size),
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// get 获取缓存的短链接，found为false表示未命中；命中时link为nil表示短链接不存在
+func (c *linkCache) get(slug string) (link *entities.ShortLink, found bool) {
+	return c.lru.Get(slug)
+}
+
+// set 缓存短链接
+func (c *linkCache) set(slug string, link *entities.ShortLink) {
+	c.lru.Set(slug, link, c.ttl)
+}
+
+// setMissing 缓存不存在的slug
+func (c *linkCache) setMissing(slug string) {
+	c.lru.Set(slug, nil, c.negativeTTL)
+}
+
+// invalidate 使指定slug的缓存失效
+func (c *linkCache) invalidate(slug string) {
+	c.lru.Delete(slug)
+}
+
+// invalidateCard 使指定卡片下所有短链接的缓存失效
+func (c *linkCache) invalidateCard(cardID uuid.UUID) int {
+	return c.lru.DeleteFunc(func(_ string, link *entities.ShortLink) bool {
+		return link != nil && link.NfcCardID == cardID
+	})
+}
+
+// stats 返回缓存命中统计
+func (c *linkCache) stats() cache.Stats {
+	return c.lru.Stats()
+}
@@ -0,0 +1,143 @@
+package shortlinks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+const benchSlugCount = 10000
+
+func benchSlugs() []string {
+	slugs := make([]string, benchSlugCount)
+	for i := range slugs {
+		slugs[i] = fmt.Sprintf("slug%05d", i)
+	}
+	return slugs
+}
+
+func benchLink(slug string) *entities.ShortLink {
+	return &entities.ShortLink{
+		ID:        uuid.New(),
+		TenantID:  uuid.New(),
+		NfcCardID: uuid.New(),
+		Slug:      slug,
+		TargetURL: "https://example.com/" + slug,
+		Active:    true,
+	}
+}
+
+func TestLinkCacheNegativeEntry(t *testing.T) {
+	c := newLinkCache(10, time.Minute, time.Minute)
+	c.setMissing("gone")
+
+	link, found := c.get("gone")
+	if !found || link != nil {
+		t.Fatalf("负缓存 get = (%v, %v)，期望(nil, true)", link, found)
+	}
+
+	c.invalidate("gone")
+	if _, found := c.get("gone"); found {
+		t.Error("失效后仍然命中")
+	}
+}
+
+func TestLinkCacheInvalidateCard(t *testing.T) {
+	c := newLinkCache(10, time.Minute, time.Minute)
+	a, b := benchLink("a"), benchLink("b")
+	b.NfcCardID = a.NfcCardID
+	other := benchLink("other")
+	c.set(a.Slug, a)
+	c.set(b.Slug, b)
+	c.set(other.Slug, other)
+	c.setMissing("missing")
+
+	if removed := c.invalidateCard(a.NfcCardID); removed != 2 {
+		t.Errorf("失效%d条，期望2条", removed)
+	}
+	if _, found := c.get("other"); !found {
+		t.Error("其他卡片的短链接不应失效")
+	}
+	if _, found := c.get("missing"); !found {
+		t.Error("负缓存不应失效")
+	}
+}
+
+func BenchmarkLinkCacheGet(b *testing.B) {
+	slugs := benchSlugs()
+	c := newLinkCache(benchSlugCount, time.Hour, time.Hour)
+	for _, slug := range slugs {
+		c.set(slug, benchLink(slug))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, found := c.get(slugs[i%len(slugs)]); !found {
+			b.Fatal("缓存未命中")
+		}
+	}
+}
+
+func BenchmarkLinkCacheGetParallel(b *testing.B) {
+	slugs := benchSlugs()
+	c := newLinkCache(benchSlugCount, time.Hour, time.Hour)
+	for _, slug := range slugs {
+		c.set(slug, benchLink(slug))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.get(slugs[i%len(slugs)])
+			i++
+		}
+	})
+}
+
+func BenchmarkLinkCacheGetMissing(b *testing.B) {
+	c := newLinkCache(benchSlugCount, time.Hour, time.Hour)
+	c.setMissing("missing")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.get("missing")
+	}
+}
+
+// BenchmarkGetBySlugCached 命中缓存时的短链接查询，不访问数据库
+func BenchmarkGetBySlugCached(b *testing.B) {
+	service := NewShortlinkService(nil, nil, nil, nil, nil, nil, nil, config.ShortLinkConfig{
+		CacheSize:       benchSlugCount,
+		CacheTTLSeconds: 3600,
+	}, log.New(io.Discard, "", 0))
+
+	slugs := benchSlugs()
+	for _, slug := range slugs {
+		service.cache.set(slug, benchLink(slug))
+	}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, err := service.GetBySlug(ctx, slugs[i%len(slugs)]); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package shortlinks
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/pkg/content"
+	"nfc-service/pkg/useragent"
+
+	"github.com/google/uuid"
+)
+
+//go:embed templates/preview.html
+var previewFS embed.FS
+
+// previewPage 分享预览页模板，只包含OpenGraph、Twitter和微信读取的元信息
+var previewPage = template.Must(template.ParseFS(previewFS, "templates/preview.html"))
+
+const (
+	// defaultPreviewRefresh 自动填充的预览多久后重新从视频获取
+	defaultPreviewRefresh = time.Hour
+	// maxPreviewDescription 自动填充时视频简介保留的最大字符数
+	maxPreviewDescription = 200
+	// defaultPreviewTitle 短链接和视频都没有标题时的预览标题
+	defaultPreviewTitle = "精彩视频"
+)
+
+// previewPageData 分享预览页模板的数据
+type previewPageData struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+}
+
+// IsPreviewRequest 判断是否为爬虫或聊天软件抓取链接预览的请求，这些请求返回预览页而不是跳转
+func IsPreviewRequest(userAgent string) bool {
+	return useragent.Parse(userAgent).IsBot
+}
+
+// Preview 返回短链接的分享预览，商户未设置的字段从卡片默认视频自动填充
+// 自动填充的值超过刷新间隔后重新获取，卡片更换默认视频后在下次刷新时生效；content-service不可用时沿用已保存的值
+func (s *ShortlinkService) Preview(ctx context.Context, link *entities.ShortLink) *entities.LinkPreview {
+	preview := link.Preview
+	if auto := s.autoPreview(ctx, link); auto != nil {
+		preview.Auto = auto
+	}
+	return &preview
+}
+
+// RenderPreviewPage 渲染爬虫和聊天软件看到的分享预览页，shortURL为短链接的完整地址
+// 页面不包含跳转目标，开启SUN校验的卡片也不会因此泄露目标地址
+func (s *ShortlinkService) RenderPreviewPage(ctx context.Context, link *entities.ShortLink, shortURL string) ([]byte, error) {
+	title, description, imageURL := s.Preview(ctx, link).Effective()
+	if title == "" {
+		title = link.Title
+	}
+	if title == "" {
+		title = defaultPreviewTitle
+	}
+	if !isWebURL(imageURL) {
+		imageURL = ""
+	}
+
+	data := &previewPageData{
+		URL:         shortURL,
+		Title:       title,
+		Description: description,
+		ImageURL:    imageURL,
+	}
+
+	var buf bytes.Buffer
+	if err := previewPage.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("渲染分享预览页面失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// autoPreview 需要时从卡片默认视频重新填充预览并保存，返回nil表示沿用已保存的值
+func (s *ShortlinkService) autoPreview(ctx context.Context, link *entities.ShortLink) *entities.AutoLinkPreview {
+	if s.cards == nil || s.videos == nil || link.NfcCardID == uuid.Nil || link.Preview.Customized() {
+		return nil
+	}
+	if auto := link.Preview.Auto; auto != nil && time.Since(auto.FilledAt) < s.previewRefresh {
+		return nil
+	}
+
+	card, err := s.cards.FindByID(ctx, link.NfcCardID)
+	if err != nil {
+		s.logger.Printf("获取短链接 %s 的卡片失败，沿用已保存的分享预览: %v", link.Slug, err)
+		return nil
+	}
+
+	auto := &entities.AutoLinkPreview{FilledAt: time.Now()}
+	if card.DefaultVideoID != nil && card.MerchantID == link.TenantID {
+		video, err := s.videos.GetVideo(ctx, card.MerchantID, *card.DefaultVideoID)
+		switch {
+		case errors.Is(err, content.ErrVideoNotFound):
+		case err != nil:
+			s.logger.Printf("获取短链接 %s 的视频失败，沿用已保存的分享预览: %v", link.Slug, err)
+			return nil
+		default:
+			auto.VideoID = &video.ID
+			auto.Title = strings.TrimSpace(video.Title)
+			auto.Description = truncateRunes(strings.TrimSpace(video.Description), maxPreviewDescription)
+			if isWebURL(video.CoverURL) {
+				auto.ImageURL = video.CoverURL
+			}
+		}
+	}
+
+	updated, err := s.repo.SetAutoPreview(ctx, link.ID, auto)
+	if err != nil {
+		s.logger.Printf("保存短链接 %s 的分享预览失败: %v", link.Slug, err)
+		return auto
+	}
+
+	// 只失效缓存，预览不影响跳转，不需要同步到边缘
+	s.cache.invalidate(updated.Slug)
+	s.publishChange(TypeShortLinkUpdated, updated)
+	return auto
+}
+
+// truncateRunes 截断到最多max个字符，超出时以省略号结尾
+func truncateRunes(value string, max int) string {
+	if utf8.RuneCountInString(value) <= max {
+		return value
+	}
+	runes := []rune(value)
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,287 @@
+package shortlinks
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/pkg/useragent"
+)
+
+const (
+	// maxRedirectRules 单个短链接允许的最大规则数量
+	maxRedirectRules = 20
+	// defaultRuleTimezone 规则未指定时区时使用的默认时区
+	defaultRuleTimezone = "Asia/Shanghai"
+	// timeOfDayLayout 每日时间段的格式
+	timeOfDayLayout = "15:04"
+)
+
+// ErrInvalidRedirectRule 跳转规则无效
+var ErrInvalidRedirectRule = errors.New("跳转规则无效")
+
+// 规则中允许使用的操作系统取值
+var ruleOSValues = map[string]string{
+	"ios":       useragent.OSiOS,
+	"android":   useragent.OSAndroid,
+	"harmonyos": useragent.OSHarmonyOS,
+	"windows":   useragent.OSWindows,
+	"macos":     useragent.OSMacOS,
+	"linux":     useragent.OSLinux,
+}
+
+// 规则中允许使用的App内置浏览器取值
+var ruleInAppBrowserValues = map[string]string{
+	"wechat":      useragent.BrowserWeChat,
+	"douyin":      useragent.BrowserDouyin,
+	"xiaohongshu": useragent.BrowserXiaohongshu,
+	"kuaishou":    useragent.BrowserKuaishou,
+	"alipay":      useragent.BrowserAlipay,
+	"qq":          useragent.BrowserQQ,
+}
+
+// RedirectContext 计算跳转目标所需的访问信息
+type RedirectContext struct {
+	UserAgent      string
+	AcceptLanguage string
+	IP             string
+	// StickyVariantID 访问者Cookie中记录的A/B变体
+	StickyVariantID string
+	Now             time.Time
+}
+
+// RedirectResult 跳转目标的计算结果
+type RedirectResult struct {
+	URL string
+	// VariantID 命中的A/B变体，未使用变体时为空
+	VariantID string
+	// DeepLink 移动端访问配置了深度链接的短链接时打开App的方式，为nil时直接重定向到URL
+	DeepLink *DeepLinkPlan
+}
+
+// ResolveTarget 计算本次访问的跳转目标
+// 按顺序匹配跳转规则，第一条命中规则的目标优先；都未命中时，如配置了A/B变体则按权重选择变体，否则使用TargetURL
+// 短链接配置了深度链接时，再根据访问者的设备决定是否先尝试打开App，计算出的目标作为回退地址
+func ResolveTarget(link *entities.ShortLink, visit *RedirectContext) *RedirectResult {
+	result := resolveURL(link, visit)
+	if len(link.DeepLinks) > 0 {
+		result.DeepLink = planDeepLink(link.DeepLinks, result.URL, useragent.Parse(visit.UserAgent))
+	}
+	return result
+}
+
+// resolveURL 按跳转规则和A/B变体计算目标URL
+func resolveURL(link *entities.ShortLink, visit *RedirectContext) *RedirectResult {
+	if len(link.Rules) > 0 {
+		now := visit.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+
+		ua := useragent.Parse(visit.UserAgent)
+		languages := parseAcceptLanguage(visit.AcceptLanguage)
+
+		for _, rule := range link.Rules {
+			if matchConditions(&rule.Conditions, ua, languages, now) {
+				return &RedirectResult{URL: rule.TargetURL}
+			}
+		}
+	}
+
+	if variant := selectVariant(link, visit); variant != nil {
+		return &RedirectResult{URL: variant.TargetURL, VariantID: variant.ID}
+	}
+
+	return &RedirectResult{URL: link.TargetURL}
+}
+
+// matchConditions 判断访问是否满足规则的全部条件
+func matchConditions(cond *entities.RuleConditions, ua useragent.Info, languages []string, now time.Time) bool {
+	if len(cond.OS) > 0 && !containsMapped(cond.OS, ruleOSValues, ua.OS) {
+		return false
+	}
+
+	if len(cond.InAppBrowsers) > 0 && !containsMapped(cond.InAppBrowsers, ruleInAppBrowserValues, ua.Browser) {
+		return false
+	}
+
+	local := now.In(ruleLocation(cond.Timezone))
+
+	if cond.StartDate != nil && local.Before(*cond.StartDate) {
+		return false
+	}
+	if cond.EndDate != nil && !local.Before(*cond.EndDate) {
+		return false
+	}
+
+	if cond.TimeOfDay != nil && !inTimeOfDay(cond.TimeOfDay, local) {
+		return false
+	}
+
+	if len(cond.Languages) > 0 && !matchLanguage(cond.Languages, languages) {
+		return false
+	}
+
+	return true
+}
+
+// containsMapped 判断规则取值列表中是否有与实际值对应的项
+func containsMapped(values []string, mapping map[string]string, actual string) bool {
+	for _, value := range values {
+		if mapping[strings.ToLower(value)] == actual {
+			return true
+		}
+	}
+	return false
+}
+
+// inTimeOfDay 判断时间是否落在每日时间段内，支持跨天的时间段
+func inTimeOfDay(window *entities.TimeOfDayWindow, local time.Time) bool {
+	start, err := parseMinuteOfDay(window.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseMinuteOfDay(window.End)
+	if err != nil {
+		return false
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	// 跨天，例如 22:00 - 06:00
+	return minute >= start || minute < end
+}
+
+// parseMinuteOfDay 将HH:MM解析为当天的分钟数
+func parseMinuteOfDay(value string) (int, error) {
+	t, err := time.Parse(timeOfDayLayout, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// matchLanguage 判断访问者语言是否匹配规则中的任一语言（前缀匹配）
+func matchLanguage(ruleLanguages, visitorLanguages []string) bool {
+	for _, visitor := range visitorLanguages {
+		for _, lang := range ruleLanguages {
+			lang = strings.ToLower(lang)
+			if visitor == lang || strings.HasPrefix(visitor, lang+"-") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseAcceptLanguage 解析Accept-Language请求头，返回小写的语言标签，忽略q=0的项
+func parseAcceptLanguage(header string) []string {
+	var languages []string
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		rejected := false
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q <= 0 {
+				rejected = true
+			}
+		}
+		if !rejected {
+			languages = append(languages, strings.ReplaceAll(tag, "_", "-"))
+		}
+	}
+	return languages
+}
+
+// ruleLocation 获取规则使用的时区，加载失败时回退到东八区
+func ruleLocation(name string) *time.Location {
+	if name == "" {
+		name = defaultRuleTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.FixedZone("CST", 8*60*60)
+	}
+	return loc
+}
+
+// ValidateRules 校验跳转规则
+func ValidateRules(rules entities.RedirectRules) error {
+	if len(rules) > maxRedirectRules {
+		return fmt.Errorf("%w: 规则数量不能超过%d条", ErrInvalidRedirectRule, maxRedirectRules)
+	}
+
+	for i, rule := range rules {
+		if err := validateRule(&rule); err != nil {
+			return fmt.Errorf("%w: 第%d条规则%s", ErrInvalidRedirectRule, i+1, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// validateRule 校验单条跳转规则
+func validateRule(rule *entities.RedirectRule) error {
+	target, err := url.Parse(rule.TargetURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return errors.New("的目标URL无效")
+	}
+
+	cond := &rule.Conditions
+
+	for _, os := range cond.OS {
+		if _, ok := ruleOSValues[strings.ToLower(os)]; !ok {
+			return fmt.Errorf("包含不支持的操作系统: %s", os)
+		}
+	}
+
+	for _, browser := range cond.InAppBrowsers {
+		if _, ok := ruleInAppBrowserValues[strings.ToLower(browser)]; !ok {
+			return fmt.Errorf("包含不支持的App内置浏览器: %s", browser)
+		}
+	}
+
+	if cond.Timezone != "" {
+		if _, err := time.LoadLocation(cond.Timezone); err != nil {
+			return fmt.Errorf("的时区无效: %s", cond.Timezone)
+		}
+	}
+
+	if cond.TimeOfDay != nil {
+		if _, err := parseMinuteOfDay(cond.TimeOfDay.Start); err != nil {
+			return fmt.Errorf("的开始时间格式无效，应为HH:MM: %s", cond.TimeOfDay.Start)
+		}
+		if _, err := parseMinuteOfDay(cond.TimeOfDay.End); err != nil {
+			return fmt.Errorf("的结束时间格式无效，应为HH:MM: %s", cond.TimeOfDay.End)
+		}
+		if cond.TimeOfDay.Start == cond.TimeOfDay.End {
+			return errors.New("的开始时间和结束时间不能相同")
+		}
+	}
+
+	if cond.StartDate != nil && cond.EndDate != nil && !cond.StartDate.Before(*cond.EndDate) {
+		return errors.New("的开始日期必须早于结束日期")
+	}
+
+	for _, lang := range cond.Languages {
+		if strings.TrimSpace(lang) == "" || strings.ContainsAny(lang, ",;* ") {
+			return fmt.Errorf("包含无效的语言: %q", lang)
+		}
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package shortlinks
 import (
 	"context"
 	"nfc-service/internal/domain/entities"
+	"nfc-service/pkg/content"
 
 	"github.com/google/uuid"
 )
@@ -18,7 +19,31 @@ type Service interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 	IncrementClicks(ctx context.Context, slug string) error
 	GetFullURL(baseURL, slug string) string
+	ResolveTarget(link *entities.ShortLink, visit *RedirectContext) *RedirectResult
+	PromoteVariant(ctx context.Context, id uuid.UUID, variantID string) (*entities.ShortLink, error)
 	UpdateDefaultForCard(ctx context.Context, cardID uuid.UUID, targetURL string) error
 	CreateDefaultForCard(ctx context.Context, cardID uuid.UUID, name, targetURL string) (*entities.ShortLink, error)
 	EnsureDefaultLinks(ctx context.Context) error
+	SuspendCardLinks(ctx context.Context, cardID uuid.UUID) (int, error)
+	ResumeCardLinks(ctx context.Context, cardID uuid.UUID) (int, error)
+	// RefreshLinks 短链接在服务外被批量修改（如卡片转让）后重新加载，失效缓存并同步到边缘
+	RefreshLinks(ctx context.Context, ids []uuid.UUID) error
+	InvalidateSlug(slug string)
+	InvalidateCard(cardID uuid.UUID)
+	RenderDeepLinkPage(plan *DeepLinkPlan) ([]byte, error)
+	// Preview 返回短链接的分享预览，商户未设置的字段从卡片默认视频自动填充
+	Preview(ctx context.Context, link *entities.ShortLink) *entities.LinkPreview
+	// RenderPreviewPage 渲染爬虫和聊天软件抓取短链接时返回的OpenGraph页面
+	RenderPreviewPage(ctx context.Context, link *entities.ShortLink, shortURL string) ([]byte, error)
+	ApplyPublishedContent(ctx context.Context, cardID, jobID uuid.UUID, platform, contentID, webURL string) (int, error)
+}
+
+// CardSource 获取卡片的默认视频，用于自动填充分享预览
+type CardSource interface {
+	FindByID(ctx context.Context, id uuid.UUID) (*entities.NfcCard, error)
+}
+
+// VideoSource 获取视频的标题、简介和封面，由content-service客户端实现
+type VideoSource interface {
+	GetVideo(ctx context.Context, merchantID, videoID uuid.UUID) (*content.Video, error)
 }
@@ -2,39 +2,174 @@ package shortlinks
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"time"
+
+	"nfc-service/internal/config"
 	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/services/edgesync"
 	"nfc-service/internal/storage"
+	"nfc-service/pkg/cache"
 	"nfc-service/pkg/cloudflare"
+	"nfc-service/pkg/slug"
 
 	"github.com/google/uuid"
 )
 
+// maxSlugAttempts 随机Slug发生唯一约束冲突时的最大尝试次数
+const maxSlugAttempts = 5
+
+// ErrShortLinkNotFound 短链接不存在
+var ErrShortLinkNotFound = repositories.ErrShortLinkNotFound
+
+const (
+	// TopicCardEvents 卡片事件主题，短链接变更事件也发布到该主题
+	TopicCardEvents = "card-events"
+	// TypeShortLinkUpdated 短链接创建或更新事件类型
+	TypeShortLinkUpdated = "shortlink.updated"
+	// TypeShortLinkDeleted 短链接删除事件类型
+	TypeShortLinkDeleted = "shortlink.deleted"
+)
+
+// KafkaProducer Kafka生产者接口
+type KafkaProducer interface {
+	// SendMessage 发送消息到指定主题
+	SendMessage(topic string, messageType string, data interface{}) error
+}
+
+// ShortLinkChangedEvent 短链接变更事件数据，用于各实例失效本地缓存
+type ShortLinkChangedEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Slug      string    `json:"slug"`
+	NfcCardID uuid.UUID `json:"nfc_card_id"`
+}
+
 // ShortlinkService 短链接服务
 type ShortlinkService struct {
 	repo     *storage.ShortlinkRepository
 	cfClient *cloudflare.Client
+	slugGen  *slug.Generator
+	edgeSync edgesync.Service
+	producer KafkaProducer
+	cards    CardSource
+	videos   VideoSource
+	cache    *linkCache
+	counter  *clickCounter
 	logger   *log.Logger
+
+	// deepLinkTimeout 中间页唤起App后等待多久回退到网页
+	deepLinkTimeout time.Duration
+	// previewRefresh 自动填充的分享预览多久后重新从视频获取
+	previewRefresh time.Duration
 }
 
 // NewShortlinkService 创建短链接服务
+// edgeSync和producer可以为nil，此时分别不向Cloudflare Workers KV同步、不广播缓存失效事件
+// cards或videos为nil时分享预览只使用商户设置的值
 func NewShortlinkService(
 	repo *storage.ShortlinkRepository,
 	cfClient *cloudflare.Client,
+	slugGen *slug.Generator,
+	edgeSync edgesync.Service,
+	producer KafkaProducer,
+	cards CardSource,
+	videos VideoSource,
+	cfg config.ShortLinkConfig,
 	logger *log.Logger,
 ) *ShortlinkService {
+	previewRefresh := time.Duration(cfg.PreviewRefreshMinutes) * time.Minute
+	if previewRefresh <= 0 {
+		previewRefresh = defaultPreviewRefresh
+	}
+
 	return &ShortlinkService{
 		repo:     repo,
 		cfClient: cfClient,
-		logger:   logger,
+		slugGen:  slugGen,
+		edgeSync: edgeSync,
+		producer: producer,
+		cards:    cards,
+		videos:   videos,
+		cache: newLinkCache(
+			cfg.CacheSize,
+			time.Duration(cfg.CacheTTLSeconds)*time.Second,
+			time.Duration(cfg.NegativeCacheTTLSeconds)*time.Second,
+		),
+		counter:         newClickCounter(repo, time.Duration(cfg.ClickFlushIntervalSeconds)*time.Second, logger),
+		logger:          logger,
+		deepLinkTimeout: time.Duration(cfg.DeepLinkTimeoutMillis) * time.Millisecond,
+		previewRefresh:  previewRefresh,
 	}
 }
 
+// Start 启动点击次数的批量写入协程
+func (s *ShortlinkService) Start() {
+	s.counter.start()
+}
+
+// Stop 停止后台协程并写入剩余的点击次数
+func (s *ShortlinkService) Stop() {
+	s.counter.stop()
+}
+
 // Create 创建短链接
 func (s *ShortlinkService) Create(ctx context.Context, link *entities.CreateShortLinkDTO) (*entities.ShortLink, error) {
 	s.logger.Printf("创建短链接: %v", link)
-	return s.repo.Create(ctx, link)
+
+	if err := ValidateRules(link.Rules); err != nil {
+		return nil, err
+	}
+
+	if err := prepareVariants(link.Variants); err != nil {
+		return nil, err
+	}
+
+	if err := prepareDeepLinks(link.DeepLinks); err != nil {
+		return nil, err
+	}
+
+	var created *entities.ShortLink
+	var err error
+
+	// 商户指定了自定义Slug，校验格式后直接创建，冲突时由存储层返回ErrSlugAlreadyExists
+	if link.Slug != "" {
+		if err := s.slugGen.ValidateVanity(link.Slug); err != nil {
+			return nil, err
+		}
+		created, err = s.repo.Create(ctx, link)
+	} else {
+		created, err = s.createWithGeneratedSlug(ctx, link)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.onLinkChanged(ctx, created)
+	return created, nil
+}
+
+// createWithGeneratedSlug 使用随机Slug创建短链接，遇到唯一约束冲突时重新生成
+func (s *ShortlinkService) createWithGeneratedSlug(ctx context.Context, link *entities.CreateShortLinkDTO) (*entities.ShortLink, error) {
+	for attempt := 1; attempt <= maxSlugAttempts; attempt++ {
+		generated, err := s.slugGen.Generate()
+		if err != nil {
+			return nil, err
+		}
+		link.Slug = generated
+
+		created, err := s.repo.Create(ctx, link)
+		if errors.Is(err, repositories.ErrSlugAlreadyExists) {
+			s.logger.Printf("随机Slug %s 已存在，第%d次重试", generated, attempt)
+			continue
+		}
+		return created, err
+	}
+
+	return nil, fmt.Errorf("生成唯一Slug失败，已尝试%d次", maxSlugAttempts)
 }
 
 // GetByID 根据ID获取短链接
@@ -43,10 +178,23 @@ func (s *ShortlinkService) GetByID(ctx context.Context, id uuid.UUID) (*entities
 	return s.repo.FindByID(ctx, id)
 }
 
-// GetBySlug 根据Slug获取短链接
+// GetBySlug 根据Slug获取短链接，优先读取进程内缓存；短链接不存在时返回nil
 func (s *ShortlinkService) GetBySlug(ctx context.Context, slug string) (*entities.ShortLink, error) {
-	s.logger.Printf("获取短链接: %s", slug)
-	return s.repo.FindBySlug(ctx, slug)
+	if link, found := s.cache.get(slug); found {
+		return link, nil
+	}
+
+	link, err := s.repo.FindBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.cache.setMissing(slug)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	s.cache.set(slug, link)
+	return link, nil
 }
 
 // GetByMerchantID 获取商户的所有短链接
@@ -64,19 +212,149 @@ func (s *ShortlinkService) GetByNfcCardID(ctx context.Context, nfcCardID uuid.UU
 // Update 更新短链接
 func (s *ShortlinkService) Update(ctx context.Context, id uuid.UUID, link *entities.UpdateShortLinkDTO) (*entities.ShortLink, error) {
 	s.logger.Printf("更新短链接: %s", id)
-	return s.repo.Update(ctx, id, link)
+
+	if link.Rules != nil {
+		if err := ValidateRules(*link.Rules); err != nil {
+			return nil, err
+		}
+	}
+
+	if link.Variants != nil {
+		if err := prepareVariants(*link.Variants); err != nil {
+			return nil, err
+		}
+	}
+
+	if link.DeepLinks != nil {
+		if err := prepareDeepLinks(*link.DeepLinks); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := s.repo.Update(ctx, id, link)
+	if err != nil {
+		return nil, err
+	}
+
+	s.onLinkChanged(ctx, updated)
+	return updated, nil
 }
 
 // Delete 删除短链接
 func (s *ShortlinkService) Delete(ctx context.Context, id uuid.UUID) error {
 	s.logger.Printf("删除短链接: %s", id)
-	return s.repo.Delete(ctx, id)
+
+	link, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.cache.invalidate(link.Slug)
+	s.publishChange(TypeShortLinkDeleted, link)
+
+	if s.edgeSync != nil {
+		if err := s.edgeSync.EnqueueDelete(ctx, link.Slug); err != nil {
+			s.logger.Printf("写入边缘同步任务失败，等待全量对账修复: %v", err)
+		}
+	}
+	return nil
+}
+
+// SuspendCardLinks 卡片停用或过期时停用其所有启用中的短链接，返回停用的数量
+func (s *ShortlinkService) SuspendCardLinks(ctx context.Context, cardID uuid.UUID) (int, error) {
+	links, err := s.repo.SuspendByNfcCardID(ctx, cardID)
+	if err != nil {
+		return 0, err
+	}
+	for _, link := range links {
+		s.onLinkChanged(ctx, link)
+	}
+	return len(links), nil
+}
+
+// ResumeCardLinks 卡片重新激活时恢复由卡片挂起的短链接，返回恢复的数量
+func (s *ShortlinkService) ResumeCardLinks(ctx context.Context, cardID uuid.UUID) (int, error) {
+	links, err := s.repo.ResumeByNfcCardID(ctx, cardID)
+	if err != nil {
+		return 0, err
+	}
+	for _, link := range links {
+		s.onLinkChanged(ctx, link)
+	}
+	return len(links), nil
+}
+
+// RefreshLinks 重新加载被数据库函数直接修改的短链接，逐条失效缓存、广播变更并写入边缘同步发件箱
+func (s *ShortlinkService) RefreshLinks(ctx context.Context, ids []uuid.UUID) error {
+	for _, id := range ids {
+		link, err := s.repo.FindByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("重新加载短链接 %s 失败: %w", id, err)
+		}
+		s.onLinkChanged(ctx, link)
+	}
+	return nil
+}
+
+// onLinkChanged 短链接创建或更新后失效缓存、广播变更并写入边缘同步发件箱
+// 这些步骤失败只记录日志，不影响主流程：缓存有过期时间兜底，边缘KV由定期全量对账修复
+func (s *ShortlinkService) onLinkChanged(ctx context.Context, link *entities.ShortLink) {
+	if link == nil {
+		return
+	}
+
+	s.cache.invalidate(link.Slug)
+	s.publishChange(TypeShortLinkUpdated, link)
+
+	if s.edgeSync != nil {
+		if err := s.edgeSync.EnqueueLink(ctx, link); err != nil {
+			s.logger.Printf("写入边缘同步任务失败，等待全量对账修复: %v", err)
+		}
+	}
+}
+
+// publishChange 广播短链接变更事件，通知其他实例失效缓存
+func (s *ShortlinkService) publishChange(msgType string, link *entities.ShortLink) {
+	if s.producer == nil {
+		return
+	}
+
+	event := &ShortLinkChangedEvent{
+		ID:        link.ID,
+		Slug:      link.Slug,
+		NfcCardID: link.NfcCardID,
+	}
+	if err := s.producer.SendMessage(TopicCardEvents, msgType, event); err != nil {
+		s.logger.Printf("发布短链接变更事件失败: %v", err)
+	}
+}
+
+// InvalidateSlug 使指定slug的缓存失效
+func (s *ShortlinkService) InvalidateSlug(slug string) {
+	s.cache.invalidate(slug)
+}
+
+// InvalidateCard 使指定卡片下所有短链接的缓存失效
+func (s *ShortlinkService) InvalidateCard(cardID uuid.UUID) {
+	if removed := s.cache.invalidateCard(cardID); removed > 0 {
+		s.logger.Printf("已失效卡片 %s 的%d条短链接缓存", cardID, removed)
+	}
+}
+
+// CacheStats 返回重定向缓存的命中统计
+func (s *ShortlinkService) CacheStats() cache.Stats {
+	return s.cache.stats()
 }
 
 // IncrementClicks 增加点击次数
+// 点击次数先在内存中累积，由后台协程定期批量写入，不会阻塞调用方
 func (s *ShortlinkService) IncrementClicks(ctx context.Context, slug string) error {
-	s.logger.Printf("增加点击次数: %s", slug)
-	return s.repo.IncrementClicks(ctx, slug)
+	s.counter.add(slug)
+	return nil
 }
 
 // GetFullURL 获取完整URL
@@ -84,6 +362,37 @@ func (s *ShortlinkService) GetFullURL(baseURL, slug string) string {
 	return fmt.Sprintf("%s/%s", baseURL, slug)
 }
 
+// ResolveTarget 根据跳转规则和A/B变体计算本次访问的目标URL
+func (s *ShortlinkService) ResolveTarget(link *entities.ShortLink, visit *RedirectContext) *RedirectResult {
+	return ResolveTarget(link, visit)
+}
+
+// PromoteVariant 将A/B测试中胜出的变体设为短链接的唯一目标，并结束测试
+func (s *ShortlinkService) PromoteVariant(ctx context.Context, id uuid.UUID, variantID string) (*entities.ShortLink, error) {
+	s.logger.Printf("推广短链接 %s 的变体 %s", id, variantID)
+
+	link, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := link.Variants.Find(variantID)
+	if variant == nil {
+		return nil, ErrVariantNotFound
+	}
+
+	promoted, err := s.repo.Update(ctx, id, &entities.UpdateShortLinkDTO{
+		TargetURL: variant.TargetURL,
+		Variants:  &entities.LinkVariants{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.onLinkChanged(ctx, promoted)
+	return promoted, nil
+}
+
 // UpdateDefaultForCard 当卡片更新时更新关联的默认短链接
 func (s *ShortlinkService) UpdateDefaultForCard(ctx context.Context, cardID uuid.UUID, targetURL string) error {
 	s.logger.Printf("为卡片 %s 更新默认短链接", cardID)
@@ -103,11 +412,12 @@ func (s *ShortlinkService) UpdateDefaultForCard(ctx context.Context, cardID uuid
 				TargetURL: targetURL,
 			}
 
-			_, err := s.repo.Update(ctx, link.ID, updateDTO)
+			updated, err := s.repo.Update(ctx, link.ID, updateDTO)
 			if err != nil {
 				s.logger.Printf("更新短链接失败: %v", err)
 				return err
 			}
+			s.onLinkChanged(ctx, updated)
 
 			s.logger.Printf("已更新卡片 %s 的默认短链接 %s", cardID, link.ID)
 			found = true
@@ -123,11 +433,12 @@ func (s *ShortlinkService) UpdateDefaultForCard(ctx context.Context, cardID uuid
 			IsDefault: &[]bool{true}[0],
 		}
 
-		_, err := s.repo.Update(ctx, links[0].ID, updateDTO)
+		updated, err := s.repo.Update(ctx, links[0].ID, updateDTO)
 		if err != nil {
 			s.logger.Printf("设置默认短链接失败: %v", err)
 			return err
 		}
+		s.onLinkChanged(ctx, updated)
 
 		s.logger.Printf("已将卡片 %s 的短链接 %s 设置为默认并更新", cardID, links[0].ID)
 	}
@@ -153,11 +464,12 @@ func (s *ShortlinkService) CreateDefaultForCard(ctx context.Context, cardID uuid
 		IsDefault: true,
 	}
 
-	link, err := s.repo.Create(ctx, linkDTO)
+	link, err := s.createWithGeneratedSlug(ctx, linkDTO)
 	if err != nil {
 		s.logger.Printf("创建默认短链接失败: %v", err)
 		return nil, err
 	}
+	s.onLinkChanged(ctx, link)
 
 	s.logger.Printf("已为卡片 %s 创建默认短链接 %s", cardID, link.ID)
 	return link, nil
@@ -0,0 +1,125 @@
+package shortlinks
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxLinkVariants 单个短链接允许的最大变体数量
+	maxLinkVariants = 10
+	// maxVariantWeight 单个变体的最大权重
+	maxVariantWeight = 1000
+)
+
+var (
+	// ErrInvalidVariant 链接变体无效
+	ErrInvalidVariant = errors.New("链接变体无效")
+	// ErrVariantNotFound 链接变体不存在
+	ErrVariantNotFound = errors.New("链接变体不存在")
+)
+
+// prepareVariants 为缺少ID的变体生成ID并校验变体列表
+func prepareVariants(variants entities.LinkVariants) error {
+	for i := range variants {
+		if variants[i].ID == "" {
+			variants[i].ID = strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+		}
+	}
+	return ValidateVariants(variants)
+}
+
+// ValidateVariants 校验链接变体，变体列表为空表示不进行A/B测试
+func ValidateVariants(variants entities.LinkVariants) error {
+	if len(variants) == 0 {
+		return nil
+	}
+	if len(variants) < 2 {
+		return fmt.Errorf("%w: A/B测试至少需要2个变体", ErrInvalidVariant)
+	}
+	if len(variants) > maxLinkVariants {
+		return fmt.Errorf("%w: 变体数量不能超过%d个", ErrInvalidVariant, maxLinkVariants)
+	}
+
+	ids := make(map[string]bool, len(variants))
+	totalWeight := 0
+	for i, variant := range variants {
+		if ids[variant.ID] {
+			return fmt.Errorf("%w: 变体ID重复: %s", ErrInvalidVariant, variant.ID)
+		}
+		ids[variant.ID] = true
+
+		if strings.TrimSpace(variant.Name) == "" {
+			return fmt.Errorf("%w: 第%d个变体名称不能为空", ErrInvalidVariant, i+1)
+		}
+
+		target, err := url.Parse(variant.TargetURL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fmt.Errorf("%w: 第%d个变体的目标URL无效", ErrInvalidVariant, i+1)
+		}
+
+		if variant.Weight < 0 || variant.Weight > maxVariantWeight {
+			return fmt.Errorf("%w: 第%d个变体的权重必须在0到%d之间", ErrInvalidVariant, i+1, maxVariantWeight)
+		}
+		totalWeight += variant.Weight
+	}
+
+	if totalWeight == 0 {
+		return fmt.Errorf("%w: 变体的权重之和必须大于0", ErrInvalidVariant)
+	}
+
+	return nil
+}
+
+// selectVariant 为访问者选择变体
+// 优先沿用Cookie中已分配的变体；否则根据设备指纹按权重确定性地分配，保证同一设备多次访问落在同一变体
+func selectVariant(link *entities.ShortLink, visit *RedirectContext) *entities.LinkVariant {
+	if len(link.Variants) == 0 {
+		return nil
+	}
+
+	if visit.StickyVariantID != "" {
+		if variant := link.Variants.Find(visit.StickyVariantID); variant != nil && variant.Weight > 0 {
+			return variant
+		}
+	}
+
+	totalWeight := 0
+	for _, variant := range link.Variants {
+		totalWeight += variant.Weight
+	}
+	if totalWeight <= 0 {
+		return nil
+	}
+
+	point := int(bucketOf(link.ID.String(), Fingerprint(visit)) % uint64(totalWeight))
+	for i := range link.Variants {
+		point -= link.Variants[i].Weight
+		if point < 0 {
+			return &link.Variants[i]
+		}
+	}
+
+	return nil
+}
+
+// Fingerprint 根据IP、User-Agent和语言计算访问者的设备指纹
+func Fingerprint(visit *RedirectContext) string {
+	sum := sha256.Sum256([]byte(visit.IP + "|" + visit.UserAgent + "|" + visit.AcceptLanguage))
+	return hex.EncodeToString(sum[:16])
+}
+
+// bucketOf 将短链接和访问者映射为稳定的哈希值
+func bucketOf(linkID, visitorKey string) uint64 {
+	sum := sha256.Sum256([]byte(linkID + ":" + visitorKey))
+	return binary.BigEndian.Uint64(sum[:8])
+}
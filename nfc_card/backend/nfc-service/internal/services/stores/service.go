@@ -0,0 +1,31 @@
+package stores
+
+import (
+	"context"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// Service 门店服务接口
+// 连锁商户的卡片可分配到门店及店内位置，碰卡按门店汇总以便比较各门店的表现
+type Service interface {
+	Create(ctx context.Context, merchantID uuid.UUID, dto *entities.StoreDTO) (*entities.Store, error)
+	Get(ctx context.Context, merchantID, id uuid.UUID) (*entities.Store, error)
+	// List 分页获取商户的门店，city为空时返回全部城市
+	List(ctx context.Context, merchantID uuid.UUID, city string, page, pageSize int) ([]*entities.Store, int, error)
+	Update(ctx context.Context, merchantID, id uuid.UUID, dto *entities.StoreDTO) (*entities.Store, error)
+	// Delete 删除门店，门店下仍有卡片时返回ErrStoreHasCards
+	Delete(ctx context.Context, merchantID, id uuid.UUID) error
+	// AssignCards 把卡片分配到门店的指定位置，已属于其他门店的卡片会被移过来
+	AssignCards(ctx context.Context, merchantID, id uuid.UUID, assignments []entities.StoreCardAssignment) (int, error)
+	// UnassignCards 把卡片移出门店
+	UnassignCards(ctx context.Context, merchantID, id uuid.UUID, cardIDs []uuid.UUID) (int, error)
+	ListCards(ctx context.Context, merchantID, id uuid.UUID, page, pageSize int) ([]*entities.StoreCard, int, error)
+	// Compare 汇总商户各门店在[from, to)内的碰卡，按碰卡次数从高到低排序
+	Compare(ctx context.Context, merchantID uuid.UUID, from, to time.Time) ([]*entities.StoreTapSummary, error)
+	// Stats 获取单个门店在[from, to)内的碰卡明细
+	Stats(ctx context.Context, merchantID, id uuid.UUID, from, to time.Time) (*entities.StoreStats, error)
+}
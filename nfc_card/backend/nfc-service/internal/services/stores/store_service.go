@@ -0,0 +1,310 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultTimezone 门店未指定时区时使用的默认时区
+	defaultTimezone = "Asia/Shanghai"
+	// timeOfDayLayout 营业时间的格式
+	timeOfDayLayout = "15:04"
+	// maxOpeningPeriods 营业时间最多的时间段数量
+	maxOpeningPeriods = 14
+	// maxAssignments 单次分配或移出的最大卡片数量
+	maxAssignments = 500
+	// maxStatsRange 统计区间的最大跨度
+	maxStatsRange = 366 * 24 * time.Hour
+	// breakdownLimit 门店碰卡明细每个维度返回的最大条数
+	breakdownLimit = 100
+)
+
+var (
+	// ErrInvalidStore 门店参数无效
+	ErrInvalidStore = errors.New("门店参数无效")
+	// ErrStoreNotFound 门店不存在
+	ErrStoreNotFound = storage.ErrStoreNotFound
+	// ErrStoreExists 门店名称或编号已存在
+	ErrStoreExists = storage.ErrStoreExists
+	// ErrStoreHasCards 门店下仍有卡片
+	ErrStoreHasCards = storage.ErrStoreHasCards
+)
+
+// storeService 门店服务的实现
+type storeService struct {
+	repo   *storage.StoreRepository
+	cards  *repositories.NfcCardRepository
+	logger *log.Logger
+}
+
+// NewStoreService 创建门店服务
+func NewStoreService(repo *storage.StoreRepository, cards *repositories.NfcCardRepository, logger *log.Logger) Service {
+	return &storeService{
+		repo:   repo,
+		cards:  cards,
+		logger: logger,
+	}
+}
+
+// Create 创建门店
+func (s *storeService) Create(ctx context.Context, merchantID uuid.UUID, dto *entities.StoreDTO) (*entities.Store, error) {
+	store, err := buildStore(merchantID, dto)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.Create(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Printf("商户 %s 创建了门店 %s: %s", merchantID, created.ID, created.Name)
+	return created, nil
+}
+
+// Get 获取门店
+func (s *storeService) Get(ctx context.Context, merchantID, id uuid.UUID) (*entities.Store, error) {
+	return s.repo.FindByID(ctx, merchantID, id)
+}
+
+// List 分页获取商户的门店
+func (s *storeService) List(ctx context.Context, merchantID uuid.UUID, city string, page, pageSize int) ([]*entities.Store, int, error) {
+	return s.repo.FindByMerchant(ctx, merchantID, strings.TrimSpace(city), page, pageSize)
+}
+
+// Update 整体修改门店信息
+func (s *storeService) Update(ctx context.Context, merchantID, id uuid.UUID, dto *entities.StoreDTO) (*entities.Store, error) {
+	store, err := buildStore(merchantID, dto)
+	if err != nil {
+		return nil, err
+	}
+	store.ID = id
+
+	return s.repo.Update(ctx, store)
+}
+
+// Delete 删除门店
+func (s *storeService) Delete(ctx context.Context, merchantID, id uuid.UUID) error {
+	if err := s.repo.Delete(ctx, merchantID, id); err != nil {
+		return err
+	}
+	s.logger.Printf("商户 %s 删除了门店 %s", merchantID, id)
+	return nil
+}
+
+// AssignCards 把卡片分配到门店
+func (s *storeService) AssignCards(ctx context.Context, merchantID, id uuid.UUID, assignments []entities.StoreCardAssignment) (int, error) {
+	if len(assignments) > maxAssignments {
+		return 0, fmt.Errorf("%w: 单次最多分配%d张卡片", ErrInvalidStore, maxAssignments)
+	}
+	if _, err := s.repo.FindByID(ctx, merchantID, id); err != nil {
+		return 0, err
+	}
+
+	seen := make(map[uuid.UUID]bool, len(assignments))
+	ids := make([]uuid.UUID, 0, len(assignments))
+	for i := range assignments {
+		assignments[i].Position = strings.TrimSpace(assignments[i].Position)
+		if seen[assignments[i].CardID] {
+			return 0, fmt.Errorf("%w: 卡片重复: %s", ErrInvalidStore, assignments[i].CardID)
+		}
+		seen[assignments[i].CardID] = true
+		ids = append(ids, assignments[i].CardID)
+	}
+
+	// 卡片必须全部属于该商户，避免部分分配
+	owned, err := s.cards.FindByIDs(ctx, merchantID, ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(owned) != len(ids) {
+		return 0, fmt.Errorf("%w: 部分卡片不存在", ErrInvalidStore)
+	}
+
+	assigned, err := s.repo.AssignCards(ctx, merchantID, id, assignments)
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Printf("商户 %s 向门店 %s 分配了 %d 张卡片", merchantID, id, assigned)
+	return assigned, nil
+}
+
+// UnassignCards 把卡片移出门店
+func (s *storeService) UnassignCards(ctx context.Context, merchantID, id uuid.UUID, cardIDs []uuid.UUID) (int, error) {
+	if len(cardIDs) > maxAssignments {
+		return 0, fmt.Errorf("%w: 单次最多移出%d张卡片", ErrInvalidStore, maxAssignments)
+	}
+	if _, err := s.repo.FindByID(ctx, merchantID, id); err != nil {
+		return 0, err
+	}
+
+	removed, err := s.repo.UnassignCards(ctx, merchantID, id, cardIDs)
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Printf("商户 %s 从门店 %s 移出了 %d 张卡片", merchantID, id, removed)
+	return removed, nil
+}
+
+// ListCards 分页获取门店的卡片
+func (s *storeService) ListCards(ctx context.Context, merchantID, id uuid.UUID, page, pageSize int) ([]*entities.StoreCard, int, error) {
+	if _, err := s.repo.FindByID(ctx, merchantID, id); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.FindCards(ctx, id, page, pageSize)
+}
+
+// Compare 汇总商户各门店的碰卡
+func (s *storeService) Compare(ctx context.Context, merchantID uuid.UUID, from, to time.Time) ([]*entities.StoreTapSummary, error) {
+	if err := validateRange(from, to); err != nil {
+		return nil, err
+	}
+
+	summaries, err := s.repo.TapSummaries(ctx, merchantID, nil, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, summary := range summaries {
+		summary.TapsPerCard = tapsPerCard(summary)
+	}
+	return summaries, nil
+}
+
+// Stats 获取单个门店的碰卡明细
+func (s *storeService) Stats(ctx context.Context, merchantID, id uuid.UUID, from, to time.Time) (*entities.StoreStats, error) {
+	if err := validateRange(from, to); err != nil {
+		return nil, err
+	}
+
+	summaries, err := s.repo.TapSummaries(ctx, merchantID, &id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, ErrStoreNotFound
+	}
+
+	stats := &entities.StoreStats{StoreTapSummary: *summaries[0]}
+	stats.TapsPerCard = tapsPerCard(&stats.StoreTapSummary)
+
+	breakdowns := []struct {
+		dimension string
+		target    *[]*entities.ClickBreakdownItem
+	}{
+		{"day", &stats.ByDay},
+		{"hour", &stats.ByHour},
+		{"position", &stats.ByPosition},
+		{"card", &stats.ByCard},
+	}
+	for _, b := range breakdowns {
+		items, err := s.repo.TapBreakdown(ctx, id, from, to, b.dimension, breakdownLimit)
+		if err != nil {
+			return nil, err
+		}
+		*b.target = items
+	}
+
+	return stats, nil
+}
+
+// buildStore 校验并构建门店
+func buildStore(merchantID uuid.UUID, dto *entities.StoreDTO) (*entities.Store, error) {
+	store := &entities.Store{
+		MerchantID:   merchantID,
+		Name:         strings.TrimSpace(dto.Name),
+		Address:      strings.TrimSpace(dto.Address),
+		City:         strings.TrimSpace(dto.City),
+		Phone:        strings.TrimSpace(dto.Phone),
+		Latitude:     dto.Latitude,
+		Longitude:    dto.Longitude,
+		Timezone:     strings.TrimSpace(dto.Timezone),
+		OpeningHours: dto.OpeningHours,
+	}
+	if code := strings.TrimSpace(dto.Code); code != "" {
+		store.Code = &code
+	}
+	if store.Timezone == "" {
+		store.Timezone = defaultTimezone
+	}
+	if store.OpeningHours == nil {
+		store.OpeningHours = entities.OpeningHours{}
+	}
+
+	if store.Name == "" {
+		return nil, fmt.Errorf("%w: 门店名称不能为空", ErrInvalidStore)
+	}
+	if (store.Latitude == nil) != (store.Longitude == nil) {
+		return nil, fmt.Errorf("%w: 经度和纬度必须同时设置", ErrInvalidStore)
+	}
+	if store.Latitude != nil && (*store.Latitude < -90 || *store.Latitude > 90) {
+		return nil, fmt.Errorf("%w: 纬度应在-90到90之间", ErrInvalidStore)
+	}
+	if store.Longitude != nil && (*store.Longitude < -180 || *store.Longitude > 180) {
+		return nil, fmt.Errorf("%w: 经度应在-180到180之间", ErrInvalidStore)
+	}
+	if _, err := time.LoadLocation(store.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: 时区无效: %s", ErrInvalidStore, store.Timezone)
+	}
+	if err := validateOpeningHours(store.OpeningHours); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// validateOpeningHours 校验营业时间
+func validateOpeningHours(hours entities.OpeningHours) error {
+	if len(hours) > maxOpeningPeriods {
+		return fmt.Errorf("%w: 营业时间最多%d段", ErrInvalidStore, maxOpeningPeriods)
+	}
+
+	for i, period := range hours {
+		if len(period.Days) == 0 {
+			return fmt.Errorf("%w: 第%d段营业时间未设置星期", ErrInvalidStore, i+1)
+		}
+		for _, day := range period.Days {
+			if day < 0 || day > 6 {
+				return fmt.Errorf("%w: 第%d段营业时间的星期无效，应为0（周日）到6（周六）: %d", ErrInvalidStore, i+1, day)
+			}
+		}
+		if _, err := time.Parse(timeOfDayLayout, period.Open); err != nil {
+			return fmt.Errorf("%w: 第%d段营业时间的开门时间格式无效，应为HH:MM: %s", ErrInvalidStore, i+1, period.Open)
+		}
+		if _, err := time.Parse(timeOfDayLayout, period.Close); err != nil {
+			return fmt.Errorf("%w: 第%d段营业时间的打烊时间格式无效，应为HH:MM: %s", ErrInvalidStore, i+1, period.Close)
+		}
+		if period.Open == period.Close {
+			return fmt.Errorf("%w: 第%d段营业时间的开门和打烊时间不能相同", ErrInvalidStore, i+1)
+		}
+	}
+	return nil
+}
+
+// validateRange 校验统计区间
+func validateRange(from, to time.Time) error {
+	if !from.Before(to) {
+		return fmt.Errorf("%w: 开始时间必须早于结束时间", ErrInvalidStore)
+	}
+	if to.Sub(from) > maxStatsRange {
+		return fmt.Errorf("%w: 统计区间不能超过一年", ErrInvalidStore)
+	}
+	return nil
+}
+
+// tapsPerCard 计算平均每张卡片的碰卡次数，保留两位小数
+func tapsPerCard(summary *entities.StoreTapSummary) float64 {
+	if summary.CardCount == 0 {
+		return 0
+	}
+	return float64(summary.Taps*100/summary.CardCount) / 100
+}
@@ -0,0 +1,62 @@
+package sun
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// keySealer 使用密钥加密密钥(AES-256-GCM)加密存储卡片密钥
+// 附加数据绑定卡片ID和密钥编号，密文被复制到其他卡片或其他密钥字段后无法解密
+type keySealer struct {
+	aead cipher.AEAD
+}
+
+// newKeySealer 创建卡片密钥加密器，kek必须为32字节
+func newKeySealer(kek []byte) (*keySealer, error) {
+	if len(kek) != 32 {
+		return nil, fmt.Errorf("密钥加密密钥必须为32字节，实际%d字节", len(kek))
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &keySealer{aead: aead}, nil
+}
+
+// seal 加密卡片密钥，返回nonce || 密文
+func (s *keySealer) seal(cardID uuid.UUID, keyNo byte, key []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return s.aead.Seal(nonce, nonce, key, sealAAD(cardID, keyNo)), nil
+}
+
+// open 解密卡片密钥
+func (s *keySealer) open(cardID uuid.UUID, keyNo byte, sealed []byte) ([]byte, error) {
+	if len(sealed) < s.aead.NonceSize() {
+		return nil, errors.New("卡片密钥密文长度无效")
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	key, err := s.aead.Open(nil, nonce, ciphertext, sealAAD(cardID, keyNo))
+	if err != nil {
+		return nil, fmt.Errorf("解密卡片密钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// sealAAD 加密卡片密钥使用的附加数据
+func sealAAD(cardID uuid.UUID, keyNo byte) []byte {
+	aad := make([]byte, 0, len(cardID)+1)
+	aad = append(aad, cardID[:]...)
+	return append(aad, keyNo)
+}
@@ -0,0 +1,59 @@
+package sun
+
+import (
+	"context"
+	"net/url"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// Service NTAG 424 DNA安全动态消息(SUN)服务接口
+type Service interface {
+	// Enable 为卡片派生新密钥并开启SUN校验，返回写卡所需的密钥和SDM偏移量
+	// 已开启的卡片再次调用会轮换密钥，旧密钥生成的URL全部失效
+	Enable(ctx context.Context, merchantID, cardID uuid.UUID) (*entities.SUNProvisioning, error)
+	// Disable 关闭卡片的SUN校验并删除密钥
+	Disable(ctx context.Context, merchantID, cardID uuid.UUID) error
+	// Status 获取卡片的SUN状态
+	Status(ctx context.Context, merchantID, cardID uuid.UUID) (*entities.SUNStatus, error)
+	// Verify 校验一次读取并推进读计数器；卡片未开启SUN时返回nil, nil
+	Verify(ctx context.Context, cardID uuid.UUID, tap *Tap) (*entities.SUNTap, error)
+	// TapFromQuery 从URL查询参数中读取SUN数据
+	TapFromQuery(query url.Values) *Tap
+	// InvalidateCard 使卡片密钥的本地缓存失效
+	InvalidateCard(cardID uuid.UUID)
+}
+
+// KafkaProducer Kafka生产者接口
+type KafkaProducer interface {
+	// SendMessage 发送消息到指定主题
+	SendMessage(topic string, messageType string, data interface{}) error
+}
+
+// keyStore 卡片SUN密钥和读计数器存储，由storage.SUNRepository实现
+type keyStore interface {
+	FindByCardID(ctx context.Context, cardID uuid.UUID) (*entities.SUNCardKeys, error)
+	FindByMerchant(ctx context.Context, merchantID, cardID uuid.UUID) (*entities.SUNCardKeys, error)
+	SaveKeys(ctx context.Context, merchantID, cardID uuid.UUID, keyVersion int, metaReadKey, fileReadKey []byte) (*entities.SUNCardKeys, error)
+	Disable(ctx context.Context, merchantID, cardID uuid.UUID) error
+	AdvanceCounter(ctx context.Context, cardID uuid.UUID, keyVersion, counter int) (bool, error)
+}
+
+// linkStore 获取卡片短链接的存储，由storage.ShortlinkRepository实现
+type linkStore interface {
+	FindByNfcCardID(ctx context.Context, nfcCardID uuid.UUID) ([]*entities.ShortLink, error)
+}
+
+// Tap 标签在URL中附加的SUN数据（十六进制）
+type Tap struct {
+	PICCData string
+	MAC      string
+}
+
+// CardSUNUpdatedEvent 卡片SUN设置变更事件，通知其他实例失效密钥缓存
+type CardSUNUpdatedEvent struct {
+	ID      uuid.UUID `json:"id"`
+	Enabled bool      `json:"enabled"`
+}
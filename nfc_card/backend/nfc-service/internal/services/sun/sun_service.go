@@ -0,0 +1,392 @@
+package sun
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/services/edgesync"
+	"nfc-service/internal/storage"
+	"nfc-service/pkg/cache"
+	"nfc-service/pkg/ntag424"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TopicCardEvents 卡片事件主题
+	TopicCardEvents = "card-events"
+	// TypeCardSUNUpdated 卡片SUN设置变更事件
+	TypeCardSUNUpdated = "card.sun_updated"
+
+	defaultPICCDataParam = "e"
+	defaultMACParam      = "c"
+	defaultCacheTTL      = 30 * time.Second
+	keyCacheSize         = 10000
+	maxKeyVersion        = 255
+)
+
+var (
+	// ErrNotConfigured 未配置主密钥或密钥加密密钥
+	ErrNotConfigured = errors.New("服务未配置SUN密钥")
+	// ErrInvalidUID 卡片UID不是7字节的十六进制UID，无法派生NTAG 424 DNA密钥
+	ErrInvalidUID = errors.New("卡片UID不是有效的NTAG 424 DNA UID")
+	// ErrTapRequired 卡片开启了SUN校验，但URL中没有SUN数据
+	ErrTapRequired = errors.New("缺少SUN校验参数")
+	// ErrTapInvalid SUN数据无法解密、UID不匹配或MAC校验失败
+	ErrTapInvalid = errors.New("SUN校验失败")
+	// ErrReplay 读计数器不大于已接受的值，URL被重放或乱序到达
+	ErrReplay = errors.New("SUN读计数器已被使用")
+)
+
+// cardKeys 解密后的卡片密钥，enabled为false时其他字段为空
+type cardKeys struct {
+	enabled     bool
+	uid         []byte
+	keyVersion  int
+	metaReadKey []byte
+	fileReadKey []byte
+}
+
+// sunService SUN服务的实现
+type sunService struct {
+	repo      keyStore
+	links     linkStore
+	edgeSync  edgesync.Service
+	producer  KafkaProducer
+	sealer    *keySealer
+	masterKey []byte
+	systemID  []byte
+	piccParam string
+	macParam  string
+	baseURL   string
+	cache     *cache.LRU[uuid.UUID, *cardKeys]
+	cacheTTL  time.Duration
+	logger    *log.Logger
+}
+
+// NewSUNService 创建SUN服务
+// 未配置密钥时仍可校验未开启SUN的卡片，但不能为卡片开启SUN；edgeSync和producer可以为nil
+func NewSUNService(
+	repo *storage.SUNRepository,
+	links *storage.ShortlinkRepository,
+	edgeSync edgesync.Service,
+	producer KafkaProducer,
+	cfg config.SUNConfig,
+	baseURL string,
+	logger *log.Logger,
+) (Service, error) {
+	s := &sunService{
+		repo:      repo,
+		links:     links,
+		edgeSync:  edgeSync,
+		producer:  producer,
+		systemID:  []byte(cfg.SystemIdentifier),
+		piccParam: cfg.PICCDataParam,
+		macParam:  cfg.MACParam,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		cache:     cache.NewLRU[uuid.UUID, *cardKeys](keyCacheSize),
+		cacheTTL:  time.Duration(cfg.CacheTTLSeconds) * time.Second,
+		logger:    logger,
+	}
+	if s.piccParam == "" {
+		s.piccParam = defaultPICCDataParam
+	}
+	if s.macParam == "" {
+		s.macParam = defaultMACParam
+	}
+	if s.cacheTTL <= 0 {
+		s.cacheTTL = defaultCacheTTL
+	}
+
+	if cfg.Configured() {
+		masterKey, err := hex.DecodeString(cfg.MasterKey)
+		if err != nil || len(masterKey) != ntag424.KeySize {
+			return nil, errors.New("SUN主密钥必须为32位十六进制字符串")
+		}
+		kek, err := hex.DecodeString(cfg.KeyEncryptionKey)
+		if err != nil {
+			return nil, errors.New("SUN密钥加密密钥必须为64位十六进制字符串")
+		}
+		sealer, err := newKeySealer(kek)
+		if err != nil {
+			return nil, err
+		}
+		s.masterKey = masterKey
+		s.sealer = sealer
+	} else {
+		logger.Printf("未配置SUN主密钥，不能为卡片开启NTAG 424 DNA防克隆校验")
+	}
+
+	return s, nil
+}
+
+// Enable 为卡片派生新密钥并开启SUN校验
+func (s *sunService) Enable(ctx context.Context, merchantID, cardID uuid.UUID) (*entities.SUNProvisioning, error) {
+	if s.sealer == nil {
+		return nil, ErrNotConfigured
+	}
+
+	record, err := s.repo.FindByMerchant(ctx, merchantID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := parseUID(record.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每次开启都使用新的密钥版本，轮换后旧密钥生成的URL无法通过校验
+	keyVersion := record.KeyVersion%maxKeyVersion + 1
+	metaReadKey, err := s.deriveKey(uid, ntag424.KeyNoSDMMetaRead, keyVersion)
+	if err != nil {
+		return nil, err
+	}
+	fileReadKey, err := s.deriveKey(uid, ntag424.KeyNoSDMFileRead, keyVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	sealedMeta, err := s.sealer.seal(cardID, ntag424.KeyNoSDMMetaRead, metaReadKey)
+	if err != nil {
+		return nil, err
+	}
+	sealedFile, err := s.sealer.seal(cardID, ntag424.KeyNoSDMFileRead, fileReadKey)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.repo.SaveKeys(ctx, merchantID, cardID, keyVersion, sealedMeta, sealedFile)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Printf("卡片 %s 已开启SUN校验，密钥版本: %d", cardID, keyVersion)
+
+	provisioning := &entities.SUNProvisioning{
+		SUNStatus:   *statusOf(saved),
+		MetaReadKey: strings.ToUpper(hex.EncodeToString(metaReadKey)),
+		FileReadKey: strings.ToUpper(hex.EncodeToString(fileReadKey)),
+	}
+
+	links, err := s.links.FindByNfcCardID(ctx, cardID)
+	if err != nil {
+		s.logger.Printf("获取卡片 %s 的短链接失败: %v", cardID, err)
+	}
+	if link := primaryLink(links); link != nil {
+		layout, err := ntag424.BuildSDMLayout(s.baseURL+"/"+link.Slug, s.piccParam, s.macParam)
+		if err != nil {
+			return nil, err
+		}
+		provisioning.Slug = link.Slug
+		provisioning.URL = layout.URL
+		provisioning.NDEFHex = layout.NDEFHex
+		provisioning.PICCDataOffset = layout.PICCDataOffset
+		provisioning.SDMMACInputOffset = layout.SDMMACInputOffset
+		provisioning.SDMMACOffset = layout.SDMMACOffset
+	}
+
+	s.changed(ctx, cardID, true, links)
+	return provisioning, nil
+}
+
+// Disable 关闭卡片的SUN校验
+func (s *sunService) Disable(ctx context.Context, merchantID, cardID uuid.UUID) error {
+	if err := s.repo.Disable(ctx, merchantID, cardID); err != nil {
+		return err
+	}
+	s.logger.Printf("卡片 %s 已关闭SUN校验", cardID)
+
+	links, err := s.links.FindByNfcCardID(ctx, cardID)
+	if err != nil {
+		s.logger.Printf("获取卡片 %s 的短链接失败: %v", cardID, err)
+	}
+	s.changed(ctx, cardID, false, links)
+	return nil
+}
+
+// Status 获取卡片的SUN状态
+func (s *sunService) Status(ctx context.Context, merchantID, cardID uuid.UUID) (*entities.SUNStatus, error) {
+	record, err := s.repo.FindByMerchant(ctx, merchantID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	return statusOf(record), nil
+}
+
+// Verify 校验一次读取：解密PICC数据、核对UID、校验MAC，最后原子地推进读计数器
+func (s *sunService) Verify(ctx context.Context, cardID uuid.UUID, tap *Tap) (*entities.SUNTap, error) {
+	keys, err := s.loadKeys(ctx, cardID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrCardNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !keys.enabled {
+		return nil, nil
+	}
+
+	if tap == nil || tap.PICCData == "" || tap.MAC == "" {
+		return nil, ErrTapRequired
+	}
+
+	encrypted, err := ntag424.ParseHex(tap.PICCData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: PICC数据不是有效的十六进制", ErrTapInvalid)
+	}
+	mac, err := ntag424.ParseHex(tap.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("%w: MAC不是有效的十六进制", ErrTapInvalid)
+	}
+
+	data, err := ntag424.DecryptPICCData(keys.metaReadKey, encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTapInvalid, err)
+	}
+	if !bytes.Equal(data.UID, keys.uid) {
+		s.logger.Printf("卡片 %s 的SUN数据UID不匹配: %s", cardID, data.UIDHex())
+		return nil, fmt.Errorf("%w: UID不匹配", ErrTapInvalid)
+	}
+	if !data.HasReadCounter {
+		return nil, fmt.Errorf("%w: 标签未开启读计数器镜像", ErrTapInvalid)
+	}
+	if err := ntag424.VerifySDMMAC(keys.fileReadKey, data.UID, data.ReadCounter, nil, mac); err != nil {
+		s.logger.Printf("卡片 %s 的SUN MAC校验失败, 计数器: %d", cardID, data.ReadCounter)
+		return nil, fmt.Errorf("%w: %v", ErrTapInvalid, err)
+	}
+
+	advanced, err := s.repo.AdvanceCounter(ctx, cardID, keys.keyVersion, int(data.ReadCounter))
+	if err != nil {
+		return nil, err
+	}
+	if !advanced {
+		s.logger.Printf("卡片 %s 的SUN读计数器重放或乱序: %d", cardID, data.ReadCounter)
+		return nil, ErrReplay
+	}
+
+	return &entities.SUNTap{
+		CardID:      cardID,
+		UID:         data.UIDHex(),
+		ReadCounter: int(data.ReadCounter),
+	}, nil
+}
+
+// TapFromQuery 从URL查询参数中读取SUN数据
+func (s *sunService) TapFromQuery(query url.Values) *Tap {
+	return &Tap{
+		PICCData: query.Get(s.piccParam),
+		MAC:      query.Get(s.macParam),
+	}
+}
+
+// InvalidateCard 使卡片密钥的本地缓存失效
+func (s *sunService) InvalidateCard(cardID uuid.UUID) {
+	s.cache.Delete(cardID)
+}
+
+// loadKeys 获取卡片解密后的密钥，未开启SUN的卡片同样缓存，避免每次重定向都查询数据库
+func (s *sunService) loadKeys(ctx context.Context, cardID uuid.UUID) (*cardKeys, error) {
+	if keys, ok := s.cache.Get(cardID); ok {
+		return keys, nil
+	}
+
+	record, err := s.repo.FindByCardID(ctx, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &cardKeys{enabled: record.Enabled}
+	if record.Enabled {
+		if s.sealer == nil {
+			return nil, ErrNotConfigured
+		}
+		if keys.uid, err = parseUID(record.UID); err != nil {
+			return nil, err
+		}
+		if keys.metaReadKey, err = s.sealer.open(cardID, ntag424.KeyNoSDMMetaRead, record.MetaReadKey); err != nil {
+			return nil, err
+		}
+		if keys.fileReadKey, err = s.sealer.open(cardID, ntag424.KeyNoSDMFileRead, record.FileReadKey); err != nil {
+			return nil, err
+		}
+		keys.keyVersion = record.KeyVersion
+	}
+
+	s.cache.Set(cardID, keys, s.cacheTTL)
+	return keys, nil
+}
+
+// deriveKey 由主密钥按卡片UID、密钥编号和密钥版本分散派生卡片密钥
+func (s *sunService) deriveKey(uid []byte, keyNo byte, keyVersion int) ([]byte, error) {
+	input := ntag424.DiversificationInput(uid, keyNo, byte(keyVersion), s.systemID)
+	return ntag424.DiversifyKey(s.masterKey, input)
+}
+
+// changed 卡片SUN设置变更后失效缓存、通知其他实例，并重新同步短链接在边缘KV中的状态
+func (s *sunService) changed(ctx context.Context, cardID uuid.UUID, enabled bool, links []*entities.ShortLink) {
+	s.InvalidateCard(cardID)
+
+	if s.producer != nil {
+		event := &CardSUNUpdatedEvent{ID: cardID, Enabled: enabled}
+		if err := s.producer.SendMessage(TopicCardEvents, TypeCardSUNUpdated, event); err != nil {
+			s.logger.Printf("发布卡片SUN变更事件失败: %v", err)
+		}
+	}
+
+	// 边缘Worker不校验SUN，开启后必须从KV中移除，由Worker回源到本服务
+	if s.edgeSync != nil {
+		for _, link := range links {
+			if err := s.edgeSync.EnqueueLink(ctx, link); err != nil {
+				s.logger.Printf("同步短链接 %s 到边缘失败: %v", link.Slug, err)
+			}
+		}
+	}
+}
+
+// statusOf 由密钥记录生成不含密钥的状态
+func statusOf(record *entities.SUNCardKeys) *entities.SUNStatus {
+	return &entities.SUNStatus{
+		CardID:      record.CardID,
+		UID:         record.UID,
+		Enabled:     record.Enabled,
+		KeyVersion:  record.KeyVersion,
+		ReadCounter: record.ReadCounter,
+		LastTapAt:   record.LastTapAt,
+	}
+}
+
+// primaryLink 选择写入标签的短链接：优先启用的默认链接，其次是最新的启用链接
+func primaryLink(links []*entities.ShortLink) *entities.ShortLink {
+	var fallback *entities.ShortLink
+	for _, link := range links {
+		if !link.Active {
+			continue
+		}
+		if link.IsDefault {
+			return link
+		}
+		if fallback == nil {
+			fallback = link
+		}
+	}
+	return fallback
+}
+
+// parseUID 解析卡片UID，允许使用冒号、横线或空格分隔
+func parseUID(uid string) ([]byte, error) {
+	cleaned := strings.NewReplacer(":", "", "-", "", " ", "").Replace(uid)
+	b, err := hex.DecodeString(cleaned)
+	if err != nil || len(b) != ntag424.UIDSize {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidUID, uid)
+	}
+	return b, nil
+}
@@ -0,0 +1,314 @@
+package sun
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/pkg/ntag424"
+
+	"github.com/google/uuid"
+)
+
+// fakeKeyStore 内存中的卡片SUN密钥，读计数器的推进规则与storage.SUNRepository一致
+type fakeKeyStore struct {
+	mu    sync.Mutex
+	cards map[uuid.UUID]*entities.SUNCardKeys
+}
+
+func (f *fakeKeyStore) find(cardID uuid.UUID) (*entities.SUNCardKeys, error) {
+	card, ok := f.cards[cardID]
+	if !ok {
+		return nil, repositories.ErrCardNotFound
+	}
+	copied := *card
+	return &copied, nil
+}
+
+func (f *fakeKeyStore) FindByCardID(ctx context.Context, cardID uuid.UUID) (*entities.SUNCardKeys, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.find(cardID)
+}
+
+func (f *fakeKeyStore) FindByMerchant(ctx context.Context, merchantID, cardID uuid.UUID) (*entities.SUNCardKeys, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	card, err := f.find(cardID)
+	if err != nil || card.MerchantID != merchantID {
+		return nil, repositories.ErrCardNotFound
+	}
+	return card, nil
+}
+
+func (f *fakeKeyStore) SaveKeys(ctx context.Context, merchantID, cardID uuid.UUID, keyVersion int, metaReadKey, fileReadKey []byte) (*entities.SUNCardKeys, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	card, ok := f.cards[cardID]
+	if !ok || card.MerchantID != merchantID {
+		return nil, repositories.ErrCardNotFound
+	}
+	card.Enabled = true
+	card.KeyVersion = keyVersion
+	card.MetaReadKey = metaReadKey
+	card.FileReadKey = fileReadKey
+	card.ReadCounter = nil
+	card.LastTapAt = nil
+	return f.find(cardID)
+}
+
+func (f *fakeKeyStore) Disable(ctx context.Context, merchantID, cardID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	card, ok := f.cards[cardID]
+	if !ok || card.MerchantID != merchantID {
+		return repositories.ErrCardNotFound
+	}
+	card.Enabled = false
+	card.MetaReadKey = nil
+	card.FileReadKey = nil
+	return nil
+}
+
+func (f *fakeKeyStore) AdvanceCounter(ctx context.Context, cardID uuid.UUID, keyVersion, counter int) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	card, ok := f.cards[cardID]
+	if !ok || !card.Enabled || card.KeyVersion != keyVersion {
+		return false, nil
+	}
+	if card.ReadCounter != nil && *card.ReadCounter >= counter {
+		return false, nil
+	}
+	now := time.Now()
+	card.ReadCounter = &counter
+	card.LastTapAt = &now
+	return true, nil
+}
+
+// fakeLinks 卡片没有短链接
+type fakeLinks struct{}
+
+func (fakeLinks) FindByNfcCardID(ctx context.Context, nfcCardID uuid.UUID) ([]*entities.ShortLink, error) {
+	return nil, nil
+}
+
+// testCard 已开启SUN的卡片及其写卡密钥
+type testCard struct {
+	id          uuid.UUID
+	uid         []byte
+	metaReadKey []byte
+	fileReadKey []byte
+}
+
+func newTestService(t *testing.T) (*sunService, *fakeKeyStore) {
+	t.Helper()
+	store := &fakeKeyStore{cards: map[uuid.UUID]*entities.SUNCardKeys{}}
+	service, err := NewSUNService(nil, nil, nil, nil, config.SUNConfig{
+		MasterKey:        "00112233445566778899aabbccddeeff",
+		KeyEncryptionKey: strings.Repeat("ab", 32),
+		SystemIdentifier: "test",
+	}, "https://s.example.com", log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("NewSUNService: %v", err)
+	}
+
+	s := service.(*sunService)
+	s.repo = store
+	s.links = fakeLinks{}
+	return s, store
+}
+
+// enableCard 添加卡片并开启SUN，返回写入标签的密钥
+func enableCard(t *testing.T, s *sunService, store *fakeKeyStore, uid string) *testCard {
+	t.Helper()
+	merchantID, cardID := uuid.New(), uuid.New()
+	store.cards[cardID] = &entities.SUNCardKeys{CardID: cardID, MerchantID: merchantID, UID: uid}
+
+	provisioning, err := s.Enable(context.Background(), merchantID, cardID)
+	if err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+
+	card := &testCard{id: cardID}
+	card.uid, _ = parseUID(uid)
+	card.metaReadKey, _ = hex.DecodeString(provisioning.MetaReadKey)
+	card.fileReadKey, _ = hex.DecodeString(provisioning.FileReadKey)
+	return card
+}
+
+// tap 模拟标签生成一次SUN数据
+func (c *testCard) tap(t *testing.T, uid []byte, counter uint32) *Tap {
+	t.Helper()
+	plain := make([]byte, ntag424.PICCDataSize)
+	plain[0] = 0xC7
+	pos := 1 + copy(plain[1:], uid)
+	plain[pos], plain[pos+1], plain[pos+2] = byte(counter), byte(counter>>8), byte(counter>>16)
+
+	block, err := aes.NewCipher(c.metaReadKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := make([]byte, ntag424.PICCDataSize)
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(encrypted, plain)
+
+	mac, err := ntag424.SDMMAC(c.fileReadKey, uid, counter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Tap{
+		PICCData: strings.ToUpper(hex.EncodeToString(encrypted)),
+		MAC:      strings.ToUpper(hex.EncodeToString(mac)),
+	}
+}
+
+func TestVerifyAcceptsIncreasingCounter(t *testing.T) {
+	s, store := newTestService(t)
+	card := enableCard(t, s, store, "04:DE:5F:1E:AC:C0:40")
+	ctx := context.Background()
+
+	for _, counter := range []uint32{1, 2, 10} {
+		result, err := s.Verify(ctx, card.id, card.tap(t, card.uid, counter))
+		if err != nil {
+			t.Fatalf("计数器%d: %v", counter, err)
+		}
+		if result.ReadCounter != int(counter) || result.UID != "04DE5F1EACC040" || result.CardID != card.id {
+			t.Errorf("计数器%d的结果为%+v", counter, result)
+		}
+	}
+
+	if got := *store.cards[card.id].ReadCounter; got != 10 {
+		t.Errorf("已接受的计数器为%d，期望10", got)
+	}
+}
+
+func TestVerifyRejectsReplayedCounter(t *testing.T) {
+	s, store := newTestService(t)
+	card := enableCard(t, s, store, "04DE5F1EACC040")
+	tap := card.tap(t, card.uid, 5)
+
+	if _, err := s.Verify(context.Background(), card.id, tap); err != nil {
+		t.Fatalf("首次校验: %v", err)
+	}
+	if _, err := s.Verify(context.Background(), card.id, tap); !errors.Is(err, ErrReplay) {
+		t.Errorf("重放的URL返回%v，期望ErrReplay", err)
+	}
+}
+
+func TestVerifyRejectsOutOfOrderCounter(t *testing.T) {
+	s, store := newTestService(t)
+	card := enableCard(t, s, store, "04DE5F1EACC040")
+	ctx := context.Background()
+
+	if _, err := s.Verify(ctx, card.id, card.tap(t, card.uid, 20)); err != nil {
+		t.Fatalf("计数器20: %v", err)
+	}
+	if _, err := s.Verify(ctx, card.id, card.tap(t, card.uid, 19)); !errors.Is(err, ErrReplay) {
+		t.Errorf("乱序到达的计数器返回%v，期望ErrReplay", err)
+	}
+	if got := *store.cards[card.id].ReadCounter; got != 20 {
+		t.Errorf("乱序请求改变了已接受的计数器: %d", got)
+	}
+}
+
+func TestVerifyRejectsBadMAC(t *testing.T) {
+	s, store := newTestService(t)
+	card := enableCard(t, s, store, "04DE5F1EACC040")
+	tap := card.tap(t, card.uid, 3)
+
+	mac, _ := hex.DecodeString(tap.MAC)
+	mac[0] ^= 0xFF
+	tap.MAC = hex.EncodeToString(mac)
+
+	if _, err := s.Verify(context.Background(), card.id, tap); !errors.Is(err, ErrTapInvalid) {
+		t.Errorf("MAC错误返回%v，期望ErrTapInvalid", err)
+	}
+	if store.cards[card.id].ReadCounter != nil {
+		t.Error("MAC校验失败不应推进计数器")
+	}
+}
+
+func TestVerifyRejectsUnknownUID(t *testing.T) {
+	s, store := newTestService(t)
+	card := enableCard(t, s, store, "04DE5F1EACC040")
+
+	// 其他标签的UID，即使MAC按该UID正确计算也不能通过
+	other, _ := hex.DecodeString("04AABBCCDDEEFF")
+	if _, err := s.Verify(context.Background(), card.id, card.tap(t, other, 1)); !errors.Is(err, ErrTapInvalid) {
+		t.Errorf("UID不匹配返回%v，期望ErrTapInvalid", err)
+	}
+	if store.cards[card.id].ReadCounter != nil {
+		t.Error("UID不匹配不应推进计数器")
+	}
+}
+
+func TestVerifyRejectsOtherCardKeys(t *testing.T) {
+	s, store := newTestService(t)
+	card := enableCard(t, s, store, "04DE5F1EACC040")
+	other := enableCard(t, s, store, "04AABBCCDDEEFF")
+
+	// 另一张卡片生成的SUN数据复制到本卡片的URL上
+	if _, err := s.Verify(context.Background(), card.id, other.tap(t, other.uid, 1)); !errors.Is(err, ErrTapInvalid) {
+		t.Errorf("其他卡片的SUN数据返回%v，期望ErrTapInvalid", err)
+	}
+}
+
+func TestVerifyUnknownOrDisabledCard(t *testing.T) {
+	s, store := newTestService(t)
+	ctx := context.Background()
+
+	if result, err := s.Verify(ctx, uuid.New(), &Tap{}); result != nil || err != nil {
+		t.Errorf("不存在的卡片返回(%v, %v)，期望不校验", result, err)
+	}
+
+	cardID := uuid.New()
+	store.cards[cardID] = &entities.SUNCardKeys{CardID: cardID, MerchantID: uuid.New(), UID: "04DE5F1EACC040"}
+	if result, err := s.Verify(ctx, cardID, &Tap{}); result != nil || err != nil {
+		t.Errorf("未开启SUN的卡片返回(%v, %v)，期望不校验", result, err)
+	}
+}
+
+func TestVerifyRequiresTap(t *testing.T) {
+	s, store := newTestService(t)
+	card := enableCard(t, s, store, "04DE5F1EACC040")
+
+	for _, tap := range []*Tap{nil, {}, {PICCData: "00"}} {
+		if _, err := s.Verify(context.Background(), card.id, tap); !errors.Is(err, ErrTapRequired) {
+			t.Errorf("缺少SUN数据%+v返回%v，期望ErrTapRequired", tap, err)
+		}
+	}
+	if _, err := s.Verify(context.Background(), card.id, &Tap{PICCData: "zz", MAC: "00"}); !errors.Is(err, ErrTapInvalid) {
+		t.Errorf("无效的十六进制返回%v，期望ErrTapInvalid", err)
+	}
+}
+
+func TestEnableRotatesKeys(t *testing.T) {
+	s, store := newTestService(t)
+	card := enableCard(t, s, store, "04DE5F1EACC040")
+	ctx := context.Background()
+
+	oldTap := card.tap(t, card.uid, 1)
+	record := store.cards[card.id]
+	if _, err := s.Enable(ctx, record.MerchantID, card.id); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	if store.cards[card.id].KeyVersion != 2 {
+		t.Errorf("密钥版本为%d，期望2", store.cards[card.id].KeyVersion)
+	}
+
+	// 轮换后旧密钥生成的URL失效
+	if _, err := s.Verify(ctx, card.id, oldTap); !errors.Is(err, ErrTapInvalid) {
+		t.Errorf("旧密钥的SUN数据返回%v，期望ErrTapInvalid", err)
+	}
+}
@@ -0,0 +1,31 @@
+package tagmanifest
+
+import (
+	"context"
+	"io"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/pkg/ndef"
+
+	"github.com/google/uuid"
+)
+
+// Service NFC标签写卡清单服务接口
+type Service interface {
+	// Build 为商户的一批卡片生成写卡清单，清单顺序与cardIDs一致
+	Build(ctx context.Context, merchantID uuid.UUID, cardIDs []uuid.UUID, opts *Options) (*entities.TagWriteManifest, error)
+	// WriteCSV 将写卡清单输出为CSV
+	WriteCSV(w io.Writer, manifest *entities.TagWriteManifest) error
+}
+
+// Options 写入标签的NDEF消息内容
+type Options struct {
+	// TagType 目标标签型号，为空时使用NTAG213
+	TagType ndef.TagType
+	// Text 可选的文本记录，支持{name}和{uid}占位符
+	Text string
+	// TextLang 文本记录的语言代码，为空时使用zh-CN
+	TextLang string
+	// AndroidPackage 可选的Android应用记录包名
+	AndroidPackage string
+}
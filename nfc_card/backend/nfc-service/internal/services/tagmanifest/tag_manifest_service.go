@@ -0,0 +1,227 @@
+package tagmanifest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/storage"
+	"nfc-service/pkg/ndef"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxBatchSize 单次生成清单的最大卡片数量
+	MaxBatchSize = 1000
+
+	defaultTextLang = "zh-CN"
+)
+
+var (
+	// ErrInvalidOptions 写卡参数无效
+	ErrInvalidOptions = errors.New("写卡参数无效")
+	// ErrTooManyCards 单次请求的卡片数量超过上限
+	ErrTooManyCards = fmt.Errorf("单次最多生成%d张卡片的写卡清单", MaxBatchSize)
+)
+
+// csvHeader 写卡清单CSV的列
+var csvHeader = []string{
+	"card_id", "uid", "name", "slug", "url", "ndef_hex", "tlv_hex",
+	"message_size", "tlv_size", "capacity", "fits", "smallest_tag", "error",
+}
+
+// tagManifestService 写卡清单服务的实现
+type tagManifestService struct {
+	cards   *repositories.NfcCardRepository
+	links   *storage.ShortlinkRepository
+	baseURL string
+	logger  *log.Logger
+}
+
+// NewTagManifestService 创建写卡清单服务，baseURL为短链接域名
+func NewTagManifestService(
+	cards *repositories.NfcCardRepository,
+	links *storage.ShortlinkRepository,
+	baseURL string,
+	logger *log.Logger,
+) Service {
+	return &tagManifestService{
+		cards:   cards,
+		links:   links,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		logger:  logger,
+	}
+}
+
+// Build 为商户的一批卡片生成写卡清单
+func (s *tagManifestService) Build(ctx context.Context, merchantID uuid.UUID, cardIDs []uuid.UUID, opts *Options) (*entities.TagWriteManifest, error) {
+	if len(cardIDs) == 0 {
+		return nil, fmt.Errorf("%w: 卡片ID不能为空", ErrInvalidOptions)
+	}
+	if len(cardIDs) > MaxBatchSize {
+		return nil, ErrTooManyCards
+	}
+
+	if opts == nil {
+		opts = &Options{}
+	}
+	tagType := opts.TagType
+	if tagType == "" {
+		tagType = ndef.TagNTAG213
+	}
+	if tagType.Capacity() == 0 {
+		return nil, fmt.Errorf("%w: 不支持的标签型号: %s", ErrInvalidOptions, tagType)
+	}
+	if opts.AndroidPackage != "" && !ndef.ValidAndroidPackage(opts.AndroidPackage) {
+		return nil, fmt.Errorf("%w: Android包名格式无效: %s", ErrInvalidOptions, opts.AndroidPackage)
+	}
+
+	s.logger.Printf("生成写卡清单: 商户=%s, 卡片数=%d, 标签=%s", merchantID, len(cardIDs), tagType)
+
+	cards, err := s.cards.FindByIDs(ctx, merchantID, cardIDs)
+	if err != nil {
+		return nil, err
+	}
+	cardsByID := make(map[uuid.UUID]*entities.NfcCard, len(cards))
+	for _, card := range cards {
+		cardsByID[card.ID] = card
+	}
+
+	linksByCard, err := s.links.FindPrimaryByNfcCardIDs(ctx, cardIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &entities.TagWriteManifest{
+		GeneratedAt: time.Now(),
+		TagType:     string(tagType),
+		Capacity:    tagType.Capacity(),
+		Items:       make([]*entities.TagWriteItem, 0, len(cardIDs)),
+	}
+
+	for _, id := range cardIDs {
+		item := &entities.TagWriteItem{CardID: id, Capacity: tagType.Capacity()}
+		manifest.Items = append(manifest.Items, item)
+		manifest.Total++
+
+		card, ok := cardsByID[id]
+		if !ok {
+			item.Error = "卡片不存在"
+			manifest.Failed++
+			continue
+		}
+		item.UID = card.UID
+		item.Name = card.Name
+
+		link, ok := linksByCard[id]
+		if !ok {
+			item.Error = "卡片没有可用的短链接"
+			manifest.Failed++
+			continue
+		}
+		item.Slug = link.Slug
+		item.URL = s.baseURL + "/" + link.Slug
+
+		if err := s.encode(item, card, tagType, opts); err != nil {
+			item.Error = err.Error()
+			manifest.Failed++
+			continue
+		}
+
+		if item.Fits {
+			manifest.Ready++
+		} else {
+			manifest.Oversize++
+		}
+	}
+
+	return manifest, nil
+}
+
+// encode 生成卡片的NDEF消息并检查标签容量
+func (s *tagManifestService) encode(item *entities.TagWriteItem, card *entities.NfcCard, tagType ndef.TagType, opts *Options) error {
+	uriRecord, err := ndef.NewURIRecord(item.URL)
+	if err != nil {
+		return err
+	}
+	records := []*ndef.Record{uriRecord}
+
+	if opts.Text != "" {
+		text := strings.NewReplacer("{name}", card.Name, "{uid}", card.UID).Replace(opts.Text)
+		lang := opts.TextLang
+		if lang == "" {
+			lang = defaultTextLang
+		}
+		textRecord, err := ndef.NewTextRecord(text, lang)
+		if err != nil {
+			return err
+		}
+		records = append(records, textRecord)
+	}
+
+	// AAR必须放在URI记录之后，否则部分Android设备不会按URI打开
+	if opts.AndroidPackage != "" {
+		aar, err := ndef.NewAndroidApplicationRecord(opts.AndroidPackage)
+		if err != nil {
+			return err
+		}
+		records = append(records, aar)
+	}
+
+	message, err := ndef.NewMessage(records...).Encode()
+	if err != nil {
+		return err
+	}
+
+	item.NDEFHex = strings.ToUpper(hex.EncodeToString(message))
+	item.TLVHex = strings.ToUpper(hex.EncodeToString(ndef.WrapTLV(message)))
+	item.MessageSize = len(message)
+	item.TLVSize = ndef.TLVSize(len(message))
+	item.Fits = tagType.Fits(len(message))
+	if smallest, ok := ndef.SmallestFit(len(message)); ok {
+		item.SmallestTag = string(smallest)
+	}
+	return nil
+}
+
+// WriteCSV 将写卡清单输出为CSV
+func (s *tagManifestService) WriteCSV(w io.Writer, manifest *entities.TagWriteManifest) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("写入CSV表头失败: %w", err)
+	}
+
+	for _, item := range manifest.Items {
+		record := []string{
+			item.CardID.String(),
+			item.UID,
+			item.Name,
+			item.Slug,
+			item.URL,
+			item.NDEFHex,
+			item.TLVHex,
+			strconv.Itoa(item.MessageSize),
+			strconv.Itoa(item.TLVSize),
+			strconv.Itoa(item.Capacity),
+			strconv.FormatBool(item.Fits),
+			item.SmallestTag,
+			item.Error,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("写入CSV失败: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,52 @@
+package tapguard
+
+import (
+	"context"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// Service 公开重定向接口的刷量防护服务接口
+// 在记录点击之前判断请求是否应被拒绝、只跳转不计数，或记为可疑点击
+type Service interface {
+	// Check 判断一次访问的处理方式，并累加被过滤的计数
+	Check(ctx context.Context, link *entities.ShortLink, req *Request) *Verdict
+	// GetSettings 获取商户生效的阈值，未自定义时返回默认值
+	GetSettings(ctx context.Context, merchantID uuid.UUID) (*entities.TapGuardSettings, error)
+	UpdateSettings(ctx context.Context, merchantID uuid.UUID, dto *entities.TapGuardSettingsDTO) (*entities.TapGuardSettings, error)
+	// ResetSettings 删除商户自定义的阈值，恢复为默认值
+	ResetSettings(ctx context.Context, merchantID uuid.UUID) (*entities.TapGuardSettings, error)
+	// FilterStats 获取商户在[from, to]内每天被过滤的碰卡次数，尚未写入数据库的计数不包含在内
+	FilterStats(ctx context.Context, merchantID uuid.UUID, from, to time.Time) (*entities.TapFilterStats, error)
+	Start()
+	Stop()
+}
+
+// Request 一次访问的请求信息
+type Request struct {
+	IP        string
+	UserAgent string
+	// DeviceID 访问者Cookie中的设备ID，首次访问时为空
+	DeviceID string
+	Now      time.Time
+}
+
+// Verdict 刷量防护的判断结果
+type Verdict struct {
+	// Blocked 同一IP请求过于频繁，应返回429且不跳转
+	Blocked    bool
+	RetryAfter time.Duration
+	// Record 是否记录点击事件
+	Record bool
+	// Count 是否计入短链接的点击次数
+	Count bool
+	// Suspicious 记录的点击事件是否标记为可疑
+	Suspicious bool
+	// Reason 被过滤的原因，正常计数时为空
+	Reason entities.TapFilterReason
+	// DeviceID 需要写入访问者Cookie的设备ID，为空时不需要写入
+	DeviceID string
+}
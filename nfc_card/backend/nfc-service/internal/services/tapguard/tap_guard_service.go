@@ -0,0 +1,383 @@
+package tapguard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/storage"
+	"nfc-service/pkg/cache"
+	"nfc-service/pkg/ratelimit"
+	"nfc-service/pkg/useragent"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultIPRatePerMinute        = 30
+	defaultIPBurst                = 10
+	defaultSlugRatePerMinute      = 120
+	defaultSlugBurst              = 30
+	defaultDuplicateWindowSeconds = 30
+	defaultTrackedKeys            = 100000
+	defaultSettingsCacheTTL       = time.Minute
+	defaultCounterFlushInterval   = 10 * time.Second
+	counterFlushTimeout           = 10 * time.Second
+	// settingsCacheSize 商户阈值缓存的最大条目数
+	settingsCacheSize = 10000
+	// maxStatsDays 过滤统计区间的最大天数
+	maxStatsDays = 366
+)
+
+// ErrInvalidRange 统计区间无效
+var ErrInvalidRange = errors.New("统计区间无效")
+
+// tapGuardService 刷量防护服务的实现
+// 令牌桶、去重记录和阈值缓存都保存在本实例内存中，多实例部署时各实例分别限流
+type tapGuardService struct {
+	repo     *storage.TapGuardRepository
+	defaults entities.TapGuardSettings
+	logger   *log.Logger
+
+	limiter     *ratelimit.Limiter
+	seen        *cache.LRU[string, struct{}]
+	settings    *cache.LRU[uuid.UUID, *entities.TapGuardSettings]
+	settingsTTL time.Duration
+
+	mu            sync.Mutex
+	pending       map[storage.TapFilterKey]int64
+	flushInterval time.Duration
+	stopOnce      sync.Once
+	done          chan struct{}
+	wg            sync.WaitGroup
+}
+
+// NewTapGuardService 创建刷量防护服务
+func NewTapGuardService(repo *storage.TapGuardRepository, cfg config.TapGuardConfig, logger *log.Logger) Service {
+	trackedKeys := cfg.TrackedKeys
+	if trackedKeys <= 0 {
+		trackedKeys = defaultTrackedKeys
+	}
+
+	settingsTTL := time.Duration(cfg.SettingsCacheTTLSeconds) * time.Second
+	if settingsTTL <= 0 {
+		settingsTTL = defaultSettingsCacheTTL
+	}
+
+	flushInterval := time.Duration(cfg.CounterFlushIntervalSeconds) * time.Second
+	if flushInterval <= 0 {
+		flushInterval = defaultCounterFlushInterval
+	}
+
+	return &tapGuardService{
+		repo:          repo,
+		defaults:      defaultSettings(cfg),
+		logger:        logger,
+		limiter:       ratelimit.NewLimiter(trackedKeys),
+		seen:          cache.NewLRU[string, struct{}](trackedKeys),
+		settings:      cache.NewLRU[uuid.UUID, *entities.TapGuardSettings](settingsCacheSize),
+		settingsTTL:   settingsTTL,
+		pending:       make(map[storage.TapFilterKey]int64),
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+	}
+}
+
+// defaultSettings 根据配置构建默认阈值，未配置的项使用内置默认值
+func defaultSettings(cfg config.TapGuardConfig) entities.TapGuardSettings {
+	settings := entities.TapGuardSettings{
+		Enabled:                true,
+		IPRatePerMinute:        cfg.IPRatePerMinute,
+		IPBurst:                cfg.IPBurst,
+		SlugRatePerMinute:      cfg.SlugRatePerMinute,
+		SlugBurst:              cfg.SlugBurst,
+		DuplicateWindowSeconds: cfg.DuplicateWindowSeconds,
+	}
+	if settings.IPRatePerMinute <= 0 {
+		settings.IPRatePerMinute = defaultIPRatePerMinute
+	}
+	if settings.IPBurst <= 0 {
+		settings.IPBurst = defaultIPBurst
+	}
+	if settings.SlugRatePerMinute <= 0 {
+		settings.SlugRatePerMinute = defaultSlugRatePerMinute
+	}
+	if settings.SlugBurst <= 0 {
+		settings.SlugBurst = defaultSlugBurst
+	}
+	if settings.DuplicateWindowSeconds < 0 {
+		settings.DuplicateWindowSeconds = defaultDuplicateWindowSeconds
+	}
+	return settings
+}
+
+// Check 判断一次访问的处理方式
+// 依次检查：IP限流（拒绝）、爬虫和链接预览（跳转不记录）、重复碰卡（跳转不记录）、短链接限流和自动化工具（记为可疑）
+func (s *tapGuardService) Check(ctx context.Context, link *entities.ShortLink, req *Request) *Verdict {
+	now := req.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	verdict := &Verdict{Record: true, Count: true}
+	deviceID := req.DeviceID
+	if _, err := uuid.Parse(deviceID); err != nil {
+		deviceID = ""
+	}
+
+	settings := s.effectiveSettings(ctx, link.TenantID)
+	if !settings.Enabled {
+		return verdict
+	}
+
+	ipKey := "ip:" + link.TenantID.String() + ":" + req.IP
+	if ok, wait := s.limiter.Allow(ipKey, settings.IPRatePerMinute, settings.IPBurst); !ok {
+		s.filtered(link.TenantID, now, entities.TapFilterRateLimited)
+		return &Verdict{Blocked: true, RetryAfter: wait, Reason: entities.TapFilterRateLimited}
+	}
+
+	ua := useragent.Parse(req.UserAgent)
+	if ua.IsBot {
+		s.filtered(link.TenantID, now, entities.TapFilterCrawler)
+		return &Verdict{Reason: entities.TapFilterCrawler}
+	}
+
+	// 首次访问的设备分配设备ID，之后用Cookie识别同一设备
+	if deviceID == "" {
+		verdict.DeviceID = uuid.NewString()
+	}
+
+	if settings.DuplicateWindowSeconds > 0 {
+		window := time.Duration(settings.DuplicateWindowSeconds) * time.Second
+		if s.duplicate(link, deviceID, verdict.DeviceID, req, window) {
+			s.filtered(link.TenantID, now, entities.TapFilterDuplicate)
+			verdict.Record = false
+			verdict.Count = false
+			verdict.Reason = entities.TapFilterDuplicate
+			return verdict
+		}
+	}
+
+	slugOK, _ := s.limiter.Allow("slug:"+link.Slug, settings.SlugRatePerMinute, settings.SlugBurst)
+	if !slugOK || ua.IsHeadless || strings.TrimSpace(req.UserAgent) == "" {
+		s.filtered(link.TenantID, now, entities.TapFilterSuspicious)
+		verdict.Count = false
+		verdict.Suspicious = true
+		verdict.Reason = entities.TapFilterSuspicious
+	}
+
+	return verdict
+}
+
+// duplicate 判断设备是否在去重时间内已经访问过该短链接，并记录本次访问
+// 带Cookie的设备按设备ID识别；没有Cookie的请求（首次访问或禁用了Cookie）按IP和User-Agent识别
+func (s *tapGuardService) duplicate(link *entities.ShortLink, deviceID, newDeviceID string, req *Request, window time.Duration) bool {
+	prefix := link.ID.String() + ":"
+	fingerprint := prefix + "f:" + fingerprintOf(req.IP, req.UserAgent)
+
+	if deviceID != "" {
+		key := prefix + "d:" + deviceID
+		if _, ok := s.seen.Get(key); ok {
+			return true
+		}
+		s.seen.Set(key, struct{}{}, window)
+		return false
+	}
+
+	if _, ok := s.seen.Get(fingerprint); ok {
+		return true
+	}
+	s.seen.Set(fingerprint, struct{}{}, window)
+	// 同时记录新分配的设备ID，带上Cookie的下一次访问也能识别为重复
+	s.seen.Set(prefix+"d:"+newDeviceID, struct{}{}, window)
+	return false
+}
+
+// fingerprintOf 根据IP和User-Agent计算设备指纹
+func fingerprintOf(ip, userAgent string) string {
+	sum := sha256.Sum256([]byte(ip + "|" + userAgent))
+	return hex.EncodeToString(sum[:8])
+}
+
+// effectiveSettings 获取商户生效的阈值，读取失败时使用默认值
+func (s *tapGuardService) effectiveSettings(ctx context.Context, merchantID uuid.UUID) *entities.TapGuardSettings {
+	if settings, ok := s.settings.Get(merchantID); ok {
+		return settings
+	}
+
+	settings, err := s.repo.FindSettings(ctx, merchantID)
+	if err != nil {
+		s.logger.Printf("获取商户 %s 的刷量防护阈值失败，使用默认值: %v", merchantID, err)
+		return s.defaultsFor(merchantID)
+	}
+	if settings == nil {
+		settings = s.defaultsFor(merchantID)
+	}
+	s.settings.Set(merchantID, settings, s.settingsTTL)
+	return settings
+}
+
+// defaultsFor 返回商户的默认阈值
+func (s *tapGuardService) defaultsFor(merchantID uuid.UUID) *entities.TapGuardSettings {
+	settings := s.defaults
+	settings.MerchantID = merchantID
+	return &settings
+}
+
+// filtered 累加一次被过滤的碰卡，计数按天定期写入数据库
+func (s *tapGuardService) filtered(merchantID uuid.UUID, at time.Time, reason entities.TapFilterReason) {
+	key := storage.TapFilterKey{
+		MerchantID: merchantID,
+		Date:       at.Format("2006-01-02"),
+		Reason:     reason,
+	}
+
+	s.mu.Lock()
+	s.pending[key]++
+	s.mu.Unlock()
+}
+
+// GetSettings 获取商户生效的阈值
+func (s *tapGuardService) GetSettings(ctx context.Context, merchantID uuid.UUID) (*entities.TapGuardSettings, error) {
+	settings, err := s.repo.FindSettings(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return s.defaultsFor(merchantID), nil
+	}
+	return settings, nil
+}
+
+// UpdateSettings 整体修改商户的阈值
+func (s *tapGuardService) UpdateSettings(ctx context.Context, merchantID uuid.UUID, dto *entities.TapGuardSettingsDTO) (*entities.TapGuardSettings, error) {
+	settings := &entities.TapGuardSettings{
+		MerchantID:             merchantID,
+		Enabled:                true,
+		IPRatePerMinute:        dto.IPRatePerMinute,
+		IPBurst:                dto.IPBurst,
+		SlugRatePerMinute:      dto.SlugRatePerMinute,
+		SlugBurst:              dto.SlugBurst,
+		DuplicateWindowSeconds: dto.DuplicateWindowSeconds,
+	}
+	if dto.Enabled != nil {
+		settings.Enabled = *dto.Enabled
+	}
+
+	saved, err := s.repo.UpsertSettings(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+	// 其他实例在缓存过期后生效
+	s.settings.Delete(merchantID)
+	s.logger.Printf("商户 %s 修改了刷量防护阈值: 启用=%t, IP=%d/分钟(突发%d), 短链接=%d/分钟(突发%d), 去重=%d秒",
+		merchantID, saved.Enabled, saved.IPRatePerMinute, saved.IPBurst, saved.SlugRatePerMinute, saved.SlugBurst, saved.DuplicateWindowSeconds)
+	return saved, nil
+}
+
+// ResetSettings 删除商户自定义的阈值
+func (s *tapGuardService) ResetSettings(ctx context.Context, merchantID uuid.UUID) (*entities.TapGuardSettings, error) {
+	if err := s.repo.DeleteSettings(ctx, merchantID); err != nil {
+		return nil, err
+	}
+	s.settings.Delete(merchantID)
+	s.logger.Printf("商户 %s 的刷量防护阈值已恢复为默认值", merchantID)
+	return s.defaultsFor(merchantID), nil
+}
+
+// FilterStats 获取商户在[from, to]内每天被过滤的碰卡次数
+func (s *tapGuardService) FilterStats(ctx context.Context, merchantID uuid.UUID, from, to time.Time) (*entities.TapFilterStats, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: 结束日期不能早于开始日期", ErrInvalidRange)
+	}
+	if to.Sub(from) > maxStatsDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: 统计区间不能超过%d天", ErrInvalidRange, maxStatsDays)
+	}
+
+	counts, err := s.repo.FilterCounts(ctx, merchantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &entities.TapFilterStats{
+		From:     from,
+		To:       to,
+		ByReason: make(map[entities.TapFilterReason]int64, len(entities.TapFilterReasons)),
+		ByDay:    counts,
+	}
+	for _, reason := range entities.TapFilterReasons {
+		stats.ByReason[reason] = 0
+	}
+	for _, count := range counts {
+		stats.ByReason[count.Reason] += count.Count
+		stats.Total += count.Count
+	}
+	if stats.ByDay == nil {
+		stats.ByDay = []*entities.TapFilterCount{}
+	}
+	return stats, nil
+}
+
+// Start 启动过滤计数的后台写入协程
+func (s *tapGuardService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.flush()
+			case <-s.done:
+				s.flush()
+				return
+			}
+		}
+	}()
+	s.logger.Printf("刷量防护已启动，默认阈值: IP=%d/分钟(突发%d), 短链接=%d/分钟(突发%d), 去重=%d秒",
+		s.defaults.IPRatePerMinute, s.defaults.IPBurst, s.defaults.SlugRatePerMinute, s.defaults.SlugBurst, s.defaults.DuplicateWindowSeconds)
+}
+
+// Stop 停止后台协程并写入剩余的计数
+func (s *tapGuardService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+}
+
+// flush 将累积的过滤计数批量写入数据库，失败时放回缓冲区等待下次写入
+func (s *tapGuardService) flush() {
+	s.mu.Lock()
+	if len(s.pending) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	batch := s.pending
+	s.pending = make(map[storage.TapFilterKey]int64, len(batch))
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), counterFlushTimeout)
+	defer cancel()
+
+	if err := s.repo.IncrementFilterCounts(ctx, batch); err != nil {
+		s.logger.Printf("写入过滤计数失败，将在下次重试: %v", err)
+
+		s.mu.Lock()
+		for key, count := range batch {
+			s.pending[key] += count
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,275 @@
+package transfers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+	"nfc-service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TopicCardEvents 卡片事件主题
+	TopicCardEvents = "card-events"
+	// TypeCardTransferred 卡片转让完成事件
+	TypeCardTransferred = "card.transferred"
+)
+
+var (
+	// ErrInvalidTransfer 转让参数无效
+	ErrInvalidTransfer = errors.New("卡片转让参数无效")
+	// ErrMerchantNotFound 目标商户不存在
+	ErrMerchantNotFound = errors.New("目标商户不存在")
+	// ErrTransferNotFound 转让不存在
+	ErrTransferNotFound = storage.ErrTransferNotFound
+	// ErrTransferPending 卡片已有待处理的转让
+	ErrTransferPending = storage.ErrTransferPending
+	// ErrTransferNotPending 转让已处理或已过期
+	ErrTransferNotPending = storage.ErrTransferNotPending
+	// ErrTransferForbidden 当前商户不能执行该操作
+	ErrTransferForbidden = storage.ErrTransferForbidden
+	// ErrTransferCardChanged 卡片已不属于发起转让的商户
+	ErrTransferCardChanged = storage.ErrTransferCardChanged
+	// ErrCardInCampaign 卡片正在参与营销活动
+	ErrCardInCampaign = storage.ErrCardInCampaign
+)
+
+// cardTransferService 卡片转让服务的实现
+type cardTransferService struct {
+	repo      *storage.CardTransferRepository
+	cards     *repositories.NfcCardRepository
+	merchants *storage.MerchantRepository
+	links     CardLinks
+	producer  KafkaProducer
+	expiry    time.Duration
+	logger    *log.Logger
+}
+
+// NewCardTransferService 创建卡片转让服务，producer为nil时不发布转让事件
+func NewCardTransferService(
+	repo *storage.CardTransferRepository,
+	cards *repositories.NfcCardRepository,
+	merchants *storage.MerchantRepository,
+	links CardLinks,
+	producer KafkaProducer,
+	cfg config.CardsConfig,
+	logger *log.Logger,
+) Service {
+	expiry := time.Duration(cfg.TransferExpiryHours) * time.Hour
+	if expiry <= 0 {
+		expiry = 7 * 24 * time.Hour
+	}
+
+	return &cardTransferService{
+		repo:      repo,
+		cards:     cards,
+		merchants: merchants,
+		links:     links,
+		producer:  producer,
+		expiry:    expiry,
+		logger:    logger,
+	}
+}
+
+// Initiate 原商户发起转让
+func (s *cardTransferService) Initiate(ctx context.Context, merchantID uuid.UUID, dto *entities.CreateCardTransferDTO, actorID *uuid.UUID) (*entities.CardTransfer, error) {
+	transfer, err := s.build(ctx, &merchantID, dto, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.Create(ctx, transfer)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Printf("商户 %s 发起了卡片 %s 到商户 %s 的转让 %s", merchantID, created.NfcCardID, created.ToMerchantID, created.ID)
+	return created, nil
+}
+
+// build 校验卡片和目标商户并生成待写入的转让，merchantID为nil时不校验卡片所属商户
+func (s *cardTransferService) build(ctx context.Context, merchantID *uuid.UUID, dto *entities.CreateCardTransferDTO, actorID *uuid.UUID) (*entities.CardTransfer, error) {
+	linkMode := dto.LinkMode
+	if linkMode == "" {
+		linkMode = entities.CardTransferMoveLinks
+	}
+	if linkMode != entities.CardTransferMoveLinks && linkMode != entities.CardTransferResetLinks {
+		return nil, fmt.Errorf("%w: 不支持的短链接处理方式: %s", ErrInvalidTransfer, linkMode)
+	}
+
+	card, err := s.cards.FindByID(ctx, dto.NfcCardID)
+	if err != nil {
+		return nil, err
+	}
+	if merchantID != nil && card.MerchantID != *merchantID {
+		return nil, repositories.ErrCardNotFound
+	}
+	if card.MerchantID == dto.ToMerchantID {
+		return nil, fmt.Errorf("%w: 不能转让给卡片当前所属的商户", ErrInvalidTransfer)
+	}
+
+	exists, err := s.merchants.Exists(ctx, dto.ToMerchantID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrMerchantNotFound
+	}
+
+	return &entities.CardTransfer{
+		NfcCardID:      card.ID,
+		CardUID:        card.UID,
+		FromMerchantID: card.MerchantID,
+		ToMerchantID:   dto.ToMerchantID,
+		LinkMode:       linkMode,
+		Note:           strings.TrimSpace(dto.Note),
+		InitiatedBy:    actorID,
+		ExpiresAt:      time.Now().Add(s.expiry),
+	}, nil
+}
+
+// Get 获取当前商户转出或转入的转让
+func (s *cardTransferService) Get(ctx context.Context, merchantID, id uuid.UUID) (*entities.CardTransfer, error) {
+	return s.repo.FindByID(ctx, &merchantID, id)
+}
+
+// List 分页获取当前商户转出或转入的转让
+func (s *cardTransferService) List(ctx context.Context, merchantID uuid.UUID, q *entities.CardTransferQuery) ([]*entities.CardTransfer, int, error) {
+	return s.repo.List(ctx, &merchantID, q)
+}
+
+// Accept 目标商户接受转让
+func (s *cardTransferService) Accept(ctx context.Context, merchantID, id uuid.UUID, actorID *uuid.UUID) (*entities.CardTransfer, error) {
+	result, err := s.repo.Accept(ctx, &merchantID, id, actorID)
+	if err != nil {
+		return nil, err
+	}
+	s.afterTransfer(ctx, result, actorID)
+	return result.Transfer, nil
+}
+
+// Reject 目标商户拒绝转让
+func (s *cardTransferService) Reject(ctx context.Context, merchantID, id uuid.UUID, actorID *uuid.UUID) (*entities.CardTransfer, error) {
+	transfer, err := s.repo.Close(ctx, merchantID, id, entities.CardTransferRejected, actorID)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Printf("商户 %s 拒绝了卡片转让 %s", merchantID, id)
+	return transfer, nil
+}
+
+// Cancel 原商户撤回转让
+func (s *cardTransferService) Cancel(ctx context.Context, merchantID, id uuid.UUID, actorID *uuid.UUID) (*entities.CardTransfer, error) {
+	transfer, err := s.repo.Close(ctx, merchantID, id, entities.CardTransferCancelled, actorID)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Printf("商户 %s 撤回了卡片转让 %s", merchantID, id)
+	return transfer, nil
+}
+
+// ListAll 管理员分页获取全部商户的转让
+func (s *cardTransferService) ListAll(ctx context.Context, q *entities.CardTransferQuery) ([]*entities.CardTransfer, int, error) {
+	return s.repo.List(ctx, nil, q)
+}
+
+// ForceAccept 管理员代目标商户完成待处理的转让
+func (s *cardTransferService) ForceAccept(ctx context.Context, id uuid.UUID, actorID *uuid.UUID) (*entities.CardTransfer, error) {
+	result, err := s.repo.Accept(ctx, nil, id, actorID)
+	if err != nil {
+		return nil, err
+	}
+	s.afterTransfer(ctx, result, actorID)
+	return result.Transfer, nil
+}
+
+// ForceTransfer 管理员直接把卡片转让给目标商户
+func (s *cardTransferService) ForceTransfer(ctx context.Context, dto *entities.CreateCardTransferDTO, actorID *uuid.UUID) (*entities.CardTransfer, error) {
+	transfer, err := s.build(ctx, nil, dto, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.repo.ForceTransfer(ctx, transfer, actorID)
+	if err != nil {
+		return nil, err
+	}
+	s.afterTransfer(ctx, result, actorID)
+	return result.Transfer, nil
+}
+
+// afterTransfer 卡片迁移完成后刷新短链接缓存和边缘KV、按需创建新的默认短链接并发布转让事件
+// 卡片已经迁移，这些步骤失败只记录日志
+func (s *cardTransferService) afterTransfer(ctx context.Context, result *entities.CardTransferResult, actorID *uuid.UUID) {
+	transfer := result.Transfer
+	s.logger.Printf("卡片 %s 已从商户 %s 转让给商户 %s（转让 %s，短链接处理方式 %s）",
+		transfer.NfcCardID, transfer.FromMerchantID, transfer.ToMerchantID, transfer.ID, transfer.LinkMode)
+
+	if s.links != nil {
+		if err := s.links.RefreshLinks(ctx, result.ShortLinkIDs); err != nil {
+			s.logger.Printf("刷新转让卡片 %s 的短链接失败: %v", transfer.NfcCardID, err)
+		}
+
+		// 原短链接留在原商户，为目标商户创建新的默认短链接，卡片需要重新写入
+		if transfer.LinkMode == entities.CardTransferResetLinks {
+			s.createDefaultLink(ctx, transfer)
+		}
+	}
+
+	s.publish(transfer, actorID)
+}
+
+// createDefaultLink 为转入的卡片创建指向卡片落地页的默认短链接
+func (s *cardTransferService) createDefaultLink(ctx context.Context, transfer *entities.CardTransfer) {
+	title := transfer.CardUID
+	if card, err := s.cards.FindByID(ctx, transfer.NfcCardID); err == nil && card.Name != "" {
+		title = card.Name
+	}
+
+	link, err := s.links.Create(ctx, &entities.CreateShortLinkDTO{
+		TenantID:  transfer.ToMerchantID,
+		NfcCardID: transfer.NfcCardID,
+		Title:     title + " - 默认链接",
+		TargetURL: "/nfc-landing/" + transfer.CardUID,
+		IsDefault: true,
+	})
+	if err != nil {
+		s.logger.Printf("为转让的卡片 %s 创建默认短链接失败: %v", transfer.NfcCardID, err)
+		return
+	}
+	s.logger.Printf("已为转让的卡片 %s 创建默认短链接 %s", transfer.NfcCardID, link.Slug)
+}
+
+// publish 发布卡片转让完成事件
+func (s *cardTransferService) publish(transfer *entities.CardTransfer, actorID *uuid.UUID) {
+	if s.producer == nil {
+		return
+	}
+
+	occurredAt := time.Now()
+	if transfer.RespondedAt != nil {
+		occurredAt = *transfer.RespondedAt
+	}
+	event := &CardTransferredEvent{
+		ID:             transfer.NfcCardID,
+		CardID:         transfer.NfcCardID,
+		UID:            transfer.CardUID,
+		TransferID:     transfer.ID,
+		FromMerchantID: transfer.FromMerchantID,
+		ToMerchantID:   transfer.ToMerchantID,
+		LinkMode:       transfer.LinkMode,
+		AdminOverride:  transfer.AdminOverride,
+		ActorID:        actorID,
+		OccurredAt:     occurredAt,
+	}
+	if err := s.producer.SendMessage(TopicCardEvents, TypeCardTransferred, event); err != nil {
+		s.logger.Printf("发布卡片转让事件失败: %v", err)
+	}
+}
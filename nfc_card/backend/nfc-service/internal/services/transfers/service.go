@@ -0,0 +1,60 @@
+package transfers
+
+import (
+	"context"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// Service 卡片转让服务接口
+// 原商户发起转让，目标商户在有效期内接受后卡片归属目标商户；卡片的点击和事件历史仍保留在原商户名下
+type Service interface {
+	// Initiate 原商户发起转让
+	Initiate(ctx context.Context, merchantID uuid.UUID, dto *entities.CreateCardTransferDTO, actorID *uuid.UUID) (*entities.CardTransfer, error)
+	// Get 获取当前商户转出或转入的转让
+	Get(ctx context.Context, merchantID, id uuid.UUID) (*entities.CardTransfer, error)
+	// List 分页获取当前商户转出或转入的转让
+	List(ctx context.Context, merchantID uuid.UUID, q *entities.CardTransferQuery) ([]*entities.CardTransfer, int, error)
+	// Accept 目标商户接受转让，卡片立即迁移到目标商户
+	Accept(ctx context.Context, merchantID, id uuid.UUID, actorID *uuid.UUID) (*entities.CardTransfer, error)
+	// Reject 目标商户拒绝转让
+	Reject(ctx context.Context, merchantID, id uuid.UUID, actorID *uuid.UUID) (*entities.CardTransfer, error)
+	// Cancel 原商户撤回转让
+	Cancel(ctx context.Context, merchantID, id uuid.UUID, actorID *uuid.UUID) (*entities.CardTransfer, error)
+	// ListAll 管理员分页获取全部商户的转让
+	ListAll(ctx context.Context, q *entities.CardTransferQuery) ([]*entities.CardTransfer, int, error)
+	// ForceAccept 管理员代目标商户完成待处理的转让
+	ForceAccept(ctx context.Context, id uuid.UUID, actorID *uuid.UUID) (*entities.CardTransfer, error)
+	// ForceTransfer 管理员直接把卡片转让给目标商户，不需要双方确认
+	ForceTransfer(ctx context.Context, dto *entities.CreateCardTransferDTO, actorID *uuid.UUID) (*entities.CardTransfer, error)
+}
+
+// CardLinks 转让后刷新迁移的短链接并为目标商户创建默认短链接，由shortlinks.Service实现
+type CardLinks interface {
+	RefreshLinks(ctx context.Context, ids []uuid.UUID) error
+	Create(ctx context.Context, link *entities.CreateShortLinkDTO) (*entities.ShortLink, error)
+}
+
+// KafkaProducer Kafka生产者接口
+type KafkaProducer interface {
+	// SendMessage 发送消息到指定主题
+	SendMessage(topic string, messageType string, data interface{}) error
+}
+
+// CardTransferredEvent 卡片转让完成事件
+// card_id与id相同，与卡片生命周期事件保持一致
+type CardTransferredEvent struct {
+	ID             uuid.UUID                     `json:"id"`
+	CardID         uuid.UUID                     `json:"card_id"`
+	UID            string                        `json:"uid"`
+	TransferID     uuid.UUID                     `json:"transfer_id"`
+	FromMerchantID uuid.UUID                     `json:"from_merchant_id"`
+	ToMerchantID   uuid.UUID                     `json:"to_merchant_id"`
+	LinkMode       entities.CardTransferLinkMode `json:"link_mode"`
+	AdminOverride  bool                          `json:"admin_override"`
+	ActorID        *uuid.UUID                    `json:"actor_id,omitempty"`
+	OccurredAt     time.Time                     `json:"occurred_at"`
+}
@@ -0,0 +1,189 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"nfc-service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// 投递请求头
+const (
+	// HeaderEventID 事件ID，重试和重新投递时不变，接收端据此去重
+	HeaderEventID = "X-Webhook-Id"
+	// HeaderDeliveryID 投递记录ID
+	HeaderDeliveryID = "X-Webhook-Delivery"
+	// HeaderEventType 事件类型
+	HeaderEventType = "X-Webhook-Event"
+	// HeaderTimestamp 签名时间，Unix秒
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature 签名，格式为sha256=<十六进制HMAC>
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	// userAgent 投递请求的User-Agent
+	userAgent = "NFC-Card-Webhooks/1.0"
+	// maxResponseBody 投递记录中保存的响应内容长度上限
+	maxResponseBody = 1024
+)
+
+// errPrivateTarget 接收端解析到内网或本机地址
+var errPrivateTarget = errors.New("不允许投递到内网地址")
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "." + body)，返回请求头X-Webhook-Signature的值
+// 接收端应使用相同方式计算并以常量时间比较，同时拒绝时间戳偏差过大的请求以防重放
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliveryRequest 一次投递请求
+type deliveryRequest struct {
+	URL        string
+	Secret     string
+	EventID    uuid.UUID
+	DeliveryID uuid.UUID
+	EventType  string
+	Body       []byte
+}
+
+// client 投递HTTP客户端
+type client struct {
+	http *http.Client
+}
+
+// newClient 创建投递客户端，allowPrivate为false时拒绝连接内网、本机和链路本地地址
+// 不跟随重定向，3xx视为投递失败
+func newClient(timeout time.Duration, allowPrivate bool) *client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		// 在建立连接时检查实际解析到的地址，避免DNS重绑定绕过注册时的检查
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return fmt.Errorf("%w: %s", errPrivateTarget, host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &client{
+		http: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// isPrivateIP 判断是否为内网、本机、链路本地或组播地址
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// deliver 签名并发送事件，接收端返回2xx视为成功，其他情况在结果的Error中说明原因
+func (c *client) deliver(ctx context.Context, req *deliveryRequest) *storage.WebhookAttempt {
+	start := time.Now()
+	attempt := &storage.WebhookAttempt{}
+	defer func() {
+		attempt.Duration = time.Since(start)
+	}()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		attempt.Error = fmt.Sprintf("创建请求失败: %v", err)
+		return attempt
+	}
+
+	timestamp := time.Now().Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", userAgent)
+	httpReq.Header.Set(HeaderEventID, req.EventID.String())
+	httpReq.Header.Set(HeaderDeliveryID, req.DeliveryID.String())
+	httpReq.Header.Set(HeaderEventType, req.EventType)
+	httpReq.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	httpReq.Header.Set(HeaderSignature, Sign(req.Secret, timestamp, req.Body))
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		attempt.Error = fmt.Sprintf("请求失败: %v", err)
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	status := resp.StatusCode
+	attempt.StatusCode = &status
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	// 读完剩余内容以便复用连接
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	attempt.Body = strings.ToValidUTF8(string(body), "")
+
+	if status < 200 || status >= 300 {
+		attempt.Error = fmt.Sprintf("接收端返回状态码%d", status)
+	}
+	return attempt
+}
+
+// validateURL 校验接收端地址，allowPrivate为false时只允许HTTPS且不能是内网地址或本机
+// 域名在投递时还会检查实际解析到的地址
+func validateURL(raw string, allowPrivate bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: 接收端地址无效", ErrInvalidWebhook)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: 接收端地址不能包含用户名和密码", ErrInvalidWebhook)
+	}
+
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !allowPrivate {
+			return fmt.Errorf("%w: 接收端必须使用HTTPS", ErrInvalidWebhook)
+		}
+	default:
+		return fmt.Errorf("%w: 接收端必须使用HTTPS", ErrInvalidWebhook)
+	}
+
+	if allowPrivate {
+		return nil
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, errPrivateTarget)
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivateIP(ip) {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, errPrivateTarget)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// Service 商户Webhook服务接口
+// 事件从Kafka消费后写入投递记录，由后台投递协程签名后POST到商户的接收端，失败时按指数退避重试
+type Service interface {
+	// CreateEndpoint 注册接收端，返回的记录包含签名密钥，之后不再返回
+	CreateEndpoint(ctx context.Context, merchantID uuid.UUID, dto *entities.CreateWebhookEndpointDTO) (*entities.WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context, merchantID uuid.UUID) ([]*entities.WebhookEndpoint, error)
+	GetEndpoint(ctx context.Context, merchantID, id uuid.UUID) (*entities.WebhookEndpoint, error)
+	UpdateEndpoint(ctx context.Context, merchantID, id uuid.UUID, dto *entities.UpdateWebhookEndpointDTO) (*entities.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, merchantID, id uuid.UUID) error
+	// RotateSecret 更换签名密钥，返回的记录包含新密钥
+	RotateSecret(ctx context.Context, merchantID, id uuid.UUID) (*entities.WebhookEndpoint, error)
+	// Ping 立即向接收端投递一条webhook.ping测试事件并返回投递结果，不影响接收端的连续失败次数
+	Ping(ctx context.Context, merchantID, id uuid.UUID) (*entities.WebhookDelivery, error)
+	// ListDeliveries 分页获取接收端的投递记录，status为空时返回全部状态
+	ListDeliveries(ctx context.Context, merchantID, endpointID uuid.UUID, status entities.WebhookDeliveryStatus, page, pageSize int) ([]*entities.WebhookDelivery, int, error)
+	GetDelivery(ctx context.Context, merchantID, endpointID, id uuid.UUID) (*entities.WebhookDelivery, error)
+	// Redeliver 以相同的事件ID和内容重新投递
+	Redeliver(ctx context.Context, merchantID, endpointID, id uuid.UUID) (*entities.WebhookDelivery, error)
+	// EventTypes 返回可订阅的事件类型
+	EventTypes() []EventType
+	// Dispatch 为Kafka消息生成投递记录，不支持订阅的消息类型直接忽略，返回生成的投递数量
+	Dispatch(ctx context.Context, topic, msgType string, data []byte) (int, error)
+	// Start 启动投递协程
+	Start()
+	// Stop 停止投递协程，等待进行中的投递完成
+	Stop()
+}
+
+// store 接收端和投递记录的存储，由storage.WebhookRepository实现
+type store interface {
+	CountEndpoints(ctx context.Context, merchantID uuid.UUID) (int, error)
+	CreateEndpoint(ctx context.Context, endpoint *entities.WebhookEndpoint) (*entities.WebhookEndpoint, error)
+	FindEndpoint(ctx context.Context, merchantID, id uuid.UUID, withSecret bool) (*entities.WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context, merchantID uuid.UUID) ([]*entities.WebhookEndpoint, error)
+	UpdateEndpoint(ctx context.Context, merchantID, id uuid.UUID, dto *entities.UpdateWebhookEndpointDTO) (*entities.WebhookEndpoint, error)
+	RotateSecret(ctx context.Context, merchantID, id uuid.UUID, secret string) (*entities.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, merchantID, id uuid.UUID) error
+	Enqueue(ctx context.Context, merchantIDs []uuid.UUID, eventID uuid.UUID, eventType string, payload []byte) (int, error)
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*storage.ClaimedWebhookDelivery, error)
+	RecordSuccess(ctx context.Context, delivery *storage.ClaimedWebhookDelivery, attempt *storage.WebhookAttempt) error
+	RecordFailure(ctx context.Context, delivery *storage.ClaimedWebhookDelivery, attempt *storage.WebhookAttempt, retryAt *time.Time, disableAfter int) (bool, error)
+	RecordPing(ctx context.Context, endpoint *entities.WebhookEndpoint, eventID uuid.UUID, eventType string, payload []byte, attempt *storage.WebhookAttempt) (*entities.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, merchantID, endpointID uuid.UUID, status entities.WebhookDeliveryStatus, page, pageSize int) ([]*entities.WebhookDelivery, int, error)
+	FindDelivery(ctx context.Context, merchantID, endpointID, id uuid.UUID) (*entities.WebhookDelivery, error)
+	Redeliver(ctx context.Context, merchantID, endpointID, id uuid.UUID) (*entities.WebhookDelivery, error)
+	PurgeDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EventType 可订阅的事件类型
+type EventType struct {
+	Type        string `json:"type"`
+	Topic       string `json:"topic"`
+	Description string `json:"description"`
+}
+
+// 事件来源主题
+const (
+	TopicCardEvents    = "card-events"
+	TopicPublishEvents = "publish-events"
+	TopicStatsEvents   = "stats-events"
+)
+
+// TypePing 测试投递的事件类型
+const TypePing = "webhook.ping"
+
+// eventTypes 可订阅的事件，类型名与Kafka消息类型一致
+// 短链接变更等内部事件不对商户开放
+var eventTypes = []EventType{
+	{Type: "shortlink.clicked", Topic: TopicStatsEvents, Description: "碰卡或扫码访问短链接，不包含刷量防护判断为可疑的访问"},
+	{Type: "card.activated", Topic: TopicCardEvents, Description: "卡片激活"},
+	{Type: "card.bound", Topic: TopicCardEvents, Description: "卡片绑定用户"},
+	{Type: "card.unbound", Topic: TopicCardEvents, Description: "卡片解除绑定"},
+	{Type: "card.deactivated", Topic: TopicCardEvents, Description: "卡片停用"},
+	{Type: "card.reactivated", Topic: TopicCardEvents, Description: "卡片重新激活"},
+	{Type: "card.expired", Topic: TopicCardEvents, Description: "卡片过期"},
+	{Type: "card.transferred", Topic: TopicCardEvents, Description: "卡片转让完成，原商户和目标商户都会收到"},
+	{Type: "campaign.activated", Topic: TopicCardEvents, Description: "营销活动开始"},
+	{Type: "campaign.ended", Topic: TopicCardEvents, Description: "营销活动结束"},
+	{Type: "publish_job.created", Topic: TopicPublishEvents, Description: "视频发布任务创建"},
+	{Type: "publish_job.completed", Topic: TopicPublishEvents, Description: "视频发布任务完成，status为completed或failed"},
+}
+
+// merchantKeys 事件数据中标识所属商户的字段，各服务的事件命名不统一
+var merchantKeys = []string{"merchant_id", "merchantId", "tenantId", "from_merchant_id", "to_merchant_id"}
@@ -0,0 +1,513 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	mathrand "math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// secretPrefix 签名密钥前缀，便于商户识别
+	secretPrefix = "whsec_"
+	// maxEventTypes 单个接收端最多订阅的事件类型数量
+	maxEventTypes = 50
+	// purgeInterval 清理过期投递记录的间隔
+	purgeInterval = time.Hour
+)
+
+var (
+	// ErrInvalidWebhook 接收端参数无效
+	ErrInvalidWebhook = errors.New("Webhook接收端参数无效")
+	// ErrTooManyEndpoints 接收端数量超过上限
+	ErrTooManyEndpoints = errors.New("Webhook接收端数量已达上限")
+	// ErrEndpointDisabled 接收端已停用
+	ErrEndpointDisabled = errors.New("Webhook接收端已停用，请先启用")
+	// ErrWebhookNotFound 接收端不存在
+	ErrWebhookNotFound = storage.ErrWebhookNotFound
+	// ErrDeliveryNotFound 投递记录不存在
+	ErrDeliveryNotFound = storage.ErrWebhookDeliveryNotFound
+)
+
+// eventNamespace 由Kafka消息内容派生事件ID的命名空间
+var eventNamespace = uuid.MustParse("6f0b9a52-3d1e-4c8a-9b7e-2a4d5c6e7f80")
+
+// webhookService 商户Webhook服务的实现
+type webhookService struct {
+	repo   store
+	client *client
+	cfg    config.WebhooksConfig
+	types  map[string]EventType
+	logger *log.Logger
+
+	interval    time.Duration
+	lease       time.Duration
+	backoffBase time.Duration
+	backoffMax  time.Duration
+	retention   time.Duration
+	lastPurge   time.Time
+	// stopCtx 服务停止时取消，用于中断进行中的请求
+	stopCtx context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewWebhookService 创建商户Webhook服务
+func NewWebhookService(repo *storage.WebhookRepository, cfg config.WebhooksConfig, logger *log.Logger) Service {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.TimeoutSeconds <= 0 {
+		cfg.TimeoutSeconds = 10
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.MaxEndpoints <= 0 {
+		cfg.MaxEndpoints = 10
+	}
+
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	interval := time.Duration(cfg.PollIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	// 租约必须覆盖请求超时，否则慢响应的投递会被其他实例重复领取
+	lease := time.Duration(cfg.LeaseSeconds) * time.Second
+	if lease < 2*timeout {
+		lease = 2 * timeout
+	}
+	backoffBase := time.Duration(cfg.BackoffBaseSeconds) * time.Second
+	if backoffBase <= 0 {
+		backoffBase = 30 * time.Second
+	}
+	backoffMax := time.Duration(cfg.BackoffMaxSeconds) * time.Second
+	if backoffMax < backoffBase {
+		backoffMax = 6 * time.Hour
+	}
+	retention := time.Duration(cfg.RetentionDays) * 24 * time.Hour
+	if retention <= 0 {
+		retention = 30 * 24 * time.Hour
+	}
+
+	types := make(map[string]EventType, len(eventTypes))
+	for _, t := range eventTypes {
+		types[t.Type] = t
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &webhookService{
+		repo:        repo,
+		client:      newClient(timeout, cfg.AllowPrivateTargets),
+		cfg:         cfg,
+		types:       types,
+		logger:      logger,
+		interval:    interval,
+		lease:       lease,
+		backoffBase: backoffBase,
+		backoffMax:  backoffMax,
+		retention:   retention,
+		stopCtx:     ctx,
+		stop:        cancel,
+	}
+}
+
+// CreateEndpoint 注册接收端
+func (s *webhookService) CreateEndpoint(ctx context.Context, merchantID uuid.UUID, dto *entities.CreateWebhookEndpointDTO) (*entities.WebhookEndpoint, error) {
+	if err := validateURL(dto.URL, s.cfg.AllowPrivateTargets); err != nil {
+		return nil, err
+	}
+	eventTypes, err := s.normalizeEventTypes(dto.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.CountEndpoints(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= s.cfg.MaxEndpoints {
+		return nil, fmt.Errorf("%w: 每个商户最多%d个", ErrTooManyEndpoints, s.cfg.MaxEndpoints)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.CreateEndpoint(ctx, &entities.WebhookEndpoint{
+		MerchantID:  merchantID,
+		URL:         dto.URL,
+		Description: strings.TrimSpace(dto.Description),
+		EventTypes:  eventTypes,
+		Secret:      secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Printf("商户 %s 注册了Webhook接收端 %s: %s", merchantID, created.ID, created.URL)
+	return created, nil
+}
+
+// ListEndpoints 获取商户的全部接收端
+func (s *webhookService) ListEndpoints(ctx context.Context, merchantID uuid.UUID) ([]*entities.WebhookEndpoint, error) {
+	return s.repo.ListEndpoints(ctx, merchantID)
+}
+
+// GetEndpoint 获取接收端
+func (s *webhookService) GetEndpoint(ctx context.Context, merchantID, id uuid.UUID) (*entities.WebhookEndpoint, error) {
+	return s.repo.FindEndpoint(ctx, merchantID, id, false)
+}
+
+// UpdateEndpoint 修改接收端
+func (s *webhookService) UpdateEndpoint(ctx context.Context, merchantID, id uuid.UUID, dto *entities.UpdateWebhookEndpointDTO) (*entities.WebhookEndpoint, error) {
+	if dto.URL != nil {
+		if err := validateURL(*dto.URL, s.cfg.AllowPrivateTargets); err != nil {
+			return nil, err
+		}
+	}
+	if dto.Description != nil {
+		description := strings.TrimSpace(*dto.Description)
+		dto.Description = &description
+	}
+	if dto.EventTypes != nil {
+		eventTypes, err := s.normalizeEventTypes(*dto.EventTypes)
+		if err != nil {
+			return nil, err
+		}
+		dto.EventTypes = &eventTypes
+	}
+
+	updated, err := s.repo.UpdateEndpoint(ctx, merchantID, id, dto)
+	if err != nil {
+		return nil, err
+	}
+	if dto.Active != nil {
+		s.logger.Printf("商户 %s 将Webhook接收端 %s 的启用状态设为%t", merchantID, id, *dto.Active)
+	}
+	return updated, nil
+}
+
+// DeleteEndpoint 删除接收端
+func (s *webhookService) DeleteEndpoint(ctx context.Context, merchantID, id uuid.UUID) error {
+	if err := s.repo.DeleteEndpoint(ctx, merchantID, id); err != nil {
+		return err
+	}
+	s.logger.Printf("商户 %s 删除了Webhook接收端 %s", merchantID, id)
+	return nil
+}
+
+// RotateSecret 更换签名密钥，已排队的投递使用新密钥签名
+func (s *webhookService) RotateSecret(ctx context.Context, merchantID, id uuid.UUID) (*entities.WebhookEndpoint, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.RotateSecret(ctx, merchantID, id, secret)
+}
+
+// Ping 立即投递测试事件
+func (s *webhookService) Ping(ctx context.Context, merchantID, id uuid.UUID) (*entities.WebhookDelivery, error) {
+	endpoint, err := s.repo.FindEndpoint(ctx, merchantID, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID := uuid.New()
+	data, err := json.Marshal(map[string]interface{}{
+		"endpointId": endpoint.ID,
+		"merchantId": endpoint.MerchantID,
+		"message":    "测试事件，收到后返回2xx即表示接收端配置正确",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化测试事件失败: %w", err)
+	}
+	body, err := json.Marshal(&entities.WebhookEvent{ID: eventID, Type: TypePing, CreatedAt: time.Now(), Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("序列化测试事件失败: %w", err)
+	}
+
+	attempt := s.client.deliver(ctx, &deliveryRequest{
+		URL:        endpoint.URL,
+		Secret:     endpoint.Secret,
+		EventID:    eventID,
+		DeliveryID: eventID,
+		EventType:  TypePing,
+		Body:       body,
+	})
+	return s.repo.RecordPing(ctx, endpoint, eventID, TypePing, body, attempt)
+}
+
+// ListDeliveries 分页获取接收端的投递记录
+func (s *webhookService) ListDeliveries(ctx context.Context, merchantID, endpointID uuid.UUID, status entities.WebhookDeliveryStatus, page, pageSize int) ([]*entities.WebhookDelivery, int, error) {
+	return s.repo.ListDeliveries(ctx, merchantID, endpointID, status, page, pageSize)
+}
+
+// GetDelivery 获取投递记录
+func (s *webhookService) GetDelivery(ctx context.Context, merchantID, endpointID, id uuid.UUID) (*entities.WebhookDelivery, error) {
+	return s.repo.FindDelivery(ctx, merchantID, endpointID, id)
+}
+
+// Redeliver 重新投递，接收端停用时需要先启用
+func (s *webhookService) Redeliver(ctx context.Context, merchantID, endpointID, id uuid.UUID) (*entities.WebhookDelivery, error) {
+	endpoint, err := s.repo.FindEndpoint(ctx, merchantID, endpointID, false)
+	if err != nil {
+		return nil, err
+	}
+	if !endpoint.Active {
+		return nil, ErrEndpointDisabled
+	}
+
+	delivery, err := s.repo.Redeliver(ctx, merchantID, endpointID, id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Printf("商户 %s 重新投递了Webhook记录 %s", merchantID, id)
+	return delivery, nil
+}
+
+// EventTypes 返回可订阅的事件类型
+func (s *webhookService) EventTypes() []EventType {
+	return eventTypes
+}
+
+// normalizeEventTypes 校验并去重订阅的事件类型
+func (s *webhookService) normalizeEventTypes(types entities.WebhookEventTypes) (entities.WebhookEventTypes, error) {
+	if len(types) > maxEventTypes {
+		return nil, fmt.Errorf("%w: 订阅的事件类型不能超过%d个", ErrInvalidWebhook, maxEventTypes)
+	}
+
+	seen := make(map[string]bool, len(types))
+	normalized := entities.WebhookEventTypes{}
+	for _, t := range types {
+		t = strings.TrimSpace(t)
+		if _, ok := s.types[t]; !ok {
+			return nil, fmt.Errorf("%w: 不支持的事件类型: %s", ErrInvalidWebhook, t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			normalized = append(normalized, t)
+		}
+	}
+	return normalized, nil
+}
+
+// Dispatch 为Kafka消息生成投递记录
+func (s *webhookService) Dispatch(ctx context.Context, topic, msgType string, data []byte) (int, error) {
+	eventType, ok := s.types[msgType]
+	if !ok || eventType.Topic != topic {
+		return 0, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return 0, fmt.Errorf("解析%s事件失败: %w", msgType, err)
+	}
+
+	// 可疑点击不计入统计，也不推送给商户
+	if suspicious, ok := fields["suspicious"]; ok && string(suspicious) == "true" {
+		return 0, nil
+	}
+
+	merchantIDs := eventMerchants(fields)
+	if len(merchantIDs) == 0 {
+		s.logger.Printf("%s事件中没有商户ID，跳过Webhook投递", msgType)
+		return 0, nil
+	}
+
+	// 事件ID由消息内容派生，Kafka重复消费同一条消息时得到相同的ID
+	eventID := uuid.NewSHA1(eventNamespace, append([]byte(topic+"\n"+msgType+"\n"), data...))
+	body, err := json.Marshal(&entities.WebhookEvent{ID: eventID, Type: msgType, CreatedAt: time.Now(), Data: data})
+	if err != nil {
+		return 0, fmt.Errorf("序列化Webhook事件失败: %w", err)
+	}
+
+	return s.repo.Enqueue(ctx, merchantIDs, eventID, msgType, body)
+}
+
+// eventMerchants 从事件数据中读取所属商户，卡片转让事件同时属于原商户和目标商户
+func eventMerchants(fields map[string]json.RawMessage) []uuid.UUID {
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, key := range merchantKeys {
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil || id == uuid.Nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Start 启动投递协程
+func (s *webhookService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	s.logger.Printf("Webhook投递协程已启动，并发数: %d，轮询间隔: %s", s.cfg.Workers, s.interval)
+}
+
+// Stop 停止投递协程，进行中的请求会被取消并在租约到期后重新投递
+func (s *webhookService) Stop() {
+	s.stop()
+	s.wg.Wait()
+}
+
+// run 轮询到期的投递，领满一批时立即继续领取
+func (s *webhookService) run() {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			claimed := s.runOnce()
+			s.purge()
+			if claimed >= s.cfg.BatchSize {
+				timer.Reset(0)
+			} else {
+				timer.Reset(s.interval)
+			}
+		case <-s.stopCtx.Done():
+			return
+		}
+	}
+}
+
+// runOnce 领取一批到期的投递并发送，返回领取的数量
+func (s *webhookService) runOnce() int {
+	claimed, err := s.repo.ClaimDue(s.stopCtx, s.cfg.BatchSize, s.lease)
+	if err != nil {
+		if s.stopCtx.Err() == nil {
+			s.logger.Printf("领取Webhook投递任务失败: %v", err)
+		}
+		return 0
+	}
+
+	sem := make(chan struct{}, s.cfg.Workers)
+	var wg sync.WaitGroup
+	for _, delivery := range claimed {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(delivery *storage.ClaimedWebhookDelivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			s.attempt(delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(claimed)
+}
+
+// attempt 发送一次投递并记录结果，失败时按指数退避安排重试
+func (s *webhookService) attempt(delivery *storage.ClaimedWebhookDelivery) {
+	result := s.client.deliver(s.stopCtx, &deliveryRequest{
+		URL:        delivery.URL,
+		Secret:     delivery.Secret,
+		EventID:    delivery.EventID,
+		DeliveryID: delivery.ID,
+		EventType:  delivery.EventType,
+		Body:       delivery.Payload,
+	})
+	// 服务停止时取消的请求不记录结果，租约到期后重新投递
+	if s.stopCtx.Err() != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if result.Error == "" {
+		if err := s.repo.RecordSuccess(ctx, delivery, result); err != nil {
+			s.logger.Printf("记录Webhook投递 %s 的结果失败: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	var retryAt *time.Time
+	if delivery.Attempts < s.cfg.MaxAttempts {
+		next := time.Now().Add(s.backoff(delivery.Attempts))
+		retryAt = &next
+	}
+
+	disabled, err := s.repo.RecordFailure(ctx, delivery, result, retryAt, s.cfg.DisableAfterFailures)
+	if err != nil {
+		s.logger.Printf("记录Webhook投递 %s 的结果失败: %v", delivery.ID, err)
+		return
+	}
+	if retryAt == nil {
+		s.logger.Printf("Webhook投递 %s 已失败%d次，不再重试: %s", delivery.ID, delivery.Attempts, result.Error)
+	}
+	if disabled {
+		s.logger.Printf("商户 %s 的Webhook接收端 %s 连续投递失败，已自动停用", delivery.MerchantID, delivery.EndpointID)
+	}
+}
+
+// backoff 第attempts次失败后的等待时间：base * 2^(attempts-1)，不超过上限，并加入最多10%的随机抖动
+func (s *webhookService) backoff(attempts int) time.Duration {
+	delay := s.backoffBase
+	for i := 1; i < attempts && delay < s.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > s.backoffMax {
+		delay = s.backoffMax
+	}
+	return delay + time.Duration(mathrand.Int63n(int64(delay)/10+1))
+}
+
+// purge 定期清理超过保留期的投递记录
+func (s *webhookService) purge() {
+	if time.Since(s.lastPurge) < purgeInterval {
+		return
+	}
+	s.lastPurge = time.Now()
+
+	ctx, cancel := context.WithTimeout(s.stopCtx, time.Minute)
+	defer cancel()
+
+	removed, err := s.repo.PurgeDeliveries(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		s.logger.Printf("清理Webhook投递记录失败: %v", err)
+		return
+	}
+	if removed > 0 {
+		s.logger.Printf("已清理%d条过期的Webhook投递记录", removed)
+	}
+}
+
+// generateSecret 生成签名密钥
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成签名密钥失败: %w", err)
+	}
+	return secretPrefix + hex.EncodeToString(buf), nil
+}
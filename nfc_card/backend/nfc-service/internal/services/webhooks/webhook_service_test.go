@@ -0,0 +1,513 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"nfc-service/internal/config"
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// fakeDelivery 内存中的投递记录
+type fakeDelivery struct {
+	entities.WebhookDelivery
+	lockedUntil time.Time
+}
+
+// fakeStore 在内存中模拟storage.WebhookRepository的领取、记录结果和自动停用
+type fakeStore struct {
+	store
+
+	mu         sync.Mutex
+	endpoints  map[uuid.UUID]*entities.WebhookEndpoint
+	deliveries map[uuid.UUID]*fakeDelivery
+	// created 记录投递的写入顺序，领取时按写入顺序排序
+	created []uuid.UUID
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		endpoints:  make(map[uuid.UUID]*entities.WebhookEndpoint),
+		deliveries: make(map[uuid.UUID]*fakeDelivery),
+	}
+}
+
+func (s *fakeStore) addEndpoint(url string) *entities.WebhookEndpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoint := &entities.WebhookEndpoint{
+		ID:         uuid.New(),
+		MerchantID: uuid.New(),
+		URL:        url,
+		Active:     true,
+		Secret:     "whsec_test",
+	}
+	s.endpoints[endpoint.ID] = endpoint
+	return endpoint
+}
+
+func (s *fakeStore) addDelivery(endpoint *entities.WebhookEndpoint, eventType string) *fakeDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	eventID := uuid.New()
+	payload, _ := json.Marshal(&entities.WebhookEvent{ID: eventID, Type: eventType, Data: json.RawMessage(`{}`)})
+	return s.insert(&fakeDelivery{WebhookDelivery: entities.WebhookDelivery{
+		EndpointID:    endpoint.ID,
+		MerchantID:    endpoint.MerchantID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        entities.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+	}})
+}
+
+func (s *fakeStore) insert(d *fakeDelivery) *fakeDelivery {
+	d.ID = uuid.New()
+	d.CreatedAt = time.Now()
+	s.deliveries[d.ID] = d
+	s.created = append(s.created, d.ID)
+	return d
+}
+
+// delivery 返回投递记录的副本
+func (s *fakeStore) delivery(id uuid.UUID) fakeDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.deliveries[id]
+}
+
+// endpoint 返回接收端的副本
+func (s *fakeStore) endpoint(id uuid.UUID) entities.WebhookEndpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.endpoints[id]
+}
+
+// makeDue 让等待重试的投递立即到期
+func (s *fakeStore) makeDue(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[id].NextAttemptAt = time.Now()
+}
+
+func (s *fakeStore) FindEndpoint(ctx context.Context, merchantID, id uuid.UUID, withSecret bool) (*entities.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoint, ok := s.endpoints[id]
+	if !ok || endpoint.MerchantID != merchantID {
+		return nil, storage.ErrWebhookNotFound
+	}
+	found := *endpoint
+	if !withSecret {
+		found.Secret = ""
+	}
+	return &found, nil
+}
+
+func (s *fakeStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*storage.ClaimedWebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var claimed []*storage.ClaimedWebhookDelivery
+	for _, id := range s.created {
+		d := s.deliveries[id]
+		endpoint := s.endpoints[d.EndpointID]
+		if len(claimed) >= limit {
+			break
+		}
+		if d.Status != entities.WebhookDeliveryPending || d.NextAttemptAt.After(now) || d.lockedUntil.After(now) || !endpoint.Active {
+			continue
+		}
+		d.lockedUntil = now.Add(lease)
+		d.Attempts++
+		claimed = append(claimed, &storage.ClaimedWebhookDelivery{
+			ID:         d.ID,
+			EndpointID: d.EndpointID,
+			MerchantID: d.MerchantID,
+			EventID:    d.EventID,
+			EventType:  d.EventType,
+			Payload:    d.Payload,
+			Attempts:   d.Attempts,
+			URL:        endpoint.URL,
+			Secret:     endpoint.Secret,
+		})
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) RecordSuccess(ctx context.Context, delivery *storage.ClaimedWebhookDelivery, attempt *storage.WebhookAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[delivery.ID]
+	d.Status = entities.WebhookDeliverySucceeded
+	d.lockedUntil = time.Time{}
+	d.ResponseStatus = attempt.StatusCode
+	d.LastError = ""
+	s.endpoints[delivery.EndpointID].ConsecutiveFailures = 0
+	return nil
+}
+
+func (s *fakeStore) RecordFailure(ctx context.Context, delivery *storage.ClaimedWebhookDelivery, attempt *storage.WebhookAttempt, retryAt *time.Time, disableAfter int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[delivery.ID]
+	d.lockedUntil = time.Time{}
+	d.ResponseStatus = attempt.StatusCode
+	d.LastError = attempt.Error
+	if retryAt == nil {
+		d.Status = entities.WebhookDeliveryFailed
+	} else {
+		d.Status = entities.WebhookDeliveryPending
+		d.NextAttemptAt = *retryAt
+	}
+
+	endpoint := s.endpoints[delivery.EndpointID]
+	endpoint.ConsecutiveFailures++
+	if !endpoint.Active || disableAfter <= 0 || endpoint.ConsecutiveFailures < disableAfter {
+		return false, nil
+	}
+	endpoint.Active = false
+	endpoint.DisabledReason = "连续" + strconv.Itoa(endpoint.ConsecutiveFailures) + "次投递失败，已自动停用"
+	for _, other := range s.deliveries {
+		if other.EndpointID == endpoint.ID && other.Status == entities.WebhookDeliveryPending {
+			other.Status = entities.WebhookDeliveryFailed
+		}
+	}
+	return true, nil
+}
+
+func (s *fakeStore) RecordPing(ctx context.Context, endpoint *entities.WebhookEndpoint, eventID uuid.UUID, eventType string, payload []byte, attempt *storage.WebhookAttempt) (*entities.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := entities.WebhookDeliveryFailed
+	if attempt.Error == "" {
+		status = entities.WebhookDeliverySucceeded
+	}
+	d := s.insert(&fakeDelivery{WebhookDelivery: entities.WebhookDelivery{
+		EndpointID:     endpoint.ID,
+		MerchantID:     endpoint.MerchantID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Payload:        payload,
+		Status:         status,
+		Attempts:       1,
+		ResponseStatus: attempt.StatusCode,
+		LastError:      attempt.Error,
+	}})
+	delivery := d.WebhookDelivery
+	return &delivery, nil
+}
+
+func (s *fakeStore) Redeliver(ctx context.Context, merchantID, endpointID, id uuid.UUID) (*entities.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	original, ok := s.deliveries[id]
+	if !ok || original.EndpointID != endpointID || original.MerchantID != merchantID {
+		return nil, storage.ErrWebhookDeliveryNotFound
+	}
+	originalID := original.ID
+	d := s.insert(&fakeDelivery{WebhookDelivery: entities.WebhookDelivery{
+		EndpointID:    original.EndpointID,
+		MerchantID:    original.MerchantID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        entities.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+		RedeliveryOf:  &originalID,
+	}})
+	delivery := d.WebhookDelivery
+	return &delivery, nil
+}
+
+const (
+	testBackoffBase = time.Minute
+	testBackoffMax  = 10 * time.Minute
+)
+
+func newTestService(t *testing.T, cfg config.WebhooksConfig) (*webhookService, *fakeStore) {
+	t.Helper()
+	// httptest接收端监听在本机地址
+	cfg.AllowPrivateTargets = true
+	cfg.BackoffBaseSeconds = int(testBackoffBase / time.Second)
+	cfg.BackoffMaxSeconds = int(testBackoffMax / time.Second)
+
+	s := NewWebhookService(nil, cfg, log.New(io.Discard, "", 0)).(*webhookService)
+	fake := newFakeStore()
+	s.repo = fake
+	t.Cleanup(s.Stop)
+	return s, fake
+}
+
+// assertRetryAt 检查下次投递时间在第attempts次失败的退避区间内
+func assertRetryAt(t *testing.T, d fakeDelivery, attempts int, failedAt time.Time) {
+	t.Helper()
+	delay := testBackoffBase << (attempts - 1)
+	if delay > testBackoffMax {
+		delay = testBackoffMax
+	}
+	earliest := failedAt.Add(delay)
+	latest := time.Now().Add(delay + delay/10 + time.Second)
+	if d.NextAttemptAt.Before(earliest) || d.NextAttemptAt.After(latest) {
+		t.Errorf("第%d次失败后的下次投递时间%s，期望在[%s, %s]内",
+			attempts, d.NextAttemptAt.Format(time.RFC3339), earliest.Format(time.RFC3339), latest.Format(time.RFC3339))
+	}
+}
+
+func TestDeliverySucceeds(t *testing.T) {
+	r := newReceiver(t, http.StatusOK)
+	s, fake := newTestService(t, config.WebhooksConfig{})
+	endpoint := fake.addEndpoint(r.URL)
+	endpoint.ConsecutiveFailures = 2
+	d := fake.addDelivery(endpoint, "card.activated")
+
+	if claimed := s.runOnce(); claimed != 1 {
+		t.Fatalf("领取了%d条投递，期望1", claimed)
+	}
+
+	got := fake.delivery(d.ID)
+	if got.Status != entities.WebhookDeliverySucceeded || got.Attempts != 1 {
+		t.Errorf("投递状态%s，次数%d，期望succeeded和1", got.Status, got.Attempts)
+	}
+	if failures := fake.endpoint(endpoint.ID).ConsecutiveFailures; failures != 0 {
+		t.Errorf("投递成功后连续失败次数为%d，期望0", failures)
+	}
+
+	req := r.next(t)
+	if req.header.Get(HeaderEventID) != d.EventID.String() || req.header.Get(HeaderDeliveryID) != d.ID.String() {
+		t.Errorf("事件ID或投递ID请求头不正确: %v", req.header)
+	}
+	timestamp, _ := strconv.ParseInt(req.header.Get(HeaderTimestamp), 10, 64)
+	if req.header.Get(HeaderSignature) != Sign(endpoint.Secret, timestamp, req.body) {
+		t.Error("投递的签名与接收端计算的不一致")
+	}
+	if string(req.body) != string(d.Payload) {
+		t.Errorf("请求体%s，期望%s", req.body, d.Payload)
+	}
+}
+
+func TestDeliveryRetriesWithBackoff(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError)
+	s, fake := newTestService(t, config.WebhooksConfig{MaxAttempts: 3})
+	endpoint := fake.addEndpoint(r.URL)
+	d := fake.addDelivery(endpoint, "card.activated")
+
+	for attempts := 1; attempts < 3; attempts++ {
+		failedAt := time.Now()
+		if claimed := s.runOnce(); claimed != 1 {
+			t.Fatalf("第%d次领取了%d条投递，期望1", attempts, claimed)
+		}
+		got := fake.delivery(d.ID)
+		if got.Status != entities.WebhookDeliveryPending || got.Attempts != attempts {
+			t.Fatalf("第%d次失败后状态%s，次数%d，期望pending", attempts, got.Status, got.Attempts)
+		}
+		if got.ResponseStatus == nil || *got.ResponseStatus != http.StatusInternalServerError || got.LastError == "" {
+			t.Errorf("第%d次失败未记录状态码和错误", attempts)
+		}
+		assertRetryAt(t, got, attempts, failedAt)
+
+		// 未到重试时间不会被领取
+		if claimed := s.runOnce(); claimed != 0 {
+			t.Fatalf("未到期的投递被领取了%d条", claimed)
+		}
+		fake.makeDue(d.ID)
+	}
+
+	// 最后一次失败后不再重试
+	s.runOnce()
+	if got := fake.delivery(d.ID); got.Status != entities.WebhookDeliveryFailed || got.Attempts != 3 {
+		t.Errorf("投递次数耗尽后状态%s，次数%d，期望failed和3", got.Status, got.Attempts)
+	}
+	if n := r.received(); n != 3 {
+		t.Errorf("接收端收到%d个请求，期望3", n)
+	}
+
+	// 接收端恢复后成功投递
+	r.status.Store(http.StatusNoContent)
+	next := fake.addDelivery(endpoint, "card.bound")
+	s.runOnce()
+	if got := fake.delivery(next.ID); got.Status != entities.WebhookDeliverySucceeded {
+		t.Errorf("接收端返回204后状态%s，期望succeeded", got.Status)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	s, _ := newTestService(t, config.WebhooksConfig{})
+
+	tests := []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, testBackoffMax},
+		{20, testBackoffMax},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			delay := s.backoff(tt.attempts)
+			if delay < tt.delay || delay > tt.delay+tt.delay/10 {
+				t.Fatalf("第%d次失败的退避时间%s，期望在[%s, %s]内", tt.attempts, delay, tt.delay, tt.delay+tt.delay/10)
+			}
+		}
+	}
+}
+
+func TestEndpointDisabledAfterConsecutiveFailures(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable)
+	s, fake := newTestService(t, config.WebhooksConfig{BatchSize: 1, DisableAfterFailures: 3})
+	endpoint := fake.addEndpoint(r.URL)
+
+	var deliveries []*fakeDelivery
+	for i := 0; i < 5; i++ {
+		deliveries = append(deliveries, fake.addDelivery(endpoint, "card.activated"))
+	}
+
+	for i := 1; i <= 3; i++ {
+		if claimed := s.runOnce(); claimed != 1 {
+			t.Fatalf("第%d次领取了%d条投递，期望1", i, claimed)
+		}
+		if active := fake.endpoint(endpoint.ID).Active; active != (i < 3) {
+			t.Fatalf("第%d次连续失败后接收端启用状态为%v", i, active)
+		}
+	}
+
+	got := fake.endpoint(endpoint.ID)
+	if got.ConsecutiveFailures != 3 || got.DisabledReason == "" {
+		t.Errorf("连续失败次数%d，停用原因%q", got.ConsecutiveFailures, got.DisabledReason)
+	}
+	// 停用后待投递记录全部置为失败，也不再领取
+	for _, d := range deliveries {
+		if status := fake.delivery(d.ID).Status; status != entities.WebhookDeliveryFailed {
+			t.Errorf("投递%s的状态为%s，期望failed", d.ID, status)
+		}
+	}
+	fake.addDelivery(endpoint, "card.bound")
+	if claimed := s.runOnce(); claimed != 0 {
+		t.Errorf("停用的接收端被领取了%d条投递", claimed)
+	}
+	if n := r.received(); n != 3 {
+		t.Errorf("接收端收到%d个请求，期望3", n)
+	}
+}
+
+func TestEndpointNotDisabledWhenSuccessInterleaves(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError)
+	s, fake := newTestService(t, config.WebhooksConfig{BatchSize: 1, DisableAfterFailures: 2})
+	endpoint := fake.addEndpoint(r.URL)
+
+	for _, status := range []int{http.StatusInternalServerError, http.StatusOK, http.StatusInternalServerError} {
+		r.status.Store(int32(status))
+		fake.addDelivery(endpoint, "card.activated")
+		s.runOnce()
+	}
+
+	got := fake.endpoint(endpoint.ID)
+	if !got.Active || got.ConsecutiveFailures != 1 {
+		t.Errorf("成功投递后接收端启用状态%v，连续失败次数%d，期望启用和1", got.Active, got.ConsecutiveFailures)
+	}
+}
+
+func TestPing(t *testing.T) {
+	r := newReceiver(t, http.StatusOK)
+	s, fake := newTestService(t, config.WebhooksConfig{})
+	endpoint := fake.addEndpoint(r.URL)
+
+	delivery, err := s.Ping(context.Background(), endpoint.MerchantID, endpoint.ID)
+	if err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if delivery.Status != entities.WebhookDeliverySucceeded || delivery.EventType != TypePing {
+		t.Errorf("测试投递状态%s，类型%s", delivery.Status, delivery.EventType)
+	}
+
+	req := r.next(t)
+	if req.header.Get(HeaderEventType) != TypePing || req.header.Get(HeaderEventID) != delivery.EventID.String() {
+		t.Errorf("测试投递的请求头不正确: %v", req.header)
+	}
+	// 测试投递使用包含密钥的接收端记录签名
+	timestamp, _ := strconv.ParseInt(req.header.Get(HeaderTimestamp), 10, 64)
+	if req.header.Get(HeaderSignature) != Sign(endpoint.Secret, timestamp, req.body) {
+		t.Error("测试投递的签名与接收端计算的不一致")
+	}
+	var event entities.WebhookEvent
+	if err := json.Unmarshal(req.body, &event); err != nil || event.Type != TypePing {
+		t.Errorf("测试事件内容无效: %s", req.body)
+	}
+
+	// 接收端返回错误时记录失败但不计入连续失败次数
+	r.status.Store(http.StatusBadGateway)
+	delivery, err = s.Ping(context.Background(), endpoint.MerchantID, endpoint.ID)
+	if err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if delivery.Status != entities.WebhookDeliveryFailed || delivery.LastError == "" {
+		t.Errorf("测试投递状态%s，错误%q，期望failed", delivery.Status, delivery.LastError)
+	}
+	if failures := fake.endpoint(endpoint.ID).ConsecutiveFailures; failures != 0 {
+		t.Errorf("测试投递后连续失败次数为%d，期望0", failures)
+	}
+
+	if _, err := s.Ping(context.Background(), uuid.New(), endpoint.ID); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("其他商户的接收端返回%v，期望ErrWebhookNotFound", err)
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError)
+	s, fake := newTestService(t, config.WebhooksConfig{MaxAttempts: 1})
+	endpoint := fake.addEndpoint(r.URL)
+	original := fake.addDelivery(endpoint, "publish_job.completed")
+
+	s.runOnce()
+	if status := fake.delivery(original.ID).Status; status != entities.WebhookDeliveryFailed {
+		t.Fatalf("投递状态%s，期望failed", status)
+	}
+	first := r.next(t)
+
+	r.status.Store(http.StatusOK)
+	redelivery, err := s.Redeliver(context.Background(), endpoint.MerchantID, endpoint.ID, original.ID)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if redelivery.ID == original.ID || redelivery.RedeliveryOf == nil || *redelivery.RedeliveryOf != original.ID {
+		t.Errorf("重新投递应生成新记录并指向原记录")
+	}
+
+	s.runOnce()
+	if status := fake.delivery(redelivery.ID).Status; status != entities.WebhookDeliverySucceeded {
+		t.Errorf("重新投递状态%s，期望succeeded", status)
+	}
+	// 重新投递使用相同的事件ID和请求体，接收端据此去重
+	second := r.next(t)
+	if second.header.Get(HeaderEventID) != first.header.Get(HeaderEventID) || string(second.body) != string(first.body) {
+		t.Error("重新投递的事件ID或请求体与原投递不同")
+	}
+	if second.header.Get(HeaderDeliveryID) != redelivery.ID.String() {
+		t.Errorf("重新投递的投递ID为%s，期望%s", second.header.Get(HeaderDeliveryID), redelivery.ID)
+	}
+
+	if _, err := s.Redeliver(context.Background(), endpoint.MerchantID, endpoint.ID, uuid.New()); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("不存在的投递记录返回%v，期望ErrDeliveryNotFound", err)
+	}
+
+	fake.endpoints[endpoint.ID].Active = false
+	if _, err := s.Redeliver(context.Background(), endpoint.MerchantID, endpoint.ID, original.ID); !errors.Is(err, ErrEndpointDisabled) {
+		t.Errorf("停用的接收端返回%v，期望ErrEndpointDisabled", err)
+	}
+}
@@ -0,0 +1,506 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrCampaignNotFound 活动不存在
+	ErrCampaignNotFound = errors.New("活动不存在")
+	// ErrCampaignStatusChanged 活动状态已被其他请求修改
+	ErrCampaignStatusChanged = errors.New("活动状态已变更，请刷新后重试")
+	// ErrCampaignTooManyCards 活动卡片数量超过上限
+	ErrCampaignTooManyCards = errors.New("活动卡片数量超过上限")
+)
+
+// campaignColumns 读取活动的列，查询时活动表的别名必须为c
+const campaignColumns = `c.id, c.merchant_id, c.name, c.description, c.target_video_id, c.target_url,
+	c.starts_at, c.ends_at, c.status, c.last_error, c.activated_at, c.ended_at, c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM campaign_cards cc WHERE cc.campaign_id = c.id) AS card_count`
+
+// campaignCardColumns 读取活动成员的列，成员表别名为cc，卡片表别名为n
+const campaignCardColumns = `cc.campaign_id, cc.nfc_card_id, cc.merchant_id, n.uid, n.name, cc.state, cc.short_link_id,
+	cc.previous_target_url, cc.applied_target_url, cc.note, cc.applied_at, cc.restored_at, cc.created_at`
+
+// CampaignRepository 营销活动存储库
+type CampaignRepository struct {
+	DB *sqlx.DB
+}
+
+// NewCampaignRepository 创建营销活动存储库
+func NewCampaignRepository(db *sqlx.DB) *CampaignRepository {
+	return &CampaignRepository{
+		DB: db,
+	}
+}
+
+// Create 在一个事务中创建活动并加入成员卡片
+func (r *CampaignRepository) Create(ctx context.Context, campaign *entities.Campaign, cardIDs []uuid.UUID) (*entities.Campaign, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO campaigns (merchant_id, name, description, target_video_id, target_url, starts_at, ends_at, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+	var id uuid.UUID
+	err = tx.GetContext(ctx, &id, query,
+		campaign.MerchantID, campaign.Name, campaign.Description, campaign.TargetVideoID, campaign.TargetURL,
+		campaign.StartsAt, campaign.EndsAt, entities.CampaignScheduled)
+	if err != nil {
+		return nil, fmt.Errorf("创建活动失败: %w", err)
+	}
+
+	if _, err := insertCampaignCards(ctx, tx, id, campaign.MerchantID, cardIDs); err != nil {
+		return nil, err
+	}
+
+	created, err := findCampaign(ctx, tx, campaign.MerchantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return created, nil
+}
+
+// FindByID 获取商户的活动
+func (r *CampaignRepository) FindByID(ctx context.Context, merchantID, id uuid.UUID) (*entities.Campaign, error) {
+	return findCampaign(ctx, r.DB, merchantID, id)
+}
+
+// FindByMerchant 分页获取商户的活动，status为空时返回全部状态
+func (r *CampaignRepository) FindByMerchant(ctx context.Context, merchantID uuid.UUID, status entities.CampaignStatus, page, pageSize int) ([]*entities.Campaign, int, error) {
+	where := "c.merchant_id = $1"
+	params := []interface{}{merchantID}
+	if status != "" {
+		where += " AND c.status = $2"
+		params = append(params, status)
+	}
+
+	var total int
+	if err := r.DB.GetContext(ctx, &total, "SELECT COUNT(*) FROM campaigns c WHERE "+where, params...); err != nil {
+		return nil, 0, fmt.Errorf("获取活动总数失败: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM campaigns c WHERE %s ORDER BY c.created_at DESC LIMIT $%d OFFSET $%d`,
+		campaignColumns, where, len(params)+1, len(params)+2)
+	params = append(params, pageSize, (page-1)*pageSize)
+
+	var campaigns []*entities.Campaign
+	if err := r.DB.SelectContext(ctx, &campaigns, query, params...); err != nil {
+		return nil, 0, fmt.Errorf("获取活动列表失败: %w", err)
+	}
+	return campaigns, total, nil
+}
+
+// Update 修改等待开始的活动
+func (r *CampaignRepository) Update(ctx context.Context, campaign *entities.Campaign) (*entities.Campaign, error) {
+	query := `
+		WITH c AS (
+			UPDATE campaigns
+			SET name = $1, description = $2, target_video_id = $3, target_url = $4, starts_at = $5, ends_at = $6,
+				last_error = '', updated_at = NOW()
+			WHERE id = $7 AND merchant_id = $8 AND status = $9
+			RETURNING *
+		)
+		SELECT ` + campaignColumns + ` FROM c
+	`
+
+	var updated entities.Campaign
+	err := r.DB.GetContext(ctx, &updated, query,
+		campaign.Name, campaign.Description, campaign.TargetVideoID, campaign.TargetURL, campaign.StartsAt, campaign.EndsAt,
+		campaign.ID, campaign.MerchantID, entities.CampaignScheduled)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCampaignStatusChanged
+		}
+		return nil, fmt.Errorf("修改活动失败: %w", err)
+	}
+	return &updated, nil
+}
+
+// AddCards 向等待开始的活动加入卡片，已在活动中的卡片被忽略，返回新加入的数量
+// 加入后卡片总数超过maxCards时整体回滚
+func (r *CampaignRepository) AddCards(ctx context.Context, merchantID, id uuid.UUID, cardIDs []uuid.UUID, maxCards int) (int, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockScheduledCampaign(ctx, tx, merchantID, id); err != nil {
+		return 0, err
+	}
+
+	added, err := insertCampaignCards(ctx, tx, id, merchantID, cardIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	if err := tx.GetContext(ctx, &total, `SELECT COUNT(*) FROM campaign_cards WHERE campaign_id = $1`, id); err != nil {
+		return 0, fmt.Errorf("获取活动卡片数量失败: %w", err)
+	}
+	if maxCards > 0 && total > maxCards {
+		return 0, ErrCampaignTooManyCards
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return added, nil
+}
+
+// RemoveCards 从等待开始的活动中移除卡片，返回移除的数量
+func (r *CampaignRepository) RemoveCards(ctx context.Context, merchantID, id uuid.UUID, cardIDs []uuid.UUID) (int, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockScheduledCampaign(ctx, tx, merchantID, id); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM campaign_cards WHERE campaign_id = $1 AND nfc_card_id = ANY($2::uuid[])`, id, uuidArray(cardIDs))
+	if err != nil {
+		return 0, fmt.Errorf("移除活动卡片失败: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return int(removed), nil
+}
+
+// FindCards 获取活动的全部成员卡片
+func (r *CampaignRepository) FindCards(ctx context.Context, id uuid.UUID) ([]*entities.CampaignCard, error) {
+	query := `
+		SELECT ` + campaignCardColumns + `
+		FROM campaign_cards cc
+		JOIN nfc_cards n ON n.id = cc.nfc_card_id
+		WHERE cc.campaign_id = $1
+		ORDER BY cc.created_at, cc.nfc_card_id
+	`
+
+	var cards []*entities.CampaignCard
+	if err := r.DB.SelectContext(ctx, &cards, query, id); err != nil {
+		return nil, fmt.Errorf("获取活动卡片失败: %w", err)
+	}
+	return cards, nil
+}
+
+// FindConflicts 返回同时属于其他切换中或进行中活动的成员卡片ID
+func (r *CampaignRepository) FindConflicts(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT DISTINCT cc.nfc_card_id
+		FROM campaign_cards cc
+		JOIN campaign_cards other ON other.nfc_card_id = cc.nfc_card_id AND other.campaign_id <> cc.campaign_id
+		JOIN campaigns c ON c.id = other.campaign_id
+		WHERE cc.campaign_id = $1 AND c.status IN ($2, $3, $4)
+	`
+
+	var ids []uuid.UUID
+	err := r.DB.SelectContext(ctx, &ids, query, id, entities.CampaignActivating, entities.CampaignActive, entities.CampaignEnding)
+	if err != nil {
+		return nil, fmt.Errorf("检查活动卡片冲突失败: %w", err)
+	}
+	return ids, nil
+}
+
+// Transition 将活动从from中的任一状态变更为to，活动已不处于这些状态时返回ErrCampaignStatusChanged
+// 变更为进行中时记录开始时间，变更为已结束或已取消时记录结束时间
+func (r *CampaignRepository) Transition(ctx context.Context, id uuid.UUID, from []entities.CampaignStatus, to entities.CampaignStatus, lastError string) (*entities.Campaign, error) {
+	now := time.Now()
+	var activatedAt, endedAt *time.Time
+	switch to {
+	case entities.CampaignActive:
+		activatedAt = &now
+	case entities.CampaignEnded, entities.CampaignCancelled:
+		endedAt = &now
+	}
+
+	statuses := make([]string, len(from))
+	for i, status := range from {
+		statuses[i] = string(status)
+	}
+
+	query := `
+		WITH c AS (
+			UPDATE campaigns
+			SET status = $1, last_error = $2, activated_at = COALESCE($3, activated_at),
+				ended_at = COALESCE($4, ended_at), updated_at = $5
+			WHERE id = $6 AND status = ANY($7)
+			RETURNING *
+		)
+		SELECT ` + campaignColumns + ` FROM c
+	`
+
+	var updated entities.Campaign
+	err := r.DB.GetContext(ctx, &updated, query, to, lastError, activatedAt, endedAt, now, id, pq.Array(statuses))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCampaignStatusChanged
+		}
+		return nil, fmt.Errorf("更新活动状态失败: %w", err)
+	}
+	return &updated, nil
+}
+
+// Touch 刷新切换中活动的更新时间，表明仍有实例在处理，避免被调度器当作中断的切换接手
+func (r *CampaignRepository) Touch(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.DB.ExecContext(ctx, `UPDATE campaigns SET updated_at = NOW() WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("更新活动时间失败: %w", err)
+	}
+	return nil
+}
+
+// Reclaim 接手超过staleBefore没有进展的切换中活动，成功时返回true；同时只会有一个实例接手成功
+func (r *CampaignRepository) Reclaim(ctx context.Context, id uuid.UUID, status entities.CampaignStatus, staleBefore time.Time) (bool, error) {
+	query := `UPDATE campaigns SET updated_at = NOW() WHERE id = $1 AND status = $2 AND updated_at < $3`
+	result, err := r.DB.ExecContext(ctx, query, id, status, staleBefore)
+	if err != nil {
+		return false, fmt.Errorf("接手活动失败: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// MerchantHasVideo 视频是否属于该商户
+func (r *CampaignRepository) MerchantHasVideo(ctx context.Context, merchantID, videoID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM videos WHERE id = $1 AND merchant_id = $2)`
+	if err := r.DB.GetContext(ctx, &exists, query, videoID, merchantID); err != nil {
+		return false, fmt.Errorf("查询商户视频失败: %w", err)
+	}
+	return exists, nil
+}
+
+// MarkCardApplied 记录成员卡片已切换到活动目标，以及切换前的目标
+func (r *CampaignRepository) MarkCardApplied(ctx context.Context, id, cardID, linkID uuid.UUID, previousURL, appliedURL string) error {
+	query := `
+		UPDATE campaign_cards
+		SET state = $1, short_link_id = $2, previous_target_url = $3, applied_target_url = $4, note = '',
+			applied_at = NOW(), restored_at = NULL
+		WHERE campaign_id = $5 AND nfc_card_id = $6
+	`
+	if _, err := r.DB.ExecContext(ctx, query, entities.CampaignCardApplied, linkID, previousURL, appliedURL, id, cardID); err != nil {
+		return fmt.Errorf("更新活动卡片状态失败: %w", err)
+	}
+	return nil
+}
+
+// MarkCardSkipped 记录成员卡片未切换及原因
+func (r *CampaignRepository) MarkCardSkipped(ctx context.Context, id, cardID uuid.UUID, note string) error {
+	query := `UPDATE campaign_cards SET state = $1, note = $2 WHERE campaign_id = $3 AND nfc_card_id = $4`
+	if _, err := r.DB.ExecContext(ctx, query, entities.CampaignCardSkipped, note, id, cardID); err != nil {
+		return fmt.Errorf("更新活动卡片状态失败: %w", err)
+	}
+	return nil
+}
+
+// MarkCardRestored 记录成员卡片已在活动结束后恢复，note说明未恢复为原目标的原因
+func (r *CampaignRepository) MarkCardRestored(ctx context.Context, id, cardID uuid.UUID, note string) error {
+	query := `UPDATE campaign_cards SET state = $1, note = $2, restored_at = NOW() WHERE campaign_id = $3 AND nfc_card_id = $4`
+	if _, err := r.DB.ExecContext(ctx, query, entities.CampaignCardRestored, note, id, cardID); err != nil {
+		return fmt.Errorf("更新活动卡片状态失败: %w", err)
+	}
+	return nil
+}
+
+// ResetCards 激活失败回滚后将成员卡片恢复为未切换
+func (r *CampaignRepository) ResetCards(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE campaign_cards
+		SET state = $1, short_link_id = NULL, previous_target_url = NULL, applied_target_url = NULL,
+			applied_at = NULL, restored_at = NULL
+		WHERE campaign_id = $2
+	`
+	if _, err := r.DB.ExecContext(ctx, query, entities.CampaignCardPending, id); err != nil {
+		return fmt.Errorf("重置活动卡片状态失败: %w", err)
+	}
+	return nil
+}
+
+// UpdatePreviousTarget 卡片处于进行中的活动时，用targetURL替换活动结束后要恢复的目标
+// 卡片不在任何进行中的活动时返回false
+func (r *CampaignRepository) UpdatePreviousTarget(ctx context.Context, cardID uuid.UUID, targetURL string) (bool, error) {
+	query := `
+		UPDATE campaign_cards cc
+		SET previous_target_url = $1
+		FROM campaigns c
+		WHERE c.id = cc.campaign_id AND cc.nfc_card_id = $2 AND cc.state = $3 AND c.status IN ($4, $5, $6)
+	`
+	result, err := r.DB.ExecContext(ctx, query, targetURL, cardID, entities.CampaignCardApplied,
+		entities.CampaignActivating, entities.CampaignActive, entities.CampaignEnding)
+	if err != nil {
+		return false, fmt.Errorf("更新活动卡片的恢复目标失败: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// FindDue 获取需要调度器处理的活动：到达开始时间的、到达结束时间的，以及切换中但超过staleBefore没有进展的
+func (r *CampaignRepository) FindDue(ctx context.Context, now, staleBefore time.Time, limit int) ([]*entities.Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns c
+		WHERE (c.status = $1 AND c.starts_at <= $2)
+			OR (c.status = $3 AND c.ends_at <= $2)
+			OR (c.status IN ($4, $5) AND c.updated_at < $6)
+		ORDER BY c.starts_at
+		LIMIT $7
+	`
+
+	var campaigns []*entities.Campaign
+	err := r.DB.SelectContext(ctx, &campaigns, query,
+		entities.CampaignScheduled, now, entities.CampaignActive,
+		entities.CampaignActivating, entities.CampaignEnding, staleBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("获取到期活动失败: %w", err)
+	}
+	return campaigns, nil
+}
+
+// Stats 统计成员卡片在[from, to)内的点击，topCards为按卡片统计返回的最大条数
+func (r *CampaignRepository) Stats(ctx context.Context, id uuid.UUID, from, to time.Time, topCards int) (*entities.CampaignStats, error) {
+	stats := &entities.CampaignStats{CampaignID: id, From: &from, To: &to}
+
+	where := `nfc_card_id IN (SELECT nfc_card_id FROM campaign_cards WHERE campaign_id = $1)
+		AND clicked_at >= $2 AND clicked_at < $3 AND NOT suspicious`
+
+	summary := `SELECT COUNT(*) AS total, COUNT(DISTINCT nfc_card_id) AS clicked_cards FROM short_link_clicks WHERE ` + where
+	row := r.DB.QueryRowxContext(ctx, summary, id, from, to)
+	if err := row.Scan(&stats.Total, &stats.ClickedCards); err != nil {
+		return nil, fmt.Errorf("统计活动点击失败: %w", err)
+	}
+
+	for dimension, target := range map[string]*[]*entities.ClickBreakdownItem{
+		"day":    &stats.ByDay,
+		"source": &stats.BySource,
+		"device": &stats.ByDevice,
+	} {
+		order := "count DESC, key"
+		if dimension == "day" {
+			order = "key"
+		}
+		query := fmt.Sprintf(`
+			SELECT COALESCE(NULLIF(%s, ''), 'unknown') AS key, COUNT(*) AS count
+			FROM short_link_clicks
+			WHERE %s
+			GROUP BY 1
+			ORDER BY %s
+		`, clickDimensions[dimension], where, order)
+		if err := r.DB.SelectContext(ctx, target, query, id, from, to); err != nil {
+			return nil, fmt.Errorf("统计活动点击分布失败: %w", err)
+		}
+	}
+
+	byCard := `
+		SELECT k.nfc_card_id, n.uid, n.name, k.count
+		FROM (
+			SELECT nfc_card_id, COUNT(*) AS count
+			FROM short_link_clicks
+			WHERE ` + where + `
+			GROUP BY nfc_card_id
+		) k
+		JOIN nfc_cards n ON n.id = k.nfc_card_id
+		ORDER BY k.count DESC, n.uid
+		LIMIT $4
+	`
+	if err := r.DB.SelectContext(ctx, &stats.ByCard, byCard, id, from, to, topCards); err != nil {
+		return nil, fmt.Errorf("统计活动卡片点击失败: %w", err)
+	}
+
+	return stats, nil
+}
+
+// findCampaign 在db或事务中获取商户的活动
+func findCampaign(ctx context.Context, q sqlx.QueryerContext, merchantID, id uuid.UUID) (*entities.Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM campaigns c WHERE c.id = $1 AND c.merchant_id = $2`
+
+	var campaign entities.Campaign
+	if err := sqlx.GetContext(ctx, q, &campaign, query, id, merchantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("获取活动失败: %w", err)
+	}
+	return &campaign, nil
+}
+
+// lockScheduledCampaign 锁定活动行，活动不存在或已开始时返回错误
+func lockScheduledCampaign(ctx context.Context, tx *sqlx.Tx, merchantID, id uuid.UUID) error {
+	var status entities.CampaignStatus
+	err := tx.GetContext(ctx, &status, `SELECT status FROM campaigns WHERE id = $1 AND merchant_id = $2 FOR UPDATE`, id, merchantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCampaignNotFound
+		}
+		return fmt.Errorf("锁定活动失败: %w", err)
+	}
+	if status != entities.CampaignScheduled {
+		return ErrCampaignStatusChanged
+	}
+	return nil
+}
+
+// insertCampaignCards 加入成员卡片，只加入属于该商户的卡片，返回新加入的数量
+func insertCampaignCards(ctx context.Context, tx *sqlx.Tx, id, merchantID uuid.UUID, cardIDs []uuid.UUID) (int, error) {
+	if len(cardIDs) == 0 {
+		return 0, nil
+	}
+
+	query := `
+		INSERT INTO campaign_cards (campaign_id, nfc_card_id, merchant_id)
+		SELECT $1, n.id, n.merchant_id FROM nfc_cards n WHERE n.merchant_id = $2 AND n.id = ANY($3::uuid[])
+		ON CONFLICT (campaign_id, nfc_card_id) DO NOTHING
+	`
+	result, err := tx.ExecContext(ctx, query, id, merchantID, uuidArray(cardIDs))
+	if err != nil {
+		return 0, fmt.Errorf("加入活动卡片失败: %w", err)
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	return int(added), nil
+}
+
+// uuidArray 将UUID列表转换为可绑定到uuid[]参数的数组
+func uuidArray(ids []uuid.UUID) interface{} {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+	return pq.Array(values)
+}
@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrImportJobNotFound 导入任务不存在
+var ErrImportJobNotFound = errors.New("导入任务不存在")
+
+// cardInsertColumns 批量插入卡片时每行的参数个数
+const cardInsertColumns = 10
+
+// CardImportRepository NFC卡片批量导入存储库
+type CardImportRepository struct {
+	DB *sqlx.DB
+}
+
+// NewCardImportRepository 创建批量导入存储库
+func NewCardImportRepository(db *sqlx.DB) *CardImportRepository {
+	return &CardImportRepository{
+		DB: db,
+	}
+}
+
+// ExistingUIDs 返回uids中已存在于nfc_cards的UID
+func (r *CardImportRepository) ExistingUIDs(ctx context.Context, uids []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(uids) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	if err := r.DB.SelectContext(ctx, &found, `SELECT uid FROM nfc_cards WHERE uid = ANY($1)`, pq.Array(uids)); err != nil {
+		return nil, fmt.Errorf("查询已存在的UID失败: %w", err)
+	}
+
+	for _, uid := range found {
+		existing[uid] = true
+	}
+	return existing, nil
+}
+
+// MerchantVideoIDs 返回ids中属于该商户的视频ID
+func (r *CardImportRepository) MerchantVideoIDs(ctx context.Context, merchantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	owned := make(map[uuid.UUID]bool)
+	if len(ids) == 0 {
+		return owned, nil
+	}
+
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+
+	var found []uuid.UUID
+	query := `SELECT id FROM videos WHERE merchant_id = $1 AND id = ANY($2::uuid[])`
+	if err := r.DB.SelectContext(ctx, &found, query, merchantID, pq.Array(values)); err != nil {
+		return nil, fmt.Errorf("查询商户视频失败: %w", err)
+	}
+
+	for _, id := range found {
+		owned[id] = true
+	}
+	return owned, nil
+}
+
+// InsertCards 在一个事务中批量插入卡片，返回实际插入的 UID -> 卡片ID
+// 并发导入时UID可能已被其他请求写入，这些行会被跳过且不出现在返回结果中
+func (r *CardImportRepository) InsertCards(ctx context.Context, cards []*entities.NfcCard) (map[string]uuid.UUID, error) {
+	inserted := make(map[string]uuid.UUID, len(cards))
+	if len(cards) == 0 {
+		return inserted, nil
+	}
+
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	placeholders := make([]string, 0, len(cards))
+	args := make([]interface{}, 0, len(cards)*cardInsertColumns)
+	for i, card := range cards {
+		base := i * cardInsertColumns
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10))
+		args = append(args,
+			card.ID,
+			card.MerchantID,
+			card.UID,
+			card.Name,
+			card.Description,
+			card.DefaultVideoID,
+			card.Status,
+			card.ExpiresAt,
+			card.CreatedAt,
+			card.UpdatedAt,
+		)
+	}
+
+	query := `
+		INSERT INTO nfc_cards (id, merchant_id, uid, name, description, default_video_id, status, expires_at, created_at, updated_at)
+		VALUES ` + strings.Join(placeholders, ", ") + `
+		ON CONFLICT (uid) DO NOTHING
+		RETURNING uid, id
+	`
+
+	rows, err := tx.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("批量插入NFC卡片失败: %w", err)
+	}
+	for rows.Next() {
+		var uid string
+		var id uuid.UUID
+		if err := rows.Scan(&uid, &id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("读取插入结果失败: %w", err)
+		}
+		inserted[uid] = id
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("读取插入结果失败: %w", err)
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	return inserted, nil
+}
+
+// CreateJob 创建导入任务
+func (r *CardImportRepository) CreateJob(ctx context.Context, job *entities.CardImportJob) error {
+	query := `
+		INSERT INTO card_import_jobs (id, merchant_id, status, format, file_name, total_rows, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.DB.ExecContext(ctx, query,
+		job.ID, job.MerchantID, job.Status, job.Format, job.FileName, job.Total, job.CreatedBy, job.CreatedAt, job.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("创建导入任务失败: %w", err)
+	}
+	return nil
+}
+
+// MarkJobRunning 将等待中的任务标记为导入中，任务已不处于等待状态时返回false
+func (r *CardImportRepository) MarkJobRunning(ctx context.Context, id uuid.UUID) (bool, error) {
+	now := time.Now()
+	query := `UPDATE card_import_jobs SET status = $1, started_at = $2, updated_at = $2 WHERE id = $3 AND status = $4`
+
+	result, err := r.DB.ExecContext(ctx, query, entities.CardImportJobRunning, now, id, entities.CardImportJobPending)
+	if err != nil {
+		return false, fmt.Errorf("更新导入任务状态失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// UpdateJobProgress 更新任务的导入进度
+func (r *CardImportRepository) UpdateJobProgress(ctx context.Context, id uuid.UUID, summary entities.CardImportSummary) error {
+	query := `
+		UPDATE card_import_jobs
+		SET processed_rows = $1, created_count = $2, duplicate_count = $3, invalid_count = $4, failed_count = $5, updated_at = $6
+		WHERE id = $7
+	`
+
+	_, err := r.DB.ExecContext(ctx, query,
+		summary.Processed, summary.Created, summary.Duplicates, summary.Invalid, summary.Failed, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("更新导入进度失败: %w", err)
+	}
+	return nil
+}
+
+// FinishJob 记录任务的最终状态和逐行结果
+func (r *CardImportRepository) FinishJob(
+	ctx context.Context,
+	id uuid.UUID,
+	status entities.CardImportJobStatus,
+	report *entities.CardImportReport,
+	errorMessage string,
+) error {
+	var errMsg *string
+	if errorMessage != "" {
+		errMsg = &errorMessage
+	}
+
+	now := time.Now()
+	query := `
+		UPDATE card_import_jobs
+		SET status = $1, processed_rows = $2, created_count = $3, duplicate_count = $4, invalid_count = $5,
+			failed_count = $6, results = $7, error_message = $8, completed_at = $9, updated_at = $9
+		WHERE id = $10
+	`
+
+	_, err := r.DB.ExecContext(ctx, query,
+		status,
+		report.Processed,
+		report.Created,
+		report.Duplicates,
+		report.Invalid,
+		report.Failed,
+		report.Results,
+		errMsg,
+		now,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("更新导入任务结果失败: %w", err)
+	}
+	return nil
+}
+
+// FailStaleJobs 将before之后没有任何进度更新的未完成任务标记为失败
+// 用于清理因实例重启而中断的任务，正在执行的任务每完成一批都会更新updated_at
+func (r *CardImportRepository) FailStaleJobs(ctx context.Context, before time.Time, reason string) (int64, error) {
+	query := `
+		UPDATE card_import_jobs
+		SET status = $1, error_message = $2, completed_at = $3, updated_at = $3
+		WHERE status IN ($4, $5) AND updated_at < $6
+	`
+
+	result, err := r.DB.ExecContext(ctx, query,
+		entities.CardImportJobFailed, reason, time.Now(), entities.CardImportJobPending, entities.CardImportJobRunning, before)
+	if err != nil {
+		return 0, fmt.Errorf("清理中断的导入任务失败: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// FindJob 获取商户的导入任务
+func (r *CardImportRepository) FindJob(ctx context.Context, merchantID, id uuid.UUID) (*entities.CardImportJob, error) {
+	query := `
+		SELECT id, merchant_id, status, format, file_name, total_rows, processed_rows, created_count,
+			duplicate_count, invalid_count, failed_count, results, error_message, created_by,
+			started_at, completed_at, created_at, updated_at
+		FROM card_import_jobs
+		WHERE id = $1 AND merchant_id = $2
+	`
+
+	var job entities.CardImportJob
+	if err := r.DB.GetContext(ctx, &job, query, id, merchantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrImportJobNotFound
+		}
+		return nil, fmt.Errorf("获取导入任务失败: %w", err)
+	}
+	return &job, nil
+}
+
+// FindJobsByMerchant 分页获取商户的导入任务，不包含逐行结果
+func (r *CardImportRepository) FindJobsByMerchant(ctx context.Context, merchantID uuid.UUID, page, pageSize int) ([]*entities.CardImportJob, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	var total int
+	if err := r.DB.GetContext(ctx, &total, `SELECT COUNT(*) FROM card_import_jobs WHERE merchant_id = $1`, merchantID); err != nil {
+		return nil, 0, fmt.Errorf("获取导入任务总数失败: %w", err)
+	}
+
+	query := `
+		SELECT id, merchant_id, status, format, file_name, total_rows, processed_rows, created_count,
+			duplicate_count, invalid_count, failed_count, error_message, created_by,
+			started_at, completed_at, created_at, updated_at
+		FROM card_import_jobs
+		WHERE merchant_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	var jobs []*entities.CardImportJob
+	if err := r.DB.SelectContext(ctx, &jobs, query, merchantID, pageSize, (page-1)*pageSize); err != nil {
+		return nil, 0, fmt.Errorf("获取导入任务列表失败: %w", err)
+	}
+	return jobs, total, nil
+}
@@ -0,0 +1,338 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrTransferNotFound 转让不存在或与当前商户无关
+	ErrTransferNotFound = errors.New("卡片转让不存在")
+	// ErrTransferPending 卡片已有待处理的转让
+	ErrTransferPending = errors.New("卡片已有待处理的转让")
+	// ErrTransferNotPending 转让已处理或已过期
+	ErrTransferNotPending = errors.New("卡片转让已处理或已过期")
+	// ErrTransferForbidden 当前商户不能执行该操作
+	ErrTransferForbidden = errors.New("无权处理该卡片转让")
+	// ErrTransferCardChanged 发起转让后卡片已不属于原商户
+	ErrTransferCardChanged = errors.New("卡片已不属于发起转让的商户")
+	// ErrCardInCampaign 卡片的默认短链接正被营销活动接管
+	ErrCardInCampaign = errors.New("卡片正在参与营销活动，请先将其移出活动")
+)
+
+// transferStatusExpr 转让的实际状态，超过有效期仍未处理的转让视为已过期
+const transferStatusExpr = `CASE WHEN status = 'pending' AND expires_at <= NOW() THEN 'expired' ELSE status END`
+
+// transferColumns 读取转让的列
+const transferColumns = `id, nfc_card_id, card_uid, from_merchant_id, to_merchant_id, ` + transferStatusExpr + ` AS status,
+	link_mode, note, initiated_by, responded_by, admin_override, expires_at, responded_at, created_at, updated_at`
+
+// 转让执行函数transfer_nfc_card的返回结果
+const (
+	transferOutcomeOK           = "ok"
+	transferOutcomeNotAccepted  = "not_accepted"
+	transferOutcomeOwnerChanged = "owner_changed"
+	transferOutcomeInCampaign   = "in_campaign"
+)
+
+// CardTransferRepository 卡片转让存储库
+// card_transfers的租户策略允许原商户和目标商户访问，写入前在事务内设置当前租户；
+// 跨商户修改卡片和短链接由数据库函数transfer_nfc_card完成
+type CardTransferRepository struct {
+	DB *sqlx.DB
+}
+
+// NewCardTransferRepository 创建卡片转让存储库
+func NewCardTransferRepository(db *sqlx.DB) *CardTransferRepository {
+	return &CardTransferRepository{
+		DB: db,
+	}
+}
+
+// setTenant 在事务内设置当前租户，使RLS策略按该商户生效；以admin角色连接时不影响结果
+func setTenant(ctx context.Context, tx *sqlx.Tx, merchantID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('app.current_tenant', $1, true)`, merchantID.String()); err != nil {
+		return fmt.Errorf("设置当前租户失败: %w", err)
+	}
+	return nil
+}
+
+// Create 由原商户发起转让，卡片已有过期未处理的转让时先将其置为过期
+func (r *CardTransferRepository) Create(ctx context.Context, transfer *entities.CardTransfer) (*entities.CardTransfer, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx, transfer.FromMerchantID); err != nil {
+		return nil, err
+	}
+
+	created, err := r.insert(ctx, tx, transfer)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return created, nil
+}
+
+// insert 写入待处理的转让
+func (r *CardTransferRepository) insert(ctx context.Context, tx *sqlx.Tx, transfer *entities.CardTransfer) (*entities.CardTransfer, error) {
+	expireQuery := `
+		UPDATE card_transfers SET status = 'expired', updated_at = NOW()
+		WHERE nfc_card_id = $1 AND status = 'pending' AND expires_at <= NOW()
+	`
+	if _, err := tx.ExecContext(ctx, expireQuery, transfer.NfcCardID); err != nil {
+		return nil, fmt.Errorf("过期旧的卡片转让失败: %w", err)
+	}
+
+	query := `
+		INSERT INTO card_transfers (nfc_card_id, card_uid, from_merchant_id, to_merchant_id, link_mode, note, initiated_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + transferColumns
+
+	var created entities.CardTransfer
+	err := tx.GetContext(ctx, &created, query,
+		transfer.NfcCardID, transfer.CardUID, transfer.FromMerchantID, transfer.ToMerchantID,
+		transfer.LinkMode, transfer.Note, transfer.InitiatedBy, transfer.ExpiresAt)
+	if err != nil {
+		if repositories.IsUniqueViolation(err) {
+			return nil, ErrTransferPending
+		}
+		return nil, fmt.Errorf("创建卡片转让失败: %w", err)
+	}
+	return &created, nil
+}
+
+// FindByID 获取转让，merchantID不为nil时只返回该商户转出或转入的转让
+func (r *CardTransferRepository) FindByID(ctx context.Context, merchantID *uuid.UUID, id uuid.UUID) (*entities.CardTransfer, error) {
+	query := `SELECT ` + transferColumns + ` FROM card_transfers WHERE id = $1`
+	params := []interface{}{id}
+	if merchantID != nil {
+		query += ` AND (from_merchant_id = $2 OR to_merchant_id = $2)`
+		params = append(params, *merchantID)
+	}
+
+	var transfer entities.CardTransfer
+	if err := r.DB.GetContext(ctx, &transfer, query, params...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTransferNotFound
+		}
+		return nil, fmt.Errorf("获取卡片转让失败: %w", err)
+	}
+	return &transfer, nil
+}
+
+// List 分页获取转让，按创建时间倒序；merchantID为nil时返回全部商户的转让
+func (r *CardTransferRepository) List(ctx context.Context, merchantID *uuid.UUID, q *entities.CardTransferQuery) ([]*entities.CardTransfer, int, error) {
+	where := "TRUE"
+	var params []interface{}
+	if merchantID != nil {
+		params = append(params, *merchantID)
+		switch q.Direction {
+		case "incoming":
+			where += " AND to_merchant_id = $1"
+		case "outgoing":
+			where += " AND from_merchant_id = $1"
+		default:
+			where += " AND (from_merchant_id = $1 OR to_merchant_id = $1)"
+		}
+	}
+	if q.Status != "" {
+		params = append(params, q.Status)
+		where += fmt.Sprintf(" AND %s = $%d", transferStatusExpr, len(params))
+	}
+
+	var total int
+	if err := r.DB.GetContext(ctx, &total, "SELECT COUNT(*) FROM card_transfers WHERE "+where, params...); err != nil {
+		return nil, 0, fmt.Errorf("获取卡片转让总数失败: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM card_transfers WHERE %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d`,
+		transferColumns, where, len(params)+1, len(params)+2)
+	params = append(params, q.PageSize, (q.Page-1)*q.PageSize)
+
+	var transfers []*entities.CardTransfer
+	if err := r.DB.SelectContext(ctx, &transfers, query, params...); err != nil {
+		return nil, 0, fmt.Errorf("获取卡片转让列表失败: %w", err)
+	}
+	return transfers, total, nil
+}
+
+// Close 结束待处理的转让：目标商户拒绝或原商户撤回
+func (r *CardTransferRepository) Close(ctx context.Context, merchantID, id uuid.UUID, status entities.CardTransferStatus, actorID *uuid.UUID) (*entities.CardTransfer, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := setTenant(ctx, tx, merchantID); err != nil {
+		return nil, err
+	}
+
+	transfer, err := r.lockPending(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 只有目标商户可以拒绝，只有原商户可以撤回
+	owner := transfer.ToMerchantID
+	if status == entities.CardTransferCancelled {
+		owner = transfer.FromMerchantID
+	}
+	if owner != merchantID {
+		if transfer.FromMerchantID != merchantID && transfer.ToMerchantID != merchantID {
+			return nil, ErrTransferNotFound
+		}
+		return nil, ErrTransferForbidden
+	}
+
+	query := `
+		UPDATE card_transfers SET status = $2, responded_by = $3, responded_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + transferColumns
+
+	var closed entities.CardTransfer
+	if err := tx.GetContext(ctx, &closed, query, id, status, actorID); err != nil {
+		return nil, fmt.Errorf("更新卡片转让失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return &closed, nil
+}
+
+// Accept 接受转让并把卡片迁移到目标商户
+// merchantID为nil表示管理员强制完成，不校验目标商户
+func (r *CardTransferRepository) Accept(ctx context.Context, merchantID *uuid.UUID, id uuid.UUID, actorID *uuid.UUID) (*entities.CardTransferResult, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if merchantID != nil {
+		if err := setTenant(ctx, tx, *merchantID); err != nil {
+			return nil, err
+		}
+	}
+
+	transfer, err := r.lockPending(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if merchantID != nil && transfer.ToMerchantID != *merchantID {
+		if transfer.FromMerchantID != *merchantID {
+			return nil, ErrTransferNotFound
+		}
+		return nil, ErrTransferForbidden
+	}
+
+	result, err := r.execute(ctx, tx, id, actorID, merchantID == nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return result, nil
+}
+
+// ForceTransfer 管理员直接把卡片转让给目标商户，不需要目标商户接受
+func (r *CardTransferRepository) ForceTransfer(ctx context.Context, transfer *entities.CardTransfer, actorID *uuid.UUID) (*entities.CardTransferResult, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	created, err := r.insert(ctx, tx, transfer)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.execute(ctx, tx, created.ID, actorID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return result, nil
+}
+
+// lockPending 锁定待处理的转让
+func (r *CardTransferRepository) lockPending(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (*entities.CardTransfer, error) {
+	var transfer entities.CardTransfer
+	err := tx.GetContext(ctx, &transfer, `SELECT `+transferColumns+` FROM card_transfers WHERE id = $1 FOR UPDATE`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTransferNotFound
+		}
+		return nil, fmt.Errorf("锁定卡片转让失败: %w", err)
+	}
+	if transfer.Status != entities.CardTransferPending {
+		return nil, ErrTransferNotPending
+	}
+	return &transfer, nil
+}
+
+// execute 把转让置为已接受并调用transfer_nfc_card迁移卡片，失败时由调用方回滚事务
+func (r *CardTransferRepository) execute(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, actorID *uuid.UUID, override bool) (*entities.CardTransferResult, error) {
+	query := `
+		UPDATE card_transfers
+		SET status = 'accepted', responded_by = $2, responded_at = NOW(), admin_override = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + transferColumns
+
+	var transfer entities.CardTransfer
+	if err := tx.GetContext(ctx, &transfer, query, id, actorID, override); err != nil {
+		return nil, fmt.Errorf("更新卡片转让失败: %w", err)
+	}
+
+	var outcome string
+	var linkIDs pq.StringArray
+	err := tx.QueryRowxContext(ctx, `SELECT outcome, short_link_ids FROM transfer_nfc_card($1)`, id).Scan(&outcome, &linkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("迁移卡片失败: %w", err)
+	}
+
+	switch outcome {
+	case transferOutcomeOK:
+	case transferOutcomeNotAccepted:
+		return nil, ErrTransferNotPending
+	case transferOutcomeOwnerChanged:
+		return nil, ErrTransferCardChanged
+	case transferOutcomeInCampaign:
+		return nil, ErrCardInCampaign
+	default:
+		return nil, fmt.Errorf("迁移卡片失败: 未知结果%s", outcome)
+	}
+
+	result := &entities.CardTransferResult{Transfer: &transfer}
+	for _, raw := range linkIDs {
+		linkID, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("解析短链接ID失败: %w", err)
+		}
+		result.ShortLinkIDs = append(result.ShortLinkIDs, linkID)
+	}
+	return result, nil
+}
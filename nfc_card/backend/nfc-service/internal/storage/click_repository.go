@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// 允许进行分布统计的维度及其对应的SQL表达式
+var clickDimensions = map[string]string{
+	"os":       "os",
+	"browser":  "browser",
+	"device":   "device_type",
+	"country":  "country",
+	"region":   "region",
+	"referrer": "referrer",
+	"variant":  "variant_id",
+	"source":   "source",
+	"day":      "to_char(date_trunc('day', clicked_at), 'YYYY-MM-DD')",
+}
+
+// ClickRepository 短链接点击事件存储库
+type ClickRepository struct {
+	DB *sqlx.DB
+}
+
+// NewClickRepository 创建点击事件存储库
+func NewClickRepository(db *sqlx.DB) *ClickRepository {
+	return &ClickRepository{
+		DB: db,
+	}
+}
+
+// BatchInsert 批量写入点击事件，并回填点击发生时卡片所属的门店
+func (r *ClickRepository) BatchInsert(ctx context.Context, events []*entities.ClickEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	const columns = 17
+	var builder strings.Builder
+	builder.WriteString(`
+		INSERT INTO short_link_clicks (
+			id, merchant_id, short_link_id, slug, nfc_card_id, clicked_at, user_agent,
+			os, browser, device_type, ip_address, country, region, referrer, variant_id, source, suspicious, store_id
+		) VALUES `)
+
+	params := make([]interface{}, 0, len(events)*columns)
+	for i, event := range events {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString("(")
+		for j := 0; j < columns; j++ {
+			if j > 0 {
+				builder.WriteString(", ")
+			}
+			fmt.Fprintf(&builder, "$%d", i*columns+j+1)
+		}
+		// 门店取卡片当前的分配，之后换店不影响已记录的点击
+		fmt.Fprintf(&builder, ", (SELECT store_id FROM nfc_cards WHERE id = $%d))", i*columns+5)
+
+		params = append(params,
+			event.ID,
+			event.TenantID,
+			event.ShortLinkID,
+			event.Slug,
+			event.NfcCardID,
+			event.ClickedAt,
+			event.UserAgent,
+			event.OS,
+			event.Browser,
+			event.DeviceType,
+			event.IPAddress,
+			event.Country,
+			event.Region,
+			event.Referrer,
+			event.VariantID,
+			event.Source,
+			event.Suspicious,
+		)
+	}
+
+	builder.WriteString(" RETURNING id, store_id")
+
+	var stored []struct {
+		ID      uuid.UUID  `db:"id"`
+		StoreID *uuid.UUID `db:"store_id"`
+	}
+	if err := r.DB.SelectContext(ctx, &stored, builder.String(), params...); err != nil {
+		return fmt.Errorf("批量写入点击事件失败: %w", err)
+	}
+
+	stores := make(map[uuid.UUID]*uuid.UUID, len(stored))
+	for _, row := range stored {
+		stores[row.ID] = row.StoreID
+	}
+	for _, event := range events {
+		event.StoreID = stores[event.ID]
+	}
+
+	return nil
+}
+
+// FindBySlug 分页获取短链接的点击事件
+func (r *ClickRepository) FindBySlug(ctx context.Context, query *entities.ClickQuery) ([]*entities.ClickEvent, int, error) {
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	where, params := clickWhere(query)
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM short_link_clicks WHERE " + where
+	if err := r.DB.GetContext(ctx, &total, countQuery, params...); err != nil {
+		return nil, 0, fmt.Errorf("获取点击事件总数失败: %w", err)
+	}
+
+	listQuery := fmt.Sprintf(`
+		SELECT id, merchant_id, short_link_id, slug, nfc_card_id, clicked_at, user_agent,
+			os, browser, device_type, ip_address, country, region, referrer, variant_id, source, store_id, suspicious
+		FROM short_link_clicks
+		WHERE %s
+		ORDER BY clicked_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(params)+1, len(params)+2)
+
+	var events []*entities.ClickEvent
+	if err := r.DB.SelectContext(ctx, &events, listQuery, append(params, pageSize, offset)...); err != nil {
+		return nil, 0, fmt.Errorf("获取点击事件列表失败: %w", err)
+	}
+
+	return events, total, nil
+}
+
+// CountBySlug 统计短链接的点击总数
+func (r *ClickRepository) CountBySlug(ctx context.Context, query *entities.ClickQuery) (int, error) {
+	where, params := clickWhere(query)
+
+	var total int
+	if err := r.DB.GetContext(ctx, &total, "SELECT COUNT(*) FROM short_link_clicks WHERE "+where, params...); err != nil {
+		return 0, fmt.Errorf("获取点击事件总数失败: %w", err)
+	}
+
+	return total, nil
+}
+
+// GroupBy 按指定维度统计短链接的点击分布
+func (r *ClickRepository) GroupBy(ctx context.Context, query *entities.ClickQuery, dimension string) ([]*entities.ClickBreakdownItem, error) {
+	expr, ok := clickDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("不支持的统计维度: %s", dimension)
+	}
+
+	where, params := clickWhere(query)
+
+	order := "count DESC, key"
+	if dimension == "day" {
+		order = "key"
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT COALESCE(NULLIF(%s, ''), 'unknown') AS key, COUNT(*) AS count
+		FROM short_link_clicks
+		WHERE %s
+		GROUP BY 1
+		ORDER BY %s
+	`, expr, where, order)
+
+	var items []*entities.ClickBreakdownItem
+	if err := r.DB.SelectContext(ctx, &items, sqlQuery, params...); err != nil {
+		return nil, fmt.Errorf("统计点击分布失败: %w", err)
+	}
+
+	return items, nil
+}
+
+// clickWhere 根据查询条件构建WHERE子句，始终限定在查询的商户内
+func clickWhere(query *entities.ClickQuery) (string, []interface{}) {
+	conditions := []string{"slug = $1", "merchant_id = $2"}
+	params := []interface{}{query.Slug, query.MerchantID}
+
+	if query.From != nil {
+		params = append(params, *query.From)
+		conditions = append(conditions, fmt.Sprintf("clicked_at >= $%d", len(params)))
+	}
+	if query.To != nil {
+		params = append(params, *query.To)
+		conditions = append(conditions, fmt.Sprintf("clicked_at < $%d", len(params)))
+	}
+	if query.Source != "" {
+		params = append(params, query.Source)
+		conditions = append(conditions, fmt.Sprintf("source = $%d", len(params)))
+	}
+	if !query.IncludeSuspicious {
+		conditions = append(conditions, "NOT suspicious")
+	}
+
+	return strings.Join(conditions, " AND "), params
+}
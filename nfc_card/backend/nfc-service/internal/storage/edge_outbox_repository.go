@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// EdgeOutboxRepository 边缘同步发件箱存储库
+type EdgeOutboxRepository struct {
+	DB *sqlx.DB
+}
+
+// NewEdgeOutboxRepository 创建边缘同步发件箱存储库
+func NewEdgeOutboxRepository(db *sqlx.DB) *EdgeOutboxRepository {
+	return &EdgeOutboxRepository{
+		DB: db,
+	}
+}
+
+// Enqueue 写入一条边缘同步任务
+func (r *EdgeOutboxRepository) Enqueue(ctx context.Context, slug string, action entities.EdgeSyncAction, targetURL string) error {
+	query := `
+		INSERT INTO edge_sync_outbox (slug, action, target_url, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := r.DB.ExecContext(ctx, query, slug, action, targetURL, time.Now()); err != nil {
+		return fmt.Errorf("写入边缘同步任务失败: %w", err)
+	}
+
+	return nil
+}
+
+// ProcessPending 在事务中锁定一批待处理任务并交给handler处理
+// handler返回每个任务的处理错误（与tasks一一对应），成功的任务标记为已处理，失败的任务记录错误并累加重试次数。
+// 使用SKIP LOCKED，多个实例可以同时消费发件箱。
+func (r *EdgeOutboxRepository) ProcessPending(
+	ctx context.Context,
+	limit, maxAttempts int,
+	handler func(tasks []*entities.EdgeSyncTask) []error,
+) (int, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, slug, action, target_url, attempts, last_error, created_at, processed_at
+		FROM edge_sync_outbox
+		WHERE processed_at IS NULL AND attempts < $1
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	var tasks []*entities.EdgeSyncTask
+	if err := tx.SelectContext(ctx, &tasks, query, maxAttempts, limit); err != nil {
+		return 0, fmt.Errorf("获取边缘同步任务失败: %w", err)
+	}
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+
+	errs := handler(tasks)
+	now := time.Now()
+
+	for i, task := range tasks {
+		var taskErr error
+		if i < len(errs) {
+			taskErr = errs[i]
+		}
+
+		if taskErr == nil {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE edge_sync_outbox SET processed_at = $1, attempts = attempts + 1, last_error = NULL WHERE id = $2`,
+				now, task.ID)
+		} else {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE edge_sync_outbox SET attempts = attempts + 1, last_error = $1 WHERE id = $2`,
+				taskErr.Error(), task.ID)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("更新边缘同步任务状态失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	return len(tasks), nil
+}
+
+// PurgeProcessed 清理指定时间之前已处理的任务
+func (r *EdgeOutboxRepository) PurgeProcessed(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM edge_sync_outbox WHERE processed_at IS NOT NULL AND processed_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("清理边缘同步任务失败: %w", err)
+	}
+
+	return result.RowsAffected()
+}
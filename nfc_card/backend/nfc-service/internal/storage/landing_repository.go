@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// landingTemplateColumns 读取落地页模板的列
+const landingTemplateColumns = `id, merchant_id, layout, theme, share_platforms, created_at, updated_at`
+
+// LandingTemplateRepository 商户落地页模板存储库
+type LandingTemplateRepository struct {
+	DB *sqlx.DB
+}
+
+// NewLandingTemplateRepository 创建落地页模板存储库
+func NewLandingTemplateRepository(db *sqlx.DB) *LandingTemplateRepository {
+	return &LandingTemplateRepository{
+		DB: db,
+	}
+}
+
+// FindByMerchant 获取商户的落地页模板，商户未设置时返回nil
+func (r *LandingTemplateRepository) FindByMerchant(ctx context.Context, merchantID uuid.UUID) (*entities.LandingTemplate, error) {
+	query := `SELECT ` + landingTemplateColumns + ` FROM landing_templates WHERE merchant_id = $1`
+
+	var tmpl entities.LandingTemplate
+	if err := r.DB.GetContext(ctx, &tmpl, query, merchantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取落地页模板失败: %w", err)
+	}
+	return &tmpl, nil
+}
+
+// Upsert 创建或整体替换商户的落地页模板
+func (r *LandingTemplateRepository) Upsert(ctx context.Context, tmpl *entities.LandingTemplate) (*entities.LandingTemplate, error) {
+	query := `
+		INSERT INTO landing_templates (merchant_id, layout, theme, share_platforms)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (merchant_id) DO UPDATE
+		SET layout = EXCLUDED.layout, theme = EXCLUDED.theme,
+			share_platforms = EXCLUDED.share_platforms, updated_at = NOW()
+		RETURNING ` + landingTemplateColumns
+
+	var saved entities.LandingTemplate
+	if err := r.DB.GetContext(ctx, &saved, query, tmpl.MerchantID, tmpl.Layout, tmpl.Theme, tmpl.SharePlatforms); err != nil {
+		return nil, fmt.Errorf("保存落地页模板失败: %w", err)
+	}
+	return &saved, nil
+}
+
+// Delete 删除商户的落地页模板，之后使用默认模板
+func (r *LandingTemplateRepository) Delete(ctx context.Context, merchantID uuid.UUID) error {
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM landing_templates WHERE merchant_id = $1`, merchantID); err != nil {
+		return fmt.Errorf("删除落地页模板失败: %w", err)
+	}
+	return nil
+}
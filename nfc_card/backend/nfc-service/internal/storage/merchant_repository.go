@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// MerchantBranding 商户的名称和Logo
+type MerchantBranding struct {
+	Name    string `db:"name"`
+	LogoURL string `db:"logo_url"`
+}
+
+// MerchantRepository 商户信息的只读存储库，商户数据由merchant-service维护
+type MerchantRepository struct {
+	DB *sqlx.DB
+}
+
+// NewMerchantRepository 创建商户存储库
+func NewMerchantRepository(db *sqlx.DB) *MerchantRepository {
+	return &MerchantRepository{
+		DB: db,
+	}
+}
+
+// FindLogoURL 获取商户的Logo地址，商户不存在或未设置Logo时返回空字符串
+func (r *MerchantRepository) FindLogoURL(ctx context.Context, merchantID uuid.UUID) (string, error) {
+	var logoURL sql.NullString
+	err := r.DB.GetContext(ctx, &logoURL, `SELECT logo_url FROM merchants WHERE id = $1`, merchantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("获取商户Logo失败: %w", err)
+	}
+	return logoURL.String, nil
+}
+
+// FindBranding 获取商户的名称和Logo，商户不存在时返回nil
+func (r *MerchantRepository) FindBranding(ctx context.Context, merchantID uuid.UUID) (*MerchantBranding, error) {
+	var branding MerchantBranding
+	query := `SELECT name, COALESCE(logo_url, '') AS logo_url FROM merchants WHERE id = $1`
+	if err := r.DB.GetContext(ctx, &branding, query, merchantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取商户信息失败: %w", err)
+	}
+	return &branding, nil
+}
+
+// Exists 判断商户是否存在
+func (r *MerchantRepository) Exists(ctx context.Context, merchantID uuid.UUID) (bool, error) {
+	var exists bool
+	if err := r.DB.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM merchants WHERE id = $1)`, merchantID); err != nil {
+		return false, fmt.Errorf("查询商户失败: %w", err)
+	}
+	return exists, nil
+}
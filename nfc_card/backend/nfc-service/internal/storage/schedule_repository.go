@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// cardScheduleColumns 读取卡片排期的列
+const cardScheduleColumns = `nfc_card_id, merchant_id, timezone, entries, created_at, updated_at`
+
+// ScheduleRepository 卡片内容排期存储库
+type ScheduleRepository struct {
+	DB *sqlx.DB
+}
+
+// NewScheduleRepository 创建卡片排期存储库
+func NewScheduleRepository(db *sqlx.DB) *ScheduleRepository {
+	return &ScheduleRepository{
+		DB: db,
+	}
+}
+
+// FindByCard 获取卡片的排期，卡片没有排期时返回nil
+func (r *ScheduleRepository) FindByCard(ctx context.Context, cardID uuid.UUID) (*entities.CardSchedule, error) {
+	query := `SELECT ` + cardScheduleColumns + ` FROM card_schedules WHERE nfc_card_id = $1`
+
+	var schedule entities.CardSchedule
+	if err := r.DB.GetContext(ctx, &schedule, query, cardID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取卡片排期失败: %w", err)
+	}
+	return &schedule, nil
+}
+
+// Upsert 创建或整体替换卡片的排期
+func (r *ScheduleRepository) Upsert(ctx context.Context, schedule *entities.CardSchedule) (*entities.CardSchedule, error) {
+	query := `
+		INSERT INTO card_schedules (nfc_card_id, merchant_id, timezone, entries)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (nfc_card_id) DO UPDATE
+		SET timezone = EXCLUDED.timezone, entries = EXCLUDED.entries, updated_at = NOW()
+		RETURNING ` + cardScheduleColumns
+
+	var saved entities.CardSchedule
+	err := r.DB.GetContext(ctx, &saved, query, schedule.NfcCardID, schedule.MerchantID, schedule.Timezone, schedule.Entries)
+	if err != nil {
+		return nil, fmt.Errorf("保存卡片排期失败: %w", err)
+	}
+	return &saved, nil
+}
+
+// Delete 删除卡片的排期，返回是否存在
+func (r *ScheduleRepository) Delete(ctx context.Context, cardID uuid.UUID) (bool, error) {
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM card_schedules WHERE nfc_card_id = $1`, cardID)
+	if err != nil {
+		return false, fmt.Errorf("删除卡片排期失败: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// MerchantVideoIDs 返回ids中属于该商户的视频ID
+func (r *ScheduleRepository) MerchantVideoIDs(ctx context.Context, merchantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	owned := make(map[uuid.UUID]bool)
+	if len(ids) == 0 {
+		return owned, nil
+	}
+
+	var found []uuid.UUID
+	query := `SELECT id FROM videos WHERE merchant_id = $1 AND id = ANY($2::uuid[])`
+	if err := r.DB.SelectContext(ctx, &found, query, merchantID, uuidArray(ids)); err != nil {
+		return nil, fmt.Errorf("查询商户视频失败: %w", err)
+	}
+
+	for _, id := range found {
+		owned[id] = true
+	}
+	return owned, nil
+}
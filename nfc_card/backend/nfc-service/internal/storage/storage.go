@@ -2,6 +2,9 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"nfc-service/internal/config"
 	"nfc-service/internal/domain/entities"
@@ -10,7 +13,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // Repositories 所有仓库的集合
@@ -18,6 +21,18 @@ type Repositories struct {
 	db                  *sqlx.DB
 	CardRepository      *CardRepository
 	ShortlinkRepository *ShortlinkRepository
+	ClickRepository     *ClickRepository
+	EdgeOutbox          *EdgeOutboxRepository
+	CardImport          *CardImportRepository
+	SUN                 *SUNRepository
+	Merchant            *MerchantRepository
+	LandingTemplate     *LandingTemplateRepository
+	Campaign            *CampaignRepository
+	Schedule            *ScheduleRepository
+	Store               *StoreRepository
+	TapGuard            *TapGuardRepository
+	CardTransfer        *CardTransferRepository
+	Webhook             *WebhookRepository
 }
 
 // NewDBConnection 创建数据库连接
@@ -34,6 +49,18 @@ func NewRepositories(db *sqlx.DB) *Repositories {
 		db:                  db,
 		CardRepository:      NewCardRepository(db),
 		ShortlinkRepository: NewShortlinkRepository(db),
+		ClickRepository:     NewClickRepository(db),
+		EdgeOutbox:          NewEdgeOutboxRepository(db),
+		CardImport:          NewCardImportRepository(db),
+		SUN:                 NewSUNRepository(db),
+		Merchant:            NewMerchantRepository(db),
+		LandingTemplate:     NewLandingTemplateRepository(db),
+		Campaign:            NewCampaignRepository(db),
+		Schedule:            NewScheduleRepository(db),
+		Store:               NewStoreRepository(db),
+		TapGuard:            NewTapGuardRepository(db),
+		CardTransfer:        NewCardTransferRepository(db),
+		Webhook:             NewWebhookRepository(db),
 	}
 }
 
@@ -96,13 +123,6 @@ func (r *CardRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return repoImpl.Delete(ctx, id)
 }
 
-// Activate 激活NFC卡片
-func (r *CardRepository) Activate(ctx context.Context, uid string) (*entities.NfcCard, error) {
-	// 使用领域存储库的实现
-	repoImpl := repositories.NewNfcCardRepository(r.DB)
-	return repoImpl.Activate(ctx, uid)
-}
-
 // ShortlinkRepository 短链接存储库
 type ShortlinkRepository struct {
 	DB *sqlx.DB
@@ -120,10 +140,10 @@ func (r *ShortlinkRepository) Create(ctx context.Context, link *entities.CreateS
 	// 直接实现SQL逻辑
 	query := `
 		INSERT INTO short_links (
-			tenant_id, nfc_card_id, slug, target_url, expires_at, created_at, updated_at, clicks, active
+			tenant_id, nfc_card_id, slug, target_url, redirect_rules, variants, deep_links, preview, expires_at, created_at, updated_at, clicks, active
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $6, 0, true
-		) RETURNING id, tenant_id, nfc_card_id, slug, target_url, clicks, active, created_at, updated_at, expires_at
+			$1, $2, $3, $4, $5, $6, $7, COALESCE($10::jsonb, '{}'), $8, $9, $9, 0, true
+		) RETURNING id, tenant_id, nfc_card_id, slug, target_url, redirect_rules, variants, deep_links, preview, clicks, active, created_at, updated_at, expires_at
 	`
 
 	now := time.Now()
@@ -136,11 +156,18 @@ func (r *ShortlinkRepository) Create(ctx context.Context, link *entities.CreateS
 		link.NfcCardID,
 		link.Slug,
 		link.TargetURL,
+		link.Rules,
+		link.Variants,
+		link.DeepLinks,
 		link.ExpiresAt,
 		now,
+		link.Preview,
 	).StructScan(&result)
 
 	if err != nil {
+		if repositories.IsSlugConflict(err) {
+			return nil, repositories.ErrSlugAlreadyExists
+		}
 		return nil, fmt.Errorf("创建短链接失败: %w", err)
 	}
 
@@ -208,19 +235,114 @@ func (r *ShortlinkRepository) FindByNfcCardID(ctx context.Context, nfcCardID uui
 	return links, nil
 }
 
+// FindByNfcCardIDs 批量获取多张NFC卡片的短链接，按创建时间升序排列
+func (r *ShortlinkRepository) FindByNfcCardIDs(ctx context.Context, nfcCardIDs []uuid.UUID) ([]*entities.ShortLink, error) {
+	if len(nfcCardIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(nfcCardIDs))
+	for i, id := range nfcCardIDs {
+		ids[i] = id.String()
+	}
+
+	query := `SELECT * FROM short_links WHERE nfc_card_id = ANY($1::uuid[]) ORDER BY created_at`
+
+	var links []*entities.ShortLink
+	err := r.DB.SelectContext(ctx, &links, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("批量获取卡片短链接失败: %w", err)
+	}
+
+	return links, nil
+}
+
+// FindPrimaryByNfcCardIDs 批量获取多张NFC卡片的主短链接，即写入标签或打印二维码时使用的链接
+// 没有短链接的卡片不在结果中
+func (r *ShortlinkRepository) FindPrimaryByNfcCardIDs(ctx context.Context, nfcCardIDs []uuid.UUID) (map[uuid.UUID]*entities.ShortLink, error) {
+	links, err := r.FindByNfcCardIDs(ctx, nfcCardIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	primary := make(map[uuid.UUID]*entities.ShortLink, len(nfcCardIDs))
+	for _, link := range links {
+		if preferLink(link, primary[link.NfcCardID]) {
+			primary[link.NfcCardID] = link
+		}
+	}
+	return primary, nil
+}
+
+// preferLink 判断link是否比current更适合作为主链接：启用的优先，其次是默认链接，最后取最早创建的
+func preferLink(link, current *entities.ShortLink) bool {
+	if current == nil {
+		return true
+	}
+	if link.Active != current.Active {
+		return link.Active
+	}
+	if link.IsDefault != current.IsDefault {
+		return link.IsDefault
+	}
+	return link.CreatedAt.Before(current.CreatedAt)
+}
+
+// FindAll 获取所有短链接，用于与边缘KV对账
+func (r *ShortlinkRepository) FindAll(ctx context.Context) ([]*entities.ShortLink, error) {
+	query := `SELECT * FROM short_links ORDER BY slug`
+
+	var links []*entities.ShortLink
+	err := r.DB.SelectContext(ctx, &links, query)
+	if err != nil {
+		return nil, fmt.Errorf("获取全部短链接失败: %w", err)
+	}
+
+	return links, nil
+}
+
 // Update 更新短链接
 func (r *ShortlinkRepository) Update(ctx context.Context, id uuid.UUID, link *entities.UpdateShortLinkDTO) (*entities.ShortLink, error) {
+	// 未提供目标URL时保留原值
 	query := `
 		UPDATE short_links SET
-			target_url = $1,
+			target_url = COALESCE(NULLIF($1, ''), target_url)
 	`
 
 	params := []interface{}{link.TargetURL}
 	paramCount := 1
 
-	// 如果提供了Active字段，则更新
+	// 如果提供了Rules字段，则整体替换跳转规则
+	if link.Rules != nil {
+		query += fmt.Sprintf(", redirect_rules = $%d", paramCount+1)
+		params = append(params, *link.Rules)
+		paramCount++
+	}
+
+	// 如果提供了Variants字段，则整体替换A/B变体
+	if link.Variants != nil {
+		query += fmt.Sprintf(", variants = $%d", paramCount+1)
+		params = append(params, *link.Variants)
+		paramCount++
+	}
+
+	// 如果提供了DeepLinks字段，则整体替换深度链接
+	if link.DeepLinks != nil {
+		query += fmt.Sprintf(", deep_links = $%d", paramCount+1)
+		params = append(params, *link.DeepLinks)
+		paramCount++
+	}
+
+	// 如果提供了Preview字段，则整体替换商户设置的预览信息，保留自动填充的值
+	if link.Preview != nil {
+		query += fmt.Sprintf(", preview = (preview - 'title' - 'description' - 'imageUrl') || $%d::jsonb", paramCount+1)
+		params = append(params, *link.Preview)
+		paramCount++
+	}
+
+	// 如果提供了Active字段，则更新；手动修改后不再由卡片生命周期自动恢复
 	if link.Active != nil {
-		query += fmt.Sprintf(", active = $%d", paramCount+1)
+		query += fmt.Sprintf(", active = $%d, suspended_by_card = FALSE", paramCount+1)
 		params = append(params, *link.Active)
 		paramCount++
 	}
@@ -239,12 +361,61 @@ func (r *ShortlinkRepository) Update(ctx context.Context, id uuid.UUID, link *en
 	err := r.DB.QueryRowxContext(ctx, query, params...).StructScan(&updated)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repositories.ErrShortLinkNotFound
+		}
 		return nil, fmt.Errorf("更新短链接失败: %w", err)
 	}
 
 	return &updated, nil
 }
 
+// SetAutoPreview 保存从视频自动填充的分享预览，由系统维护，不修改updated_at
+func (r *ShortlinkRepository) SetAutoPreview(ctx context.Context, id uuid.UUID, auto *entities.AutoLinkPreview) (*entities.ShortLink, error) {
+	data, err := json.Marshal(auto)
+	if err != nil {
+		return nil, fmt.Errorf("序列化分享预览失败: %w", err)
+	}
+
+	query := `UPDATE short_links SET preview = jsonb_set(preview, '{auto}', $2::jsonb) WHERE id = $1 RETURNING *`
+
+	var updated entities.ShortLink
+	if err := r.DB.GetContext(ctx, &updated, query, id, data); err != nil {
+		return nil, fmt.Errorf("保存分享预览失败: %w", err)
+	}
+	return &updated, nil
+}
+
+// SuspendByNfcCardID 停用卡片下所有启用中的短链接并标记为由卡片挂起，返回被停用的短链接
+func (r *ShortlinkRepository) SuspendByNfcCardID(ctx context.Context, nfcCardID uuid.UUID) ([]*entities.ShortLink, error) {
+	query := `
+		UPDATE short_links SET active = FALSE, suspended_by_card = TRUE, updated_at = $2
+		WHERE nfc_card_id = $1 AND active
+		RETURNING *
+	`
+
+	var links []*entities.ShortLink
+	if err := r.DB.SelectContext(ctx, &links, query, nfcCardID, time.Now()); err != nil {
+		return nil, fmt.Errorf("挂起卡片短链接失败: %w", err)
+	}
+	return links, nil
+}
+
+// ResumeByNfcCardID 恢复由卡片挂起的短链接，返回被恢复的短链接
+func (r *ShortlinkRepository) ResumeByNfcCardID(ctx context.Context, nfcCardID uuid.UUID) ([]*entities.ShortLink, error) {
+	query := `
+		UPDATE short_links SET active = TRUE, suspended_by_card = FALSE, updated_at = $2
+		WHERE nfc_card_id = $1 AND suspended_by_card
+		RETURNING *
+	`
+
+	var links []*entities.ShortLink
+	if err := r.DB.SelectContext(ctx, &links, query, nfcCardID, time.Now()); err != nil {
+		return nil, fmt.Errorf("恢复卡片短链接失败: %w", err)
+	}
+	return links, nil
+}
+
 // Delete 删除短链接
 func (r *ShortlinkRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM short_links WHERE id = $1`
@@ -268,3 +439,31 @@ func (r *ShortlinkRepository) IncrementClicks(ctx context.Context, slug string)
 
 	return nil
 }
+
+// IncrementClicksBatch 批量增加多个短链接的点击次数
+func (r *ShortlinkRepository) IncrementClicksBatch(ctx context.Context, counts map[string]int) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	slugs := make([]string, 0, len(counts))
+	increments := make([]int64, 0, len(counts))
+	for slug, count := range counts {
+		slugs = append(slugs, slug)
+		increments = append(increments, int64(count))
+	}
+
+	query := `
+		UPDATE short_links AS s
+		SET clicks = s.clicks + v.increment
+		FROM (SELECT unnest($1::text[]) AS slug, unnest($2::bigint[]) AS increment) AS v
+		WHERE s.slug = v.slug
+	`
+
+	_, err := r.DB.ExecContext(ctx, query, pq.Array(slugs), pq.Array(increments))
+	if err != nil {
+		return fmt.Errorf("批量增加点击次数失败: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrStoreNotFound 门店不存在
+	ErrStoreNotFound = errors.New("门店不存在")
+	// ErrStoreExists 同一商户下门店名称或编号重复
+	ErrStoreExists = errors.New("门店名称或编号已存在")
+	// ErrStoreHasCards 门店下仍有卡片
+	ErrStoreHasCards = errors.New("门店下仍有卡片，请先移除卡片")
+)
+
+// storeColumns 读取门店的列，门店表别名为s
+const storeColumns = `s.id, s.merchant_id, s.name, s.code, s.address, s.city, s.phone, s.latitude, s.longitude,
+	s.timezone, s.opening_hours, s.created_at, s.updated_at,
+	(SELECT COUNT(*) FROM nfc_cards n WHERE n.store_id = s.id) AS card_count`
+
+// storeTapDimensions 门店碰卡明细允许的统计维度，c为点击表，s为门店表，n为卡片表
+// 日期和小时按门店所在时区计算；位置取卡片当前的位置
+var storeTapDimensions = map[string]string{
+	"day":      "to_char(c.clicked_at AT TIME ZONE s.timezone, 'YYYY-MM-DD')",
+	"hour":     "to_char(c.clicked_at AT TIME ZONE s.timezone, 'HH24')",
+	"position": "n.position",
+	"card":     "n.uid",
+}
+
+// StoreRepository 门店存储库
+type StoreRepository struct {
+	DB *sqlx.DB
+}
+
+// NewStoreRepository 创建门店存储库
+func NewStoreRepository(db *sqlx.DB) *StoreRepository {
+	return &StoreRepository{
+		DB: db,
+	}
+}
+
+// Create 创建门店
+func (r *StoreRepository) Create(ctx context.Context, store *entities.Store) (*entities.Store, error) {
+	query := `
+		INSERT INTO stores (merchant_id, name, code, address, city, phone, latitude, longitude, timezone, opening_hours)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+	var id uuid.UUID
+	err := r.DB.GetContext(ctx, &id, query,
+		store.MerchantID, store.Name, store.Code, store.Address, store.City, store.Phone,
+		store.Latitude, store.Longitude, store.Timezone, store.OpeningHours)
+	if err != nil {
+		if repositories.IsUniqueViolation(err) {
+			return nil, ErrStoreExists
+		}
+		return nil, fmt.Errorf("创建门店失败: %w", err)
+	}
+	return r.FindByID(ctx, store.MerchantID, id)
+}
+
+// FindByID 获取商户的门店
+func (r *StoreRepository) FindByID(ctx context.Context, merchantID, id uuid.UUID) (*entities.Store, error) {
+	query := `SELECT ` + storeColumns + ` FROM stores s WHERE s.id = $1 AND s.merchant_id = $2`
+
+	var store entities.Store
+	if err := r.DB.GetContext(ctx, &store, query, id, merchantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrStoreNotFound
+		}
+		return nil, fmt.Errorf("获取门店失败: %w", err)
+	}
+	return &store, nil
+}
+
+// FindByMerchant 分页获取商户的门店，city为空时返回全部城市
+func (r *StoreRepository) FindByMerchant(ctx context.Context, merchantID uuid.UUID, city string, page, pageSize int) ([]*entities.Store, int, error) {
+	where := "s.merchant_id = $1"
+	params := []interface{}{merchantID}
+	if city != "" {
+		where += " AND s.city = $2"
+		params = append(params, city)
+	}
+
+	var total int
+	if err := r.DB.GetContext(ctx, &total, "SELECT COUNT(*) FROM stores s WHERE "+where, params...); err != nil {
+		return nil, 0, fmt.Errorf("获取门店总数失败: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM stores s WHERE %s ORDER BY s.name LIMIT $%d OFFSET $%d`,
+		storeColumns, where, len(params)+1, len(params)+2)
+	params = append(params, pageSize, (page-1)*pageSize)
+
+	var stores []*entities.Store
+	if err := r.DB.SelectContext(ctx, &stores, query, params...); err != nil {
+		return nil, 0, fmt.Errorf("获取门店列表失败: %w", err)
+	}
+	return stores, total, nil
+}
+
+// Update 整体修改门店信息
+func (r *StoreRepository) Update(ctx context.Context, store *entities.Store) (*entities.Store, error) {
+	query := `
+		UPDATE stores
+		SET name = $1, code = $2, address = $3, city = $4, phone = $5, latitude = $6, longitude = $7,
+			timezone = $8, opening_hours = $9, updated_at = NOW()
+		WHERE id = $10 AND merchant_id = $11
+	`
+	result, err := r.DB.ExecContext(ctx, query,
+		store.Name, store.Code, store.Address, store.City, store.Phone, store.Latitude, store.Longitude,
+		store.Timezone, store.OpeningHours, store.ID, store.MerchantID)
+	if err != nil {
+		if repositories.IsUniqueViolation(err) {
+			return nil, ErrStoreExists
+		}
+		return nil, fmt.Errorf("修改门店失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, ErrStoreNotFound
+	}
+	return r.FindByID(ctx, store.MerchantID, store.ID)
+}
+
+// Delete 删除没有卡片的门店，历史点击和发布任务的门店字段置空
+func (r *StoreRepository) Delete(ctx context.Context, merchantID, id uuid.UUID) error {
+	query := `
+		DELETE FROM stores s
+		WHERE s.id = $1 AND s.merchant_id = $2
+			AND NOT EXISTS (SELECT 1 FROM nfc_cards n WHERE n.store_id = s.id)
+	`
+	result, err := r.DB.ExecContext(ctx, query, id, merchantID)
+	if err != nil {
+		return fmt.Errorf("删除门店失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	if _, err := r.FindByID(ctx, merchantID, id); err != nil {
+		return err
+	}
+	return ErrStoreHasCards
+}
+
+// AssignCards 把商户的卡片分配到门店，已属于其他门店的卡片会被移过来，返回分配的卡片数量
+func (r *StoreRepository) AssignCards(ctx context.Context, merchantID, storeID uuid.UUID, assignments []entities.StoreCardAssignment) (int, error) {
+	if len(assignments) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uuid.UUID, len(assignments))
+	positions := make([]string, len(assignments))
+	for i, assignment := range assignments {
+		ids[i] = assignment.CardID
+		positions[i] = assignment.Position
+	}
+
+	query := `
+		UPDATE nfc_cards n
+		SET store_id = $1, position = NULLIF(a.position, ''), updated_at = NOW()
+		FROM unnest($3::uuid[], $4::text[]) AS a(id, position)
+		WHERE n.id = a.id AND n.merchant_id = $2
+	`
+	result, err := r.DB.ExecContext(ctx, query, storeID, merchantID, uuidArray(ids), pq.Array(positions))
+	if err != nil {
+		return 0, fmt.Errorf("分配门店卡片失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	return int(rowsAffected), nil
+}
+
+// UnassignCards 把卡片移出门店，返回移出的卡片数量
+func (r *StoreRepository) UnassignCards(ctx context.Context, merchantID, storeID uuid.UUID, cardIDs []uuid.UUID) (int, error) {
+	if len(cardIDs) == 0 {
+		return 0, nil
+	}
+
+	query := `
+		UPDATE nfc_cards
+		SET store_id = NULL, position = NULL, updated_at = NOW()
+		WHERE store_id = $1 AND merchant_id = $2 AND id = ANY($3::uuid[])
+	`
+	result, err := r.DB.ExecContext(ctx, query, storeID, merchantID, uuidArray(cardIDs))
+	if err != nil {
+		return 0, fmt.Errorf("移出门店卡片失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	return int(rowsAffected), nil
+}
+
+// FindCards 分页获取门店的卡片，按位置排序
+func (r *StoreRepository) FindCards(ctx context.Context, storeID uuid.UUID, page, pageSize int) ([]*entities.StoreCard, int, error) {
+	var total int
+	if err := r.DB.GetContext(ctx, &total, `SELECT COUNT(*) FROM nfc_cards WHERE store_id = $1`, storeID); err != nil {
+		return nil, 0, fmt.Errorf("获取门店卡片总数失败: %w", err)
+	}
+
+	query := `
+		SELECT id, uid, name, status, COALESCE(position, '') AS position
+		FROM nfc_cards
+		WHERE store_id = $1
+		ORDER BY position NULLS LAST, name
+		LIMIT $2 OFFSET $3
+	`
+	var cards []*entities.StoreCard
+	if err := r.DB.SelectContext(ctx, &cards, query, storeID, pageSize, (page-1)*pageSize); err != nil {
+		return nil, 0, fmt.Errorf("获取门店卡片失败: %w", err)
+	}
+	return cards, total, nil
+}
+
+// TapSummaries 汇总商户各门店在[from, to)内的碰卡，storeID非空时只统计该门店
+func (r *StoreRepository) TapSummaries(ctx context.Context, merchantID uuid.UUID, storeID *uuid.UUID, from, to time.Time) ([]*entities.StoreTapSummary, error) {
+	query := `
+		SELECT s.id AS store_id, s.name AS store_name, s.city,
+			(SELECT COUNT(*) FROM nfc_cards n WHERE n.store_id = s.id) AS card_count,
+			COUNT(c.id) AS taps,
+			COUNT(c.id) FILTER (WHERE c.source = 'nfc') AS nfc_taps,
+			COUNT(c.id) FILTER (WHERE c.source = 'qr') AS qr_taps,
+			COUNT(DISTINCT c.nfc_card_id) AS tapped_cards
+		FROM stores s
+		LEFT JOIN short_link_clicks c ON c.store_id = s.id AND c.clicked_at >= $2 AND c.clicked_at < $3 AND NOT c.suspicious
+		WHERE s.merchant_id = $1 AND ($4::uuid IS NULL OR s.id = $4)
+		GROUP BY s.id
+		ORDER BY taps DESC, s.name
+	`
+	var summaries []*entities.StoreTapSummary
+	if err := r.DB.SelectContext(ctx, &summaries, query, merchantID, from, to, storeID); err != nil {
+		return nil, fmt.Errorf("汇总门店碰卡失败: %w", err)
+	}
+	return summaries, nil
+}
+
+// TapBreakdown 按维度统计门店在[from, to)内的碰卡分布
+func (r *StoreRepository) TapBreakdown(ctx context.Context, storeID uuid.UUID, from, to time.Time, dimension string, limit int) ([]*entities.ClickBreakdownItem, error) {
+	expr, ok := storeTapDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("不支持的统计维度: %s", dimension)
+	}
+
+	order := "count DESC, key"
+	if dimension == "day" || dimension == "hour" {
+		order = "key"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COALESCE(NULLIF(%s, ''), 'unknown') AS key, COUNT(*) AS count
+		FROM short_link_clicks c
+		JOIN stores s ON s.id = c.store_id
+		LEFT JOIN nfc_cards n ON n.id = c.nfc_card_id
+		WHERE c.store_id = $1 AND c.clicked_at >= $2 AND c.clicked_at < $3 AND NOT c.suspicious
+		GROUP BY 1
+		ORDER BY %s
+		LIMIT $4
+	`, expr, order)
+
+	var items []*entities.ClickBreakdownItem
+	if err := r.DB.SelectContext(ctx, &items, query, storeID, from, to, limit); err != nil {
+		return nil, fmt.Errorf("统计门店碰卡分布失败: %w", err)
+	}
+	return items, nil
+}
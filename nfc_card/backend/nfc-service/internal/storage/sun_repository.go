@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+	"nfc-service/internal/domain/repositories"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// sunColumns 读取卡片SUN密钥记录的列
+const sunColumns = `id, merchant_id, uid, sun_enabled, sun_key_version, sun_meta_read_key, sun_file_read_key, sun_read_counter, sun_last_tap_at`
+
+// SUNRepository NFC卡片SUN密钥和读计数器存储库
+type SUNRepository struct {
+	DB *sqlx.DB
+}
+
+// NewSUNRepository 创建SUN存储库
+func NewSUNRepository(db *sqlx.DB) *SUNRepository {
+	return &SUNRepository{
+		DB: db,
+	}
+}
+
+// FindByCardID 获取卡片的SUN密钥记录，用于重定向时校验，不按商户过滤
+func (r *SUNRepository) FindByCardID(ctx context.Context, cardID uuid.UUID) (*entities.SUNCardKeys, error) {
+	query := `SELECT ` + sunColumns + ` FROM nfc_cards WHERE id = $1`
+
+	var keys entities.SUNCardKeys
+	if err := r.DB.GetContext(ctx, &keys, query, cardID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repositories.ErrCardNotFound
+		}
+		return nil, fmt.Errorf("获取卡片SUN密钥失败: %w", err)
+	}
+	return &keys, nil
+}
+
+// FindByMerchant 获取商户下卡片的SUN密钥记录
+func (r *SUNRepository) FindByMerchant(ctx context.Context, merchantID, cardID uuid.UUID) (*entities.SUNCardKeys, error) {
+	query := `SELECT ` + sunColumns + ` FROM nfc_cards WHERE id = $1 AND merchant_id = $2`
+
+	var keys entities.SUNCardKeys
+	if err := r.DB.GetContext(ctx, &keys, query, cardID, merchantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repositories.ErrCardNotFound
+		}
+		return nil, fmt.Errorf("获取卡片SUN密钥失败: %w", err)
+	}
+	return &keys, nil
+}
+
+// SaveKeys 保存加密后的卡片密钥并启用SUN，同时清空读计数器
+// 新密钥下旧的SUN URL无法通过MAC校验，因此可以安全地从头开始记录计数器
+func (r *SUNRepository) SaveKeys(ctx context.Context, merchantID, cardID uuid.UUID, keyVersion int, metaReadKey, fileReadKey []byte) (*entities.SUNCardKeys, error) {
+	query := `
+		UPDATE nfc_cards
+		SET sun_enabled = TRUE, sun_key_version = $3, sun_meta_read_key = $4, sun_file_read_key = $5,
+			sun_read_counter = NULL, sun_last_tap_at = NULL, updated_at = $6
+		WHERE id = $1 AND merchant_id = $2
+		RETURNING ` + sunColumns
+
+	var keys entities.SUNCardKeys
+	err := r.DB.GetContext(ctx, &keys, query, cardID, merchantID, keyVersion, metaReadKey, fileReadKey, time.Now())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repositories.ErrCardNotFound
+		}
+		return nil, fmt.Errorf("保存卡片SUN密钥失败: %w", err)
+	}
+	return &keys, nil
+}
+
+// Disable 关闭卡片的SUN校验并删除密钥，保留密钥版本以便重新开启时派生新密钥
+func (r *SUNRepository) Disable(ctx context.Context, merchantID, cardID uuid.UUID) error {
+	query := `
+		UPDATE nfc_cards
+		SET sun_enabled = FALSE, sun_meta_read_key = NULL, sun_file_read_key = NULL,
+			sun_read_counter = NULL, sun_last_tap_at = NULL, updated_at = $3
+		WHERE id = $1 AND merchant_id = $2
+	`
+
+	result, err := r.DB.ExecContext(ctx, query, cardID, merchantID, time.Now())
+	if err != nil {
+		return fmt.Errorf("关闭卡片SUN校验失败: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if rows == 0 {
+		return repositories.ErrCardNotFound
+	}
+	return nil
+}
+
+// AdvanceCounter 仅当读计数器大于已接受的值时更新，返回false表示计数器重放或乱序
+// 条件更新保证并发的相同请求中只有一个能成功；keyVersion防止密钥轮换期间误用旧密钥校验的结果
+func (r *SUNRepository) AdvanceCounter(ctx context.Context, cardID uuid.UUID, keyVersion, counter int) (bool, error) {
+	query := `
+		UPDATE nfc_cards
+		SET sun_read_counter = $3, sun_last_tap_at = $4
+		WHERE id = $1 AND sun_enabled AND sun_key_version = $2
+			AND (sun_read_counter IS NULL OR sun_read_counter < $3)
+	`
+
+	result, err := r.DB.ExecContext(ctx, query, cardID, keyVersion, counter, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("更新SUN读计数器失败: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// EnabledCardIDs 获取所有启用SUN的卡片ID
+func (r *SUNRepository) EnabledCardIDs(ctx context.Context) (map[uuid.UUID]bool, error) {
+	var ids []uuid.UUID
+	if err := r.DB.SelectContext(ctx, &ids, `SELECT id FROM nfc_cards WHERE sun_enabled`); err != nil {
+		return nil, fmt.Errorf("获取启用SUN的卡片失败: %w", err)
+	}
+
+	enabled := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		enabled[id] = true
+	}
+	return enabled, nil
+}
+
+// EnabledAmong 获取给定卡片中启用SUN的卡片ID
+func (r *SUNRepository) EnabledAmong(ctx context.Context, cardIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	enabled := make(map[uuid.UUID]bool)
+	if len(cardIDs) == 0 {
+		return enabled, nil
+	}
+
+	ids := make([]string, len(cardIDs))
+	for i, id := range cardIDs {
+		ids[i] = id.String()
+	}
+
+	var found []uuid.UUID
+	query := `SELECT id FROM nfc_cards WHERE sun_enabled AND id = ANY($1::uuid[])`
+	if err := r.DB.SelectContext(ctx, &found, query, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("获取启用SUN的卡片失败: %w", err)
+	}
+
+	for _, id := range found {
+		enabled[id] = true
+	}
+	return enabled, nil
+}
+
+// IsEnabled 判断卡片是否启用了SUN
+func (r *SUNRepository) IsEnabled(ctx context.Context, cardID uuid.UUID) (bool, error) {
+	var enabled bool
+	err := r.DB.GetContext(ctx, &enabled, `SELECT sun_enabled FROM nfc_cards WHERE id = $1`, cardID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("获取卡片SUN状态失败: %w", err)
+	}
+	return enabled, nil
+}
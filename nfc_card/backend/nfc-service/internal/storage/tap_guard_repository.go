@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// tapGuardSettingsColumns 读取商户刷量防护阈值的列
+const tapGuardSettingsColumns = `merchant_id, enabled, ip_rate_per_minute, ip_burst, slug_rate_per_minute, slug_burst,
+	duplicate_window_seconds, updated_at`
+
+// TapFilterKey 过滤计数的累加键
+type TapFilterKey struct {
+	MerchantID uuid.UUID
+	Date       string // YYYY-MM-DD
+	Reason     entities.TapFilterReason
+}
+
+// TapGuardRepository 刷量防护存储库
+type TapGuardRepository struct {
+	DB *sqlx.DB
+}
+
+// NewTapGuardRepository 创建刷量防护存储库
+func NewTapGuardRepository(db *sqlx.DB) *TapGuardRepository {
+	return &TapGuardRepository{
+		DB: db,
+	}
+}
+
+// FindSettings 获取商户自定义的阈值，没有自定义时返回nil
+func (r *TapGuardRepository) FindSettings(ctx context.Context, merchantID uuid.UUID) (*entities.TapGuardSettings, error) {
+	query := `SELECT ` + tapGuardSettingsColumns + ` FROM tap_guard_settings WHERE merchant_id = $1`
+
+	var settings entities.TapGuardSettings
+	if err := r.DB.GetContext(ctx, &settings, query, merchantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取刷量防护阈值失败: %w", err)
+	}
+	settings.Custom = true
+	return &settings, nil
+}
+
+// UpsertSettings 创建或整体替换商户的阈值
+func (r *TapGuardRepository) UpsertSettings(ctx context.Context, settings *entities.TapGuardSettings) (*entities.TapGuardSettings, error) {
+	query := `
+		INSERT INTO tap_guard_settings (
+			merchant_id, enabled, ip_rate_per_minute, ip_burst, slug_rate_per_minute, slug_burst, duplicate_window_seconds
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (merchant_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+			ip_rate_per_minute = EXCLUDED.ip_rate_per_minute,
+			ip_burst = EXCLUDED.ip_burst,
+			slug_rate_per_minute = EXCLUDED.slug_rate_per_minute,
+			slug_burst = EXCLUDED.slug_burst,
+			duplicate_window_seconds = EXCLUDED.duplicate_window_seconds,
+			updated_at = NOW()
+		RETURNING ` + tapGuardSettingsColumns
+
+	var saved entities.TapGuardSettings
+	err := r.DB.GetContext(ctx, &saved, query,
+		settings.MerchantID, settings.Enabled, settings.IPRatePerMinute, settings.IPBurst,
+		settings.SlugRatePerMinute, settings.SlugBurst, settings.DuplicateWindowSeconds)
+	if err != nil {
+		return nil, fmt.Errorf("保存刷量防护阈值失败: %w", err)
+	}
+	saved.Custom = true
+	return &saved, nil
+}
+
+// DeleteSettings 删除商户自定义的阈值，恢复为默认值
+func (r *TapGuardRepository) DeleteSettings(ctx context.Context, merchantID uuid.UUID) error {
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM tap_guard_settings WHERE merchant_id = $1`, merchantID); err != nil {
+		return fmt.Errorf("删除刷量防护阈值失败: %w", err)
+	}
+	return nil
+}
+
+// IncrementFilterCounts 批量累加过滤计数
+func (r *TapGuardRepository) IncrementFilterCounts(ctx context.Context, counts map[TapFilterKey]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	merchantIDs := make([]uuid.UUID, 0, len(counts))
+	dates := make([]string, 0, len(counts))
+	reasons := make([]string, 0, len(counts))
+	values := make([]int64, 0, len(counts))
+	for key, count := range counts {
+		merchantIDs = append(merchantIDs, key.MerchantID)
+		dates = append(dates, key.Date)
+		reasons = append(reasons, string(key.Reason))
+		values = append(values, count)
+	}
+
+	query := `
+		INSERT INTO tap_filter_counts (merchant_id, date, reason, count)
+		SELECT * FROM unnest($1::uuid[], $2::date[], $3::varchar[], $4::bigint[])
+		ON CONFLICT (merchant_id, date, reason) DO UPDATE
+		SET count = tap_filter_counts.count + EXCLUDED.count
+	`
+	_, err := r.DB.ExecContext(ctx, query, uuidArray(merchantIDs), pq.Array(dates), pq.Array(reasons), pq.Array(values))
+	if err != nil {
+		return fmt.Errorf("写入过滤计数失败: %w", err)
+	}
+	return nil
+}
+
+// FilterCounts 获取商户在[from, to]内每天按原因的过滤计数
+func (r *TapGuardRepository) FilterCounts(ctx context.Context, merchantID uuid.UUID, from, to time.Time) ([]*entities.TapFilterCount, error) {
+	query := `
+		SELECT to_char(date, 'YYYY-MM-DD') AS date, reason, count
+		FROM tap_filter_counts
+		WHERE merchant_id = $1 AND date BETWEEN $2::date AND $3::date
+		ORDER BY date, reason
+	`
+
+	var counts []*entities.TapFilterCount
+	err := r.DB.SelectContext(ctx, &counts, query, merchantID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("获取过滤计数失败: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,456 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"nfc-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrWebhookNotFound Webhook接收端不存在
+	ErrWebhookNotFound = errors.New("Webhook接收端不存在")
+	// ErrWebhookDeliveryNotFound 投递记录不存在
+	ErrWebhookDeliveryNotFound = errors.New("Webhook投递记录不存在")
+)
+
+// webhookEndpointColumns 读取接收端的列，不包含签名密钥
+const webhookEndpointColumns = `id, merchant_id, url, description, event_types, active, consecutive_failures,
+	disabled_reason, disabled_at, last_success_at, last_failure_at, created_at, updated_at`
+
+// webhookDeliveryColumns 列表中返回的投递记录列，不包含请求体和响应内容
+const webhookDeliveryColumns = `id, endpoint_id, merchant_id, event_id, event_type, status, attempts, next_attempt_at,
+	response_status, last_error, duration_ms, delivered_at, redelivery_of, created_at, updated_at`
+
+// webhookDisabledError 接收端停用后其待投递记录的失败原因
+const webhookDisabledError = "接收端已停用"
+
+// WebhookAttempt 一次投递尝试的结果
+type WebhookAttempt struct {
+	StatusCode *int
+	Body       string
+	Error      string
+	Duration   time.Duration
+}
+
+// durationMs 耗时毫秒数
+func (a *WebhookAttempt) durationMs() int {
+	return int(a.Duration / time.Millisecond)
+}
+
+// ClaimedWebhookDelivery 领取的待投递记录及其接收端
+type ClaimedWebhookDelivery struct {
+	ID         uuid.UUID       `db:"id"`
+	EndpointID uuid.UUID       `db:"endpoint_id"`
+	MerchantID uuid.UUID       `db:"merchant_id"`
+	EventID    uuid.UUID       `db:"event_id"`
+	EventType  string          `db:"event_type"`
+	Payload    json.RawMessage `db:"payload"`
+	Attempts   int             `db:"attempts"`
+	URL        string          `db:"url"`
+	Secret     string          `db:"secret"`
+}
+
+// WebhookRepository 商户Webhook接收端和投递记录存储库
+// webhook_deliveries同时作为投递队列，多个实例通过FOR UPDATE SKIP LOCKED和租约领取
+type WebhookRepository struct {
+	DB *sqlx.DB
+}
+
+// NewWebhookRepository 创建Webhook存储库
+func NewWebhookRepository(db *sqlx.DB) *WebhookRepository {
+	return &WebhookRepository{
+		DB: db,
+	}
+}
+
+// CountEndpoints 统计商户的接收端数量
+func (r *WebhookRepository) CountEndpoints(ctx context.Context, merchantID uuid.UUID) (int, error) {
+	var count int
+	if err := r.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM webhook_endpoints WHERE merchant_id = $1`, merchantID); err != nil {
+		return 0, fmt.Errorf("统计Webhook接收端失败: %w", err)
+	}
+	return count, nil
+}
+
+// CreateEndpoint 创建接收端，返回的记录包含签名密钥
+func (r *WebhookRepository) CreateEndpoint(ctx context.Context, endpoint *entities.WebhookEndpoint) (*entities.WebhookEndpoint, error) {
+	query := `
+		INSERT INTO webhook_endpoints (merchant_id, url, description, secret, event_types)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + webhookEndpointColumns + `, secret`
+
+	var created entities.WebhookEndpoint
+	err := r.DB.GetContext(ctx, &created, query,
+		endpoint.MerchantID, endpoint.URL, endpoint.Description, endpoint.Secret, endpoint.EventTypes)
+	if err != nil {
+		return nil, fmt.Errorf("创建Webhook接收端失败: %w", err)
+	}
+	return &created, nil
+}
+
+// FindEndpoint 获取商户的接收端，withSecret为true时同时读取签名密钥
+func (r *WebhookRepository) FindEndpoint(ctx context.Context, merchantID, id uuid.UUID, withSecret bool) (*entities.WebhookEndpoint, error) {
+	columns := webhookEndpointColumns
+	if withSecret {
+		columns += ", secret"
+	}
+
+	var endpoint entities.WebhookEndpoint
+	query := `SELECT ` + columns + ` FROM webhook_endpoints WHERE id = $1 AND merchant_id = $2`
+	if err := r.DB.GetContext(ctx, &endpoint, query, id, merchantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("获取Webhook接收端失败: %w", err)
+	}
+	return &endpoint, nil
+}
+
+// ListEndpoints 获取商户的全部接收端
+func (r *WebhookRepository) ListEndpoints(ctx context.Context, merchantID uuid.UUID) ([]*entities.WebhookEndpoint, error) {
+	var endpoints []*entities.WebhookEndpoint
+	query := `SELECT ` + webhookEndpointColumns + ` FROM webhook_endpoints WHERE merchant_id = $1 ORDER BY created_at`
+	if err := r.DB.SelectContext(ctx, &endpoints, query, merchantID); err != nil {
+		return nil, fmt.Errorf("获取Webhook接收端列表失败: %w", err)
+	}
+	return endpoints, nil
+}
+
+// UpdateEndpoint 修改接收端
+// 重新启用时清零连续失败次数；停用时其待投递记录一并置为失败
+func (r *WebhookRepository) UpdateEndpoint(ctx context.Context, merchantID, id uuid.UUID, dto *entities.UpdateWebhookEndpointDTO) (*entities.WebhookEndpoint, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE webhook_endpoints SET updated_at = NOW()`
+	params := []interface{}{id, merchantID}
+	if dto.URL != nil {
+		params = append(params, *dto.URL)
+		query += fmt.Sprintf(", url = $%d", len(params))
+	}
+	if dto.Description != nil {
+		params = append(params, *dto.Description)
+		query += fmt.Sprintf(", description = $%d", len(params))
+	}
+	if dto.EventTypes != nil {
+		params = append(params, *dto.EventTypes)
+		query += fmt.Sprintf(", event_types = $%d", len(params))
+	}
+	if dto.Active != nil {
+		params = append(params, *dto.Active)
+		query += fmt.Sprintf(`, active = $%[1]d,
+			consecutive_failures = CASE WHEN $%[1]d AND NOT active THEN 0 ELSE consecutive_failures END,
+			disabled_reason = CASE WHEN $%[1]d OR active THEN '' ELSE disabled_reason END,
+			disabled_at = CASE WHEN $%[1]d THEN NULL WHEN active THEN NOW() ELSE disabled_at END`, len(params))
+	}
+	query += ` WHERE id = $1 AND merchant_id = $2 RETURNING ` + webhookEndpointColumns
+
+	var updated entities.WebhookEndpoint
+	if err := tx.GetContext(ctx, &updated, query, params...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("更新Webhook接收端失败: %w", err)
+	}
+
+	if !updated.Active {
+		if err := failPendingDeliveries(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return &updated, nil
+}
+
+// RotateSecret 更换接收端的签名密钥，返回的记录包含新密钥
+func (r *WebhookRepository) RotateSecret(ctx context.Context, merchantID, id uuid.UUID, secret string) (*entities.WebhookEndpoint, error) {
+	query := `
+		UPDATE webhook_endpoints SET secret = $3, updated_at = NOW()
+		WHERE id = $1 AND merchant_id = $2
+		RETURNING ` + webhookEndpointColumns + `, secret`
+
+	var updated entities.WebhookEndpoint
+	if err := r.DB.GetContext(ctx, &updated, query, id, merchantID, secret); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("更换Webhook签名密钥失败: %w", err)
+	}
+	return &updated, nil
+}
+
+// DeleteEndpoint 删除接收端及其投递记录
+func (r *WebhookRepository) DeleteEndpoint(ctx context.Context, merchantID, id uuid.UUID) error {
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1 AND merchant_id = $2`, id, merchantID)
+	if err != nil {
+		return fmt.Errorf("删除Webhook接收端失败: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Enqueue 为订阅了该事件的商户接收端生成待投递记录，返回生成的数量
+// 同一事件重复写入时忽略，Kafka重复消费不会重复投递
+func (r *WebhookRepository) Enqueue(ctx context.Context, merchantIDs []uuid.UUID, eventID uuid.UUID, eventType string, payload []byte) (int, error) {
+	query := `
+		INSERT INTO webhook_deliveries (endpoint_id, merchant_id, event_id, event_type, payload)
+		SELECT e.id, e.merchant_id, $2, $3, $4
+		FROM webhook_endpoints e
+		WHERE e.merchant_id = ANY($1::uuid[]) AND e.active
+			AND (cardinality(e.event_types) = 0 OR $3 = ANY(e.event_types))
+		ON CONFLICT (endpoint_id, event_id) WHERE redelivery_of IS NULL DO NOTHING
+	`
+	result, err := r.DB.ExecContext(ctx, query, uuidArray(merchantIDs), eventID, eventType, payload)
+	if err != nil {
+		return 0, fmt.Errorf("写入Webhook投递记录失败: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return int(rows), nil
+}
+
+// ClaimDue 领取到期的待投递记录并加上租约，同时把投递次数加一
+// 租约到期仍未记录结果的投递（实例中途退出）会被重新领取
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*ClaimedWebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhook_endpoints e ON e.id = d.endpoint_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW()
+				AND (d.locked_until IS NULL OR d.locked_until < NOW())
+				AND e.active
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET locked_until = NOW() + $2::float8 * INTERVAL '1 second', attempts = d.attempts + 1, updated_at = NOW()
+		FROM due, webhook_endpoints e
+		WHERE d.id = due.id AND e.id = d.endpoint_id
+		RETURNING d.id, d.endpoint_id, d.merchant_id, d.event_id, d.event_type, d.payload, d.attempts, e.url, e.secret
+	`
+
+	var claimed []*ClaimedWebhookDelivery
+	if err := r.DB.SelectContext(ctx, &claimed, query, limit, lease.Seconds()); err != nil {
+		return nil, fmt.Errorf("领取Webhook投递任务失败: %w", err)
+	}
+	return claimed, nil
+}
+
+// RecordSuccess 记录投递成功并清零接收端的连续失败次数
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, delivery *ClaimedWebhookDelivery, attempt *WebhookAttempt) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'succeeded', locked_until = NULL, response_status = $2, response_body = $3, last_error = '',
+			duration_ms = $4, delivered_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := tx.ExecContext(ctx, query, delivery.ID, attempt.StatusCode, attempt.Body, attempt.durationMs()); err != nil {
+		return fmt.Errorf("记录Webhook投递结果失败: %w", err)
+	}
+
+	query = `
+		UPDATE webhook_endpoints
+		SET consecutive_failures = 0, last_success_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := tx.ExecContext(ctx, query, delivery.EndpointID); err != nil {
+		return fmt.Errorf("更新Webhook接收端失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// RecordFailure 记录投递失败，retryAt为nil时不再重试
+// 接收端连续失败达到disableAfter次时自动停用，其待投递记录一并置为失败，返回true
+func (r *WebhookRepository) RecordFailure(ctx context.Context, delivery *ClaimedWebhookDelivery, attempt *WebhookAttempt, retryAt *time.Time, disableAfter int) (bool, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = CASE WHEN $2::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			next_attempt_at = COALESCE($2::timestamptz, next_attempt_at),
+			locked_until = NULL, response_status = $3, response_body = $4, last_error = $5,
+			duration_ms = $6, updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err = tx.ExecContext(ctx, query, delivery.ID, retryAt, attempt.StatusCode, attempt.Body, attempt.Error, attempt.durationMs())
+	if err != nil {
+		return false, fmt.Errorf("记录Webhook投递结果失败: %w", err)
+	}
+
+	var state struct {
+		Failures int  `db:"consecutive_failures"`
+		Active   bool `db:"active"`
+	}
+	query = `
+		UPDATE webhook_endpoints
+		SET consecutive_failures = consecutive_failures + 1, last_failure_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING consecutive_failures, active
+	`
+	if err := tx.GetContext(ctx, &state, query, delivery.EndpointID); err != nil {
+		return false, fmt.Errorf("更新Webhook接收端失败: %w", err)
+	}
+
+	disabled := false
+	if state.Active && disableAfter > 0 && state.Failures >= disableAfter {
+		query = `
+			UPDATE webhook_endpoints
+			SET active = FALSE, disabled_reason = $2, disabled_at = NOW(), updated_at = NOW()
+			WHERE id = $1
+		`
+		reason := fmt.Sprintf("连续%d次投递失败，已自动停用", state.Failures)
+		if _, err := tx.ExecContext(ctx, query, delivery.EndpointID, reason); err != nil {
+			return false, fmt.Errorf("停用Webhook接收端失败: %w", err)
+		}
+		if err := failPendingDeliveries(ctx, tx, delivery.EndpointID); err != nil {
+			return false, err
+		}
+		disabled = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return disabled, nil
+}
+
+// failPendingDeliveries 接收端停用后把其待投递记录置为失败，重新启用后可手动重新投递
+func failPendingDeliveries(ctx context.Context, tx *sqlx.Tx, endpointID uuid.UUID) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'failed', locked_until = NULL, last_error = $2, updated_at = NOW()
+		WHERE endpoint_id = $1 AND status = 'pending'
+	`
+	if _, err := tx.ExecContext(ctx, query, endpointID, webhookDisabledError); err != nil {
+		return fmt.Errorf("更新待投递记录失败: %w", err)
+	}
+	return nil
+}
+
+// RecordPing 记录一次测试投递
+func (r *WebhookRepository) RecordPing(ctx context.Context, endpoint *entities.WebhookEndpoint, eventID uuid.UUID, eventType string, payload []byte, attempt *WebhookAttempt) (*entities.WebhookDelivery, error) {
+	status := entities.WebhookDeliveryFailed
+	if attempt.Error == "" {
+		status = entities.WebhookDeliverySucceeded
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (
+			endpoint_id, merchant_id, event_id, event_type, payload, status, attempts,
+			response_status, response_body, last_error, duration_ms, delivered_at
+		) VALUES ($1, $2, $3, $4, $5, $6::text, 1, $7, $8, $9, $10, CASE WHEN $6::text = 'succeeded' THEN NOW() END)
+		RETURNING ` + webhookDeliveryColumns + `, payload, response_body`
+
+	var delivery entities.WebhookDelivery
+	err := r.DB.GetContext(ctx, &delivery, query,
+		endpoint.ID, endpoint.MerchantID, eventID, eventType, payload, status,
+		attempt.StatusCode, attempt.Body, attempt.Error, attempt.durationMs())
+	if err != nil {
+		return nil, fmt.Errorf("记录Webhook测试投递失败: %w", err)
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries 分页获取接收端的投递记录，按创建时间倒序，status为空时返回全部状态
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, merchantID, endpointID uuid.UUID, status entities.WebhookDeliveryStatus, page, pageSize int) ([]*entities.WebhookDelivery, int, error) {
+	where := "merchant_id = $1 AND endpoint_id = $2"
+	params := []interface{}{merchantID, endpointID}
+	if status != "" {
+		params = append(params, status)
+		where += fmt.Sprintf(" AND status = $%d", len(params))
+	}
+
+	var total int
+	if err := r.DB.GetContext(ctx, &total, "SELECT COUNT(*) FROM webhook_deliveries WHERE "+where, params...); err != nil {
+		return nil, 0, fmt.Errorf("获取Webhook投递记录总数失败: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM webhook_deliveries WHERE %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d`,
+		webhookDeliveryColumns, where, len(params)+1, len(params)+2)
+	params = append(params, pageSize, (page-1)*pageSize)
+
+	var deliveries []*entities.WebhookDelivery
+	if err := r.DB.SelectContext(ctx, &deliveries, query, params...); err != nil {
+		return nil, 0, fmt.Errorf("获取Webhook投递记录失败: %w", err)
+	}
+	return deliveries, total, nil
+}
+
+// FindDelivery 获取投递记录，包含请求体和响应内容
+func (r *WebhookRepository) FindDelivery(ctx context.Context, merchantID, endpointID, id uuid.UUID) (*entities.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `, payload, response_body
+		FROM webhook_deliveries
+		WHERE id = $1 AND endpoint_id = $2 AND merchant_id = $3
+	`
+
+	var delivery entities.WebhookDelivery
+	if err := r.DB.GetContext(ctx, &delivery, query, id, endpointID, merchantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, fmt.Errorf("获取Webhook投递记录失败: %w", err)
+	}
+	return &delivery, nil
+}
+
+// Redeliver 以原投递记录的事件ID和请求体生成新的待投递记录
+func (r *WebhookRepository) Redeliver(ctx context.Context, merchantID, endpointID, id uuid.UUID) (*entities.WebhookDelivery, error) {
+	query := `
+		INSERT INTO webhook_deliveries (endpoint_id, merchant_id, event_id, event_type, payload, redelivery_of)
+		SELECT endpoint_id, merchant_id, event_id, event_type, payload, id
+		FROM webhook_deliveries
+		WHERE id = $1 AND endpoint_id = $2 AND merchant_id = $3
+		RETURNING ` + webhookDeliveryColumns
+
+	var delivery entities.WebhookDelivery
+	if err := r.DB.GetContext(ctx, &delivery, query, id, endpointID, merchantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, fmt.Errorf("重新投递Webhook失败: %w", err)
+	}
+	return &delivery, nil
+}
+
+// PurgeDeliveries 删除before之前创建且已结束的投递记录，返回删除的数量
+func (r *WebhookRepository) PurgeDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM webhook_deliveries WHERE created_at < $1 AND status <> 'pending'`
+	result, err := r.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("清理Webhook投递记录失败: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
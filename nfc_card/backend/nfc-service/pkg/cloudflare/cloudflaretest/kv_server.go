@@ -0,0 +1,278 @@
+package cloudflaretest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 错误代码，与Cloudflare API的错误代码含义一致
+const (
+	CodeAuthentication = 10000
+	CodeNotFound       = 10009
+	CodeInternal       = 10001
+	CodeBadRequest     = 10026
+)
+
+// Entry 模拟KV中存储的键值
+type Entry struct {
+	Value    string
+	Metadata map[string]string
+}
+
+// KVServer 模拟Cloudflare Workers KV API的本地服务，用于测试cloudflare.WorkersClient及其调用方
+// 只实现客户端用到的接口：键值读写、批量写入/删除和分页列出键
+type KVServer struct {
+	*httptest.Server
+
+	Token       string
+	AccountID   string
+	NamespaceID string
+	// PageSize 列出键时每页的最大数量，小于等于0时使用请求中的limit
+	PageSize int
+
+	mu       sync.Mutex
+	entries  map[string]Entry
+	failures int
+	requests []string
+}
+
+// NewKVServer 启动模拟服务，请求必须携带Bearer token才能访问指定账户和命名空间
+func NewKVServer(token, accountID, namespaceID string) *KVServer {
+	s := &KVServer{
+		Token:       token,
+		AccountID:   accountID,
+		NamespaceID: namespaceID,
+		entries:     make(map[string]Entry),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Set 直接写入键值，用于准备测试数据
+func (s *KVServer) Set(key, value string, metadata map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = Entry{Value: value, Metadata: metadata}
+}
+
+// Get 直接读取键值
+func (s *KVServer) Get(key string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	return entry, ok
+}
+
+// Keys 返回按字典序排列的所有键
+func (s *KVServer) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedKeys("")
+}
+
+// FailNext 之后的n个请求返回500错误
+func (s *KVServer) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+}
+
+// Requests 返回收到的请求，格式为"方法 路径"，路径不含命名空间前缀
+func (s *KVServer) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// handle 分发请求
+func (s *KVServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+s.Token {
+		writeError(w, http.StatusForbidden, CodeAuthentication, "Authentication error")
+		return
+	}
+
+	prefix := fmt.Sprintf("/accounts/%s/storage/kv/namespaces/%s", s.AccountID, s.NamespaceID)
+	if !strings.HasPrefix(r.URL.EscapedPath(), prefix+"/") {
+		writeError(w, http.StatusNotFound, CodeNotFound, "namespace not found")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.EscapedPath(), prefix)
+	s.requests = append(s.requests, r.Method+" "+path)
+
+	if s.failures > 0 {
+		s.failures--
+		writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+		return
+	}
+
+	switch {
+	case path == "/bulk" && r.Method == http.MethodPut:
+		s.bulkPut(w, r)
+	case path == "/bulk" && r.Method == http.MethodDelete:
+		s.bulkDelete(w, r)
+	case path == "/keys" && r.Method == http.MethodGet:
+		s.listKeys(w, r)
+	case strings.HasPrefix(path, "/values/"):
+		key, err := url.PathUnescape(strings.TrimPrefix(path, "/values/"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid key")
+			return
+		}
+		s.value(w, r, key)
+	default:
+		writeError(w, http.StatusNotFound, CodeNotFound, "route not found")
+	}
+}
+
+// bulkPut 批量写入
+func (s *KVServer) bulkPut(w http.ResponseWriter, r *http.Request) {
+	var entries []struct {
+		Key      string            `json:"key"`
+		Value    string            `json:"value"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid bulk write body")
+		return
+	}
+	for _, entry := range entries {
+		s.entries[entry.Key] = Entry{Value: entry.Value, Metadata: entry.Metadata}
+	}
+	writeResult(w, nil, nil)
+}
+
+// bulkDelete 批量删除
+func (s *KVServer) bulkDelete(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	if err := json.NewDecoder(r.Body).Decode(&keys); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid bulk delete body")
+		return
+	}
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	writeResult(w, nil, nil)
+}
+
+// listKeys 分页列出键，游标为下一页起始位置
+func (s *KVServer) listKeys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if s.PageSize > 0 && (limit <= 0 || limit > s.PageSize) {
+		limit = s.PageSize
+	}
+	if limit <= 0 {
+		limit = 1000
+	}
+
+	start := 0
+	if cursor := query.Get("cursor"); cursor != "" {
+		var err error
+		if start, err = strconv.Atoi(cursor); err != nil || start < 0 {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid cursor")
+			return
+		}
+	}
+
+	keys := s.sortedKeys(query.Get("prefix"))
+	if start > len(keys) {
+		start = len(keys)
+	}
+	end := start + limit
+	if end > len(keys) {
+		end = len(keys)
+	}
+
+	page := make([]map[string]interface{}, 0, end-start)
+	for _, key := range keys[start:end] {
+		item := map[string]interface{}{"name": key}
+		if metadata := s.entries[key].Metadata; metadata != nil {
+			item["metadata"] = metadata
+		}
+		page = append(page, item)
+	}
+
+	cursor := ""
+	if end < len(keys) {
+		cursor = strconv.Itoa(end)
+	}
+	writeResult(w, page, map[string]interface{}{"count": len(page), "cursor": cursor})
+}
+
+// value 读写单个键
+func (s *KVServer) value(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodGet:
+		entry, ok := s.entries[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, CodeNotFound, "key not found")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, entry.Value)
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid value")
+			return
+		}
+		s.entries[key] = Entry{Value: string(body)}
+		writeResult(w, nil, nil)
+	case http.MethodDelete:
+		if _, ok := s.entries[key]; !ok {
+			writeError(w, http.StatusNotFound, CodeNotFound, "key not found")
+			return
+		}
+		delete(s.entries, key)
+		writeResult(w, nil, nil)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, CodeBadRequest, "method not allowed")
+	}
+}
+
+// sortedKeys 返回带指定前缀的键，调用方需持有锁
+func (s *KVServer) sortedKeys(prefix string) []string {
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeResult 写入成功响应
+func writeResult(w http.ResponseWriter, result interface{}, resultInfo interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"errors":      []interface{}{},
+		"messages":    []interface{}{},
+		"result":      result,
+		"result_info": resultInfo,
+	})
+}
+
+// writeError 写入Cloudflare格式的错误响应
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  false,
+		"errors":   []map[string]interface{}{{"code": code, "message": message}},
+		"messages": []interface{}{},
+		"result":   nil,
+	})
+}
@@ -1,38 +1,347 @@
 package cloudflare
 
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBaseURL Cloudflare API默认地址
+	DefaultBaseURL = "https://api.cloudflare.com/client/v4"
+
+	// maxBulkSize Workers KV批量接口单次最多处理的键数量
+	maxBulkSize = 10000
+	// listPageSize 列出键时每页的数量
+	listPageSize = 1000
+	// metadataTargetHash 存储在键元数据中的目标URL摘要字段
+	metadataTargetHash = "target_hash"
+)
+
+// ErrKeyNotFound KV中不存在指定的键
+var ErrKeyNotFound = errors.New("KV键不存在")
+
+// KVEntry Workers KV中的一个键值对
+type KVEntry struct {
+	Key      string            `json:"key"`
+	Value    string            `json:"value"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// KVKey 列出键时返回的键信息
+type KVKey struct {
+	Name     string            `json:"name"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// APIError Cloudflare API返回的错误
+type APIError struct {
+	StatusCode int
+	Code       int
+	Message    string
+}
+
+// Error 实现error接口
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Cloudflare API错误(HTTP %d, 代码 %d): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// apiResponse Cloudflare API的通用响应结构
+type apiResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result     json.RawMessage `json:"result"`
+	ResultInfo struct {
+		Cursor string `json:"cursor"`
+		Count  int    `json:"count"`
+	} `json:"result_info"`
+}
+
 // WorkersClient 是Cloudflare Workers API的客户端
+//
+// 短链接以 slug -> 目标URL 的形式写入Workers KV命名空间，由边缘Worker读取后直接重定向；
+// 键不存在时Worker应回源到nfc-service处理。
 type WorkersClient struct {
-	APIToken string
-	ZoneID   string
+	APIToken    string
+	AccountID   string
+	NamespaceID string
+	BaseURL     string
+	HTTPClient  *http.Client
 }
 
 // NewWorkersClient 创建一个新的Cloudflare Workers客户端
-func NewWorkersClient(apiToken, zoneID string) *WorkersClient {
+// baseURL为空时使用DefaultBaseURL，测试时可指向本地模拟的Cloudflare API
+func NewWorkersClient(baseURL, apiToken, accountID, namespaceID string) *WorkersClient {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
 	return &WorkersClient{
-		APIToken: apiToken,
-		ZoneID:   zoneID,
+		APIToken:    apiToken,
+		AccountID:   accountID,
+		NamespaceID: namespaceID,
+		BaseURL:     strings.TrimRight(baseURL, "/"),
+		HTTPClient:  &http.Client{Timeout: 15 * time.Second},
 	}
 }
 
 // CreateRedirect 创建重定向规则
 func (c *WorkersClient) CreateRedirect(slug, targetURL string) error {
-	// 实现创建重定向的逻辑
-	// 在实际环境中，这里应该调用Cloudflare API
-	return nil
+	return c.PutRedirects(context.Background(), map[string]string{slug: targetURL})
 }
 
 // UpdateRedirect 更新重定向规则
 func (c *WorkersClient) UpdateRedirect(slug, targetURL string) error {
-	// 实现更新重定向的逻辑
-	// 在实际环境中，这里应该调用Cloudflare API
-	return nil
+	return c.PutRedirects(context.Background(), map[string]string{slug: targetURL})
 }
 
 // DeleteRedirect 删除重定向规则
 func (c *WorkersClient) DeleteRedirect(slug string) error {
-	// 实现删除重定向的逻辑
-	// 在实际环境中，这里应该调用Cloudflare API
+	return c.BulkDelete(context.Background(), []string{slug})
+}
+
+// PutRedirects 批量写入重定向规则，键的元数据中记录目标URL的摘要，便于对账时比较
+func (c *WorkersClient) PutRedirects(ctx context.Context, redirects map[string]string) error {
+	entries := make([]KVEntry, 0, len(redirects))
+	for slug, targetURL := range redirects {
+		entries = append(entries, KVEntry{
+			Key:      slug,
+			Value:    targetURL,
+			Metadata: map[string]string{metadataTargetHash: TargetHash(targetURL)},
+		})
+	}
+	return c.BulkPut(ctx, entries)
+}
+
+// ListRedirects 列出KV中所有重定向规则，返回 slug -> 目标URL摘要
+func (c *WorkersClient) ListRedirects(ctx context.Context) (map[string]string, error) {
+	keys, err := c.ListKeys(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	redirects := make(map[string]string, len(keys))
+	for _, key := range keys {
+		redirects[key.Name] = key.Metadata[metadataTargetHash]
+	}
+	return redirects, nil
+}
+
+// TargetHash 计算目标URL的摘要
+func TargetHash(targetURL string) string {
+	sum := sha256.Sum256([]byte(targetURL))
+	return hex.EncodeToString(sum[:8])
+}
+
+// GetValue 读取键的值
+func (c *WorkersClient) GetValue(ctx context.Context, key string) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/values/"+url.PathEscape(key), nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("读取KV值失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrKeyNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", decodeError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("读取KV值失败: %w", err)
+	}
+	return string(body), nil
+}
+
+// PutValue 写入单个键值
+func (c *WorkersClient) PutValue(ctx context.Context, key, value string) error {
+	req, err := c.newRequest(ctx, http.MethodPut, "/values/"+url.PathEscape(key), strings.NewReader(value))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	_, err = c.do(req)
+	return err
+}
+
+// DeleteValue 删除单个键，键不存在时不返回错误
+func (c *WorkersClient) DeleteValue(ctx context.Context, key string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, "/values/"+url.PathEscape(key), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(req)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// BulkPut 批量写入键值对
+func (c *WorkersClient) BulkPut(ctx context.Context, entries []KVEntry) error {
+	for start := 0; start < len(entries); start += maxBulkSize {
+		end := start + maxBulkSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		body, err := json.Marshal(entries[start:end])
+		if err != nil {
+			return fmt.Errorf("序列化KV批量写入请求失败: %w", err)
+		}
+
+		req, err := c.newRequest(ctx, http.MethodPut, "/bulk", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		if _, err := c.do(req); err != nil {
+			return fmt.Errorf("KV批量写入失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// BulkDelete 批量删除键
+func (c *WorkersClient) BulkDelete(ctx context.Context, keys []string) error {
+	for start := 0; start < len(keys); start += maxBulkSize {
+		end := start + maxBulkSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		body, err := json.Marshal(keys[start:end])
+		if err != nil {
+			return fmt.Errorf("序列化KV批量删除请求失败: %w", err)
+		}
+
+		req, err := c.newRequest(ctx, http.MethodDelete, "/bulk", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		if _, err := c.do(req); err != nil {
+			return fmt.Errorf("KV批量删除失败: %w", err)
+		}
+	}
 	return nil
 }
 
-// 根据需要添加更多方法，如创建、更新或删除Workers等
+// ListKeys 分页列出命名空间中的所有键
+func (c *WorkersClient) ListKeys(ctx context.Context, prefix string) ([]KVKey, error) {
+	var keys []KVKey
+	cursor := ""
+
+	for {
+		query := url.Values{}
+		query.Set("limit", fmt.Sprintf("%d", listPageSize))
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+
+		req, err := c.newRequest(ctx, http.MethodGet, "/keys?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := c.do(req)
+		if err != nil {
+			return nil, fmt.Errorf("列出KV键失败: %w", err)
+		}
+
+		var page []KVKey
+		if err := json.Unmarshal(result.Result, &page); err != nil {
+			return nil, fmt.Errorf("解析KV键列表失败: %w", err)
+		}
+		keys = append(keys, page...)
+
+		cursor = result.ResultInfo.Cursor
+		if cursor == "" || len(page) == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// newRequest 创建指向KV命名空间的请求
+func (c *WorkersClient) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	if c.AccountID == "" || c.NamespaceID == "" {
+		return nil, errors.New("未配置Cloudflare账户ID或KV命名空间ID")
+	}
+
+	endpoint := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s%s",
+		c.BaseURL, url.PathEscape(c.AccountID), url.PathEscape(c.NamespaceID), path)
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建Cloudflare请求失败: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.APIToken)
+	return req, nil
+}
+
+// do 发送请求并解析Cloudflare的通用响应
+func (c *WorkersClient) do(req *http.Request) (*apiResponse, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求Cloudflare API失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, decodeError(resp)
+	}
+
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析Cloudflare响应失败: %w", err)
+	}
+	if !result.Success {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: "请求未成功"}
+		if len(result.Errors) > 0 {
+			apiErr.Code = result.Errors[0].Code
+			apiErr.Message = result.Errors[0].Message
+		}
+		return nil, apiErr
+	}
+
+	return &result, nil
+}
+
+// decodeError 从错误响应中解析APIError
+func decodeError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && len(result.Errors) > 0 {
+		apiErr.Code = result.Errors[0].Code
+		apiErr.Message = result.Errors[0].Message
+	}
+	return apiErr
+}
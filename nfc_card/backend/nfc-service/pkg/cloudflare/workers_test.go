@@ -0,0 +1,213 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nfc-service/pkg/cloudflare/cloudflaretest"
+)
+
+const (
+	testToken     = "test-token"
+	testAccount   = "account-1"
+	testNamespace = "namespace-1"
+)
+
+func newTestClient(t *testing.T) (*WorkersClient, *cloudflaretest.KVServer) {
+	t.Helper()
+	server := cloudflaretest.NewKVServer(testToken, testAccount, testNamespace)
+	t.Cleanup(server.Close)
+	return NewWorkersClient(server.URL, testToken, testAccount, testNamespace), server
+}
+
+func TestPutRedirectsStoresValueAndTargetHash(t *testing.T) {
+	client, server := newTestClient(t)
+
+	redirects := map[string]string{
+		"abc":  "https://example.com/a",
+		"spec": "https://example.com/path?q=1&x=y",
+	}
+	if err := client.PutRedirects(context.Background(), redirects); err != nil {
+		t.Fatalf("PutRedirects: %v", err)
+	}
+
+	for slug, target := range redirects {
+		entry, ok := server.Get(slug)
+		if !ok {
+			t.Fatalf("键%q未写入", slug)
+		}
+		if entry.Value != target {
+			t.Errorf("键%q的值为%q，期望%q", slug, entry.Value, target)
+		}
+		if got := entry.Metadata[metadataTargetHash]; got != TargetHash(target) {
+			t.Errorf("键%q的摘要为%q，期望%q", slug, got, TargetHash(target))
+		}
+	}
+
+	if got := server.Requests(); !reflect.DeepEqual(got, []string{"PUT /bulk"}) {
+		t.Errorf("请求为%v，期望一次批量写入", got)
+	}
+}
+
+func TestBulkDeleteRemovesKeys(t *testing.T) {
+	client, server := newTestClient(t)
+	server.Set("keep", "https://example.com/keep", nil)
+	server.Set("drop1", "https://example.com/1", nil)
+	server.Set("drop2", "https://example.com/2", nil)
+
+	if err := client.BulkDelete(context.Background(), []string{"drop1", "drop2", "missing"}); err != nil {
+		t.Fatalf("BulkDelete: %v", err)
+	}
+
+	if got := server.Keys(); !reflect.DeepEqual(got, []string{"keep"}) {
+		t.Errorf("剩余的键为%v，期望[keep]", got)
+	}
+}
+
+func TestDeleteValueIgnoresMissingKey(t *testing.T) {
+	client, server := newTestClient(t)
+	server.Set("a/b", "https://example.com", nil)
+
+	if err := client.DeleteValue(context.Background(), "a/b"); err != nil {
+		t.Fatalf("DeleteValue: %v", err)
+	}
+	if _, ok := server.Get("a/b"); ok {
+		t.Error("键未删除")
+	}
+	if err := client.DeleteValue(context.Background(), "a/b"); err != nil {
+		t.Errorf("删除不存在的键返回错误: %v", err)
+	}
+}
+
+func TestPutAndGetValue(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	if err := client.PutValue(ctx, "slug", "https://example.com"); err != nil {
+		t.Fatalf("PutValue: %v", err)
+	}
+	value, err := client.GetValue(ctx, "slug")
+	if err != nil {
+		t.Fatalf("GetValue: %v", err)
+	}
+	if value != "https://example.com" {
+		t.Errorf("GetValue = %q", value)
+	}
+
+	if _, err := client.GetValue(ctx, "missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("读取不存在的键返回%v，期望ErrKeyNotFound", err)
+	}
+}
+
+func TestListKeysFollowsCursor(t *testing.T) {
+	client, server := newTestClient(t)
+	server.PageSize = 2
+
+	want := make(map[string]string)
+	for i := 0; i < 5; i++ {
+		slug := fmt.Sprintf("slug%d", i)
+		target := fmt.Sprintf("https://example.com/%d", i)
+		server.Set(slug, target, map[string]string{metadataTargetHash: TargetHash(target)})
+		want[slug] = TargetHash(target)
+	}
+
+	got, err := client.ListRedirects(context.Background())
+	if err != nil {
+		t.Fatalf("ListRedirects: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListRedirects = %v，期望%v", got, want)
+	}
+
+	listRequests := 0
+	for _, request := range server.Requests() {
+		if strings.HasPrefix(request, "GET /keys") {
+			listRequests++
+		}
+	}
+	if listRequests != 3 {
+		t.Errorf("分页请求%d次，期望3次", listRequests)
+	}
+}
+
+func TestListKeysWithPrefix(t *testing.T) {
+	client, server := newTestClient(t)
+	server.Set("promo-a", "1", nil)
+	server.Set("promo-b", "2", nil)
+	server.Set("other", "3", nil)
+
+	keys, err := client.ListKeys(context.Background(), "promo-")
+	if err != nil {
+		t.Fatalf("ListKeys: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Name != "promo-a" || keys[1].Name != "promo-b" {
+		t.Errorf("ListKeys = %+v", keys)
+	}
+}
+
+func TestAuthenticationError(t *testing.T) {
+	server := cloudflaretest.NewKVServer(testToken, testAccount, testNamespace)
+	defer server.Close()
+	client := NewWorkersClient(server.URL, "wrong-token", testAccount, testNamespace)
+
+	err := client.PutRedirects(context.Background(), map[string]string{"a": "https://example.com"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("错误类型为%T，期望*APIError: %v", err, err)
+	}
+	if apiErr.StatusCode != http.StatusForbidden || apiErr.Code != cloudflaretest.CodeAuthentication {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+	if len(server.Keys()) != 0 {
+		t.Error("认证失败的请求不应写入数据")
+	}
+}
+
+func TestServerErrorEnvelope(t *testing.T) {
+	client, server := newTestClient(t)
+	server.FailNext(1)
+
+	_, err := client.ListKeys(context.Background(), "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("错误类型为%T，期望*APIError: %v", err, err)
+	}
+	if apiErr.StatusCode != http.StatusInternalServerError || apiErr.Code != cloudflaretest.CodeInternal || apiErr.Message != "internal error" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+
+	if _, err := client.ListKeys(context.Background(), ""); err != nil {
+		t.Errorf("恢复后的请求失败: %v", err)
+	}
+}
+
+func TestUnsuccessfulEnvelopeWithOKStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"success":false,"errors":[{"code":10014,"message":"namespace busy"}],"result":null}`)
+	}))
+	defer server.Close()
+	client := NewWorkersClient(server.URL, testToken, testAccount, testNamespace)
+
+	err := client.BulkDelete(context.Background(), []string{"a"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("错误类型为%T，期望*APIError: %v", err, err)
+	}
+	if apiErr.StatusCode != http.StatusOK || apiErr.Code != 10014 || apiErr.Message != "namespace busy" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+}
+
+func TestMissingNamespaceConfig(t *testing.T) {
+	client := NewWorkersClient("http://127.0.0.1:0", testToken, testAccount, "")
+	if err := client.PutValue(context.Background(), "a", "b"); err == nil {
+		t.Error("未配置命名空间时应返回错误")
+	}
+}
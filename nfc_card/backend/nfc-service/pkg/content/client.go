@@ -0,0 +1,101 @@
+package content
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrVideoNotFound 视频不存在或不属于指定商户
+var ErrVideoNotFound = errors.New("视频不存在")
+
+// 视频转码状态
+const (
+	TranscodeCompleted = "completed"
+	TranscodeFailed    = "failed"
+)
+
+// Video content-service返回的视频播放信息
+type Video struct {
+	ID              uuid.UUID `json:"id"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	URL             string    `json:"url"`
+	MP4URL          string    `json:"mp4Url"`
+	CoverURL        string    `json:"coverUrl"`
+	Duration        float64   `json:"duration"`
+	Width           int       `json:"width"`
+	Height          int       `json:"height"`
+	IsTranscoded    bool      `json:"isTranscoded"`
+	TranscodeStatus string    `json:"transcodeStatus"`
+}
+
+// Playable 视频是否有可播放的地址
+func (v *Video) Playable() bool {
+	return v.URL != "" && v.TranscodeStatus != TranscodeFailed
+}
+
+// HLS 播放地址是否为HLS主播放列表，此时MP4URL为不支持HLS的浏览器提供回退
+func (v *Video) HLS() bool {
+	return v.MP4URL != "" && v.MP4URL != v.URL
+}
+
+// Client content-service内部接口的客户端
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// NewClient 创建content-service客户端，token为服务间调用令牌
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// GetVideo 获取商户视频的播放地址和封面
+func (c *Client) GetVideo(ctx context.Context, merchantID, videoID uuid.UUID) (*Video, error) {
+	endpoint := fmt.Sprintf("%s/internal/v1/videos/%s?merchantId=%s", c.BaseURL, url.PathEscape(videoID.String()), url.QueryEscape(merchantID.String()))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建视频请求失败: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Internal-Token", c.Token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求content-service失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("读取content-service响应失败: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrVideoNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("content-service返回状态码%d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var video Video
+	if err := json.Unmarshal(body, &video); err != nil {
+		return nil, fmt.Errorf("解析视频信息失败: %w", err)
+	}
+	return &video, nil
+}
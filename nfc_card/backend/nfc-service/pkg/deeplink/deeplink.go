@@ -0,0 +1,168 @@
+package deeplink
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"nfc-service/pkg/useragent"
+)
+
+// 平台常量，与distribution-service的发布渠道一致
+const (
+	PlatformDouyin      = "douyin"
+	PlatformXiaohongshu = "xiaohongshu"
+	PlatformKuaishou    = "kuaishou"
+)
+
+// ErrUnsafeURL App链接使用了不允许的scheme
+var ErrUnsafeURL = errors.New("App链接无效")
+
+// Link 平台内容的原生App链接
+type Link struct {
+	Platform       string
+	AppURL         string
+	AndroidPackage string
+	WebURL         string
+}
+
+// platform 平台的链接格式，{id}替换为平台内容ID
+type platform struct {
+	name           string // 展示名称
+	appURL         string
+	webURL         string
+	androidPackage string
+	browser        string // 平台App内置浏览器在useragent中的名称
+}
+
+// 视频号没有公开的scheme，不在此列表中，发布完成后不会自动生成深度链接
+var platforms = map[string]platform{
+	PlatformDouyin: {
+		name:           "抖音",
+		appURL:         "snssdk1128://aweme/detail/{id}",
+		webURL:         "https://www.douyin.com/video/{id}",
+		androidPackage: "com.ss.android.ugc.aweme",
+		browser:        useragent.BrowserDouyin,
+	},
+	PlatformXiaohongshu: {
+		name:           "小红书",
+		appURL:         "xhsdiscover://item/{id}",
+		webURL:         "https://www.xiaohongshu.com/explore/{id}",
+		androidPackage: "com.xingin.xhs",
+		browser:        useragent.BrowserXiaohongshu,
+	},
+	PlatformKuaishou: {
+		name:           "快手",
+		appURL:         "kwai://work?photoId={id}",
+		webURL:         "https://www.kuaishou.com/short-video/{id}",
+		androidPackage: "com.smile.gifmaker",
+		browser:        useragent.BrowserKuaishou,
+	},
+}
+
+// contentIDPattern 平台内容ID只允许字母、数字、下划线和连字符，避免拼接出异常的链接
+var contentIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// 不允许作为App链接的scheme
+var blockedSchemes = map[string]bool{
+	"javascript": true,
+	"data":       true,
+	"vbscript":   true,
+	"file":       true,
+	"about":      true,
+	"blob":       true,
+	"intent":     true, // intent链接由服务根据包名生成
+}
+
+// Build 根据平台和内容ID生成原生App链接，webURL为空时使用平台的网页地址
+// 各平台的通用链接需要平台方授权域名，自动生成的链接只包含自定义scheme
+// 平台不支持或内容ID格式无效时返回false
+func Build(platformName, contentID, webURL string) (Link, bool) {
+	p, ok := platforms[platformName]
+	if !ok || !contentIDPattern.MatchString(contentID) {
+		return Link{}, false
+	}
+
+	escaped := url.QueryEscape(contentID)
+	link := Link{
+		Platform:       platformName,
+		AppURL:         strings.ReplaceAll(p.appURL, "{id}", escaped),
+		AndroidPackage: p.androidPackage,
+		WebURL:         webURL,
+	}
+	if link.WebURL == "" {
+		link.WebURL = strings.ReplaceAll(p.webURL, "{id}", escaped)
+	}
+	return link, true
+}
+
+// Supported 判断平台是否支持自动生成深度链接
+func Supported(platformName string) bool {
+	_, ok := platforms[platformName]
+	return ok
+}
+
+// DisplayName 返回平台的展示名称，未知平台返回"App"
+func DisplayName(platformName string) string {
+	if p, ok := platforms[platformName]; ok {
+		return p.name
+	}
+	return "App"
+}
+
+// PlatformForBrowser 返回App内置浏览器所属的平台，不是已知平台的内置浏览器时返回空字符串
+func PlatformForBrowser(browser string) string {
+	for name, p := range platforms {
+		if p.browser == browser {
+			return name
+		}
+	}
+	return ""
+}
+
+// ValidateAppURL 校验App链接，必须是带scheme的绝对地址，且不能使用可执行脚本或访问本地资源的scheme
+func ValidateAppURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return fmt.Errorf("%w: %s", ErrUnsafeURL, raw)
+	}
+	if blockedSchemes[strings.ToLower(u.Scheme)] {
+		return fmt.Errorf("%w: 不支持%s链接", ErrUnsafeURL, u.Scheme)
+	}
+	return nil
+}
+
+// IntentURL 将自定义scheme链接转换为Android Chrome的intent链接
+// App未安装时Chrome直接打开fallbackURL，不会停留在无法打开的页面
+func IntentURL(appURL, androidPackage, fallbackURL string) (string, error) {
+	u, err := url.Parse(appURL)
+	if err != nil || u.Scheme == "" {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeURL, appURL)
+	}
+	if u.Scheme == "http" || u.Scheme == "https" {
+		return "", fmt.Errorf("%w: 网页链接不需要转换为intent链接", ErrUnsafeURL)
+	}
+
+	rest := strings.TrimPrefix(appURL[len(u.Scheme)+1:], "//")
+
+	var b strings.Builder
+	b.WriteString("intent://")
+	b.WriteString(rest)
+	b.WriteString("#Intent;scheme=")
+	b.WriteString(u.Scheme)
+	b.WriteString(";")
+	if androidPackage != "" {
+		b.WriteString("package=")
+		b.WriteString(androidPackage)
+		b.WriteString(";")
+	}
+	if fallbackURL != "" {
+		b.WriteString("S.browser_fallback_url=")
+		b.WriteString(url.QueryEscape(fallbackURL))
+		b.WriteString(";")
+	}
+	b.WriteString("end")
+	return b.String(), nil
+}
@@ -0,0 +1,150 @@
+package geoip
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Location IP地址对应的地理位置
+type Location struct {
+	Country string `json:"country"`
+	Region  string `json:"region"`
+}
+
+// ipRange 一段IP地址区间及其所属位置
+type ipRange struct {
+	start    netip.Addr
+	end      netip.Addr
+	location Location
+}
+
+// DB 本地GeoIP数据库
+//
+// 数据文件为CSV格式，每行依次为: 起始IP,结束IP,国家代码,地区
+// 例如: 1.0.1.0,1.0.3.255,CN,福建
+// 同时支持IPv4与IPv6区间，区间之间不允许重叠。
+type DB struct {
+	ranges []ipRange
+}
+
+// Open 从文件加载GeoIP数据库
+func Open(path string) (*DB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开GeoIP数据库失败: %w", err)
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+// Load 从Reader加载GeoIP数据库
+func Load(r io.Reader) (*DB, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	db := &DB{}
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("解析GeoIP数据库第%d行失败: %w", line, err)
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("GeoIP数据库第%d行字段不足", line)
+		}
+
+		start, err := netip.ParseAddr(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("GeoIP数据库第%d行起始IP无效: %w", line, err)
+		}
+		end, err := netip.ParseAddr(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("GeoIP数据库第%d行结束IP无效: %w", line, err)
+		}
+		if start.Is4() != end.Is4() || end.Less(start) {
+			return nil, fmt.Errorf("GeoIP数据库第%d行IP区间无效", line)
+		}
+
+		location := Location{Country: strings.TrimSpace(record[2])}
+		if len(record) > 3 {
+			location.Region = strings.TrimSpace(record[3])
+		}
+
+		db.ranges = append(db.ranges, ipRange{start: start, end: end, location: location})
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return db.ranges[i].start.Less(db.ranges[j].start)
+	})
+
+	return db, nil
+}
+
+// Lookup 查询IP地址所在的地理位置，未找到时返回false
+func (db *DB) Lookup(ip string) (Location, bool) {
+	if db == nil || len(db.ranges) == 0 {
+		return Location{}, false
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Location{}, false
+	}
+	addr = addr.Unmap()
+
+	// 找到第一个起始IP大于addr的区间，前一个区间即为候选
+	idx := sort.Search(len(db.ranges), func(i int) bool {
+		return addr.Less(db.ranges[i].start)
+	})
+	if idx == 0 {
+		return Location{}, false
+	}
+
+	candidate := db.ranges[idx-1]
+	if candidate.start.Is4() != addr.Is4() || candidate.end.Less(addr) {
+		return Location{}, false
+	}
+
+	return candidate.location, true
+}
+
+// Len 返回数据库中的区间数量
+func (db *DB) Len() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.ranges)
+}
+
+// Anonymize 对IP地址进行匿名化处理
+// IPv4保留前24位，IPv6保留前48位，其余位清零；无法解析时返回空字符串
+func Anonymize(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+
+	return prefix.Addr().String()
+}
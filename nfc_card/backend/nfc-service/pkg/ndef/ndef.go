@@ -0,0 +1,246 @@
+package ndef
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// TNF 记录的类型名称格式（Type Name Format）
+type TNF byte
+
+const (
+	// TNFEmpty 空记录
+	TNFEmpty TNF = 0x00
+	// TNFWellKnown NFC Forum定义的类型，例如URI(U)和文本(T)
+	TNFWellKnown TNF = 0x01
+	// TNFMedia RFC 2046定义的媒体类型
+	TNFMedia TNF = 0x02
+	// TNFAbsoluteURI 绝对URI类型
+	TNFAbsoluteURI TNF = 0x03
+	// TNFExternal NFC Forum外部类型，例如Android应用记录
+	TNFExternal TNF = 0x04
+)
+
+// 记录头部标志位
+const (
+	flagMB = 0x80 // 消息的第一条记录
+	flagME = 0x40 // 消息的最后一条记录
+	flagSR = 0x10 // 短记录，负载长度用1个字节表示
+	flagIL = 0x08 // 包含ID字段
+)
+
+// AndroidApplicationType Android应用记录(AAR)的外部类型
+const AndroidApplicationType = "android.com:pkg"
+
+// ErrEmptyMessage NDEF消息中没有记录
+var ErrEmptyMessage = errors.New("NDEF消息不能为空")
+
+// Android包名由至少两段组成，每段以字母开头，例如 com.example.app
+var androidPackagePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\.[A-Za-z][A-Za-z0-9_]*)+$`)
+
+// uriPrefixes URI记录的前缀缩写表（NFC Forum URI RTD），下标即缩写码
+// 编码时选择能匹配的最长前缀，0x00表示不缩写
+var uriPrefixes = []string{
+	"",
+	"http://www.",
+	"https://www.",
+	"http://",
+	"https://",
+	"tel:",
+	"mailto:",
+	"ftp://anonymous:anonymous@",
+	"ftp://ftp.",
+	"ftps://",
+	"sftp://",
+	"smb://",
+	"nfs://",
+	"ftp://",
+	"dav://",
+	"news:",
+	"telnet://",
+	"imap:",
+	"rtsp://",
+	"urn:",
+	"pop:",
+	"sip:",
+	"sips:",
+	"tftp:",
+	"btspp://",
+	"btl2cap://",
+	"btgoep://",
+	"tcpobex://",
+	"irdaobex://",
+	"file://",
+	"urn:epc:id:",
+	"urn:epc:tag:",
+	"urn:epc:pat:",
+	"urn:epc:raw:",
+	"urn:epc:",
+	"urn:nfc:",
+}
+
+// Record 一条NDEF记录
+type Record struct {
+	TNF     TNF
+	Type    []byte
+	ID      []byte
+	Payload []byte
+}
+
+// NewURIRecord 创建URI记录，自动使用最长的前缀缩写以节省标签空间
+func NewURIRecord(uri string) (*Record, error) {
+	if uri == "" {
+		return nil, errors.New("URI不能为空")
+	}
+
+	code, rest := abbreviateURI(uri)
+	payload := make([]byte, 0, 1+len(rest))
+	payload = append(payload, code)
+	payload = append(payload, rest...)
+
+	return &Record{TNF: TNFWellKnown, Type: []byte("U"), Payload: payload}, nil
+}
+
+// abbreviateURI 返回URI的前缀缩写码和去掉前缀后的剩余部分
+func abbreviateURI(uri string) (byte, string) {
+	best := 0
+	for code := 1; code < len(uriPrefixes); code++ {
+		prefix := uriPrefixes[code]
+		if len(prefix) > len(uriPrefixes[best]) && strings.HasPrefix(uri, prefix) {
+			best = code
+		}
+	}
+	return byte(best), uri[len(uriPrefixes[best]):]
+}
+
+// NewTextRecord 创建UTF-8编码的文本记录，lang为IANA语言代码，例如zh-CN、en
+func NewTextRecord(text, lang string) (*Record, error) {
+	if lang == "" {
+		lang = "en"
+	}
+	if len(lang) > 0x3F {
+		return nil, fmt.Errorf("语言代码过长: %s", lang)
+	}
+	if !utf8.ValidString(text) {
+		return nil, errors.New("文本不是有效的UTF-8字符串")
+	}
+
+	payload := make([]byte, 0, 1+len(lang)+len(text))
+	// 状态字节：最高位0表示UTF-8，低6位为语言代码长度
+	payload = append(payload, byte(len(lang)))
+	payload = append(payload, lang...)
+	payload = append(payload, text...)
+
+	return &Record{TNF: TNFWellKnown, Type: []byte("T"), Payload: payload}, nil
+}
+
+// NewAndroidApplicationRecord 创建Android应用记录(AAR)
+// Android设备读取到包含AAR的标签时会优先交给该应用处理，未安装时跳转到应用商店
+func NewAndroidApplicationRecord(packageName string) (*Record, error) {
+	if !ValidAndroidPackage(packageName) {
+		return nil, fmt.Errorf("Android包名格式无效: %q", packageName)
+	}
+
+	return &Record{TNF: TNFExternal, Type: []byte(AndroidApplicationType), Payload: []byte(packageName)}, nil
+}
+
+// ValidAndroidPackage 判断Android应用包名格式是否有效
+func ValidAndroidPackage(packageName string) bool {
+	return androidPackagePattern.MatchString(packageName)
+}
+
+// encode 编码单条记录，first和last分别表示是否为消息的第一条和最后一条记录
+func (r *Record) encode(first, last bool) ([]byte, error) {
+	if len(r.Type) > 0xFF {
+		return nil, errors.New("NDEF记录类型过长")
+	}
+	if len(r.ID) > 0xFF {
+		return nil, errors.New("NDEF记录ID过长")
+	}
+
+	header := byte(r.TNF) & 0x07
+	if first {
+		header |= flagMB
+	}
+	if last {
+		header |= flagME
+	}
+	short := len(r.Payload) <= 0xFF
+	if short {
+		header |= flagSR
+	}
+	if len(r.ID) > 0 {
+		header |= flagIL
+	}
+
+	buf := make([]byte, 0, r.size())
+	buf = append(buf, header, byte(len(r.Type)))
+	if short {
+		buf = append(buf, byte(len(r.Payload)))
+	} else {
+		n := len(r.Payload)
+		buf = append(buf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	if len(r.ID) > 0 {
+		buf = append(buf, byte(len(r.ID)))
+	}
+	buf = append(buf, r.Type...)
+	buf = append(buf, r.ID...)
+	buf = append(buf, r.Payload...)
+
+	return buf, nil
+}
+
+// size 编码后记录的字节数
+func (r *Record) size() int {
+	n := 2 + len(r.Type) + len(r.ID) + len(r.Payload)
+	if len(r.Payload) <= 0xFF {
+		n++
+	} else {
+		n += 4
+	}
+	if len(r.ID) > 0 {
+		n++
+	}
+	return n
+}
+
+// Message 由一条或多条记录组成的NDEF消息
+type Message struct {
+	Records []*Record
+}
+
+// NewMessage 创建NDEF消息
+func NewMessage(records ...*Record) *Message {
+	return &Message{Records: records}
+}
+
+// Encode 将消息编码为NDEF字节数据（不包含标签上的TLV包装）
+func (m *Message) Encode() ([]byte, error) {
+	if len(m.Records) == 0 {
+		return nil, ErrEmptyMessage
+	}
+
+	var buf []byte
+	for i, record := range m.Records {
+		encoded, err := record.encode(i == 0, i == len(m.Records)-1)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, encoded...)
+	}
+	return buf, nil
+}
+
+// EncodeHex 将消息编码为大写十六进制字符串，便于写卡工具直接使用
+func (m *Message) EncodeHex() (string, error) {
+	data, err := m.Encode()
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(data)), nil
+}
@@ -0,0 +1,86 @@
+package ndef
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TagType NFC标签芯片型号
+type TagType string
+
+const (
+	// TagNTAG213 NTAG213，144字节用户存储区
+	TagNTAG213 TagType = "ntag213"
+	// TagNTAG215 NTAG215，504字节用户存储区
+	TagNTAG215 TagType = "ntag215"
+	// TagNTAG216 NTAG216，888字节用户存储区
+	TagNTAG216 TagType = "ntag216"
+)
+
+// 标签TLV块的类型
+const (
+	tlvNDEF       = 0x03
+	tlvTerminator = 0xFE
+)
+
+// tagCapacities 各型号标签可用于存储NDEF TLV的用户存储区字节数
+var tagCapacities = map[TagType]int{
+	TagNTAG213: 144,
+	TagNTAG215: 504,
+	TagNTAG216: 888,
+}
+
+// TagTypes 支持的标签型号，按容量从小到大排列
+var TagTypes = []TagType{TagNTAG213, TagNTAG215, TagNTAG216}
+
+// ParseTagType 解析标签型号，忽略大小写
+func ParseTagType(value string) (TagType, error) {
+	tag := TagType(strings.ToLower(strings.TrimSpace(value)))
+	if _, ok := tagCapacities[tag]; !ok {
+		return "", fmt.Errorf("不支持的标签型号: %s", value)
+	}
+	return tag, nil
+}
+
+// Capacity 标签用户存储区的字节数
+func (t TagType) Capacity() int {
+	return tagCapacities[t]
+}
+
+// WrapTLV 将NDEF消息包装为写入Type 2标签的TLV块，并追加终止符
+// 消息长度小于255字节时长度字段占1个字节，否则为0xFF加2个字节
+func WrapTLV(message []byte) []byte {
+	buf := make([]byte, 0, TLVSize(len(message)))
+	buf = append(buf, tlvNDEF)
+	if len(message) < 0xFF {
+		buf = append(buf, byte(len(message)))
+	} else {
+		buf = append(buf, 0xFF, byte(len(message)>>8), byte(len(message)))
+	}
+	buf = append(buf, message...)
+	buf = append(buf, tlvTerminator)
+	return buf
+}
+
+// TLVSize 长度为messageSize的NDEF消息包装为TLV后占用的字节数
+func TLVSize(messageSize int) int {
+	if messageSize < 0xFF {
+		return messageSize + 3
+	}
+	return messageSize + 5
+}
+
+// Fits 判断NDEF消息能否写入该型号的标签
+func (t TagType) Fits(messageSize int) bool {
+	return TLVSize(messageSize) <= t.Capacity()
+}
+
+// SmallestFit 返回能容纳该消息的最小标签型号，都放不下时返回false
+func SmallestFit(messageSize int) (TagType, bool) {
+	for _, tag := range TagTypes {
+		if tag.Fits(messageSize) {
+			return tag, true
+		}
+	}
+	return "", false
+}
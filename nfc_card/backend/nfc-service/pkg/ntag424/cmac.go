@@ -0,0 +1,101 @@
+package ntag424
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+)
+
+// KeySize NTAG 424 DNA使用的AES-128密钥长度
+const KeySize = 16
+
+// rb AES-128 CMAC子密钥生成使用的常量（RFC 4493）
+const rb = 0x87
+
+// newCipher 创建AES-128分组密码
+func newCipher(key []byte) (cipher.Block, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("%w: 需要%d字节，实际%d字节", ErrInvalidKey, KeySize, len(key))
+	}
+	return aes.NewCipher(key)
+}
+
+// subkeys 生成CMAC的子密钥K1和K2
+func subkeys(block cipher.Block) (k1, k2 []byte) {
+	l := make([]byte, aes.BlockSize)
+	block.Encrypt(l, l)
+	k1 = shiftLeft(l)
+	k2 = shiftLeft(k1)
+	return k1, k2
+}
+
+// shiftLeft 将分组左移一位，最高位溢出时与Rb异或
+func shiftLeft(in []byte) []byte {
+	out := make([]byte, len(in))
+	var carry byte
+	for i := len(in) - 1; i >= 0; i-- {
+		out[i] = in[i]<<1 | carry
+		carry = in[i] >> 7
+	}
+	if carry != 0 {
+		out[len(out)-1] ^= rb
+	}
+	return out
+}
+
+// CMAC 计算AES-128 CMAC（RFC 4493 / NIST SP 800-38B），返回16字节的完整MAC
+func CMAC(key, msg []byte) ([]byte, error) {
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cmac(block, msg), nil
+}
+
+// cmac 使用已创建的分组密码计算CMAC
+func cmac(block cipher.Block, msg []byte) []byte {
+	k1, k2 := subkeys(block)
+
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	complete := n > 0 && len(msg)%aes.BlockSize == 0
+	if n == 0 {
+		n = 1
+	}
+
+	// 最后一个分组：完整时与K1异或，否则按10*填充后与K2异或
+	last := make([]byte, aes.BlockSize)
+	offset := (n - 1) * aes.BlockSize
+	if complete {
+		copy(last, msg[offset:])
+		xor(last, k1)
+	} else {
+		rest := copy(last, msg[offset:])
+		last[rest] = 0x80
+		xor(last, k2)
+	}
+
+	mac := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		xor(mac, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(mac, mac)
+	}
+	xor(mac, last)
+	block.Encrypt(mac, mac)
+	return mac
+}
+
+// xor 将src异或到dst上
+func xor(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+// truncateMAC 按NTAG 424 DNA的规则截断MAC：取16字节CMAC中的奇数位字节（第1、3、…、15字节）
+func truncateMAC(mac []byte) []byte {
+	out := make([]byte, 0, len(mac)/2)
+	for i := 1; i < len(mac); i += 2 {
+		out = append(out, mac[i])
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package ntag424
+
+import (
+	"crypto/aes"
+	"errors"
+)
+
+// maxDiversificationInput AN10922 AES-128密钥分散输入M的最大长度
+const maxDiversificationInput = 31
+
+// 分散输入中用于区分SUN相关密钥的密钥编号
+const (
+	// KeyNoSDMMetaRead 解密PICC数据(UID和读计数器)的SDMMetaReadKey
+	KeyNoSDMMetaRead byte = 0x01
+	// KeyNoSDMFileRead 生成SDM MAC会话密钥的SDMFileReadKey
+	KeyNoSDMFileRead byte = 0x02
+)
+
+// DiversifyKey 按NXP AN10922的AES-128密钥分散算法由主密钥和分散输入派生卡片密钥
+// input为1到31字节的分散输入M，通常由UID、应用标识和系统标识组成
+// 计算CMAC(K, 0x01 || M)，其中数据固定填充到两个分组（32字节），不足时使用K2，恰好32字节时使用K1
+func DiversifyKey(masterKey, input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input) > maxDiversificationInput {
+		return nil, errors.New("密钥分散输入长度必须为1到31字节")
+	}
+
+	block, err := newCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	k1, k2 := subkeys(block)
+
+	data := make([]byte, 2*aes.BlockSize)
+	data[0] = 0x01
+	n := 1 + copy(data[1:], input)
+	if n < len(data) {
+		data[n] = 0x80
+		xor(data[aes.BlockSize:], k2)
+	} else {
+		xor(data[aes.BlockSize:], k1)
+	}
+
+	key := make([]byte, aes.BlockSize)
+	block.Encrypt(key, data[:aes.BlockSize])
+	xor(key, data[aes.BlockSize:])
+	block.Encrypt(key, key)
+	return key, nil
+}
+
+// DiversificationInput 构造卡片密钥的分散输入：UID || 密钥编号 || 密钥版本 || 系统标识
+// 同一张卡片的不同密钥编号、不同密钥版本都会派生出互不相关的密钥
+// 系统标识超出分散输入长度上限的部分会被截断
+func DiversificationInput(uid []byte, keyNo, keyVersion byte, systemIdentifier []byte) []byte {
+	input := make([]byte, 0, maxDiversificationInput)
+	input = append(input, uid...)
+	input = append(input, keyNo, keyVersion)
+	if room := maxDiversificationInput - len(input); room > 0 {
+		if len(systemIdentifier) > room {
+			systemIdentifier = systemIdentifier[:room]
+		}
+		input = append(input, systemIdentifier...)
+	}
+	return input
+}
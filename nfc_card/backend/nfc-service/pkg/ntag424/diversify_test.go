@@ -0,0 +1,75 @@
+package ntag424
+
+import (
+	"bytes"
+	"testing"
+)
+
+// NXP AN10922 AES-128密钥分散示例：M = UID || AID || 系统标识("NXP Abu")
+func TestDiversifyKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		masterKey string
+		input     string
+		key       string
+	}{
+		{"AN10922", "00112233445566778899aabbccddeeff", "04782e21801d803042f54e585020416275", "a8dd63a3b89d54b37ca802473fda9175"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := DiversifyKey(mustHex(t, tt.masterKey), mustHex(t, tt.input))
+			if err != nil {
+				t.Fatalf("DiversifyKey: %v", err)
+			}
+			if want := mustHex(t, tt.key); !bytes.Equal(key, want) {
+				t.Errorf("DiversifyKey = %x，期望%x", key, want)
+			}
+		})
+	}
+}
+
+func TestDiversifyKeyInputLength(t *testing.T) {
+	masterKey := make([]byte, KeySize)
+	for _, n := range []int{0, maxDiversificationInput + 1} {
+		if _, err := DiversifyKey(masterKey, make([]byte, n)); err == nil {
+			t.Errorf("%d字节的分散输入应返回错误", n)
+		}
+	}
+	if _, err := DiversifyKey(masterKey, make([]byte, maxDiversificationInput)); err != nil {
+		t.Errorf("%d字节的分散输入返回错误: %v", maxDiversificationInput, err)
+	}
+}
+
+func TestDiversificationInputSeparatesKeys(t *testing.T) {
+	uid := mustHex(t, "04de5f1eacc040")
+	systemID := []byte("nfc-card")
+
+	input := DiversificationInput(uid, KeyNoSDMMetaRead, 1, systemID)
+	if want := append(append(append([]byte(nil), uid...), KeyNoSDMMetaRead, 1), systemID...); !bytes.Equal(input, want) {
+		t.Errorf("DiversificationInput = %x，期望%x", input, want)
+	}
+
+	// 超长的系统标识被截断到分散输入上限
+	long := DiversificationInput(uid, KeyNoSDMMetaRead, 1, bytes.Repeat([]byte("x"), 64))
+	if len(long) != maxDiversificationInput {
+		t.Errorf("分散输入长度%d，期望%d", len(long), maxDiversificationInput)
+	}
+
+	masterKey := mustHex(t, "00112233445566778899aabbccddeeff")
+	keys := map[string]bool{}
+	for _, in := range [][]byte{
+		DiversificationInput(uid, KeyNoSDMMetaRead, 1, systemID),
+		DiversificationInput(uid, KeyNoSDMFileRead, 1, systemID),
+		DiversificationInput(uid, KeyNoSDMMetaRead, 2, systemID),
+	} {
+		key, err := DiversifyKey(masterKey, in)
+		if err != nil {
+			t.Fatalf("DiversifyKey: %v", err)
+		}
+		keys[string(key)] = true
+	}
+	if len(keys) != 3 {
+		t.Error("不同密钥编号和版本派生出了相同的密钥")
+	}
+}
@@ -0,0 +1,146 @@
+package ntag424
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// UIDSize NTAG 424 DNA的UID长度
+	UIDSize = 7
+	// MaxReadCounter SDM读计数器为3字节，达到最大值后标签不再递增
+	MaxReadCounter = 0xFFFFFF
+	// PICCDataSize 加密PICC数据的长度
+	PICCDataSize = aes.BlockSize
+	// MACSize 截断后SDM MAC的长度
+	MACSize = 8
+)
+
+// PICC数据标签字节的标志位
+const (
+	piccTagUIDMirror     = 0x80 // 包含UID
+	piccTagCounterMirror = 0x40 // 包含SDM读计数器
+	piccTagUIDLengthMask = 0x0F // 低4位为UID长度
+)
+
+var (
+	// ErrInvalidKey 密钥长度无效
+	ErrInvalidKey = errors.New("AES密钥长度无效")
+	// ErrInvalidPICCData 加密PICC数据格式无效或解密后格式不正确（通常是密钥不匹配）
+	ErrInvalidPICCData = errors.New("PICC数据无效")
+	// ErrInvalidMAC SDM MAC格式无效
+	ErrInvalidMAC = errors.New("SDM MAC格式无效")
+	// ErrMACMismatch SDM MAC校验失败
+	ErrMACMismatch = errors.New("SDM MAC校验失败")
+)
+
+// 会话密钥派生向量SV2的固定前缀（AN12196 SDM会话密钥生成）
+var sv2Prefix = []byte{0x3C, 0xC3, 0x00, 0x01, 0x00, 0x80}
+
+// PICCData 解密后的PICC数据
+type PICCData struct {
+	// UID 标签UID，标签未开启UID镜像时为空
+	UID []byte
+	// ReadCounter SDM读计数器，每次读取NDEF文件时由标签递增
+	ReadCounter uint32
+	// HasReadCounter 标签是否开启了读计数器镜像
+	HasReadCounter bool
+}
+
+// UIDHex UID的大写十六进制表示
+func (p *PICCData) UIDHex() string {
+	return strings.ToUpper(hex.EncodeToString(p.UID))
+}
+
+// DecryptPICCData 使用SDMMetaReadKey解密URL中的PICC数据（AES-128 CBC，IV为0）
+func DecryptPICCData(metaReadKey, encrypted []byte) (*PICCData, error) {
+	if len(encrypted) != PICCDataSize {
+		return nil, fmt.Errorf("%w: 需要%d字节，实际%d字节", ErrInvalidPICCData, PICCDataSize, len(encrypted))
+	}
+
+	block, err := newCipher(metaReadKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make([]byte, PICCDataSize)
+	iv := make([]byte, aes.BlockSize)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, encrypted)
+
+	tag := plain[0]
+	data := &PICCData{}
+	pos := 1
+
+	if tag&piccTagUIDMirror != 0 {
+		// NTAG 424 DNA只支持7字节UID，其他长度说明解密结果无意义
+		if int(tag&piccTagUIDLengthMask) != UIDSize {
+			return nil, fmt.Errorf("%w: UID长度标志无效", ErrInvalidPICCData)
+		}
+		data.UID = append([]byte(nil), plain[pos:pos+UIDSize]...)
+		pos += UIDSize
+	}
+
+	if tag&piccTagCounterMirror != 0 {
+		// 读计数器按小端序存储
+		data.ReadCounter = uint32(plain[pos]) | uint32(plain[pos+1])<<8 | uint32(plain[pos+2])<<16
+		data.HasReadCounter = true
+	}
+
+	if data.UID == nil && !data.HasReadCounter {
+		return nil, fmt.Errorf("%w: 未包含UID和读计数器", ErrInvalidPICCData)
+	}
+	return data, nil
+}
+
+// SessionMACKey 由SDMFileReadKey、UID和读计数器派生SDM MAC会话密钥
+// KSesSDMFileReadMAC = CMAC(SDMFileReadKey, 3CC3 0001 0080 || UID || SDMReadCtr)
+func SessionMACKey(fileReadKey, uid []byte, counter uint32) ([]byte, error) {
+	sv2 := make([]byte, 0, 2*aes.BlockSize)
+	sv2 = append(sv2, sv2Prefix...)
+	sv2 = append(sv2, uid...)
+	sv2 = append(sv2, byte(counter), byte(counter>>8), byte(counter>>16))
+	for len(sv2)%aes.BlockSize != 0 {
+		sv2 = append(sv2, 0x00)
+	}
+	return CMAC(fileReadKey, sv2)
+}
+
+// SDMMAC 计算SDM MAC：使用会话密钥对MAC输入计算CMAC并截断为8字节
+// macInput为NDEF文件中从SDMMACInputOffset到SDMMACOffset的数据，两者相同时为空
+func SDMMAC(fileReadKey, uid []byte, counter uint32, macInput []byte) ([]byte, error) {
+	sessionKey, err := SessionMACKey(fileReadKey, uid, counter)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := CMAC(sessionKey, macInput)
+	if err != nil {
+		return nil, err
+	}
+	return truncateMAC(mac), nil
+}
+
+// VerifySDMMAC 以常量时间比较校验SDM MAC
+func VerifySDMMAC(fileReadKey, uid []byte, counter uint32, macInput, mac []byte) error {
+	if len(mac) != MACSize {
+		return fmt.Errorf("%w: 需要%d字节，实际%d字节", ErrInvalidMAC, MACSize, len(mac))
+	}
+
+	expected, err := SDMMAC(fileReadKey, uid, counter, macInput)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(expected, mac) != 1 {
+		return ErrMACMismatch
+	}
+	return nil
+}
+
+// ParseHex 解析URL参数中的十六进制数据，忽略大小写
+func ParseHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimSpace(s))
+}
@@ -0,0 +1,206 @@
+package ntag424
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"testing"
+)
+
+// NXP AN12196 SUN消息示例：SDMMetaReadKey和SDMFileReadKey均为全0，
+// URL为 ...?picc_data=EF963FF7828658A599F3041510671E88&cmac=94EED9EE65337086，MAC输入为空
+const (
+	an12196Key               = "00000000000000000000000000000000"
+	an12196PICCData          = "ef963ff7828658a599f3041510671e88"
+	an12196UID               = "04de5f1eacc040"
+	an12196Counter    uint32 = 61
+	an12196SessionKey        = "3fb5f6e3a807a03d5e3570ace393776f"
+	an12196MAC               = "94eed9ee65337086"
+)
+
+// encryptPICCData 按标签的格式加密PICC数据：标签字节 || UID || 读计数器(小端) || 填充
+func encryptPICCData(t *testing.T, key []byte, tag byte, uid []byte, counter uint32) []byte {
+	t.Helper()
+	plain := make([]byte, PICCDataSize)
+	plain[0] = tag
+	pos := 1 + copy(plain[1:], uid)
+	plain[pos], plain[pos+1], plain[pos+2] = byte(counter), byte(counter>>8), byte(counter>>16)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := make([]byte, PICCDataSize)
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(encrypted, plain)
+	return encrypted
+}
+
+func TestDecryptPICCData(t *testing.T) {
+	key := mustHex(t, "000102030405060708090a0b0c0d0e0f")
+	uid := mustHex(t, "04a1b2c3d4e5f6")
+
+	tests := []struct {
+		name       string
+		key        []byte
+		encrypted  []byte
+		uid        string
+		counter    uint32
+		hasCounter bool
+		err        error
+	}{
+		{
+			name:       "AN12196",
+			key:        mustHex(t, an12196Key),
+			encrypted:  mustHex(t, an12196PICCData),
+			uid:        an12196UID,
+			counter:    an12196Counter,
+			hasCounter: true,
+		},
+		{
+			name:       "计数器最大值",
+			key:        key,
+			encrypted:  encryptPICCData(t, key, 0xC7, uid, MaxReadCounter),
+			uid:        "04a1b2c3d4e5f6",
+			counter:    MaxReadCounter,
+			hasCounter: true,
+		},
+		{
+			name:      "只有UID",
+			key:       key,
+			encrypted: encryptPICCData(t, key, 0x87, uid, 0),
+			uid:       "04a1b2c3d4e5f6",
+		},
+		{
+			name:      "UID长度标志无效",
+			key:       key,
+			encrypted: encryptPICCData(t, key, 0xC4, uid, 1),
+			err:       ErrInvalidPICCData,
+		},
+		{
+			name:      "未镜像UID和计数器",
+			key:       key,
+			encrypted: encryptPICCData(t, key, 0x07, uid, 1),
+			err:       ErrInvalidPICCData,
+		},
+		{
+			name:      "长度错误",
+			key:       key,
+			encrypted: make([]byte, PICCDataSize-1),
+			err:       ErrInvalidPICCData,
+		},
+		{
+			name:      "密钥长度错误",
+			key:       make([]byte, 8),
+			encrypted: make([]byte, PICCDataSize),
+			err:       ErrInvalidKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := DecryptPICCData(tt.key, tt.encrypted)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("错误为%v，期望%v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecryptPICCData: %v", err)
+			}
+			if !bytes.Equal(data.UID, mustHex(t, tt.uid)) {
+				t.Errorf("UID = %x，期望%s", data.UID, tt.uid)
+			}
+			if data.HasReadCounter != tt.hasCounter || data.ReadCounter != tt.counter {
+				t.Errorf("读计数器 = (%d, %v)，期望(%d, %v)", data.ReadCounter, data.HasReadCounter, tt.counter, tt.hasCounter)
+			}
+		})
+	}
+}
+
+func TestDecryptPICCDataWrongKey(t *testing.T) {
+	// 用错误的密钥解密得到的标签字节几乎不可能同时满足UID长度标志
+	wrongKey := mustHex(t, "ffffffffffffffffffffffffffffffff")
+	if _, err := DecryptPICCData(wrongKey, mustHex(t, an12196PICCData)); !errors.Is(err, ErrInvalidPICCData) {
+		t.Errorf("错误为%v，期望ErrInvalidPICCData", err)
+	}
+}
+
+// KSesSDMFileReadMAC = CMAC(SDMFileReadKey, 3CC3 0001 0080 || UID || SDMReadCtr)
+func TestSessionMACKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		uid     string
+		counter uint32
+		want    string
+	}{
+		{"AN12196", an12196Key, an12196UID, an12196Counter, an12196SessionKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := SessionMACKey(mustHex(t, tt.key), mustHex(t, tt.uid), tt.counter)
+			if err != nil {
+				t.Fatalf("SessionMACKey: %v", err)
+			}
+			if want := mustHex(t, tt.want); !bytes.Equal(key, want) {
+				t.Errorf("SessionMACKey = %x，期望%x", key, want)
+			}
+		})
+	}
+}
+
+func TestSDMMAC(t *testing.T) {
+	mac, err := SDMMAC(mustHex(t, an12196Key), mustHex(t, an12196UID), an12196Counter, nil)
+	if err != nil {
+		t.Fatalf("SDMMAC: %v", err)
+	}
+	if want := mustHex(t, an12196MAC); !bytes.Equal(mac, want) {
+		t.Errorf("SDMMAC = %x，期望%x", mac, want)
+	}
+}
+
+func TestVerifySDMMAC(t *testing.T) {
+	key := mustHex(t, an12196Key)
+	uid := mustHex(t, an12196UID)
+	flipped := mustHex(t, an12196MAC)
+	flipped[MACSize-1] ^= 0x01
+
+	tests := []struct {
+		name     string
+		counter  uint32
+		macInput []byte
+		mac      []byte
+		err      error
+	}{
+		{"AN12196", an12196Counter, nil, mustHex(t, an12196MAC), nil},
+		{"MAC被篡改", an12196Counter, nil, flipped, ErrMACMismatch},
+		{"计数器不匹配", an12196Counter + 1, nil, mustHex(t, an12196MAC), ErrMACMismatch},
+		{"MAC输入不匹配", an12196Counter, []byte("extra"), mustHex(t, an12196MAC), ErrMACMismatch},
+		{"未截断的MAC", an12196Counter, nil, mustHex(t, "e194c7ee12d9f7ee8a65c8331b704386"), ErrInvalidMAC},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySDMMAC(key, uid, tt.counter, tt.macInput, tt.mac)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("错误为%v，期望%v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	got, err := ParseHex(" EF963FF7828658A599F3041510671E88 ")
+	if err != nil {
+		t.Fatalf("ParseHex: %v", err)
+	}
+	if !bytes.Equal(got, mustHex(t, an12196PICCData)) {
+		t.Errorf("ParseHex = %x", got)
+	}
+	if _, err := ParseHex("zz"); err == nil {
+		t.Error("无效的十六进制应返回错误")
+	}
+}
@@ -0,0 +1,73 @@
+package ntag424
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"nfc-service/pkg/ndef"
+)
+
+// ndefFileHeaderSize NDEF文件开头的NLEN字段长度，SDM偏移量从文件开头算起
+const ndefFileHeaderSize = 2
+
+// SDMLayout 写入标签的SUN URL模板及ChangeFileSettings所需的SDM偏移量
+type SDMLayout struct {
+	// URL 带占位符的URL，占位符在每次读取时由标签替换为实际数据
+	URL string `json:"url"`
+	// NDEFHex 写入NDEF文件的消息（不含NLEN字段）
+	NDEFHex string `json:"ndefHex"`
+	// PICCDataOffset 加密PICC数据在NDEF文件中的偏移量
+	PICCDataOffset int `json:"piccDataOffset"`
+	// SDMMACInputOffset MAC输入的起始偏移量，与SDMMACOffset相同表示MAC输入为空
+	SDMMACInputOffset int `json:"sdmMacInputOffset"`
+	// SDMMACOffset SDM MAC在NDEF文件中的偏移量
+	SDMMACOffset int `json:"sdmMacOffset"`
+}
+
+// BuildSDMLayout 为基础URL生成SUN URL模板并计算各占位符在NDEF文件中的偏移量
+// piccParam和macParam分别为加密PICC数据和MAC的查询参数名
+func BuildSDMLayout(baseURL, piccParam, macParam string) (*SDMLayout, error) {
+	if baseURL == "" || piccParam == "" || macParam == "" {
+		return nil, errors.New("URL和参数名不能为空")
+	}
+
+	piccPlaceholder := strings.Repeat("0", 2*PICCDataSize)
+	macPlaceholder := strings.Repeat("0", 2*MACSize)
+
+	separator := "?"
+	if strings.Contains(baseURL, "?") {
+		separator = "&"
+	}
+	url := baseURL + separator + piccParam + "=" + piccPlaceholder + "&" + macParam + "=" + macPlaceholder
+
+	record, err := ndef.NewURIRecord(url)
+	if err != nil {
+		return nil, err
+	}
+	message := ndef.NewMessage(record)
+	encoded, err := message.Encode()
+	if err != nil {
+		return nil, err
+	}
+	ndefHex, err := message.EncodeHex()
+	if err != nil {
+		return nil, err
+	}
+
+	// 参数名后紧跟占位符，按"参数名="定位可以避免与URL其他部分中的0混淆
+	piccIndex := bytes.Index(encoded, []byte(piccParam+"="+piccPlaceholder))
+	macIndex := bytes.LastIndex(encoded, []byte(macParam+"="+macPlaceholder))
+	if piccIndex < 0 || macIndex < 0 {
+		return nil, errors.New("无法在NDEF消息中定位SUN占位符")
+	}
+
+	macOffset := ndefFileHeaderSize + macIndex + len(macParam) + 1
+	return &SDMLayout{
+		URL:               url,
+		NDEFHex:           ndefHex,
+		PICCDataOffset:    ndefFileHeaderSize + piccIndex + len(piccParam) + 1,
+		SDMMACInputOffset: macOffset,
+		SDMMACOffset:      macOffset,
+	}, nil
+}
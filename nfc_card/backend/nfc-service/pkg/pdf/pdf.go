@@ -0,0 +1,282 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// A4页面尺寸（点，1点=1/72英寸）
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// MMToPoint 毫米转换为点
+func MMToPoint(mm float64) float64 {
+	return mm * 72 / 25.4
+}
+
+// Document 只包含矩形、单行文字和位图的最小PDF文档
+// 文字使用内置的Helvetica字体，只能输出WinAnsi编码范围内的字符
+type Document struct {
+	width  float64
+	height float64
+	pages  []*Page
+	images []*imageObject
+}
+
+// Page PDF页面，坐标原点在左上角，单位为点
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+// imageObject 页面引用的位图
+type imageObject struct {
+	width  int
+	height int
+	rgb    []byte
+	alpha  []byte
+}
+
+// New 创建指定页面尺寸的文档
+func New(width, height float64) *Document {
+	return &Document{width: width, height: height}
+}
+
+// Width 页面宽度
+func (d *Document) Width() float64 {
+	return d.width
+}
+
+// Height 页面高度
+func (d *Document) Height() float64 {
+	return d.height
+}
+
+// AddPage 添加一个新页面
+func (d *Document) AddPage() *Page {
+	page := &Page{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// SetFillColor 设置后续矩形和文字的填充颜色，忽略透明度
+func (p *Page) SetFillColor(c color.Color) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	fmt.Fprintf(&p.content, "%s %s %s rg\n", num(float64(n.R)/255), num(float64(n.G)/255), num(float64(n.B)/255))
+}
+
+// Rect 填充矩形，(x, y)为左上角
+func (p *Page) Rect(x, y, w, h float64) {
+	fmt.Fprintf(&p.content, "%s %s %s %s re f\n", num(x), num(p.doc.height-y-h), num(w), num(h))
+}
+
+// Text 输出单行文字，(x, y)为基线起点，不支持的字符替换为?
+func (p *Page) Text(x, y, size float64, text string) {
+	fmt.Fprintf(&p.content, "BT /F1 %s Tf %s %s Td (%s) Tj ET\n", num(size), num(x), num(p.doc.height-y), escapeText(text))
+}
+
+// TextWidth 估算文字宽度，Helvetica按平均字宽计算
+func TextWidth(size float64, text string) float64 {
+	return float64(len([]rune(text))) * size * 0.556
+}
+
+// Image 文档中的位图，同一位图可以在多个页面重复绘制而只嵌入一次
+type Image struct {
+	index int
+}
+
+// AddImage 将位图嵌入文档，完全不透明时不生成透明度蒙版
+func (d *Document) AddImage(img image.Image) *Image {
+	bounds := img.Bounds()
+	obj := &imageObject{
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		rgb:    make([]byte, 0, bounds.Dx()*bounds.Dy()*3),
+		alpha:  make([]byte, 0, bounds.Dx()*bounds.Dy()),
+	}
+	opaque := true
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			c := color.NRGBAModel.Convert(img.At(px, py)).(color.NRGBA)
+			obj.rgb = append(obj.rgb, c.R, c.G, c.B)
+			obj.alpha = append(obj.alpha, c.A)
+			if c.A != 0xff {
+				opaque = false
+			}
+		}
+	}
+	if opaque {
+		obj.alpha = nil
+	}
+
+	d.images = append(d.images, obj)
+	return &Image{index: len(d.images)}
+}
+
+// DrawImage 将位图绘制到(x, y)为左上角、w×h大小的区域
+func (p *Page) DrawImage(img *Image, x, y, w, h float64) {
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(w), num(h), num(x), num(p.doc.height-y-h), img.index)
+}
+
+// WriteTo 输出PDF文件
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	out := &countingWriter{w: w}
+	var offsets []int64
+
+	// 对象编号：1目录 2页面树 3字体，之后依次为位图（带透明度时紧跟其SMask）、各页面及其内容流
+	next := 4
+	imageIDs := make([]int, len(d.images))
+	for i, img := range d.images {
+		imageIDs[i] = next
+		next++
+		if img.alpha != nil {
+			next++
+		}
+	}
+	pageIDs := make([]int, len(d.pages))
+	for i := range d.pages {
+		pageIDs[i] = next
+		next += 2
+	}
+
+	begin := func(id int) {
+		for len(offsets) < id {
+			offsets = append(offsets, 0)
+		}
+		offsets[id-1] = out.n
+		fmt.Fprintf(out, "%d 0 obj\n", id)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	begin(1)
+	out.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	kids := make([]string, len(pageIDs))
+	for i, id := range pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	begin(2)
+	fmt.Fprintf(out, "<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(pageIDs))
+
+	begin(3)
+	out.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	for i, img := range d.images {
+		id := imageIDs[i]
+		smask := ""
+		if img.alpha != nil {
+			smask = fmt.Sprintf(" /SMask %d 0 R", id+1)
+		}
+		begin(id)
+		writeStream(out, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8%s",
+			img.width, img.height, smask), img.rgb)
+		if img.alpha != nil {
+			begin(id + 1)
+			writeStream(out, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8",
+				img.width, img.height), img.alpha)
+		}
+	}
+
+	var xobjects strings.Builder
+	for i, id := range imageIDs {
+		fmt.Fprintf(&xobjects, "/Im%d %d 0 R ", i+1, id)
+	}
+	for i, page := range d.pages {
+		id := pageIDs[i]
+		begin(id)
+		fmt.Fprintf(out, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R >> /XObject << %s>> >> /Contents %d 0 R >>\nendobj\n",
+			num(d.width), num(d.height), xobjects.String(), id+1)
+		begin(id + 1)
+		writeStream(out, "", page.content.Bytes())
+	}
+
+	xref := out.n
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.n, out.err
+}
+
+// writeStream 以FlateDecode压缩输出流对象
+func writeStream(out *countingWriter, dict string, data []byte) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(data)
+	zw.Close()
+
+	if dict != "" {
+		dict += " "
+	}
+	fmt.Fprintf(out, "<< %s/Filter /FlateDecode /Length %d >>\nstream\n", dict, compressed.Len())
+	out.Write(compressed.Bytes())
+	out.WriteString("\nendstream\nendobj\n")
+}
+
+// escapeText 转义PDF字符串中的特殊字符，WinAnsi以外的字符替换为?
+func escapeText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// Encodable 判断文字能否完整输出，中文等WinAnsi以外的字符会被替换为?
+func Encodable(text string) bool {
+	for _, r := range text {
+		if r < 0x20 || (r >= 0x7f && r < 0xa0) || r > 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// num 格式化数值，去掉多余的小数位
+func num(v float64) string {
+	s := strings.TrimRight(fmt.Sprintf("%.3f", v), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// countingWriter 记录已写入的字节数，用于生成交叉引用表
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+// Write 实现io.Writer接口，出错后不再写入
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// WriteString 写入字符串
+func (c *countingWriter) WriteString(s string) {
+	c.Write([]byte(s))
+}
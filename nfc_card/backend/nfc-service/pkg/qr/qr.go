@@ -0,0 +1,163 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	goqrcode "github.com/skip2/go-qrcode"
+)
+
+// Level 纠错级别
+type Level string
+
+const (
+	// LevelL 约7%的码字可恢复
+	LevelL Level = "L"
+	// LevelM 约15%的码字可恢复
+	LevelM Level = "M"
+	// LevelQ 约25%的码字可恢复
+	LevelQ Level = "Q"
+	// LevelH 约30%的码字可恢复
+	LevelH Level = "H"
+)
+
+// recoveryLevels 纠错级别对应的编码参数
+var recoveryLevels = map[Level]goqrcode.RecoveryLevel{
+	LevelL: goqrcode.Low,
+	LevelM: goqrcode.Medium,
+	LevelQ: goqrcode.High,
+	LevelH: goqrcode.Highest,
+}
+
+// levelRank 纠错级别从低到高的顺序
+var levelRank = map[Level]int{LevelL: 0, LevelM: 1, LevelQ: 2, LevelH: 3}
+
+// Format 二维码图片格式
+type Format string
+
+const (
+	// FormatPNG PNG位图
+	FormatPNG Format = "png"
+	// FormatSVG SVG矢量图
+	FormatSVG Format = "svg"
+)
+
+// ContentType 图片格式对应的MIME类型
+func (f Format) ContentType() string {
+	if f == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+const (
+	// DefaultQuietZone 规范要求的静区宽度（模块数）
+	DefaultQuietZone = 4
+	// MaxQuietZone 允许设置的最大静区宽度（模块数）
+	MaxQuietZone = 32
+	// logoMinLevel 带Logo时的最低纠错级别，Logo遮挡的模块依靠纠错恢复
+	logoMinLevel = LevelQ
+	// logoRatio Logo区域边长占码区边长的比例，在Q级纠错下留有足够余量
+	logoRatio = 0.22
+)
+
+// ErrInvalidOptions 二维码参数无效
+var ErrInvalidOptions = errors.New("二维码参数无效")
+
+// ParseLevel 解析纠错级别，忽略大小写
+func ParseLevel(value string) (Level, error) {
+	level := Level(strings.ToUpper(strings.TrimSpace(value)))
+	if _, ok := recoveryLevels[level]; !ok {
+		return "", fmt.Errorf("%w: 不支持的纠错级别: %s", ErrInvalidOptions, value)
+	}
+	return level, nil
+}
+
+// ParseFormat 解析图片格式，忽略大小写
+func ParseFormat(value string) (Format, error) {
+	format := Format(strings.ToLower(strings.TrimSpace(value)))
+	if format != FormatPNG && format != FormatSVG {
+		return "", fmt.Errorf("%w: 不支持的图片格式: %s", ErrInvalidOptions, value)
+	}
+	return format, nil
+}
+
+// ParseColor 解析RRGGBB或RRGGBBAA格式的十六进制颜色，可以带#前缀
+func ParseColor(value string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("%w: 颜色格式无效: %s", ErrInvalidOptions, value)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("%w: 颜色格式无效: %s", ErrInvalidOptions, value)
+	}
+	return color.NRGBA{R: uint8(n >> 24), G: uint8(n >> 16), B: uint8(n >> 8), A: uint8(n)}, nil
+}
+
+// Matrix 二维码模块矩阵，包含四周的静区
+type Matrix struct {
+	modules   [][]bool
+	quietZone int
+}
+
+// Encode 将内容编码为二维码矩阵
+// withLogo为true时纠错级别低于Q会提高到Q，保证中心Logo遮挡的模块可以恢复
+func Encode(content string, level Level, quietZone int, withLogo bool) (*Matrix, error) {
+	if content == "" {
+		return nil, fmt.Errorf("%w: 二维码内容不能为空", ErrInvalidOptions)
+	}
+	if level == "" {
+		level = LevelM
+	}
+	if _, ok := recoveryLevels[level]; !ok {
+		return nil, fmt.Errorf("%w: 不支持的纠错级别: %s", ErrInvalidOptions, level)
+	}
+	if quietZone < 0 || quietZone > MaxQuietZone {
+		return nil, fmt.Errorf("%w: 静区宽度必须在0到%d之间", ErrInvalidOptions, MaxQuietZone)
+	}
+	if withLogo && levelRank[level] < levelRank[logoMinLevel] {
+		level = logoMinLevel
+	}
+
+	code, err := goqrcode.New(content, recoveryLevels[level])
+	if err != nil {
+		return nil, fmt.Errorf("生成二维码失败: %w", err)
+	}
+	code.DisableBorder = true
+
+	return &Matrix{modules: code.Bitmap(), quietZone: quietZone}, nil
+}
+
+// Size 包含静区的矩阵边长（模块数）
+func (m *Matrix) Size() int {
+	return len(m.modules) + 2*m.quietZone
+}
+
+// Dark 判断(x, y)处的模块是否为深色，坐标包含静区
+func (m *Matrix) Dark(x, y int) bool {
+	x -= m.quietZone
+	y -= m.quietZone
+	if x < 0 || y < 0 || y >= len(m.modules) || x >= len(m.modules[y]) {
+		return false
+	}
+	return m.modules[y][x]
+}
+
+// LogoArea 中心Logo占用的模块区域，坐标包含静区
+// 区域对齐到模块网格并且边长与码区同奇偶，保证居中
+func (m *Matrix) LogoArea() (offset, size int) {
+	symbol := len(m.modules)
+	size = int(float64(symbol) * logoRatio)
+	if (symbol-size)%2 != 0 {
+		size--
+	}
+	return m.quietZone + (symbol-size)/2, size
+}
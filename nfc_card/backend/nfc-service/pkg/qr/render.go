@@ -0,0 +1,223 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// logoEmbedSize SVG中内嵌Logo位图的边长（像素）
+const logoEmbedSize = 256
+
+// Style 二维码的外观
+type Style struct {
+	// Size 输出图片边长（像素），SVG为width和height属性
+	Size int
+	// Foreground 深色模块颜色
+	Foreground color.NRGBA
+	// Background 浅色模块和静区颜色
+	Background color.NRGBA
+	// Logo 可选的中心Logo，编码矩阵时需要传入withLogo
+	Logo image.Image
+}
+
+// DefaultStyle 白底黑码
+func DefaultStyle(size int) *Style {
+	return &Style{
+		Size:       size,
+		Foreground: color.NRGBA{A: 0xff},
+		Background: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// Write 按格式输出二维码图片
+func (m *Matrix) Write(w io.Writer, format Format, style *Style) error {
+	if format == FormatSVG {
+		return m.WriteSVG(w, style)
+	}
+	return m.WritePNG(w, style)
+}
+
+// WritePNG 输出PNG图片
+// 每个模块占整数个像素避免模糊，图片边长不能整除时剩余像素平均分配到四周的静区
+func (m *Matrix) WritePNG(w io.Writer, style *Style) error {
+	img, err := m.Image(style)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("编码PNG失败: %w", err)
+	}
+	return nil
+}
+
+// Image 将二维码渲染为位图
+func (m *Matrix) Image(style *Style) (*image.NRGBA, error) {
+	dim := m.Size()
+	scale := style.Size / dim
+	if scale < 1 {
+		return nil, fmt.Errorf("%w: 图片边长至少需要%d像素", ErrInvalidOptions, dim)
+	}
+	margin := (style.Size - scale*dim) / 2
+
+	img := image.NewNRGBA(image.Rect(0, 0, style.Size, style.Size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: style.Background}, image.Point{}, draw.Src)
+
+	fg := &image.Uniform{C: style.Foreground}
+	for y := 0; y < dim; y++ {
+		for x := 0; x < dim; x++ {
+			if !m.Dark(x, y) {
+				continue
+			}
+			rect := image.Rect(margin+x*scale, margin+y*scale, margin+(x+1)*scale, margin+(y+1)*scale)
+			draw.Draw(img, rect, fg, image.Point{}, draw.Src)
+		}
+	}
+
+	if style.Logo != nil {
+		offset, size := m.LogoArea()
+		area := image.Rect(margin+offset*scale, margin+offset*scale, margin+(offset+size)*scale, margin+(offset+size)*scale)
+		m.drawLogo(img, area, scale, style)
+	}
+
+	return img, nil
+}
+
+// drawLogo 在area区域铺背景色后居中绘制Logo，四周保留一个模块的留白
+func (m *Matrix) drawLogo(img *image.NRGBA, area image.Rectangle, scale int, style *Style) {
+	draw.Draw(img, area, &image.Uniform{C: style.Background}, image.Point{}, draw.Src)
+
+	inner := area.Inset(scale)
+	if inner.Empty() {
+		return
+	}
+	logo := FitImage(style.Logo, inner.Dx(), inner.Dy())
+	bounds := logo.Bounds()
+	at := image.Pt(inner.Min.X+(inner.Dx()-bounds.Dx())/2, inner.Min.Y+(inner.Dy()-bounds.Dy())/2)
+	draw.Draw(img, bounds.Add(at), logo, bounds.Min, draw.Over)
+}
+
+// WriteSVG 输出SVG图片
+// 同一行连续的深色模块合并为一个矩形，Logo以内嵌PNG的方式绘制
+func (m *Matrix) WriteSVG(w io.Writer, style *Style) error {
+	dim := m.Size()
+	if style.Size < 1 {
+		return fmt.Errorf("%w: 图片边长必须大于0", ErrInvalidOptions)
+	}
+
+	var path strings.Builder
+	for y := 0; y < dim; y++ {
+		for x := 0; x < dim; {
+			if !m.Dark(x, y) {
+				x++
+				continue
+			}
+			run := 1
+			for x+run < dim && m.Dark(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", x, y, run, run)
+			x += run
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		style.Size, style.Size, dim, dim)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d"%s/>`, dim, dim, svgFill(style.Background))
+	fmt.Fprintf(&buf, `<path d="%s"%s/>`, path.String(), svgFill(style.Foreground))
+
+	if style.Logo != nil {
+		offset, size := m.LogoArea()
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d"%s/>`, offset, offset, size, size, svgFill(style.Background))
+		if size > 2 {
+			var logo bytes.Buffer
+			if err := png.Encode(&logo, FitImage(style.Logo, logoEmbedSize, logoEmbedSize)); err != nil {
+				return fmt.Errorf("编码Logo失败: %w", err)
+			}
+			fmt.Fprintf(&buf, `<image x="%d" y="%d" width="%d" height="%d" preserveAspectRatio="xMidYMid meet" href="data:image/png;base64,%s"/>`,
+				offset+1, offset+1, size-2, size-2, base64.StdEncoding.EncodeToString(logo.Bytes()))
+		}
+	}
+
+	buf.WriteString("</svg>\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// svgFill 生成fill属性，半透明颜色附加fill-opacity
+func svgFill(c color.NRGBA) string {
+	fill := fmt.Sprintf(` fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A != 0xff {
+		fill += fmt.Sprintf(` fill-opacity="%.3f"`, float64(c.A)/0xff)
+	}
+	return fill
+}
+
+// FitImage 将图片等比缩放到不超过maxW×maxH
+// 缩小时按源像素区域取平均，避免直接采样产生锯齿；放大时取最近像素
+func FitImage(src image.Image, maxW, maxH int) *image.NRGBA {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	if sw == 0 || sh == 0 {
+		return image.NewNRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	dw, dh := maxW, sh*maxW/sw
+	if dh > maxH {
+		dw, dh = sw*maxH/sh, maxH
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	// 统一转换为NRGBA便于按像素取值
+	source := image.NewNRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(source, source.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, (y+1)*sh/dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, (x+1)*sw/dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			dst.SetNRGBA(x, y, averageNRGBA(source, x0, y0, x1, y1))
+		}
+	}
+	return dst
+}
+
+// averageNRGBA 计算区域内像素的平均颜色，颜色按透明度加权避免透明像素的颜色渗入边缘
+func averageNRGBA(img *image.NRGBA, x0, y0, x1, y1 int) color.NRGBA {
+	var r, g, b, a, n uint64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			c := img.NRGBAAt(x, y)
+			alpha := uint64(c.A)
+			r += uint64(c.R) * alpha
+			g += uint64(c.G) * alpha
+			b += uint64(c.B) * alpha
+			a += alpha
+			n++
+		}
+	}
+	if a == 0 {
+		return color.NRGBA{}
+	}
+	return color.NRGBA{R: uint8(r / a), G: uint8(g / a), B: uint8(b / a), A: uint8(a / n)}
+}
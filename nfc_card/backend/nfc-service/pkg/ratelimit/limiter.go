@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"nfc-service/pkg/cache"
+)
+
+// bucket 令牌桶的状态
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter 按键隔离的令牌桶限流器，并发安全
+// 令牌桶保存在定长LRU中，长时间未使用的桶会被淘汰，淘汰后重新创建的桶是满的，与自然补满等价
+type Limiter struct {
+	mu      sync.Mutex
+	buckets *cache.LRU[string, *bucket]
+	now     func() time.Time
+}
+
+// NewLimiter 创建限流器，capacity为最多同时跟踪的键数量
+func NewLimiter(capacity int) *Limiter {
+	return &Limiter{
+		buckets: cache.NewLRU[string, *bucket](capacity),
+		now:     time.Now,
+	}
+}
+
+// Allow 从key对应的令牌桶中取一个令牌
+// ratePerMinute为每分钟补充的令牌数，burst为桶容量；令牌不足时返回false和下一个令牌的等待时间
+func (l *Limiter) Allow(key string, ratePerMinute, burst int) (bool, time.Duration) {
+	if ratePerMinute <= 0 || burst <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	perSecond := float64(ratePerMinute) / 60
+
+	b, ok := l.buckets.Get(key)
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * perSecond
+		if b.tokens > float64(burst) {
+			b.tokens = float64(burst)
+		}
+		b.last = now
+	}
+
+	// 桶在补满所需的时间后过期，过期后重建的满桶与继续补充的结果相同
+	ttl := time.Duration(float64(burst)/perSecond*float64(time.Second)) + time.Second
+
+	if b.tokens < 1 {
+		l.buckets.Set(key, b, ttl)
+		wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	l.buckets.Set(key, b, ttl)
+	return true, 0
+}
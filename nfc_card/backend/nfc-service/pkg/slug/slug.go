@@ -0,0 +1,158 @@
+package slug
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultAlphabet 默认字符集，去掉了容易混淆的0/O、1/l/I
+	DefaultAlphabet = "23456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+	// DefaultLength 默认随机Slug长度
+	DefaultLength = 8
+	// MinLength 随机Slug的最小长度
+	MinLength = 4
+
+	// MinVanityLength 自定义Slug的最小长度
+	MinVanityLength = 3
+	// MaxVanityLength 自定义Slug的最大长度，与short_links.slug列长度一致
+	MaxVanityLength = 50
+)
+
+// ErrInvalidSlug 自定义Slug无效
+var ErrInvalidSlug = errors.New("自定义Slug无效")
+
+// 自定义Slug只允许字母、数字、连字符和下划线，且必须以字母或数字开头和结尾
+var vanityPattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9_-]*[A-Za-z0-9])?$`)
+
+// defaultReserved 与系统路由冲突或容易被滥用的保留字
+var defaultReserved = []string{
+	"api", "health", "r", "nfc-landing", "admin", "login", "logout", "register",
+	"static", "assets", "favicon.ico", "robots.txt", "metrics", "debug", "swagger",
+	"www", "app", "help", "support", "about", "terms", "privacy",
+}
+
+// defaultProfanity 不允许出现在自定义Slug中的不文明词汇（按子串匹配，忽略大小写）
+var defaultProfanity = []string{
+	"fuck", "shit", "bitch", "cunt", "porn", "nigger", "faggot",
+	"caonima", "shabi", "wocao", "nmsl",
+}
+
+// Generator Slug生成器
+type Generator struct {
+	alphabet  []rune
+	length    int
+	reserved  map[string]bool
+	profanity []string
+}
+
+// NewGenerator 创建Slug生成器
+// length小于等于0时使用DefaultLength，alphabet为空时使用DefaultAlphabet；extraReserved会追加到默认保留字列表
+func NewGenerator(length int, alphabet string, extraReserved []string) (*Generator, error) {
+	if length <= 0 {
+		length = DefaultLength
+	}
+	if length < MinLength || length > MaxVanityLength {
+		return nil, fmt.Errorf("Slug长度必须在%d到%d之间", MinLength, MaxVanityLength)
+	}
+	if alphabet == "" {
+		alphabet = DefaultAlphabet
+	}
+
+	runes := []rune(alphabet)
+	seen := make(map[rune]bool, len(runes))
+	for _, r := range runes {
+		if seen[r] {
+			return nil, fmt.Errorf("Slug字符集包含重复字符: %q", r)
+		}
+		if !vanityPattern.MatchString(string(r)) {
+			return nil, fmt.Errorf("Slug字符集包含不允许的字符: %q", r)
+		}
+		seen[r] = true
+	}
+	if len(runes) < 2 {
+		return nil, errors.New("Slug字符集至少需要2个字符")
+	}
+
+	reserved := make(map[string]bool, len(defaultReserved)+len(extraReserved))
+	for _, word := range append(defaultReserved, extraReserved...) {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			reserved[word] = true
+		}
+	}
+
+	return &Generator{
+		alphabet:  runes,
+		length:    length,
+		reserved:  reserved,
+		profanity: defaultProfanity,
+	}, nil
+}
+
+// MustNewGenerator 创建Slug生成器，参数无效时panic，用于包级变量等参数固定的场景
+func MustNewGenerator(length int, alphabet string, extraReserved []string) *Generator {
+	g, err := NewGenerator(length, alphabet, extraReserved)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// Generate 使用加密安全的随机源生成Slug，跳过保留字和包含不文明词汇的结果
+func (g *Generator) Generate() (string, error) {
+	max := big.NewInt(int64(len(g.alphabet)))
+	buf := make([]rune, g.length)
+
+	for {
+		for i := range buf {
+			// rand.Int内部使用拒绝采样，保证每个字符等概率
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", fmt.Errorf("生成随机Slug失败: %w", err)
+			}
+			buf[i] = g.alphabet[n.Int64()]
+		}
+
+		candidate := string(buf)
+		if !g.IsReserved(candidate) && !g.containsProfanity(candidate) {
+			return candidate, nil
+		}
+	}
+}
+
+// IsReserved 判断Slug是否为保留字
+func (g *Generator) IsReserved(slug string) bool {
+	return g.reserved[strings.ToLower(slug)]
+}
+
+// ValidateVanity 校验商户自定义的Slug
+func (g *Generator) ValidateVanity(slug string) error {
+	if len(slug) < MinVanityLength || len(slug) > MaxVanityLength {
+		return fmt.Errorf("%w: 长度必须在%d到%d个字符之间", ErrInvalidSlug, MinVanityLength, MaxVanityLength)
+	}
+	if !vanityPattern.MatchString(slug) {
+		return fmt.Errorf("%w: 只能包含字母、数字、连字符和下划线，且必须以字母或数字开头和结尾", ErrInvalidSlug)
+	}
+	if g.IsReserved(slug) {
+		return fmt.Errorf("%w: %s是系统保留字", ErrInvalidSlug, slug)
+	}
+	if g.containsProfanity(slug) {
+		return fmt.Errorf("%w: 包含不允许使用的词汇", ErrInvalidSlug)
+	}
+	return nil
+}
+
+// containsProfanity 判断Slug是否包含不文明词汇，匹配前去掉连字符和下划线
+func (g *Generator) containsProfanity(slug string) bool {
+	normalized := strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(slug))
+	for _, word := range g.profanity {
+		if strings.Contains(normalized, word) {
+			return true
+		}
+	}
+	return false
+}
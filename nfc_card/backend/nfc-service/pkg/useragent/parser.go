@@ -0,0 +1,172 @@
+package useragent
+
+import (
+	"strings"
+)
+
+// 操作系统常量
+const (
+	OSiOS       = "iOS"
+	OSAndroid   = "Android"
+	OSHarmonyOS = "HarmonyOS"
+	OSWindows   = "Windows"
+	OSMacOS     = "macOS"
+	OSLinux     = "Linux"
+	OSUnknown   = "unknown"
+)
+
+// 浏览器常量（包含常见的App内置浏览器）
+const (
+	BrowserWeChat      = "WeChat"
+	BrowserDouyin      = "Douyin"
+	BrowserXiaohongshu = "Xiaohongshu"
+	BrowserKuaishou    = "Kuaishou"
+	BrowserAlipay      = "Alipay"
+	BrowserQQ          = "QQ"
+	BrowserUC          = "UC"
+	BrowserEdge        = "Edge"
+	BrowserChrome      = "Chrome"
+	BrowserFirefox     = "Firefox"
+	BrowserSafari      = "Safari"
+	BrowserUnknown     = "unknown"
+)
+
+// 设备类型常量
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+// Info 解析后的User-Agent信息
+type Info struct {
+	OS         string `json:"os"`
+	Browser    string `json:"browser"`
+	DeviceType string `json:"deviceType"`
+	IsBot      bool   `json:"isBot"`
+	IsPreview  bool   `json:"isPreview"`  // 聊天软件或社交平台抓取链接预览的请求，同时也是爬虫
+	IsHeadless bool   `json:"isHeadless"` // 无头浏览器或自动化工具
+}
+
+// 匹配规则按顺序检查，App内置浏览器必须排在通用浏览器之前
+var browserRules = []struct {
+	token   string
+	browser string
+}{
+	{"micromessenger", BrowserWeChat},
+	{"aweme", BrowserDouyin},
+	{"bytedancewebview", BrowserDouyin},
+	{"xhsdiscover", BrowserXiaohongshu},
+	{"xiaohongshu", BrowserXiaohongshu},
+	{"kwai", BrowserKuaishou},
+	{"alipayclient", BrowserAlipay},
+	{"mqqbrowser", BrowserQQ},
+	{" qq/", BrowserQQ},
+	{"ucbrowser", BrowserUC},
+	{"edg/", BrowserEdge},
+	{"edge/", BrowserEdge},
+	{"firefox/", BrowserFirefox},
+	{"fxios/", BrowserFirefox},
+	{"crios/", BrowserChrome},
+	{"chrome/", BrowserChrome},
+	{"safari/", BrowserSafari},
+}
+
+// 常见爬虫标识
+var botTokens = []string{
+	"bot", "spider", "crawler", "slurp", "curl/", "wget/", "python-requests", "go-http-client",
+}
+
+// 聊天软件和社交平台抓取链接预览（unfurl）时使用的标识
+var previewTokens = []string{
+	"slackbot-linkexpanding", "slack-imgproxy", "wechatshareextension", "facebookexternalhit", "twitterbot",
+	"whatsapp/", "telegrambot", "discordbot", "linkedinbot", "skypeuripreview", "dingtalkbot", "larkbot",
+	"embedly", "iframely", "pinterestbot", "redditbot", "vkshare",
+}
+
+// 无头浏览器和常见自动化工具的标识，这些请求不一定是爬虫，但很少来自真实的碰卡
+var headlessTokens = []string{
+	"headlesschrome", "phantomjs", "puppeteer", "playwright", "selenium", "webdriver", "okhttp", "httpclient",
+}
+
+// Parse 解析User-Agent字符串
+func Parse(ua string) Info {
+	lower := strings.ToLower(ua)
+
+	info := Info{
+		OS:         parseOS(lower),
+		Browser:    BrowserUnknown,
+		DeviceType: DeviceDesktop,
+	}
+
+	for _, rule := range browserRules {
+		if strings.Contains(lower, rule.token) {
+			info.Browser = rule.browser
+			break
+		}
+	}
+
+	for _, token := range headlessTokens {
+		if strings.Contains(lower, token) {
+			info.IsHeadless = true
+			break
+		}
+	}
+
+	for _, token := range previewTokens {
+		if strings.Contains(lower, token) {
+			info.IsPreview = true
+			info.IsBot = true
+			info.DeviceType = DeviceBot
+			return info
+		}
+	}
+
+	for _, token := range botTokens {
+		if strings.Contains(lower, token) {
+			info.IsBot = true
+			info.DeviceType = DeviceBot
+			return info
+		}
+	}
+
+	switch {
+	case strings.Contains(lower, "ipad") || (strings.Contains(lower, "android") && !strings.Contains(lower, "mobile")):
+		info.DeviceType = DeviceTablet
+	case strings.Contains(lower, "mobile") || strings.Contains(lower, "iphone") || strings.Contains(lower, "android"):
+		info.DeviceType = DeviceMobile
+	}
+
+	return info
+}
+
+// parseOS 从小写的User-Agent中识别操作系统
+func parseOS(lower string) string {
+	switch {
+	case strings.Contains(lower, "harmonyos") || strings.Contains(lower, "openharmony"):
+		return OSHarmonyOS
+	case strings.Contains(lower, "iphone") || strings.Contains(lower, "ipad") || strings.Contains(lower, "ipod"):
+		return OSiOS
+	case strings.Contains(lower, "android"):
+		return OSAndroid
+	case strings.Contains(lower, "windows"):
+		return OSWindows
+	case strings.Contains(lower, "mac os x") || strings.Contains(lower, "macintosh"):
+		return OSMacOS
+	case strings.Contains(lower, "linux"):
+		return OSLinux
+	default:
+		return OSUnknown
+	}
+}
+
+// IsInAppBrowser 判断是否为社交/内容App的内置浏览器
+func (i Info) IsInAppBrowser() bool {
+	switch i.Browser {
+	case BrowserWeChat, BrowserDouyin, BrowserXiaohongshu, BrowserKuaishou, BrowserAlipay, BrowserQQ:
+		return true
+	default:
+		return false
+	}
+}
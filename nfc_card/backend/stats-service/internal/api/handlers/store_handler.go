@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"stats-service/internal/services"
+)
+
+// maxStoreStatsDays 门店统计的最大天数
+const maxStoreStatsDays = 366
+
+// GetStoreStats 获取商户各门店的汇总统计数据，包括碰卡、发布任务和平台互动
+// GET /api/v1/stats/stores
+func (h *StatsHandler) GetStoreStats(c *gin.Context) {
+	tenantID, _ := c.Get("tenantID")
+	tenantIDStr, ok := tenantID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "租户ID格式错误"})
+		return
+	}
+
+	startDate, endDate, ok := storeStatsDateRange(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.statsService.GetStoreStats(tenantIDStr, startDate, endDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"startDate": startDate.Format("2006-01-02"),
+		"endDate":   endDate.Format("2006-01-02"),
+		"data":      stats,
+	})
+}
+
+// GetStoreDailyStats 获取单个门店的每日统计数据
+// GET /api/v1/stats/stores/:storeId/daily
+func (h *StatsHandler) GetStoreDailyStats(c *gin.Context) {
+	tenantID, _ := c.Get("tenantID")
+	tenantIDStr, ok := tenantID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "租户ID格式错误"})
+		return
+	}
+
+	storeID, err := uuid.Parse(c.Param("storeId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的门店ID"})
+		return
+	}
+
+	startDate, endDate, ok := storeStatsDateRange(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.statsService.GetStoreDailyStats(tenantIDStr, storeID, startDate, endDate)
+	if errors.Is(err, services.ErrStoreNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"storeId":   storeID,
+		"startDate": startDate.Format("2006-01-02"),
+		"endDate":   endDate.Format("2006-01-02"),
+		"data":      stats,
+	})
+}
+
+// storeStatsDateRange 解析startDate/endDate（YYYY-MM-DD），默认为最近30天，失败时已写入响应
+func storeStatsDateRange(c *gin.Context) (time.Time, time.Time, bool) {
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "加载时区失败"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	now := time.Now().In(location)
+	startDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location).AddDate(0, 0, -30)
+	endDate := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 59, 0, location)
+
+	if startDateStr := c.Query("startDate"); startDateStr != "" {
+		parsedStartDate, err := time.ParseInLocation("2006-01-02", startDateStr, location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "开始日期格式无效，应为YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+		startDate = parsedStartDate
+	}
+
+	if endDateStr := c.Query("endDate"); endDateStr != "" {
+		parsedEndDate, err := time.ParseInLocation("2006-01-02", endDateStr, location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "结束日期格式无效，应为YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+		// 确保时间是当天的23:59:59
+		endDate = time.Date(parsedEndDate.Year(), parsedEndDate.Month(), parsedEndDate.Day(), 23, 59, 59, 0, location)
+	}
+
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "结束日期不能早于开始日期"})
+		return time.Time{}, time.Time{}, false
+	}
+	if endDate.Sub(startDate) > maxStoreStatsDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "统计区间不能超过一年"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	return startDate, endDate, true
+}
@@ -66,6 +66,12 @@ func NewRouter(cfg *config.Config, statsService *services.StatsService) *gin.Eng
 			// 按时间范围统计
 			stats.GET("/time-range/:videoId", statsHandler.GetStatsTimeRange)
 
+			// 各门店汇总统计
+			stats.GET("/stores", statsHandler.GetStoreStats)
+
+			// 门店每日统计
+			stats.GET("/stores/:storeId/daily", statsHandler.GetStoreDailyStats)
+
 			// 导出每日统计数据
 			stats.GET("/export/daily", exportHandler.ExportDailyStats)
 
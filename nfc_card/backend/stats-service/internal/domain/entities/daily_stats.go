@@ -21,6 +21,8 @@ type DailyStats struct {
 	CollectCount int64     `json:"collectCount" db:"collects"` // 当日收藏数
 	CreatedAt    time.Time `json:"createdAt" db:"created_at"`  // 创建时间
 	UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`  // 更新时间
+	// StoreID 统计时卡片所属的门店，写入时由数据库根据卡片填充
+	StoreID *uuid.UUID `json:"storeId" db:"store_id"`
 }
 
 // NewDailyStats 创建新的每日统计数据
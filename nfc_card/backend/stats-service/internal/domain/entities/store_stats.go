@@ -0,0 +1,35 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StoreStats 门店在统计区间内的汇总数据
+type StoreStats struct {
+	StoreID       uuid.UUID `json:"storeId" db:"store_id"`
+	StoreName     string    `json:"storeName" db:"store_name"`
+	City          string    `json:"city" db:"city"`
+	CardCount     int64     `json:"cardCount" db:"card_count"`         // 当前分配到门店的卡片数
+	TapCount      int64     `json:"tapCount" db:"taps"`                // 碰卡/扫码次数
+	PublishCount  int64     `json:"publishCount" db:"publish_jobs"`    // 发布任务数
+	PublishedJobs int64     `json:"publishedJobs" db:"published_jobs"` // 发布成功的任务数
+	ViewCount     int64     `json:"viewCount" db:"views"`              // 平台播放/观看次数
+	LikeCount     int64     `json:"likeCount" db:"likes"`              // 点赞数
+	CommentCount  int64     `json:"commentCount" db:"comments"`        // 评论数
+	ShareCount    int64     `json:"shareCount" db:"shares"`            // 分享数
+	CollectCount  int64     `json:"collectCount" db:"collects"`        // 收藏数
+}
+
+// StoreDailyStats 门店的每日统计数据
+type StoreDailyStats struct {
+	Date         time.Time `json:"date" db:"date"`
+	TapCount     int64     `json:"tapCount" db:"taps"`
+	PublishCount int64     `json:"publishCount" db:"publish_jobs"`
+	ViewCount    int64     `json:"viewCount" db:"views"`
+	LikeCount    int64     `json:"likeCount" db:"likes"`
+	CommentCount int64     `json:"commentCount" db:"comments"`
+	ShareCount   int64     `json:"shareCount" db:"shares"`
+	CollectCount int64     `json:"collectCount" db:"collects"`
+}
@@ -122,14 +122,15 @@ func (r *StatsRepository) SaveDailyStats(ctx context.Context, stats *entities.Da
 			return fmt.Errorf("更新每日统计数据失败: %w", err)
 		}
 	} else {
-		// 插入新记录
+		// 插入新记录，门店取卡片当前的分配
 		insertQuery := `
 			INSERT INTO daily_stats (
 				id, tenant_id, video_id, nfc_card_id, platform, date,
 				view_count, like_count, comment_count, share_count, collect_count,
-				created_at, updated_at
+				created_at, updated_at, store_id
 			) VALUES (
-				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+				(SELECT store_id FROM nfc_cards WHERE id = $4)
 			)
 		`
 		_, err = r.db.ExecContext(ctx, insertQuery,
@@ -204,7 +205,7 @@ func (r *StatsRepository) GetLatestPlatformStats(ctx context.Context, tenantID,
 func (r *StatsRepository) GetDailyStats(ctx context.Context, tenantID, videoID uuid.UUID, platform string, startDate, endDate time.Time) ([]*entities.DailyStats, error) {
 	query := `
 		SELECT 
-			id, tenant_id, video_id, nfc_card_id, platform, date,
+			id, tenant_id, video_id, nfc_card_id, store_id, platform, date,
 			view_count, like_count, comment_count, share_count, collect_count,
 			created_at, updated_at
 		FROM daily_stats
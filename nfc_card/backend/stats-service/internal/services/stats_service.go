@@ -24,8 +24,13 @@ type StatsRepo interface {
 	SaveDailyStats(ctx context.Context, stats *entities.DailyStats) error
 	GetAllPlatformStats(ctx context.Context) ([]*entities.PlatformStats, error)
 	GetPlatformsToRefresh(ctx context.Context, lastUpdateTime time.Time) ([]*entities.PlatformStats, error)
+	GetStoreStats(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) ([]*entities.StoreStats, error)
+	GetStoreDailyStats(ctx context.Context, tenantID, storeID uuid.UUID, startDate, endDate time.Time) ([]*entities.StoreDailyStats, error)
 }
 
+// ErrStoreNotFound 门店不存在或不属于当前商户
+var ErrStoreNotFound = errors.New("门店不存在")
+
 // StatsService 统计服务
 type StatsService struct {
 	repo     StatsRepo
@@ -580,3 +585,38 @@ func (s *StatsService) GetAdapter(platform string) (adapters.PlatformAdapter, bo
 	adapter, ok := s.adapters[platform]
 	return adapter, ok
 }
+
+// GetStoreStats 获取商户各门店在时间范围内的汇总数据
+func (s *StatsService) GetStoreStats(tenantID string, startDate, endDate time.Time) ([]*entities.StoreStats, error) {
+	tenantUUID, err := uuid.Parse(tenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "无效的租户ID")
+	}
+
+	stats, err := s.repo.GetStoreStats(context.Background(), tenantUUID, startDate, endDate)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取门店统计数据失败")
+	}
+
+	return stats, nil
+}
+
+// GetStoreDailyStats 获取单个门店在时间范围内的每日统计数据
+// 门店不存在或不属于当前商户时返回ErrStoreNotFound
+func (s *StatsService) GetStoreDailyStats(tenantID string, storeID uuid.UUID, startDate, endDate time.Time) ([]*entities.StoreDailyStats, error) {
+	tenantUUID, err := uuid.Parse(tenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "无效的租户ID")
+	}
+
+	stats, err := s.repo.GetStoreDailyStats(context.Background(), tenantUUID, storeID, startDate, endDate)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取门店每日统计数据失败")
+	}
+	// 日期区间非空，仓库只在门店不属于该商户时返回空列表
+	if len(stats) == 0 {
+		return nil, ErrStoreNotFound
+	}
+
+	return stats, nil
+}
@@ -79,3 +79,13 @@ func (a *RepoAdapter) GetPlatformsToRefresh(ctx context.Context, lastUpdateTime
 	}
 	return nil, fmt.Errorf("仓库未初始化")
 }
+
+// GetStoreStats 获取各门店的汇总数据
+func (a *RepoAdapter) GetStoreStats(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) ([]*entities.StoreStats, error) {
+	return a.repos.GetStoreStats(ctx, tenantID, startDate, endDate)
+}
+
+// GetStoreDailyStats 获取门店的每日统计数据
+func (a *RepoAdapter) GetStoreDailyStats(ctx context.Context, tenantID, storeID uuid.UUID, startDate, endDate time.Time) ([]*entities.StoreDailyStats, error) {
+	return a.repos.GetStoreDailyStats(ctx, tenantID, storeID, startDate, endDate)
+}
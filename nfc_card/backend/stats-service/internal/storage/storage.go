@@ -153,7 +153,7 @@ func (r *Repositories) GetLatestPlatformStats(ctx context.Context, tenantID, vid
 func (r *Repositories) GetDailyStats(ctx context.Context, tenantID, videoID uuid.UUID, platform string, startDate, endDate time.Time) ([]*entities.DailyStats, error) {
 	query := `
 		SELECT 
-			id, tenant_id, video_id, nfc_card_id, platform, date,
+			id, tenant_id, video_id, nfc_card_id, store_id, platform, date,
 			view_count, like_count, comment_count, share_count, collect_count,
 			created_at, updated_at
 		FROM daily_stats
@@ -310,14 +310,15 @@ func (r *Repositories) SaveDailyStats(ctx context.Context, stats *entities.Daily
 			return fmt.Errorf("更新每日统计数据失败: %w", err)
 		}
 	} else {
-		// 插入新记录
+		// 插入新记录，门店取卡片当前的分配
 		insertQuery := `
 			INSERT INTO daily_stats (
 				id, tenant_id, video_id, nfc_card_id, platform, date,
 				view_count, like_count, comment_count, share_count, collect_count,
-				created_at, updated_at
+				created_at, updated_at, store_id
 			) VALUES (
-				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+				(SELECT store_id FROM nfc_cards WHERE id = $4)
 			)
 		`
 		_, err = r.db.ExecContext(ctx, insertQuery,
@@ -331,3 +332,83 @@ func (r *Repositories) SaveDailyStats(ctx context.Context, stats *entities.Daily
 
 	return nil
 }
+
+// GetStoreStats 获取商户各门店在[startDate, endDate]内的汇总数据
+// 碰卡和发布任务按写入时记录的门店归属统计，卡片换门店后历史数据仍计入原门店
+func (r *Repositories) GetStoreStats(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) ([]*entities.StoreStats, error) {
+	query := `
+		SELECT
+			s.id AS store_id, s.name AS store_name, s.city,
+			(SELECT COUNT(*) FROM nfc_cards c WHERE c.store_id = s.id) AS card_count,
+			(SELECT COUNT(*) FROM short_link_clicks k
+				WHERE k.store_id = s.id AND NOT k.suspicious AND k.clicked_at BETWEEN $2 AND $3) AS taps,
+			(SELECT COUNT(*) FROM publish_jobs j
+				WHERE j.store_id = s.id AND j.created_at BETWEEN $2 AND $3) AS publish_jobs,
+			(SELECT COUNT(*) FROM publish_jobs j
+				WHERE j.store_id = s.id AND j.status = 'completed' AND j.created_at BETWEEN $2 AND $3) AS published_jobs,
+			COALESCE(d.views, 0) AS views,
+			COALESCE(d.likes, 0) AS likes,
+			COALESCE(d.comments, 0) AS comments,
+			COALESCE(d.shares, 0) AS shares,
+			COALESCE(d.collects, 0) AS collects
+		FROM stores s
+		LEFT JOIN (
+			SELECT store_id,
+				SUM(view_count) AS views, SUM(like_count) AS likes, SUM(comment_count) AS comments,
+				SUM(share_count) AS shares, SUM(collect_count) AS collects
+			FROM daily_stats
+			WHERE tenant_id = $1 AND store_id IS NOT NULL AND platform <> 'all' AND date BETWEEN $4::date AND $5::date
+			GROUP BY store_id
+		) d ON d.store_id = s.id
+		WHERE s.merchant_id = $1
+		ORDER BY taps DESC, s.name
+	`
+
+	var stats []*entities.StoreStats
+	// daily_stats按日期存储，日期参数按调用方时区格式化，避免受数据库会话时区影响
+	err := r.db.SelectContext(ctx, &stats, query, tenantID, startDate, endDate,
+		startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("查询门店统计数据失败: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GetStoreDailyStats 获取单个门店在[startDate, endDate]内的每日统计数据，没有数据的日期补零
+// 碰卡和发布任务按门店时区划分日期，门店不存在或不属于该商户时返回空列表
+func (r *Repositories) GetStoreDailyStats(ctx context.Context, tenantID, storeID uuid.UUID, startDate, endDate time.Time) ([]*entities.StoreDailyStats, error) {
+	query := `
+		WITH days AS (
+			SELECT generate_series($3::date, $4::date, INTERVAL '1 day')::date AS date
+		), store AS (
+			SELECT id, timezone FROM stores WHERE id = $2 AND merchant_id = $1
+		)
+		SELECT
+			days.date,
+			(SELECT COUNT(*) FROM short_link_clicks k, store
+				WHERE k.store_id = store.id AND NOT k.suspicious AND (k.clicked_at AT TIME ZONE store.timezone)::date = days.date) AS taps,
+			(SELECT COUNT(*) FROM publish_jobs j, store
+				WHERE j.store_id = store.id AND (j.created_at AT TIME ZONE store.timezone)::date = days.date) AS publish_jobs,
+			COALESCE(SUM(d.view_count), 0) AS views,
+			COALESCE(SUM(d.like_count), 0) AS likes,
+			COALESCE(SUM(d.comment_count), 0) AS comments,
+			COALESCE(SUM(d.share_count), 0) AS shares,
+			COALESCE(SUM(d.collect_count), 0) AS collects
+		FROM days
+		LEFT JOIN daily_stats d
+			ON d.date = days.date AND d.tenant_id = $1 AND d.store_id = $2 AND d.platform <> 'all'
+		WHERE EXISTS (SELECT 1 FROM store)
+		GROUP BY days.date
+		ORDER BY days.date
+	`
+
+	var stats []*entities.StoreDailyStats
+	err := r.db.SelectContext(ctx, &stats, query, tenantID, storeID,
+		startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("查询门店每日统计数据失败: %w", err)
+	}
+
+	return stats, nil
+}
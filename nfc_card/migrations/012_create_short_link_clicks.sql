@@ -0,0 +1,30 @@
+-- 012_create_short_link_clicks.sql
+-- 短链接点击事件表，记录每一次碰卡/访问的设备、地区和来源
+
+CREATE TABLE IF NOT EXISTS short_link_clicks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    short_link_id UUID NOT NULL REFERENCES short_links(id) ON DELETE CASCADE,
+    slug VARCHAR(50) NOT NULL,
+    nfc_card_id UUID REFERENCES nfc_cards(id) ON DELETE SET NULL,
+    clicked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    user_agent TEXT,
+    os VARCHAR(50),
+    browser VARCHAR(50),
+    device_type VARCHAR(20),
+    ip_address VARCHAR(45), -- 已匿名化的IP地址
+    country VARCHAR(10),
+    region VARCHAR(100),
+    referrer TEXT
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_short_link_clicks_slug_clicked_at ON short_link_clicks(slug, clicked_at DESC);
+CREATE INDEX IF NOT EXISTS idx_short_link_clicks_short_link_id ON short_link_clicks(short_link_id);
+CREATE INDEX IF NOT EXISTS idx_short_link_clicks_merchant_id ON short_link_clicks(merchant_id);
+CREATE INDEX IF NOT EXISTS idx_short_link_clicks_nfc_card_id ON short_link_clicks(nfc_card_id);
+
+-- 启用租户隔离
+SELECT auth.create_tenant_schema_for_table('short_link_clicks');
+ALTER TABLE short_link_clicks FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON short_link_clicks TO admin USING (true);
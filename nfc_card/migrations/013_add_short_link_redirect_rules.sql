@@ -0,0 +1,9 @@
+-- 013_add_short_link_redirect_rules.sql
+-- 为短链接添加有序的动态跳转规则，未命中任何规则时使用target_url兜底
+
+ALTER TABLE short_links
+    ADD COLUMN IF NOT EXISTS redirect_rules JSONB NOT NULL DEFAULT '[]';
+
+-- 规则必须是JSON数组
+ALTER TABLE short_links
+    ADD CONSTRAINT chk_short_links_redirect_rules_array CHECK (jsonb_typeof(redirect_rules) = 'array');
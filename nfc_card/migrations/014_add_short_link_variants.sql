@@ -0,0 +1,13 @@
+-- 014_add_short_link_variants.sql
+-- 短链接A/B测试：加权目标变体，以及点击事件命中的变体
+
+ALTER TABLE short_links
+    ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '[]';
+
+ALTER TABLE short_links
+    ADD CONSTRAINT chk_short_links_variants_array CHECK (jsonb_typeof(variants) = 'array');
+
+ALTER TABLE short_link_clicks
+    ADD COLUMN IF NOT EXISTS variant_id VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_short_link_clicks_slug_variant_id ON short_link_clicks(slug, variant_id);
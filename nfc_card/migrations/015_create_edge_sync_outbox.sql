@@ -0,0 +1,17 @@
+-- 015_create_edge_sync_outbox.sql
+-- 短链接同步到Cloudflare Workers KV的发件箱
+
+CREATE TABLE IF NOT EXISTS edge_sync_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    slug VARCHAR(50) NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('upsert', 'delete')),
+    target_url TEXT NOT NULL DEFAULT '',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_edge_sync_outbox_pending ON edge_sync_outbox(id) WHERE processed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_edge_sync_outbox_processed_at ON edge_sync_outbox(processed_at) WHERE processed_at IS NOT NULL;
@@ -0,0 +1,32 @@
+-- 016_create_card_import_jobs.sql
+-- NFC卡片批量导入任务表，记录异步导入的进度和逐行结果
+
+CREATE TABLE IF NOT EXISTS card_import_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    format VARCHAR(10) NOT NULL,
+    file_name VARCHAR(255),
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    created_count INTEGER NOT NULL DEFAULT 0,
+    duplicate_count INTEGER NOT NULL DEFAULT 0,
+    invalid_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    results JSONB NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    created_by UUID,
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_card_import_jobs_merchant_id_created_at ON card_import_jobs(merchant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_card_import_jobs_unfinished ON card_import_jobs(status) WHERE status IN ('pending', 'running');
+
+-- 启用租户隔离
+SELECT auth.create_tenant_schema_for_table('card_import_jobs');
+ALTER TABLE card_import_jobs FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON card_import_jobs TO admin USING (true);
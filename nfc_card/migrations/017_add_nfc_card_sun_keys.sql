@@ -0,0 +1,20 @@
+-- 017_add_nfc_card_sun_keys.sql
+-- NTAG 424 DNA安全动态消息(SUN)：每张卡片的AES密钥和最后一次接受的读计数器
+-- 密钥由服务端主密钥按卡片UID分散派生，使用密钥加密密钥(AES-256-GCM)加密后存储，数据库中不保存明文
+
+ALTER TABLE nfc_cards
+    ADD COLUMN IF NOT EXISTS sun_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS sun_key_version SMALLINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS sun_meta_read_key BYTEA,
+    ADD COLUMN IF NOT EXISTS sun_file_read_key BYTEA,
+    ADD COLUMN IF NOT EXISTS sun_read_counter INTEGER,
+    ADD COLUMN IF NOT EXISTS sun_last_tap_at TIMESTAMP WITH TIME ZONE;
+
+-- 启用SUN的卡片必须有密钥，读计数器为3字节无符号整数
+ALTER TABLE nfc_cards
+    ADD CONSTRAINT chk_nfc_cards_sun_keys CHECK (NOT sun_enabled OR (sun_meta_read_key IS NOT NULL AND sun_file_read_key IS NOT NULL)),
+    ADD CONSTRAINT chk_nfc_cards_sun_read_counter CHECK (sun_read_counter IS NULL OR sun_read_counter BETWEEN 0 AND 16777215),
+    ADD CONSTRAINT chk_nfc_cards_sun_key_version CHECK (sun_key_version BETWEEN 0 AND 255);
+
+-- 边缘同步对账时需要查询所有启用SUN的卡片
+CREATE INDEX IF NOT EXISTS idx_nfc_cards_sun_enabled ON nfc_cards(id) WHERE sun_enabled;
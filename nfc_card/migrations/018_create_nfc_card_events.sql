@@ -0,0 +1,38 @@
+-- 018_create_nfc_card_events.sql
+-- NFC卡片生命周期：限制状态取值，记录每次状态变更的历史，过期/停用时挂起的短链接单独标记以便重新激活时恢复
+
+-- 旧版本激活卡片时只写入activated_at，没有更新状态
+UPDATE nfc_cards SET status = 'activated' WHERE status = 'new' AND activated_at IS NOT NULL;
+
+ALTER TABLE nfc_cards
+    ADD CONSTRAINT chk_nfc_cards_status CHECK (status IN ('new', 'activated', 'bound', 'deactivated', 'expired'));
+
+-- 过期扫描只关心设置了有效期且尚未过期的卡片
+CREATE INDEX IF NOT EXISTS idx_nfc_cards_expires_at ON nfc_cards(expires_at) WHERE expires_at IS NOT NULL AND status <> 'expired';
+
+-- 卡片停用或过期时由系统挂起的短链接，重新激活时只恢复这些短链接，不影响商户手动停用的短链接
+ALTER TABLE short_links
+    ADD COLUMN IF NOT EXISTS suspended_by_card BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS nfc_card_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    nfc_card_id UUID NOT NULL REFERENCES nfc_cards(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('activate', 'bind', 'unbind', 'deactivate', 'reactivate', 'expire')),
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    user_id UUID,
+    actor_type VARCHAR(20) NOT NULL CHECK (actor_type IN ('user', 'system')),
+    actor_id UUID,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_nfc_card_events_card_id_created_at ON nfc_card_events(nfc_card_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_nfc_card_events_merchant_id_created_at ON nfc_card_events(merchant_id, created_at DESC);
+
+-- 启用租户隔离
+SELECT auth.create_tenant_schema_for_table('nfc_card_events');
+ALTER TABLE nfc_card_events FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON nfc_card_events TO admin USING (true);
@@ -0,0 +1,10 @@
+-- 019_add_short_link_click_source.sql
+-- 点击来源：碰NFC标签(nfc)或扫描打印的二维码(qr)，二维码中的短链接带有src=qr参数
+
+ALTER TABLE short_link_clicks
+    ADD COLUMN IF NOT EXISTS source VARCHAR(16) NOT NULL DEFAULT 'nfc';
+
+ALTER TABLE short_link_clicks
+    ADD CONSTRAINT chk_short_link_clicks_source CHECK (source IN ('nfc', 'qr'));
+
+CREATE INDEX IF NOT EXISTS idx_short_link_clicks_slug_source ON short_link_clicks(slug, source);
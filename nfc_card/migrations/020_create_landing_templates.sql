@@ -0,0 +1,17 @@
+-- 020_create_landing_templates.sql
+-- NFC落地页模板：每个商户一条记录，选择内置布局并设置主题颜色、文案和分享平台，没有记录时使用默认模板
+
+CREATE TABLE IF NOT EXISTS landing_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL UNIQUE REFERENCES merchants(id),
+    layout VARCHAR(20) NOT NULL DEFAULT 'classic' CHECK (layout IN ('classic', 'immersive', 'minimal')),
+    theme JSONB NOT NULL DEFAULT '{}',
+    share_platforms JSONB NOT NULL DEFAULT '["douyin", "kuaishou", "xiaohongshu", "wechat"]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- 启用租户隔离
+SELECT auth.create_tenant_schema_for_table('landing_templates');
+ALTER TABLE landing_templates FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON landing_templates TO admin USING (true);
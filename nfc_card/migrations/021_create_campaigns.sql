@@ -0,0 +1,53 @@
+-- 021_create_campaigns.sql
+-- 营销活动：把一组卡片的默认短链接在活动期间统一指向活动视频或活动页面，活动结束后恢复为各自原来的目标
+
+CREATE TABLE IF NOT EXISTS campaigns (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    target_video_id UUID,
+    target_url TEXT,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    -- activating/ending为切换中的中间状态，实例中途退出时由调度器接手继续
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled'
+        CHECK (status IN ('scheduled', 'activating', 'active', 'ending', 'ended', 'cancelled')),
+    last_error TEXT NOT NULL DEFAULT '',
+    activated_at TIMESTAMP WITH TIME ZONE,
+    ended_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_campaigns_target CHECK ((target_video_id IS NULL) <> (target_url IS NULL)),
+    CONSTRAINT chk_campaigns_window CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+-- 活动成员卡片，记录切换前的目标以便活动结束后恢复
+CREATE TABLE IF NOT EXISTS campaign_cards (
+    campaign_id UUID NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    nfc_card_id UUID NOT NULL REFERENCES nfc_cards(id) ON DELETE CASCADE,
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    state VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'applied', 'skipped', 'restored')),
+    short_link_id UUID REFERENCES short_links(id) ON DELETE SET NULL,
+    previous_target_url TEXT,
+    applied_target_url TEXT,
+    note TEXT NOT NULL DEFAULT '',
+    applied_at TIMESTAMP WITH TIME ZONE,
+    restored_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (campaign_id, nfc_card_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_campaigns_merchant_id_created_at ON campaigns(merchant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_campaigns_status_starts_at ON campaigns(status, starts_at) WHERE status NOT IN ('ended', 'cancelled');
+CREATE INDEX IF NOT EXISTS idx_campaign_cards_nfc_card_id ON campaign_cards(nfc_card_id);
+
+-- 启用租户隔离
+SELECT auth.create_tenant_schema_for_table('campaigns');
+ALTER TABLE campaigns FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON campaigns TO admin USING (true);
+
+SELECT auth.create_tenant_schema_for_table('campaign_cards');
+ALTER TABLE campaign_cards FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON campaign_cards TO admin USING (true);
@@ -0,0 +1,19 @@
+-- 022_create_card_schedules.sql
+-- 卡片内容排期：按日期、星期和每日时间段切换落地页播放的视频或跳转地址，每张卡片一条记录，条目按顺序存储为JSONB
+
+CREATE TABLE IF NOT EXISTS card_schedules (
+    nfc_card_id UUID PRIMARY KEY REFERENCES nfc_cards(id) ON DELETE CASCADE,
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Shanghai',
+    entries JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_card_schedules_merchant_id ON card_schedules(merchant_id);
+
+-- 启用租户隔离
+SELECT auth.create_tenant_schema_for_table('card_schedules');
+ALTER TABLE card_schedules FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON card_schedules TO admin USING (true);
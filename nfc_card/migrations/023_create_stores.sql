@@ -0,0 +1,50 @@
+-- 023_create_stores.sql
+-- 门店：连锁商户的实体店，卡片可分配到门店及店内位置（如12号桌、收银台）
+-- 点击、发布任务和每日统计在写入时记录卡片当时所属的门店，卡片换店后历史数据仍归属原门店
+
+CREATE TABLE IF NOT EXISTS stores (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    name VARCHAR(100) NOT NULL,
+    -- 商户内部的门店编号，可为空
+    code VARCHAR(50),
+    address TEXT NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL DEFAULT '',
+    phone VARCHAR(50) NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Shanghai',
+    -- 营业时间，[{"days":[1,2,3,4,5],"open":"09:00","close":"21:00"}]
+    opening_hours JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_stores_coordinates CHECK ((latitude IS NULL) = (longitude IS NULL))
+);
+
+-- 卡片所属门店和店内位置
+ALTER TABLE nfc_cards
+    ADD COLUMN IF NOT EXISTS store_id UUID REFERENCES stores(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS position VARCHAR(100);
+
+-- 点击和发布任务记录发生时卡片所属的门店
+ALTER TABLE short_link_clicks
+    ADD COLUMN IF NOT EXISTS store_id UUID REFERENCES stores(id) ON DELETE SET NULL;
+
+ALTER TABLE publish_jobs
+    ADD COLUMN IF NOT EXISTS store_id UUID REFERENCES stores(id) ON DELETE SET NULL;
+
+-- 统计服务的每日统计表
+ALTER TABLE IF EXISTS daily_stats
+    ADD COLUMN IF NOT EXISTS store_id UUID REFERENCES stores(id) ON DELETE SET NULL;
+
+-- 创建索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stores_merchant_id_name ON stores(merchant_id, name);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stores_merchant_id_code ON stores(merchant_id, code) WHERE code IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_nfc_cards_store_id ON nfc_cards(store_id) WHERE store_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_short_link_clicks_store_id_clicked_at ON short_link_clicks(store_id, clicked_at) WHERE store_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_publish_jobs_store_id ON publish_jobs(store_id) WHERE store_id IS NOT NULL;
+
+-- 启用租户隔离
+SELECT auth.create_tenant_schema_for_table('stores');
+ALTER TABLE stores FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON stores TO admin USING (true);
@@ -0,0 +1,39 @@
+-- 024_create_tap_guard.sql
+-- 公开重定向接口的刷量防护：商户级阈值、按原因的过滤计数，以及点击事件的可疑标记
+
+-- 商户的防护阈值，没有记录的商户使用服务配置中的默认值
+CREATE TABLE IF NOT EXISTS tap_guard_settings (
+    merchant_id UUID PRIMARY KEY REFERENCES merchants(id),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    ip_rate_per_minute INTEGER NOT NULL CHECK (ip_rate_per_minute > 0),
+    ip_burst INTEGER NOT NULL CHECK (ip_burst > 0),
+    slug_rate_per_minute INTEGER NOT NULL CHECK (slug_rate_per_minute > 0),
+    slug_burst INTEGER NOT NULL CHECK (slug_burst > 0),
+    -- 同一设备在该时间内重复碰同一张卡只计一次（秒），0表示不去重
+    duplicate_window_seconds INTEGER NOT NULL CHECK (duplicate_window_seconds >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- 每天按原因被过滤的碰卡次数：rate_limited（拒绝）、crawler（爬虫/链接预览）、duplicate（重复碰卡）、suspicious（可疑）
+CREATE TABLE IF NOT EXISTS tap_filter_counts (
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    date DATE NOT NULL,
+    reason VARCHAR(20) NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (merchant_id, date, reason)
+);
+
+-- 可疑点击仍然记录以便排查，但不计入点击次数和统计
+ALTER TABLE short_link_clicks ADD COLUMN IF NOT EXISTS suspicious BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_short_link_clicks_suspicious ON short_link_clicks(merchant_id, clicked_at) WHERE suspicious;
+
+-- 启用租户隔离
+SELECT auth.create_tenant_schema_for_table('tap_guard_settings');
+ALTER TABLE tap_guard_settings FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON tap_guard_settings TO admin USING (true);
+
+SELECT auth.create_tenant_schema_for_table('tap_filter_counts');
+ALTER TABLE tap_filter_counts FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON tap_filter_counts TO admin USING (true);
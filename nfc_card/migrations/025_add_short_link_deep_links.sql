@@ -0,0 +1,8 @@
+-- 025_add_short_link_deep_links.sql
+-- 短链接的原生App深度链接：跳转时先尝试在App中打开，超时后回退到网页
+
+ALTER TABLE short_links
+    ADD COLUMN IF NOT EXISTS deep_links JSONB NOT NULL DEFAULT '[]';
+
+ALTER TABLE short_links
+    ADD CONSTRAINT chk_short_links_deep_links_array CHECK (jsonb_typeof(deep_links) = 'array');
@@ -0,0 +1,134 @@
+-- 026_create_card_transfers.sql
+-- 卡片在商户之间转让：原商户发起，目标商户接受后卡片归属目标商户，短链接随卡片迁移或重置，历史数据保留在原商户名下
+
+CREATE TABLE IF NOT EXISTS card_transfers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    nfc_card_id UUID NOT NULL REFERENCES nfc_cards(id) ON DELETE CASCADE,
+    -- 发起时卡片的UID快照，接受前目标商户无法读取原商户的卡片
+    card_uid VARCHAR(255) NOT NULL,
+    from_merchant_id UUID NOT NULL REFERENCES merchants(id),
+    to_merchant_id UUID NOT NULL REFERENCES merchants(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'accepted', 'rejected', 'cancelled', 'expired')),
+    -- move：短链接随卡片迁移到目标商户，已写入卡片的URL继续有效；reset：短链接留在原商户并停用，为目标商户创建新的默认短链接
+    link_mode VARCHAR(10) NOT NULL DEFAULT 'move' CHECK (link_mode IN ('move', 'reset')),
+    note TEXT NOT NULL DEFAULT '',
+    initiated_by UUID,
+    responded_by UUID,
+    -- 由管理员直接完成，没有经过目标商户接受
+    admin_override BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_card_transfers_merchants CHECK (from_merchant_id <> to_merchant_id)
+);
+
+-- 每张卡片同时只能有一个待处理的转让
+CREATE UNIQUE INDEX IF NOT EXISTS idx_card_transfers_nfc_card_id_pending ON card_transfers(nfc_card_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_card_transfers_from_merchant_id_created_at ON card_transfers(from_merchant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_card_transfers_to_merchant_id_created_at ON card_transfers(to_merchant_id, created_at DESC);
+
+-- 卡片事件历史增加转出和转入，两条事件分别记在原商户和目标商户名下
+ALTER TABLE nfc_card_events DROP CONSTRAINT IF EXISTS nfc_card_events_action_check;
+ALTER TABLE nfc_card_events
+    ADD CONSTRAINT nfc_card_events_action_check
+    CHECK (action IN ('activate', 'bind', 'unbind', 'deactivate', 'reactivate', 'expire', 'transfer_out', 'transfer_in'));
+
+-- 转让记录对原商户和目标商户都可见，不能使用auth.create_tenant_schema_for_table的单列策略
+ALTER TABLE card_transfers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE card_transfers FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation_policy ON card_transfers
+    USING (auth.current_tenant_id() IN (from_merchant_id, to_merchant_id))
+    WITH CHECK (auth.current_tenant_id() IN (from_merchant_id, to_merchant_id));
+CREATE POLICY admin_policy ON card_transfers TO admin USING (true);
+
+-- 执行已接受的转让
+-- nfc_cards和short_links的租户策略不允许任何一方把数据改到另一个商户名下，因此由SECURITY DEFINER函数完成迁移；
+-- 函数属主需要能绕过RLS（迁移默认以超级用户执行），调用方负责在同一事务中校验并把转让置为accepted
+-- 返回outcome：ok、not_accepted（转让不是accepted状态）、owner_changed（卡片已不属于原商户）、in_campaign（卡片正被营销活动接管）
+CREATE OR REPLACE FUNCTION transfer_nfc_card(p_transfer_id UUID)
+RETURNS TABLE (outcome TEXT, short_link_ids UUID[]) AS $$
+DECLARE
+    t card_transfers%ROWTYPE;
+    c nfc_cards%ROWTYPE;
+    new_status VARCHAR(50);
+    ids UUID[];
+BEGIN
+    SELECT * INTO t FROM card_transfers WHERE id = p_transfer_id FOR UPDATE;
+    IF NOT FOUND OR t.status <> 'accepted' THEN
+        RETURN QUERY SELECT 'not_accepted'::TEXT, ARRAY[]::UUID[];
+        RETURN;
+    END IF;
+
+    SELECT * INTO c FROM nfc_cards WHERE id = t.nfc_card_id FOR UPDATE;
+    IF NOT FOUND OR c.merchant_id <> t.from_merchant_id THEN
+        RETURN QUERY SELECT 'owner_changed'::TEXT, ARRAY[]::UUID[];
+        RETURN;
+    END IF;
+
+    -- 活动期间默认短链接指向原商户的活动目标，需要原商户先把卡片移出活动
+    IF EXISTS (SELECT 1 FROM campaign_cards WHERE nfc_card_id = c.id AND state = 'applied') THEN
+        RETURN QUERY SELECT 'in_campaign'::TEXT, ARRAY[]::UUID[];
+        RETURN;
+    END IF;
+
+    -- 绑定的用户属于原商户，转让后解除绑定
+    new_status := CASE WHEN c.status = 'bound' THEN 'activated' ELSE c.status END;
+
+    -- 原商户尚未生效的活动不再接管该卡片，排期引用原商户的视频，一并清除
+    UPDATE campaign_cards SET state = 'skipped', note = '卡片已转让给其他商户'
+    WHERE nfc_card_id = c.id AND state = 'pending';
+    DELETE FROM card_schedules WHERE nfc_card_id = c.id;
+
+    IF t.link_mode = 'move' THEN
+        -- 默认短链接改为指向卡片落地页，其他短链接停用；跳转规则、变体和深度链接都属于原商户的配置
+        WITH moved AS (
+            UPDATE short_links SET
+                merchant_id = t.to_merchant_id,
+                target_url = CASE WHEN is_default THEN '/nfc-landing/' || c.uid ELSE target_url END,
+                active = CASE WHEN is_default THEN active ELSE FALSE END,
+                suspended_by_card = CASE WHEN is_default THEN suspended_by_card ELSE FALSE END,
+                redirect_rules = '[]',
+                variants = '[]',
+                deep_links = '[]',
+                expires_at = NULL,
+                clicks = 0,
+                updated_at = NOW()
+            WHERE nfc_card_id = c.id
+            RETURNING id
+        )
+        SELECT array_agg(id) INTO ids FROM moved;
+    ELSE
+        -- 短链接连同点击历史留在原商户，与卡片解除关联并停用
+        WITH detached AS (
+            UPDATE short_links SET
+                nfc_card_id = NULL,
+                active = FALSE,
+                suspended_by_card = FALSE,
+                updated_at = NOW()
+            WHERE nfc_card_id = c.id
+            RETURNING id
+        )
+        SELECT array_agg(id) INTO ids FROM detached;
+    END IF;
+
+    UPDATE nfc_cards SET
+        merchant_id = t.to_merchant_id,
+        status = new_status,
+        default_video_id = NULL,
+        user_id = NULL,
+        bound_at = NULL,
+        store_id = NULL,
+        position = NULL,
+        updated_at = NOW()
+    WHERE id = c.id;
+
+    INSERT INTO nfc_card_events (merchant_id, nfc_card_id, action, from_status, to_status, user_id, actor_type, actor_id, reason)
+    VALUES
+        (t.from_merchant_id, c.id, 'transfer_out', c.status, new_status, c.user_id, 'user', t.responded_by, t.note),
+        (t.to_merchant_id, c.id, 'transfer_in', c.status, new_status, NULL, 'user', t.responded_by, t.note);
+
+    RETURN QUERY SELECT 'ok'::TEXT, COALESCE(ids, ARRAY[]::UUID[]);
+END
+$$ LANGUAGE plpgsql SECURITY DEFINER SET search_path = public, auth;
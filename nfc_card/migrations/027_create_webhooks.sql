@@ -0,0 +1,65 @@
+-- 027_create_webhooks.sql
+-- 商户Webhook：按事件类型订阅碰卡、卡片、活动和发布事件，签名投递到商户自己的系统，保留投递记录以便排查和重新投递
+
+-- 商户注册的接收端
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    url TEXT NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    -- HMAC-SHA256签名密钥，投递时需要原文，只在创建和轮换时返回给商户
+    secret VARCHAR(100) NOT NULL,
+    -- 订阅的事件类型，为空表示订阅全部事件
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    -- 连续投递失败的次数，任意一次成功后清零，达到阈值后自动停用
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_reason TEXT NOT NULL DEFAULT '',
+    disabled_at TIMESTAMP WITH TIME ZONE,
+    last_success_at TIMESTAMP WITH TIME ZONE,
+    last_failure_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_merchant_id ON webhook_endpoints(merchant_id) WHERE active;
+
+-- 投递记录，同时作为投递队列：pending等待投递或重试，succeeded投递成功，failed重试耗尽或接收端已停用
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    endpoint_id UUID NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    -- 同一条Kafka消息的事件ID相同，用于接收端去重
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    -- 投递中的租约，实例中途退出时租约到期后由其他实例接手
+    locked_until TIMESTAMP WITH TIME ZONE,
+    response_status INTEGER,
+    response_body TEXT NOT NULL DEFAULT '',
+    last_error TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    -- 重新投递时指向原投递记录
+    redelivery_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Kafka重复消费同一条消息时不重复投递
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_event ON webhook_deliveries(endpoint_id, event_id) WHERE redelivery_of IS NULL;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_id_created_at ON webhook_deliveries(endpoint_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhook_deliveries(created_at);
+
+-- 启用租户隔离
+SELECT auth.create_tenant_schema_for_table('webhook_endpoints');
+ALTER TABLE webhook_endpoints FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON webhook_endpoints TO admin USING (true);
+
+SELECT auth.create_tenant_schema_for_table('webhook_deliveries');
+ALTER TABLE webhook_deliveries FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON webhook_deliveries TO admin USING (true);
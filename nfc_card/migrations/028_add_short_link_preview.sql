@@ -0,0 +1,25 @@
+-- 028_add_short_link_preview.sql
+-- 短链接的分享预览信息：聊天软件和社交平台抓取短链接时返回OpenGraph页面而不是跳转
+-- title/description/imageUrl为商户设置的值，auto为从卡片默认视频自动填充的值
+
+ALTER TABLE short_links
+    ADD COLUMN IF NOT EXISTS preview JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE short_links
+    ADD CONSTRAINT chk_short_links_preview_object CHECK (jsonb_typeof(preview) = 'object');
+
+-- 卡片转让时短链接改属新商户，原商户设置的预览和从原商户视频填充的预览都不再适用
+CREATE OR REPLACE FUNCTION reset_short_link_preview()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.merchant_id IS DISTINCT FROM OLD.merchant_id THEN
+        NEW.preview := '{}';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_short_links_reset_preview ON short_links;
+CREATE TRIGGER trg_short_links_reset_preview
+    BEFORE UPDATE OF merchant_id ON short_links
+    FOR EACH ROW EXECUTE FUNCTION reset_short_link_preview();
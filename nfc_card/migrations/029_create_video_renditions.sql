@@ -0,0 +1,29 @@
+-- 029_create_video_renditions.sql
+-- 视频转码输出：每个分辨率的MP4文件和HLS播放列表，以及视频的HLS主播放列表
+
+ALTER TABLE videos
+    ADD COLUMN IF NOT EXISTS hls_master_key TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS video_renditions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    name VARCHAR(20) NOT NULL,                       -- 分辨率名称，如720p
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    file_key TEXT NOT NULL,                          -- MP4文件
+    file_size BIGINT NOT NULL DEFAULT 0,
+    playlist_key TEXT NOT NULL DEFAULT '',           -- HLS播放列表，未打包HLS时为空
+    segment_count INTEGER NOT NULL DEFAULT 0,
+    bandwidth INTEGER NOT NULL DEFAULT 0,            -- 峰值码率（bps），即主播放列表的BANDWIDTH
+    average_bandwidth INTEGER NOT NULL DEFAULT 0,    -- 平均码率（bps）
+    codecs VARCHAR(100) NOT NULL DEFAULT '',         -- RFC 6381编码标识，如avc1.4d401f,mp4a.40.2
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (video_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_renditions_merchant_id ON video_renditions(merchant_id);
+
+SELECT auth.create_tenant_schema_for_table('video_renditions');
+ALTER TABLE video_renditions FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON video_renditions TO admin USING (true);
@@ -0,0 +1,29 @@
+-- 030_create_video_uploads.sql
+-- 视频断点续传上传（tus 1.0）：已写入对象存储分片上传的分片，以及不足一个分片的待写入数据
+
+CREATE TABLE IF NOT EXISTS video_uploads (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    video_id UUID NOT NULL,                          -- 上传完成后创建的视频ID，创建上传时预先生成
+    file_key TEXT NOT NULL,
+    storage_upload_id TEXT NOT NULL,                 -- 对象存储分片上传ID
+    file_name VARCHAR(255) NOT NULL,
+    file_type VARCHAR(100) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    upload_length BIGINT NOT NULL CHECK (upload_length > 0),
+    upload_offset BIGINT NOT NULL DEFAULT 0 CHECK (upload_offset >= 0 AND upload_offset <= upload_length),
+    parts JSONB NOT NULL DEFAULT '[]' CHECK (jsonb_typeof(parts) = 'array'),
+    pending_size BIGINT NOT NULL DEFAULT 0,          -- 不足一个分片、暂存在临时对象中的字节数
+    status VARCHAR(20) NOT NULL DEFAULT 'uploading' CHECK (status IN ('uploading', 'completed')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_uploads_merchant_id ON video_uploads(merchant_id);
+CREATE INDEX IF NOT EXISTS idx_video_uploads_expires_at ON video_uploads(expires_at);
+
+SELECT auth.create_tenant_schema_for_table('video_uploads');
+ALTER TABLE video_uploads FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON video_uploads TO admin USING (true);
@@ -0,0 +1,31 @@
+-- 031_create_video_upload_sessions.sql
+-- 视频直传会话：客户端使用预签名URL直接上传到对象存储，完成后回调校验大小和SHA-256再创建视频
+
+CREATE TABLE IF NOT EXISTS video_upload_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    video_id UUID NOT NULL,                          -- 完成后创建的视频ID，创建会话时预先生成
+    file_key TEXT NOT NULL,
+    storage_upload_id TEXT NOT NULL DEFAULT '',      -- 对象存储分片上传ID，单次上传时为空
+    part_size BIGINT NOT NULL DEFAULT 0,
+    part_count INTEGER NOT NULL DEFAULT 0,
+    file_name VARCHAR(255) NOT NULL,
+    file_type VARCHAR(100) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    size BIGINT NOT NULL CHECK (size > 0),
+    checksum_sha256 CHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed')),
+    failure_reason TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_upload_sessions_merchant_status ON video_upload_sessions(merchant_id, status);
+CREATE INDEX IF NOT EXISTS idx_video_upload_sessions_expires_at ON video_upload_sessions(expires_at);
+
+SELECT auth.create_tenant_schema_for_table('video_upload_sessions');
+ALTER TABLE video_upload_sessions FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON video_upload_sessions TO admin USING (true);
@@ -0,0 +1,34 @@
+-- 032_create_transcode_jobs.sql
+-- 转码任务队列：工作进程通过FOR UPDATE SKIP LOCKED领取任务，持有租约并定期心跳，租约过期的任务由其他实例接管
+
+CREATE TABLE IF NOT EXISTS transcode_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'completed', 'failed', 'cancelled')),
+    attempts INTEGER NOT NULL DEFAULT 0,             -- 已领取次数，包括工作进程崩溃的执行
+    max_attempts INTEGER NOT NULL DEFAULT 3,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),  -- 最早可领取时间，重试时按退避时间推迟
+    worker_id VARCHAR(255) NOT NULL DEFAULT '',      -- 持有租约的工作进程
+    lease_expires_at TIMESTAMP WITH TIME ZONE,
+    heartbeat_at TIMESTAMP WITH TIME ZONE,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE, -- 执行中的任务在下次心跳时中断
+    last_error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- 每个视频同时只能有一个未结束的任务
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transcode_jobs_active_video
+    ON transcode_jobs(video_id) WHERE status IN ('queued', 'running');
+CREATE INDEX IF NOT EXISTS idx_transcode_jobs_queued ON transcode_jobs(run_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_transcode_jobs_lease ON transcode_jobs(lease_expires_at) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_transcode_jobs_status ON transcode_jobs(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_transcode_jobs_merchant_id ON transcode_jobs(merchant_id);
+
+SELECT auth.create_tenant_schema_for_table('transcode_jobs');
+ALTER TABLE transcode_jobs FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON transcode_jobs TO admin USING (true);
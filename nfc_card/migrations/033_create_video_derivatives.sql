@@ -0,0 +1,32 @@
+-- 033_create_video_derivatives.sql
+-- 渠道衍生视频：按输出规格（宽高比、填充方式、时长、码率）从原始视频生成，分发到对应平台时使用
+
+CREATE TABLE IF NOT EXISTS video_derivatives (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    merchant_id UUID NOT NULL REFERENCES merchants(id),
+    profile VARCHAR(50) NOT NULL,                    -- 输出规格名称，与分发渠道同名，如douyin
+    file_key TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    duration DOUBLE PRECISION NOT NULL DEFAULT 0,    -- 超过规格最大时长时为截断后的时长
+    file_size BIGINT NOT NULL DEFAULT 0,
+    bitrate INTEGER NOT NULL DEFAULT 0,              -- 平均码率（bps）
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (video_id, profile)
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_derivatives_merchant_id ON video_derivatives(merchant_id);
+
+SELECT auth.create_tenant_schema_for_table('video_derivatives');
+ALTER TABLE video_derivatives FORCE ROW LEVEL SECURITY;
+CREATE POLICY admin_policy ON video_derivatives TO admin USING (true);
+
+-- 转码队列同时处理衍生视频任务，profile为空表示视频本身的转码
+ALTER TABLE transcode_jobs
+    ADD COLUMN IF NOT EXISTS profile VARCHAR(50) NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS idx_transcode_jobs_active_video;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transcode_jobs_active_video_profile
+    ON transcode_jobs(video_id, profile) WHERE status IN ('queued', 'running');